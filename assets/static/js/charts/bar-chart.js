@@ -21,6 +21,9 @@ export function initializeBarChart() {
   // Get device pixel ratio for high-resolution rendering
   const dpr = window.devicePixelRatio || 1;
 
+  // Amounts are in the base currency, when the user has one
+  const currency = canvas.getAttribute('data-currency') || '';
+
   // Get chart data from the server
   let chartData = canvas.getAttribute('data-finance')
   if (!chartData || chartData.length === 0) return;
@@ -267,7 +270,7 @@ export function initializeBarChart() {
 
         // Show tooltip
         tooltip.innerHTML = `<strong>${point.Month}</strong><br>
-                                  Savings: ${formatMoney(point.Savings)}<br>
+                                  Savings: ${formatMoney(point.Savings, currency)}<br>
                                   (${point.SavingsPercentage.toFixed(1)}%)`;
         tooltip.style.left = `${e.clientX - rect.left}px`;
         tooltip.style.top = `${e.clientY - rect.top - 70}px`;
@@ -283,7 +286,7 @@ export function initializeBarChart() {
         found = true;
 
         tooltip.innerHTML = `<strong>${point.Month}</strong><br>
-                                  Income: ${formatMoney(point.Income)}`;
+                                  Income: ${formatMoney(point.Income, currency)}`;
         tooltip.style.left = `${e.clientX - rect.left}px`;
         tooltip.style.top = `${e.clientY - rect.top - 60}px`;
         tooltip.classList.add('visible');
@@ -298,7 +301,7 @@ export function initializeBarChart() {
         found = true;
 
         tooltip.innerHTML = `<strong>${point.Month}</strong><br>
-                                  Spending: ${formatMoney(point.Spending)}`;
+                                  Spending: ${formatMoney(point.Spending, currency)}`;
         tooltip.style.left = `${e.clientX - rect.left}px`;
         tooltip.style.top = `${e.clientY - rect.top - 60}px`;
         tooltip.classList.add('visible');
@@ -436,7 +439,7 @@ export function initializeBarChart() {
     const openYear = canvas.getAttribute('data-open-year');
     if (openMonth && openYear) {
       const targetUrl = `/?month=${openMonth}&year=${openYear}`;
-      const idx = chartData.findIndex(p => p.URL === targetUrl);
+      const idx = chartData.findIndex(p => p.URL === targetUrl || p.URL.startsWith(targetUrl + '&'));
       if (idx !== -1) {
         currentOffset = Math.max(0, Math.min(idx, chartData.length - config.visibleMonths));
       }
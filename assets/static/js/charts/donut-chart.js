@@ -145,6 +145,8 @@ class DonutChart {
     }
     this.ctx = this.canvas.getContext('2d');
     this.type = this.canvas.getAttribute('data-type') || 'expense';
+    // Amounts are in the base currency, when the user has one
+    this.currency = this.canvas.getAttribute('data-currency') || '';
     this.data = data || [];
     this.config = {
       padding: 30,
@@ -336,7 +338,7 @@ class DonutChart {
     this.ctx.fillStyle = isExpense
       ? this.config.colors.expense_base
       : this.config.colors.income_base;
-    this.ctx.fillText(formatMoney(total), centerX, centerY + 15);
+    this.ctx.fillText(formatMoney(total, this.currency), centerX, centerY + 15);
   }
 
   setupEventListeners() {
@@ -465,7 +467,7 @@ class DonutChart {
 
     const budgetInfo = segment.category.budget.status != 'no_budget'
       ? `<div class="budget-status ${segment.category.budget.status}">
-           Budget: ${formatMoney(segment.category.budget.amount)}
+           Budget: ${formatMoney(segment.category.budget.amount, this.currency)}
            (${segment.category.budget.percentage_used.toFixed(0)}% used)
          </div>`
       : '';
@@ -474,7 +476,7 @@ class DonutChart {
       <div class="tooltip-color" style="background-color: ${segment.color}"></div>
       <div class="tooltip-content">
         <div class="tooltip-category">${segment.category.name}</div>
-        <div class="tooltip-amount">${formatMoney(Math.abs(segment.category.amount))}</div>
+        <div class="tooltip-amount">${formatMoney(Math.abs(segment.category.amount), this.currency)}</div>
         <div class="tooltip-percentage">${segment.percentage.toFixed(1)}% of total</div>
         ${budgetInfo}
       </div>
@@ -531,7 +533,7 @@ class DonutChart {
   }
 
   renderBudgetRemaining(remaining) {
-    let formattedRemaining = formatMoney(Math.abs(remaining))
+    let formattedRemaining = formatMoney(Math.abs(remaining), this.currency)
     if (remaining <= 0) {
       return `<div class='budget-warning'>Over budget by ${formattedRemaining}</div>`
     } else {
@@ -544,9 +546,9 @@ class DonutChart {
     const budgetSection = category.budget && category.budget.status !== 'no_budget' ? `
       <div class="budget-container">
         <div class="budget-info">
-          <span class="budget-spent">${formatMoney(category.budget.spent)}</span>
+          <span class="budget-spent">${formatMoney(category.budget.spent, this.currency)}</span>
           <span class="budget-separator">/</span>
-          <span class="budget-total">${formatMoney(category.budget.amount)}</span>
+          <span class="budget-total">${formatMoney(category.budget.amount, this.currency)}</span>
         </div>
         <div class="budget-bar">
           <div class="budget-progress budget-${category.budget.status}"
@@ -559,11 +561,15 @@ class DonutChart {
     let redirectTo = '';
     const openMonth = this.canvas?.getAttribute('data-open-month');
     const openYear = this.canvas?.getAttribute('data-open-year');
+    const account = this.canvas?.getAttribute('data-account');
     if (openMonth && openYear) {
       const rp = new URLSearchParams();
       rp.set('open_month', openMonth);
       rp.set('open_year', openYear);
       rp.set('open_category', category.name);
+      if (account && account !== '0') {
+        rp.set('account', account);
+      }
       redirectTo = encodeURIComponent('/?' + rp.toString());
     }
 
@@ -573,7 +579,7 @@ class DonutChart {
         <div class="table-cell">${expense.description}</div>
         <div class="table-cell source-column">${expense.source}</div>
         <div class="table-cell ${expense.expense_type === 0 ? 'expense' : 'income'}">
-          ${formatMoney(Math.abs(expense.amount))}
+          ${formatMoney(Math.abs(expense.amount), this.currency ? expense.currency : '')}
         </div>
       </a>
   `).join('');
@@ -581,9 +587,9 @@ class DonutChart {
     return `
       ${budgetSection}
       <div class="category-stats">
-        <span>Total: ${formatMoney(Math.abs(category.amount))}</span>
+        <span>Total: ${formatMoney(Math.abs(category.amount), this.currency)}</span>
         <span>Transactions: ${category.expenses.length}</span>
-        <span>Avg: ${formatMoney(Math.abs(category.average_amount))}</span>
+        <span>Avg: ${formatMoney(Math.abs(category.average_amount), this.currency)}</span>
       </div>
       <div class="table">
         <div class="table-header">
@@ -5,9 +5,10 @@
 /**
  * Format currency amount in cents to display format
  * @param {number} amount - Amount in cents (e.g., 12345 = €123.45)
- * @returns {string} Formatted currency string (e.g., "123,45€")
+ * @param {string} [currency] - Currency code shown instead of the euro sign
+ * @returns {string} Formatted currency string (e.g., "123,45€" or "123,45 USD")
  */
-export function formatMoney(amount) {
+export function formatMoney(amount, currency) {
   const absAmount = Math.abs(amount);
   const sign = amount < 0 ? '-' : '';
 
@@ -19,7 +20,8 @@ export function formatMoney(amount) {
     intPart = intPart.replace(/\B(?=(\d{3})+(?!\d))/g, '.');
   }
 
-  return `${sign}${intPart},${decPart}€`;
+  const symbol = currency ? ` ${currency}` : '€';
+  return `${sign}${intPart},${decPart}${symbol}`;
 }
 
 /**
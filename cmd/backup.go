@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/GustavoCaso/expensetrace/storage"
+)
+
+func backupCommand(ctx context.Context, a *app, args []string) error {
+	flags := a.flagSet("backup", "<file>")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError(flags, "backup needs the file to write the copy to")
+	}
+	path := positional[0]
+
+	// SQLite refuses to overwrite a file, fail with a clearer message
+	if _, statErr := os.Stat(path); !errors.Is(statErr, fs.ErrNotExist) {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	return a.withStorage(ctx, func(s storage.Storage) error {
+		if backupErr := s.Backup(ctx, path); backupErr != nil {
+			return fmt.Errorf("failed to back up the database: %w", backupErr)
+		}
+
+		fmt.Fprintf(a.stdout, "Database backed up to %s\n", path)
+		return nil
+	})
+}
@@ -0,0 +1,16 @@
+package main
+
+import "context"
+
+func configCommand(_ context.Context, a *app, args []string) error {
+	fs := a.flagSet("config", "print")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || positional[0] != "print" {
+		return usageError(fs, "config needs the print subcommand")
+	}
+
+	return a.conf.Write(a.stdout)
+}
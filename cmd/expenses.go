@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/service/expense"
+	"github.com/GustavoCaso/expensetrace/service/importsvc"
+	"github.com/GustavoCaso/expensetrace/storage"
+)
+
+// importSessionTTL bounds the import sessions of files imported from the
+// command line, which are mapped and imported right away.
+const importSessionTTL = 5 * time.Minute
+
+func importCommand(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("import", "<file> --user <username> [--mapping <name>]")
+	username := fs.String("user", "", "user to import the expenses for")
+	mapping := fs.String("mapping", "", "saved mapping to read the file with, matched by header row when empty")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError(fs, "import needs a file")
+	}
+	if err = requireFlag(fs, "user", *username); err != nil {
+		return err
+	}
+	path := positional[0]
+
+	return a.withStorage(ctx, func(s storage.Storage) error {
+		userID, userErr := lookupUser(ctx, s, *username)
+		if userErr != nil {
+			return userErr
+		}
+
+		categories, categoriesErr := s.GetCategories(ctx, userID)
+		if categoriesErr != nil {
+			return categoriesErr
+		}
+
+		file, openErr := os.Open(path)
+		if openErr != nil {
+			return openErr
+		}
+		defer file.Close()
+
+		importService := importsvc.New(s, a.logger, importSessionTTL)
+		m := matcher.New(categories)
+		name := filepath.Base(path)
+
+		var result importsvc.ExecuteResult
+		var importErr error
+		if *mapping != "" {
+			result, importErr = importService.ImportWithMappingProfile(ctx, userID, name, file, *mapping, m)
+		} else {
+			result, importErr = importService.AutoImport(ctx, userID, name, file, m)
+		}
+		if importErr != nil {
+			return importErr
+		}
+
+		fmt.Fprintf(a.stdout, "%s: imported %d expenses", name, result.Imported)
+		if result.SkippedDuplicates > 0 {
+			fmt.Fprintf(a.stdout, ", skipped %d duplicates", result.SkippedDuplicates)
+		}
+		if result.LikelyDuplicates > 0 {
+			fmt.Fprintf(a.stdout, ", %d likely duplicates imported, review them", result.LikelyDuplicates)
+		}
+		if result.ErrorRows > 0 {
+			fmt.Fprintf(a.stdout, ", %d rows could not be read", result.ErrorRows)
+		}
+		if result.RejectedStatements > 0 {
+			fmt.Fprintf(a.stdout, ", %d statements left out: %s", result.RejectedStatements, result.RejectedReason)
+		}
+		fmt.Fprintln(a.stdout)
+		return nil
+	})
+}
+
+func exportCommand(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("export", "--user <username> [--format csv|json] [--output <file>]")
+	username := fs.String("user", "", "user whose expenses are exported")
+	format := fs.String("format", "csv", "export format, csv or json")
+	output := fs.String("output", "", "file to write the export to instead of stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usageError(fs, "export takes no arguments")
+	}
+	if err = requireFlag(fs, "user", *username); err != nil {
+		return err
+	}
+	if *format != "csv" && *format != "json" {
+		return usageError(fs, "unknown format %q", *format)
+	}
+
+	return a.withStorage(ctx, func(s storage.Storage) error {
+		userID, userErr := lookupUser(ctx, s, *username)
+		if userErr != nil {
+			return userErr
+		}
+
+		w := a.stdout
+		if *output != "" {
+			file, createErr := os.Create(*output)
+			if createErr != nil {
+				return createErr
+			}
+			defer file.Close()
+			w = file
+		}
+
+		expenseService := expense.New(s, a.logger)
+		if *format == "json" {
+			return expenseService.ExportJSON(ctx, userID, w)
+		}
+		return expenseService.Export(ctx, userID, w)
+	})
+}
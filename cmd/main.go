@@ -3,79 +3,239 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
-	"net/http"
+	"io"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"strings"
 
 	"github.com/GustavoCaso/expensetrace/config"
+	importUtil "github.com/GustavoCaso/expensetrace/import"
 	"github.com/GustavoCaso/expensetrace/logger"
-	"github.com/GustavoCaso/expensetrace/router"
 	"github.com/GustavoCaso/expensetrace/storage"
 	"github.com/GustavoCaso/expensetrace/storage/sqlite"
 )
 
-func main() {
-	conf := config.Parse()
+// errUsage is returned when a command is called with the wrong arguments,
+// once its usage has been printed.
+var errUsage = errors.New("invalid usage")
+
+// app holds what every command needs: the configuration, the logger and the
+// standard streams. The storage is opened by the commands that use it.
+type app struct {
+	conf   *config.Config
+	logger *logger.Logger
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+func commands() []command {
+	return []command{
+		{"serve", "", "Start the web server (the default command)", serveCommand},
+		{"migrate", "up|status", "Apply pending schema migrations or list them", migrateCommand},
+		{"user", "create|reset-password|delete <username>", "Manage users", userCommand},
+		{"import", "<file> --user <username> [--mapping <name>]", "Import a statement file", importCommand},
+		{"export", "--user <username> [--format csv|json] [--output <file>]", "Export expenses", exportCommand},
+		{"report", "--user <username> [--month YYYY-MM] [--format text|json]", "Print a monthly report", reportCommand},
+		{"backup", "<file>", "Write a copy of the database to a new file", backupCommand},
+		{"config", "print", "Print the effective configuration", configCommand},
+	}
+}
 
-	appLogger := logger.New(conf.Logger)
+func main() {
+	os.Exit(execute(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-	appLogger.Info("Using database", "path", conf.DBFile)
+// execute runs the command named by the first argument after the global
+// flags and returns the process exit code.
+func execute(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("expensetrace", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(stderr, fs) }
 
-	storage, err := sqlite.New(conf.DBFile)
+	conf, err := config.Parse(fs, args)
 	if err != nil {
-		appLogger.Fatal("Unable to get DB", "error", err.Error())
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		// The flag package already printed flag errors and the usage
+		if fs.Parsed() {
+			fmt.Fprintf(stderr, "Error: invalid configuration: %s\n", err)
+		}
+		return 2
 	}
 
-	err = storage.ApplyMigrations(context.Background(), appLogger)
-	if err != nil {
-		appLogger.Fatal("Unable to create schema", "error", err.Error())
+	args = fs.Args()
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
 	}
 
-	err = run(conf.Port, conf.Timeout, storage, appLogger)
-	if err != nil {
-		appLogger.Error("failed to run the expensetrace web service", "error", err)
-		os.Exit(1)
+	if name == "help" {
+		usage(stdout, fs)
+		return 0
+	}
+
+	var cmd *command
+	for _, c := range commands() {
+		if c.name == name {
+			cmd = &c
+			break
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "Unknown command %q\n\n", name)
+		usage(stderr, fs)
+		return 2
+	}
+
+	loggerConf := conf.Logger
+	// Command output goes to stdout, keep the logs apart from it
+	if name != "serve" && loggerConf.Output == "stdout" {
+		loggerConf.Output = "stderr"
+	}
+
+	a := &app{
+		conf:   conf,
+		logger: logger.New(loggerConf),
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
 	}
 
-	err = storage.Close()
+	if err = cmd.run(ctx, a, args); err != nil {
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "Error: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+func usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage: expensetrace [flags] <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.summary)
+		if c.args != "" {
+			fmt.Fprintf(w, "           %s %s\n", c.name, c.args)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags, taking precedence over the environment variables named and the config file:")
+
+	output := fs.Output()
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fs.SetOutput(output)
+}
+
+// openStorage opens the database, loading the configured bank providers
+// first. Migrations are applied unless migrate is false.
+func (a *app) openStorage(ctx context.Context, migrate bool) (storage.Storage, error) {
+	a.logger.Info("Using database", "path", a.conf.DBFile)
+
+	if a.conf.ProvidersDir != "" {
+		a.logger.Info("Loading bank providers", "path", a.conf.ProvidersDir)
+
+		if err := importUtil.LoadProviders(a.conf.ProvidersDir); err != nil {
+			return nil, fmt.Errorf("unable to load bank providers: %w", err)
+		}
+	}
+
+	s, err := sqlite.New(a.conf.DBFile)
 	if err != nil {
-		appLogger.Error("Error closing storage", "error", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("unable to open the database: %w", err)
+	}
+
+	if migrate {
+		if err = s.ApplyMigrations(ctx, a.logger); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("unable to create schema: %w", err)
+		}
 	}
 
-	os.Exit(0)
+	return s, nil
 }
 
-func run(port string, timeout time.Duration, storage storage.Storage, logger *logger.Logger) error {
-	handler := router.New(storage, logger)
-	logger.Info("Starting web server", "url", fmt.Sprintf("http://localhost:%s", port))
+// withStorage runs fn with the migrated database, closing it afterwards.
+func (a *app) withStorage(ctx context.Context, fn func(s storage.Storage) error) error {
+	s, err := a.openStorage(ctx, true)
+	if err != nil {
+		return err
+	}
 
-	server := &http.Server{
-		Addr:              fmt.Sprintf(":%s", port),
-		ReadHeaderTimeout: timeout,
-		Handler:           handler,
+	err = fn(s)
+	if closeErr := s.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("error closing storage: %w", closeErr)
 	}
+	return err
+}
 
-	go func() {
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			logger.Fatal("Unexpected server error", "error", err)
+// flagSet returns the flag set of a command, printing errors and usage to
+// the command's stderr.
+func (a *app) flagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: expensetrace %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args allowing flags after positional arguments, as in
+// "import statement.csv --user alice", and returns the positional ones.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		// The flag package already printed the error and the usage
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
 		}
-	}()
 
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
 
-	<-signalChan
+// usageError prints the usage of the command and returns errUsage.
+func usageError(fs *flag.FlagSet, format string, args ...any) error {
+	fmt.Fprintf(fs.Output(), format+"\n", args...)
+	fs.Usage()
+	return errUsage
+}
 
-	logger.Info("Received shutdown signal, shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	err := server.Shutdown(ctx)
-	if err != nil {
-		logger.Error("Issue shutting down server", "error", err)
+// requireFlag fails with the usage of the command when a required flag is
+// missing.
+func requireFlag(fs *flag.FlagSet, name, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return usageError(fs, "--%s is required", name)
 	}
 	return nil
 }
+
+// lookupUser returns the ID of the user named on the command line.
+func lookupUser(ctx context.Context, s storage.Storage, username string) (int64, error) {
+	user, err := s.GetUserByUsername(ctx, username)
+	if err != nil {
+		return 0, userError(username, err)
+	}
+
+	return user.ID(), nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// noPendingMigrations matches the summary of migrate status when nothing is
+// pending, but not "20 of 20".
+var noPendingMigrations = regexp.MustCompile(`\b0 of`)
+
+const evoCSV = `Fecha de la operación,Fecha Valor,Concepto,Importe,Divisa,Tipo de movimiento,Saldo disponible
+01/01/2024,,Restaurant bill,-1234.56,USD,,5000.00
+02/01/2024,,Uber ride,-5000.00,USD,,0.00`
+
+func setupCLI(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("EXPENSETRACE_DB", filepath.Join(dir, "expensetrace.db"))
+	t.Setenv("EXPENSETRACE_LOG_OUTPUT", "discard")
+	t.Setenv("EXPENSETRACE_PROVIDERS_DIR", "")
+	return dir
+}
+
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := execute(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCLIManagesUsersAndExpenses(t *testing.T) {
+	dir := setupCLI(t)
+
+	code, _, stderr := runCLI(t, "secret-password\n", "user", "create", "alice")
+	if code != 0 {
+		t.Fatalf("user create exited with %d: %s", code, stderr)
+	}
+
+	statement := filepath.Join(dir, "evo_transactions.csv")
+	if err := os.WriteFile(statement, []byte(evoCSV), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, "", "import", statement, "--user", "alice")
+	if code != 0 {
+		t.Fatalf("import exited with %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "imported 2 expenses") {
+		t.Errorf("Unexpected import output %q", stdout)
+	}
+
+	code, stdout, stderr = runCLI(t, "", "export", "--user", "alice")
+	if code != 0 {
+		t.Fatalf("export exited with %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "ID,Source,Date") || !strings.Contains(stdout, "uber ride") {
+		t.Errorf("Unexpected CSV export %q", stdout)
+	}
+
+	code, stdout, stderr = runCLI(t, "", "export", "--user", "alice", "--format", "json")
+	if code != 0 {
+		t.Fatalf("export exited with %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"description": "restaurant bill"`) {
+		t.Errorf("Unexpected JSON export %q", stdout)
+	}
+
+	code, stdout, stderr = runCLI(t, "", "report", "--user", "alice", "--month", "2024-01")
+	if code != 0 {
+		t.Fatalf("report exited with %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "January 2024") || !strings.Contains(stdout, "-6.234,56") {
+		t.Errorf("Unexpected report %q", stdout)
+	}
+
+	code, _, stderr = runCLI(t, "", "export", "--user", "bob")
+	if code != 1 || !strings.Contains(stderr, `user "bob" not found`) {
+		t.Errorf("Expected exporting an unknown user to fail, got %d: %s", code, stderr)
+	}
+
+	code, _, _ = runCLI(t, "", "user", "delete", "alice")
+	if code != 2 {
+		t.Errorf("Expected deleting without --yes to be refused, got %d", code)
+	}
+
+	code, _, stderr = runCLI(t, "", "user", "delete", "alice", "--yes")
+	if code != 0 {
+		t.Fatalf("user delete exited with %d: %s", code, stderr)
+	}
+
+	code, _, _ = runCLI(t, "", "export", "--user", "alice")
+	if code != 1 {
+		t.Errorf("Expected the deleted user to be gone, got %d", code)
+	}
+}
+
+func TestCLIMigrateAndBackup(t *testing.T) {
+	dir := setupCLI(t)
+
+	code, stdout, _ := runCLI(t, "", "migrate", "status")
+	if code != 0 || noPendingMigrations.MatchString(stdout) || !strings.Contains(stdout, "pending") {
+		t.Errorf("Expected every migration to be pending on a new database, got %d: %s", code, stdout)
+	}
+
+	if code, _, _ = runCLI(t, "", "migrate", "up"); code != 0 {
+		t.Fatalf("migrate up exited with %d", code)
+	}
+
+	code, stdout, _ = runCLI(t, "", "migrate", "status")
+	if code != 0 || !noPendingMigrations.MatchString(stdout) {
+		t.Errorf("Expected no pending migrations, got %d: %s", code, stdout)
+	}
+
+	backup := filepath.Join(dir, "backup.db")
+	code, _, stderr := runCLI(t, "", "backup", backup)
+	if code != 0 {
+		t.Fatalf("backup exited with %d: %s", code, stderr)
+	}
+	if _, err := os.Stat(backup); err != nil {
+		t.Errorf("Expected the backup to be written: %v", err)
+	}
+
+	code, _, stderr = runCLI(t, "", "backup", backup)
+	if code != 1 || !strings.Contains(stderr, "already exists") {
+		t.Errorf("Expected backing up over an existing file to fail, got %d: %s", code, stderr)
+	}
+}
+
+func TestCLIUsage(t *testing.T) {
+	setupCLI(t)
+
+	code, _, stderr := runCLI(t, "", "unknown")
+	if code != 2 || !strings.Contains(stderr, "Usage: expensetrace") {
+		t.Errorf("Expected the usage for an unknown command, got %d: %s", code, stderr)
+	}
+
+	code, stdout, _ := runCLI(t, "", "help")
+	if code != 0 || !strings.Contains(stdout, "-inbox-dir") || !strings.Contains(stdout, "EXPENSETRACE_INBOX_DIR") {
+		t.Errorf("Expected the usage to list the global flags, got %d: %s", code, stdout)
+	}
+
+	code, _, stderr = runCLI(t, "", "import", "statement.csv")
+	if code != 2 || !strings.Contains(stderr, "--user is required") {
+		t.Errorf("Expected import without --user to fail, got %d: %s", code, stderr)
+	}
+}
+
+func TestCLIConfigPrint(t *testing.T) {
+	dir := setupCLI(t)
+
+	configFile := filepath.Join(dir, "expensetrace.yaml")
+	if err := os.WriteFile(configFile, []byte("port: 9000\ntimeout: 20s\nsecure_cookies: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EXPENSETRACE_TIMEOUT", "30s")
+
+	code, stdout, stderr := runCLI(t, "", "--config", configFile, "--port", "9001", "config", "print")
+	if code != 0 {
+		t.Fatalf("config print exited with %d: %s", code, stderr)
+	}
+	for _, expected := range []string{`port: "9001"`, "timeout: 30s", "secure_cookies: true", "output: discard"} {
+		if !strings.Contains(stdout, expected) {
+			t.Errorf("Expected the effective config to contain %q, got:\n%s", expected, stdout)
+		}
+	}
+
+	code, _, stderr = runCLI(t, "", "--port", "http", "config", "print")
+	if code != 2 || !strings.Contains(stderr, "invalid configuration") {
+		t.Errorf("Expected an invalid port to be rejected, got %d: %s", code, stderr)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/storage"
+)
+
+func migrateCommand(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("migrate", "up|status")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError(fs, "migrate needs a subcommand")
+	}
+
+	switch positional[0] {
+	case "up":
+		return a.withStorage(ctx, func(s storage.Storage) error {
+			fmt.Fprintln(a.stdout, "Database schema is up to date")
+			return nil
+		})
+	case "status":
+		s, openErr := a.openStorage(ctx, false)
+		if openErr != nil {
+			return openErr
+		}
+		defer s.Close()
+
+		return migrationStatus(ctx, a, s)
+	default:
+		return usageError(fs, "unknown migrate subcommand %q", positional[0])
+	}
+}
+
+func migrationStatus(ctx context.Context, a *app, s storage.Storage) error {
+	migrations, err := s.GetMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, m := range migrations {
+		appliedAt := "pending"
+		if m.Applied() {
+			appliedAt = m.AppliedAt.Format(time.RFC3339)
+		} else {
+			pending++
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", m.Version, m.Name, appliedAt)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "\n%d of %d migrations pending\n", pending, len(migrations))
+	return nil
+}
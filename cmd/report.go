@@ -0,0 +1,310 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/service/report"
+	"github.com/GustavoCaso/expensetrace/storage"
+	"github.com/GustavoCaso/expensetrace/util"
+)
+
+// defaultTrendMonths is how many months the trends cover by default.
+const defaultTrendMonths = 12
+
+// monthReport is the report of one month of the trend, empty when the
+// month has no report.
+type monthReport struct {
+	date   time.Time
+	report domain.Report
+}
+
+func reportCommand(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("report", "--user <username> [--month YYYY-MM] [--months N] [--format text|json]")
+	username := fs.String("user", "", "user to report on")
+	month := fs.String("month", time.Now().Format("2006-01"), "month to report on")
+	months := fs.Int("months", defaultTrendMonths, "number of months shown in the trends, ending with --month")
+	format := fs.String("format", "text", "output format, text or json")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usageError(fs, "report takes no arguments")
+	}
+	if err = requireFlag(fs, "user", *username); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return usageError(fs, "unknown format %q", *format)
+	}
+	if *months < 1 {
+		return usageError(fs, "--months must be at least 1")
+	}
+
+	date, err := time.Parse("2006-01", *month)
+	if err != nil {
+		return usageError(fs, "invalid month %q, use the YYYY-MM format", *month)
+	}
+
+	return a.withStorage(ctx, func(s storage.Storage) error {
+		userID, userErr := lookupUser(ctx, s, *username)
+		if userErr != nil {
+			return userErr
+		}
+
+		reportService := report.New(s, a.logger)
+		reportService.Generate(ctx, userID, nil)
+
+		trend := make([]monthReport, 0, *months)
+		for i := *months - 1; i >= 0; i-- {
+			d := date.AddDate(0, -i, 0)
+			trend = append(trend, monthReport{
+				date:   d,
+				report: reportService.ForMonth(userID, nil, int(d.Month()), d.Year()),
+			})
+		}
+
+		if *format == "json" {
+			return writeReportJSON(a.stdout, trend)
+		}
+		return writeReport(a.stdout, trend)
+	})
+}
+
+// writeReport renders the report of the last month of the trend as colored
+// tables, with sparklines of the months before it.
+func writeReport(out io.Writer, trend []monthReport) error {
+	current := trend[len(trend)-1]
+	r := current.report
+
+	var b strings.Builder
+	b.WriteString(util.ColorOutput(monthTitle(current), "bold", "underline"))
+	b.WriteString("\n\n")
+
+	writeTable(&b, [][]cell{
+		{{text: "Income"}, moneyCell(r.Income), {}},
+		{{text: "Spending"}, moneyCell(r.Spending), {}},
+		{{text: "Savings"}, moneyCell(r.Savings), signedCell(fmt.Sprintf("%.2f%%", r.SavingsPercentage), r.Savings)},
+	})
+
+	if len(r.ExpenseCategories) > 0 {
+		categories := slices.Clone(r.ExpenseCategories)
+		// Biggest spending first
+		slices.SortFunc(categories, func(c1, c2 domain.CategoryReport) int {
+			return cmp.Or(cmp.Compare(c1.Amount, c2.Amount), strings.Compare(c1.Name, c2.Name))
+		})
+
+		rows := [][]cell{{
+			header("Category", false), header("Amount", true), header("Share", true),
+			header("Budget", true), header("Used", true), header("Status", false), header("Trend", false),
+		}}
+		for _, category := range categories {
+			rows = append(rows, []cell{
+				{text: category.Name},
+				moneyCell(category.Amount),
+				{text: fmt.Sprintf("%.2f%%", category.PercentageOfTotal), right: true},
+				budgetCell(category.Budget),
+				usedCell(category.Budget),
+				statusCell(category.Budget.Status),
+				{text: util.Sparkline(categoryTrend(trend, category.Name))},
+			})
+		}
+
+		b.WriteString("\n")
+		writeTable(&b, rows)
+	}
+
+	if len(trend) > 1 {
+		var income, spending, savings []int64
+		for _, month := range trend {
+			income = append(income, month.report.Income)
+			spending = append(spending, -month.report.Spending)
+			savings = append(savings, month.report.Savings)
+		}
+
+		fmt.Fprintf(&b, "\n%s\n", util.ColorOutput(
+			fmt.Sprintf("Trend %s to %s", trend[0].date.Format("Jan 2006"), current.date.Format("Jan 2006")),
+			"bold",
+		))
+		writeTable(&b, [][]cell{
+			{{text: "Income"}, {text: util.Sparkline(income)}},
+			{{text: "Spending"}, {text: util.Sparkline(spending)}},
+			{{text: "Savings"}, {text: util.Sparkline(savings)}},
+		})
+	}
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func monthTitle(month monthReport) string {
+	if month.report.Title != "" {
+		return month.report.Title
+	}
+	return fmt.Sprintf("%s %d", month.date.Month(), month.date.Year())
+}
+
+// categoryTrend returns what was spent in the category every month of the
+// trend, as positive amounts.
+func categoryTrend(trend []monthReport, name string) []int64 {
+	amounts := make([]int64, 0, len(trend))
+	for _, month := range trend {
+		var amount int64
+		for _, category := range month.report.ExpenseCategories {
+			if category.Name == name {
+				amount = -category.Amount
+				break
+			}
+		}
+		amounts = append(amounts, amount)
+	}
+	return amounts
+}
+
+// cell is a table cell. Colors are applied once the cell is padded, so the
+// escape codes do not break the alignment.
+type cell struct {
+	text   string
+	right  bool
+	colors []string
+}
+
+func header(text string, right bool) cell {
+	return cell{text: text, right: right, colors: []string{"bold"}}
+}
+
+func moneyCell(amount int64) cell {
+	return cell{text: util.FormatMoney(amount, ".", ","), right: true}
+}
+
+func signedCell(text string, amount int64) cell {
+	c := cell{text: text, right: true, colors: []string{"green"}}
+	if amount < 0 {
+		c.colors = []string{"red"}
+	}
+	return c
+}
+
+func budgetCell(budget domain.BudgetInfo) cell {
+	if budget.Status == domain.BudgetStatusNoBudget {
+		return cell{text: "-", right: true}
+	}
+	return moneyCell(budget.Amount)
+}
+
+func usedCell(budget domain.BudgetInfo) cell {
+	if budget.Status == domain.BudgetStatusNoBudget {
+		return cell{text: "-", right: true}
+	}
+	return cell{text: fmt.Sprintf("%.2f%%", budget.PercentageUsed), right: true}
+}
+
+func statusCell(status domain.BudgetStatus) cell {
+	switch status {
+	case domain.BudgetStatusUnder:
+		return cell{text: "under", colors: []string{"green"}}
+	case domain.BudgetStatusNear:
+		return cell{text: "near", colors: []string{"yellow"}}
+	case domain.BudgetStatusOver:
+		return cell{text: "over", colors: []string{"red", "bold"}}
+	default:
+		return cell{text: "no budget"}
+	}
+}
+
+func writeTable(b *strings.Builder, rows [][]cell) {
+	widths := []int{}
+	for _, row := range rows {
+		for i, c := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(c.text))
+		}
+	}
+
+	for _, row := range rows {
+		line := make([]string, 0, len(row))
+		for i, c := range row {
+			padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c.text))
+			text := c.text
+			if len(c.colors) > 0 {
+				text = util.ColorOutput(text, c.colors...)
+			}
+			if c.right {
+				line = append(line, padding+text)
+			} else {
+				line = append(line, text+padding)
+			}
+		}
+		b.WriteString(strings.TrimRight(strings.Join(line, "  "), " "))
+		b.WriteString("\n")
+	}
+}
+
+// reportJSON is the report written by --format json. The expenses of every
+// category are left out, a trend of the months before is added.
+type reportJSON struct {
+	domain.Report
+
+	ExpenseCategories []categoryJSON `json:"expense_categories"`
+	IncomeCategories  []categoryJSON `json:"income_categories"`
+	Trend             []trendJSON    `json:"trend"`
+}
+
+type categoryJSON struct {
+	domain.CategoryReport
+
+	Expenses []domain.Expense `json:"expenses,omitempty"`
+}
+
+type trendJSON struct {
+	Month             string  `json:"month"`
+	Income            int64   `json:"income"`
+	Spending          int64   `json:"spending"`
+	Savings           int64   `json:"savings"`
+	SavingsPercentage float32 `json:"savings_percentage"`
+}
+
+func writeReportJSON(out io.Writer, trend []monthReport) error {
+	current := trend[len(trend)-1]
+
+	output := reportJSON{
+		Report:            current.report,
+		ExpenseCategories: categoriesJSON(current.report.ExpenseCategories),
+		IncomeCategories:  categoriesJSON(current.report.IncomeCategories),
+		Trend:             make([]trendJSON, 0, len(trend)),
+	}
+	output.Title = monthTitle(current)
+
+	for _, month := range trend {
+		output.Trend = append(output.Trend, trendJSON{
+			Month:             month.date.Format("2006-01"),
+			Income:            month.report.Income,
+			Spending:          month.report.Spending,
+			Savings:           month.report.Savings,
+			SavingsPercentage: month.report.SavingsPercentage,
+		})
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+func categoriesJSON(categories []domain.CategoryReport) []categoryJSON {
+	result := make([]categoryJSON, 0, len(categories))
+	for _, category := range categories {
+		result = append(result, categoryJSON{CategoryReport: category})
+	}
+	return result
+}
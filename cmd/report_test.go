@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func testTrend() []monthReport {
+	months := []time.Time{
+		time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	return []monthReport{
+		// No expenses were stored in January
+		{date: months[0]},
+		{date: months[1], report: domain.Report{
+			Title:    "February 2024",
+			Income:   300000,
+			Spending: -100000,
+			Savings:  200000,
+			ExpenseCategories: []domain.CategoryReport{
+				{Name: "Food", Amount: -100000, Budget: domain.BudgetInfo{Status: domain.BudgetStatusNoBudget}},
+			},
+		}},
+		{date: months[2], report: domain.Report{
+			Title:             "March 2024",
+			Income:            300000,
+			Spending:          -350000,
+			Savings:           -50000,
+			SavingsPercentage: -16.67,
+			ExpenseCategories: []domain.CategoryReport{
+				{
+					Name:              "Rent",
+					Amount:            -150000,
+					PercentageOfTotal: 42.86,
+					Budget:            domain.BudgetInfo{Status: domain.BudgetStatusNoBudget},
+				},
+				{
+					Name:              "Food",
+					Amount:            -200000,
+					PercentageOfTotal: 57.14,
+					Expenses:          []domain.Expense{nil},
+					Budget: domain.BudgetInfo{
+						Amount:         150000,
+						Spent:          200000,
+						Remaining:      -50000,
+						PercentageUsed: 133.33,
+						Status:         domain.BudgetStatusOver,
+					},
+				},
+			},
+		}},
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = noColor })
+
+	var out bytes.Buffer
+	if err := writeReport(&out, testTrend()); err != nil {
+		t.Fatalf("writeReport returned error: %v", err)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	expected := []string{
+		"March 2024",
+		"",
+		"Income     3.000,00",
+		"Spending  -3.500,00",
+		"Savings     -500,00  -16.67%",
+		"",
+		"Category     Amount   Share    Budget     Used  Status     Trend",
+		"Food      -2.000,00  57.14%  1.500,00  133.33%  over       ▁▄█",
+		"Rent      -1.500,00  42.86%         -        -  no budget  ▁▁█",
+		"",
+		"Trend Jan 2024 to Mar 2024",
+		"Income    ▁██",
+		"Spending  ▁▃█",
+		"Savings   ▂█▁",
+	}
+	for i, line := range expected {
+		if i >= len(lines) || lines[i] != line {
+			t.Fatalf("Unexpected report, line %d should be %q:\n%s", i, line, out.String())
+		}
+	}
+}
+
+func TestWriteReportJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeReportJSON(&out, testTrend()); err != nil {
+		t.Fatalf("writeReportJSON returned error: %v", err)
+	}
+
+	if strings.Contains(out.String(), `"expenses"`) {
+		t.Errorf("Expected the expenses of the categories to be left out:\n%s", out.String())
+	}
+
+	var decoded struct {
+		Title             string `json:"title"`
+		Savings           int64  `json:"savings"`
+		ExpenseCategories []struct {
+			Name   string `json:"name"`
+			Budget struct {
+				Status string `json:"status"`
+			} `json:"budget"`
+		} `json:"expense_categories"`
+		Trend []struct {
+			Month    string `json:"month"`
+			Spending int64  `json:"spending"`
+		} `json:"trend"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to decode the JSON report: %v", err)
+	}
+
+	if decoded.Title != "March 2024" || decoded.Savings != -50000 || len(decoded.ExpenseCategories) != 2 {
+		t.Errorf("Unexpected JSON report %+v", decoded)
+	}
+	if len(decoded.Trend) != 3 || decoded.Trend[0].Month != "2024-01" || decoded.Trend[2].Spending != -350000 {
+		t.Errorf("Unexpected trend %+v", decoded.Trend)
+	}
+}
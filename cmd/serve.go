@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/config"
+	"github.com/GustavoCaso/expensetrace/logger"
+	"github.com/GustavoCaso/expensetrace/router"
+	"github.com/GustavoCaso/expensetrace/service/importsvc"
+	"github.com/GustavoCaso/expensetrace/service/inbox"
+	"github.com/GustavoCaso/expensetrace/service/webhook"
+	"github.com/GustavoCaso/expensetrace/storage"
+)
+
+// inboxSessionTTL bounds the import sessions of inbox files, which are
+// mapped and imported right away.
+const inboxSessionTTL = 5 * time.Minute
+
+// webhookInterval is how often queued webhook deliveries are posted.
+const webhookInterval = 10 * time.Second
+
+func serveCommand(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("serve", "")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usageError(fs, "serve takes no arguments")
+	}
+
+	storage, err := a.openStorage(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	watcherCtx, stopWatcher := context.WithCancel(ctx)
+	watcherDone := make(chan struct{})
+	if a.conf.InboxDir != "" {
+		importService := importsvc.New(storage, a.logger, inboxSessionTTL)
+		watcher := inbox.New(storage, importService, a.logger, a.conf.InboxDir, a.conf.InboxInterval)
+		go func() {
+			watcher.Run(watcherCtx)
+			close(watcherDone)
+		}()
+	} else {
+		close(watcherDone)
+	}
+
+	dispatcherCtx, stopDispatcher := context.WithCancel(ctx)
+	dispatcherDone := make(chan struct{})
+	dispatcher := webhook.NewDispatcher(storage, a.logger, webhookInterval)
+	go func() {
+		dispatcher.Run(dispatcherCtx)
+		close(dispatcherDone)
+	}()
+
+	err = run(a.conf, storage, a.logger)
+
+	// Let an import in progress finish before closing the storage
+	stopWatcher()
+	<-watcherDone
+
+	// Deliveries left queued are posted on the next start
+	stopDispatcher()
+	<-dispatcherDone
+
+	if err != nil {
+		return fmt.Errorf("failed to run the expensetrace web service: %w", err)
+	}
+
+	if err = storage.Close(); err != nil {
+		return fmt.Errorf("error closing storage: %w", err)
+	}
+
+	return nil
+}
+
+func run(conf *config.Config, storage storage.Storage, logger *logger.Logger) error {
+	handler := router.New(
+		storage,
+		logger,
+		router.WithSecureCookies(conf.SecureCookies),
+		router.WithTrustedOrigins(conf.TrustedOrigins),
+		router.WithEmbedding(conf.AllowEmbedding),
+		router.WithImportSessionTTL(conf.ImportSessionTTL),
+	)
+	logger.Info("Starting web server", "url", fmt.Sprintf("http://localhost:%s", conf.Port))
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%s", conf.Port),
+		ReadHeaderTimeout: conf.Timeout,
+		Handler:           handler,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Unexpected server error", "error", err)
+		}
+	}()
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+
+	<-signalChan
+
+	logger.Info("Received shutdown signal, shutting down server...")
+	ctx, cancel := context.WithTimeout(context.Background(), conf.Timeout)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if err != nil {
+		logger.Error("Issue shutting down server", "error", err)
+	}
+	return nil
+}
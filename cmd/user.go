@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/service/auth"
+	"github.com/GustavoCaso/expensetrace/storage"
+)
+
+func userCommand(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("user", "create|reset-password|delete <username>")
+	yes := fs.Bool("yes", false, "confirm deleting the user and all of their data")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return usageError(fs, "user needs a subcommand and a username")
+	}
+	action, username := positional[0], positional[1]
+
+	switch action {
+	case "create", "reset-password":
+		password, readErr := a.readPassword()
+		if readErr != nil {
+			return readErr
+		}
+
+		return a.withStorage(ctx, func(s storage.Storage) error {
+			authService := auth.New(s, a.logger)
+			if action == "create" {
+				if _, createErr := authService.CreateUser(ctx, username, password); createErr != nil {
+					return createErr
+				}
+				fmt.Fprintf(a.stdout, "Created user %s\n", username)
+				return nil
+			}
+
+			if resetErr := authService.ResetPassword(ctx, username, password); resetErr != nil {
+				return userError(username, resetErr)
+			}
+			fmt.Fprintf(a.stdout, "Reset the password of %s\n", username)
+			return nil
+		})
+	case "delete":
+		if !*yes {
+			return usageError(fs, "deleting %s removes all of their data, pass --yes to confirm", username)
+		}
+
+		return a.withStorage(ctx, func(s storage.Storage) error {
+			if deleteErr := auth.New(s, a.logger).DeleteUser(ctx, username); deleteErr != nil {
+				return userError(username, deleteErr)
+			}
+			fmt.Fprintf(a.stdout, "Deleted user %s\n", username)
+			return nil
+		})
+	default:
+		return usageError(fs, "unknown user subcommand %q", action)
+	}
+}
+
+// readPassword reads the password from the first line of stdin, so it can
+// be piped in by scripts without showing up in the process list.
+func (a *app) readPassword() (string, error) {
+	if f, ok := a.stdin.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprint(a.stderr, "Password: ")
+		}
+	}
+
+	line, err := bufio.NewReader(a.stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read the password: %w", err)
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("no password given on stdin")
+	}
+	return password, nil
+}
+
+// userError names the user in not found errors.
+func userError(username string, err error) error {
+	var notFound *domain.NotFoundError
+	if errors.As(err, &notFound) {
+		return fmt.Errorf("user %q not found", username)
+	}
+	return err
+}
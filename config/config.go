@@ -1,18 +1,44 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
 	"github.com/GustavoCaso/expensetrace/logger"
 )
 
 type Config struct {
-	DBFile  string
-	Logger  logger.Config
-	Port    string
-	Timeout time.Duration
+	DBFile  string        `yaml:"db"`
+	Logger  logger.Config `yaml:"log"`
+	Port    string        `yaml:"port"`
+	Timeout time.Duration `yaml:"timeout"`
+	// ProvidersDir holds extra bank provider definitions (YAML or JSON).
+	ProvidersDir string `yaml:"providers_dir"`
+	// InboxDir enables automatic imports of the files users drop in a
+	// subdirectory named after their username. Empty disables them.
+	InboxDir string `yaml:"inbox_dir"`
+	// InboxInterval is how often the inbox is checked for new files.
+	InboxInterval time.Duration `yaml:"inbox_interval"`
+	// SecureCookies marks session cookies as HTTPS only.
+	SecureCookies bool `yaml:"secure_cookies"`
+	// TrustedOrigins are the origins allowed to make cross-origin requests
+	// besides the one serving the app.
+	TrustedOrigins []string `yaml:"trusted_origins"`
+	// AllowEmbedding allows the app to be embedded in an iframe.
+	AllowEmbedding bool `yaml:"allow_embedding"`
+	// ImportSessionTTL is how long an import started from the web page is
+	// kept before it expires.
+	ImportSessionTTL time.Duration `yaml:"import_session_ttl"`
 }
 
 const (
@@ -22,57 +48,270 @@ const (
 	defaultLogOutput = "stdout"
 	defaultPort      = "8080"
 	defaultTimeout   = 5 * time.Second
+
+	defaultInboxInterval    = time.Minute
+	defaultImportSessionTTL = 30 * time.Minute
+
+	maxPort = 65535
 )
 
-func (c *Config) parseEnv() {
-	if db := os.Getenv("EXPENSETRACE_DB"); db != "" {
-		c.DBFile = db
-	} else {
-		c.DBFile = defaultDBFile
+// envPrefix is the prefix of the environment variables read by Parse.
+const envPrefix = "EXPENSETRACE_"
+
+// setting is a configuration value that can be set from the environment
+// and the command line. The config file sets it through the yaml tags.
+type setting struct {
+	name   string
+	usage  string
+	isBool bool
+	set    func(c *Config, value string) error
+}
+
+// flagValue holds the value of a setting given on the command line.
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (f *flagValue) String() string { return f.value }
+
+func (f *flagValue) Set(value string) error {
+	f.value = value
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
+
+var settings = []setting{
+	{"db", "path to the SQLite database file", false, func(c *Config, v string) error {
+		c.DBFile = v
+		return nil
+	}},
+	{"port", "web server port", false, func(c *Config, v string) error {
+		c.Port = v
+		return nil
+	}},
+	{"timeout", "web server timeout", false, func(c *Config, v string) error {
+		return setDuration(&c.Timeout, v)
+	}},
+	{"log-level", "log level: debug, info, warn or error", false, func(c *Config, v string) error {
+		c.Logger.Level = logger.Level(v)
+		return nil
+	}},
+	{"log-format", "log format: text or json", false, func(c *Config, v string) error {
+		c.Logger.Format = logger.Format(v)
+		return nil
+	}},
+	{"log-output", "log output: stdout, stderr, discard or a file path", false, func(c *Config, v string) error {
+		c.Logger.Output = v
+		return nil
+	}},
+	{"providers-dir", "directory with additional bank provider definitions", false, func(c *Config, v string) error {
+		c.ProvidersDir = v
+		return nil
+	}},
+	{"inbox-dir", "directory watched for statements to import", false, func(c *Config, v string) error {
+		c.InboxDir = v
+		return nil
+	}},
+	{"inbox-interval", "how often the import inbox is checked", false, func(c *Config, v string) error {
+		return setDuration(&c.InboxInterval, v)
+	}},
+	{"secure-cookies", "mark session cookies as HTTPS only", true, func(c *Config, v string) error {
+		return setBool(&c.SecureCookies, v)
+	}},
+	{"trusted-origins", "comma-separated origins trusted for CSRF protection", false, func(c *Config, v string) error {
+		c.TrustedOrigins = splitList(v)
+		return nil
+	}},
+	{"allow-embedding", "allow the app to be embedded in an iframe", true, func(c *Config, v string) error {
+		return setBool(&c.AllowEmbedding, v)
+	}},
+	{"import-session-ttl", "how long an import from the web page is kept", false, func(c *Config, v string) error {
+		return setDuration(&c.ImportSessionTTL, v)
+	}},
+}
+
+// envName returns the environment variable of a setting, e.g.
+// EXPENSETRACE_LOG_LEVEL for log-level.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func defaults() *Config {
+	return &Config{
+		DBFile: defaultDBFile,
+		Logger: logger.Config{
+			Level:  defaultLogLevel,
+			Format: defaultLogFormat,
+			Output: defaultLogOutput,
+		},
+		Port:             defaultPort,
+		Timeout:          defaultTimeout,
+		InboxInterval:    defaultInboxInterval,
+		ImportSessionTTL: defaultImportSessionTTL,
+	}
+}
+
+// Parse builds the configuration from, in order of precedence, the flags in
+// args, EXPENSETRACE_* environment variables, the config file and the
+// defaults. The flags are registered on fs, which is left holding the
+// arguments after them. The config file is named by the --config flag or
+// EXPENSETRACE_CONFIG.
+func Parse(fs *flag.FlagSet, args []string) (*Config, error) {
+	configFile := fs.String("config", "", "path to a YAML config file ("+envPrefix+"CONFIG)")
+	flags := map[string]*flagValue{}
+	for _, s := range settings {
+		flags[s.name] = &flagValue{isBool: s.isBool}
+		fs.Var(flags[s.name], s.name, fmt.Sprintf("%s (%s)", s.usage, envName(s.name)))
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	conf := defaults()
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv(envPrefix + "CONFIG")
+	}
+	if path != "" {
+		if err := conf.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(envName(s.name)); ok && value != "" {
+			if err := s.set(conf, value); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", envName(s.name), err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.name == f.Name && flagErr == nil {
+				if err := s.set(conf, flags[s.name].value); err != nil {
+					flagErr = fmt.Errorf("invalid --%s: %w", s.name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func (c *Config) readFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// validate reports the first setting with an invalid value.
+func (c *Config) validate() error {
+	if c.DBFile == "" {
+		return errors.New("db must not be empty")
 	}
 
-	if level := os.Getenv("EXPENSETRACE_LOG_LEVEL"); level != "" {
-		c.Logger.Level = logger.Level(level)
-	} else {
-		c.Logger.Level = defaultLogLevel
+	port, err := strconv.Atoi(c.Port)
+	if err != nil || port < 1 || port > maxPort {
+		return fmt.Errorf("port %q must be a number between 1 and 65535", c.Port)
 	}
 
-	if format := os.Getenv("EXPENSETRACE_LOG_FORMAT"); format != "" {
-		c.Logger.Format = logger.Format(format)
-	} else {
-		c.Logger.Format = defaultLogFormat
+	switch c.Logger.Level {
+	case logger.LevelDebug, logger.LevelInfo, logger.LevelWarn, logger.LevelError:
+	default:
+		return fmt.Errorf("log level %q must be debug, info, warn or error", c.Logger.Level)
 	}
 
-	if output := os.Getenv("EXPENSETRACE_LOG_OUTPUT"); output != "" {
-		c.Logger.Output = output
-	} else {
-		c.Logger.Output = defaultLogOutput
+	switch c.Logger.Format {
+	case logger.FormatText, logger.FormatJSON:
+	default:
+		return fmt.Errorf("log format %q must be text or json", c.Logger.Format)
 	}
 
-	// Initialize configuration from environment variables
-	if port := os.Getenv("EXPENSETRACE_PORT"); port != "" {
-		c.Port = port
-	} else {
-		c.Port = defaultPort
+	if c.Logger.Output == "" {
+		return errors.New("log output must not be empty")
 	}
 
-	if customTimeout := os.Getenv("EXPENSETRACE_TIMEOUT"); customTimeout != "" {
-		duration, durationErr := time.ParseDuration(customTimeout)
-		if durationErr != nil {
-			fmt.Fprintf(os.Stderr, "Failed to parse custom timeout, using default timeout of 5s")
-			c.Timeout = defaultTimeout
-		} else {
-			c.Timeout = duration
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"timeout", c.Timeout},
+		{"inbox interval", c.InboxInterval},
+		{"import session ttl", c.ImportSessionTTL},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %s", d.name, d.value)
+		}
+	}
+
+	for _, origin := range c.TrustedOrigins {
+		u, parseErr := url.Parse(origin)
+		if parseErr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return fmt.Errorf("trusted origin %q must be a scheme and host like https://example.com", origin)
 		}
-	} else {
-		c.Timeout = defaultTimeout
 	}
+
+	return nil
+}
+
+// Write prints the configuration in the format of the config file.
+func (c *Config) Write(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return encoder.Close()
 }
 
-func Parse() *Config {
-	conf := &Config{}
+func setDuration(d *time.Duration, value string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = duration
+	return nil
+}
 
-	conf.parseEnv()
+func setBool(b *bool, value string) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
 
-	return conf
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
 }
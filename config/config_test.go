@@ -1,12 +1,28 @@
 package config
 
 import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
+func parse(t *testing.T, args ...string) *Config {
+	t.Helper()
+	conf, err := Parse(flag.NewFlagSet("test", flag.ContinueOnError), args)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	return conf
+}
+
 func TestParseDefaults(t *testing.T) {
 	// Test parsing the config file
-	conf := Parse()
+	conf := parse(t)
 	// Verify database path
 	if conf.DBFile != defaultDBFile {
 		t.Errorf("Expected DB path '%s', got '%s'", defaultDBFile, conf.DBFile)
@@ -31,6 +47,26 @@ func TestParseDefaults(t *testing.T) {
 	if conf.Timeout.String() != defaultTimeout.String() {
 		t.Fatalf("Expected timeout '%s', got '%s'", defaultTimeout.String(), conf.Timeout.String())
 	}
+
+	if conf.ProvidersDir != "" {
+		t.Fatalf("Expected no providers dir, got '%s'", conf.ProvidersDir)
+	}
+
+	if conf.InboxDir != "" {
+		t.Fatalf("Expected no inbox dir, got '%s'", conf.InboxDir)
+	}
+
+	if conf.InboxInterval != defaultInboxInterval {
+		t.Fatalf("Expected inbox interval '%s', got '%s'", defaultInboxInterval, conf.InboxInterval)
+	}
+
+	if conf.SecureCookies || conf.AllowEmbedding || len(conf.TrustedOrigins) != 0 {
+		t.Fatalf("Expected no security settings by default, got %+v", conf)
+	}
+
+	if conf.ImportSessionTTL != defaultImportSessionTTL {
+		t.Fatalf("Expected import session ttl '%s', got '%s'", defaultImportSessionTTL, conf.ImportSessionTTL)
+	}
 }
 
 func TestParseENV(t *testing.T) {
@@ -40,9 +76,12 @@ func TestParseENV(t *testing.T) {
 	t.Setenv("EXPENSETRACE_LOG_OUTPUT", "discard")
 	t.Setenv("EXPENSETRACE_PORT", "8765")
 	t.Setenv("EXPENSETRACE_TIMEOUT", "10s")
+	t.Setenv("EXPENSETRACE_PROVIDERS_DIR", "/etc/expensetrace/providers")
+	t.Setenv("EXPENSETRACE_INBOX_DIR", "/srv/expensetrace/inbox")
+	t.Setenv("EXPENSETRACE_INBOX_INTERVAL", "30s")
 
 	// Test parsing the config file
-	conf := Parse()
+	conf := parse(t)
 	// Verify database path
 	if conf.DBFile != "test.db" {
 		t.Errorf("Expected DB path 'test.db', got '%s'", conf.DBFile)
@@ -67,4 +106,182 @@ func TestParseENV(t *testing.T) {
 	if conf.Timeout.String() != "10s" {
 		t.Fatalf("Expected timeout '%s', got '%s'", "10s", conf.Timeout.String())
 	}
+
+	if conf.ProvidersDir != "/etc/expensetrace/providers" {
+		t.Fatalf("Expected providers dir '%s', got '%s'", "/etc/expensetrace/providers", conf.ProvidersDir)
+	}
+
+	if conf.InboxDir != "/srv/expensetrace/inbox" {
+		t.Fatalf("Expected inbox dir '%s', got '%s'", "/srv/expensetrace/inbox", conf.InboxDir)
+	}
+
+	if conf.InboxInterval.String() != "30s" {
+		t.Fatalf("Expected inbox interval '%s', got '%s'", "30s", conf.InboxInterval.String())
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "expensetrace.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestParseFile(t *testing.T) {
+	path := writeConfigFile(t, `
+db: /data/expenses.db
+port: 9000
+timeout: 20s
+log:
+  level: debug
+  format: json
+  output: stderr
+inbox_dir: /srv/inbox
+secure_cookies: true
+trusted_origins:
+  - https://example.com
+  - https://app.example.com
+allow_embedding: true
+import_session_ttl: 1h
+`)
+
+	conf := parse(t, "--config", path)
+
+	if conf.DBFile != "/data/expenses.db" || conf.Port != "9000" || conf.Timeout != 20*time.Second {
+		t.Errorf("Unexpected server settings %q %q %s", conf.DBFile, conf.Port, conf.Timeout)
+	}
+	if conf.Logger.Level != "debug" || conf.Logger.Format != "json" || conf.Logger.Output != "stderr" {
+		t.Errorf("Unexpected logger settings %+v", conf.Logger)
+	}
+	if conf.InboxDir != "/srv/inbox" || conf.InboxInterval != defaultInboxInterval {
+		t.Errorf("Unexpected inbox settings %q %s", conf.InboxDir, conf.InboxInterval)
+	}
+	if !conf.SecureCookies || !conf.AllowEmbedding {
+		t.Errorf("Expected secure cookies and embedding to be enabled")
+	}
+	if !slices.Equal(conf.TrustedOrigins, []string{"https://example.com", "https://app.example.com"}) {
+		t.Errorf("Unexpected trusted origins %v", conf.TrustedOrigins)
+	}
+	if conf.ImportSessionTTL != time.Hour {
+		t.Errorf("Expected import session ttl 1h, got %s", conf.ImportSessionTTL)
+	}
+}
+
+func TestParsePrecedence(t *testing.T) {
+	path := writeConfigFile(t, "db: file.db\nport: 9000\ntimeout: 20s\n")
+	t.Setenv("EXPENSETRACE_CONFIG", path)
+	t.Setenv("EXPENSETRACE_PORT", "9001")
+	t.Setenv("EXPENSETRACE_TIMEOUT", "30s")
+	t.Setenv("EXPENSETRACE_SECURE_COOKIES", "true")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	conf, err := Parse(fs, []string{"--timeout", "40s", "--allow-embedding", "serve"})
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	// flags > env > file > defaults
+	if conf.DBFile != "file.db" {
+		t.Errorf("Expected the db from the file, got %q", conf.DBFile)
+	}
+	if conf.Port != "9001" {
+		t.Errorf("Expected the port from the environment, got %q", conf.Port)
+	}
+	if conf.Timeout != 40*time.Second {
+		t.Errorf("Expected the timeout from the flags, got %s", conf.Timeout)
+	}
+	if !conf.SecureCookies || !conf.AllowEmbedding {
+		t.Errorf("Expected secure cookies from the environment and embedding from the flags")
+	}
+	if conf.Logger.Level != defaultLogLevel {
+		t.Errorf("Expected the default log level, got %q", conf.Logger.Level)
+	}
+	if !slices.Equal(fs.Args(), []string{"serve"}) {
+		t.Errorf("Expected the arguments after the flags to be kept, got %v", fs.Args())
+	}
+}
+
+func TestParseValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		env      map[string]string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "unknown key in the file",
+			file:     "prot: 9000\n",
+			expected: "field prot not found",
+		},
+		{
+			name:     "invalid port",
+			env:      map[string]string{"EXPENSETRACE_PORT": "http"},
+			expected: "port \"http\" must be a number",
+		},
+		{
+			name:     "invalid duration",
+			args:     []string{"--inbox-interval", "often"},
+			expected: "invalid --inbox-interval",
+		},
+		{
+			name:     "negative duration",
+			file:     "import_session_ttl: -1m\n",
+			expected: "import session ttl must be positive",
+		},
+		{
+			name:     "invalid log level",
+			args:     []string{"--log-level", "verbose"},
+			expected: "log level \"verbose\"",
+		},
+		{
+			name:     "invalid trusted origin",
+			env:      map[string]string{"EXPENSETRACE_TRUSTED_ORIGINS": "example.com"},
+			expected: "trusted origin \"example.com\"",
+		},
+		{
+			name:     "invalid boolean",
+			env:      map[string]string{"EXPENSETRACE_ALLOW_EMBEDDING": "sometimes"},
+			expected: "invalid EXPENSETRACE_ALLOW_EMBEDDING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"--config", writeConfigFile(t, tt.file)}, args...)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := Parse(flag.NewFlagSet("test", flag.ContinueOnError), args)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestWriteRoundTrips(t *testing.T) {
+	conf := parse(t, "--port", "9000", "--trusted-origins", "https://example.com", "--secure-cookies")
+
+	var out bytes.Buffer
+	if err := conf.Write(&out); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	for _, expected := range []string{"port: \"9000\"", "timeout: 5s", "level: info", "- https://example.com"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected the printed config to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	reparsed := parse(t, "--config", writeConfigFile(t, out.String()))
+	if reparsed.Port != "9000" || !reparsed.SecureCookies || len(reparsed.TrustedOrigins) != 1 {
+		t.Errorf("Expected the printed config to be read back, got %+v", reparsed)
+	}
 }
@@ -0,0 +1,146 @@
+package domain
+
+import "time"
+
+// AccountType is the kind of an account.
+type AccountType string
+
+const (
+	CheckingAccount AccountType = "checking"
+	SavingsAccount  AccountType = "savings"
+	CreditAccount   AccountType = "credit"
+	CashAccount     AccountType = "cash"
+)
+
+// AccountTypes are the account types offered in the account form.
+var AccountTypes = []AccountType{CheckingAccount, SavingsAccount, CreditAccount, CashAccount}
+
+// Account is where expenses are paid from and income paid into: a bank
+// account, a credit card, a wallet.
+type Account interface {
+	ID() int64
+	Name() string
+	Institution() string
+	Currency() string
+	Type() AccountType
+	// OpeningBalance is the balance in cents before the first expense of
+	// the account.
+	OpeningBalance() int64
+	CreatedAt() time.Time
+}
+
+type account struct {
+	id             int64
+	name           string
+	institution    string
+	currency       string
+	accountType    AccountType
+	openingBalance int64
+	createdAt      time.Time
+}
+
+func (a *account) ID() int64 {
+	return a.id
+}
+
+func (a *account) Name() string {
+	return a.name
+}
+
+func (a *account) Institution() string {
+	return a.institution
+}
+
+func (a *account) Currency() string {
+	return a.currency
+}
+
+func (a *account) Type() AccountType {
+	return a.accountType
+}
+
+func (a *account) OpeningBalance() int64 {
+	return a.openingBalance
+}
+
+func (a *account) CreatedAt() time.Time {
+	return a.createdAt
+}
+
+func NewAccount(
+	id int64,
+	name, institution, currency string,
+	accountType AccountType,
+	openingBalance int64,
+	createdAt time.Time,
+) Account {
+	return &account{
+		id:             id,
+		name:           name,
+		institution:    institution,
+		currency:       currency,
+		accountType:    accountType,
+		openingBalance: openingBalance,
+		createdAt:      createdAt,
+	}
+}
+
+func EmptyAccount() Account {
+	return NewAccount(0, "", "", "", CheckingAccount, 0, time.Time{})
+}
+
+// AccountTotals are the totals of the expenses of an account.
+type AccountTotals struct {
+	// Amount is the sum in cents of the expenses in the account's currency.
+	Amount int64
+	Count  int
+	// Foreign counts the expenses in another currency, left out of Amount.
+	Foreign int
+}
+
+// AccountSummary is an account with its current balance.
+type AccountSummary struct {
+	Account
+	Balance      int64
+	ExpenseCount int
+	// Unconverted counts the expenses in another currency left out of the
+	// balance for lack of an exchange rate to the account's currency.
+	Unconverted int
+}
+
+// AccountEntry is an expense of an account with the balance of the account
+// right after it. Transfer marks one side of a transfer between accounts,
+// Unconverted an expense in another currency left out of the balance.
+type AccountEntry struct {
+	Expense     Expense
+	Balance     int64
+	Transfer    bool
+	Unconverted bool
+}
+
+// AccountFormData holds parsed and validated account form data.
+type AccountFormData struct {
+	Name           string
+	Institution    string
+	Currency       string
+	Type           AccountType
+	OpeningBalance int64
+}
+
+type AccountsViewData struct {
+	ViewBase
+	Accounts   []AccountSummary
+	Types      []AccountType
+	FormErrors map[string]string
+	Form       AccountFormData
+}
+
+type AccountViewData struct {
+	ViewBase
+	Account AccountSummary
+	// History is newest first.
+	History    []AccountEntry
+	Types      []AccountType
+	FormErrors map[string]string
+	Form       AccountFormData
+}
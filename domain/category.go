@@ -18,6 +18,12 @@ type EnhancedCategory struct {
 	TotalAmount     int64
 	SpendingCount   int
 	IncomeCount     int
+	// Currency is the base currency of the amounts, empty when amounts are
+	// added as they are.
+	Currency string
+	// Unconverted counts the expenses left out of the amounts for lack of
+	// an exchange rate to Currency.
+	Unconverted int
 }
 
 // CategoryFormData holds parsed and validated category form data.
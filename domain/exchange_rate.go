@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// ExchangeRate is the price of one unit of the Base currency in the Quote
+// currency on Date: 1 EUR = Rate USD.
+type ExchangeRate interface {
+	ID() int64
+	Date() time.Time
+	Base() string
+	Quote() string
+	Rate() float64
+}
+
+type exchangeRate struct {
+	id    int64
+	date  time.Time
+	base  string
+	quote string
+	rate  float64
+}
+
+func (e *exchangeRate) ID() int64 {
+	return e.id
+}
+
+func (e *exchangeRate) Date() time.Time {
+	return e.date
+}
+
+func (e *exchangeRate) Base() string {
+	return e.base
+}
+
+func (e *exchangeRate) Quote() string {
+	return e.quote
+}
+
+func (e *exchangeRate) Rate() float64 {
+	return e.rate
+}
+
+func NewExchangeRate(id int64, date time.Time, base, quote string, rate float64) ExchangeRate {
+	return &exchangeRate{
+		id:    id,
+		date:  date,
+		base:  base,
+		quote: quote,
+		rate:  rate,
+	}
+}
+
+type ExchangeRatesViewData struct {
+	ViewBase
+	// Rates are the most recent rates, newest first.
+	Rates []ExchangeRate
+}
@@ -21,15 +21,22 @@ type Expense interface {
 	Type() ExpenseType
 	Currency() string
 	CategoryID() *int64
+	AccountID() *int64
+	// ExternalID is the identifier the bank gave the transaction, like an
+	// OFX FITID, or empty when the source has none.
+	ExternalID() string
 }
 
 type ExpenseView struct {
 	Expense
-	Cat Category
+	Cat  Category
+	Acct Account
 }
 
 func (e *ExpenseView) Category() Category { return e.Cat }
 
+func (e *ExpenseView) Account() Account { return e.Acct }
+
 func (e *ExpenseView) CategoryID() int64 {
 	if e.Expense.CategoryID() != nil {
 		return *e.Expense.CategoryID()
@@ -37,6 +44,13 @@ func (e *ExpenseView) CategoryID() int64 {
 	return 0
 }
 
+func (e *ExpenseView) AccountID() int64 {
+	if e.Expense.AccountID() != nil {
+		return *e.Expense.AccountID()
+	}
+	return 0
+}
+
 type ExpensesByYear map[int]map[string][]*ExpenseView
 
 type ExpensesViewData struct {
@@ -48,12 +62,14 @@ type ExpensesViewData struct {
 	CurrentMonth string
 	Filter       *ExpenseFilter
 	Sort         *SortOptions
+	Accounts     []Account
 }
 
 type ExpenseViewData struct {
 	ViewBase
 	Expense    *ExpenseView
 	Categories []Category
+	Accounts   []Account
 	FormErrors map[string]string
 	Action     string
 	RedirectTo string
@@ -68,6 +84,8 @@ type expense struct {
 	expenseType ExpenseType
 	currency    string
 	categoryID  *int64
+	accountID   *int64
+	externalID  string
 }
 
 func NewExpense(
@@ -77,6 +95,7 @@ func NewExpense(
 	date time.Time,
 	expenseType ExpenseType,
 	categoryID *int64,
+	accountID *int64,
 ) Expense {
 	return &expense{
 		id:          id,
@@ -87,6 +106,24 @@ func NewExpense(
 		expenseType: expenseType,
 		currency:    currency,
 		categoryID:  categoryID,
+		accountID:   accountID,
+	}
+}
+
+// WithExternalID returns a copy of the expense carrying the identifier the
+// bank gave the transaction.
+func WithExternalID(e Expense, externalID string) Expense {
+	return &expense{
+		id:          e.ID(),
+		source:      e.Source(),
+		description: e.Description(),
+		amount:      e.Amount(),
+		date:        e.Date(),
+		expenseType: e.Type(),
+		currency:    e.Currency(),
+		categoryID:  e.CategoryID(),
+		accountID:   e.AccountID(),
+		externalID:  externalID,
 	}
 }
 
@@ -122,6 +159,14 @@ func (e *expense) CategoryID() *int64 {
 	return e.categoryID
 }
 
+func (e *expense) AccountID() *int64 {
+	return e.accountID
+}
+
+func (e *expense) ExternalID() string {
+	return e.externalID
+}
+
 func (e *expense) MarshalJSON() ([]byte, error) {
 	return json.Marshal(map[string]any{
 		"id":           e.id,
@@ -132,5 +177,6 @@ func (e *expense) MarshalJSON() ([]byte, error) {
 		"expense_type": e.expenseType,
 		"currency":     e.currency,
 		"category_id":  e.categoryID,
+		"account_id":   e.accountID,
 	})
 }
@@ -18,6 +18,7 @@ type ExpenseFilter struct {
 	AmountMax   *int64     // Maximum amount in cents (inclusive)
 	DateFrom    *time.Time // Start date (inclusive)
 	DateTo      *time.Time // End date (inclusive)
+	AccountID   *int64     // Exact match on the account
 }
 
 // SortField represents a field that can be sorted on.
@@ -69,6 +70,14 @@ func ParseExpenseFilters(params url.Values) (*ExpenseFilter, *SortOptions, error
 		filter.Source = &src
 	}
 
+	if accountStr := params.Get("account"); accountStr != "" {
+		val, err := strconv.ParseInt(accountStr, 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid account: %w", err)
+		}
+		filter.AccountID = &val
+	}
+
 	// Parse amount range
 	if minStr := params.Get("amount_min"); minStr != "" {
 		val, err := parseAmount(minStr)
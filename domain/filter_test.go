@@ -291,6 +291,15 @@ func TestParseExpenseFilters(t *testing.T) {
 			wantSort: DefaultSortOptions(),
 			wantErr:  false,
 		},
+		{
+			name:        "account filter",
+			queryString: "account=3",
+			wantFilter: &ExpenseFilter{
+				AccountID: int64Ptr(3),
+			},
+			wantSort: DefaultSortOptions(),
+			wantErr:  false,
+		},
 		{
 			name:        "custom sort",
 			queryString: "sort=amount:asc",
@@ -328,6 +337,11 @@ func TestParseExpenseFilters(t *testing.T) {
 			queryString: "date_from=not-a-date",
 			wantErr:     true,
 		},
+		{
+			name:        "invalid account",
+			queryString: "account=visa",
+			wantErr:     true,
+		},
 		{
 			name:        "invalid sort",
 			queryString: "sort=invalid:format",
@@ -371,6 +385,9 @@ func TestParseExpenseFilters(t *testing.T) {
 			if !equalTimePtr(filter.DateTo, tt.wantFilter.DateTo) {
 				t.Errorf("DateTo: expected %v, got %v", tt.wantFilter.DateTo, filter.DateTo)
 			}
+			if !equalInt64Ptr(filter.AccountID, tt.wantFilter.AccountID) {
+				t.Errorf("AccountID: expected %v, got %v", tt.wantFilter.AccountID, filter.AccountID)
+			}
 
 			// Compare sort
 			if sort.Field != tt.wantSort.Field {
@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 // PreviewData is the view model rendered by the import preview partial.
 type PreviewData struct {
 	ViewBase
@@ -8,6 +10,54 @@ type PreviewData struct {
 	Headers         []string
 	PreviewRows     [][]string
 	TotalRows       int
+	// Sheets lists the sheets of a spreadsheet, Sheet being the one shown.
+	Sheets []string
+	Sheet  string
+	// HeaderRow is the number of rows skipped before the header row.
+	HeaderRow int
+	// Delimiter and Encoding are the CSV dialect, detected or chosen.
+	Delimiter  string
+	Encoding   string
+	Delimiters []DelimiterOption
+	Encodings  []string
+	// RecordsPath selects the records of a JSON file.
+	RecordsPath string
+	// MatchedProfile is the saved mapping whose header fingerprint matches
+	// the uploaded file, if any.
+	MatchedProfile MappingProfile
+	DateLayouts    []DateLayoutOption
+	// Accounts are offered as the account to import into, AccountID being
+	// the one picked when uploading the file.
+	Accounts  []Account
+	AccountID int64
+}
+
+// DelimiterOption is a CSV field separator users can pick when previewing
+// a file.
+type DelimiterOption struct {
+	Value string
+	Label string // e.g. Semicolon (;)
+}
+
+// DateLayoutOption is a date format users can pick when mapping a file.
+type DateLayoutOption struct {
+	Layout string // Go time layout
+	Label  string // e.g. DD/MM/YYYY
+}
+
+// FormatAmbiguity lists the rows of a column that read differently depending
+// on the format used, so users can check the format applied to their file.
+type FormatAmbiguity struct {
+	Field      string   // date or amount
+	Candidates []string // labels of the formats that can read the column
+	Rows       []AmbiguousRow
+}
+
+// AmbiguousRow is a value and how it reads under each candidate format.
+type AmbiguousRow struct {
+	Row     int
+	Value   string
+	Results []string // one per FormatAmbiguity.Candidates entry
 }
 
 // MappingData is the view model rendered by the import mapping-preview partial.
@@ -18,4 +68,305 @@ type MappingData struct {
 	PreviewExpenses []Expense
 	TotalRows       int
 	Errors          []string
+	// SavedProfileName is set when the mapping was just saved for reuse.
+	SavedProfileName string
+	// DateLayout is the label of the date format applied, detected when the
+	// user did not choose one.
+	DateLayout  string
+	Warnings    []string
+	Ambiguities []FormatAmbiguity
+	NewRows     int
+	// Duplicates are skipped unless users choose to include them.
+	Duplicates []DuplicateRow
+}
+
+// MappingProfile is a field mapping saved by a user under a name so recurring
+// files from the same bank can be imported without mapping them again. The
+// mapping itself is stored encoded, as its shape is owned by the import
+// package.
+type MappingProfile interface {
+	ID() int64
+	Name() string
+	// HeaderFingerprint identifies the header row the mapping was created for.
+	HeaderFingerprint() string
+	Mapping() []byte
+	UpdatedAt() time.Time
+}
+
+type mappingProfile struct {
+	id                int64
+	name              string
+	headerFingerprint string
+	mapping           []byte
+	updatedAt         time.Time
+}
+
+func (p *mappingProfile) ID() int64 {
+	return p.id
+}
+
+func (p *mappingProfile) Name() string {
+	return p.name
+}
+
+func (p *mappingProfile) HeaderFingerprint() string {
+	return p.headerFingerprint
+}
+
+func (p *mappingProfile) Mapping() []byte {
+	return p.mapping
+}
+
+func (p *mappingProfile) UpdatedAt() time.Time {
+	return p.updatedAt
+}
+
+func NewMappingProfile(id int64, name, headerFingerprint string, mapping []byte, updatedAt time.Time) MappingProfile {
+	return &mappingProfile{
+		id:                id,
+		name:              name,
+		headerFingerprint: headerFingerprint,
+		mapping:           mapping,
+		updatedAt:         updatedAt,
+	}
+}
+
+// ImportViewData is the view model rendered by the import page.
+type ImportViewData struct {
+	ViewBase
+	MappingProfiles []MappingProfile
+	// Accounts are offered as the account to import into.
+	Accounts []Account
+}
+
+// ImportBatch records a file import, so its expenses can be told apart and
+// rolled back together.
+type ImportBatch interface {
+	ID() int64
+	Filename() string
+	// Format names the provider or file format used to read the file.
+	Format() string
+	// Mapping is the JSON encoded field mapping of interactive imports.
+	Mapping() []byte
+	// RowCount is the number of rows read from the file.
+	RowCount() int
+	Imported() int
+	// SkippedDuplicates counts the rows matching an existing expense.
+	SkippedDuplicates() int
+	ImportedAt() time.Time
+	// RolledBackAt is nil unless the batch expenses were deleted.
+	RolledBackAt() *time.Time
+}
+
+type importBatch struct {
+	id                int64
+	filename          string
+	format            string
+	mapping           []byte
+	rowCount          int
+	imported          int
+	skippedDuplicates int
+	importedAt        time.Time
+	rolledBackAt      *time.Time
+}
+
+func (b *importBatch) ID() int64 {
+	return b.id
+}
+
+func (b *importBatch) Filename() string {
+	return b.filename
+}
+
+func (b *importBatch) Format() string {
+	return b.format
+}
+
+func (b *importBatch) Mapping() []byte {
+	return b.mapping
+}
+
+func (b *importBatch) RowCount() int {
+	return b.rowCount
+}
+
+func (b *importBatch) Imported() int {
+	return b.imported
+}
+
+func (b *importBatch) SkippedDuplicates() int {
+	return b.skippedDuplicates
+}
+
+func (b *importBatch) ImportedAt() time.Time {
+	return b.importedAt
+}
+
+func (b *importBatch) RolledBackAt() *time.Time {
+	return b.rolledBackAt
+}
+
+func NewImportBatch(
+	id int64,
+	filename, format string,
+	mapping []byte,
+	rowCount, imported, skippedDuplicates int,
+	importedAt time.Time,
+	rolledBackAt *time.Time,
+) ImportBatch {
+	return &importBatch{
+		id:                id,
+		filename:          filename,
+		format:            format,
+		mapping:           mapping,
+		rowCount:          rowCount,
+		imported:          imported,
+		skippedDuplicates: skippedDuplicates,
+		importedAt:        importedAt,
+		rolledBackAt:      rolledBackAt,
+	}
+}
+
+// ImportHistoryViewData is the view model rendered by the import history page.
+type ImportHistoryViewData struct {
+	ViewBase
+	Batches []ImportBatch
+}
+
+// DuplicateStatus classifies an imported row against the stored expenses.
+type DuplicateStatus string
+
+const (
+	// DuplicateNone is a new expense.
+	DuplicateNone DuplicateStatus = "new"
+	// DuplicateExact matches a stored expense source, date, description and
+	// amount.
+	DuplicateExact DuplicateStatus = "exact"
+	// DuplicateLikely has the amount of a stored expense, a date at most a
+	// couple of days apart and a similar description.
+	DuplicateLikely DuplicateStatus = "likely"
+)
+
+// DuplicateRow is an imported row flagged as a duplicate, shown so users can
+// decide to import it anyway.
+type DuplicateRow struct {
+	Row     int
+	Status  DuplicateStatus
+	Expense Expense
+	// Existing is the stored expense the row matches.
+	Existing Expense
+}
+
+// ImportSession is an interactive import in progress, kept between the
+// upload and the import of the file. Data and Mapping are JSON encoded, as
+// their shape is owned by the import package, and the uploaded file is
+// stored alongside the session.
+type ImportSession interface {
+	ID() string
+	UserID() int64
+	Filename() string
+	// Data is the header and a sample of the rows of the file.
+	Data() []byte
+	// Mapping is empty until a field mapping is applied.
+	Mapping() []byte
+	CreatedAt() time.Time
+	ExpiresAt() time.Time
+}
+
+type importSession struct {
+	id        string
+	userID    int64
+	filename  string
+	data      []byte
+	mapping   []byte
+	createdAt time.Time
+	expiresAt time.Time
+}
+
+func (s *importSession) ID() string {
+	return s.id
+}
+
+func (s *importSession) UserID() int64 {
+	return s.userID
+}
+
+func (s *importSession) Filename() string {
+	return s.filename
+}
+
+func (s *importSession) Data() []byte {
+	return s.data
+}
+
+func (s *importSession) Mapping() []byte {
+	return s.mapping
+}
+
+func (s *importSession) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+func (s *importSession) ExpiresAt() time.Time {
+	return s.expiresAt
+}
+
+func NewImportSession(
+	id string,
+	userID int64,
+	filename string,
+	data, mapping []byte,
+	createdAt, expiresAt time.Time,
+) ImportSession {
+	return &importSession{
+		id:        id,
+		userID:    userID,
+		filename:  filename,
+		data:      data,
+		mapping:   mapping,
+		createdAt: createdAt,
+		expiresAt: expiresAt,
+	}
+}
+
+// InboxFailure is a file from a user's import inbox that could not be
+// imported, kept to tell the user until they dismiss it.
+type InboxFailure interface {
+	ID() int64
+	Filename() string
+	// Message is the reason the import failed.
+	Message() string
+	FailedAt() time.Time
+}
+
+type inboxFailure struct {
+	id       int64
+	filename string
+	message  string
+	failedAt time.Time
+}
+
+func (f *inboxFailure) ID() int64 {
+	return f.id
+}
+
+func (f *inboxFailure) Filename() string {
+	return f.filename
+}
+
+func (f *inboxFailure) Message() string {
+	return f.message
+}
+
+func (f *inboxFailure) FailedAt() time.Time {
+	return f.failedAt
+}
+
+func NewInboxFailure(id int64, filename, message string, failedAt time.Time) InboxFailure {
+	return &inboxFailure{
+		id:       id,
+		filename: filename,
+		message:  message,
+		failedAt: failedAt,
+	}
 }
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// Migration is a schema migration and when it was applied. AppliedAt is the
+// zero time for migrations still pending.
+type Migration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// Applied reports whether the migration has been applied.
+func (m Migration) Applied() bool {
+	return !m.AppliedAt.IsZero()
+}
@@ -48,6 +48,12 @@ type Report struct {
 	AverageSpendingPerDay int64            `json:"average_spending_per_day"`
 	ExpenseCategories     []CategoryReport `json:"expense_categories"`
 	IncomeCategories      []CategoryReport `json:"income_categories"`
+	// Currency is the user's base currency the amounts are converted to,
+	// empty when amounts are added as they are.
+	Currency string `json:"currency,omitempty"`
+	// Unconverted counts the expenses left out of the amounts for lack of
+	// an exchange rate to Currency.
+	Unconverted int `json:"unconverted"`
 }
 
 // ChartDataPoint represents a single point in the spending/income chart.
@@ -65,6 +71,10 @@ type HomeViewData struct {
 	ViewBase
 	ChartData  []ChartDataPoint
 	ReportCard ReportCardData
+	// Accounts are offered to limit the reports to one account, AccountID
+	// being the selected one, 0 for all of them.
+	Accounts  []Account
+	AccountID int64
 }
 
 // ReportCardData is the view data for a single report card, optionally
@@ -74,4 +84,5 @@ type ReportCardData struct {
 	OpenCategory string
 	OpenMonth    int
 	OpenYear     int
+	AccountID    int64
 }
@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+// Transfer links the two sides of money moved between the user's own
+// accounts: the charge leaving one account and the income arriving in the
+// other. Linked expenses count neither as spending nor as income.
+type Transfer interface {
+	ID() int64
+	ChargeID() int64
+	IncomeID() int64
+	CreatedAt() time.Time
+}
+
+type transfer struct {
+	id        int64
+	chargeID  int64
+	incomeID  int64
+	createdAt time.Time
+}
+
+func (t *transfer) ID() int64 {
+	return t.id
+}
+
+func (t *transfer) ChargeID() int64 {
+	return t.chargeID
+}
+
+func (t *transfer) IncomeID() int64 {
+	return t.incomeID
+}
+
+func (t *transfer) CreatedAt() time.Time {
+	return t.createdAt
+}
+
+func NewTransfer(id, chargeID, incomeID int64, createdAt time.Time) Transfer {
+	return &transfer{
+		id:        id,
+		chargeID:  chargeID,
+		incomeID:  incomeID,
+		createdAt: createdAt,
+	}
+}
+
+// TransferPair is a transfer with both of its expenses. ID is 0 for a
+// detected pair that is not linked yet.
+type TransferPair struct {
+	ID     int64
+	Charge Expense
+	Income Expense
+}
+
+type TransfersViewData struct {
+	ViewBase
+	Transfers []TransferPair
+	// Candidates are the detected pairs waiting to be linked.
+	Candidates []TransferPair
+	WindowDays int
+}
@@ -13,14 +13,22 @@ type User interface {
 	ID() int64
 	Username() string
 	PasswordHash() string
+	// DefaultCurrency is the currency suggested for imported files without
+	// a currency column. Empty when the user has not set one.
+	DefaultCurrency() string
+	// BaseCurrency is the currency reports are converted to. Empty when the
+	// user has not set one, amounts are then added as they are.
+	BaseCurrency() string
 	CreatedAt() time.Time
 }
 
 type user struct {
-	id           int64
-	username     string
-	passwordHash string
-	createdAt    time.Time
+	id              int64
+	username        string
+	passwordHash    string
+	defaultCurrency string
+	baseCurrency    string
+	createdAt       time.Time
 }
 
 func (u *user) ID() int64 {
@@ -35,16 +43,26 @@ func (u *user) PasswordHash() string {
 	return u.passwordHash
 }
 
+func (u *user) DefaultCurrency() string {
+	return u.defaultCurrency
+}
+
+func (u *user) BaseCurrency() string {
+	return u.baseCurrency
+}
+
 func (u *user) CreatedAt() time.Time {
 	return u.createdAt
 }
 
-func NewUser(id int64, username, passwordHash string, createdAt time.Time) User {
+func NewUser(id int64, username, passwordHash, defaultCurrency, baseCurrency string, createdAt time.Time) User {
 	return &user{
-		id:           id,
-		username:     username,
-		passwordHash: passwordHash,
-		createdAt:    createdAt,
+		id:              id,
+		username:        username,
+		passwordHash:    passwordHash,
+		defaultCurrency: defaultCurrency,
+		baseCurrency:    baseCurrency,
+		createdAt:       createdAt,
 	}
 }
 
@@ -79,3 +97,73 @@ func NewSession(id string, userID int64, expiresAt, createdAt time.Time) Session
 		createdAt: createdAt,
 	}
 }
+
+type ProfileViewData struct {
+	ViewBase
+	APITokens []APIToken
+	// NewAPIToken is the token just created, shown once.
+	NewAPIToken string
+	Webhooks    []Webhook
+	// WebhookDeliveries are the most recent deliveries, newest first.
+	WebhookDeliveries []WebhookDelivery
+	// WebhookEvents are the events offered when adding a webhook.
+	WebhookEvents []string
+	// NewWebhookSecret is the signing secret of the webhook just created.
+	NewWebhookSecret string
+}
+
+// APIToken is a personal access token used to call the API. Only a hash of
+// the token is stored, the prefix identifies it to its owner.
+type APIToken interface {
+	ID() int64
+	UserID() int64
+	Name() string
+	Prefix() string
+	CreatedAt() time.Time
+	// LastUsedAt is the zero time when the token was never used.
+	LastUsedAt() time.Time
+}
+
+type apiToken struct {
+	id         int64
+	userID     int64
+	name       string
+	prefix     string
+	createdAt  time.Time
+	lastUsedAt time.Time
+}
+
+func (t *apiToken) ID() int64 {
+	return t.id
+}
+
+func (t *apiToken) UserID() int64 {
+	return t.userID
+}
+
+func (t *apiToken) Name() string {
+	return t.name
+}
+
+func (t *apiToken) Prefix() string {
+	return t.prefix
+}
+
+func (t *apiToken) CreatedAt() time.Time {
+	return t.createdAt
+}
+
+func (t *apiToken) LastUsedAt() time.Time {
+	return t.lastUsedAt
+}
+
+func NewAPIToken(id, userID int64, name, prefix string, createdAt, lastUsedAt time.Time) APIToken {
+	return &apiToken{
+		id:         id,
+		userID:     userID,
+		name:       name,
+		prefix:     prefix,
+		createdAt:  createdAt,
+		lastUsedAt: lastUsedAt,
+	}
+}
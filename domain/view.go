@@ -12,4 +12,9 @@ type ViewBase struct {
 	LoggedIn         bool
 	Username         string
 	UsernameInitials string
+	DefaultCurrency  string
+	BaseCurrency     string
+	// InboxFailures are the files from the user's import inbox that could
+	// not be imported, shown on every page until dismissed.
+	InboxFailures []InboxFailure
 }
@@ -0,0 +1,195 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Events sent to webhooks.
+const (
+	EventExpenseCreated  = "expense.created"
+	EventExpenseUpdated  = "expense.updated"
+	EventExpenseDeleted  = "expense.deleted"
+	EventImportCompleted = "import.completed"
+	EventCategoryUpdated = "category.updated"
+	EventBudgetExceeded  = "budget.exceeded"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{
+	EventExpenseCreated,
+	EventExpenseUpdated,
+	EventExpenseDeleted,
+	EventImportCompleted,
+	EventCategoryUpdated,
+	EventBudgetExceeded,
+}
+
+// Webhook is a URL the user's events are posted to. The secret signs every
+// delivery, so the receiver can tell it comes from the app.
+type Webhook interface {
+	ID() int64
+	UserID() int64
+	URL() string
+	Secret() string
+	Events() []string
+	CreatedAt() time.Time
+	// Subscribed reports whether the webhook receives the event.
+	Subscribed(event string) bool
+}
+
+type webhook struct {
+	id        int64
+	userID    int64
+	url       string
+	secret    string
+	events    []string
+	createdAt time.Time
+}
+
+func (w *webhook) ID() int64 {
+	return w.id
+}
+
+func (w *webhook) UserID() int64 {
+	return w.userID
+}
+
+func (w *webhook) URL() string {
+	return w.url
+}
+
+func (w *webhook) Secret() string {
+	return w.secret
+}
+
+func (w *webhook) Events() []string {
+	return w.events
+}
+
+func (w *webhook) CreatedAt() time.Time {
+	return w.createdAt
+}
+
+func (w *webhook) Subscribed(event string) bool {
+	return slices.Contains(w.events, event)
+}
+
+func NewWebhook(id, userID int64, url, secret string, events []string, createdAt time.Time) Webhook {
+	return &webhook{
+		id:        id,
+		userID:    userID,
+		url:       url,
+		secret:    secret,
+		events:    events,
+		createdAt: createdAt,
+	}
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending deliveries are waiting for their next attempt.
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliveryDelivered deliveries were accepted by the receiver.
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryFailed deliveries were given up after too many attempts.
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookAttempt is the outcome of posting a delivery to its webhook.
+type WebhookAttempt struct {
+	At time.Time
+	// StatusCode is the status the receiver answered with, 0 when it could
+	// not be reached.
+	StatusCode int
+	// Error is empty for successful attempts.
+	Error string
+}
+
+// WebhookDelivery is an event waiting to be posted to a webhook, or already
+// posted. Deliveries are the outbox of the events and their log.
+type WebhookDelivery interface {
+	ID() int64
+	Webhook() Webhook
+	Event() string
+	// Payload is the JSON body posted to the webhook.
+	Payload() []byte
+	Status() WebhookDeliveryStatus
+	Attempts() int
+	// LastAttempt has the zero time when the delivery was never attempted.
+	LastAttempt() WebhookAttempt
+	NextAttemptAt() time.Time
+	CreatedAt() time.Time
+}
+
+type webhookDelivery struct {
+	id            int64
+	webhook       Webhook
+	event         string
+	payload       []byte
+	status        WebhookDeliveryStatus
+	attempts      int
+	lastAttempt   WebhookAttempt
+	nextAttemptAt time.Time
+	createdAt     time.Time
+}
+
+func (d *webhookDelivery) ID() int64 {
+	return d.id
+}
+
+func (d *webhookDelivery) Webhook() Webhook {
+	return d.webhook
+}
+
+func (d *webhookDelivery) Event() string {
+	return d.event
+}
+
+func (d *webhookDelivery) Payload() []byte {
+	return d.payload
+}
+
+func (d *webhookDelivery) Status() WebhookDeliveryStatus {
+	return d.status
+}
+
+func (d *webhookDelivery) Attempts() int {
+	return d.attempts
+}
+
+func (d *webhookDelivery) LastAttempt() WebhookAttempt {
+	return d.lastAttempt
+}
+
+func (d *webhookDelivery) NextAttemptAt() time.Time {
+	return d.nextAttemptAt
+}
+
+func (d *webhookDelivery) CreatedAt() time.Time {
+	return d.createdAt
+}
+
+func NewWebhookDelivery(
+	id int64,
+	webhook Webhook,
+	event string,
+	payload []byte,
+	status WebhookDeliveryStatus,
+	attempts int,
+	lastAttempt WebhookAttempt,
+	nextAttemptAt, createdAt time.Time,
+) WebhookDelivery {
+	return &webhookDelivery{
+		id:            id,
+		webhook:       webhook,
+		event:         event,
+		payload:       payload,
+		status:        status,
+		attempts:      attempts,
+		lastAttempt:   lastAttempt,
+		nextAttemptAt: nextAttemptAt,
+		createdAt:     createdAt,
+	}
+}
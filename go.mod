@@ -6,6 +6,7 @@ require (
 	github.com/fatih/color v1.17.0
 	github.com/mattn/go-sqlite3 v1.14.22
 	golang.org/x/crypto v0.52.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,286 @@
+package importutil
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	storageType "github.com/GustavoCaso/expensetrace/storage"
+)
+
+// defaultCamtSource is used when the account servicer is not named in the
+// statement.
+const defaultCamtSource = "CAMT"
+
+const (
+	camtCredit     = "CRDT"
+	camtDebit      = "DBIT"
+	camtPending    = "PDNG"
+	camtDateLayout = "2006-01-02"
+	camtDateLen    = len(camtDateLayout)
+)
+
+// camtDocument covers both camt.053 (BkToCstmrStmt/Stmt) and camt.052
+// (BkToCstmrAcctRpt/Rpt). Tags are matched without namespace so every
+// published version of the schemas is accepted.
+type camtDocument struct {
+	XMLName    xml.Name        `xml:"Document"`
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+	Reports    []camtStatement `xml:"BkToCstmrAcctRpt>Rpt"`
+}
+
+type camtStatement struct {
+	Account camtAccount `xml:"Acct"`
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtAccount struct {
+	Currency string `xml:"Ccy"`
+	Name     string `xml:"Svcr>FinInstnId>Nm"`
+	BICFI    string `xml:"Svcr>FinInstnId>BICFI"`
+	BIC      string `xml:"Svcr>FinInstnId>BIC"`
+}
+
+type camtEntry struct {
+	Amount              camtAmount      `xml:"Amt"`
+	CreditDebit         string          `xml:"CdtDbtInd"`
+	Status              camtStatus      `xml:"Sts"`
+	BookingDate         camtDate        `xml:"BookgDt"`
+	ValueDate           camtDate        `xml:"ValDt"`
+	AdditionalEntryInfo string          `xml:"AddtlNtryInf"`
+	Transactions        []camtTxDetails `xml:"NtryDtls>TxDtls"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtStatus holds the entry status, which is plain text up to camt.05x.001.06
+// and a <Cd> element from version 08 onwards.
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtTxDetails struct {
+	Remittance       camtRemittance   `xml:"RmtInf"`
+	RelatedParties   camtRelatedParty `xml:"RltdPties"`
+	AdditionalTxInfo string           `xml:"AddtlTxInf"`
+}
+
+type camtRemittance struct {
+	Unstructured []string `xml:"Ustrd"`
+	References   []string `xml:"Strd>CdtrRefInf>Ref"`
+}
+
+type camtRelatedParty struct {
+	Creditor       string `xml:"Cdtr>Nm"`
+	CreditorParty  string `xml:"Cdtr>Pty>Nm"`
+	Debtor         string `xml:"Dbtr>Nm"`
+	DebtorParty    string `xml:"Dbtr>Pty>Nm"`
+	UltimateCredit string `xml:"UltmtCdtr>Nm"`
+}
+
+// ParseCamt parses an ISO 20022 camt.053 statement or camt.052 account
+// report and returns one expense per booked <Ntry>. Pending entries are
+// skipped because they may still change or disappear.
+func ParseCamt(reader io.Reader, categoryMatcher *matcher.Matcher) ([]domain.Expense, error) {
+	var doc camtDocument
+
+	if err := xml.NewDecoder(reader).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("error parsing camt XML: %w", err)
+	}
+
+	statements := slices.Concat(doc.Statements, doc.Reports)
+	if len(statements) == 0 {
+		return nil, errors.New("XML file is not a camt.053 or camt.052 document")
+	}
+
+	expenses := []domain.Expense{}
+	for _, stmt := range statements {
+		source := stmt.Account.source()
+
+		for i, entry := range stmt.Entries {
+			if entry.status() == camtPending {
+				continue
+			}
+
+			expense, err := entry.toExpense(source, stmt.Account.Currency, categoryMatcher)
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: %w", i+1, err)
+			}
+
+			expenses = append(expenses, expense)
+		}
+	}
+
+	return expenses, nil
+}
+
+// ImportCamt parses a camt.053/camt.052 file and stores its entries.
+func ImportCamt(
+	ctx context.Context,
+	userID int64,
+	accountID *int64,
+	filename string,
+	reader io.Reader,
+	storage storageType.Storage,
+	categoryMatcher *matcher.Matcher,
+) ImportInfo {
+	expenses, err := ParseCamt(reader, categoryMatcher)
+	if err != nil {
+		return ImportInfo{Error: err}
+	}
+
+	batch := newImportBatch(filename, FormatCamt, len(expenses))
+	return storeExpenses(ctx, userID, accountID, batch, expenses, storage)
+}
+
+func (a camtAccount) source() string {
+	for _, candidate := range []string{a.Name, a.BICFI, a.BIC} {
+		if candidate = strings.TrimSpace(candidate); candidate != "" {
+			return candidate
+		}
+	}
+	return defaultCamtSource
+}
+
+func (e camtEntry) status() string {
+	if code := strings.TrimSpace(e.Status.Code); code != "" {
+		return code
+	}
+	return strings.TrimSpace(e.Status.Value)
+}
+
+func (e camtEntry) toExpense(
+	source, accountCurrency string,
+	categoryMatcher *matcher.Matcher,
+) (domain.Expense, error) {
+	date, err := e.date()
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := parseDecimalAmount(e.Amount.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount %q: %w", e.Amount.Value, err)
+	}
+	if amount < 0 {
+		amount = -amount
+	}
+
+	var et domain.ExpenseType
+	// CdtDbtInd is the direction of the entry, reversals (RvslInd) included:
+	// the reversal of a debit is booked as a credit
+	switch strings.TrimSpace(e.CreditDebit) {
+	case camtDebit:
+		et = domain.ChargeType
+	case camtCredit:
+		et = domain.IncomeType
+	default:
+		return nil, fmt.Errorf("invalid credit/debit indicator %q", e.CreditDebit)
+	}
+
+	if et == domain.ChargeType {
+		amount = -amount
+	}
+
+	currency := e.Amount.Currency
+	if currency == "" {
+		currency = accountCurrency
+	}
+	if currency == "" {
+		return nil, errors.New("no currency found")
+	}
+
+	description := strings.ToLower(e.description(et))
+	categoryID, _ := categoryMatcher.Match(description)
+
+	return domain.NewExpense(
+		0,
+		source,
+		description,
+		currency,
+		amount,
+		date,
+		et,
+		categoryID,
+		nil,
+	), nil
+}
+
+func (e camtEntry) date() (time.Time, error) {
+	for _, d := range []camtDate{e.BookingDate, e.ValueDate} {
+		value := strings.TrimSpace(d.Date)
+		if value == "" {
+			value = strings.TrimSpace(d.DateTime)
+		}
+		if len(value) < camtDateLen {
+			continue
+		}
+
+		t, err := time.Parse(camtDateLayout, value[:camtDateLen])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q: %w", value, err)
+		}
+		return t, nil
+	}
+
+	return time.Time{}, errors.New("entry has no booking or value date")
+}
+
+// description builds the expense description from the counterparty name and
+// the remittance information, falling back to the additional entry
+// information when the bank provides nothing else.
+func (e camtEntry) description(et domain.ExpenseType) string {
+	parts := []string{}
+	add := func(values ...string) {
+		for _, v := range values {
+			v = strings.Join(strings.Fields(v), " ")
+			if v != "" && !containsFold(parts, v) {
+				parts = append(parts, v)
+			}
+		}
+	}
+
+	for _, tx := range e.Transactions {
+		if et == domain.ChargeType {
+			add(tx.RelatedParties.Creditor, tx.RelatedParties.CreditorParty, tx.RelatedParties.UltimateCredit)
+		} else {
+			add(tx.RelatedParties.Debtor, tx.RelatedParties.DebtorParty)
+		}
+	}
+
+	for _, tx := range e.Transactions {
+		add(tx.Remittance.Unstructured...)
+		add(tx.Remittance.References...)
+	}
+
+	if len(parts) == 0 {
+		add(e.AdditionalEntryInfo)
+		for _, tx := range e.Transactions {
+			add(tx.AdditionalTxInfo)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func containsFold(values []string, v string) bool {
+	return slices.ContainsFunc(values, func(existing string) bool {
+		return strings.EqualFold(existing, v)
+	})
+}
@@ -0,0 +1,227 @@
+package importutil
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>1</MsgId><CreDtTm>2024-02-01T08:00:00</CreDtTm></GrpHdr>
+    <Stmt>
+      <Id>STMT-1</Id>
+      <Acct>
+        <Id><IBAN>ES9121000418450200051332</IBAN></Id>
+        <Ccy>EUR</Ccy>
+        <Svcr><FinInstnId><BICFI>BKBKESMMXXX</BICFI><Nm>Bankinter</Nm></FinInstnId></Svcr>
+      </Acct>
+      <Ntry>
+        <Amt Ccy="EUR">12.50</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2024-01-05</Dt></BookgDt>
+        <ValDt><Dt>2024-01-06</Dt></ValDt>
+        <NtryDtls>
+          <TxDtls>
+            <RltdPties><Cdtr><Pty><Nm>Restaurant La Plaza</Nm></Pty></Cdtr></RltdPties>
+            <RmtInf><Ustrd>Card payment</Ustrd><Ustrd>ref 123</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">2500</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><DtTm>2024-01-10T09:30:00+01:00</DtTm></BookgDt>
+        <NtryDtls>
+          <TxDtls>
+            <RltdPties><Dbtr><Pty><Nm>ACME Corp</Nm></Pty></Dbtr></RltdPties>
+            <RmtInf><Strd><CdtrRefInf><Ref>Salary January</Ref></CdtrRefInf></Strd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">99.99</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts><Cd>PDNG</Cd></Sts>
+        <BookgDt><Dt>2024-01-31</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+const camt052 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.052.001.02">
+  <BkToCstmrAcctRpt>
+    <Rpt>
+      <Acct><Id><IBAN>DE89370400440532013000</IBAN></Id></Acct>
+      <Ntry>
+        <Amt Ccy="USD">40.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <RvslInd>true</RvslInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2024-03-01</Dt></BookgDt>
+        <AddtlNtryInf>Refund Uber</AddtlNtryInf>
+      </Ntry>
+    </Rpt>
+  </BkToCstmrAcctRpt>
+</Document>`
+
+func TestParseCamt(t *testing.T) {
+	tests := []struct {
+		name                 string
+		data                 string
+		expectedSource       string
+		expectedAmounts      []int64
+		expectedTypes        []domain.ExpenseType
+		expectedCurrencies   []string
+		expectedDescriptions []string
+		expectedDates        []time.Time
+	}{
+		{
+			name:               "camt.053 statement",
+			data:               camt053,
+			expectedSource:     "Bankinter",
+			expectedAmounts:    []int64{-1250, 250000},
+			expectedTypes:      []domain.ExpenseType{domain.ChargeType, domain.IncomeType},
+			expectedCurrencies: []string{"EUR", "EUR"},
+			expectedDescriptions: []string{
+				"restaurant la plaza card payment ref 123",
+				"acme corp salary january",
+			},
+			expectedDates: []time.Time{
+				time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:                 "camt.052 report with reversal",
+			data:                 camt052,
+			expectedSource:       defaultCamtSource,
+			expectedAmounts:      []int64{4000},
+			expectedTypes:        []domain.ExpenseType{domain.IncomeType},
+			expectedCurrencies:   []string{"USD"},
+			expectedDescriptions: []string{"refund uber"},
+			expectedDates: []time.Time{
+				time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expenses, err := ParseCamt(strings.NewReader(tt.data), matcher.New(nil))
+			if err != nil {
+				t.Fatalf("ParseCamt failed: %v", err)
+			}
+
+			if len(expenses) != len(tt.expectedAmounts) {
+				t.Fatalf("Expected %d expenses, got %d", len(tt.expectedAmounts), len(expenses))
+			}
+
+			for i, exp := range expenses {
+				if exp.Source() != tt.expectedSource {
+					t.Errorf("Expense[%d].Source = %q, want %q", i, exp.Source(), tt.expectedSource)
+				}
+				if exp.Amount() != tt.expectedAmounts[i] {
+					t.Errorf("Expense[%d].Amount = %d, want %d", i, exp.Amount(), tt.expectedAmounts[i])
+				}
+				if exp.Type() != tt.expectedTypes[i] {
+					t.Errorf("Expense[%d].Type = %v, want %v", i, exp.Type(), tt.expectedTypes[i])
+				}
+				if exp.Currency() != tt.expectedCurrencies[i] {
+					t.Errorf("Expense[%d].Currency = %q, want %q", i, exp.Currency(), tt.expectedCurrencies[i])
+				}
+				if exp.Description() != tt.expectedDescriptions[i] {
+					t.Errorf(
+						"Expense[%d].Description = %q, want %q",
+						i,
+						exp.Description(),
+						tt.expectedDescriptions[i],
+					)
+				}
+				if !exp.Date().Equal(tt.expectedDates[i]) {
+					t.Errorf("Expense[%d].Date = %v, want %v", i, exp.Date(), tt.expectedDates[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseCamtErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "not XML",
+			data: "date,description,amount",
+		},
+		{
+			name: "other XML document",
+			data: `<Document><CstmrCdtTrfInitn></CstmrCdtTrfInitn></Document>`,
+		},
+		{
+			name: "invalid indicator",
+			data: `<Document><BkToCstmrStmt><Stmt><Ntry><Amt Ccy="EUR">1</Amt><CdtDbtInd>X</CdtDbtInd>` +
+				`<BookgDt><Dt>2024-01-01</Dt></BookgDt></Ntry></Stmt></BkToCstmrStmt></Document>`,
+		},
+		{
+			name: "missing date",
+			data: `<Document><BkToCstmrStmt><Stmt><Ntry><Amt Ccy="EUR">1</Amt><CdtDbtInd>DBIT</CdtDbtInd>` +
+				`</Ntry></Stmt></BkToCstmrStmt></Document>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCamt(strings.NewReader(tt.data), matcher.New(nil))
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestImportCamt(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	categories := []domain.Category{
+		domain.NewCategory(1, "Food", "restaurant", 0),
+	}
+	for _, c := range categories {
+		_, err := s.CreateCategory(context.Background(), user.ID(), c.Name(), c.Pattern(), 0)
+		if err != nil {
+			t.Fatalf("Failed to create category: %v", err)
+		}
+	}
+
+	info := ImportCamt(
+		context.Background(),
+		user.ID(),
+		nil,
+		"statement.xml",
+		strings.NewReader(camt053),
+		s,
+		matcher.New(categories),
+	)
+	if info.Error != nil {
+		t.Fatalf("ImportCamt failed: %v", info.Error)
+	}
+
+	if info.TotalImports != 2 {
+		t.Errorf("TotalImports = %d, want 2", info.TotalImports)
+	}
+
+	if info.ImportWithoutCategory != 1 {
+		t.Errorf("ImportWithoutCategory = %d, want 1", info.ImportWithoutCategory)
+	}
+}
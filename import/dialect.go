@@ -0,0 +1,298 @@
+package importutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+// Encodings read from CSV files.
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingUTF16       = "utf-16"
+	EncodingWindows1252 = "windows-1252"
+	EncodingISO88591    = "iso-8859-1"
+)
+
+// Encodings are the character sets users can choose when previewing a CSV
+// file.
+var Encodings = []string{EncodingUTF8, EncodingUTF16, EncodingWindows1252, EncodingISO88591}
+
+// Delimiters are the field separators detected in, or chosen for, CSV files.
+var Delimiters = []domain.DelimiterOption{
+	{Value: ",", Label: "Comma (,)"},
+	{Value: ";", Label: "Semicolon (;)"},
+	{Value: "\t", Label: "Tab"},
+	{Value: "|", Label: "Pipe (|)"},
+}
+
+// AutoHeaderRow asks ParseFileWithOptions to find the header row, skipping
+// the title and account details some banks write before it.
+const AutoHeaderRow = -1
+
+// delimiterSampleLines is how many lines are read to detect the delimiter.
+const delimiterSampleLines = 50
+
+// maxPreambleRows is how far down the header row is looked for.
+const maxPreambleRows = 20
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// windows1252 maps the bytes 0x80-0x9F, where Windows-1252 differs from
+// ISO-8859-1. Unassigned bytes keep their ISO-8859-1 control character.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// dialectSampleSize is how much of a file is looked at to detect its
+// encoding and delimiter.
+const dialectSampleSize = 64 * 1024
+
+// decodeReader returns a reader converting r to UTF-8, removing any byte
+// order mark. With no encoding given it is detected from the start of the
+// file: a BOM, valid UTF-8, or else Windows-1252, which most single byte
+// exports use.
+func decodeReader(r io.Reader, encoding string) (*bufio.Reader, string, error) {
+	br := bufio.NewReaderSize(r, dialectSampleSize)
+	sample, err := br.Peek(dialectSampleSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, "", err
+	}
+
+	if encoding == "" {
+		switch {
+		case bytes.HasPrefix(sample, utf8BOM):
+			encoding = EncodingUTF8
+		case bytes.HasPrefix(sample, utf16LEBOM), bytes.HasPrefix(sample, utf16BEBOM):
+			encoding = EncodingUTF16
+		case validUTF8Prefix(sample):
+			encoding = EncodingUTF8
+		default:
+			encoding = EncodingWindows1252
+		}
+	}
+
+	switch encoding {
+	case EncodingUTF8:
+		if !validUTF8Prefix(bytes.TrimPrefix(sample, utf8BOM)) {
+			return nil, "", errors.New("file is not valid UTF-8, choose its encoding")
+		}
+		if bytes.HasPrefix(sample, utf8BOM) {
+			_, _ = br.Discard(len(utf8BOM))
+		}
+		return br, encoding, nil
+	case EncodingUTF16:
+		var order binary.ByteOrder = binary.LittleEndian
+		switch {
+		case bytes.HasPrefix(sample, utf16LEBOM):
+			_, _ = br.Discard(len(utf16LEBOM))
+		case bytes.HasPrefix(sample, utf16BEBOM):
+			order = binary.BigEndian
+			_, _ = br.Discard(len(utf16BEBOM))
+		}
+		return bufio.NewReaderSize(&utf16Reader{r: br, order: order}, dialectSampleSize), encoding, nil
+	case EncodingWindows1252:
+		return bufio.NewReaderSize(&singleByteReader{r: br, windows1252: true}, dialectSampleSize), encoding, nil
+	case EncodingISO88591:
+		return bufio.NewReaderSize(&singleByteReader{r: br}, dialectSampleSize), encoding, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported encoding %q", encoding)
+	}
+}
+
+// validUTF8Prefix reports whether data is valid UTF-8, allowing it to end
+// in the middle of a character as samples do.
+func validUTF8Prefix(data []byte) bool {
+	for cut := 0; cut < utf8.UTFMax && cut <= len(data); cut++ {
+		if utf8.Valid(data[:len(data)-cut]) {
+			return true
+		}
+	}
+	return false
+}
+
+// utf16Reader converts UTF-16 text to UTF-8.
+type utf16Reader struct {
+	r       io.Reader
+	order   binary.ByteOrder
+	unit    [2]byte
+	pending []byte
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(u.pending) > 0 {
+			copied := copy(p[n:], u.pending)
+			u.pending = u.pending[copied:]
+			n += copied
+			continue
+		}
+
+		r, err := u.readRune()
+		if err != nil {
+			if n > 0 && errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return n, err
+		}
+		u.pending = utf8.AppendRune(u.pending[:0], r)
+	}
+	return n, nil
+}
+
+func (u *utf16Reader) readRune() (rune, error) {
+	first, err := u.readUnit()
+	if err != nil {
+		return 0, err
+	}
+
+	r := rune(first)
+	if !utf16.IsSurrogate(r) {
+		return r, nil
+	}
+
+	second, err := u.readUnit()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return utf8.RuneError, nil
+		}
+		return 0, err
+	}
+	return utf16.DecodeRune(r, rune(second)), nil
+}
+
+func (u *utf16Reader) readUnit() (uint16, error) {
+	if _, err := io.ReadFull(u.r, u.unit[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, errors.New("file is not valid UTF-16, choose its encoding")
+		}
+		return 0, err
+	}
+	return u.order.Uint16(u.unit[:]), nil
+}
+
+// singleByteReader converts ISO-8859-1, or Windows-1252, text to UTF-8.
+type singleByteReader struct {
+	r           io.Reader
+	windows1252 bool
+	buf         []byte
+	pending     []byte
+}
+
+func (s *singleByteReader) Read(p []byte) (int, error) {
+	if len(s.pending) == 0 {
+		if cap(s.buf) == 0 {
+			s.buf = make([]byte, len(p)/utf8.UTFMax+1)
+		}
+		read, err := s.r.Read(s.buf[:cap(s.buf)])
+		if read == 0 {
+			return 0, err
+		}
+
+		s.pending = s.pending[:0]
+		for _, c := range s.buf[:read] {
+			if s.windows1252 && c >= 0x80 && c <= 0x9F {
+				s.pending = utf8.AppendRune(s.pending, windows1252[c-0x80])
+				continue
+			}
+			s.pending = utf8.AppendRune(s.pending, rune(c))
+		}
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// sniffDelimiter picks the delimiter splitting the first lines of text into
+// the most rows of the same number of fields, comma when in doubt.
+func sniffDelimiter(text string) string {
+	lines := strings.SplitN(text, "\n", delimiterSampleLines+1)
+	if len(lines) > delimiterSampleLines {
+		// The last element holds the rest of the file
+		lines = lines[:delimiterSampleLines]
+	}
+	sample := strings.Join(lines, "\n")
+
+	best := Delimiters[0].Value
+	bestScore := 0
+	for _, option := range Delimiters {
+		r := csv.NewReader(strings.NewReader(sample))
+		r.Comma = []rune(option.Value)[0]
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+
+		counts := map[int]int{}
+		for {
+			record, err := r.Read()
+			if err != nil {
+				break
+			}
+			if len(record) > 1 {
+				counts[len(record)]++
+			}
+		}
+
+		score := 0
+		for _, count := range counts {
+			score = max(score, count)
+		}
+
+		if score > bestScore {
+			best = option.Value
+			bestScore = score
+		}
+	}
+
+	return best
+}
+
+// detectHeaderRow returns the number of rows before the header: the first
+// row with as many values as most rows have. Title and account rows before
+// it have fewer.
+func detectHeaderRow(records [][]string) int {
+	counts := map[int]int{}
+	for _, record := range records {
+		counts[filledCells(record)]++
+	}
+
+	common := 0
+	for filled, count := range counts {
+		if count > counts[common] || (count == counts[common] && filled > common) {
+			common = filled
+		}
+	}
+
+	for i, record := range records[:min(len(records), maxPreambleRows)] {
+		if filledCells(record) >= common {
+			return i
+		}
+	}
+
+	return 0
+}
+
+func filledCells(record []string) int {
+	filled := 0
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			filled++
+		}
+	}
+	return filled
+}
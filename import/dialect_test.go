@@ -0,0 +1,174 @@
+package importutil
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestParseFileDetectsDialect(t *testing.T) {
+	utf16LE := func(text string) []byte {
+		b := append([]byte{}, utf16LEBOM...)
+		for _, unit := range utf16.Encode([]rune(text)) {
+			b = append(b, byte(unit), byte(unit>>8))
+		}
+		return b
+	}
+
+	tests := []struct {
+		name              string
+		content           []byte
+		expectedDelimiter string
+		expectedEncoding  string
+		expectedHeaderRow int
+		expectedRow       []string
+	}{
+		{
+			name:              "comma separated UTF-8",
+			content:           []byte("date,description,amount\n2024-01-01,Café,-1.00\n"),
+			expectedDelimiter: ",",
+			expectedEncoding:  EncodingUTF8,
+			expectedRow:       []string{"2024-01-01", "Café", "-1.00"},
+		},
+		{
+			name:              "UTF-8 with byte order mark",
+			content:           []byte("\xEF\xBB\xBFdate,description,amount\n2024-01-01,Tea,-1.00\n"),
+			expectedDelimiter: ",",
+			expectedEncoding:  EncodingUTF8,
+			expectedRow:       []string{"2024-01-01", "Tea", "-1.00"},
+		},
+		{
+			name: "semicolon separated Windows-1252",
+			content: []byte("Datum;Omschrijving;Bedrag\n01-01-2024;Caf\xe9 Espa\xf1a;-1,00\n" +
+				"02-01-2024;Ticket \x80 5;-5,00\n"),
+			expectedDelimiter: ";",
+			expectedEncoding:  EncodingWindows1252,
+			expectedRow:       []string{"01-01-2024", "Café España", "-1,00"},
+		},
+		{
+			name:              "tab separated UTF-16",
+			content:           utf16LE("date\tdescription\tamount\n2024-01-01\tTea, milk\t-1.00\n"),
+			expectedDelimiter: "\t",
+			expectedEncoding:  EncodingUTF16,
+			expectedRow:       []string{"2024-01-01", "Tea, milk", "-1.00"},
+		},
+		{
+			name: "account details before the header",
+			content: []byte("Account statement\nAccount;NL00BANK0123456789\n\n" +
+				"Date;Description;Amount;Balance\n" +
+				"2024-01-01;Tea;-1.00;99.00\n2024-01-02;Lunch;-12.00;87.00\n"),
+			expectedDelimiter: ";",
+			expectedEncoding:  EncodingUTF8,
+			// The CSV reader skips the blank line
+			expectedHeaderRow: 2,
+			expectedRow:       []string{"2024-01-01", "Tea", "-1.00", "99.00"},
+		},
+		{
+			name:              "pipe separated",
+			content:           []byte("date|description|amount\n2024-01-01|Tea; milk|-1.00\n"),
+			expectedDelimiter: "|",
+			expectedEncoding:  EncodingUTF8,
+			expectedRow:       []string{"2024-01-01", "Tea; milk", "-1.00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ParseFile("statement.csv", bytes.NewReader(tt.content))
+			if err != nil {
+				t.Fatalf("ParseFile returned error: %v", err)
+			}
+
+			if data.Delimiter != tt.expectedDelimiter {
+				t.Errorf("Delimiter = %q, want %q", data.Delimiter, tt.expectedDelimiter)
+			}
+			if data.Encoding != tt.expectedEncoding {
+				t.Errorf("Encoding = %q, want %q", data.Encoding, tt.expectedEncoding)
+			}
+			if data.HeaderRow != tt.expectedHeaderRow {
+				t.Errorf("HeaderRow = %d, want %d", data.HeaderRow, tt.expectedHeaderRow)
+			}
+			if strings.Join(data.Rows[0], "|") != strings.Join(tt.expectedRow, "|") {
+				t.Errorf("First row = %q, want %q", data.Rows[0], tt.expectedRow)
+			}
+		})
+	}
+}
+
+func TestParseFileWithOptionsOverridesDialect(t *testing.T) {
+	content := []byte("date;description;amount\n2024-01-01;Caf\xe9;-1.00\n")
+
+	data, err := ParseFileWithOptions("statement.csv", bytes.NewReader(content), ParseOptions{
+		Delimiter: ",",
+		Encoding:  EncodingISO88591,
+	})
+	if err != nil {
+		t.Fatalf("ParseFileWithOptions returned error: %v", err)
+	}
+
+	if len(data.Headers) != 1 || data.Rows[0][0] != "2024-01-01;Café;-1.00" {
+		t.Errorf("Expected a single column read as ISO-8859-1, got %q", data.Rows)
+	}
+
+	options := data.Options()
+	if options.Delimiter != "," || options.Encoding != EncodingISO88591 || options.HeaderRow != 0 {
+		t.Errorf("Unexpected options %+v", options)
+	}
+
+	_, err = ParseFileWithOptions("statement.csv", bytes.NewReader(content), ParseOptions{Encoding: EncodingUTF8})
+	if err == nil {
+		t.Error("Expected an error reading Windows-1252 bytes as UTF-8")
+	}
+
+	_, err = ParseFileWithOptions("statement.csv", bytes.NewReader(content), ParseOptions{Delimiter: ";;"})
+	if err == nil {
+		t.Error("Expected an error for a multi character delimiter")
+	}
+}
+
+func TestDetectHeaderRow(t *testing.T) {
+	tests := []struct {
+		name     string
+		records  [][]string
+		expected int
+	}{
+		{
+			name:     "header first",
+			records:  [][]string{{"date", "amount"}, {"2024-01-01", "1.00"}},
+			expected: 0,
+		},
+		{
+			name: "title and blank rows",
+			records: [][]string{
+				{"Statement", "", ""},
+				{"", "", ""},
+				{"date", "description", "amount"},
+				{"2024-01-01", "Tea", "1.00"},
+			},
+			expected: 2,
+		},
+		{
+			name: "rows with empty optional columns",
+			records: [][]string{
+				{"date", "description", "amount", "notes"},
+				{"2024-01-01", "Tea", "1.00", ""},
+				{"2024-01-02", "Lunch", "12.00", ""},
+			},
+			expected: 0,
+		},
+		{
+			name:     "empty file",
+			records:  nil,
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectHeaderRow(tt.records); got != tt.expected {
+				t.Errorf("detectHeaderRow() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
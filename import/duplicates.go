@@ -0,0 +1,204 @@
+package importutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+// duplicateWindow is how far apart the dates of likely duplicates can be.
+const duplicateWindow = 2 * 24 * time.Hour
+
+// minDescriptionSimilarity is the share of words two descriptions must have
+// in common to be considered similar.
+const minDescriptionSimilarity = 0.5
+
+// Duplicate is the classification of an expense about to be imported.
+type Duplicate struct {
+	Status domain.DuplicateStatus
+	// Existing is the stored expense matched, nil for new expenses.
+	Existing domain.Expense
+}
+
+// DuplicateCounts summarizes a classification.
+type DuplicateCounts struct {
+	New    int
+	Exact  int
+	Likely int
+}
+
+// detectDuplicates classifies expenses against the stored expenses returned
+// by existingIn for the dates they span.
+func detectDuplicates(
+	expenses []domain.Expense,
+	existingIn func(start, end time.Time) ([]domain.Expense, error),
+) ([]Duplicate, error) {
+	if len(expenses) == 0 {
+		return []Duplicate{}, nil
+	}
+
+	start, end := expenses[0].Date(), expenses[0].Date()
+	for _, e := range expenses[1:] {
+		if e.Date().Before(start) {
+			start = e.Date()
+		}
+		if e.Date().After(end) {
+			end = e.Date()
+		}
+	}
+
+	existing, err := existingIn(start.Add(-duplicateWindow), end.Add(duplicateWindow))
+	if err != nil {
+		return nil, fmt.Errorf("error loading expenses to detect duplicates: %w", err)
+	}
+
+	return classifyDuplicates(expenses, existing), nil
+}
+
+// classifyDuplicates matches every stored expense to one incoming expense at
+// most, so repeated charges, like two coffees on the same day, are only
+// duplicates when they were already stored as many times. Expenses carrying
+// the same external ID as a stored one are matched first, then exact matches
+// are resolved before likely ones. Expenses with different external IDs are
+// never duplicates of each other.
+func classifyDuplicates(incoming, existing []domain.Expense) []Duplicate {
+	result := make([]Duplicate, len(incoming))
+	used := make([]bool, len(existing))
+
+	byAmount := map[int64][]int{}
+	byExternalID := map[string][]int{}
+	for j, e := range existing {
+		byAmount[e.Amount()] = append(byAmount[e.Amount()], j)
+		if e.ExternalID() != "" {
+			byExternalID[e.ExternalID()] = append(byExternalID[e.ExternalID()], j)
+		}
+	}
+
+	match := func(
+		status domain.DuplicateStatus,
+		candidates func(e domain.Expense) []int,
+		matches func(a, b domain.Expense) bool,
+	) {
+		for i, e := range incoming {
+			if result[i].Status != "" {
+				continue
+			}
+			for _, j := range candidates(e) {
+				if !used[j] && matches(e, existing[j]) {
+					used[j] = true
+					result[i] = Duplicate{Status: status, Existing: existing[j]}
+					break
+				}
+			}
+		}
+	}
+
+	sameExternalID := func(e domain.Expense) []int { return byExternalID[e.ExternalID()] }
+	sameAmount := func(e domain.Expense) []int { return byAmount[e.Amount()] }
+
+	match(domain.DuplicateExact, sameExternalID, sameTransaction)
+	match(domain.DuplicateExact, sameAmount, exactDuplicate)
+	match(domain.DuplicateLikely, sameAmount, likelyDuplicate)
+
+	for i := range result {
+		if result[i].Status == "" {
+			result[i].Status = domain.DuplicateNone
+		}
+	}
+
+	return result
+}
+
+// sameTransaction reports whether both expenses carry the same external ID
+// given by the same source and account.
+func sameTransaction(a, b domain.Expense) bool {
+	return a.ExternalID() != "" &&
+		a.ExternalID() == b.ExternalID() &&
+		a.Source() == b.Source() &&
+		sameAccount(a.AccountID(), b.AccountID())
+}
+
+// distinctTransactions reports whether the source and account of both
+// expenses gave them different external IDs.
+func distinctTransactions(a, b domain.Expense) bool {
+	return a.ExternalID() != "" && b.ExternalID() != "" &&
+		a.ExternalID() != b.ExternalID() &&
+		a.Source() == b.Source() &&
+		sameAccount(a.AccountID(), b.AccountID())
+}
+
+func sameAccount(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func exactDuplicate(a, b domain.Expense) bool {
+	return a.Source() == b.Source() &&
+		a.Date().Unix() == b.Date().Unix() &&
+		a.Description() == b.Description() &&
+		a.Amount() == b.Amount() &&
+		!distinctTransactions(a, b)
+}
+
+func likelyDuplicate(a, b domain.Expense) bool {
+	diff := a.Date().Sub(b.Date())
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return a.Amount() == b.Amount() &&
+		diff <= duplicateWindow &&
+		similarDescription(a.Description(), b.Description()) &&
+		!distinctTransactions(a, b)
+}
+
+// similarDescription reports whether one description contains the other,
+// like "amazon" and "amazon marketplace", or they share most of their words.
+func similarDescription(a, b string) bool {
+	wordsA := descriptionWords(a)
+	wordsB := descriptionWords(b)
+
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+
+	joinedA := strings.Join(wordsA, " ")
+	joinedB := strings.Join(wordsB, " ")
+	if strings.Contains(joinedA, joinedB) || strings.Contains(joinedB, joinedA) {
+		return true
+	}
+
+	set := map[string]bool{}
+	for _, w := range wordsA {
+		set[w] = true
+	}
+
+	common := 0
+	union := len(set)
+	seen := map[string]bool{}
+	for _, w := range wordsB {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+
+		if set[w] {
+			common++
+		} else {
+			union++
+		}
+	}
+
+	return float64(common)/float64(union) >= minDescriptionSimilarity
+}
+
+func descriptionWords(description string) []string {
+	return strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,264 @@
+package importutil
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func TestClassifyDuplicates(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	charge := func(source, description string, amount int64, date time.Time) domain.Expense {
+		return domain.NewExpense(0, source, description, "EUR", amount, date, domain.ChargeType, nil, nil)
+	}
+
+	tests := []struct {
+		name     string
+		incoming []domain.Expense
+		existing []domain.Expense
+		expected []domain.DuplicateStatus
+	}{
+		{
+			name:     "new expense",
+			incoming: []domain.Expense{charge("bank", "coffee", -300, day(15))},
+			existing: []domain.Expense{charge("bank", "lunch", -1200, day(15))},
+			expected: []domain.DuplicateStatus{domain.DuplicateNone},
+		},
+		{
+			name:     "exact duplicate",
+			incoming: []domain.Expense{charge("bank", "coffee", -300, day(15))},
+			existing: []domain.Expense{charge("bank", "coffee", -300, day(15))},
+			expected: []domain.DuplicateStatus{domain.DuplicateExact},
+		},
+		{
+			name: "repeated charges only match stored ones once",
+			incoming: []domain.Expense{
+				charge("bank", "coffee", -300, day(15)),
+				charge("bank", "coffee", -300, day(15)),
+			},
+			existing: []domain.Expense{charge("bank", "coffee", -300, day(15))},
+			expected: []domain.DuplicateStatus{domain.DuplicateExact, domain.DuplicateNone},
+		},
+		{
+			name:     "same charge from another source",
+			incoming: []domain.Expense{charge("card", "amazon marketplace", -2599, day(16))},
+			existing: []domain.Expense{charge("bank", "amazon", -2599, day(15))},
+			expected: []domain.DuplicateStatus{domain.DuplicateLikely},
+		},
+		{
+			name:     "shared words",
+			incoming: []domain.Expense{charge("bank", "payment netflix com", -1299, day(13))},
+			existing: []domain.Expense{charge("bank", "netflix.com subscription", -1299, day(15))},
+			expected: []domain.DuplicateStatus{domain.DuplicateLikely},
+		},
+		{
+			name:     "too far apart",
+			incoming: []domain.Expense{charge("bank", "amazon", -2599, day(20))},
+			existing: []domain.Expense{charge("bank", "amazon", -2599, day(15))},
+			expected: []domain.DuplicateStatus{domain.DuplicateNone},
+		},
+		{
+			name:     "different description",
+			incoming: []domain.Expense{charge("bank", "cinema", -2599, day(15))},
+			existing: []domain.Expense{charge("bank", "amazon", -2599, day(15))},
+			expected: []domain.DuplicateStatus{domain.DuplicateNone},
+		},
+		{
+			name:     "different amount",
+			incoming: []domain.Expense{charge("bank", "amazon", -2500, day(15))},
+			existing: []domain.Expense{charge("bank", "amazon", -2599, day(15))},
+			expected: []domain.DuplicateStatus{domain.DuplicateNone},
+		},
+		{
+			name: "exact matches win over likely ones",
+			incoming: []domain.Expense{
+				charge("bank", "amazon", -2599, day(14)),
+				charge("bank", "amazon", -2599, day(15)),
+			},
+			existing: []domain.Expense{charge("bank", "amazon", -2599, day(15))},
+			expected: []domain.DuplicateStatus{domain.DuplicateNone, domain.DuplicateExact},
+		},
+		{
+			name: "same external ID with a revised description and date",
+			incoming: []domain.Expense{
+				domain.WithExternalID(charge("bank", "amazon eu sarl", -2599, day(17)), "T1"),
+			},
+			existing: []domain.Expense{domain.WithExternalID(charge("bank", "amazon", -2599, day(15)), "T1")},
+			expected: []domain.DuplicateStatus{domain.DuplicateExact},
+		},
+		{
+			name: "different external IDs",
+			incoming: []domain.Expense{
+				domain.WithExternalID(charge("bank", "coffee", -300, day(15)), "T2"),
+			},
+			existing: []domain.Expense{domain.WithExternalID(charge("bank", "coffee", -300, day(15)), "T1")},
+			expected: []domain.DuplicateStatus{domain.DuplicateNone},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := classifyDuplicates(tt.incoming, tt.existing)
+
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d results, got %d", len(tt.expected), len(result))
+			}
+
+			for i, d := range result {
+				if d.Status != tt.expected[i] {
+					t.Errorf("Expense[%d] status = %q, want %q", i, d.Status, tt.expected[i])
+				}
+				if (d.Status == domain.DuplicateNone) != (d.Existing == nil) {
+					t.Errorf("Expense[%d] existing = %v with status %q", i, d.Existing, d.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestImportOFXSkipsExactDuplicates(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	// The salary of the statement, exported by another bank a day later
+	salary := domain.NewExpense(
+		0, "Other bank", "monthly salary", "EUR", 250000,
+		time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC), domain.IncomeType, nil, nil,
+	)
+	if _, err := s.InsertExpenses(context.Background(), user.ID(), []domain.Expense{salary}); err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	info := ImportOFX(
+		context.Background(), user.ID(), nil, "statement.ofx", strings.NewReader(sgmlOFX), s, matcher.New(nil),
+	)
+	if info.Error != nil {
+		t.Fatalf("ImportOFX failed: %v", info.Error)
+	}
+
+	if info.TotalImports != 2 || info.LikelyDuplicates != 1 || info.SkippedDuplicates != 0 {
+		t.Errorf("TotalImports = %d, LikelyDuplicates = %d, SkippedDuplicates = %d, want 2, 1 and 0",
+			info.TotalImports, info.LikelyDuplicates, info.SkippedDuplicates)
+	}
+
+	// Importing the same statement again
+	info = ImportOFX(
+		context.Background(), user.ID(), nil, "statement.ofx", strings.NewReader(sgmlOFX), s, matcher.New(nil),
+	)
+	if info.Error != nil {
+		t.Fatalf("ImportOFX failed: %v", info.Error)
+	}
+
+	if info.TotalImports != 0 || info.ExactDuplicates != 2 || info.SkippedDuplicates != 2 {
+		t.Errorf("TotalImports = %d, ExactDuplicates = %d, SkippedDuplicates = %d, want 0, 2 and 2",
+			info.TotalImports, info.ExactDuplicates, info.SkippedDuplicates)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	if len(expenses) != 3 {
+		t.Fatalf("Expected 3 expenses, got %d", len(expenses))
+	}
+}
+
+func TestImportOFXMatchesTransactionIDs(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	info := ImportOFX(
+		context.Background(), user.ID(), nil, "statement.ofx", strings.NewReader(sgmlOFX), s, matcher.New(nil),
+	)
+	if info.Error != nil {
+		t.Fatalf("ImportOFX failed: %v", info.Error)
+	}
+
+	// The bank renamed the restaurant and moved it to the day it settled
+	revised := strings.NewReplacer(
+		"<NAME>Restaurant &amp; Bar", "<NAME>Restaurant Bar Ltd",
+		"<DTPOSTED>20240105120000.000[-5:EST]", "<DTPOSTED>20240108",
+	).Replace(sgmlOFX)
+
+	info = ImportOFX(
+		context.Background(), user.ID(), nil, "statement.ofx", strings.NewReader(revised), s, matcher.New(nil),
+	)
+	if info.Error != nil {
+		t.Fatalf("ImportOFX failed: %v", info.Error)
+	}
+
+	if info.TotalImports != 0 || info.ExactDuplicates != 2 || info.SkippedDuplicates != 2 {
+		t.Errorf("TotalImports = %d, ExactDuplicates = %d, SkippedDuplicates = %d, want 0, 2 and 2",
+			info.TotalImports, info.ExactDuplicates, info.SkippedDuplicates)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	if len(expenses) != 2 {
+		t.Fatalf("Expected 2 expenses, got %d", len(expenses))
+	}
+
+	for _, e := range expenses {
+		if e.ExternalID() != "T1" && e.ExternalID() != "T2" {
+			t.Errorf("Expense %q external ID = %q, want the FITID", e.Description(), e.ExternalID())
+		}
+	}
+}
+
+func TestImportMT940KeepsRecurringChargesAcrossStatements(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	statements := []string{
+		`:20:JANUARY
+:25:10020030/1234567
+:60F:C240130EUR100,00
+:61:240131D3,50NMSCREF1
+:86:Coffee shop
+:62F:C240131EUR96,50
+-`,
+		`:20:FEBRUARY
+:25:10020030/1234567
+:60F:C240131EUR96,50
+:61:240201D3,50NMSCREF2
+:86:Coffee shop
+:62F:C240201EUR93,00
+-`,
+	}
+
+	var info ImportInfo
+	for _, statement := range statements {
+		reader := strings.NewReader(statement)
+		info = ImportMT940(context.Background(), user.ID(), nil, "statement.sta", reader, s, matcher.New(nil))
+		if info.Error != nil {
+			t.Fatalf("ImportMT940 failed: %v", info.Error)
+		}
+	}
+
+	// The coffee of February 1 looks like the one of January 31, it is
+	// imported and reported for review
+	if info.TotalImports != 1 || info.LikelyDuplicates != 1 || info.SkippedDuplicates != 0 {
+		t.Errorf("TotalImports = %d, LikelyDuplicates = %d, SkippedDuplicates = %d, want 1, 1 and 0",
+			info.TotalImports, info.LikelyDuplicates, info.SkippedDuplicates)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	if len(expenses) != 2 {
+		t.Fatalf("Expected both coffees to be stored, got %d expenses", len(expenses))
+	}
+}
@@ -0,0 +1,273 @@
+package importutil
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/util"
+)
+
+// formatSampleSize is the number of rows inspected when detecting the format
+// of a column.
+const formatSampleSize = 100
+
+// maxAmbiguousRows limits the rows reported for an ambiguous column.
+const maxAmbiguousRows = 10
+
+const thousandsGroupLen = 3
+
+// DateLayouts are the date formats offered when mapping a file, in the order
+// they are preferred when several of them can read the same column.
+var DateLayouts = []domain.DateLayoutOption{
+	{Layout: "02/01/2006", Label: "DD/MM/YYYY"},
+	{Layout: "01/02/2006", Label: "MM/DD/YYYY"},
+	{Layout: "2006-01-02", Label: "YYYY-MM-DD"},
+	{Layout: time.RFC3339, Label: "YYYY-MM-DDThh:mm:ssZ"},
+	{Layout: time.DateTime, Label: "YYYY-MM-DD hh:mm:ss"},
+	{Layout: "02-01-2006", Label: "DD-MM-YYYY"},
+	{Layout: "01-02-2006", Label: "MM-DD-YYYY"},
+	{Layout: "02.01.2006", Label: "DD.MM.YYYY"},
+	{Layout: "2006/01/02", Label: "YYYY/MM/DD"},
+	{Layout: "02/01/06", Label: "DD/MM/YY"},
+	{Layout: "01/02/06", Label: "MM/DD/YY"},
+}
+
+// amountFormat is a pair of decimal and thousands separators.
+type amountFormat struct {
+	decimal   string
+	thousands string
+	label     string
+}
+
+var amountFormats = []amountFormat{
+	{decimal: ".", thousands: ",", label: "1,234.56"},
+	{decimal: ",", thousands: ".", label: "1.234,56"},
+}
+
+// DateLayoutLabel returns the user facing name of a date layout.
+func DateLayoutLabel(layout string) string {
+	for _, option := range DateLayouts {
+		if option.Layout == layout {
+			return option.Label
+		}
+	}
+	return layout
+}
+
+// detectDateLayout finds the first date layout able to read every sampled
+// value of the column. When other layouts can read them too and give a
+// different date for some rows, those rows are reported as ambiguous. An
+// empty layout means none of the known layouts fits.
+func detectDateLayout(data *ParsedData, column int) (string, *domain.FormatAmbiguity) {
+	indexes, values := columnSample(data, column)
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	matching := []domain.DateLayoutOption{}
+	for _, option := range DateLayouts {
+		parsesAll := true
+		for _, v := range values {
+			if _, err := time.Parse(option.Layout, v); err != nil {
+				parsesAll = false
+				break
+			}
+		}
+		if parsesAll {
+			matching = append(matching, option)
+		}
+	}
+
+	if len(matching) == 0 {
+		return "", nil
+	}
+
+	layout := matching[0].Layout
+	if len(matching) == 1 {
+		return layout, nil
+	}
+
+	ambiguity := &domain.FormatAmbiguity{Field: "date"}
+	for _, option := range matching {
+		ambiguity.Candidates = append(ambiguity.Candidates, option.Label)
+	}
+
+	for i, v := range values {
+		results := make([]string, 0, len(matching))
+		for _, option := range matching {
+			t, _ := time.Parse(option.Layout, v)
+			results = append(results, t.Format(time.DateOnly))
+		}
+
+		if !allEqual(results) && len(ambiguity.Rows) < maxAmbiguousRows {
+			ambiguity.Rows = append(ambiguity.Rows, domain.AmbiguousRow{Row: indexes[i], Value: v, Results: results})
+		}
+	}
+
+	if len(ambiguity.Rows) == 0 {
+		return layout, nil
+	}
+
+	return layout, ambiguity
+}
+
+// detectAmountAmbiguity reports the amounts that are valid, but different,
+// numbers under both the 1,234.56 and the 1.234,56 conventions, like 1.234.
+func detectAmountAmbiguity(data *ParsedData, column int) *domain.FormatAmbiguity {
+	indexes, values := columnSample(data, column)
+
+	ambiguity := &domain.FormatAmbiguity{Field: "amount"}
+	for _, format := range amountFormats {
+		ambiguity.Candidates = append(ambiguity.Candidates, format.label)
+	}
+
+	for i, v := range values {
+		results := make([]string, 0, len(amountFormats))
+		parsed := []int64{}
+		for _, format := range amountFormats {
+			if !validAmountFormat(v, format.decimal, format.thousands) {
+				results = append(results, "-")
+				continue
+			}
+
+			amount, err := parseLocaleAmount(v, format.decimal, format.thousands)
+			if err != nil {
+				results = append(results, "-")
+				continue
+			}
+
+			parsed = append(parsed, amount)
+			results = append(results, util.FormatMoney(amount, ".", ","))
+		}
+
+		if len(parsed) > 1 && !allEqual(parsed) && len(ambiguity.Rows) < maxAmbiguousRows {
+			ambiguity.Rows = append(ambiguity.Rows, domain.AmbiguousRow{Row: indexes[i], Value: v, Results: results})
+		}
+	}
+
+	if len(ambiguity.Rows) == 0 {
+		return nil
+	}
+
+	return ambiguity
+}
+
+// amountFormatLabel returns the user facing name of a decimal separator.
+// Mappings without one use the 1,234.56 convention.
+func amountFormatLabel(decimal string) string {
+	for _, format := range amountFormats {
+		if format.decimal == decimal {
+			return format.label
+		}
+	}
+	return amountFormats[0].label
+}
+
+// parseDateWithLayout parses a date with the given layout, falling back to
+// the default formats when no layout is known.
+func parseDateWithLayout(dateStr, layout string) (time.Time, error) {
+	if layout == "" {
+		return parseDate(dateStr)
+	}
+
+	return time.Parse(layout, strings.TrimSpace(dateStr))
+}
+
+// parseLocaleAmount converts an amount written with the given separators into
+// cents. Currency symbols, spaces and other characters are ignored.
+func parseLocaleAmount(value, decimal, thousands string) (int64, error) {
+	if thousands != "" {
+		value = strings.ReplaceAll(value, thousands, "")
+	}
+	value = strings.ReplaceAll(value, decimal, ".")
+
+	cleaned := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == '-' || r == '+' {
+			return r
+		}
+		return -1
+	}, value)
+
+	if strings.Count(cleaned, ".") > 1 {
+		return 0, errors.New("amount has more than one decimal separator")
+	}
+
+	amount, err := parseDecimalAmount(cleaned)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", value, err)
+	}
+	return amount, nil
+}
+
+// validAmountFormat reports whether value is a well formed number for the
+// given separators: thousands groups of three digits and at most three
+// decimals.
+func validAmountFormat(value, decimal, thousands string) bool {
+	value = strings.TrimSpace(value)
+	value = strings.TrimLeft(value, "+-")
+	if value == "" {
+		return false
+	}
+
+	integer, fraction, hasFraction := strings.Cut(value, decimal)
+	if hasFraction && (fraction == "" || len(fraction) > thousandsGroupLen || !onlyDigits(fraction)) {
+		return false
+	}
+
+	groups := strings.Split(integer, thousands)
+	if len(groups[0]) == 0 || !onlyDigits(groups[0]) {
+		return false
+	}
+	if len(groups) > 1 && len(groups[0]) > thousandsGroupLen {
+		return false
+	}
+	for _, group := range groups[1:] {
+		if len(group) != thousandsGroupLen || !onlyDigits(group) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// columnSample returns the first non-empty values of a column together with
+// their row indexes.
+func columnSample(data *ParsedData, column int) ([]int, []string) {
+	indexes := []int{}
+	values := []string{}
+
+	for i, row := range data.Rows {
+		if len(values) == formatSampleSize {
+			break
+		}
+		if column < 0 || column >= len(row) {
+			continue
+		}
+
+		v := strings.TrimSpace(row[column])
+		if v == "" {
+			continue
+		}
+
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+
+	return indexes, values
+}
+
+func onlyDigits(s string) bool {
+	return !strings.ContainsFunc(s, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+}
+
+func allEqual[T comparable](values []T) bool {
+	return !slices.ContainsFunc(values, func(v T) bool {
+		return v != values[0]
+	})
+}
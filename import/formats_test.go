@@ -0,0 +1,241 @@
+package importutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GustavoCaso/expensetrace/matcher"
+)
+
+func TestDetectDateLayout(t *testing.T) {
+	tests := []struct {
+		name           string
+		dates          []string
+		expectedLayout string
+		ambiguousRows  []int
+	}{
+		{
+			name:           "day first",
+			dates:          []string{"13/01/2024", "02/02/2024"},
+			expectedLayout: "02/01/2006",
+		},
+		{
+			name:           "month first",
+			dates:          []string{"01/13/2024", "02/02/2024"},
+			expectedLayout: "01/02/2006",
+		},
+		{
+			name:           "ambiguous prefers day first",
+			dates:          []string{"01/02/2024", "05/05/2024", "03/04/2024"},
+			expectedLayout: "02/01/2006",
+			ambiguousRows:  []int{0, 2},
+		},
+		{
+			name:           "iso",
+			dates:          []string{"2024-01-31", ""},
+			expectedLayout: "2006-01-02",
+		},
+		{
+			name:           "dotted",
+			dates:          []string{"31.01.2024"},
+			expectedLayout: "02.01.2006",
+		},
+		{
+			name:           "unknown",
+			dates:          []string{"Jan 5th"},
+			expectedLayout: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &ParsedData{Headers: []string{"date"}}
+			for _, d := range tt.dates {
+				data.Rows = append(data.Rows, []string{d})
+			}
+
+			layout, ambiguity := detectDateLayout(data, 0)
+			if layout != tt.expectedLayout {
+				t.Errorf("layout = %q, want %q", layout, tt.expectedLayout)
+			}
+
+			if len(tt.ambiguousRows) == 0 {
+				if ambiguity != nil {
+					t.Errorf("Expected no ambiguity, got %+v", ambiguity)
+				}
+				return
+			}
+
+			if ambiguity == nil {
+				t.Fatal("Expected ambiguity to be reported")
+			}
+
+			if len(ambiguity.Rows) != len(tt.ambiguousRows) {
+				t.Fatalf("Expected %d ambiguous rows, got %+v", len(tt.ambiguousRows), ambiguity.Rows)
+			}
+
+			for i, row := range ambiguity.Rows {
+				if row.Row != tt.ambiguousRows[i] {
+					t.Errorf("Ambiguous row[%d] = %d, want %d", i, row.Row, tt.ambiguousRows[i])
+				}
+				if len(row.Results) != len(ambiguity.Candidates) {
+					t.Errorf("Expected one result per candidate, got %v", row.Results)
+				}
+			}
+
+			if ambiguity.Rows[0].Results[0] != "2024-02-01" || ambiguity.Rows[0].Results[1] != "2024-01-02" {
+				t.Errorf("Unexpected results %v", ambiguity.Rows[0].Results)
+			}
+		})
+	}
+}
+
+func TestParseLocaleAmount(t *testing.T) {
+	tests := []struct {
+		value     string
+		decimal   string
+		thousands string
+		want      int64
+		wantErr   bool
+	}{
+		{"1.234,56", ",", ".", 123456, false},
+		{"-1.234,56 €", ",", ".", -123456, false},
+		{"1,234.56", ".", ",", 123456, false},
+		{"12.5", ".", ",", 1250, false},
+		{"100", ".", ",", 10000, false},
+		{"1 234,5", ",", " ", 123450, false},
+		{"1'234.50", ".", "'", 123450, false},
+		{"", ".", ",", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseLocaleAmount(tt.value, tt.decimal, tt.thousands)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLocaleAmount(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseLocaleAmount(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidAmountFormat(t *testing.T) {
+	tests := []struct {
+		value     string
+		decimal   string
+		thousands string
+		want      bool
+	}{
+		{"1.234", ".", ",", true},
+		{"1.234", ",", ".", true},
+		{"-5.00", ",", ".", false},
+		{"1,234.56", ",", ".", false},
+		{"12345,67", ",", ".", true},
+		{"1234.567.8", ".", ",", false},
+		{"abc", ".", ",", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value+tt.decimal, func(t *testing.T) {
+			if got := validAmountFormat(tt.value, tt.decimal, tt.thousands); got != tt.want {
+				t.Errorf("validAmountFormat(%q, %q, %q) = %v, want %v",
+					tt.value, tt.decimal, tt.thousands, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMappingWithLocaleFormats(t *testing.T) {
+	csvData := `fecha,concepto,importe,divisa
+31.01.2024,Mercadona,"1.234,56",EUR
+01.02.2024,Reembolso,"-20,00",EUR
+02.02.2024,Farmacia,"1.500",EUR`
+
+	parsed, err := ParseFile("test.csv", strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	mapping := &FieldMapping{
+		Source:             "Mi Banco",
+		DateColumn:         0,
+		DescriptionColumn:  1,
+		AmountColumn:       2,
+		CurrencyColumn:     3,
+		DecimalSeparator:   ",",
+		ThousandsSeparator: ".",
+		InvertSign:         true,
+	}
+
+	result, err := ApplyMapping(parsed, mapping, matcher.New(nil))
+	if err != nil {
+		t.Fatalf("ApplyMapping failed: %v", err)
+	}
+
+	if len(result.Errors) != 0 {
+		t.Fatalf("Expected no errors, got %v", result.Errors)
+	}
+
+	if result.DateLayout != "02.01.2006" {
+		t.Errorf("DateLayout = %q, want detected 02.01.2006", result.DateLayout)
+	}
+
+	expectedAmounts := []int64{-123456, 2000, -150000}
+	for i, exp := range result.Expenses {
+		if exp.Amount() != expectedAmounts[i] {
+			t.Errorf("Expense[%d].Amount = %d, want %d", i, exp.Amount(), expectedAmounts[i])
+		}
+	}
+
+	// 1.500 is 1.50 or 1500 depending on the decimal separator
+	if len(result.Ambiguities) != 1 || result.Ambiguities[0].Field != "amount" {
+		t.Fatalf("Expected an amount ambiguity, got %+v", result.Ambiguities)
+	}
+
+	if len(result.Ambiguities[0].Rows) != 1 || result.Ambiguities[0].Rows[0].Row != 2 {
+		t.Errorf("Expected row 2 to be ambiguous, got %+v", result.Ambiguities[0].Rows)
+	}
+
+	if len(result.Warnings) != 1 {
+		t.Errorf("Expected 1 warning, got %v", result.Warnings)
+	}
+}
+
+func TestApplyMappingWithExplicitDateLayout(t *testing.T) {
+	csvData := `date,description,amount,currency
+01/02/2024,Coffee,-5.00,USD
+13/02/2024,Lunch,-12.00,USD`
+
+	parsed, err := ParseFile("test.csv", strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	mapping := &FieldMapping{
+		Source:            "Test Bank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+		DateLayout:        "01/02/2006",
+	}
+
+	result, err := ApplyMapping(parsed, mapping, matcher.New(nil))
+	if err != nil {
+		t.Fatalf("ApplyMapping failed: %v", err)
+	}
+
+	if len(result.Expenses) != 1 || result.Expenses[0].Date().Month() != 1 {
+		t.Fatalf("Expected the first row read as January 2nd, got %v", result.Expenses)
+	}
+
+	if len(result.Errors) != 1 || result.Errors[0].RowIndex != 1 {
+		t.Errorf("Expected row 1 to fail with the month first layout, got %v", result.Errors)
+	}
+
+	if len(result.Warnings) != 0 {
+		t.Errorf("Expected no warnings for an explicit layout, got %v", result.Warnings)
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"maps"
 	"slices"
 	"strings"
 	"time"
@@ -24,10 +24,38 @@ type JSONExpense struct {
 	Currency    string    `json:"currency"`
 }
 
+// Formats recorded on the import batches of files not read by a provider.
+const (
+	FormatJSON    = "JSON"
+	FormatOFX     = "OFX"
+	FormatCamt    = "camt"
+	FormatMT940   = "MT940"
+	FormatMapping = "Field mapping"
+)
+
 type ImportInfo struct {
 	TotalImports          int
 	ImportWithoutCategory int
-	Error                 error
+	// ExactDuplicates counts the rows identical to an existing expense.
+	ExactDuplicates int
+	// LikelyDuplicates counts the rows resembling an existing expense: same
+	// amount, a date within two days and a similar description.
+	LikelyDuplicates int
+	// SkippedDuplicates counts the duplicates left out of the import.
+	SkippedDuplicates int
+	// InvalidRows counts the rows of mapped files that could not be read.
+	InvalidRows int
+	// ImportBatchID identifies the batch recording the import.
+	ImportBatchID int64
+	// ClosingBalance is the balance reported by statement formats that
+	// include one (MT940), so the import can be checked against the bank.
+	ClosingBalance *Balance
+	// RejectedStatements counts the statements of a file left out of the
+	// import because they do not match their closing balance (MT940),
+	// RejectedReason explaining which.
+	RejectedStatements int
+	RejectedReason     string
+	Error              error
 }
 
 type entry struct {
@@ -36,56 +64,70 @@ type entry struct {
 	description string
 	amount      int64
 	currency    string
+	fee         int64
 }
 
 type transformer func(v string, entry *entry) error
 
-var defaultSourceTransformers = map[string][]transformer{
-	"evo":       evoTransformers,
-	"revolut":   revolutTransformers,
-	"bankinter": bankinterTransformers,
+func availableSources() []string {
+	return providers.names()
 }
 
-var availableSources = slices.Sorted(maps.Keys(defaultSourceTransformers))
-
-func SupportedProvider(filename string) bool {
-	source, err := extractFileSource(filename)
-	if err != nil {
-		return false
-	}
-	_, ok := defaultSourceTransformers[source]
+// SupportedProvider reports whether a provider definition exists for the
+// file, matched either by the <source>_ filename prefix or by its header row.
+func SupportedProvider(filename string, header []string) bool {
+	_, ok := providers.find(filename, header)
 	return ok
 }
 
-func SupportedJSONSchema(reader io.Reader) (bool, []JSONExpense) {
-	var expenses []JSONExpense
+// SupportedJSONSchema reports whether reader holds an array of JSONExpense,
+// judging by its first element so large files are not read twice.
+func SupportedJSONSchema(reader io.Reader) bool {
+	decoder := json.NewDecoder(reader)
 
-	// Try to unmarshal into expected type
-	if err := json.NewDecoder(reader).Decode(&expenses); err != nil {
-		return false, expenses
+	token, err := decoder.Token()
+	if err != nil || token != json.Delim('[') {
+		return false
 	}
 
 	// Validate that it's not empty and has required fields
-	if len(expenses) == 0 {
-		return false, expenses
+	if !decoder.More() {
+		return false
 	}
 
-	// Validate first expense has all required fields
-	first := expenses[0]
-	if first.Source == "" || first.Description == "" ||
-		first.Currency == "" || first.Date.IsZero() {
-		return false, expenses
+	var first JSONExpense
+	if err = decoder.Decode(&first); err != nil {
+		return false
 	}
 
-	return true, expenses
+	return first.Source != "" && first.Description != "" &&
+		first.Currency != "" && !first.Date.IsZero()
 }
 
-func ImportJSON(ctx context.Context, userID int64, expenses []JSONExpense, storage storageType.Storage,
-	categoryMatcher *matcher.Matcher) ImportInfo {
-	info := ImportInfo{}
-	storageExpenses := []domain.Expense{}
+// ImportJSON imports an array of JSONExpense, decoding and storing it an
+// element at a time.
+func ImportJSON(ctx context.Context, userID int64, accountID *int64, filename string, reader io.Reader,
+	storage storageType.Storage, categoryMatcher *matcher.Matcher) ImportInfo {
+	decoder := json.NewDecoder(reader)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return ImportInfo{Error: errors.New("JSON file is not an array of expenses")}
+	}
+
+	importer, err := NewBatchImporter(
+		ctx, userID, accountID, storage, newImportBatch(filename, FormatJSON, 0), KeepLikelyDuplicates,
+	)
+	if err != nil {
+		return ImportInfo{Error: err}
+	}
+
+	rowCount := 0
+	for decoder.More() {
+		var jsonExp JSONExpense
+		if err = decoder.Decode(&jsonExp); err != nil {
+			importer.Abort()
+			return ImportInfo{Error: fmt.Errorf("error reading expense %d: %w", rowCount+1, err)}
+		}
 
-	for _, jsonExp := range expenses {
 		description := strings.ToLower(jsonExp.Description)
 		categoryID, _ := categoryMatcher.Match(description)
 
@@ -105,86 +147,110 @@ func ImportJSON(ctx context.Context, userID int64, expenses []JSONExpense, stora
 			jsonExp.Date,
 			et,
 			categoryID,
+			nil,
 		)
 
-		if categoryID == nil {
-			info.ImportWithoutCategory++
+		if err = importer.Add(rowCount, expense); err != nil {
+			importer.Abort()
+			return ImportInfo{Error: err}
 		}
-
-		storageExpenses = append(storageExpenses, expense)
+		rowCount++
 	}
 
-	inserted, err := storage.InsertExpenses(ctx, userID, storageExpenses)
-
-	info.TotalImports = int(inserted)
-	if err != nil {
-		info.Error = fmt.Errorf("unexpected error inserting expenses: %w", err)
+	// Closing bracket, missing from truncated files
+	if _, err = decoder.Token(); err != nil {
+		importer.Abort()
+		return ImportInfo{Error: fmt.Errorf("error reading JSON: %w", err)}
 	}
 
+	info, err := importer.Finish(rowCount)
+	info.Error = err
 	return info
 }
 
+// ImportCSV imports a file exported by one of the providers, reading and
+// storing it a record at a time.
 func ImportCSV(
 	ctx context.Context,
 	userID int64,
+	accountID *int64,
 	filename string,
 	reader io.Reader,
 	storage storageType.Storage,
 	categoryMatcher *matcher.Matcher,
 ) ImportInfo {
 	info := ImportInfo{}
-	expenses := []domain.Expense{}
 
-	source, err := extractFileSource(filename)
+	r := csv.NewReader(reader)
+	r.ReuseRecord = true
 
-	if err != nil {
+	header, err := r.Read()
+	if err != nil && !errors.Is(err, io.EOF) {
 		info.Error = err
 		return info
 	}
+	header = slices.Clone(header)
 
-	transformerFuncs, ok := defaultSourceTransformers[source]
+	provider, ok := providers.find(filename, header)
 	if !ok {
+		source, sourceErr := extractFileSource(filename)
+		if sourceErr != nil {
+			info.Error = sourceErr
+			return info
+		}
+
 		info.Error = fmt.Errorf(
 			"no source transformer avilable for %s. Available sources: %s",
 			source,
-			availableSources,
+			availableSources(),
 		)
 		return info
 	}
 
-	captilizedSource := withTitleCase(source)
-
-	r := csv.NewReader(reader)
-
-	// Read all records
-	records, err := r.ReadAll()
+	transformers, err := provider.transformers(header)
 	if err != nil {
 		info.Error = err
 		return info
 	}
 
-	startRow := 1 // Skip header row
+	importer, err := NewBatchImporter(
+		ctx, userID, accountID, storage, newImportBatch(filename, provider.Source, 0), KeepLikelyDuplicates,
+	)
+	if err != nil {
+		info.Error = err
+		return info
+	}
 
 	// Process each record
-	for i := startRow; i < len(records); i++ {
-		record := records[i]
+	rowCount := 0
+	for {
+		record, readErr := r.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			importer.Abort()
+			info.Error = readErr
+			return info
+		}
 
 		ex := &entry{}
-		for idxcol, f := range transformerFuncs {
-			if f == nil {
-				// skip this col
+		for _, t := range transformers {
+			if t.column >= len(record) {
 				continue
 			}
 
-			value := record[idxcol]
+			value := record[t.column]
 			if value != "" {
-				tranformerErr := f(value, ex)
+				tranformerErr := t.transform(value, ex)
 				if tranformerErr != nil {
+					importer.Abort()
 					info.Error = tranformerErr
 					return info
 				}
 			}
 		}
+		provider.finish(ex)
 
 		categoryID, _ := categoryMatcher.Match(ex.description)
 		var et domain.ExpenseType
@@ -196,41 +262,86 @@ func ImportCSV(
 
 		expense := domain.NewExpense(
 			0,
-			captilizedSource,
+			provider.Source,
 			ex.description,
 			ex.currency,
 			ex.amount,
 			ex.date,
 			et,
 			categoryID,
+			nil,
 		)
 
-		if categoryID == nil {
-			info.ImportWithoutCategory++
+		if err = importer.Add(rowCount, expense); err != nil {
+			importer.Abort()
+			info.Error = err
+			return info
 		}
-
-		expenses = append(expenses, expense)
+		rowCount++
 	}
 
-	inserted, err := storage.InsertExpenses(ctx, userID, expenses)
+	info, err = importer.Finish(rowCount)
+	info.Error = err
+	return info
+}
 
-	info.TotalImports = int(inserted)
+// newImportBatch describes an import about to be stored.
+func newImportBatch(filename, format string, rowCount int) domain.ImportBatch {
+	return domain.NewImportBatch(0, filename, format, nil, rowCount, 0, 0, time.Time{}, nil)
+}
+
+// storeExpenses inserts already parsed expenses as an import batch and
+// reports the outcome. Exact duplicates of existing expenses are skipped,
+// likely duplicates are imported and only reported.
+func storeExpenses(
+	ctx context.Context,
+	userID int64,
+	accountID *int64,
+	batch domain.ImportBatch,
+	expenses []domain.Expense,
+	storage storageType.Storage,
+) ImportInfo {
+	importer, err := NewBatchImporter(ctx, userID, accountID, storage, batch, KeepLikelyDuplicates)
 	if err != nil {
-		info.Error = fmt.Errorf("unexpected error inserting expenses: %w", err)
+		return ImportInfo{Error: err}
 	}
 
+	for i, e := range expenses {
+		if err = importer.Add(i, e); err != nil {
+			importer.Abort()
+			return ImportInfo{Error: err}
+		}
+	}
+
+	info, err := importer.Finish(batch.RowCount())
+	info.Error = err
 	return info
 }
 
+// withAccount returns the expense stored in the given account.
+func withAccount(e domain.Expense, accountID *int64) domain.Expense {
+	return domain.WithExternalID(domain.NewExpense(
+		e.ID(),
+		e.Source(),
+		e.Description(),
+		e.Currency(),
+		e.Amount(),
+		e.Date(),
+		e.Type(),
+		e.CategoryID(),
+		accountID,
+	), e.ExternalID())
+}
+
 func extractFileSource(filename string) (string, error) {
 	parts := strings.Split(filename, "_")
 	if len(parts) <= 1 {
 		return "", fmt.Errorf(
 			"no able to extract source from filename. Use filename with format <source>_*.csv. Available sources: %s",
-			availableSources,
+			availableSources(),
 		)
 	}
-	return parts[0], nil
+	return strings.ToLower(parts[0]), nil
 }
 
 func withTitleCase(s string) string {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 
@@ -126,7 +127,7 @@ CHARGE,Current,2024-01-01 10:00:00,2024-01-01 10:01:00,ATM Withdrawal,100.00,2.5
 			matcher := matcher.New(categories)
 
 			reader := strings.NewReader(tt.csvData)
-			info := ImportCSV(context.Background(), user.ID(), tt.filename, reader, s, matcher)
+			info := ImportCSV(context.Background(), user.ID(), nil, tt.filename, reader, s, matcher)
 			if info.Error != nil {
 				t.Errorf("Import failed with error: %v", info.Error)
 			}
@@ -220,13 +221,11 @@ func TestImportJSON(t *testing.T) {
 		}
 	]`
 
-	reader := strings.NewReader(jsonData)
-	valid, jsonExpenses := SupportedJSONSchema(reader)
-	if !valid {
+	if !SupportedJSONSchema(strings.NewReader(jsonData)) {
 		t.Fatal("JSON expenses are invalid")
 	}
 
-	info := ImportJSON(context.Background(), user.ID(), jsonExpenses, s, matcher)
+	info := ImportJSON(context.Background(), user.ID(), nil, "expenses.json", strings.NewReader(jsonData), s, matcher)
 
 	if info.Error != nil {
 		t.Errorf("Import failed with error: %v", info.Error)
@@ -282,6 +281,43 @@ func TestImportJSON(t *testing.T) {
 	}
 }
 
+func TestImportIntoAccount(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	account, err := s.CreateAccount(context.Background(), user.ID(), domain.NewAccount(
+		0, "Evo", "", "EUR", domain.CheckingAccount, 0, time.Time{},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	accountID := account.ID()
+
+	csvData := `Fecha de la operación,Fecha Valor,Concepto,Importe,Divisa,Tipo de movimiento,Saldo disponible
+01/01/2024,,Restaurant bill,-1234.56,EUR,,5000.00
+03/01/2024,,Salary,500000.00,EUR,,500000.00`
+
+	info := ImportCSV(
+		context.Background(), user.ID(), &accountID, "evo_test.csv", strings.NewReader(csvData), s, matcher.New(nil),
+	)
+	if info.Error != nil {
+		t.Fatalf("Import failed with error: %v", info.Error)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	if len(expenses) != 2 {
+		t.Fatalf("Expected 2 expenses, got %d", len(expenses))
+	}
+	for _, ex := range expenses {
+		if ex.AccountID() == nil || *ex.AccountID() != accountID {
+			t.Errorf("Expense %s AccountID = %v, want %d", ex.Description(), ex.AccountID(), accountID)
+		}
+	}
+}
+
 func TestImportInvalidCSV(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -321,7 +357,7 @@ CHARGE,Current,invalid-date,2024-01-01 10:01:00,Restaurant bill,1234.56,0.00,USD
 			matcher := matcher.New(categories)
 
 			reader := strings.NewReader(tt.csvData)
-			info := ImportCSV(context.Background(), user.ID(), tt.filename, reader, s, matcher)
+			info := ImportCSV(context.Background(), user.ID(), nil, tt.filename, reader, s, matcher)
 			if info.Error == nil {
 				t.Errorf("Expected error")
 			}
@@ -346,8 +382,7 @@ func TestImportInvalidJSON(t *testing.T) {
 
 	reader := strings.NewReader(jsonData)
 
-	valid, _ := SupportedJSONSchema(reader)
-	if valid {
+	if SupportedJSONSchema(reader) {
 		t.Fatal("expected invalid JSON")
 	}
 }
@@ -0,0 +1,281 @@
+package importutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jsonObject is a decoded JSON object keeping its keys in document order,
+// so headers follow the order fields appear in the file.
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// decodeOrderedJSON decodes a single JSON document. Objects are decoded as
+// *jsonObject, arrays as []any and numbers as json.Number, keeping the
+// digits written in the file.
+func decodeOrderedJSON(reader io.Reader) (any, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+
+	value, err := decodeJSONValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the JSON document")
+	}
+
+	return value, nil
+}
+
+func decodeJSONValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		object := &jsonObject{values: map[string]any{}}
+		for decoder.More() {
+			keyToken, keyErr := decoder.Token()
+			if keyErr != nil {
+				return nil, keyErr
+			}
+			key, _ := keyToken.(string)
+
+			value, valueErr := decodeJSONValue(decoder)
+			if valueErr != nil {
+				return nil, valueErr
+			}
+
+			if _, seen := object.values[key]; !seen {
+				object.keys = append(object.keys, key)
+			}
+			object.values[key] = value
+		}
+		// Closing brace
+		if _, err = decoder.Token(); err != nil {
+			return nil, err
+		}
+		return object, nil
+	case '[':
+		array := []any{}
+		for decoder.More() {
+			value, valueErr := decodeJSONValue(decoder)
+			if valueErr != nil {
+				return nil, valueErr
+			}
+			array = append(array, value)
+		}
+		// Closing bracket
+		if _, err = decoder.Token(); err != nil {
+			return nil, err
+		}
+		return array, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q", delim)
+	}
+}
+
+// jsonPathStep is one part of a records path: an object key, an array
+// index, or every element of an array.
+type jsonPathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath reads a records path such as data.transactions[*] or
+// $.accounts[0].movements. A leading $ names the document root.
+func parseJSONPath(selector string) ([]jsonPathStep, error) {
+	rest := strings.TrimSpace(selector)
+	rest = strings.TrimPrefix(rest, "$")
+	rest = strings.TrimPrefix(rest, ".")
+
+	var steps []jsonPathStep
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, `["`):
+			quoted, err := strconv.QuotedPrefix(rest[1:])
+			if err != nil || !strings.HasPrefix(rest[1+len(quoted):], "]") {
+				return nil, fmt.Errorf("invalid records path %q: bad quoted key", selector)
+			}
+			key, _ := strconv.Unquote(quoted)
+			steps = append(steps, jsonPathStep{key: key})
+			rest = rest[len(quoted)+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid records path %q: missing ]", selector)
+			}
+			inside := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			if inside == "*" {
+				steps = append(steps, jsonPathStep{wildcard: true})
+				continue
+			}
+			index, err := strconv.Atoi(inside)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid records path %q: bad index %q", selector, inside)
+			}
+			steps = append(steps, jsonPathStep{index: index, isIndex: true})
+		case rest[0] == '.':
+			rest = rest[1:]
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			rest = rest[end:]
+
+			if key == "*" {
+				steps = append(steps, jsonPathStep{wildcard: true})
+				continue
+			}
+			steps = append(steps, jsonPathStep{key: key})
+		}
+	}
+
+	return steps, nil
+}
+
+// selectJSONRecords returns the values the records path points to. A path
+// ending on an array selects its elements, so data.transactions and
+// data.transactions[*] are the same.
+func selectJSONRecords(root any, selector string) ([]any, error) {
+	steps, err := parseJSONPath(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []any{root}
+	for _, step := range steps {
+		var next []any
+		for _, value := range current {
+			switch {
+			case step.wildcard:
+				switch v := value.(type) {
+				case []any:
+					next = append(next, v...)
+				case *jsonObject:
+					for _, key := range v.keys {
+						next = append(next, v.values[key])
+					}
+				}
+			case step.isIndex:
+				if array, ok := value.([]any); ok && step.index < len(array) {
+					next = append(next, array[step.index])
+				}
+			default:
+				if object, ok := value.(*jsonObject); ok {
+					if child, found := object.values[step.key]; found {
+						next = append(next, child)
+					}
+				}
+			}
+		}
+		current = next
+	}
+
+	if len(current) == 1 {
+		if array, ok := current[0].([]any); ok {
+			current = array
+		}
+	}
+
+	if len(current) == 0 {
+		return nil, fmt.Errorf("records path %q matches nothing", selector)
+	}
+
+	return current, nil
+}
+
+// findJSONRecords returns the path of the records in a document: the root
+// array, or else the first array of objects found, looking at the keys in
+// document order and shallower arrays first.
+func findJSONRecords(root any) (string, bool) {
+	type candidate struct {
+		path  string
+		value any
+	}
+
+	queue := []candidate{{path: "$", value: root}}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+
+		switch v := c.value.(type) {
+		case []any:
+			if len(v) > 0 {
+				if _, ok := v[0].(*jsonObject); ok {
+					return c.path + "[*]", true
+				}
+			}
+		case *jsonObject:
+			for _, key := range v.keys {
+				queue = append(queue, candidate{path: joinJSONPath(c.path, key), value: v.values[key]})
+			}
+		}
+	}
+
+	return "", false
+}
+
+// flattenJSONRecord turns a record into columns named by their path within
+// it, e.g. amount.value or tags[0], in document order.
+func flattenJSONRecord(prefix string, value any, add func(column, value string)) {
+	switch v := value.(type) {
+	case *jsonObject:
+		if len(v.keys) == 0 && prefix != "" {
+			add(prefix, "")
+		}
+		for _, key := range v.keys {
+			flattenJSONRecord(joinJSONPath(prefix, key), v.values[key], add)
+		}
+	case []any:
+		if len(v) == 0 {
+			add(prefix, "")
+		}
+		for i, element := range v {
+			flattenJSONRecord(fmt.Sprintf("%s[%d]", prefix, i), element, add)
+		}
+	case nil:
+		add(prefix, "")
+	default:
+		add(prefix, fmt.Sprintf("%v", v))
+	}
+}
+
+// joinJSONPath appends key to path, quoting keys that would not read back
+// as a single step.
+func joinJSONPath(path, key string) string {
+	if path == "$" {
+		path = ""
+	}
+	if key == "" || key == "*" || strings.ContainsAny(key, ".[]$") {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
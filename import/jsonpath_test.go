@@ -0,0 +1,158 @@
+package importutil
+
+import (
+	"strings"
+	"testing"
+)
+
+const nestedJSON = `{
+	"account": {"iban": "NL00BANK0123456789"},
+	"data": {
+		"transactions": [
+			{
+				"id": "t1",
+				"bookedAt": "2024-01-05",
+				"amount": {"value": "-12.50", "currency": "EUR"},
+				"merchant": {"name": "Coffee shop"}
+			},
+			{
+				"id": "t2",
+				"bookedAt": "2024-01-06",
+				"amount": {"value": 2500.00, "currency": "EUR"},
+				"merchant": {"name": "Employer", "category": "salary"},
+				"tags": ["income"]
+			}
+		]
+	}
+}`
+
+func TestParseNestedJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		recordsPath string
+		expectPath  string
+	}{
+		{name: "records found in the document", expectPath: "data.transactions[*]"},
+		{name: "wildcard selector", recordsPath: "data.transactions[*]", expectPath: "data.transactions[*]"},
+		{name: "selector ending on the array", recordsPath: "$.data.transactions", expectPath: "$.data.transactions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseFileWithOptions(
+				"export.json",
+				strings.NewReader(nestedJSON),
+				ParseOptions{RecordsPath: tt.recordsPath},
+			)
+			if err != nil {
+				t.Fatalf("ParseFileWithOptions failed: %v", err)
+			}
+
+			if parsed.RecordsPath != tt.expectPath {
+				t.Errorf("RecordsPath = %q, want %q", parsed.RecordsPath, tt.expectPath)
+			}
+
+			// Headers follow the document and include fields missing from
+			// the first record
+			expectedHeaders := []string{
+				"id", "bookedAt", "amount.value", "amount.currency", "merchant.name", "merchant.category", "tags[0]",
+			}
+			if strings.Join(parsed.Headers, ",") != strings.Join(expectedHeaders, ",") {
+				t.Fatalf("Headers = %v, want %v", parsed.Headers, expectedHeaders)
+			}
+
+			expectedRows := [][]string{
+				{"t1", "2024-01-05", "-12.50", "EUR", "Coffee shop", "", ""},
+				{"t2", "2024-01-06", "2500.00", "EUR", "Employer", "salary", "income"},
+			}
+			for i, row := range expectedRows {
+				if strings.Join(parsed.Rows[i], "|") != strings.Join(row, "|") {
+					t.Errorf("Row %d = %q, want %q", i, parsed.Rows[i], row)
+				}
+			}
+		})
+	}
+}
+
+func TestParseJSONHeaderOrderIsStable(t *testing.T) {
+	jsonData := `[{"date": "2024-01-01", "description": "Tea", "amount": -1, "currency": "EUR"}]`
+
+	for range 20 {
+		parsed, err := ParseFile("test.json", strings.NewReader(jsonData))
+		if err != nil {
+			t.Fatalf("ParseFile failed: %v", err)
+		}
+
+		if strings.Join(parsed.Headers, ",") != "date,description,amount,currency" {
+			t.Fatalf("Headers = %v, want the document order", parsed.Headers)
+		}
+		if parsed.RecordsPath != "$[*]" {
+			t.Errorf("RecordsPath = %q, want %q", parsed.RecordsPath, "$[*]")
+		}
+	}
+}
+
+func TestParseJSONRecordsPathErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		recordsPath string
+		expected    string
+	}{
+		{name: "missing key", recordsPath: "data.movements[*]", expected: "matches nothing"},
+		{name: "records are not objects", recordsPath: "account.iban", expected: "not an object"},
+		{name: "unclosed bracket", recordsPath: "data.transactions[0", expected: "missing ]"},
+		{name: "bad index", recordsPath: "data.transactions[first]", expected: "bad index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFileWithOptions(
+				"export.json",
+				strings.NewReader(nestedJSON),
+				ParseOptions{RecordsPath: tt.recordsPath},
+			)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestSelectJSONRecords(t *testing.T) {
+	root, err := decodeOrderedJSON(strings.NewReader(`{
+		"accounts": [
+			{"movements": [{"n": 1}, {"n": 2}]},
+			{"movements": [{"n": 3}]}
+		],
+		"odd.key": [{"n": 4}]
+	}`))
+	if err != nil {
+		t.Fatalf("decodeOrderedJSON failed: %v", err)
+	}
+
+	tests := []struct {
+		selector string
+		expected int
+	}{
+		{selector: "accounts[*].movements[*]", expected: 3},
+		{selector: "accounts[1].movements", expected: 1},
+		{selector: `["odd.key"][*]`, expected: 1},
+		{selector: "$.accounts.*", expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			records, selectErr := selectJSONRecords(root, tt.selector)
+			if selectErr != nil {
+				t.Fatalf("selectJSONRecords failed: %v", selectErr)
+			}
+			if len(records) != tt.expected {
+				t.Errorf("Got %d records, want %d", len(records), tt.expected)
+			}
+		})
+	}
+
+	if path := joinJSONPath("", "odd.key"); path != `["odd.key"]` {
+		t.Errorf("joinJSONPath quoted key = %q", path)
+	}
+}
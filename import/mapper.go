@@ -4,12 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/GustavoCaso/expensetrace/domain"
 	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/util"
 )
 
 var amountRe = regexp.MustCompile(`(?P<charge>-)?(?P<amount>\d+)\.?(?P<decimal>\d*)`)
@@ -17,13 +19,79 @@ var chargeIdx = amountRe.SubexpIndex("charge")
 var amountIdx = amountRe.SubexpIndex("amount")
 var decimalIdx = amountRe.SubexpIndex("decimal")
 
+// Ways a file can report amounts.
+const (
+	// AmountSigned is a single column with negative charges.
+	AmountSigned = "signed"
+	// AmountDebitCredit uses one column for charges and another for income.
+	AmountDebitCredit = "debit_credit"
+	// AmountIndicator is an amount column plus a column telling whether the
+	// row is a charge, like Revolut's CHARGE type.
+	AmountIndicator = "indicator"
+)
+
+// NoColumn marks an optional column that is not present in the file.
+const NoColumn = -1
+
 // FieldMapping defines how file columns map to expense fields.
+// It is stored as JSON in saved mapping profiles.
 type FieldMapping struct {
-	Source            string // Manual source input (e.g., "Chase Bank")
-	DateColumn        int    // Index of date column
-	DescriptionColumn int    // Index of description column
-	AmountColumn      int    // Index of amount column
-	CurrencyColumn    int    // Index of currency column
+	Source string `json:"source"` // Manual source input (e.g., "Chase Bank")
+	// AccountID is the account the expenses are stored in, nil for none.
+	AccountID         *int64 `json:"account_id,omitempty"`
+	DateColumn        int    `json:"date_column"`        // Index of date column
+	DescriptionColumn int    `json:"description_column"` // Index of description column
+	AmountColumn      int    `json:"amount_column"`      // Index of amount column
+	CurrencyColumn    int    `json:"currency_column"`    // Index of currency column, or NoColumn
+	// DefaultCurrency is used when there is no currency column or the cell
+	// is empty.
+	DefaultCurrency string `json:"default_currency,omitempty"`
+	// DateLayout is a Go time layout. When empty it is detected from the file.
+	DateLayout string `json:"date_layout,omitempty"`
+	// DecimalSeparator is "." or ",". When empty, commas are ignored and a
+	// period separates the decimals.
+	DecimalSeparator   string `json:"decimal_separator,omitempty"`
+	ThousandsSeparator string `json:"thousands_separator,omitempty"`
+	// InvertSign is for banks reporting charges as positive amounts.
+	InvertSign bool `json:"invert_sign,omitempty"`
+	// AmountMode is one of AmountSigned (default), AmountDebitCredit or
+	// AmountIndicator.
+	AmountMode   string `json:"amount_mode,omitempty"`
+	DebitColumn  int    `json:"debit_column,omitempty"`
+	CreditColumn int    `json:"credit_column,omitempty"`
+	// IndicatorColumn rows whose value is one of ChargeValues are charges,
+	// any other row is income.
+	IndicatorColumn int      `json:"indicator_column,omitempty"`
+	ChargeValues    []string `json:"charge_values,omitempty"`
+	// Sheet, HeaderRow, Delimiter, Encoding and RecordsPath record how the
+	// file was read, so saved mappings read the next file the same way.
+	Sheet       string `json:"sheet,omitempty"`
+	HeaderRow   int    `json:"header_row,omitempty"`
+	Delimiter   string `json:"delimiter,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+	RecordsPath string `json:"records_path,omitempty"`
+}
+
+// ParseOptions returns how the mapped file was read. found is false for
+// mappings that did not record it.
+func (m *FieldMapping) ParseOptions() (ParseOptions, bool) {
+	options := ParseOptions{
+		Sheet:       m.Sheet,
+		HeaderRow:   m.HeaderRow,
+		Delimiter:   m.Delimiter,
+		Encoding:    m.Encoding,
+		RecordsPath: m.RecordsPath,
+	}
+	return options, m.Sheet != "" || m.Delimiter != "" || m.RecordsPath != ""
+}
+
+// SetParseOptions records how the mapped file was read.
+func (m *FieldMapping) SetParseOptions(options ParseOptions) {
+	m.Sheet = options.Sheet
+	m.HeaderRow = options.HeaderRow
+	m.Delimiter = options.Delimiter
+	m.Encoding = options.Encoding
+	m.RecordsPath = options.RecordsPath
 }
 
 // mappingError represents an error that occurred while mapping a specific row.
@@ -36,6 +104,11 @@ type mappingError struct {
 type MappingResult struct {
 	Expenses []domain.Expense
 	Errors   []mappingError
+	// DateLayout is the layout used to read dates, empty when the default
+	// formats were tried row by row.
+	DateLayout  string
+	Warnings    []string
+	Ambiguities []domain.FormatAmbiguity
 }
 
 // Validate checks if the field mapping is valid.
@@ -49,15 +122,167 @@ func (m *FieldMapping) Validate(headerCount int) error {
 	if m.DescriptionColumn < 0 || m.DescriptionColumn >= headerCount {
 		return fmt.Errorf("invalid description column index: %d", m.DescriptionColumn)
 	}
-	if m.AmountColumn < 0 || m.AmountColumn >= headerCount {
-		return fmt.Errorf("invalid amount column index: %d", m.AmountColumn)
+	if err := m.validateAmount(headerCount); err != nil {
+		return err
+	}
+	if err := m.validateCurrency(headerCount); err != nil {
+		return err
+	}
+	if !slices.Contains([]string{"", ".", ","}, m.DecimalSeparator) {
+		return fmt.Errorf("invalid decimal separator: %q", m.DecimalSeparator)
 	}
+	if !slices.Contains([]string{"", ".", ",", " ", "'"}, m.ThousandsSeparator) {
+		return fmt.Errorf("invalid thousands separator: %q", m.ThousandsSeparator)
+	}
+	if m.ThousandsSeparator != "" && m.ThousandsSeparator == m.DecimalSeparator {
+		return errors.New("decimal and thousands separators must differ")
+	}
+	return nil
+}
+
+func (m *FieldMapping) validateCurrency(headerCount int) error {
+	if m.DefaultCurrency != "" {
+		if code, ok := util.NormalizeCurrency(m.DefaultCurrency); !ok || code != m.DefaultCurrency {
+			return fmt.Errorf("invalid default currency: %q", m.DefaultCurrency)
+		}
+	}
+
+	if m.CurrencyColumn == NoColumn {
+		if m.DefaultCurrency == "" {
+			return errors.New("a currency column or a default currency is required")
+		}
+		return nil
+	}
+
 	if m.CurrencyColumn < 0 || m.CurrencyColumn >= headerCount {
 		return fmt.Errorf("invalid currency column index: %d", m.CurrencyColumn)
 	}
 	return nil
 }
 
+// rowCurrency returns the currency of a row, falling back to the default
+// currency.
+func (m *FieldMapping) rowCurrency(row []string) (string, error) {
+	if m.CurrencyColumn != NoColumn {
+		if currency := strings.TrimSpace(row[m.CurrencyColumn]); currency != "" {
+			return currency, nil
+		}
+	}
+
+	if m.DefaultCurrency == "" {
+		return "", errors.New("row has no currency")
+	}
+	return m.DefaultCurrency, nil
+}
+
+func (m *FieldMapping) validateAmount(headerCount int) error {
+	switch m.AmountMode {
+	case "", AmountSigned:
+	case AmountDebitCredit:
+		if m.DebitColumn < 0 || m.DebitColumn >= headerCount {
+			return fmt.Errorf("invalid debit column index: %d", m.DebitColumn)
+		}
+		if m.CreditColumn < 0 || m.CreditColumn >= headerCount {
+			return fmt.Errorf("invalid credit column index: %d", m.CreditColumn)
+		}
+		if m.DebitColumn == m.CreditColumn {
+			return errors.New("debit and credit columns must differ")
+		}
+		return nil
+	case AmountIndicator:
+		if m.IndicatorColumn < 0 || m.IndicatorColumn >= headerCount {
+			return fmt.Errorf("invalid indicator column index: %d", m.IndicatorColumn)
+		}
+		if len(m.ChargeValues) == 0 {
+			return errors.New("charge values are required with an indicator column")
+		}
+	default:
+		return fmt.Errorf("invalid amount mode: %q", m.AmountMode)
+	}
+
+	if m.AmountColumn < 0 || m.AmountColumn >= headerCount {
+		return fmt.Errorf("invalid amount column index: %d", m.AmountColumn)
+	}
+	return nil
+}
+
+// amountColumns returns the columns holding amounts for the mapping mode.
+func (m *FieldMapping) amountColumns() []int {
+	if m.AmountMode == AmountDebitCredit {
+		return []int{m.DebitColumn, m.CreditColumn}
+	}
+	return []int{m.AmountColumn}
+}
+
+// parseAmount reads an amount with the mapping separators, or with the
+// default parsing when none were chosen.
+func (m *FieldMapping) parseAmount(amountStr string) (int64, error) {
+	if m.DecimalSeparator == "" {
+		return parseAmount(amountStr)
+	}
+	return parseLocaleAmount(amountStr, m.DecimalSeparator, m.ThousandsSeparator)
+}
+
+// rowAmount returns the signed amount of a row.
+func (m *FieldMapping) rowAmount(row []string) (int64, error) {
+	switch m.AmountMode {
+	case AmountDebitCredit:
+		var amount int64
+		debitStr := strings.TrimSpace(row[m.DebitColumn])
+		creditStr := strings.TrimSpace(row[m.CreditColumn])
+
+		if debitStr == "" && creditStr == "" {
+			return 0, errors.New("row has no debit or credit amount")
+		}
+
+		if debitStr != "" {
+			debit, err := m.parseAmount(debitStr)
+			if err != nil {
+				return 0, fmt.Errorf("invalid debit %q: %w", debitStr, err)
+			}
+			amount -= abs(debit)
+		}
+
+		if creditStr != "" {
+			credit, err := m.parseAmount(creditStr)
+			if err != nil {
+				return 0, fmt.Errorf("invalid credit %q: %w", creditStr, err)
+			}
+			amount += abs(credit)
+		}
+
+		return amount, nil
+	case AmountIndicator:
+		amountStr := row[m.AmountColumn]
+		amount, err := m.parseAmount(amountStr)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount %q: %w", amountStr, err)
+		}
+
+		indicator := strings.TrimSpace(row[m.IndicatorColumn])
+		if slices.ContainsFunc(m.ChargeValues, func(v string) bool {
+			return strings.EqualFold(strings.TrimSpace(v), indicator)
+		}) {
+			return -abs(amount), nil
+		}
+		return abs(amount), nil
+	default:
+		amountStr := row[m.AmountColumn]
+		amount, err := m.parseAmount(amountStr)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount %q: %w", amountStr, err)
+		}
+		return amount, nil
+	}
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
 // ApplyMapping applies the field mapping to parsed data and creates expenses.
 func ApplyMapping(
 	data *ParsedData,
@@ -73,8 +298,33 @@ func ApplyMapping(
 		Errors:   make([]mappingError, 0),
 	}
 
+	result.DateLayout = mapping.DateLayout
+	if result.DateLayout == "" {
+		layout, ambiguity := detectDateLayout(data, mapping.DateColumn)
+		if ambiguity != nil {
+			result.Ambiguities = append(result.Ambiguities, *ambiguity)
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"Dates can be read as %s, %s was used. Choose the date format if dates look wrong.",
+				strings.Join(ambiguity.Candidates, " or "),
+				DateLayoutLabel(layout),
+			))
+		}
+		result.DateLayout = layout
+	}
+
+	for _, column := range mapping.amountColumns() {
+		if ambiguity := detectAmountAmbiguity(data, column); ambiguity != nil {
+			result.Ambiguities = append(result.Ambiguities, *ambiguity)
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"Some %s amounts read differently depending on the decimal separator, %s was used.",
+				data.Headers[column],
+				amountFormatLabel(mapping.DecimalSeparator),
+			))
+		}
+	}
+
 	for i, row := range data.Rows {
-		expense, err := mapRow(row, mapping, categoryMatcher)
+		expense, err := mapRow(row, mapping, result.DateLayout, categoryMatcher)
 		if err != nil {
 			result.Errors = append(result.Errors, mappingError{
 				RowIndex: i,
@@ -92,25 +342,33 @@ func ApplyMapping(
 func mapRow(
 	row []string,
 	mapping *FieldMapping,
+	dateLayout string,
 	categoryMatcher *matcher.Matcher,
 ) (domain.Expense, error) {
 	// Extract values from row
 	source := mapping.Source // Use manual source input
 	dateStr := row[mapping.DateColumn]
 	description := strings.ToLower(row[mapping.DescriptionColumn])
-	amountStr := row[mapping.AmountColumn]
-	currency := row[mapping.CurrencyColumn]
+
+	currency, err := mapping.rowCurrency(row)
+	if err != nil {
+		return nil, err
+	}
 
 	// Parse date
-	date, err := parseDate(dateStr)
+	date, err := parseDateWithLayout(dateStr, dateLayout)
 	if err != nil {
 		return nil, fmt.Errorf("invalid date %q: %w", dateStr, err)
 	}
 
 	// Parse amount
-	amount, err := parseAmount(amountStr)
+	amount, err := mapping.rowAmount(row)
 	if err != nil {
-		return nil, fmt.Errorf("invalid amount %q: %w", amountStr, err)
+		return nil, err
+	}
+
+	if mapping.InvertSign {
+		amount = -amount
 	}
 
 	// Determine expense type
@@ -134,6 +392,7 @@ func mapRow(
 		date,
 		expenseType,
 		categoryID,
+		nil,
 	)
 
 	return expense, nil
@@ -145,6 +404,7 @@ var fallbackFormats = []string{
 	"2006-01-02",           // ISO format
 	"01/02/2006",           // MM/DD/YYYY
 	"2006-01-02T15:04:05Z", // ISO with time
+	time.DateTime,          // spreadsheet date cells with a time
 }
 
 // parseDate attempts to parse a date string using the specified format.
@@ -193,3 +453,34 @@ func parseAmount(amountStr string) (int64, error) {
 
 	return parsedAmount, nil
 }
+
+// parseDecimalAmount converts a plain decimal amount ("-12.5", "+1234,56") as
+// found in bank statement formats into cents. Either a period or a comma is
+// accepted as the decimal separator; thousands separators are not.
+func parseDecimalAmount(value string) (int64, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "+")
+	value = strings.ReplaceAll(value, ",", ".")
+	if value == "" {
+		return 0, errors.New("amount is empty")
+	}
+
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	units, decimals, _ := strings.Cut(value, ".")
+	if units == "" {
+		units = "0"
+	}
+	decimals = (decimals + "00")[:2]
+
+	cents, err := strconv.ParseInt(units+decimals, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if negative {
+		cents = -cents
+	}
+
+	return cents, nil
+}
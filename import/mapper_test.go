@@ -54,6 +54,133 @@ func TestFieldMappingValidate(t *testing.T) {
 			wantErr:     true,
 			errContains: "date column",
 		},
+		{
+			name: "invalid decimal separator",
+			mapping: &FieldMapping{
+				Source:            "Test Bank",
+				DateColumn:        0,
+				DescriptionColumn: 1,
+				AmountColumn:      2,
+				CurrencyColumn:    3,
+				DecimalSeparator:  ";",
+			},
+			headerCount: 4,
+			wantErr:     true,
+			errContains: "decimal separator",
+		},
+		{
+			name: "same separators",
+			mapping: &FieldMapping{
+				Source:             "Test Bank",
+				DateColumn:         0,
+				DescriptionColumn:  1,
+				AmountColumn:       2,
+				CurrencyColumn:     3,
+				DecimalSeparator:   ",",
+				ThousandsSeparator: ",",
+			},
+			headerCount: 4,
+			wantErr:     true,
+			errContains: "must differ",
+		},
+		{
+			name: "debit and credit columns",
+			mapping: &FieldMapping{
+				Source:            "Test Bank",
+				DateColumn:        0,
+				DescriptionColumn: 1,
+				CurrencyColumn:    2,
+				AmountMode:        AmountDebitCredit,
+				DebitColumn:       3,
+				CreditColumn:      4,
+			},
+			headerCount: 5,
+			wantErr:     false,
+		},
+		{
+			name: "same debit and credit column",
+			mapping: &FieldMapping{
+				Source:            "Test Bank",
+				DateColumn:        0,
+				DescriptionColumn: 1,
+				CurrencyColumn:    2,
+				AmountMode:        AmountDebitCredit,
+				DebitColumn:       3,
+				CreditColumn:      3,
+			},
+			headerCount: 5,
+			wantErr:     true,
+			errContains: "debit and credit columns must differ",
+		},
+		{
+			name: "indicator without charge values",
+			mapping: &FieldMapping{
+				Source:            "Test Bank",
+				DateColumn:        0,
+				DescriptionColumn: 1,
+				AmountColumn:      2,
+				CurrencyColumn:    3,
+				AmountMode:        AmountIndicator,
+				IndicatorColumn:   4,
+			},
+			headerCount: 5,
+			wantErr:     true,
+			errContains: "charge values",
+		},
+		{
+			name: "default currency without currency column",
+			mapping: &FieldMapping{
+				Source:            "Test Bank",
+				DateColumn:        0,
+				DescriptionColumn: 1,
+				AmountColumn:      2,
+				CurrencyColumn:    NoColumn,
+				DefaultCurrency:   "EUR",
+			},
+			headerCount: 3,
+			wantErr:     false,
+		},
+		{
+			name: "no currency column nor default currency",
+			mapping: &FieldMapping{
+				Source:            "Test Bank",
+				DateColumn:        0,
+				DescriptionColumn: 1,
+				AmountColumn:      2,
+				CurrencyColumn:    NoColumn,
+			},
+			headerCount: 3,
+			wantErr:     true,
+			errContains: "default currency is required",
+		},
+		{
+			name: "invalid default currency",
+			mapping: &FieldMapping{
+				Source:            "Test Bank",
+				DateColumn:        0,
+				DescriptionColumn: 1,
+				AmountColumn:      2,
+				CurrencyColumn:    NoColumn,
+				DefaultCurrency:   "euro",
+			},
+			headerCount: 3,
+			wantErr:     true,
+			errContains: "invalid default currency",
+		},
+		{
+			name: "unknown amount mode",
+			mapping: &FieldMapping{
+				Source:            "Test Bank",
+				DateColumn:        0,
+				DescriptionColumn: 1,
+				AmountColumn:      2,
+				CurrencyColumn:    3,
+				AmountMode:        "both",
+			},
+			headerCount: 4,
+			wantErr:     true,
+			errContains: "amount mode",
+		},
 	}
 
 	for _, tt := range tests {
@@ -145,6 +272,151 @@ func TestApplyMapping(t *testing.T) {
 	}
 }
 
+func TestApplyMappingDebitCreditColumns(t *testing.T) {
+	csvData := `date,description,debit,credit,currency
+01/01/2024,groceries,50.00,,EUR
+02/01/2024,salary,,2500.00,EUR
+03/01/2024,refund,-10.00,,EUR
+04/01/2024,nothing,,,EUR`
+
+	parsed, err := ParseFile("test.csv", strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	mapping := &FieldMapping{
+		Source:            "Test Bank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		CurrencyColumn:    4,
+		AmountMode:        AmountDebitCredit,
+		DebitColumn:       2,
+		CreditColumn:      3,
+	}
+
+	result, err := ApplyMapping(parsed, mapping, matcher.New(nil))
+	if err != nil {
+		t.Fatalf("ApplyMapping failed: %v", err)
+	}
+
+	if len(result.Expenses) != 3 {
+		t.Fatalf("Expected 3 expenses, got %d", len(result.Expenses))
+	}
+
+	expected := []struct {
+		amount int64
+		kind   domain.ExpenseType
+	}{
+		{-5000, domain.ChargeType},
+		{250000, domain.IncomeType},
+		{-1000, domain.ChargeType},
+	}
+	for i, want := range expected {
+		if result.Expenses[i].Amount() != want.amount {
+			t.Errorf("Expense[%d].Amount = %d, want %d", i, result.Expenses[i].Amount(), want.amount)
+		}
+		if result.Expenses[i].Type() != want.kind {
+			t.Errorf("Expense[%d].Type = %v, want %v", i, result.Expenses[i].Type(), want.kind)
+		}
+	}
+
+	if len(result.Errors) != 1 || result.Errors[0].RowIndex != 3 {
+		t.Fatalf("Expected an error for row 3, got %+v", result.Errors)
+	}
+}
+
+func TestApplyMappingIndicatorColumn(t *testing.T) {
+	csvData := `date,description,amount,type,currency
+01/01/2024,groceries,50.00,DBIT,EUR
+02/01/2024,salary,2500.00,CRDT,EUR
+03/01/2024,coffee,-3.00, dbit ,EUR`
+
+	parsed, err := ParseFile("test.csv", strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	mapping := &FieldMapping{
+		Source:            "Test Bank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    4,
+		AmountMode:        AmountIndicator,
+		IndicatorColumn:   3,
+		ChargeValues:      []string{"DBIT"},
+	}
+
+	result, err := ApplyMapping(parsed, mapping, matcher.New(nil))
+	if err != nil {
+		t.Fatalf("ApplyMapping failed: %v", err)
+	}
+
+	if len(result.Errors) != 0 {
+		t.Fatalf("Expected 0 errors, got %+v", result.Errors)
+	}
+
+	amounts := []int64{-5000, 250000, -300}
+	for i, want := range amounts {
+		if result.Expenses[i].Amount() != want {
+			t.Errorf("Expense[%d].Amount = %d, want %d", i, result.Expenses[i].Amount(), want)
+		}
+	}
+
+	if result.Expenses[1].Type() != domain.IncomeType {
+		t.Errorf("Expense[1].Type = %v, want IncomeType", result.Expenses[1].Type())
+	}
+}
+
+func TestApplyMappingDefaultCurrency(t *testing.T) {
+	csvData := `date,description,amount,currency
+01/01/2024,groceries,-50.00,USD
+02/01/2024,salary,2500.00,`
+
+	parsed, err := ParseFile("test.csv", strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		column   int
+		expected []string
+	}{
+		{name: "empty cells", column: 3, expected: []string{"USD", "EUR"}},
+		{name: "no currency column", column: NoColumn, expected: []string{"EUR", "EUR"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := &FieldMapping{
+				Source:            "Test Bank",
+				DateColumn:        0,
+				DescriptionColumn: 1,
+				AmountColumn:      2,
+				CurrencyColumn:    tt.column,
+				DefaultCurrency:   "EUR",
+			}
+
+			result, applyErr := ApplyMapping(parsed, mapping, matcher.New(nil))
+			if applyErr != nil {
+				t.Fatalf("ApplyMapping failed: %v", applyErr)
+			}
+
+			if len(result.Expenses) != len(tt.expected) {
+				t.Fatalf("Expected %d expenses, got %d (errors: %+v)",
+					len(tt.expected), len(result.Expenses), result.Errors)
+			}
+
+			for i, want := range tt.expected {
+				if result.Expenses[i].Currency() != want {
+					t.Errorf("Expense[%d].Currency = %q, want %q", i, result.Expenses[i].Currency(), want)
+				}
+			}
+		})
+	}
+}
+
 func TestApplyMappingWithErrors(t *testing.T) {
 	csvData := `date,description,amount,currency
 invalid-date,Coffee,-5.00,USD
@@ -0,0 +1,407 @@
+package importutil
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	storageType "github.com/GustavoCaso/expensetrace/storage"
+	"github.com/GustavoCaso/expensetrace/util"
+)
+
+// defaultMT940Source is the source assigned to expenses imported from MT940
+// statements without an account identification (:25:). Statements do not
+// carry a human readable bank name.
+const defaultMT940Source = "MT940"
+
+const mt940DateLayout = "060102"
+
+var mt940TagRe = regexp.MustCompile(`^:(\d{2}[A-Z]?):(.*)$`)
+
+// :61: Value date, optional entry date, debit/credit mark, optional funds
+// code, amount, transaction type and references.
+var mt940StatementLineRe = regexp.MustCompile(
+	`^(?P<valueDate>\d{6})(?P<entryDate>\d{4})?(?P<mark>R?[CD])[A-Z]?(?P<amount>\d+,\d*)` +
+		`(?P<type>[NFS][A-Z0-9]{3})(?P<reference>.*)$`,
+)
+
+// :60F:, :60M:, :62F: and :62M:
+// Debit/credit mark, date, currency and amount.
+var mt940BalanceRe = regexp.MustCompile(`^(?P<mark>[CD])(?P<date>\d{6})(?P<currency>[A-Z]{3})(?P<amount>\d+,\d*)$`)
+
+// structured :86: subfields used by German banks (?20-?29 purpose, ?32-?33
+// counterparty name).
+var mt940SubfieldRe = regexp.MustCompile(`\?(\d{2})([^?]*)`)
+
+// structured :86: narratives start with a subfield, optionally preceded by
+// the three digit business transaction code.
+var mt940StructuredRe = regexp.MustCompile(`^(\d{3})?\?\d{2}`)
+
+// Balance is an account balance reported by a bank statement. Amount is in
+// cents and negative for debit balances.
+type Balance struct {
+	Date     time.Time
+	Currency string
+	Amount   int64
+}
+
+// MT940Statement is a single statement (message) of an MT940 file.
+type MT940Statement struct {
+	Account        string
+	OpeningBalance *Balance
+	ClosingBalance *Balance
+	Expenses       []domain.Expense
+}
+
+// Reconciles reports whether the opening balance plus every transaction
+// equals the closing balance. Statements missing either balance cannot be
+// checked and are considered reconciled.
+func (s *MT940Statement) Reconciles() bool {
+	if s.OpeningBalance == nil || s.ClosingBalance == nil {
+		return true
+	}
+
+	total := s.OpeningBalance.Amount
+	for _, exp := range s.Expenses {
+		total += exp.Amount()
+	}
+
+	return total == s.ClosingBalance.Amount
+}
+
+type mt940Line struct {
+	statementLine string
+	supplementary string
+	narrative     []string
+}
+
+// ParseMT940 parses a SWIFT MT940 file. A file can contain several
+// statements; each one starts with a :20: tag and is closed by a line
+// containing "-" (or "-}" when SWIFT envelope blocks are present).
+func ParseMT940(reader io.Reader, categoryMatcher *matcher.Matcher) ([]*MT940Statement, error) {
+	scanner := bufio.NewScanner(reader)
+
+	statements := []*MT940Statement{}
+	var current *MT940Statement
+	var lines []*mt940Line
+	var tag string
+
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+
+		for _, line := range lines {
+			expense, err := line.toExpense(current, categoryMatcher)
+			if err != nil {
+				return err
+			}
+			current.Expenses = append(current.Expenses, expense)
+		}
+
+		statements = append(statements, current)
+		current = nil
+		lines = nil
+		return nil
+	}
+
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r ")
+
+		if text == "-" || text == "-}" {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			tag = ""
+			continue
+		}
+
+		// SWIFT envelope blocks ({1:...}{2:...}{4:)
+		if strings.HasPrefix(text, "{") {
+			continue
+		}
+
+		match := mt940TagRe.FindStringSubmatch(text)
+		if match == nil {
+			// Continuation of the previous tag
+			if err := appendContinuation(tag, text, lines); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		tag = match[1]
+		value := match[2]
+
+		switch tag {
+		case "20":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			current = &MT940Statement{}
+		case "25":
+			if current != nil {
+				current.Account = strings.TrimSpace(value)
+			}
+		case "60F", "60M":
+			balance, err := parseMT940Balance(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid opening balance %q: %w", value, err)
+			}
+			if current != nil {
+				current.OpeningBalance = balance
+			}
+		case "62F", "62M":
+			balance, err := parseMT940Balance(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid closing balance %q: %w", value, err)
+			}
+			if current != nil {
+				current.ClosingBalance = balance
+			}
+		case "61":
+			lines = append(lines, &mt940Line{statementLine: value})
+		case "86":
+			if len(lines) > 0 {
+				lines[len(lines)-1].narrative = append(lines[len(lines)-1].narrative, value)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading MT940: %w", err)
+	}
+
+	// Some exports omit the trailing "-"
+	if err := finish(); err != nil {
+		return nil, err
+	}
+
+	if len(statements) == 0 {
+		return nil, errors.New("MT940 file contains no statements")
+	}
+
+	return statements, nil
+}
+
+// ImportMT940 parses an MT940 file, checks every statement against its
+// closing balance and stores the transactions of the ones that match.
+// Statements that don't are left out and reported in RejectedStatements,
+// the file fails when none match. The closing balance of the last
+// statement imported is reported back in ImportInfo.
+func ImportMT940(
+	ctx context.Context,
+	userID int64,
+	accountID *int64,
+	filename string,
+	reader io.Reader,
+	storage storageType.Storage,
+	categoryMatcher *matcher.Matcher,
+) ImportInfo {
+	statements, err := ParseMT940(reader, categoryMatcher)
+	if err != nil {
+		return ImportInfo{Error: err}
+	}
+
+	expenses := []domain.Expense{}
+	rejected := []string{}
+	var closingBalance *Balance
+	for _, stmt := range statements {
+		if !stmt.Reconciles() {
+			rejected = append(rejected, fmt.Sprintf(
+				"statement for account %q does not match its closing balance %s %s",
+				stmt.Account,
+				util.FormatMoney(stmt.ClosingBalance.Amount, ".", ","),
+				stmt.ClosingBalance.Currency,
+			))
+			continue
+		}
+		expenses = append(expenses, stmt.Expenses...)
+		closingBalance = stmt.ClosingBalance
+	}
+
+	if len(rejected) == len(statements) {
+		return ImportInfo{Error: errors.New(strings.Join(rejected, "; "))}
+	}
+
+	batch := newImportBatch(filename, FormatMT940, len(expenses))
+	info := storeExpenses(ctx, userID, accountID, batch, expenses, storage)
+	info.ClosingBalance = closingBalance
+	info.RejectedStatements = len(rejected)
+	info.RejectedReason = strings.Join(rejected, "; ")
+
+	return info
+}
+
+func appendContinuation(tag, text string, lines []*mt940Line) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	last := lines[len(lines)-1]
+	switch tag {
+	case "61":
+		if last.supplementary != "" {
+			return fmt.Errorf("unexpected continuation line for :61: %q", text)
+		}
+		last.supplementary = text
+	case "86":
+		last.narrative = append(last.narrative, text)
+	}
+
+	return nil
+}
+
+func parseMT940Balance(value string) (*Balance, error) {
+	match := mt940BalanceRe.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return nil, errors.New("balance does not match expected pattern")
+	}
+
+	date, err := time.Parse(mt940DateLayout, match[mt940BalanceRe.SubexpIndex("date")])
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := parseDecimalAmount(match[mt940BalanceRe.SubexpIndex("amount")])
+	if err != nil {
+		return nil, err
+	}
+
+	if match[mt940BalanceRe.SubexpIndex("mark")] == "D" {
+		amount = -amount
+	}
+
+	return &Balance{
+		Date:     date,
+		Currency: match[mt940BalanceRe.SubexpIndex("currency")],
+		Amount:   amount,
+	}, nil
+}
+
+func (l *mt940Line) toExpense(stmt *MT940Statement, categoryMatcher *matcher.Matcher) (domain.Expense, error) {
+	match := mt940StatementLineRe.FindStringSubmatch(l.statementLine)
+	if match == nil {
+		return nil, fmt.Errorf("invalid :61: statement line %q", l.statementLine)
+	}
+
+	date, err := mt940EntryDate(
+		match[mt940StatementLineRe.SubexpIndex("valueDate")],
+		match[mt940StatementLineRe.SubexpIndex("entryDate")],
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date in statement line %q: %w", l.statementLine, err)
+	}
+
+	amount, err := parseDecimalAmount(match[mt940StatementLineRe.SubexpIndex("amount")])
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount in statement line %q: %w", l.statementLine, err)
+	}
+
+	// D (debit) and RC (reversal of credit) take money out of the account
+	var et domain.ExpenseType
+	switch match[mt940StatementLineRe.SubexpIndex("mark")] {
+	case "D", "RC":
+		et = domain.ChargeType
+		amount = -amount
+	default:
+		et = domain.IncomeType
+	}
+
+	var currency string
+	switch {
+	case stmt.OpeningBalance != nil:
+		currency = stmt.OpeningBalance.Currency
+	case stmt.ClosingBalance != nil:
+		currency = stmt.ClosingBalance.Currency
+	default:
+		return nil, errors.New("MT940 statement has no balance to take the currency from")
+	}
+
+	description := l.description(match[mt940StatementLineRe.SubexpIndex("reference")])
+	categoryID, _ := categoryMatcher.Match(description)
+
+	source := stmt.Account
+	if source == "" {
+		source = defaultMT940Source
+	}
+
+	return domain.NewExpense(
+		0,
+		source,
+		description,
+		currency,
+		amount,
+		date,
+		et,
+		categoryID,
+		nil,
+	), nil
+}
+
+// mt940EntryDate returns the booking (entry) date when present, falling back
+// to the value date. The entry date has no year, so it is taken from the
+// value date and corrected when both dates straddle a year boundary.
+func mt940EntryDate(valueDate, entryDate string) (time.Time, error) {
+	value, err := time.Parse(mt940DateLayout, valueDate)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if entryDate == "" {
+		return value, nil
+	}
+
+	entry, err := time.Parse("0102", entryDate)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	year := value.Year()
+	switch {
+	case value.Month() == time.January && entry.Month() == time.December:
+		year--
+	case value.Month() == time.December && entry.Month() == time.January:
+		year++
+	}
+
+	return time.Date(year, entry.Month(), entry.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// description builds the expense description from the :86: narrative. When
+// the narrative starts with a structured ?NN subfield only the purpose and
+// counterparty name are kept, any other narrative is used as it is. Without
+// a narrative the :61: references are used instead.
+func (l *mt940Line) description(reference string) string {
+	narrative := strings.Join(l.narrative, "")
+
+	var parts []string
+	if mt940StructuredRe.MatchString(narrative) {
+		var purpose, name []string
+		for _, sub := range mt940SubfieldRe.FindAllStringSubmatch(narrative, -1) {
+			switch {
+			case sub[1] >= "20" && sub[1] <= "29":
+				purpose = append(purpose, sub[2])
+			case sub[1] == "32" || sub[1] == "33":
+				name = append(name, sub[2])
+			}
+		}
+		parts = append(parts, strings.Join(name, ""), strings.Join(purpose, ""))
+	} else {
+		parts = append(parts, strings.Join(l.narrative, " "))
+	}
+
+	description := strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+	if description == "" {
+		description = strings.TrimSpace(strings.Join([]string{reference, l.supplementary}, " "))
+	}
+
+	return strings.ToLower(description)
+}
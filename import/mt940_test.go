@@ -0,0 +1,256 @@
+package importutil
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+const mt940Data = `{1:F01BKBKESMMAXXX0000000000}{2:O9400000000000BKBKESMMXXXX00000000000000000000N}{4:
+:20:STARTUMSE
+:25:10020030/1234567
+:28C:00001/001
+:60F:C231229EUR1000,00
+:61:2401020102D12,50NMSCNONREF//B1
+:86:Restaurant La Plaza
+card payment
+:61:2312311231C2500,00NTRFNONREF
+:86:166?00GUTSCHRIFT?20Salary ?21January?32ACME
+?33 Corp
+:61:2401050105D40,00NDDTMANDATE1
+/SUPPLEMENTARY
+:62F:C240105EUR3447,50
+-}
+{4:
+:20:SECOND
+:25:10020030/1234567
+:60F:C240105EUR3447,50
+:61:240106D7,25NMSCREF2
+:86:Invoice? see ref 42
+:62F:C240106EUR3440,25
+-}`
+
+func TestParseMT940(t *testing.T) {
+	statements, err := ParseMT940(strings.NewReader(mt940Data), matcher.New(nil))
+	if err != nil {
+		t.Fatalf("ParseMT940 failed: %v", err)
+	}
+
+	if len(statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(statements))
+	}
+
+	first := statements[0]
+	if first.Account != "10020030/1234567" {
+		t.Errorf("Account = %q, want %q", first.Account, "10020030/1234567")
+	}
+
+	if first.OpeningBalance == nil || first.OpeningBalance.Amount != 100000 {
+		t.Fatalf("OpeningBalance = %+v, want 100000", first.OpeningBalance)
+	}
+
+	if first.ClosingBalance == nil || first.ClosingBalance.Amount != 344750 {
+		t.Fatalf("ClosingBalance = %+v, want 344750", first.ClosingBalance)
+	}
+
+	if !first.ClosingBalance.Date.Equal(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ClosingBalance.Date = %v, want 2024-01-05", first.ClosingBalance.Date)
+	}
+
+	if !first.Reconciles() {
+		t.Error("Expected first statement to reconcile")
+	}
+
+	expectedAmounts := []int64{-1250, 250000, -4000}
+	expectedTypes := []domain.ExpenseType{domain.ChargeType, domain.IncomeType, domain.ChargeType}
+	expectedDescriptions := []string{
+		"restaurant la plaza card payment",
+		"acme corp salary january",
+		"mandate1 /supplementary",
+	}
+	expectedDates := []time.Time{
+		time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+	}
+
+	if len(first.Expenses) != len(expectedAmounts) {
+		t.Fatalf("Expected %d expenses, got %d", len(expectedAmounts), len(first.Expenses))
+	}
+
+	for i, exp := range first.Expenses {
+		if exp.Amount() != expectedAmounts[i] {
+			t.Errorf("Expense[%d].Amount = %d, want %d", i, exp.Amount(), expectedAmounts[i])
+		}
+		if exp.Type() != expectedTypes[i] {
+			t.Errorf("Expense[%d].Type = %v, want %v", i, exp.Type(), expectedTypes[i])
+		}
+		if exp.Description() != expectedDescriptions[i] {
+			t.Errorf("Expense[%d].Description = %q, want %q", i, exp.Description(), expectedDescriptions[i])
+		}
+		if !exp.Date().Equal(expectedDates[i]) {
+			t.Errorf("Expense[%d].Date = %v, want %v", i, exp.Date(), expectedDates[i])
+		}
+		if exp.Currency() != "EUR" {
+			t.Errorf("Expense[%d].Currency = %q, want EUR", i, exp.Currency())
+		}
+		if exp.Source() != "10020030/1234567" {
+			t.Errorf("Expense[%d].Source = %q, want the account 10020030/1234567", i, exp.Source())
+		}
+	}
+
+	second := statements[1]
+	if len(second.Expenses) != 1 {
+		t.Fatalf("Expected 1 expense in second statement, got %d", len(second.Expenses))
+	}
+
+	if !second.Expenses[0].Date().Equal(time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Second statement expense date = %v, want 2024-01-06", second.Expenses[0].Date())
+	}
+
+	if second.Expenses[0].Description() != "invoice? see ref 42" {
+		t.Errorf("Second statement expense description = %q, want the whole narrative",
+			second.Expenses[0].Description())
+	}
+}
+
+func TestParseMT940Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "no statements",
+			data: "date,description,amount",
+		},
+		{
+			name: "invalid balance",
+			data: ":20:REF\n:60F:X240101EUR1,00\n-",
+		},
+		{
+			name: "invalid statement line",
+			data: ":20:REF\n:60F:C240101EUR1,00\n:61:invalid\n-",
+		},
+		{
+			name: "no currency",
+			data: ":20:REF\n:61:240101D1,00NMSCREF\n-",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMT940(strings.NewReader(tt.data), matcher.New(nil))
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestImportMT940(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	info := ImportMT940(
+		context.Background(),
+		user.ID(),
+		nil,
+		"statement.sta",
+		strings.NewReader(mt940Data),
+		s,
+		matcher.New(nil),
+	)
+	if info.Error != nil {
+		t.Fatalf("ImportMT940 failed: %v", info.Error)
+	}
+
+	if info.TotalImports != 4 {
+		t.Errorf("TotalImports = %d, want 4", info.TotalImports)
+	}
+
+	if info.ClosingBalance == nil {
+		t.Fatal("Expected closing balance to be reported")
+	}
+
+	if info.ClosingBalance.Amount != 344025 || info.ClosingBalance.Currency != "EUR" {
+		t.Errorf("ClosingBalance = %+v, want 344025 EUR", info.ClosingBalance)
+	}
+}
+
+func TestImportMT940BalanceMismatch(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	data := `:20:REF
+:60F:C240101EUR100,00
+:61:240102D10,00NMSCREF
+:86:coffee
+:62F:C240102EUR50,00
+-`
+
+	reader := strings.NewReader(data)
+	info := ImportMT940(context.Background(), user.ID(), nil, "statement.sta", reader, s, matcher.New(nil))
+	if info.Error == nil {
+		t.Fatal("Expected error for statement not matching its closing balance")
+	}
+
+	expenses, err := s.GetAllExpenseTypes(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	if len(expenses) != 0 {
+		t.Fatalf("Expected no expenses to be stored, got %d", len(expenses))
+	}
+}
+
+func TestImportMT940ImportsBalancedStatements(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	data := `:20:FIRST
+:60F:C240101EUR100,00
+:61:240102D10,00NMSCREF
+:86:coffee
+:62F:C240102EUR90,00
+-
+:20:SECOND
+:25:DE89370400440532013000
+:60F:C240102EUR90,00
+:61:240103D20,00NMSCREF
+:86:lunch
+:62F:C240103EUR50,00
+-`
+
+	info := ImportMT940(
+		context.Background(), user.ID(), nil, "statement.sta", strings.NewReader(data), s, matcher.New(nil),
+	)
+	if info.Error != nil {
+		t.Fatalf("ImportMT940 failed: %v", info.Error)
+	}
+
+	if info.TotalImports != 1 || info.RejectedStatements != 1 {
+		t.Errorf("TotalImports = %d, RejectedStatements = %d, want 1 and 1", info.TotalImports, info.RejectedStatements)
+	}
+	if !strings.Contains(info.RejectedReason, "DE89370400440532013000") {
+		t.Errorf("RejectedReason = %q, want the account of the statement", info.RejectedReason)
+	}
+	if info.ClosingBalance == nil || info.ClosingBalance.Amount != 9000 {
+		t.Errorf("ClosingBalance = %+v, want the balance of the imported statement", info.ClosingBalance)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	// Without a :25: account the default source is used
+	if len(expenses) != 1 || expenses[0].Description() != "coffee" || expenses[0].Source() != defaultMT940Source {
+		t.Fatalf("Expected only the coffee of the balanced statement, got %d expenses", len(expenses))
+	}
+}
@@ -0,0 +1,247 @@
+package importutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	storageType "github.com/GustavoCaso/expensetrace/storage"
+)
+
+// ofxDateLayout is the date part of an OFX datetime value
+// (YYYYMMDD[HHMMSS[.XXX]][[gmt offset[:tz name]]]).
+const ofxDateLayout = "20060102"
+
+// defaultOFXSource is used when the statement does not carry an <ORG> element.
+const defaultOFXSource = "OFX"
+
+// OFXTransaction is a single <STMTTRN> entry of an OFX statement.
+type OFXTransaction struct {
+	FITID   string // Financial institution transaction ID, unique per account
+	Expense domain.Expense
+}
+
+// OFXStatement holds the transactions extracted from an OFX/QFX file.
+type OFXStatement struct {
+	Source       string
+	Currency     string
+	Transactions []OFXTransaction
+}
+
+// ofxTransaction accumulates the leaf values of a <STMTTRN> aggregate.
+type ofxTransaction struct {
+	trnType  string
+	posted   string
+	amount   string
+	fitID    string
+	name     string
+	memo     string
+	currency string
+	// inOrigCurrency is set while inside <ORIGCURRENCY>, whose CURSYM
+	// describes the original currency rather than the one of TRNAMT.
+	inOrigCurrency bool
+}
+
+// ParseOFX parses an OFX 1.x (SGML) or 2.x (XML) statement. Both versions are
+// handled by the same tokenizer: SGML leaf elements have no closing tag, so
+// every element carrying a value is treated as a leaf and only aggregate
+// closing tags are relevant. Transactions sharing a FITID are only returned
+// once, and the FITID is kept as the external ID of their expense so later
+// imports recognize them.
+func ParseOFX(reader io.Reader, categoryMatcher *matcher.Matcher) (*OFXStatement, error) {
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading OFX: %w", err)
+	}
+
+	body := string(raw)
+	start := strings.Index(strings.ToUpper(body), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("OFX file has no <OFX> element")
+	}
+	body = body[start:]
+
+	statement := &OFXStatement{}
+	seen := map[string]bool{}
+	var current *ofxTransaction
+	var pending []ofxTransaction
+
+	for {
+		open := strings.IndexByte(body, '<')
+		if open < 0 {
+			break
+		}
+		closing := strings.IndexByte(body[open:], '>')
+		if closing < 0 {
+			return nil, errors.New("OFX file has an unterminated tag")
+		}
+
+		tag := strings.ToUpper(strings.TrimSpace(body[open+1 : open+closing]))
+		body = body[open+closing+1:]
+
+		value := body
+		if next := strings.IndexByte(body, '<'); next >= 0 {
+			value = body[:next]
+		}
+		value = strings.TrimSpace(html.UnescapeString(value))
+
+		switch {
+		case tag == "" || strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!"):
+			continue
+		case tag == "/STMTTRN":
+			if current != nil {
+				pending = append(pending, *current)
+				current = nil
+			}
+		case current != nil && (tag == "ORIGCURRENCY" || tag == "/ORIGCURRENCY"):
+			current.inOrigCurrency = tag == "ORIGCURRENCY"
+		case strings.HasPrefix(tag, "/"):
+			continue
+		case tag == "STMTTRN":
+			current = &ofxTransaction{}
+		case value != "":
+			if current != nil {
+				current.set(tag, value)
+				continue
+			}
+			switch tag {
+			case "CURDEF":
+				statement.Currency = value
+			case "ORG":
+				statement.Source = value
+			}
+		}
+	}
+
+	if statement.Source == "" {
+		statement.Source = defaultOFXSource
+	}
+
+	for _, trn := range pending {
+		if trn.fitID != "" {
+			if seen[trn.fitID] {
+				continue
+			}
+			seen[trn.fitID] = true
+		}
+
+		expense, trnErr := trn.toExpense(statement, categoryMatcher)
+		if trnErr != nil {
+			return nil, trnErr
+		}
+
+		statement.Transactions = append(statement.Transactions, OFXTransaction{
+			FITID:   trn.fitID,
+			Expense: expense,
+		})
+	}
+
+	return statement, nil
+}
+
+// ImportOFX parses an OFX/QFX statement and stores its transactions.
+func ImportOFX(
+	ctx context.Context,
+	userID int64,
+	accountID *int64,
+	filename string,
+	reader io.Reader,
+	storage storageType.Storage,
+	categoryMatcher *matcher.Matcher,
+) ImportInfo {
+	statement, err := ParseOFX(reader, categoryMatcher)
+	if err != nil {
+		return ImportInfo{Error: err}
+	}
+
+	expenses := make([]domain.Expense, 0, len(statement.Transactions))
+	for _, trn := range statement.Transactions {
+		expenses = append(expenses, trn.Expense)
+	}
+
+	batch := newImportBatch(filename, FormatOFX, len(statement.Transactions))
+	return storeExpenses(ctx, userID, accountID, batch, expenses, storage)
+}
+
+func (t *ofxTransaction) set(tag, value string) {
+	switch tag {
+	case "TRNTYPE":
+		t.trnType = value
+	case "DTPOSTED":
+		t.posted = value
+	case "TRNAMT":
+		t.amount = value
+	case "FITID":
+		t.fitID = value
+	case "NAME":
+		t.name = value
+	case "MEMO":
+		t.memo = value
+	case "CURSYM":
+		if !t.inOrigCurrency {
+			t.currency = value
+		}
+	}
+}
+
+func (t *ofxTransaction) toExpense(
+	statement *OFXStatement,
+	categoryMatcher *matcher.Matcher,
+) (domain.Expense, error) {
+	if len(t.posted) < len(ofxDateLayout) {
+		return nil, fmt.Errorf("invalid OFX date %q for transaction %q", t.posted, t.fitID)
+	}
+	date, err := time.Parse(ofxDateLayout, t.posted[:len(ofxDateLayout)])
+	if err != nil {
+		return nil, fmt.Errorf("invalid OFX date %q for transaction %q: %w", t.posted, t.fitID, err)
+	}
+
+	amount, err := parseDecimalAmount(t.amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OFX amount %q for transaction %q: %w", t.amount, t.fitID, err)
+	}
+
+	currency := t.currency
+	if currency == "" {
+		currency = statement.Currency
+	}
+	if currency == "" {
+		return nil, fmt.Errorf("no currency found for transaction %q", t.fitID)
+	}
+
+	description := t.name
+	if t.memo != "" && !strings.EqualFold(t.memo, t.name) {
+		description = strings.TrimSpace(description + " " + t.memo)
+	}
+	if description == "" {
+		description = t.trnType
+	}
+	description = strings.ToLower(description)
+
+	var et domain.ExpenseType
+	if amount < 0 {
+		et = domain.ChargeType
+	} else {
+		et = domain.IncomeType
+	}
+
+	categoryID, _ := categoryMatcher.Match(description)
+
+	return domain.WithExternalID(domain.NewExpense(
+		0,
+		statement.Source,
+		description,
+		currency,
+		amount,
+		date,
+		et,
+		categoryID,
+		nil,
+	), t.fitID), nil
+}
@@ -0,0 +1,292 @@
+package importutil
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+const sgmlOFX = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+<OFX>
+<SIGNONMSGSRSV1>
+<SONRS>
+<STATUS><CODE>0<SEVERITY>INFO</STATUS>
+<DTSERVER>20240131120000
+<LANGUAGE>ENG
+<FI><ORG>MyBank<FID>1234</FI>
+</SONRS>
+</SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<TRNUID>1
+<STMTRS>
+<CURDEF>EUR
+<BANKACCTFROM><BANKID>0001<ACCTID>123456<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240101
+<DTEND>20240131
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240105120000.000[-5:EST]
+<TRNAMT>-12.5
+<FITID>T1
+<NAME>Restaurant &amp; Bar
+<MEMO>Card payment
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240110
+<TRNAMT>2500,00
+<FITID>T2
+<NAME>Salary
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240105120000.000[-5:EST]
+<TRNAMT>-12.5
+<FITID>T1
+<NAME>Restaurant &amp; Bar
+<MEMO>Card payment
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1000.00<DTASOF>20240131</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>`
+
+const xmlOFX = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <TRNUID>1</TRNUID>
+      <CCSTMTRS>
+        <CURDEF>USD</CURDEF>
+        <CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240215</DTPOSTED>
+            <TRNAMT>-42.10</TRNAMT>
+            <FITID>X1</FITID>
+            <NAME>Uber</NAME>
+            <MEMO>Uber</MEMO>
+          </STMTTRN>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240216</DTPOSTED>
+            <TRNAMT>-20.00</TRNAMT>
+            <FITID>X2</FITID>
+            <NAME>Hotel</NAME>
+            <CURRENCY><CURRATE>1.1</CURRATE><CURSYM>GBP</CURSYM></CURRENCY>
+          </STMTTRN>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240217</DTPOSTED>
+            <TRNAMT>-30.00</TRNAMT>
+            <FITID>X3</FITID>
+            <NAME>Museum</NAME>
+            <ORIGCURRENCY><CURRATE>1.1</CURRATE><CURSYM>GBP</CURSYM></ORIGCURRENCY>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>`
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name                 string
+		data                 string
+		expectedSource       string
+		expectedFITIDs       []string
+		expectedAmounts      []int64
+		expectedCurrencies   []string
+		expectedDescriptions []string
+		expectedDates        []time.Time
+	}{
+		{
+			name:               "SGML 1.x",
+			data:               sgmlOFX,
+			expectedSource:     "MyBank",
+			expectedFITIDs:     []string{"T1", "T2"},
+			expectedAmounts:    []int64{-1250, 250000},
+			expectedCurrencies: []string{"EUR", "EUR"},
+			expectedDescriptions: []string{
+				"restaurant & bar card payment",
+				"salary",
+			},
+			expectedDates: []time.Time{
+				time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:               "XML 2.x",
+			data:               xmlOFX,
+			expectedSource:     defaultOFXSource,
+			expectedFITIDs:     []string{"X1", "X2", "X3"},
+			expectedAmounts:    []int64{-4210, -2000, -3000},
+			expectedCurrencies: []string{"USD", "GBP", "USD"},
+			expectedDescriptions: []string{
+				"uber",
+				"hotel",
+				"museum",
+			},
+			expectedDates: []time.Time{
+				time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 2, 16, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement, err := ParseOFX(strings.NewReader(tt.data), matcher.New(nil))
+			if err != nil {
+				t.Fatalf("ParseOFX failed: %v", err)
+			}
+
+			if statement.Source != tt.expectedSource {
+				t.Errorf("Source = %q, want %q", statement.Source, tt.expectedSource)
+			}
+
+			if len(statement.Transactions) != len(tt.expectedFITIDs) {
+				t.Fatalf("Expected %d transactions, got %d", len(tt.expectedFITIDs), len(statement.Transactions))
+			}
+
+			for i, trn := range statement.Transactions {
+				if trn.FITID != tt.expectedFITIDs[i] {
+					t.Errorf("Transaction[%d].FITID = %q, want %q", i, trn.FITID, tt.expectedFITIDs[i])
+				}
+				if trn.Expense.Amount() != tt.expectedAmounts[i] {
+					t.Errorf("Transaction[%d].Amount = %d, want %d", i, trn.Expense.Amount(), tt.expectedAmounts[i])
+				}
+				if trn.Expense.Currency() != tt.expectedCurrencies[i] {
+					t.Errorf(
+						"Transaction[%d].Currency = %q, want %q",
+						i,
+						trn.Expense.Currency(),
+						tt.expectedCurrencies[i],
+					)
+				}
+				if trn.Expense.Description() != tt.expectedDescriptions[i] {
+					t.Errorf(
+						"Transaction[%d].Description = %q, want %q",
+						i,
+						trn.Expense.Description(),
+						tt.expectedDescriptions[i],
+					)
+				}
+				if !trn.Expense.Date().Equal(tt.expectedDates[i]) {
+					t.Errorf("Transaction[%d].Date = %v, want %v", i, trn.Expense.Date(), tt.expectedDates[i])
+				}
+				if trn.Expense.Source() != tt.expectedSource {
+					t.Errorf("Transaction[%d].Source = %q, want %q", i, trn.Expense.Source(), tt.expectedSource)
+				}
+			}
+		})
+	}
+}
+
+func TestParseOFXErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "no OFX element",
+			data: "date,description,amount\n2024-01-01,coffee,-1.00",
+		},
+		{
+			name: "invalid date",
+			data: "<OFX><CURDEF>EUR<STMTTRN><DTPOSTED>2024<TRNAMT>-1.00<FITID>1</STMTTRN></OFX>",
+		},
+		{
+			name: "invalid amount",
+			data: "<OFX><CURDEF>EUR<STMTTRN><DTPOSTED>20240101<TRNAMT>abc<FITID>1</STMTTRN></OFX>",
+		},
+		{
+			name: "missing currency",
+			data: "<OFX><STMTTRN><DTPOSTED>20240101<TRNAMT>-1.00<FITID>1</STMTTRN></OFX>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseOFX(strings.NewReader(tt.data), matcher.New(nil))
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestImportOFX(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	categories := []domain.Category{
+		domain.NewCategory(1, "Food", "restaurant", 0),
+	}
+	for _, c := range categories {
+		_, err := s.CreateCategory(context.Background(), user.ID(), c.Name(), c.Pattern(), 0)
+		if err != nil {
+			t.Fatalf("Failed to create category: %v", err)
+		}
+	}
+
+	info := ImportOFX(
+		context.Background(),
+		user.ID(),
+		nil,
+		"statement.ofx",
+		strings.NewReader(sgmlOFX),
+		s,
+		matcher.New(categories),
+	)
+	if info.Error != nil {
+		t.Fatalf("ImportOFX failed: %v", info.Error)
+	}
+
+	if info.TotalImports != 2 {
+		t.Errorf("TotalImports = %d, want 2", info.TotalImports)
+	}
+
+	if info.ImportWithoutCategory != 1 {
+		t.Errorf("ImportWithoutCategory = %d, want 1", info.ImportWithoutCategory)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	if len(expenses) != 2 {
+		t.Fatalf("Expected 2 expenses, got %d", len(expenses))
+	}
+
+	if expenses[0].Type() != domain.ChargeType {
+		t.Errorf("Expense[0].Type = %v, want ChargeType", expenses[0].Type())
+	}
+
+	if expenses[1].Type() != domain.IncomeType {
+		t.Errorf("Expense[1].Type = %v, want IncomeType", expenses[1].Type())
+	}
+}
@@ -1,106 +1,360 @@
 package importutil
 
 import (
+	"crypto/sha256"
 	"encoding/csv"
-	"encoding/json"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"path"
+	"strings"
+	"unicode/utf8"
 )
 
 // ParsedData represents the raw data extracted from a file.
 type ParsedData struct {
-	Headers []string   // Column headers/field names
-	Rows    [][]string // Data rows (all values as strings)
-	Format  string     // File format (csv or json)
+	Headers []string // Column headers/field names
+	// Rows holds the first data rows, up to SampleRows, all values as
+	// strings. Files are read in full with OpenFile.
+	Rows   [][]string
+	Format string // File format (csv, json, xlsx or ods)
+	// Sheets lists the sheets of a spreadsheet, Sheet being the one read.
+	Sheets []string
+	Sheet  string
+	// HeaderRow is the number of rows skipped before the header row.
+	HeaderRow int
+	// Delimiter and Encoding are the CSV dialect the file was read with.
+	Delimiter string
+	Encoding  string
+	// RecordsPath selects the records of a JSON file.
+	RecordsPath string
+
+	totalRows int
+}
+
+// SampleRows is the number of data rows kept from a file to preview it and
+// detect its formats, bounding the memory used by large files.
+const SampleRows = 1000
+
+// headerSampleRecords is the number of CSV records read to find the header
+// row.
+const headerSampleRecords = 100
+
+// RowReader reads the data rows of a file one at a time.
+type RowReader interface {
+	// Read returns the next data row, or io.EOF after the last one.
+	Read() ([]string, error)
 }
 
-// ParseFile parses a CSV or JSON file and extracts headers and rows
-// without making assumptions about structure or field mapping.
+// ParseOptions choose how a file is read and which part of it holds the
+// table to import. Empty values are detected from the file.
+type ParseOptions struct {
+	// Sheet is the spreadsheet sheet to read, the first one when empty.
+	Sheet string
+	// HeaderRow is the number of rows before the header row, for files
+	// starting with a title or account details, or AutoHeaderRow. Ignored
+	// for JSON.
+	HeaderRow int
+	// Delimiter separates the fields of CSV files, one of Delimiters.
+	Delimiter string
+	// Encoding is the character set of CSV files, one of Encodings.
+	Encoding string
+	// RecordsPath selects the records of JSON files, such as
+	// data.transactions[*]. Found in the document when empty.
+	RecordsPath string
+}
+
+// Options returns the options reading the file the same way again.
+func (p *ParsedData) Options() ParseOptions {
+	return ParseOptions{
+		Sheet:       p.Sheet,
+		HeaderRow:   p.HeaderRow,
+		Delimiter:   p.Delimiter,
+		Encoding:    p.Encoding,
+		RecordsPath: p.RecordsPath,
+	}
+}
+
+// ParseFile parses a file and extracts headers and rows without making
+// assumptions about structure or field mapping. The CSV dialect and the
+// header row are detected.
 func ParseFile(filename string, reader io.Reader) (*ParsedData, error) {
+	return ParseFileWithOptions(filename, reader, ParseOptions{HeaderRow: AutoHeaderRow})
+}
+
+// ParseFileWithOptions parses a CSV, JSON, XLSX or ODS file reading the
+// table chosen by options. Only the first SampleRows rows are kept, the
+// rest are counted.
+func ParseFileWithOptions(filename string, reader io.Reader, options ParseOptions) (*ParsedData, error) {
+	data, rows, err := OpenFile(filename, reader, options)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		row, readErr := rows.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("error reading %s: %w", strings.ToUpper(data.Format), readErr)
+		}
+
+		if len(data.Rows) < SampleRows {
+			data.Rows = append(data.Rows, row)
+		}
+		data.totalRows++
+	}
+
+	return data, nil
+}
+
+// OpenFile reads the header of a CSV, JSON, XLSX or ODS file and returns a
+// reader of its data rows. CSV files are read as rows are requested, so
+// files of any size can be imported; the other formats are read whole.
+// The returned data holds no rows.
+func OpenFile(filename string, reader io.Reader, options ParseOptions) (*ParsedData, RowReader, error) {
 	fileFormat := path.Ext(filename)
 
+	if options.HeaderRow < AutoHeaderRow {
+		return nil, nil, fmt.Errorf("invalid header row %d", options.HeaderRow)
+	}
+
+	if fileFormat == ".csv" {
+		return openCSV(reader, options)
+	}
+
+	var data *ParsedData
+	var err error
 	switch fileFormat {
-	case ".csv":
-		return parseCSV(reader)
 	case ".json":
-		return parseJSON(reader)
+		data, err = parseJSON(reader, options)
+	case ".xlsx":
+		data, err = parseXLSX(reader, options)
+	case ".ods":
+		data, err = parseODS(reader, options)
 	default:
-		return nil, fmt.Errorf("unsupported file format: %s", fileFormat)
+		return nil, nil, fmt.Errorf("unsupported file format: %s", fileFormat)
+	}
+	if err != nil {
+		return nil, nil, err
 	}
-}
 
-// parseCSV reads CSV data and extracts headers and rows.
-func parseCSV(reader io.Reader) (*ParsedData, error) {
-	r := csv.NewReader(reader)
+	rows := &sliceRows{rows: data.Rows}
+	data.Rows = nil
+	return data, rows, nil
+}
 
-	// Read all records
-	records, err := r.ReadAll()
+// openCSV reads the start of CSV data to find its dialect and header row.
+func openCSV(reader io.Reader, options ParseOptions) (*ParsedData, RowReader, error) {
+	text, encoding, err := decodeReader(reader, options.Encoding)
 	if err != nil {
-		return nil, fmt.Errorf("error reading CSV: %w", err)
+		return nil, nil, err
+	}
+
+	delimiter := options.Delimiter
+	if delimiter == "" {
+		sample, _ := text.Peek(dialectSampleSize)
+		delimiter = sniffDelimiter(string(sample))
+	}
+	if utf8.RuneCountInString(delimiter) != 1 {
+		return nil, nil, fmt.Errorf("invalid delimiter %q", delimiter)
+	}
+
+	r := csv.NewReader(text)
+	r.Comma, _ = utf8.DecodeRuneInString(delimiter)
+	// Title rows before the header have fewer fields, they must not stop
+	// the preview so users can skip them
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	// Read the records the header is looked for in
+	sampleSize := max(headerSampleRecords, options.HeaderRow+2)
+	records := make([][]string, 0, sampleSize)
+	for len(records) < sampleSize {
+		record, readErr := r.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return nil, nil, fmt.Errorf("error reading CSV: %w", readErr)
+		}
+		records = append(records, record)
 	}
 
 	if len(records) == 0 {
-		return nil, errors.New("CSV file is empty")
+		return nil, nil, errors.New("CSV file is empty")
+	}
+
+	data, err := tableFromRecords(records, options.HeaderRow)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CSV file %w", err)
+	}
+
+	rows := &csvRows{
+		sampled: data.Rows,
+		reader:  r,
+		width:   len(data.Headers),
 	}
 
-	// First row is headers
-	headers := records[0]
+	data.Rows = nil
+	data.Format = "csv"
+	data.Delimiter = delimiter
+	data.Encoding = encoding
+	return data, rows, nil
+}
+
+// csvRows reads the data rows of a CSV file, starting with the ones read
+// while looking for the header.
+type csvRows struct {
+	sampled [][]string
+	reader  *csv.Reader
+	width   int
+}
+
+func (c *csvRows) Read() ([]string, error) {
+	if len(c.sampled) > 0 {
+		row := c.sampled[0]
+		c.sampled = c.sampled[1:]
+		return row, nil
+	}
+
+	record, err := c.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	return padRow(record, c.width), nil
+}
+
+// sliceRows reads data rows already in memory.
+type sliceRows struct {
+	rows [][]string
+}
 
-	// Remaining rows are data
-	rows := records[1:]
+func (s *sliceRows) Read() ([]string, error) {
+	if len(s.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+// tableFromRecords splits records into the header row, found after
+// headerRow rows or detected, and the data rows. Rows are padded to the
+// width of the table so every column can be read from every row.
+func tableFromRecords(records [][]string, headerRow int) (*ParsedData, error) {
+	if headerRow == AutoHeaderRow {
+		headerRow = detectHeaderRow(records)
+	}
+
+	if headerRow >= len(records) {
+		return nil, fmt.Errorf("has no header row after skipping %d rows", headerRow)
+	}
+
+	headers := records[headerRow]
+	rows := records[headerRow+1:]
 
 	if len(rows) == 0 {
-		return nil, errors.New("CSV file has no data rows")
+		return nil, errors.New("has no data rows")
+	}
+
+	width := len(headers)
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	headers = padRow(headers, width)
+	for i, header := range headers {
+		if strings.TrimSpace(header) == "" {
+			headers[i] = fmt.Sprintf("Column %d", i+1)
+		}
+	}
+
+	for i, row := range rows {
+		rows[i] = padRow(row, width)
 	}
 
 	return &ParsedData{
-		Headers: headers,
-		Rows:    rows,
-		Format:  "csv",
+		Headers:   headers,
+		Rows:      rows,
+		HeaderRow: headerRow,
 	}, nil
 }
 
-// parseJSON reads JSON data and extracts headers and rows.
-// Expects an array of objects with consistent fields.
-func parseJSON(reader io.Reader) (*ParsedData, error) {
-	var data []map[string]interface{}
+func padRow(row []string, width int) []string {
+	if len(row) >= width {
+		return row
+	}
+	padded := make([]string, width)
+	copy(padded, row)
+	return padded
+}
 
-	decoder := json.NewDecoder(reader)
-	if err := decoder.Decode(&data); err != nil {
+// parseJSON reads the records of a JSON document, the objects selected by
+// the records path. Nested fields become columns named by their path, such
+// as amount.value, taken from every record in the order they first appear.
+func parseJSON(reader io.Reader, options ParseOptions) (*ParsedData, error) {
+	root, err := decodeOrderedJSON(reader)
+	if err != nil {
 		return nil, fmt.Errorf("error parsing JSON: %w", err)
 	}
 
-	if len(data) == 0 {
-		return nil, errors.New("JSON file contains no records")
+	recordsPath := options.RecordsPath
+	if recordsPath == "" {
+		var found bool
+		recordsPath, found = findJSONRecords(root)
+		if !found {
+			return nil, errors.New("JSON file contains no records")
+		}
+	}
+
+	records, err := selectJSONRecords(root, recordsPath)
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract headers from first object's keys
-	headers := make([]string, 0, len(data[0]))
-	for key := range data[0] {
-		headers = append(headers, key)
+	var headers []string
+	columns := map[string]int{}
+	values := make([]map[int]string, 0, len(records))
+	for i, record := range records {
+		if _, ok := record.(*jsonObject); !ok {
+			return nil, fmt.Errorf("record %d selected by %q is not an object", i+1, recordsPath)
+		}
+
+		recordValues := map[int]string{}
+		flattenJSONRecord("", record, func(column, value string) {
+			index, seen := columns[column]
+			if !seen {
+				index = len(headers)
+				columns[column] = index
+				headers = append(headers, column)
+			}
+			recordValues[index] = value
+		})
+		values = append(values, recordValues)
+	}
+
+	if len(headers) == 0 {
+		return nil, errors.New("JSON file contains no records")
 	}
 
 	// Convert all records to string rows
-	rows := make([][]string, 0, len(data))
-	for _, record := range data {
+	rows := make([][]string, 0, len(values))
+	for _, recordValues := range values {
 		row := make([]string, len(headers))
-		for i, header := range headers {
-			// Convert any type to string
-			if val, ok := record[header]; ok && val != nil {
-				row[i] = fmt.Sprintf("%v", val)
-			} else {
-				row[i] = ""
-			}
+		for index, value := range recordValues {
+			row[index] = value
 		}
 		rows = append(rows, row)
 	}
 
 	return &ParsedData{
-		Headers: headers,
-		Rows:    rows,
-		Format:  "json",
+		Headers:     headers,
+		Rows:        rows,
+		Format:      "json",
+		RecordsPath: recordsPath,
 	}, nil
 }
 
@@ -114,5 +368,18 @@ func (p *ParsedData) GetPreviewRows(n int) [][]string {
 
 // GetTotalRows returns the total number of data rows.
 func (p *ParsedData) GetTotalRows() int {
-	return len(p.Rows)
+	return max(p.totalRows, len(p.Rows))
+}
+
+// HeaderFingerprint identifies a file layout by its header row, so files
+// exported by the same bank can be recognized. Case and surrounding spaces
+// are ignored.
+func HeaderFingerprint(headers []string) string {
+	normalized := make([]string, len(headers))
+	for i, h := range headers {
+		normalized[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(normalized, "\x1f")))
+	return hex.EncodeToString(sum[:])
 }
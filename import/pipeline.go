@@ -0,0 +1,376 @@
+package importutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	storageType "github.com/GustavoCaso/expensetrace/storage"
+)
+
+// importChunkSize is the number of expenses checked for duplicates and
+// inserted at a time, bounding the memory used by large imports.
+const importChunkSize = 1000
+
+// KeepDuplicate decides whether a duplicate read from a data row is
+// imported.
+type KeepDuplicate func(row int, d Duplicate) bool
+
+// KeepLikelyDuplicates imports likely duplicates and skips exact ones, the
+// choice made for files imported without a review step. Likely duplicates
+// are only similar to a stored expense, like a recurring charge a day after
+// the last one, so they are imported and reported rather than lost.
+func KeepLikelyDuplicates(_ int, d Duplicate) bool {
+	return d.Status == domain.DuplicateLikely
+}
+
+// BatchImporter stores the expenses of an import batch as they are read
+// from a file. Expenses are checked for duplicates and inserted a chunk at
+// a time, all in one transaction, so the file never needs to be held in
+// memory and a failed import stores nothing.
+type BatchImporter struct {
+	ctx    context.Context
+	writer storageType.ImportBatchWriter
+	keep   KeepDuplicate
+	// accountID is the account the expenses are stored in, nil to keep the
+	// account they were read with.
+	accountID *int64
+
+	chunk      []domain.Expense
+	chunkRows  []int
+	duplicates duplicateTracker
+
+	info ImportInfo
+}
+
+// duplicateTracker classifies the expenses of a file a chunk at a time.
+type duplicateTracker struct {
+	// matched holds the stored expenses already matched as duplicates, so
+	// each one is matched once across chunks.
+	matched map[int64]bool
+}
+
+func newDuplicateTracker() duplicateTracker {
+	return duplicateTracker{matched: map[int64]bool{}}
+}
+
+// detect classifies a chunk against the stored expenses returned by
+// existingIn that no earlier chunk matched.
+func (t duplicateTracker) detect(
+	chunk []domain.Expense,
+	existingIn func(start, end time.Time) ([]domain.Expense, error),
+) ([]Duplicate, error) {
+	duplicates, err := detectDuplicates(chunk, func(start, end time.Time) ([]domain.Expense, error) {
+		existing, lookupErr := existingIn(start, end)
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+
+		unmatched := existing[:0]
+		for _, e := range existing {
+			if !t.matched[e.ID()] {
+				unmatched = append(unmatched, e)
+			}
+		}
+		return unmatched, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range duplicates {
+		if d.Existing != nil {
+			t.matched[d.Existing.ID()] = true
+		}
+	}
+	return duplicates, nil
+}
+
+// NewBatchImporter starts storing an import batch in the given account, or
+// in none when accountID is nil. Callers Add the expenses read and then
+// Finish, or Abort on failure.
+func NewBatchImporter(
+	ctx context.Context,
+	userID int64,
+	accountID *int64,
+	storage storageType.Storage,
+	batch domain.ImportBatch,
+	keep KeepDuplicate,
+) (*BatchImporter, error) {
+	writer, err := storage.BeginImportBatch(ctx, userID, batch)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error inserting expenses: %w", err)
+	}
+
+	return &BatchImporter{
+		ctx:        ctx,
+		writer:     writer,
+		keep:       keep,
+		accountID:  accountID,
+		chunk:      make([]domain.Expense, 0, importChunkSize),
+		chunkRows:  make([]int, 0, importChunkSize),
+		duplicates: newDuplicateTracker(),
+	}, nil
+}
+
+// Add queues an expense read from the given data row, storing the queued
+// expenses once a chunk is full.
+func (b *BatchImporter) Add(row int, expense domain.Expense) error {
+	if b.accountID != nil {
+		expense = withAccount(expense, b.accountID)
+	}
+
+	b.chunk = append(b.chunk, expense)
+	b.chunkRows = append(b.chunkRows, row)
+
+	if len(b.chunk) < importChunkSize {
+		return nil
+	}
+	return b.flush()
+}
+
+// Finish stores the queued expenses and commits the batch, recording
+// rowCount rows read from the file.
+func (b *BatchImporter) Finish(rowCount int) (ImportInfo, error) {
+	if err := b.flush(); err != nil {
+		b.Abort()
+		return b.info, err
+	}
+
+	stored, err := b.writer.Commit(b.ctx, rowCount, b.info.SkippedDuplicates)
+	if err != nil {
+		b.Abort()
+		return b.info, fmt.Errorf("unexpected error inserting expenses: %w", err)
+	}
+
+	b.info.TotalImports = stored.Imported()
+	b.info.ImportBatchID = stored.ID()
+	return b.info, nil
+}
+
+// Abort discards the batch.
+func (b *BatchImporter) Abort() {
+	_ = b.writer.Rollback()
+}
+
+// existingExpenses returns the stored expenses dated between start and end,
+// along with the ones sharing an external ID with the queued expenses, so a
+// transaction the bank moved to another date is still recognized.
+func (b *BatchImporter) existingExpenses(start, end time.Time) ([]domain.Expense, error) {
+	existing, err := b.writer.GetExpensesFromDateRange(b.ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var externalIDs []string
+	for _, e := range b.chunk {
+		if e.ExternalID() != "" {
+			externalIDs = append(externalIDs, e.ExternalID())
+		}
+	}
+	if len(externalIDs) == 0 {
+		return existing, nil
+	}
+
+	sameID, err := b.writer.GetExpensesByExternalIDs(b.ctx, externalIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := map[int64]bool{}
+	for _, e := range existing {
+		loaded[e.ID()] = true
+	}
+	for _, e := range sameID {
+		if !loaded[e.ID()] {
+			existing = append(existing, e)
+		}
+	}
+	return existing, nil
+}
+
+func (b *BatchImporter) flush() error {
+	if len(b.chunk) == 0 {
+		return nil
+	}
+
+	duplicates, err := b.duplicates.detect(b.chunk, b.existingExpenses)
+	if err != nil {
+		return err
+	}
+
+	expenses := make([]domain.Expense, 0, len(b.chunk))
+	for i, e := range b.chunk {
+		d := duplicates[i]
+		switch d.Status {
+		case domain.DuplicateExact:
+			b.info.ExactDuplicates++
+		case domain.DuplicateLikely:
+			b.info.LikelyDuplicates++
+		}
+
+		if d.Status != domain.DuplicateNone && !b.keep(b.chunkRows[i], d) {
+			b.info.SkippedDuplicates++
+			continue
+		}
+
+		if e.CategoryID() == nil {
+			b.info.ImportWithoutCategory++
+		}
+		expenses = append(expenses, e)
+	}
+
+	if _, err = b.writer.InsertExpenses(b.ctx, expenses); err != nil {
+		return fmt.Errorf("unexpected error inserting expenses: %w", err)
+	}
+
+	b.chunk = b.chunk[:0]
+	b.chunkRows = b.chunkRows[:0]
+	return nil
+}
+
+// ImportRows maps the rows read from a file and stores them as an import
+// batch in the account of the mapping, a chunk at a time. The mapping must
+// name the date layout to use, or none to try the default formats. Rows
+// that cannot be mapped are left out and counted in InvalidRows.
+func ImportRows(
+	ctx context.Context,
+	userID int64,
+	storage storageType.Storage,
+	batch domain.ImportBatch,
+	rows RowReader,
+	mapping *FieldMapping,
+	categoryMatcher *matcher.Matcher,
+	keep KeepDuplicate,
+) ImportInfo {
+	importer, err := NewBatchImporter(ctx, userID, mapping.AccountID, storage, batch, keep)
+	if err != nil {
+		return ImportInfo{Error: err}
+	}
+
+	rowCount := 0
+	invalidRows := 0
+	for ; ; rowCount++ {
+		row, readErr := rows.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			importer.Abort()
+			return ImportInfo{Error: fmt.Errorf("error reading row %d: %w", rowCount+1, readErr)}
+		}
+
+		expense, mapErr := mapRow(row, mapping, mapping.DateLayout, categoryMatcher)
+		if mapErr != nil {
+			invalidRows++
+			continue
+		}
+
+		if err = importer.Add(rowCount, expense); err != nil {
+			importer.Abort()
+			return ImportInfo{Error: err}
+		}
+	}
+
+	info, err := importer.Finish(rowCount)
+	info.InvalidRows = invalidRows
+	info.Error = err
+	return info
+}
+
+// FlaggedRow is a data row matching a stored expense.
+type FlaggedRow struct {
+	// Row is the data row the expense was read from.
+	Row       int
+	Expense   domain.Expense
+	Duplicate Duplicate
+}
+
+// RowClassification is what importing the rows of a file would do.
+type RowClassification struct {
+	Errors     []mappingError
+	Duplicates []FlaggedRow
+	Counts     DuplicateCounts
+}
+
+// ClassifyRows maps every row read from a file and classifies the expenses
+// against the stored ones, a chunk at a time like ImportRows, without
+// storing anything. The mapping must name the date layout to use, or none
+// to try the default formats.
+func ClassifyRows(
+	ctx context.Context,
+	userID int64,
+	storage storageType.Storage,
+	rows RowReader,
+	mapping *FieldMapping,
+	categoryMatcher *matcher.Matcher,
+) (*RowClassification, error) {
+	result := &RowClassification{Errors: []mappingError{}, Duplicates: []FlaggedRow{}}
+	tracker := newDuplicateTracker()
+	chunk := make([]domain.Expense, 0, importChunkSize)
+	chunkRows := make([]int, 0, importChunkSize)
+
+	classify := func() error {
+		duplicates, err := tracker.detect(chunk, func(start, end time.Time) ([]domain.Expense, error) {
+			return storage.GetExpensesFromDateRange(ctx, userID, start, end)
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, d := range duplicates {
+			switch d.Status {
+			case domain.DuplicateExact:
+				result.Counts.Exact++
+			case domain.DuplicateLikely:
+				result.Counts.Likely++
+			case domain.DuplicateNone:
+				result.Counts.New++
+				continue
+			}
+			result.Duplicates = append(result.Duplicates, FlaggedRow{
+				Row:       chunkRows[i],
+				Expense:   chunk[i],
+				Duplicate: d,
+			})
+		}
+
+		chunk = chunk[:0]
+		chunkRows = chunkRows[:0]
+		return nil
+	}
+
+	for rowCount := 0; ; rowCount++ {
+		row, readErr := rows.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("error reading row %d: %w", rowCount+1, readErr)
+		}
+
+		expense, mapErr := mapRow(row, mapping, mapping.DateLayout, categoryMatcher)
+		if mapErr != nil {
+			result.Errors = append(result.Errors, mappingError{RowIndex: rowCount, Error: mapErr})
+			continue
+		}
+
+		chunk = append(chunk, expense)
+		chunkRows = append(chunkRows, rowCount)
+		if len(chunk) < importChunkSize {
+			continue
+		}
+		if err := classify(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := classify(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,143 @@
+package importutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+// largeCSV returns a file with rows data rows, one coffee a day.
+func largeCSV(rows int) string {
+	var b strings.Builder
+	b.WriteString("date,description,amount,currency\n")
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range rows {
+		fmt.Fprintf(&b, "%s,Coffee %d,-3.50,EUR\n", start.AddDate(0, 0, i).Format("2006-01-02"), i)
+	}
+	return b.String()
+}
+
+func TestParseFileKeepsASample(t *testing.T) {
+	const rows = SampleRows*2 + 10
+
+	data, err := ParseFile("large.csv", strings.NewReader(largeCSV(rows)))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	if len(data.Rows) != SampleRows {
+		t.Errorf("Kept %d rows, want %d", len(data.Rows), SampleRows)
+	}
+	if data.GetTotalRows() != rows {
+		t.Errorf("GetTotalRows() = %d, want %d", data.GetTotalRows(), rows)
+	}
+}
+
+func TestImportRowsInChunks(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	const rows = importChunkSize*2 + 5
+
+	// The first and the last coffees are already stored, in different chunks
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	stored := []domain.Expense{
+		domain.NewExpense(0, "Bank", "coffee 0", "EUR", -350, start, domain.ChargeType, nil, nil),
+		domain.NewExpense(
+			0, "Bank", fmt.Sprintf("coffee %d", rows-1), "EUR", -350,
+			start.AddDate(0, 0, rows-1), domain.ChargeType, nil,
+			nil,
+		),
+	}
+	if _, err := s.InsertExpenses(ctx, user.ID(), stored); err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	data, rowReader, err := OpenFile("large.csv", strings.NewReader(largeCSV(rows)), ParseOptions{})
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	if len(data.Rows) != 0 {
+		t.Errorf("OpenFile should not keep rows, got %d", len(data.Rows))
+	}
+
+	mapping := &FieldMapping{
+		Source:            "Bank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+		DateLayout:        "2006-01-02",
+	}
+
+	info := ImportRows(
+		ctx,
+		user.ID(),
+		s,
+		newImportBatch("large.csv", FormatMapping, 0),
+		rowReader,
+		mapping,
+		matcher.New(nil),
+		func(int, Duplicate) bool { return false },
+	)
+	if info.Error != nil {
+		t.Fatalf("ImportRows failed: %v", info.Error)
+	}
+
+	if info.TotalImports != rows-2 || info.ExactDuplicates != 2 || info.SkippedDuplicates != 2 {
+		t.Errorf("TotalImports = %d, ExactDuplicates = %d, SkippedDuplicates = %d, want %d, 2 and 2",
+			info.TotalImports, info.ExactDuplicates, info.SkippedDuplicates, rows-2)
+	}
+
+	batches, err := s.GetImportBatches(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get import batches: %v", err)
+	}
+	if len(batches) != 1 || batches[0].ID() != info.ImportBatchID {
+		t.Fatalf("Expected the import batch %d, got %d batches", info.ImportBatchID, len(batches))
+	}
+
+	batch := batches[0]
+	if batch.RowCount() != rows || batch.Imported() != rows-2 {
+		t.Errorf("Batch rows = %d, imported = %d, want %d and %d", batch.RowCount(), batch.Imported(), rows, rows-2)
+	}
+}
+
+func TestImportRowsCountsInvalidRows(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	csvData := "date,description,amount,currency\n" +
+		"2024-01-01,Tea,-1.00,EUR\n" +
+		"yesterday,Lunch,-12.00,EUR\n"
+
+	_, rows, err := OpenFile("statement.csv", strings.NewReader(csvData), ParseOptions{})
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+
+	info := ImportRows(
+		context.Background(),
+		user.ID(),
+		s,
+		newImportBatch("statement.csv", FormatMapping, 0),
+		rows,
+		&FieldMapping{Source: "Bank", DescriptionColumn: 1, AmountColumn: 2, CurrencyColumn: 3},
+		matcher.New(nil),
+		KeepLikelyDuplicates,
+	)
+	if info.Error != nil {
+		t.Fatalf("ImportRows failed: %v", info.Error)
+	}
+
+	if info.TotalImports != 1 || info.InvalidRows != 1 {
+		t.Errorf("TotalImports = %d, InvalidRows = %d, want 1 and 1", info.TotalImports, info.InvalidRows)
+	}
+}
@@ -0,0 +1,457 @@
+package importutil
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sign conventions supported by provider definitions.
+const (
+	// SignSigned means negative amounts are charges and positive ones income.
+	SignSigned = "signed"
+	// SignInverted means the bank reports charges as positive amounts.
+	SignInverted = "inverted"
+)
+
+// builtinProviders holds the provider definitions shipped with ExpenseTrace.
+//
+//go:embed providers/*.yaml
+var builtinProviders embed.FS
+
+// ProviderDefinition describes how to read the CSV export of a bank. It is
+// loaded from YAML or JSON so new banks can be supported without writing Go
+// code.
+type ProviderDefinition struct {
+	// Name identifies the provider and is matched against the filename
+	// prefix (<name>_*.csv).
+	Name string `yaml:"name" json:"name"`
+	// Source is stored on every imported expense. Defaults to Name in title case.
+	Source string `yaml:"source" json:"source"`
+	// Headers is the exact header row of the export. When set, files with
+	// that header are recognized regardless of their name.
+	Headers []string        `yaml:"headers" json:"headers"`
+	Columns ProviderColumns `yaml:"columns" json:"columns"`
+	// DateLayout is a Go time layout, e.g. 02/01/2006.
+	DateLayout         string `yaml:"date_layout" json:"date_layout"`
+	DecimalSeparator   string `yaml:"decimal_separator" json:"decimal_separator"`
+	ThousandsSeparator string `yaml:"thousands_separator" json:"thousands_separator"`
+	// Sign is either "signed" (default) or "inverted".
+	Sign string `yaml:"sign" json:"sign"`
+	// ChargeValues lists the values of the type column marking a row as a
+	// charge, whose amount is then negated.
+	ChargeValues []string `yaml:"charge_values" json:"charge_values"`
+	// DescriptionCleanup holds regular expressions removed from the
+	// lowercased description.
+	DescriptionCleanup []string `yaml:"description_cleanup" json:"description_cleanup"`
+	// Currency is used for every row instead of a currency column.
+	Currency string `yaml:"currency" json:"currency"`
+
+	cleanup []*regexp.Regexp
+}
+
+// ProviderColumns maps expense fields to columns of the export. Only date,
+// description and amount are required.
+type ProviderColumns struct {
+	Date        *Column `yaml:"date" json:"date"`
+	Description *Column `yaml:"description" json:"description"`
+	Amount      *Column `yaml:"amount" json:"amount"`
+	Fee         *Column `yaml:"fee" json:"fee"`
+	Currency    *Column `yaml:"currency" json:"currency"`
+	Type        *Column `yaml:"type" json:"type"`
+}
+
+// Column references a CSV column either by zero-based index or by header name.
+type Column struct {
+	Index  int
+	Header string
+}
+
+// UnmarshalYAML accepts either an integer index or a header name.
+func (c *Column) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: column must be an index or a header name", value.Line)
+	}
+
+	if value.Tag == "!!int" {
+		index, err := strconv.Atoi(value.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid column index: %w", value.Line, err)
+		}
+		c.Index = index
+		return nil
+	}
+
+	c.Header = value.Value
+	return nil
+}
+
+// UnmarshalJSON accepts either an integer index or a header name.
+func (c *Column) UnmarshalJSON(data []byte) error {
+	var index int
+	if err := json.Unmarshal(data, &index); err == nil {
+		c.Index = index
+		return nil
+	}
+
+	var header string
+	if err := json.Unmarshal(data, &header); err != nil {
+		return errors.New("column must be an index or a header name")
+	}
+	c.Header = header
+	return nil
+}
+
+// resolve returns the column index within the given header row.
+func (c *Column) resolve(headers []string) (int, error) {
+	if c.Header != "" {
+		for i, h := range headers {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(c.Header)) {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("column %q not found in file header", c.Header)
+	}
+
+	if c.Index < 0 || c.Index >= len(headers) {
+		return 0, fmt.Errorf("column index %d out of range, file has %d columns", c.Index, len(headers))
+	}
+	return c.Index, nil
+}
+
+// providerRegistry holds every known provider definition by name.
+type providerRegistry struct {
+	mu        sync.RWMutex
+	providers map[string]*ProviderDefinition
+}
+
+var providers = newProviderRegistry()
+
+func newProviderRegistry() *providerRegistry {
+	registry := &providerRegistry{providers: map[string]*ProviderDefinition{}}
+
+	definitions, err := loadProviderFS(builtinProviders, "providers")
+	if err != nil {
+		panic(fmt.Sprintf("invalid builtin provider definition: %s", err))
+	}
+	registry.add(definitions)
+
+	return registry
+}
+
+func (r *providerRegistry) add(definitions []*ProviderDefinition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range definitions {
+		r.providers[d.Name] = d
+	}
+}
+
+func (r *providerRegistry) get(name string) (*ProviderDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d, ok := r.providers[strings.ToLower(name)]
+	return d, ok
+}
+
+// find looks the provider up by filename prefix first and by header row
+// otherwise.
+func (r *providerRegistry) find(filename string, headers []string) (*ProviderDefinition, bool) {
+	if source, err := extractFileSource(filename); err == nil {
+		if d, ok := r.get(source); ok {
+			return d, true
+		}
+	}
+
+	if len(headers) == 0 {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, name := range slices.Sorted(maps.Keys(r.providers)) {
+		if r.providers[name].matchesHeaders(headers) {
+			return r.providers[name], true
+		}
+	}
+
+	return nil, false
+}
+
+func (r *providerRegistry) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(r.providers))
+}
+
+// LoadProviders reads every .yaml, .yml and .json provider definition in dir
+// and registers them, replacing builtin providers with the same name.
+func LoadProviders(dir string) error {
+	definitions, err := loadProviderFS(os.DirFS(dir), ".")
+	if err != nil {
+		return fmt.Errorf("error loading providers from %s: %w", dir, err)
+	}
+
+	providers.add(definitions)
+	return nil
+}
+
+func loadProviderFS(fsys fs.FS, dir string) ([]*ProviderDefinition, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := []*ProviderDefinition{}
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+
+		data, readErr := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		definition, parseErr := ParseProviderDefinition(e.Name(), data)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+
+		definitions = append(definitions, definition)
+	}
+
+	return definitions, nil
+}
+
+// ParseProviderDefinition decodes and validates a provider definition. The
+// format is chosen from the filename extension.
+func ParseProviderDefinition(filename string, data []byte) (*ProviderDefinition, error) {
+	definition := &ProviderDefinition{}
+
+	var err error
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(definition)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(definition)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	if err = definition.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return definition, nil
+}
+
+func (d *ProviderDefinition) validate() error {
+	d.Name = strings.ToLower(strings.TrimSpace(d.Name))
+	if d.Name == "" {
+		return errors.New("name is required")
+	}
+	if strings.Contains(d.Name, "_") {
+		return errors.New("name cannot contain '_', it separates the provider from the rest of the filename")
+	}
+	if d.Source == "" {
+		d.Source = withTitleCase(d.Name)
+	}
+
+	if d.Columns.Date == nil || d.Columns.Description == nil || d.Columns.Amount == nil {
+		return errors.New("date, description and amount columns are required")
+	}
+	if d.Columns.Currency == nil && d.Currency == "" {
+		return errors.New("either a currency column or a fixed currency is required")
+	}
+
+	if d.DateLayout == "" {
+		return errors.New("date_layout is required")
+	}
+
+	if d.DecimalSeparator == "" {
+		d.DecimalSeparator = "."
+	}
+	if d.ThousandsSeparator == "" && d.DecimalSeparator != "," {
+		d.ThousandsSeparator = ","
+	}
+	if d.DecimalSeparator == d.ThousandsSeparator {
+		return errors.New("decimal and thousands separators must differ")
+	}
+
+	switch d.Sign {
+	case "":
+		d.Sign = SignSigned
+	case SignSigned, SignInverted:
+	default:
+		return fmt.Errorf("invalid sign %q, must be %q or %q", d.Sign, SignSigned, SignInverted)
+	}
+
+	if len(d.ChargeValues) > 0 && d.Columns.Type == nil {
+		return errors.New("charge_values requires a type column")
+	}
+
+	d.cleanup = make([]*regexp.Regexp, 0, len(d.DescriptionCleanup))
+	for _, pattern := range d.DescriptionCleanup {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid description_cleanup pattern %q: %w", pattern, err)
+		}
+		d.cleanup = append(d.cleanup, re)
+	}
+
+	return nil
+}
+
+func (d *ProviderDefinition) matchesHeaders(headers []string) bool {
+	if len(d.Headers) == 0 || len(d.Headers) != len(headers) {
+		return false
+	}
+
+	for i, h := range d.Headers {
+		if !strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(headers[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// columnTransformer applies a transformer to the value of a single column.
+type columnTransformer struct {
+	column    int
+	transform transformer
+}
+
+// transformers resolves the definition columns against the file header and
+// returns the transformers to run, in an order where the type and amount are
+// known before the fee is applied.
+func (d *ProviderDefinition) transformers(headers []string) ([]columnTransformer, error) {
+	steps := []struct {
+		column    *Column
+		transform transformer
+	}{
+		{d.Columns.Type, d.transformType},
+		{d.Columns.Date, d.transformDate},
+		{d.Columns.Description, d.transformDescription},
+		{d.Columns.Amount, d.transformAmount},
+		{d.Columns.Fee, d.transformFee},
+		{d.Columns.Currency, transformCurrency},
+	}
+
+	result := []columnTransformer{}
+	for _, step := range steps {
+		if step.column == nil {
+			continue
+		}
+
+		index, err := step.column.resolve(headers)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", d.Name, err)
+		}
+
+		result = append(result, columnTransformer{column: index, transform: step.transform})
+	}
+
+	return result, nil
+}
+
+// finish applies the fee and sign conventions once every column was read.
+func (d *ProviderDefinition) finish(e *entry) {
+	// A row without amount only carries the fee
+	if e.fee != 0 {
+		if e.amount != 0 {
+			e.amount -= e.fee
+		} else {
+			e.amount = e.fee
+		}
+	}
+
+	if e.charge {
+		e.amount *= -1
+	}
+
+	if d.Sign == SignInverted {
+		e.amount *= -1
+	}
+
+	if d.Currency != "" {
+		e.currency = d.Currency
+	}
+}
+
+func (d *ProviderDefinition) transformType(v string, e *entry) error {
+	e.charge = slices.ContainsFunc(d.ChargeValues, func(charge string) bool {
+		return strings.EqualFold(charge, strings.TrimSpace(v))
+	})
+	return nil
+}
+
+func (d *ProviderDefinition) transformDate(v string, e *entry) error {
+	t, err := time.Parse(d.DateLayout, strings.TrimSpace(v))
+	if err != nil {
+		return err
+	}
+	e.date = t
+	return nil
+}
+
+func (d *ProviderDefinition) transformDescription(v string, e *entry) error {
+	s := strings.ToLower(v)
+	for _, re := range d.cleanup {
+		s = re.ReplaceAllString(s, "")
+	}
+	e.description = strings.TrimSpace(s)
+	return nil
+}
+
+func (d *ProviderDefinition) transformAmount(v string, e *entry) error {
+	amount, err := d.parseAmount(v)
+	if err != nil {
+		return err
+	}
+	e.amount = amount
+	return nil
+}
+
+func (d *ProviderDefinition) transformFee(v string, e *entry) error {
+	fee, err := d.parseAmount(v)
+	if err != nil {
+		return err
+	}
+	e.fee = fee
+	return nil
+}
+
+func transformCurrency(v string, e *entry) error {
+	e.currency = strings.TrimSpace(v)
+	return nil
+}
+
+// parseAmount normalizes the provider separators before converting to cents.
+func (d *ProviderDefinition) parseAmount(v string) (int64, error) {
+	return parseLocaleAmount(v, d.DecimalSeparator, d.ThousandsSeparator)
+}
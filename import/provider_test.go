@@ -0,0 +1,261 @@
+package importutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func TestBuiltinProviders(t *testing.T) {
+	names := availableSources()
+	expected := []string{"bankinter", "evo", "revolut"}
+
+	for _, name := range expected {
+		found := false
+		for _, n := range names {
+			if n == name {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected builtin provider %q, got %v", name, names)
+		}
+	}
+}
+
+func TestParseProviderDefinition(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		data     string
+		wantErr  string
+	}{
+		{
+			name:     "valid yaml",
+			filename: "mybank.yaml",
+			data: `name: mybank
+columns: {date: Date, description: 1, amount: Amount}
+date_layout: 2006-01-02
+currency: EUR`,
+		},
+		{
+			name:     "valid json",
+			filename: "mybank.json",
+			data: `{"name": "mybank", "columns": {"date": 0, "description": "Concept", "amount": 2},
+"date_layout": "2006-01-02", "currency": "EUR"}`,
+		},
+		{
+			name:     "missing name",
+			filename: "mybank.yaml",
+			data: `columns: {date: 0, description: 1, amount: 2}
+date_layout: 2006-01-02
+currency: EUR`,
+			wantErr: "name is required",
+		},
+		{
+			name:     "missing columns",
+			filename: "mybank.yaml",
+			data: `name: mybank
+columns: {date: 0}
+date_layout: 2006-01-02
+currency: EUR`,
+			wantErr: "columns are required",
+		},
+		{
+			name:     "missing currency",
+			filename: "mybank.yaml",
+			data: `name: mybank
+columns: {date: 0, description: 1, amount: 2}
+date_layout: 2006-01-02`,
+			wantErr: "currency",
+		},
+		{
+			name:     "invalid sign",
+			filename: "mybank.yaml",
+			data: `name: mybank
+columns: {date: 0, description: 1, amount: 2}
+date_layout: 2006-01-02
+currency: EUR
+sign: backwards`,
+			wantErr: "invalid sign",
+		},
+		{
+			name:     "same separators",
+			filename: "mybank.yaml",
+			data: `name: mybank
+columns: {date: 0, description: 1, amount: 2}
+date_layout: 2006-01-02
+currency: EUR
+decimal_separator: "."
+thousands_separator: "."`,
+			wantErr: "separators must differ",
+		},
+		{
+			name:     "invalid cleanup pattern",
+			filename: "mybank.yaml",
+			data: `name: mybank
+columns: {date: 0, description: 1, amount: 2}
+date_layout: 2006-01-02
+currency: EUR
+description_cleanup: ["("]`,
+			wantErr: "invalid description_cleanup",
+		},
+		{
+			name:     "unknown field",
+			filename: "mybank.yaml",
+			data: `name: mybank
+colums: {date: 0, description: 1, amount: 2}`,
+			wantErr: "colums",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			definition, err := ParseProviderDefinition(tt.filename, []byte(tt.data))
+
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error containing %q, got nil", tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if definition.Source != "Mybank" {
+				t.Errorf("Source = %q, want %q", definition.Source, "Mybank")
+			}
+		})
+	}
+}
+
+func TestLoadProvidersImportsCustomBank(t *testing.T) {
+	dir := t.TempDir()
+
+	definition := `name: testbank
+headers: [Fecha, Concepto, Importe, Comision]
+columns:
+  date: Fecha
+  description: Concepto
+  amount: Importe
+  fee: Comision
+date_layout: 02.01.2006
+decimal_separator: ","
+thousands_separator: "."
+sign: inverted
+description_cleanup: ["^compra tarjeta "]
+currency: EUR
+`
+	err := os.WriteFile(filepath.Join(dir, "testbank.yaml"), []byte(definition), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write provider: %v", err)
+	}
+
+	// Ignored, not a provider definition
+	err = os.WriteFile(filepath.Join(dir, "README.txt"), []byte("notes"), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if err = LoadProviders(dir); err != nil {
+		t.Fatalf("LoadProviders failed: %v", err)
+	}
+
+	csvData := `Fecha,Concepto,Importe,Comision
+15.01.2024,Compra tarjeta Mercadona,"1.234,56","1,00"
+16.01.2024,Devolucion,"-20,00",`
+
+	header := []string{"Fecha", "Concepto", "Importe", "Comision"}
+	if !SupportedProvider("export.csv", header) {
+		t.Fatal("Expected provider to be detected from the header row")
+	}
+
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	reader := strings.NewReader(csvData)
+	info := ImportCSV(context.Background(), user.ID(), nil, "export.csv", reader, s, matcher.New(nil))
+	if info.Error != nil {
+		t.Fatalf("ImportCSV failed: %v", info.Error)
+	}
+
+	if info.TotalImports != 2 {
+		t.Fatalf("TotalImports = %d, want 2", info.TotalImports)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	byDescription := map[string]int64{}
+	for _, e := range expenses {
+		if e.Source() != "Testbank" {
+			t.Errorf("Source = %q, want %q", e.Source(), "Testbank")
+		}
+		if e.Currency() != "EUR" {
+			t.Errorf("Currency = %q, want EUR", e.Currency())
+		}
+		byDescription[e.Description()] = e.Amount()
+
+		if e.Description() == "mercadona" && !e.Date().Equal(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Date = %v, want 2024-01-15", e.Date())
+		}
+	}
+
+	if byDescription["mercadona"] != -123356 {
+		t.Errorf("mercadona amount = %d, want -123356", byDescription["mercadona"])
+	}
+
+	if byDescription["devolucion"] != 2000 {
+		t.Errorf("devolucion amount = %d, want 2000", byDescription["devolucion"])
+	}
+}
+
+func TestLoadProvidersInvalidDefinition(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"name": "broken"}`), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write provider: %v", err)
+	}
+
+	err = LoadProviders(dir)
+	if err == nil {
+		t.Fatal("Expected error loading invalid provider")
+	}
+
+	if !strings.Contains(err.Error(), "broken.json") {
+		t.Errorf("Expected error to name the file, got %v", err)
+	}
+}
+
+func TestProviderMissingHeaderColumn(t *testing.T) {
+	definition, err := ParseProviderDefinition("bank.yaml", []byte(`name: bank
+columns: {date: Date, description: Concept, amount: Amount}
+date_layout: 2006-01-02
+currency: EUR`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err = definition.transformers([]string{"Date", "Amount"})
+	if err == nil {
+		t.Fatal("Expected error for missing column")
+	}
+
+	if !strings.Contains(err.Error(), "Concept") {
+		t.Errorf("Expected error to name the missing column, got %v", err)
+	}
+}
@@ -1,98 +1,148 @@
 package importutil
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"sync"
+	"encoding/json"
+	"fmt"
+	"io"
 	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	storageType "github.com/GustavoCaso/expensetrace/storage"
 )
 
 // ImportSession stores temporary data for a multi-step import process.
 type ImportSession struct {
-	ID        string
-	Filename  string
+	ID string
+	// UserID is the user who uploaded the file, the only one who can see
+	// or import it.
+	UserID   int64
+	Filename string
+	// Data holds the header and a sample of the rows of the file. The whole
+	// file is stored with the session and read with SessionStore.Open.
 	Data      *ParsedData
 	Mapping   *FieldMapping
 	CreatedAt time.Time
 	ExpiresAt time.Time
 }
 
-// SessionStore manages import sessions with automatic cleanup.
+// SessionStore keeps import sessions in storage, so they survive restarts
+// and are shared by every server using the same database. Expired sessions
+// are cleaned up automatically.
 type SessionStore struct {
-	sessions map[string]*ImportSession
-	mu       sync.RWMutex
-	ttl      time.Duration
+	storage storageType.Storage
+	ttl     time.Duration
 }
 
 // NewSessionStore creates a new session store with the specified TTL.
-func NewSessionStore(ttl time.Duration) *SessionStore {
+func NewSessionStore(storage storageType.Storage, ttl time.Duration) *SessionStore {
 	store := &SessionStore{
-		sessions: make(map[string]*ImportSession),
-		ttl:      ttl,
+		storage: storage,
+		ttl:     ttl,
 	}
 	// Start background cleanup
 	go store.cleanup()
 	return store
 }
 
-// Create creates a new import session and returns its ID.
-func (s *SessionStore) Create(filename string, data *ParsedData) string {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Create creates a new import session for the user, storing the uploaded
+// file read from file, and returns its ID.
+func (s *SessionStore) Create(
+	ctx context.Context,
+	userID int64,
+	filename string,
+	file io.Reader,
+	data *ParsedData,
+) (string, error) {
+	encoded, err := encodeSessionData(data)
+	if err != nil {
+		return "", err
+	}
 
 	sessionID := generateSessionID()
 	now := time.Now()
 
-	session := &ImportSession{
-		ID:        sessionID,
-		Filename:  filename,
-		Data:      data,
-		CreatedAt: now,
-		ExpiresAt: now.Add(s.ttl),
+	session := domain.NewImportSession(sessionID, userID, filename, encoded, nil, now, now.Add(s.ttl))
+	if err = s.storage.CreateImportSession(ctx, session, file); err != nil {
+		return "", err
 	}
 
-	s.sessions[sessionID] = session
-	return sessionID
+	return sessionID, nil
 }
 
-// Get retrieves a session by ID.
-func (s *SessionStore) Get(sessionID string) (*ImportSession, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// Get retrieves a session of the user by ID. Missing and expired sessions,
+// and sessions of other users, return a domain.NotFoundError.
+func (s *SessionStore) Get(ctx context.Context, userID int64, sessionID string) (*ImportSession, error) {
+	stored, err := s.storage.GetImportSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
 
-	session, exists := s.sessions[sessionID]
-	if !exists {
-		return nil, false
+	data, err := decodeSessionData(stored.Data())
+	if err != nil {
+		return nil, err
 	}
 
-	// Check if expired
-	if time.Now().After(session.ExpiresAt) {
-		return nil, false
+	var mapping *FieldMapping
+	if len(stored.Mapping()) > 0 {
+		mapping = &FieldMapping{}
+		if err = json.Unmarshal(stored.Mapping(), mapping); err != nil {
+			return nil, fmt.Errorf("error decoding import session mapping: %w", err)
+		}
 	}
 
-	return session, true
+	return &ImportSession{
+		ID:        stored.ID(),
+		UserID:    stored.UserID(),
+		Filename:  stored.Filename(),
+		Data:      data,
+		Mapping:   mapping,
+		CreatedAt: stored.CreatedAt(),
+		ExpiresAt: stored.ExpiresAt(),
+	}, nil
 }
 
-// Update updates an existing session.
-func (s *SessionStore) Update(sessionID string, mapping *FieldMapping) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	session, exists := s.sessions[sessionID]
-	if !exists {
-		return false
-	}
+// Open returns the uploaded file of a session of the user.
+func (s *SessionStore) Open(ctx context.Context, userID int64, sessionID string) (io.ReadCloser, error) {
+	return s.storage.OpenImportSessionFile(ctx, userID, sessionID)
+}
 
+// Update stores the mapping applied to a session.
+func (s *SessionStore) Update(ctx context.Context, session *ImportSession, mapping *FieldMapping) error {
 	session.Mapping = mapping
-	return true
+	return s.save(ctx, session)
 }
 
-// Delete removes a session from the store.
-func (s *SessionStore) Delete(sessionID string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Replace swaps the parsed data of a session, after reading the file with
+// other ParseOptions. The mapping is cleared as columns may have changed.
+func (s *SessionStore) Replace(ctx context.Context, session *ImportSession, data *ParsedData) error {
+	session.Data = data
+	session.Mapping = nil
+	return s.save(ctx, session)
+}
 
-	delete(s.sessions, sessionID)
+// Delete removes a session of the user from the store, along with its file.
+func (s *SessionStore) Delete(ctx context.Context, userID int64, sessionID string) error {
+	return s.storage.DeleteImportSession(ctx, userID, sessionID)
+}
+
+func (s *SessionStore) save(ctx context.Context, session *ImportSession) error {
+	data, err := encodeSessionData(session.Data)
+	if err != nil {
+		return err
+	}
+
+	var mapping []byte
+	if session.Mapping != nil {
+		mapping, err = json.Marshal(session.Mapping)
+		if err != nil {
+			return fmt.Errorf("error encoding mapping: %w", err)
+		}
+	}
+
+	return s.storage.UpdateImportSession(ctx, session.UserID, session.ID, data, mapping)
 }
 
 // cleanup periodically removes expired sessions.
@@ -101,15 +151,43 @@ func (s *SessionStore) cleanup() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		s.mu.Lock()
-		now := time.Now()
-		for id, session := range s.sessions {
-			if now.After(session.ExpiresAt) {
-				delete(s.sessions, id)
-			}
-		}
-		s.mu.Unlock()
+		// Failures are retried on the next tick
+		_, _ = s.storage.DeleteExpiredImportSessions(context.Background())
+	}
+}
+
+// parsedDataFields has the fields of ParsedData without its methods, so
+// sessionData can embed them.
+type parsedDataFields ParsedData
+
+// sessionData is how the parsed data of a session is stored, keeping the
+// row count of files larger than the sample.
+type sessionData struct {
+	parsedDataFields
+
+	TotalRows int
+}
+
+func encodeSessionData(data *ParsedData) ([]byte, error) {
+	encoded, err := json.Marshal(sessionData{
+		parsedDataFields: parsedDataFields(*data),
+		TotalRows:        data.GetTotalRows(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding import session data: %w", err)
 	}
+	return encoded, nil
+}
+
+func decodeSessionData(encoded []byte) (*ParsedData, error) {
+	var stored sessionData
+	if err := json.Unmarshal(encoded, &stored); err != nil {
+		return nil, fmt.Errorf("error decoding import session data: %w", err)
+	}
+
+	data := ParsedData(stored.parsedDataFields)
+	data.totalRows = stored.TotalRows
+	return &data, nil
 }
 
 // generateSessionID creates a random session ID.
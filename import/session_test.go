@@ -1,70 +1,108 @@
 package importutil
 
 import (
+	"context"
+	"errors"
+	"io"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	storageType "github.com/GustavoCaso/expensetrace/storage"
+	"github.com/GustavoCaso/expensetrace/testutil"
 )
 
+func setupSessionStore(t *testing.T, ttl time.Duration) (*SessionStore, storageType.Storage, domain.User) {
+	t.Helper()
+	s, user := testutil.SetupTestStorage(t, testutil.TestLogger(t))
+	return NewSessionStore(s, ttl), s, user
+}
+
 func TestSessionStoreCreate(t *testing.T) {
-	store := NewSessionStore(10 * time.Minute)
+	store, _, user := setupSessionStore(t, 10*time.Minute)
+	ctx := context.Background()
 
-	data := &ParsedData{
-		Headers: []string{"source", "date", "description", "amount", "currency"},
-		Rows:    [][]string{{"Bank A", "01/01/2024", "Coffee", "-5.00", "USD"}},
-		Format:  "csv",
+	content := "source,date,description,amount,currency\nBank A,01/01/2024,Coffee,-5.00,USD\n"
+	data, err := ParseFile("test.csv", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
 	}
 
-	sessionID := store.Create("test.csv", data)
+	sessionID, err := store.Create(ctx, user.ID(), "test.csv", strings.NewReader(content), data)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
 	if sessionID == "" {
 		t.Fatal("Create returned empty session ID")
 	}
 
-	session, exists := store.Get(sessionID)
-	if !exists {
-		t.Fatal("Session not found after creation")
+	session, err := store.Get(ctx, user.ID(), sessionID)
+	if err != nil {
+		t.Fatalf("Session not found after creation: %v", err)
 	}
 
 	if session.ID != sessionID {
 		t.Errorf("Session.ID = %q, want %q", session.ID, sessionID)
 	}
+	if session.UserID != user.ID() {
+		t.Errorf("Session.UserID = %d, want %d", session.UserID, user.ID())
+	}
 	if session.Filename != "test.csv" {
 		t.Errorf("Session.Filename = %q, want 'test.csv'", session.Filename)
 	}
-	if session.Data != data {
-		t.Error("Session.Data does not match created data")
+	if strings.Join(session.Data.Headers, ",") != strings.Join(data.Headers, ",") ||
+		session.Data.Delimiter != "," || session.Data.GetTotalRows() != 1 {
+		t.Errorf("Session.Data = %+v, want %+v", session.Data, data)
 	}
 	if session.Mapping != nil {
 		t.Error("Session.Mapping should be nil initially")
 	}
-}
 
-func TestSessionStoreGet(t *testing.T) {
-	store := NewSessionStore(10 * time.Minute)
+	file, err := store.Open(ctx, user.ID(), sessionID)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer file.Close()
 
-	data := &ParsedData{
-		Headers: []string{"source"},
-		Rows:    [][]string{{"Bank A"}},
-		Format:  "csv",
+	stored, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("Failed to read the session file: %v", err)
 	}
+	if string(stored) != content {
+		t.Errorf("Session file = %q, want %q", stored, content)
+	}
+}
 
-	sessionID := store.Create("test.csv", data)
+func TestSessionStoreKeepsTheRowCountOfLargeFiles(t *testing.T) {
+	store, _, user := setupSessionStore(t, 10*time.Minute)
+	ctx := context.Background()
 
-	session, exists := store.Get(sessionID)
-	if !exists {
-		t.Fatal("Get failed to retrieve existing session")
+	const rows = SampleRows + 10
+	data, err := ParseFile("large.csv", strings.NewReader(largeCSV(rows)))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
 	}
-	if session.ID != sessionID {
-		t.Errorf("Retrieved session ID = %q, want %q", session.ID, sessionID)
+
+	sessionID, err := store.Create(ctx, user.ID(), "large.csv", strings.NewReader(largeCSV(rows)), data)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	session, err := store.Get(ctx, user.ID(), sessionID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
 	}
 
-	_, exists = store.Get("non-existent-id")
-	if exists {
-		t.Error("Get returned true for non-existent session")
+	if len(session.Data.Rows) != SampleRows || session.Data.GetTotalRows() != rows {
+		t.Errorf("Stored %d rows out of %d, want %d out of %d",
+			len(session.Data.Rows), session.Data.GetTotalRows(), SampleRows, rows)
 	}
 }
 
-func TestSessionStoreUpdate(t *testing.T) {
-	store := NewSessionStore(10 * time.Minute)
+func TestSessionStoreGet(t *testing.T) {
+	store, s, user := setupSessionStore(t, 10*time.Minute)
+	ctx := context.Background()
 
 	data := &ParsedData{
 		Headers: []string{"source"},
@@ -72,34 +110,39 @@ func TestSessionStoreUpdate(t *testing.T) {
 		Format:  "csv",
 	}
 
-	sessionID := store.Create("test.csv", data)
-
-	mapping := &FieldMapping{
-		Source:            "Test Bank",
-		DateColumn:        1,
-		DescriptionColumn: 2,
-		AmountColumn:      3,
-		CurrencyColumn:    4,
+	sessionID, err := store.Create(ctx, user.ID(), "test.csv", strings.NewReader("source\nBank A\n"), data)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
 	}
 
-	success := store.Update(sessionID, mapping)
-	if !success {
-		t.Fatal("Update failed for existing session")
+	session, err := store.Get(ctx, user.ID(), sessionID)
+	if err != nil {
+		t.Fatalf("Get failed to retrieve existing session: %v", err)
+	}
+	if session.ID != sessionID {
+		t.Errorf("Retrieved session ID = %q, want %q", session.ID, sessionID)
 	}
 
-	session, _ := store.Get(sessionID)
-	if session.Mapping != mapping {
-		t.Error("Session.Mapping was not updated")
+	var notFound *domain.NotFoundError
+	if _, err = store.Get(ctx, user.ID(), "non-existent-id"); !errors.As(err, &notFound) {
+		t.Errorf("Get of a missing session should return NotFoundError, got %v", err)
 	}
 
-	success = store.Update("non-existent-id", mapping)
-	if success {
-		t.Error("Update returned true for non-existent session")
+	other, err := s.CreateUser(ctx, "other", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err = store.Get(ctx, other.ID(), sessionID); !errors.As(err, &notFound) {
+		t.Errorf("Get of another user's session should return NotFoundError, got %v", err)
+	}
+	if _, err = store.Open(ctx, other.ID(), sessionID); !errors.As(err, &notFound) {
+		t.Errorf("Open of another user's session should return NotFoundError, got %v", err)
 	}
 }
 
-func TestSessionStoreDelete(t *testing.T) {
-	store := NewSessionStore(10 * time.Minute)
+func TestSessionStoreUpdate(t *testing.T) {
+	store, _, user := setupSessionStore(t, 10*time.Minute)
+	ctx := context.Background()
 
 	data := &ParsedData{
 		Headers: []string{"source"},
@@ -107,49 +150,100 @@ func TestSessionStoreDelete(t *testing.T) {
 		Format:  "csv",
 	}
 
-	sessionID := store.Create("test.csv", data)
+	sessionID, err := store.Create(ctx, user.ID(), "test.csv", strings.NewReader("source\nBank A\n"), data)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
 
-	_, exists := store.Get(sessionID)
-	if !exists {
-		t.Fatal("Session should exist before deletion")
+	mappings := []*FieldMapping{
+		{Source: "Test Bank", DateColumn: 1, DescriptionColumn: 2, AmountColumn: 3, CurrencyColumn: 4},
+		{Source: "Another Bank", DateColumn: 0, DescriptionColumn: 3, AmountColumn: 2, CurrencyColumn: 4},
 	}
 
-	store.Delete(sessionID)
+	for _, mapping := range mappings {
+		session, getErr := store.Get(ctx, user.ID(), sessionID)
+		if getErr != nil {
+			t.Fatalf("Get failed: %v", getErr)
+		}
 
-	_, exists = store.Get(sessionID)
-	if exists {
-		t.Error("Session still exists after deletion")
+		if err = store.Update(ctx, session, mapping); err != nil {
+			t.Fatalf("Update failed for existing session: %v", err)
+		}
+
+		session, getErr = store.Get(ctx, user.ID(), sessionID)
+		if getErr != nil {
+			t.Fatalf("Get failed: %v", getErr)
+		}
+		if session.Mapping == nil || session.Mapping.Source != mapping.Source ||
+			session.Mapping.DescriptionColumn != mapping.DescriptionColumn {
+			t.Errorf("Session.Mapping = %+v, want %+v", session.Mapping, mapping)
+		}
 	}
 
-	store.Delete("non-existent-id")
+	missing := &ImportSession{ID: "non-existent-id", UserID: user.ID(), Data: data}
+	var notFound *domain.NotFoundError
+	if err = store.Update(ctx, missing, mappings[0]); !errors.As(err, &notFound) {
+		t.Errorf("Update of a missing session should return NotFoundError, got %v", err)
+	}
 }
 
-func TestSessionStoreExpiration(t *testing.T) {
-	store := NewSessionStore(100 * time.Millisecond)
+func TestSessionStoreReplace(t *testing.T) {
+	store, _, user := setupSessionStore(t, 10*time.Minute)
+	ctx := context.Background()
 
+	content := "Statement\ndate\n2024-01-01\n"
 	data := &ParsedData{
-		Headers: []string{"source"},
-		Rows:    [][]string{{"Bank A"}},
+		Headers: []string{"Statement"},
+		Rows:    [][]string{{"date"}, {"2024-01-01"}},
 		Format:  "csv",
 	}
 
-	sessionID := store.Create("test.csv", data)
+	sessionID, err := store.Create(ctx, user.ID(), "test.csv", strings.NewReader(content), data)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
 
-	_, exists := store.Get(sessionID)
-	if !exists {
-		t.Fatal("Session should exist immediately after creation")
+	session, err := store.Get(ctx, user.ID(), sessionID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err = store.Update(ctx, session, &FieldMapping{Source: "Bank"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
 	}
 
-	time.Sleep(150 * time.Millisecond)
+	replaced := &ParsedData{
+		Headers:   []string{"date"},
+		Rows:      [][]string{{"2024-01-01"}},
+		Format:    "csv",
+		HeaderRow: 1,
+	}
+	if err = store.Replace(ctx, session, replaced); err != nil {
+		t.Fatalf("Replace should succeed for an existing session: %v", err)
+	}
 
-	_, exists = store.Get(sessionID)
-	if exists {
-		t.Error("Session should be expired after TTL")
+	session, err = store.Get(ctx, user.ID(), sessionID)
+	if err != nil {
+		t.Fatalf("Session should exist: %v", err)
+	}
+
+	if session.Data.HeaderRow != 1 || session.Data.Headers[0] != "date" || session.Mapping != nil {
+		t.Error("Replace should swap the data and clear the mapping")
+	}
+
+	file, err := store.Open(ctx, user.ID(), sessionID)
+	if err != nil {
+		t.Fatalf("Replace should keep the uploaded file: %v", err)
+	}
+	stored, _ := io.ReadAll(file)
+	file.Close()
+	if string(stored) != content {
+		t.Errorf("Session file = %q, want %q", stored, content)
 	}
 }
 
-func TestSessionStoreCleanup(t *testing.T) {
-	store := NewSessionStore(50 * time.Millisecond)
+func TestSessionStoreDelete(t *testing.T) {
+	store, _, user := setupSessionStore(t, 10*time.Minute)
+	ctx := context.Background()
 
 	data := &ParsedData{
 		Headers: []string{"source"},
@@ -157,32 +251,32 @@ func TestSessionStoreCleanup(t *testing.T) {
 		Format:  "csv",
 	}
 
-	id1 := store.Create("test1.csv", data)
-	id2 := store.Create("test2.csv", data)
-
-	_, exists := store.Get(id1)
-	if !exists {
-		t.Fatal("Session 1 should exist")
-	}
-	_, exists = store.Get(id2)
-	if !exists {
-		t.Fatal("Session 2 should exist")
+	sessionID, err := store.Create(ctx, user.ID(), "test.csv", strings.NewReader("source\nBank A\n"), data)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
 	}
 
-	time.Sleep(100 * time.Millisecond)
+	if err = store.Delete(ctx, user.ID(), sessionID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
 
-	_, exists = store.Get(id1)
-	if exists {
-		t.Error("Session 1 should be expired")
+	if _, err = store.Get(ctx, user.ID(), sessionID); err == nil {
+		t.Error("Session still exists after deletion")
+	}
+	if _, err = store.Open(ctx, user.ID(), sessionID); err == nil {
+		t.Error("Session file still exists after deletion")
 	}
-	_, exists = store.Get(id2)
-	if exists {
-		t.Error("Session 2 should be expired")
+
+	// Deleting a missing session does nothing
+	if err = store.Delete(ctx, user.ID(), "non-existent-id"); err != nil {
+		t.Errorf("Delete of a missing session failed: %v", err)
 	}
 }
 
-func TestSessionStoreConcurrency(t *testing.T) {
-	store := NewSessionStore(1 * time.Minute)
+func TestSessionStoreExpiration(t *testing.T) {
+	// Sessions created by this store expire as soon as they are created
+	store, _, user := setupSessionStore(t, -time.Minute)
+	ctx := context.Background()
 
 	data := &ParsedData{
 		Headers: []string{"source"},
@@ -190,34 +284,14 @@ func TestSessionStoreConcurrency(t *testing.T) {
 		Format:  "csv",
 	}
 
-	const numGoroutines = 10
-	done := make(chan bool)
-	sessionIDs := make(chan string, numGoroutines)
-
-	for range numGoroutines {
-		go func() {
-			id := store.Create("test.csv", data)
-			sessionIDs <- id
-			done <- true
-		}()
+	sessionID, err := store.Create(ctx, user.ID(), "test.csv", strings.NewReader("source\nBank A\n"), data)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
 	}
 
-	for range numGoroutines {
-		<-done
-	}
-	close(sessionIDs)
-
-	count := 0
-	for id := range sessionIDs {
-		_, exists := store.Get(id)
-		if !exists {
-			t.Errorf("Session %s not found", id)
-		}
-		count++
-	}
-
-	if count != 10 {
-		t.Errorf("Expected 10 sessions, got %d", count)
+	var notFound *domain.NotFoundError
+	if _, err = store.Get(ctx, user.ID(), sessionID); !errors.As(err, &notFound) {
+		t.Errorf("Session should be expired after TTL, got %v", err)
 	}
 }
 
@@ -240,43 +314,3 @@ func TestGenerateSessionID(t *testing.T) {
 		ids[id] = true
 	}
 }
-
-func TestSessionStoreMultipleUpdates(t *testing.T) {
-	store := NewSessionStore(10 * time.Minute)
-
-	data := &ParsedData{
-		Headers: []string{"source"},
-		Rows:    [][]string{{"Bank A"}},
-		Format:  "csv",
-	}
-
-	sessionID := store.Create("test.csv", data)
-
-	mapping1 := &FieldMapping{
-		Source:            "Test Bank",
-		DateColumn:        1,
-		DescriptionColumn: 2,
-		AmountColumn:      3,
-		CurrencyColumn:    4,
-	}
-
-	mapping2 := &FieldMapping{
-		Source:            "Another Bank",
-		DateColumn:        0,
-		DescriptionColumn: 3,
-		AmountColumn:      2,
-		CurrencyColumn:    4,
-	}
-
-	store.Update(sessionID, mapping1)
-	session, _ := store.Get(sessionID)
-	if session.Mapping != mapping1 {
-		t.Error("First mapping not stored correctly")
-	}
-
-	store.Update(sessionID, mapping2)
-	session, _ = store.Get(sessionID)
-	if session.Mapping != mapping2 {
-		t.Error("Second mapping not stored correctly (should overwrite first)")
-	}
-}
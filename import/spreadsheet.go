@@ -0,0 +1,688 @@
+package importutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSpreadsheetPartSize bounds the uncompressed size of the files read
+// from a spreadsheet archive.
+const maxSpreadsheetPartSize = 64 << 20 // 64MB
+
+// maxSpreadsheetColumns is the column limit of spreadsheet applications,
+// ODS files repeat empty cells up to it.
+const maxSpreadsheetColumns = 16384
+
+const (
+	odsOfficeNS = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	odsTableNS  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odsTextNS   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+)
+
+const hoursPerDay = 24
+
+var (
+	// excelEpoch is day zero of the 1900 date system, which counts the
+	// non-existent 29 February 1900, so serials from March 1900 line up.
+	excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	// excelEpoch1904 is day zero of the date system used by old Mac files.
+	excelEpoch1904 = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// openSpreadsheet reads a zip based spreadsheet file.
+func openSpreadsheet(reader io.Reader) (*zip.Reader, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading spreadsheet: %w", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("error reading spreadsheet: %w", err)
+	}
+
+	return archive, nil
+}
+
+// decodeSpreadsheetPart unmarshals a file of the archive. Missing files are
+// reported with found=false.
+func decodeSpreadsheetPart(archive *zip.Reader, name string, v any) (bool, error) {
+	name = strings.TrimPrefix(name, "/")
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return true, fmt.Errorf("error opening %s: %w", name, err)
+		}
+		defer rc.Close()
+
+		limited := &io.LimitedReader{R: rc, N: maxSpreadsheetPartSize}
+		if err = xml.NewDecoder(limited).Decode(v); err != nil {
+			if limited.N <= 0 {
+				return true, fmt.Errorf("%s is too large", name)
+			}
+			return true, fmt.Errorf("error parsing %s: %w", name, err)
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// selectSheet returns the position of the sheet chosen by name, the first
+// sheet when no name is given.
+func selectSheet(sheets []string, name string) (int, error) {
+	if len(sheets) == 0 {
+		return 0, errors.New("spreadsheet has no sheets")
+	}
+
+	if name == "" {
+		return 0, nil
+	}
+
+	for i, sheet := range sheets {
+		if sheet == name {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("sheet %q not found. Available sheets: %s", name, strings.Join(sheets, ", "))
+}
+
+// spreadsheetTable builds the parsed data of a sheet.
+func spreadsheetTable(records [][]string, format string, sheets []string, sheet int, options ParseOptions) (
+	*ParsedData,
+	error,
+) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("sheet %q is empty", sheets[sheet])
+	}
+
+	data, err := tableFromRecords(records, options.HeaderRow)
+	if err != nil {
+		return nil, fmt.Errorf("sheet %q %w", sheets[sheet], err)
+	}
+
+	data.Format = format
+	data.Sheets = sheets
+	data.Sheet = sheets[sheet]
+	return data, nil
+}
+
+// appendRecord keeps non-empty rows, trimming their trailing empty cells.
+func appendRecord(records [][]string, row []string) [][]string {
+	last := len(row)
+	for last > 0 && strings.TrimSpace(row[last-1]) == "" {
+		last--
+	}
+	if last == 0 {
+		return records
+	}
+	return append(records, row[:last])
+}
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 string `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a shared or inline string, either plain or made of runs.
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Style  int      `xml:"s,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+// xlsxDateKind tells how a cell style displays numbers.
+type xlsxDateKind int
+
+const (
+	xlsxNumber xlsxDateKind = iota
+	xlsxDate
+	xlsxDateTime
+)
+
+// xlsxCellReader turns cells into the text shown by spreadsheet
+// applications, converting dates stored as serial numbers.
+type xlsxCellReader struct {
+	sharedStrings []string
+	styles        []xlsxDateKind
+	epoch         time.Time
+}
+
+// parseXLSX reads a sheet of an Office Open XML workbook.
+func parseXLSX(reader io.Reader, options ParseOptions) (*ParsedData, error) {
+	archive, err := openSpreadsheet(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var workbook xlsxWorkbook
+	found, err := decodeSpreadsheetPart(archive, "xl/workbook.xml", &workbook)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("not an XLSX file: workbook not found")
+	}
+
+	sheets := make([]string, 0, len(workbook.Sheets))
+	for _, sheet := range workbook.Sheets {
+		sheets = append(sheets, sheet.Name)
+	}
+
+	sheet, err := selectSheet(sheets, options.Sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := xlsxSheetPath(archive, workbook.Sheets[sheet].RID)
+	if err != nil {
+		return nil, err
+	}
+
+	cells, err := newXLSXCellReader(archive, workbook)
+	if err != nil {
+		return nil, err
+	}
+
+	var worksheet xlsxWorksheet
+	found, err = decodeSpreadsheetPart(archive, target, &worksheet)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("sheet %q not found in the file", sheets[sheet])
+	}
+
+	records := make([][]string, 0, len(worksheet.Rows))
+	for _, row := range worksheet.Rows {
+		record, rowErr := cells.row(row.Cells)
+		if rowErr != nil {
+			return nil, rowErr
+		}
+		records = appendRecord(records, record)
+	}
+
+	return spreadsheetTable(records, "xlsx", sheets, sheet, options)
+}
+
+// xlsxSheetPath resolves the file holding a sheet from its relationship ID.
+func xlsxSheetPath(archive *zip.Reader, rid string) (string, error) {
+	var rels xlsxRelationships
+	if _, err := decodeSpreadsheetPart(archive, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != rid {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return rel.Target, nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+
+	return "", fmt.Errorf("sheet relationship %q not found", rid)
+}
+
+func newXLSXCellReader(archive *zip.Reader, workbook xlsxWorkbook) (*xlsxCellReader, error) {
+	cells := &xlsxCellReader{epoch: excelEpoch}
+	if workbook.Properties.Date1904 == "1" || workbook.Properties.Date1904 == "true" {
+		cells.epoch = excelEpoch1904
+	}
+
+	var shared xlsxSharedStrings
+	if _, err := decodeSpreadsheetPart(archive, "xl/sharedStrings.xml", &shared); err != nil {
+		return nil, err
+	}
+	cells.sharedStrings = make([]string, 0, len(shared.Items))
+	for _, item := range shared.Items {
+		cells.sharedStrings = append(cells.sharedStrings, item.String())
+	}
+
+	var styles xlsxStyles
+	if _, err := decodeSpreadsheetPart(archive, "xl/styles.xml", &styles); err != nil {
+		return nil, err
+	}
+	customFormats := map[int]string{}
+	for _, numFmt := range styles.NumFmts {
+		customFormats[numFmt.ID] = numFmt.Code
+	}
+	cells.styles = make([]xlsxDateKind, 0, len(styles.CellXfs))
+	for _, xf := range styles.CellXfs {
+		cells.styles = append(cells.styles, xlsxNumberFormatKind(xf.NumFmtID, customFormats))
+	}
+
+	return cells, nil
+}
+
+// row places the cells of a row in their columns.
+func (c *xlsxCellReader) row(cells []xlsxCell) ([]string, error) {
+	row := []string{}
+	for _, cell := range cells {
+		column := len(row)
+		if cell.Ref != "" {
+			var err error
+			if column, err = xlsxColumn(cell.Ref); err != nil {
+				return nil, err
+			}
+		}
+
+		value, err := c.value(cell)
+		if err != nil {
+			return nil, fmt.Errorf("cell %s: %w", cell.Ref, err)
+		}
+
+		for len(row) < column {
+			row = append(row, "")
+		}
+		if column < len(row) {
+			row[column] = value
+		} else {
+			row = append(row, value)
+		}
+	}
+	return row, nil
+}
+
+func (c *xlsxCellReader) value(cell xlsxCell) (string, error) {
+	switch cell.Type {
+	case "s":
+		index, err := strconv.Atoi(strings.TrimSpace(cell.Value))
+		if err != nil || index < 0 || index >= len(c.sharedStrings) {
+			return "", fmt.Errorf("invalid shared string %q", cell.Value)
+		}
+		return c.sharedStrings[index], nil
+	case "inlineStr":
+		return cell.Inline.String(), nil
+	case "str", "e":
+		return cell.Value, nil
+	case "b":
+		if cell.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case "d":
+		return isoDateCell(cell.Value), nil
+	}
+
+	// Numbers
+	if cell.Value == "" {
+		return "", nil
+	}
+
+	number, err := strconv.ParseFloat(cell.Value, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid number %q", cell.Value)
+	}
+
+	kind := xlsxNumber
+	if cell.Style >= 0 && cell.Style < len(c.styles) {
+		kind = c.styles[cell.Style]
+	}
+
+	switch kind {
+	case xlsxDate:
+		return serialToTime(number, c.epoch).Format(time.DateOnly), nil
+	case xlsxDateTime:
+		return serialToTime(number, c.epoch).Format(time.DateTime), nil
+	case xlsxNumber:
+	}
+
+	return formatSpreadsheetNumber(number), nil
+}
+
+// formatSpreadsheetNumber writes numbers with at least two decimals, as
+// amounts are read as cents when they have no decimals.
+func formatSpreadsheetNumber(number float64) string {
+	formatted := strconv.FormatFloat(number, 'f', -1, 64)
+	whole, decimals, _ := strings.Cut(formatted, ".")
+	for len(decimals) < 2 {
+		decimals += "0"
+	}
+	return whole + "." + decimals
+}
+
+// xlsxColumn returns the zero based column of a cell reference like "AB12".
+func xlsxColumn(ref string) (int, error) {
+	column := 0
+	letters := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+		letters++
+	}
+
+	if letters == 0 || column > maxSpreadsheetColumns {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+
+	return column - 1, nil
+}
+
+// Built-in number formats showing dates, times or both.
+var (
+	xlsxBuiltinDateFormats     = map[int]bool{14: true, 15: true, 16: true, 17: true}
+	xlsxBuiltinDateTimeFormats = map[int]bool{
+		18: true, 19: true, 20: true, 21: true, 22: true, 45: true, 46: true, 47: true,
+	}
+)
+
+// xlsxFormatLiterals matches the parts of a format code that are not date
+// or time tokens: quoted text, escaped characters and [colors]/[$-locales].
+var xlsxFormatLiterals = regexp.MustCompile(`"[^"]*"|\\.|\[[^\]]*\]`)
+
+func xlsxNumberFormatKind(id int, customFormats map[int]string) xlsxDateKind {
+	if xlsxBuiltinDateFormats[id] {
+		return xlsxDate
+	}
+	if xlsxBuiltinDateTimeFormats[id] {
+		return xlsxDateTime
+	}
+
+	code, ok := customFormats[id]
+	if !ok {
+		return xlsxNumber
+	}
+
+	code = strings.ToLower(xlsxFormatLiterals.ReplaceAllString(code, ""))
+	hasTime := strings.ContainsAny(code, "hs")
+	hasDate := strings.ContainsAny(code, "yd")
+
+	switch {
+	case hasTime:
+		return xlsxDateTime
+	case hasDate:
+		return xlsxDate
+	default:
+		return xlsxNumber
+	}
+}
+
+// serialToTime converts a spreadsheet serial date, the days since the epoch
+// with the time of day as the fraction, rounded to the second.
+func serialToTime(serial float64, epoch time.Time) time.Time {
+	seconds := math.Round(serial * hoursPerDay * float64(time.Hour/time.Second))
+	return epoch.Add(time.Duration(seconds) * time.Second)
+}
+
+// isoDateCell formats ISO 8601 date cells like serial dates are.
+func isoDateCell(value string) string {
+	for _, layout := range []string{"2006-01-02T15:04:05", time.DateOnly, time.RFC3339} {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+			return t.Format(time.DateOnly)
+		}
+		return t.Format(time.DateTime)
+	}
+	return value
+}
+
+type odsCell struct {
+	value    string
+	repeated int
+}
+
+// parseODS reads a sheet of an OpenDocument spreadsheet.
+func parseODS(reader io.Reader, options ParseOptions) (*ParsedData, error) {
+	archive, err := openSpreadsheet(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var content *zip.File
+	for _, file := range archive.File {
+		if file.Name == "content.xml" {
+			content = file
+			break
+		}
+	}
+	if content == nil {
+		return nil, errors.New("not an ODS file: content not found")
+	}
+
+	rc, err := content.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening content.xml: %w", err)
+	}
+	defer rc.Close()
+
+	limited := &io.LimitedReader{R: rc, N: maxSpreadsheetPartSize}
+	sheets, tables, err := readODSTables(xml.NewDecoder(limited), options.Sheet)
+	if err != nil {
+		if limited.N <= 0 {
+			return nil, errors.New("content.xml is too large")
+		}
+		return nil, err
+	}
+
+	sheet, err := selectSheet(sheets, options.Sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	return spreadsheetTable(tables[sheet], "ods", sheets, sheet, options)
+}
+
+// readODSTables lists the tables of an ODS document, keeping only the rows
+// of the one named sheet, or of the first table when no name is given.
+func readODSTables(decoder *xml.Decoder, sheet string) ([]string, [][][]string, error) {
+	sheets := []string{}
+	tables := [][][]string{}
+
+	keep := false
+	var row []odsCell
+	var cell *odsCell
+	var text strings.Builder
+	inCell := false
+	rowRepeated := 1
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing content.xml: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == odsTableNS && t.Name.Local == "table":
+				name := odsAttr(t, odsTableNS, "name")
+				sheets = append(sheets, name)
+				tables = append(tables, nil)
+				keep = (sheet == "" && len(sheets) == 1) || name == sheet
+			case !keep:
+			case t.Name.Space == odsTableNS && t.Name.Local == "table-row":
+				row = []odsCell{}
+				rowRepeated = odsRepeat(t, "number-rows-repeated")
+			case t.Name.Space == odsTableNS &&
+				(t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell"):
+				cell = &odsCell{value: odsCellValue(t), repeated: odsRepeat(t, "number-columns-repeated")}
+				inCell = cell.value == ""
+				text.Reset()
+			case inCell && t.Name.Space == odsTextNS && t.Name.Local == "p" && text.Len() > 0:
+				text.WriteString("\n")
+			case inCell && t.Name.Space == odsTextNS && t.Name.Local == "s":
+				text.WriteString(strings.Repeat(" ", odsRepeat(t, "c")))
+			case inCell && t.Name.Space == odsTextNS && t.Name.Local == "tab":
+				text.WriteString("\t")
+			case inCell && t.Name.Space == odsTextNS && t.Name.Local == "line-break":
+				text.WriteString("\n")
+			}
+		case xml.CharData:
+			if keep && inCell {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if !keep || t.Name.Space != odsTableNS {
+				continue
+			}
+			switch t.Name.Local {
+			case "table-cell", "covered-table-cell":
+				if cell != nil {
+					if inCell {
+						cell.value = text.String()
+					}
+					row = append(row, *cell)
+				}
+				cell = nil
+				inCell = false
+			case "table-row":
+				// Empty rows are left out, ODS files repeat them until the
+				// end of the sheet
+				record := odsRecord(row)
+				if len(record) == 0 {
+					continue
+				}
+				for range rowRepeated {
+					tables[len(tables)-1] = append(tables[len(tables)-1], record)
+				}
+			case "table":
+				keep = false
+			}
+		}
+	}
+
+	return sheets, tables, nil
+}
+
+// odsRecord expands repeated cells, leaving out trailing empty ones.
+func odsRecord(cells []odsCell) []string {
+	last := len(cells)
+	for last > 0 && strings.TrimSpace(cells[last-1].value) == "" {
+		last--
+	}
+
+	record := []string{}
+	for _, cell := range cells[:last] {
+		for range cell.repeated {
+			if len(record) >= maxSpreadsheetColumns {
+				return record
+			}
+			record = append(record, cell.value)
+		}
+	}
+	return record
+}
+
+// odsCellValue reads the typed value of a cell, empty for text cells whose
+// value is their paragraphs.
+func odsCellValue(start xml.StartElement) string {
+	switch odsAttr(start, odsOfficeNS, "value-type") {
+	case "float", "currency", "percentage":
+		value := odsAttr(start, odsOfficeNS, "value")
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return value
+		}
+		return formatSpreadsheetNumber(number)
+	case "date":
+		return isoDateCell(odsAttr(start, odsOfficeNS, "date-value"))
+	case "boolean":
+		if odsAttr(start, odsOfficeNS, "boolean-value") == "true" {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return ""
+}
+
+func odsAttr(start xml.StartElement, space, local string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == space && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// odsRepeat reads a repetition count, table:number-*-repeated or text:c.
+func odsRepeat(start xml.StartElement, local string) int {
+	for _, attr := range start.Attr {
+		if attr.Name.Local != local {
+			continue
+		}
+		n, err := strconv.Atoi(attr.Value)
+		if err != nil || n < 1 {
+			return 1
+		}
+		return n
+	}
+	return 1
+}
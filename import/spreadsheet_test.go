@@ -0,0 +1,332 @@
+package importutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GustavoCaso/expensetrace/matcher"
+)
+
+// zipFiles builds a spreadsheet archive holding files.
+func zipFiles(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.NewReader(buf.Bytes())
+}
+
+func xlsxFiles(workbookPr string) map[string]string {
+	return map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+  xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  ` + workbookPr + `
+  <sheets>
+    <sheet name="Summary" sheetId="1" r:id="rId1"/>
+    <sheet name="Transactions" sheetId="2" r:id="rId2"/>
+  </sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+    Target="worksheets/sheet1.xml"/>
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+    Target="/xl/worksheets/sheet2.xml"/>
+  <Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+    Target="styles.xml"/>
+</Relationships>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <si><t>Date</t></si>
+  <si><t>Description</t></si>
+  <si><t>Amount</t></si>
+  <si><r><t>Coffee </t></r><r><rPr><b/></rPr><t>shop</t></r></si>
+  <si><t>Account 1234</t></si>
+  <si><t>Total</t></si>
+</sst>`,
+		"xl/styles.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <numFmts count="2">
+    <numFmt numFmtId="164" formatCode="dd/mm/yyyy"/>
+    <numFmt numFmtId="165" formatCode="#,##0.00\ &quot;€&quot;"/>
+  </numFmts>
+  <cellXfs count="5">
+    <xf numFmtId="0"/>
+    <xf numFmtId="14"/>
+    <xf numFmtId="164"/>
+    <xf numFmtId="22"/>
+    <xf numFmtId="165"/>
+  </cellXfs>
+</styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="s"><v>5</v></c><c r="B1" t="s"><v>2</v></c></row>
+    <row r="2"><c r="A2" t="inlineStr"><is><t>Spent</t></is></c><c r="B2" s="4"><v>-42.5</v></c></row>
+  </sheetData>
+</worksheet>`,
+		"xl/worksheets/sheet2.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="s"><v>4</v></c></row>
+    <row r="3"><c r="A3" t="s"><v>0</v></c><c r="B3" t="s"><v>1</v></c><c r="C3" t="s"><v>2</v></c></row>
+    <row r="4"><c r="A4" s="1"><v>45306</v></c><c r="B4" t="s"><v>3</v></c><c r="C4" s="4"><v>-3.5</v></c></row>
+    <row r="5"><c r="A5" s="2"><v>45307</v></c><c r="C5"><v>1.25E3</v></c></row>
+    <row r="6"><c r="A6" s="3"><v>45308.5</v></c><c r="B6" t="str"><v>Rent</v></c><c r="C6"><v>-800</v></c></row>
+    <row r="7"><c r="A7"/><c r="B7"/></row>
+  </sheetData>
+</worksheet>`,
+	}
+}
+
+func TestParseXLSX(t *testing.T) {
+	data, err := ParseFileWithOptions(
+		"statement.xlsx",
+		zipFiles(t, xlsxFiles("")),
+		ParseOptions{Sheet: "Transactions", HeaderRow: 1},
+	)
+	if err != nil {
+		t.Fatalf("Failed to parse XLSX: %v", err)
+	}
+
+	if data.Format != "xlsx" || data.Sheet != "Transactions" || data.HeaderRow != 1 {
+		t.Errorf("Format = %q, Sheet = %q, HeaderRow = %d", data.Format, data.Sheet, data.HeaderRow)
+	}
+
+	if !reflect.DeepEqual(data.Sheets, []string{"Summary", "Transactions"}) {
+		t.Errorf("Sheets = %v", data.Sheets)
+	}
+
+	if !reflect.DeepEqual(data.Headers, []string{"Date", "Description", "Amount"}) {
+		t.Errorf("Headers = %v", data.Headers)
+	}
+
+	expectedRows := [][]string{
+		{"2024-01-15", "Coffee shop", "-3.50"},
+		{"2024-01-16", "", "1250.00"},
+		{"2024-01-17 12:00:00", "Rent", "-800.00"},
+	}
+	if !reflect.DeepEqual(data.Rows, expectedRows) {
+		t.Errorf("Rows = %v, want %v", data.Rows, expectedRows)
+	}
+}
+
+func TestParseXLSXFirstSheetByDefault(t *testing.T) {
+	data, err := ParseFile("statement.xlsx", zipFiles(t, xlsxFiles("")))
+	if err != nil {
+		t.Fatalf("Failed to parse XLSX: %v", err)
+	}
+
+	if data.Sheet != "Summary" {
+		t.Errorf("Sheet = %q, want Summary", data.Sheet)
+	}
+
+	if !reflect.DeepEqual(data.Rows, [][]string{{"Spent", "-42.50"}}) {
+		t.Errorf("Rows = %v", data.Rows)
+	}
+}
+
+func TestParseXLSX1904DateSystem(t *testing.T) {
+	data, err := ParseFileWithOptions(
+		"statement.xlsx",
+		zipFiles(t, xlsxFiles(`<workbookPr date1904="1"/>`)),
+		ParseOptions{Sheet: "Transactions", HeaderRow: 1},
+	)
+	if err != nil {
+		t.Fatalf("Failed to parse XLSX: %v", err)
+	}
+
+	// Serials count from 1904-01-01, 1462 days later than the 1900 system
+	if data.Rows[0][0] != "2028-01-16" {
+		t.Errorf("Date = %q, want 2028-01-16", data.Rows[0][0])
+	}
+}
+
+func TestParseXLSXErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		options  ParseOptions
+		expected string
+	}{
+		{
+			name:     "unknown sheet",
+			files:    xlsxFiles(""),
+			options:  ParseOptions{Sheet: "Budget"},
+			expected: `sheet "Budget" not found`,
+		},
+		{
+			name:     "header row past the end",
+			files:    xlsxFiles(""),
+			options:  ParseOptions{HeaderRow: 5},
+			expected: "no header row",
+		},
+		{
+			name:     "not a workbook",
+			files:    map[string]string{"content.xml": "<office:document-content/>"},
+			expected: "not an XLSX file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFileWithOptions("statement.xlsx", zipFiles(t, tt.files), tt.options)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	_, err := ParseFile("statement.xlsx", strings.NewReader("date,amount"))
+	if err == nil {
+		t.Error("Expected error for a file that is not a zip archive")
+	}
+}
+
+const odsContent = `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content
+  xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+  xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+  xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+  xmlns:calcext="urn:org:documentfoundation:names:experimental:calc:xmlns:calcext:1.0">
+  <office:body>
+    <office:spreadsheet>
+      <table:table table:name="Notes">
+        <table:table-row>
+          <table:table-cell office:value-type="string"><text:p>Note</text:p></table:table-cell>
+        </table:table-row>
+        <table:table-row>
+          <table:table-cell office:value-type="string"><text:p>Nothing here</text:p></table:table-cell>
+        </table:table-row>
+      </table:table>
+      <table:table table:name="Movements">
+        <table:table-row>
+          <table:table-cell office:value-type="string"><text:p>Bank export</text:p></table:table-cell>
+        </table:table-row>
+        <table:table-row>
+          <table:table-cell office:value-type="string"><text:p>Date</text:p></table:table-cell>
+          <table:table-cell office:value-type="string"><text:p>Concept</text:p></table:table-cell>
+          <table:table-cell table:number-columns-repeated="2"/>
+          <table:table-cell office:value-type="string"><text:p>Amount</text:p></table:table-cell>
+          <table:table-cell table:number-columns-repeated="16379"/>
+        </table:table-row>
+        <table:table-row table:number-rows-repeated="2">
+          <table:table-cell office:value-type="date" office:date-value="2024-03-01" calcext:value-type="date">
+            <text:p>01/03/24</text:p>
+          </table:table-cell>
+          <table:table-cell office:value-type="string"><text:p>Gym<text:s text:c="2"/>fee</text:p></table:table-cell>
+          <table:table-cell table:number-columns-repeated="2"/>
+          <table:table-cell office:value-type="currency" office:currency="EUR" office:value="-29.9">
+            <text:p>-29,90 €</text:p>
+          </table:table-cell>
+        </table:table-row>
+        <table:table-row>
+          <table:table-cell office:value-type="date" office:date-value="2024-03-02T18:30:00"/>
+          <table:table-cell
+            office:value-type="string"><text:p>Dinner</text:p><text:p>with friends</text:p></table:table-cell>
+          <table:covered-table-cell/>
+          <table:table-cell/>
+          <table:table-cell office:value-type="float" office:value="-45"><text:p>-45</text:p></table:table-cell>
+        </table:table-row>
+        <table:table-row table:number-rows-repeated="1048570">
+          <table:table-cell table:number-columns-repeated="16384"/>
+        </table:table-row>
+      </table:table>
+    </office:spreadsheet>
+  </office:body>
+</office:document-content>`
+
+func TestParseODS(t *testing.T) {
+	files := map[string]string{
+		"mimetype":    "application/vnd.oasis.opendocument.spreadsheet",
+		"content.xml": odsContent,
+	}
+
+	options := ParseOptions{Sheet: "Movements", HeaderRow: 1}
+	data, err := ParseFileWithOptions("statement.ods", zipFiles(t, files), options)
+	if err != nil {
+		t.Fatalf("Failed to parse ODS: %v", err)
+	}
+
+	if data.Format != "ods" || data.Sheet != "Movements" {
+		t.Errorf("Format = %q, Sheet = %q", data.Format, data.Sheet)
+	}
+
+	if !reflect.DeepEqual(data.Sheets, []string{"Notes", "Movements"}) {
+		t.Errorf("Sheets = %v", data.Sheets)
+	}
+
+	if !reflect.DeepEqual(data.Headers, []string{"Date", "Concept", "Column 3", "Column 4", "Amount"}) {
+		t.Errorf("Headers = %v", data.Headers)
+	}
+
+	expectedRows := [][]string{
+		{"2024-03-01", "Gym  fee", "", "", "-29.90"},
+		{"2024-03-01", "Gym  fee", "", "", "-29.90"},
+		{"2024-03-02 18:30:00", "Dinner\nwith friends", "", "", "-45.00"},
+	}
+	if !reflect.DeepEqual(data.Rows, expectedRows) {
+		t.Errorf("Rows = %q, want %q", data.Rows, expectedRows)
+	}
+
+	data, err = ParseFile("statement.ods", zipFiles(t, files))
+	if err != nil {
+		t.Fatalf("Failed to parse ODS: %v", err)
+	}
+
+	if data.Sheet != "Notes" {
+		t.Errorf("Sheet = %q, want the first sheet", data.Sheet)
+	}
+
+	_, err = ParseFileWithOptions("statement.ods", zipFiles(t, files), ParseOptions{Sheet: "Budget"})
+	if err == nil {
+		t.Error("Expected error for an unknown sheet")
+	}
+}
+
+func TestParseODSWithMapping(t *testing.T) {
+	files := map[string]string{"content.xml": odsContent}
+
+	options := ParseOptions{Sheet: "Movements", HeaderRow: 1}
+	data, err := ParseFileWithOptions("statement.ods", zipFiles(t, files), options)
+	if err != nil {
+		t.Fatalf("Failed to parse ODS: %v", err)
+	}
+
+	result, err := ApplyMapping(data, &FieldMapping{
+		Source:            "Bank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      4,
+		CurrencyColumn:    NoColumn,
+		DefaultCurrency:   "EUR",
+	}, matcher.New(nil))
+	if err != nil {
+		t.Fatalf("Failed to apply mapping: %v", err)
+	}
+
+	if len(result.Errors) != 0 || len(result.Expenses) != 3 {
+		t.Fatalf("Expected 3 expenses and no errors, got %d and %v", len(result.Expenses), result.Errors)
+	}
+
+	if result.Expenses[2].Amount() != -4500 || result.Expenses[2].Date().Day() != 2 {
+		t.Errorf("Unexpected expense %d on %v", result.Expenses[2].Amount(), result.Expenses[2].Date())
+	}
+}
@@ -0,0 +1,270 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/util"
+)
+
+type accountHandler struct {
+	*router
+}
+
+func (a *accountHandler) RegisterRoutes(mux *routeMux) {
+	mux.HandleFunc("GET /accounts", func(w http.ResponseWriter, r *http.Request) {
+		a.accountsHandler(r.Context(), w, domain.AccountsViewData{}, nil)
+	})
+
+	mux.HandleFunc("POST /accounts", func(w http.ResponseWriter, r *http.Request) {
+		a.createAccountHandler(r.Context(), w, r)
+	})
+
+	mux.HandleFunc("GET /accounts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		a.accountHandler(r.Context(), w, r, nil, nil)
+	})
+
+	mux.HandleFunc("POST /accounts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		a.updateAccountHandler(r.Context(), w, r)
+	})
+
+	mux.HandleFunc("POST /accounts/{id}/delete", func(w http.ResponseWriter, r *http.Request) {
+		a.deleteAccountHandler(r.Context(), w, r)
+	})
+}
+
+// accountsHandler renders the list of accounts. data carries the form being
+// created when it has errors.
+func (a *accountHandler) accountsHandler(
+	ctx context.Context,
+	w http.ResponseWriter,
+	data domain.AccountsViewData,
+	banner *domain.Banner,
+) {
+	userID := userIDFromContext(ctx)
+	data.ViewBase = viewBaseFromContext(ctx)
+	data.Types = domain.AccountTypes
+	if data.FormErrors == nil {
+		data.FormErrors = map[string]string{}
+		data.Form = domain.AccountFormData{Type: domain.CheckingAccount}
+	}
+	if banner != nil {
+		data.Banner = *banner
+	}
+
+	defer func() {
+		a.renderHTML(w, http.StatusOK, data, "base", "pages/accounts/index.html")
+	}()
+
+	accounts, err := a.accountService.Summaries(ctx, userID)
+	if err != nil {
+		a.logger.Error("Failed to get accounts", "error", err, "user_id", userID)
+		data.Error = err.Error()
+		return
+	}
+
+	data.Accounts = accounts
+}
+
+func (a *accountHandler) createAccountHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
+	formErrors := map[string]string{}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	form, err := parseAccountForm(r, formErrors)
+	if err != nil {
+		a.accountsHandler(ctx, w, domain.AccountsViewData{
+			ViewBase: domain.ViewBase{Error: err.Error()},
+		}, nil)
+		return
+	}
+
+	data := domain.AccountsViewData{Form: form, FormErrors: formErrors}
+	if len(formErrors) > 0 {
+		a.accountsHandler(ctx, w, data, nil)
+		return
+	}
+
+	account, err := a.accountService.Create(ctx, userID, form)
+	if err != nil {
+		data.Error = err.Error()
+		a.accountsHandler(ctx, w, data, nil)
+		return
+	}
+
+	a.accountsHandler(ctx, w, domain.AccountsViewData{}, &domain.Banner{
+		Icon:    "✅",
+		Message: fmt.Sprintf("Account %s created", account.Name()),
+	})
+}
+
+// accountHandler renders an account with its history. form replaces the
+// stored details in the edit form when it has errors.
+func (a *accountHandler) accountHandler(
+	ctx context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	form *accountForm,
+	banner *domain.Banner,
+) {
+	userID := userIDFromContext(ctx)
+	data := domain.AccountViewData{
+		ViewBase:   viewBaseFromContext(ctx),
+		Types:      domain.AccountTypes,
+		FormErrors: map[string]string{},
+	}
+	if banner != nil {
+		data.Banner = *banner
+	}
+
+	defer func() {
+		a.renderHTML(w, http.StatusOK, data, "base", "pages/accounts/show.html")
+	}()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		data.Error = fmt.Sprintf("Invalid account ID: %s", err.Error())
+		return
+	}
+
+	summary, history, err := a.accountService.History(ctx, userID, id)
+	if err != nil {
+		var notFound *domain.NotFoundError
+		if errors.As(err, &notFound) {
+			data.Error = "Account not found"
+			return
+		}
+		a.logger.Error("Failed to get account", "error", err, "id", id)
+		data.Error = err.Error()
+		return
+	}
+
+	data.Account = summary
+	data.History = history
+	data.Form = domain.AccountFormData{
+		Name:           summary.Name(),
+		Institution:    summary.Institution(),
+		Currency:       summary.Currency(),
+		Type:           summary.Type(),
+		OpeningBalance: summary.OpeningBalance(),
+	}
+
+	if form != nil {
+		data.Form = form.data
+		data.FormErrors = form.errors
+		if form.err != nil {
+			data.Error = form.err.Error()
+		}
+	}
+}
+
+// accountForm is a submitted account form that failed to save.
+type accountForm struct {
+	data   domain.AccountFormData
+	errors map[string]string
+	err    error
+}
+
+func (a *accountHandler) updateAccountHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
+	formErrors := map[string]string{}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		a.accountsHandler(ctx, w, domain.AccountsViewData{
+			ViewBase: domain.ViewBase{Error: fmt.Sprintf("Invalid account ID: %s", err.Error())},
+		}, nil)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	form, err := parseAccountForm(r, formErrors)
+	if err != nil || len(formErrors) > 0 {
+		a.accountHandler(ctx, w, r, &accountForm{data: form, errors: formErrors, err: err}, nil)
+		return
+	}
+
+	account, err := a.accountService.Update(ctx, userID, id, form)
+	if err != nil {
+		a.accountHandler(ctx, w, r, &accountForm{data: form, errors: formErrors, err: err}, nil)
+		return
+	}
+
+	a.accountHandler(ctx, w, r, nil, &domain.Banner{
+		Icon:    "✅",
+		Message: fmt.Sprintf("Account %s updated", account.Name()),
+	})
+}
+
+func (a *accountHandler) deleteAccountHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		a.accountsHandler(ctx, w, domain.AccountsViewData{
+			ViewBase: domain.ViewBase{Error: fmt.Sprintf("Invalid account ID: %s", err.Error())},
+		}, nil)
+		return
+	}
+
+	if err = a.accountService.Delete(ctx, userID, id); err != nil {
+		a.accountsHandler(ctx, w, domain.AccountsViewData{
+			ViewBase: domain.ViewBase{Error: fmt.Sprintf("Error deleting the account: %s", err.Error())},
+		}, nil)
+		return
+	}
+
+	a.accountsHandler(ctx, w, domain.AccountsViewData{}, &domain.Banner{
+		Icon:    "✅",
+		Message: "Account deleted",
+	})
+}
+
+// parseAccountForm parses the account form fields, recording invalid fields
+// in formErrors.
+func parseAccountForm(r *http.Request, formErrors map[string]string) (domain.AccountFormData, error) {
+	if err := r.ParseForm(); err != nil {
+		return domain.AccountFormData{}, fmt.Errorf("invalid form data: %w", err)
+	}
+
+	form := domain.AccountFormData{
+		Name:        strings.TrimSpace(r.FormValue("name")),
+		Institution: strings.TrimSpace(r.FormValue("institution")),
+		Currency:    r.FormValue("currency"),
+		Type:        domain.AccountType(r.FormValue("type")),
+	}
+
+	if form.Name == "" {
+		formErrors["name"] = nameIsRequired
+	}
+
+	if form.Currency == "" {
+		formErrors["currency"] = currencyIsRequired
+	} else if currency, ok := util.NormalizeCurrency(form.Currency); ok {
+		form.Currency = currency
+	} else {
+		formErrors["currency"] = currencyInvalid
+	}
+
+	if !slices.Contains(domain.AccountTypes, form.Type) {
+		formErrors["type"] = typeInvalid
+	}
+
+	if balanceStr := strings.TrimSpace(r.FormValue("opening_balance")); balanceStr != "" {
+		balance, err := strconv.ParseFloat(balanceStr, 64)
+		if err != nil {
+			formErrors["opening_balance"] = amountInvalidFormat
+		} else {
+			form.OpeningBalance = int64(math.Round(balance * centsMultiplier))
+		}
+	}
+
+	return form, nil
+}
@@ -0,0 +1,166 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func TestAccountsHandler(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	handler := New(s, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts", nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status OK; got %v", resp.Status)
+	}
+
+	ensureNoErrorInTemplateResponse(t, "accounts", resp.Body)
+}
+
+func TestCreateAccountHandler(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	handler := New(s, logger)
+
+	form := url.Values{}
+	form.Set("name", "Revolut")
+	form.Set("institution", "Revolut Ltd")
+	form.Set("currency", "eur")
+	form.Set("type", string(domain.CheckingAccount))
+	form.Set("opening_balance", "120.50")
+
+	req := httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "Account Revolut created") {
+		t.Fatalf("Expected the account to be created, got %s", w.Body.String())
+	}
+
+	accounts, err := s.GetAccounts(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get accounts: %v", err)
+	}
+	if len(accounts) != 1 {
+		t.Fatalf("Expected 1 account, got %d", len(accounts))
+	}
+	if accounts[0].Currency() != "EUR" || accounts[0].OpeningBalance() != 12050 {
+		t.Errorf("Expected EUR with an opening balance of 12050, got %s and %d",
+			accounts[0].Currency(), accounts[0].OpeningBalance())
+	}
+
+	// Missing fields are reported on the form
+	form = url.Values{}
+	form.Set("type", "piggy")
+	req = httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, message := range []string{nameIsRequired, currencyIsRequired, typeInvalid} {
+		if !strings.Contains(body, message) {
+			t.Errorf("Expected the form error %q", message)
+		}
+	}
+}
+
+func TestAccountHandler(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	account, err := s.CreateAccount(context.Background(), user.ID(), domain.NewAccount(
+		0, "Checking", "", "EUR", domain.CheckingAccount, 10000, time.Time{},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	accountID := account.ID()
+
+	_, err = s.InsertExpenses(context.Background(), user.ID(), []domain.Expense{
+		domain.NewExpense(0, "bank", "Groceries", "EUR", -2550, time.Now(), domain.ChargeType, nil, &accountID),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	handler := New(s, logger)
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", accountID), nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status OK; got %v", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read the response: %v", err)
+	}
+	if !strings.Contains(string(body), "Groceries") {
+		t.Error("Expected the account history to list the expense")
+	}
+	if !strings.Contains(string(body), "74,50") {
+		t.Errorf("Expected the account balance of 74,50, got %s", body)
+	}
+}
+
+func TestDeleteAccountHandler(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	account, err := s.CreateAccount(context.Background(), user.ID(), domain.NewAccount(
+		0, "Cash", "", "EUR", domain.CashAccount, 0, time.Time{},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	handler := New(s, logger)
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/accounts/%d/delete", account.ID()), nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "Account deleted") {
+		t.Fatalf("Expected the account to be deleted, got %s", w.Body.String())
+	}
+
+	accounts, err := s.GetAccounts(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get accounts: %v", err)
+	}
+	if len(accounts) != 0 {
+		t.Errorf("Expected no accounts, got %d", len(accounts))
+	}
+}
@@ -0,0 +1,239 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+// apiPrefix is where the JSON API is served. Breaking changes to the API
+// get a new version prefix.
+const apiPrefix = "/api/v1"
+
+const (
+	// maxJSONSize bounds the request bodies of the API.
+	maxJSONSize = 1 << 20 // 1MB
+
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// Error codes of the API, returned in apiError.Code.
+const (
+	errCodeBadRequest   = "bad_request"
+	errCodeValidation   = "validation_failed"
+	errCodeUnauthorized = "unauthorized"
+	errCodeNotFound     = "not_found"
+	errCodeImport       = "import_failed"
+	errCodeInternal     = "internal_error"
+)
+
+type apiHandler struct {
+	*router
+}
+
+func (a *apiHandler) RegisterRoutes(mux *routeMux) {
+	mux.HandleFunc("GET "+apiPrefix+"/expenses", a.listExpensesHandler)
+	mux.HandleFunc("POST "+apiPrefix+"/expenses", a.createExpenseHandler)
+	mux.HandleFunc("GET "+apiPrefix+"/expenses/{id}", a.expenseHandler)
+	mux.HandleFunc("PUT "+apiPrefix+"/expenses/{id}", a.updateExpenseHandler)
+	mux.HandleFunc("DELETE "+apiPrefix+"/expenses/{id}", a.deleteExpenseHandler)
+
+	mux.HandleFunc("GET "+apiPrefix+"/categories", a.listCategoriesHandler)
+	mux.HandleFunc("POST "+apiPrefix+"/categories", a.createCategoryHandler)
+	mux.HandleFunc("GET "+apiPrefix+"/categories/uncategorized", a.uncategorizedHandler)
+	mux.HandleFunc("GET "+apiPrefix+"/categories/{id}", a.categoryHandler)
+	mux.HandleFunc("PATCH "+apiPrefix+"/categories/{id}", a.updateCategoryHandler)
+	mux.HandleFunc("DELETE "+apiPrefix+"/categories/{id}", a.deleteCategoryHandler)
+
+	mux.HandleFunc("GET "+apiPrefix+"/reports/{year}/{month}", a.reportHandler)
+
+	mux.HandleFunc("POST "+apiPrefix+"/imports", a.uploadImportHandler)
+	mux.HandleFunc("POST "+apiPrefix+"/imports/{session}/mapping", a.importMappingHandler)
+	mux.HandleFunc("POST "+apiPrefix+"/imports/{session}/execute", a.executeImportHandler)
+
+	// Unknown API routes answer with an error object rather than the HTML app
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, _ *http.Request) {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "no such API route")
+	})
+}
+
+// apiResponse wraps every successful response body. Pagination is only set
+// on lists.
+type apiResponse struct {
+	Data       any            `json:"data"`
+	Pagination *apiPagination `json:"pagination,omitempty"`
+}
+
+type apiPagination struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// apiErrorResponse is the body of every failed request.
+type apiErrorResponse struct {
+	Error apiError `json:"error"`
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields holds the validation message of each invalid field.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeAPIData(w http.ResponseWriter, status int, data any) {
+	writeJSON(w, status, apiResponse{Data: data})
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiErrorResponse{Error: apiError{Code: code, Message: message}})
+}
+
+func writeAPIValidationError(w http.ResponseWriter, fields map[string]string) {
+	writeJSON(w, http.StatusUnprocessableEntity, apiErrorResponse{Error: apiError{
+		Code:    errCodeValidation,
+		Message: "the request has invalid fields",
+		Fields:  fields,
+	}})
+}
+
+// writeStorageError answers with not found for missing records, and hides
+// any other error behind an internal error.
+func (a *apiHandler) writeStorageError(w http.ResponseWriter, err error, message string) {
+	var notFound *domain.NotFoundError
+	if errors.As(err, &notFound) {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, message)
+		return
+	}
+
+	a.logger.Error("API request failed", "error", err)
+	writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
+}
+
+// decodeJSON reads the request body into v, answering with a bad request
+// when it cannot.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONSize)
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+
+	return true
+}
+
+// pathID parses the {id} path value, answering with not found when it is
+// not an ID.
+func pathID(w http.ResponseWriter, r *http.Request, message string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, message)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// parsePagination reads the page and per_page query parameters. Pages start
+// at 1.
+func parsePagination(r *http.Request) (int, int, error) {
+	query := r.URL.Query()
+	page, perPage := 1, defaultPageSize
+
+	if value := query.Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, errors.New("invalid page, it must be a positive number")
+		}
+		page = parsed
+	}
+
+	if value := query.Get("per_page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageSize {
+			return 0, 0, errors.New("invalid per_page, it must be between 1 and 500")
+		}
+		perPage = parsed
+	}
+
+	return page, perPage, nil
+}
+
+// writeAPIPage answers with the requested page of items.
+func writeAPIPage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	page, perPage, err := parsePagination(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, err.Error())
+		return
+	}
+
+	// Empty pages are encoded as an empty list rather than null
+	if items == nil {
+		items = []T{}
+	}
+
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+
+	writeJSON(w, http.StatusOK, apiResponse{
+		Data: items[start:end],
+		Pagination: &apiPagination{
+			Page:       page,
+			PerPage:    perPage,
+			Total:      len(items),
+			TotalPages: (len(items) + perPage - 1) / perPage,
+		},
+	})
+}
+
+// tokenAuthMiddleware authenticates API requests with the bearer token of
+// the Authorization header. Other requests are left to authMiddleware, and
+// the OpenAPI document is public.
+func tokenAuthMiddleware(router *router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == openAPIPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, errCodeUnauthorized, "missing bearer token")
+			return
+		}
+
+		user, err := router.authService.AuthenticatedTokenUser(r.Context(), strings.TrimSpace(token))
+		if err != nil {
+			var notFoundErr *domain.NotFoundError
+			if errors.As(err, &notFoundErr) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeAPIError(w, http.StatusUnauthorized, errCodeUnauthorized, "invalid or revoked token")
+				return
+			}
+			router.logger.Error("Failed to authenticate API token", "error", err)
+			writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userIDKey, user.ID())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,224 @@
+package router
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+// apiCategory is a category as returned by the API.
+type apiCategory struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Pattern       string `json:"pattern"`
+	MonthlyBudget int64  `json:"monthly_budget"`
+}
+
+// apiCreatedCategory is the response to a created category, with the number
+// of uncategorized expenses it matched.
+type apiCreatedCategory struct {
+	apiCategory
+	CategorizedExpenses int `json:"categorized_expenses"`
+}
+
+// apiCategoryRequest is the body creating or updating a category. On updates
+// omitted fields keep their value.
+type apiCategoryRequest struct {
+	Name          string `json:"name"`
+	Pattern       string `json:"pattern"`
+	MonthlyBudget *int64 `json:"monthly_budget"`
+}
+
+// apiUncategorizedGroup is the uncategorized expenses sharing a description.
+type apiUncategorizedGroup struct {
+	Description string           `json:"description"`
+	Slug        string           `json:"slug"`
+	Count       int              `json:"count"`
+	Total       int64            `json:"total"`
+	Expenses    []domain.Expense `json:"expenses"`
+}
+
+func newAPICategory(c domain.Category) apiCategory {
+	return apiCategory{
+		ID:            c.ID(),
+		Name:          c.Name(),
+		Pattern:       c.Pattern(),
+		MonthlyBudget: c.MonthlyBudget(),
+	}
+}
+
+func (a *apiHandler) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	categories, err := a.categoryService.List(ctx, userIDFromContext(ctx))
+	if err != nil {
+		a.writeStorageError(w, err, "record not found")
+		return
+	}
+
+	result := make([]apiCategory, 0, len(categories))
+	for _, c := range categories {
+		result = append(result, newAPICategory(c))
+	}
+
+	writeAPIPage(w, r, result)
+}
+
+func (a *apiHandler) categoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r, "category not found")
+	if !ok {
+		return
+	}
+
+	c, err := a.categoryService.Get(ctx, userIDFromContext(ctx), id)
+	if err != nil {
+		a.writeStorageError(w, err, "category not found")
+		return
+	}
+
+	writeAPIData(w, http.StatusOK, newAPICategory(c))
+}
+
+// createCategoryHandler creates a category, categorizing the uncategorized
+// expenses it matches.
+func (a *apiHandler) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var body apiCategoryRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	fields := map[string]string{}
+	if body.Name == "" {
+		fields["name"] = "Name is required"
+	}
+	if body.Pattern == "" {
+		fields["pattern"] = "Pattern is required"
+	} else if _, err := regexp.Compile(body.Pattern); err != nil {
+		fields["pattern"] = "Invalid pattern: " + err.Error()
+	}
+	var monthlyBudget int64
+	if body.MonthlyBudget != nil {
+		if monthlyBudget = *body.MonthlyBudget; monthlyBudget < 0 {
+			fields["monthly_budget"] = "Budget cannot be negative"
+		}
+	}
+	if len(fields) > 0 {
+		writeAPIValidationError(w, fields)
+		return
+	}
+
+	form := domain.CategoryFormData{
+		Name:          body.Name,
+		Pattern:       body.Pattern,
+		MonthlyBudget: monthlyBudget,
+	}
+
+	id, matched, err := a.categoryService.Create(ctx, userIDFromContext(ctx), form)
+	if err != nil {
+		a.writeStorageError(w, err, "record not found")
+		return
+	}
+
+	a.logger.Info("Category created from the API", "id", id)
+
+	writeAPIData(w, http.StatusCreated, apiCreatedCategory{
+		apiCategory:         newAPICategory(domain.NewCategory(id, form.Name, form.Pattern, form.MonthlyBudget)),
+		CategorizedExpenses: len(matched),
+	})
+}
+
+// updateCategoryHandler updates the given fields of a category. A new
+// pattern recategorizes the expenses, like on the category page.
+func (a *apiHandler) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r, "category not found")
+	if !ok {
+		return
+	}
+
+	var body apiCategoryRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	budget := ""
+	if body.MonthlyBudget != nil {
+		if *body.MonthlyBudget < 0 {
+			writeAPIValidationError(w, map[string]string{"monthly_budget": "Budget cannot be negative"})
+			return
+		}
+		budget = strconv.FormatFloat(float64(*body.MonthlyBudget)/centsMultiplier, 'f', 2, 64)
+	}
+
+	if body.Pattern != "" {
+		if _, err := regexp.Compile(body.Pattern); err != nil {
+			writeAPIValidationError(w, map[string]string{"pattern": "Invalid pattern: " + err.Error()})
+			return
+		}
+	}
+
+	updated, _, _, err := a.categoryService.Update(ctx, userIDFromContext(ctx), id, body.Name, body.Pattern, budget)
+	if err != nil {
+		a.writeStorageError(w, err, "category not found")
+		return
+	}
+
+	writeAPIData(w, http.StatusOK, newAPICategory(updated))
+}
+
+func (a *apiHandler) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	id, ok := pathID(w, r, "category not found")
+	if !ok {
+		return
+	}
+
+	if _, err := a.categoryService.Get(ctx, userID, id); err != nil {
+		a.writeStorageError(w, err, "category not found")
+		return
+	}
+
+	if err := a.categoryService.Delete(ctx, userID, id); err != nil {
+		a.writeStorageError(w, err, "category not found")
+		return
+	}
+
+	a.logger.Info("Category deleted from the API", "id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// uncategorizedHandler lists the uncategorized expenses grouped by
+// description, the most frequent first. The q parameter searches them.
+func (a *apiHandler) uncategorizedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	groups, keys, _, _, err := a.categoryService.GetUncategorized(ctx, userIDFromContext(ctx), r.URL.Query().Get("q"))
+	if err != nil {
+		a.writeStorageError(w, err, "record not found")
+		return
+	}
+
+	result := make([]apiUncategorizedGroup, 0, len(keys))
+	for _, key := range keys {
+		group := groups[key]
+		result = append(result, apiUncategorizedGroup{
+			Description: key,
+			Slug:        group.Slug,
+			Count:       group.Count,
+			Total:       group.Total,
+			Expenses:    group.Expenses,
+		})
+	}
+
+	writeAPIPage(w, r, result)
+}
@@ -0,0 +1,228 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+// apiExpenseRequest is the body creating or replacing an expense. Its fields
+// are named like the expenses the API returns, with the amount in cents.
+type apiExpenseRequest struct {
+	Source      string              `json:"source"`
+	Description string              `json:"description"`
+	Amount      int64               `json:"amount"`
+	Currency    string              `json:"currency"`
+	Date        string              `json:"date"`
+	ExpenseType *domain.ExpenseType `json:"expense_type"`
+	CategoryID  *int64              `json:"category_id"`
+	AccountID   *int64              `json:"account_id"`
+}
+
+// listExpensesHandler lists the expenses matching the same filter and sort
+// query parameters as the expenses page.
+func (a *apiHandler) listExpensesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	expenseFilter, sortOptions, err := domain.ParseExpenseFilters(r.URL.Query())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, err.Error())
+		return
+	}
+
+	expenses, err := a.expenseService.List(ctx, userIDFromContext(ctx), expenseFilter, sortOptions)
+	if err != nil {
+		a.writeStorageError(w, err, "record not found")
+		return
+	}
+
+	writeAPIPage(w, r, expenses)
+}
+
+func (a *apiHandler) expenseHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r, "expense not found")
+	if !ok {
+		return
+	}
+
+	expenseView, err := a.expenseService.Get(ctx, userIDFromContext(ctx), id)
+	if err != nil {
+		a.writeStorageError(w, err, "expense not found")
+		return
+	}
+
+	writeAPIData(w, http.StatusOK, expenseView.Expense)
+}
+
+func (a *apiHandler) createExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	var body apiExpenseRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	newExpense, ok := a.expenseFromRequest(w, r, 0, body)
+	if !ok {
+		return
+	}
+
+	created, err := a.expenseService.Create(ctx, userID, newExpense)
+	if err != nil {
+		a.writeStorageError(w, err, "record not found")
+		return
+	}
+
+	a.logger.Info("Expense created from the API", "id", created.ID())
+
+	writeAPIData(w, http.StatusCreated, created)
+}
+
+// updateExpenseHandler replaces every field of an expense.
+func (a *apiHandler) updateExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	id, ok := pathID(w, r, "expense not found")
+	if !ok {
+		return
+	}
+
+	if _, err := a.expenseService.Get(ctx, userID, id); err != nil {
+		a.writeStorageError(w, err, "expense not found")
+		return
+	}
+
+	var body apiExpenseRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	updatedExpense, ok := a.expenseFromRequest(w, r, id, body)
+	if !ok {
+		return
+	}
+
+	updated, err := a.expenseService.Update(ctx, userID, updatedExpense)
+	if err != nil {
+		a.writeStorageError(w, err, "record not found")
+		return
+	}
+	if updated != 1 {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "expense not found")
+		return
+	}
+
+	a.logger.Info("Expense updated from the API", "id", id)
+
+	writeAPIData(w, http.StatusOK, updatedExpense)
+}
+
+func (a *apiHandler) deleteExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	id, ok := pathID(w, r, "expense not found")
+	if !ok {
+		return
+	}
+
+	if _, err := a.expenseService.Get(ctx, userID, id); err != nil {
+		a.writeStorageError(w, err, "expense not found")
+		return
+	}
+
+	if err := a.expenseService.Delete(ctx, userID, id); err != nil {
+		a.writeStorageError(w, err, "record not found")
+		return
+	}
+
+	a.logger.Info("Expense deleted from the API", "id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// expenseFromRequest validates the body the same way as the expense form,
+// answering with the invalid fields when it is not valid.
+func (a *apiHandler) expenseFromRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	id int64,
+	body apiExpenseRequest,
+) (domain.Expense, bool) {
+	fields := map[string]string{}
+
+	if body.Source == "" {
+		fields["source"] = sourceIsRequired
+	}
+	if body.Description == "" {
+		fields["description"] = descriptionIsRequired
+	}
+	if body.Currency == "" {
+		fields["currency"] = currencyIsRequired
+	}
+	if body.Amount == 0 {
+		fields["amount"] = amountIsRequired
+	}
+
+	// Dates are accepted as days or as the timestamps the API returns
+	var date time.Time
+	if body.Date == "" {
+		fields["date"] = dateIsRequired
+	} else {
+		var err error
+		if date, err = time.Parse("2006-01-02", body.Date); err != nil {
+			if date, err = time.Parse(time.RFC3339, body.Date); err != nil {
+				fields["date"] = dateInvalidFormat
+			}
+		}
+	}
+
+	expenseType := domain.ChargeType
+	if body.ExpenseType == nil {
+		fields["expense_type"] = typeIsRequired
+	} else if *body.ExpenseType != domain.ChargeType && *body.ExpenseType != domain.IncomeType {
+		fields["expense_type"] = typeInvalid
+	} else {
+		expenseType = *body.ExpenseType
+	}
+
+	if body.CategoryID != nil {
+		if _, err := a.categoryService.Get(r.Context(), userIDFromContext(r.Context()), *body.CategoryID); err != nil {
+			fields["category_id"] = categoryInvalid
+		}
+	}
+
+	if body.AccountID != nil {
+		if _, err := a.accountService.Get(r.Context(), userIDFromContext(r.Context()), *body.AccountID); err != nil {
+			fields["account_id"] = accountInvalid
+		}
+	}
+
+	if len(fields) > 0 {
+		writeAPIValidationError(w, fields)
+		return nil, false
+	}
+
+	// The type decides the sign, like in the expense form
+	amount := body.Amount
+	if (expenseType == domain.ChargeType && amount > 0) || (expenseType == domain.IncomeType && amount < 0) {
+		amount = -amount
+	}
+
+	return domain.NewExpense(
+		id,
+		body.Source,
+		body.Description,
+		body.Currency,
+		amount,
+		date,
+		expenseType,
+		body.CategoryID,
+		body.AccountID,
+	), true
+}
@@ -0,0 +1,325 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	importUtil "github.com/GustavoCaso/expensetrace/import"
+	"github.com/GustavoCaso/expensetrace/service/importsvc"
+)
+
+const (
+	importStatusImported        = "imported"
+	importStatusMappingRequired = "mapping_required"
+)
+
+// apiImport is the response to an uploaded file. Files the app recognizes
+// are imported right away, any other file starts an import session to be
+// mapped and executed.
+type apiImport struct {
+	Status  string            `json:"status"`
+	Result  *apiImportResult  `json:"result,omitempty"`
+	Session *apiImportSession `json:"session,omitempty"`
+}
+
+type apiImportResult struct {
+	Imported          int         `json:"imported"`
+	WithoutCategory   int         `json:"without_category"`
+	ErrorRows         int         `json:"error_rows"`
+	ExactDuplicates   int         `json:"exact_duplicates"`
+	LikelyDuplicates  int         `json:"likely_duplicates"`
+	SkippedDuplicates int         `json:"skipped_duplicates"`
+	ImportBatchID     int64       `json:"import_batch_id"`
+	ClosingBalance    *apiBalance `json:"closing_balance,omitempty"`
+	// RejectedStatements counts the MT940 statements left out because they
+	// do not match their closing balance, RejectedReason explaining which.
+	RejectedStatements int    `json:"rejected_statements"`
+	RejectedReason     string `json:"rejected_reason,omitempty"`
+}
+
+type apiBalance struct {
+	Date     string `json:"date"`
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+}
+
+// apiImportSession is the preview of a file waiting for its field mapping.
+type apiImportSession struct {
+	ID          string     `json:"id"`
+	Filename    string     `json:"filename"`
+	Headers     []string   `json:"headers"`
+	PreviewRows [][]string `json:"preview_rows"`
+	TotalRows   int        `json:"total_rows"`
+	Sheets      []string   `json:"sheets,omitempty"`
+	Sheet       string     `json:"sheet,omitempty"`
+	HeaderRow   int        `json:"header_row"`
+	Delimiter   string     `json:"delimiter,omitempty"`
+	Encoding    string     `json:"encoding,omitempty"`
+	RecordsPath string     `json:"records_path,omitempty"`
+}
+
+// apiMappingRequest applies either a field mapping or a saved mapping to an
+// import session.
+type apiMappingRequest struct {
+	Mapping          *importUtil.FieldMapping `json:"mapping"`
+	MappingProfileID *int64                   `json:"mapping_profile_id"`
+}
+
+// apiMappingPreview is the outcome of a field mapping, to review before the
+// import is executed.
+type apiMappingPreview struct {
+	Headers         []string          `json:"headers"`
+	PreviewExpenses []domain.Expense  `json:"preview_expenses"`
+	TotalRows       int               `json:"total_rows"`
+	Errors          []string          `json:"errors"`
+	DateLayout      string            `json:"date_layout"`
+	Warnings        []string          `json:"warnings"`
+	Ambiguities     []apiAmbiguity    `json:"ambiguities"`
+	NewRows         int               `json:"new_rows"`
+	Duplicates      []apiDuplicateRow `json:"duplicates"`
+}
+
+type apiAmbiguity struct {
+	Field      string            `json:"field"`
+	Candidates []string          `json:"candidates"`
+	Rows       []apiAmbiguousRow `json:"rows"`
+}
+
+type apiAmbiguousRow struct {
+	Row     int      `json:"row"`
+	Value   string   `json:"value"`
+	Results []string `json:"results"`
+}
+
+type apiDuplicateRow struct {
+	Row      int                    `json:"row"`
+	Status   domain.DuplicateStatus `json:"status"`
+	Expense  domain.Expense         `json:"expense"`
+	Existing domain.Expense         `json:"existing"`
+}
+
+// apiExecuteRequest lists the rows flagged as duplicates to import anyway.
+type apiExecuteRequest struct {
+	IncludeRows []int `json:"include_rows"`
+}
+
+// uploadImportHandler imports the file of the multipart file field.
+func (a *apiHandler) uploadImportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxMemory); err != nil { //nolint:gosec // MaxBytesReader applied above
+		writeAPIError(w, http.StatusBadRequest, errCodeBadRequest, "invalid multipart form: "+err.Error())
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		message := "error retrieving the file"
+		if errors.Is(err, http.ErrMissingFile) {
+			message = "no file submitted"
+		}
+		writeAPIValidationError(w, map[string]string{"file": message})
+		return
+	}
+	defer file.Close()
+
+	var accountID *int64
+	if value := r.FormValue("account_id"); value != "" {
+		id, parseErr := strconv.ParseInt(value, 10, 64)
+		if parseErr != nil {
+			writeAPIValidationError(w, map[string]string{"account_id": accountInvalid})
+			return
+		}
+		accountID = &id
+	}
+
+	categoryMatcher, err := a.categoryMatcher(ctx, userID)
+	if err != nil {
+		a.writeStorageError(w, err, "record not found")
+		return
+	}
+
+	info, needsPreview, previewReader, err := a.importService.ImportFile(
+		ctx,
+		userID,
+		accountID,
+		header.Filename,
+		file,
+		categoryMatcher,
+	)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, errCodeImport, err.Error())
+		return
+	}
+
+	if needsPreview {
+		preview, previewErr := a.importService.Preview(ctx, userID, header.Filename, previewReader)
+		if previewErr != nil {
+			writeAPIError(w, http.StatusUnprocessableEntity, errCodeImport, "error parsing file: "+previewErr.Error())
+			return
+		}
+
+		writeAPIData(w, http.StatusCreated, apiImport{
+			Status:  importStatusMappingRequired,
+			Session: newAPIImportSession(preview),
+		})
+		return
+	}
+
+	if info.Error != nil && info.TotalImports == 0 {
+		writeAPIError(w, http.StatusUnprocessableEntity, errCodeImport, "error importing expenses: "+info.Error.Error())
+		return
+	}
+
+	result := &apiImportResult{
+		Imported:           info.TotalImports,
+		WithoutCategory:    info.ImportWithoutCategory,
+		ErrorRows:          info.InvalidRows,
+		ExactDuplicates:    info.ExactDuplicates,
+		LikelyDuplicates:   info.LikelyDuplicates,
+		SkippedDuplicates:  info.SkippedDuplicates,
+		ImportBatchID:      info.ImportBatchID,
+		RejectedStatements: info.RejectedStatements,
+		RejectedReason:     info.RejectedReason,
+	}
+	if info.ClosingBalance != nil {
+		result.ClosingBalance = &apiBalance{
+			Date:     info.ClosingBalance.Date.Format("2006-01-02"),
+			Currency: info.ClosingBalance.Currency,
+			Amount:   info.ClosingBalance.Amount,
+		}
+	}
+
+	writeAPIData(w, http.StatusCreated, apiImport{Status: importStatusImported, Result: result})
+}
+
+// importMappingHandler applies a field mapping to an import session,
+// returning the preview of the mapped rows.
+func (a *apiHandler) importMappingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+	sessionID := r.PathValue("session")
+
+	// Columns left out of the mapping are not mapped
+	body := apiMappingRequest{Mapping: &importUtil.FieldMapping{CurrencyColumn: importUtil.NoColumn}}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	categoryMatcher, err := a.categoryMatcher(ctx, userID)
+	if err != nil {
+		a.writeStorageError(w, err, "record not found")
+		return
+	}
+
+	var result importsvc.MappingApplication
+	if body.MappingProfileID != nil {
+		result, err = a.importService.ApplyMappingProfile(
+			ctx,
+			userID,
+			sessionID,
+			*body.MappingProfileID,
+			categoryMatcher,
+		)
+	} else {
+		if body.Mapping.Source == "" {
+			writeAPIValidationError(w, map[string]string{"mapping.source": sourceIsRequired})
+			return
+		}
+		result, err = a.importService.ApplyMapping(ctx, userID, sessionID, body.Mapping, categoryMatcher)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, errCodeImport, err.Error())
+		return
+	}
+
+	preview := apiMappingPreview{
+		Headers:         result.Headers,
+		PreviewExpenses: result.PreviewExpenses,
+		TotalRows:       result.TotalRows,
+		Errors:          result.Errors,
+		DateLayout:      result.DateLayout,
+		Warnings:        result.Warnings,
+		Ambiguities:     make([]apiAmbiguity, 0, len(result.Ambiguities)),
+		NewRows:         result.NewRows,
+		Duplicates:      make([]apiDuplicateRow, 0, len(result.Duplicates)),
+	}
+	for _, ambiguity := range result.Ambiguities {
+		rows := make([]apiAmbiguousRow, 0, len(ambiguity.Rows))
+		for _, row := range ambiguity.Rows {
+			rows = append(rows, apiAmbiguousRow{Row: row.Row, Value: row.Value, Results: row.Results})
+		}
+		preview.Ambiguities = append(preview.Ambiguities, apiAmbiguity{
+			Field:      ambiguity.Field,
+			Candidates: ambiguity.Candidates,
+			Rows:       rows,
+		})
+	}
+	for _, duplicate := range result.Duplicates {
+		preview.Duplicates = append(preview.Duplicates, apiDuplicateRow{
+			Row:      duplicate.Row,
+			Status:   duplicate.Status,
+			Expense:  duplicate.Expense,
+			Existing: duplicate.Existing,
+		})
+	}
+
+	writeAPIData(w, http.StatusOK, preview)
+}
+
+// executeImportHandler imports the rows of a mapped import session.
+func (a *apiHandler) executeImportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	// The body is optional, without it no duplicate is imported
+	var body apiExecuteRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &body) {
+		return
+	}
+
+	categoryMatcher, err := a.categoryMatcher(ctx, userID)
+	if err != nil {
+		a.writeStorageError(w, err, "record not found")
+		return
+	}
+
+	result, err := a.importService.Execute(ctx, userID, r.PathValue("session"), body.IncludeRows, categoryMatcher)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, errCodeImport, err.Error())
+		return
+	}
+
+	writeAPIData(w, http.StatusCreated, apiImport{
+		Status: importStatusImported,
+		Result: &apiImportResult{
+			Imported:          int(result.Imported),
+			WithoutCategory:   result.WithoutCategory,
+			ErrorRows:         result.ErrorRows,
+			ExactDuplicates:   result.ExactDuplicates,
+			LikelyDuplicates:  result.LikelyDuplicates,
+			SkippedDuplicates: result.SkippedDuplicates,
+			ImportBatchID:     result.ImportBatchID,
+		},
+	})
+}
+
+func newAPIImportSession(preview importsvc.FilePreview) *apiImportSession {
+	return &apiImportSession{
+		ID:          preview.SessionID,
+		Filename:    preview.Filename,
+		Headers:     preview.Headers,
+		PreviewRows: preview.PreviewRows,
+		TotalRows:   preview.TotalRows,
+		Sheets:      preview.Sheets,
+		Sheet:       preview.Sheet,
+		HeaderRow:   preview.HeaderRow,
+		Delimiter:   preview.Delimiter,
+		Encoding:    preview.Encoding,
+		RecordsPath: preview.RecordsPath,
+	}
+}
@@ -0,0 +1,25 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// reportHandler returns the report of a month, with the same figures as the
+// report card of the reports page.
+func (a *apiHandler) reportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	year, yearErr := strconv.Atoi(r.PathValue("year"))
+	month, monthErr := strconv.Atoi(r.PathValue("month"))
+	if yearErr != nil || monthErr != nil || month < int(time.January) || month > int(time.December) {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "invalid year or month")
+		return
+	}
+
+	a.reportService.Generate(ctx, userID, nil)
+
+	writeAPIData(w, http.StatusOK, a.reportService.ForMonth(userID, nil, month, year))
+}
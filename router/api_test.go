@@ -0,0 +1,453 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/logger"
+	"github.com/GustavoCaso/expensetrace/service/auth"
+	"github.com/GustavoCaso/expensetrace/storage"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+// apiTestResponse decodes both successful and failed API responses.
+type apiTestResponse struct {
+	Data       json.RawMessage `json:"data"`
+	Pagination *apiPagination  `json:"pagination"`
+	Error      apiError        `json:"error"`
+}
+
+type apiTestExpense struct {
+	ID          int64     `json:"id"`
+	Source      string    `json:"source"`
+	Description string    `json:"description"`
+	Amount      int64     `json:"amount"`
+	Currency    string    `json:"currency"`
+	Date        time.Time `json:"date"`
+	ExpenseType int       `json:"expense_type"`
+	CategoryID  *int64    `json:"category_id"`
+}
+
+func createAPIToken(t *testing.T, s storage.Storage, logger *logger.Logger, user domain.User) string {
+	t.Helper()
+
+	token, _, err := auth.New(s, logger).CreateAPIToken(t.Context(), user.ID(), "test")
+	if err != nil {
+		t.Fatalf("Failed to create API token: %v", err)
+	}
+
+	return token
+}
+
+func callAPI(
+	t *testing.T,
+	handler http.Handler,
+	token, method, path string,
+	body any,
+) (int, apiTestResponse) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to encode body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return serveAPI(t, handler, req)
+}
+
+func serveAPI(t *testing.T, handler http.Handler, req *http.Request) (int, apiTestResponse) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var response apiTestResponse
+	if w.Code != http.StatusNoContent {
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Fatalf("%s %s: expected a JSON response, got %q: %s", req.Method, req.URL, contentType, w.Body)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", req.Method, req.URL, err)
+		}
+	}
+
+	return w.Code, response
+}
+
+func decodeData(t *testing.T, response apiTestResponse, v any) {
+	t.Helper()
+
+	if err := json.Unmarshal(response.Data, v); err != nil {
+		t.Fatalf("Failed to decode data %s: %v", response.Data, err)
+	}
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	handler := New(s, logger)
+
+	status, response := callAPI(t, handler, "", http.MethodGet, "/api/v1/expenses", nil)
+	if status != http.StatusUnauthorized || response.Error.Code != errCodeUnauthorized {
+		t.Errorf("Expected a request without token to be unauthorized, got %d %+v", status, response.Error)
+	}
+
+	status, response = callAPI(t, handler, "et_unknown", http.MethodGet, "/api/v1/expenses", nil)
+	if status != http.StatusUnauthorized || response.Error.Code != errCodeUnauthorized {
+		t.Errorf("Expected an unknown token to be unauthorized, got %d %+v", status, response.Error)
+	}
+
+	// Sessions of the web app do not open the API
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/expenses", nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	status, _ = serveAPI(t, handler, req)
+	if status != http.StatusUnauthorized {
+		t.Errorf("Expected a session cookie to be unauthorized, got %d", status)
+	}
+
+	token := createAPIToken(t, s, logger, user)
+
+	status, response = callAPI(t, handler, token, http.MethodGet, "/api/v1/expenses", nil)
+	if status != http.StatusOK {
+		t.Errorf("Expected the token to be accepted, got %d %+v", status, response.Error)
+	}
+
+	status, response = callAPI(t, handler, token, http.MethodGet, "/api/v1/unknown", nil)
+	if status != http.StatusNotFound || response.Error.Code != errCodeNotFound {
+		t.Errorf("Expected unknown routes to be not found, got %d %+v", status, response.Error)
+	}
+
+	// Bearer tokens are not sent by browsers, cross-origin calls are allowed
+	body := strings.NewReader(`{"name":"Food","pattern":"food"}`)
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/categories", body)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	status, response = serveAPI(t, handler, req)
+	if status != http.StatusCreated {
+		t.Errorf("Expected a cross-origin API call to succeed, got %d %+v", status, response.Error)
+	}
+}
+
+func TestAPIExpenses(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	handler := New(s, logger)
+	token := createAPIToken(t, s, logger, user)
+
+	categoryID, err := s.CreateCategory(t.Context(), user.ID(), "Food", "restaurant", 0)
+	if err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+
+	status, response := callAPI(t, handler, token, http.MethodPost, "/api/v1/expenses", map[string]any{
+		"source": "Bank",
+		"amount": 1000,
+		"date":   "03/01/2025",
+	})
+	if status != http.StatusUnprocessableEntity || response.Error.Code != errCodeValidation {
+		t.Fatalf("Expected a validation error, got %d %+v", status, response.Error)
+	}
+	for _, field := range []string{"description", "currency", "date", "expense_type"} {
+		if _, ok := response.Error.Fields[field]; !ok {
+			t.Errorf("Expected field %s to be reported, got %v", field, response.Error.Fields)
+		}
+	}
+
+	for i, description := range []string{"Restaurant", "Cinema", "Restaurant again"} {
+		status, response = callAPI(t, handler, token, http.MethodPost, "/api/v1/expenses", map[string]any{
+			"source":       "Bank",
+			"description":  description,
+			"amount":       1000 * (i + 1),
+			"currency":     "EUR",
+			"date":         fmt.Sprintf("2025-03-0%d", i+1),
+			"expense_type": domain.ChargeType,
+		})
+		if status != http.StatusCreated {
+			t.Fatalf("Expected the expense to be created, got %d %+v", status, response.Error)
+		}
+	}
+
+	var created apiTestExpense
+	decodeData(t, response, &created)
+	if created.ID == 0 || created.Amount != -3000 {
+		t.Errorf("Expected a charge with an ID and a negative amount, got %+v", created)
+	}
+
+	status, response = callAPI(t, handler, token, http.MethodGet,
+		"/api/v1/expenses?description=restaurant&sort=amount:asc&per_page=1&page=2", nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected expenses, got %d %+v", status, response.Error)
+	}
+	var listed []apiTestExpense
+	decodeData(t, response, &listed)
+	if len(listed) != 1 || listed[0].Description != "Restaurant" {
+		t.Errorf("Expected the second restaurant expense by amount, got %+v", listed)
+	}
+	if response.Pagination == nil || response.Pagination.Total != 2 || response.Pagination.TotalPages != 2 {
+		t.Errorf("Expected two pages of one expense, got %+v", response.Pagination)
+	}
+
+	status, response = callAPI(t, handler, token, http.MethodGet, "/api/v1/expenses?sort=name:asc", nil)
+	if status != http.StatusBadRequest || response.Error.Code != errCodeBadRequest {
+		t.Errorf("Expected an invalid sort to be a bad request, got %d %+v", status, response.Error)
+	}
+
+	path := fmt.Sprintf("/api/v1/expenses/%d", created.ID)
+	status, response = callAPI(t, handler, token, http.MethodPut, path, map[string]any{
+		"source":       "Bank",
+		"description":  "Salary",
+		"amount":       -250000,
+		"currency":     "EUR",
+		"date":         "2025-03-31T00:00:00Z",
+		"expense_type": domain.IncomeType,
+		"category_id":  categoryID,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Expected the expense to be updated, got %d %+v", status, response.Error)
+	}
+
+	status, response = callAPI(t, handler, token, http.MethodGet, path, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected the expense, got %d %+v", status, response.Error)
+	}
+	var updated apiTestExpense
+	decodeData(t, response, &updated)
+	if updated.Description != "Salary" || updated.Amount != 250000 ||
+		updated.CategoryID == nil || *updated.CategoryID != categoryID {
+		t.Errorf("Expected the updated income, got %+v", updated)
+	}
+
+	otherUser, err := s.CreateUser(t.Context(), "other", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherToken := createAPIToken(t, s, logger, otherUser)
+	status, _ = callAPI(t, handler, otherToken, http.MethodGet, path, nil)
+	if status != http.StatusNotFound {
+		t.Errorf("Expected another user's expense to be not found, got %d", status)
+	}
+
+	status, _ = callAPI(t, handler, token, http.MethodDelete, path, nil)
+	if status != http.StatusNoContent {
+		t.Errorf("Expected the expense to be deleted, got %d", status)
+	}
+	status, response = callAPI(t, handler, token, http.MethodDelete, path, nil)
+	if status != http.StatusNotFound || response.Error.Code != errCodeNotFound {
+		t.Errorf("Expected a deleted expense to be not found, got %d %+v", status, response.Error)
+	}
+}
+
+func TestAPICategories(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	handler := New(s, logger)
+	token := createAPIToken(t, s, logger, user)
+
+	_, err := s.InsertExpenses(t.Context(), user.ID(), []domain.Expense{
+		domain.NewExpense(0, "Bank", "cinema", "EUR", -1200, time.Now(), domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Bank", "cinema", "EUR", -1100, time.Now().AddDate(0, 0, -1), domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Bank", "grocery", "EUR", -5000, time.Now(), domain.ChargeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	status, response := callAPI(t, handler, token, http.MethodGet, "/api/v1/categories/uncategorized", nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected uncategorized expenses, got %d %+v", status, response.Error)
+	}
+	var groups []struct {
+		Description string `json:"description"`
+		Count       int    `json:"count"`
+		Total       int64  `json:"total"`
+	}
+	decodeData(t, response, &groups)
+	if len(groups) != 2 || groups[0].Description != "cinema" || groups[0].Count != 2 || groups[0].Total != -2300 {
+		t.Errorf("Expected the cinema expenses first, got %+v", groups)
+	}
+
+	status, response = callAPI(t, handler, token, http.MethodPost, "/api/v1/categories", map[string]any{
+		"name":    "Entertainment",
+		"pattern": "(",
+	})
+	if status != http.StatusUnprocessableEntity || response.Error.Fields["pattern"] == "" {
+		t.Errorf("Expected an invalid pattern to be reported, got %d %+v", status, response.Error)
+	}
+
+	status, response = callAPI(t, handler, token, http.MethodPost, "/api/v1/categories", map[string]any{
+		"name":    "Entertainment",
+		"pattern": "cinema",
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("Expected the category to be created, got %d %+v", status, response.Error)
+	}
+	var created apiCreatedCategory
+	decodeData(t, response, &created)
+	if created.ID == 0 || created.CategorizedExpenses != 2 {
+		t.Errorf("Expected the category to categorize both cinema expenses, got %+v", created)
+	}
+
+	path := fmt.Sprintf("/api/v1/categories/%d", created.ID)
+	status, response = callAPI(t, handler, token, http.MethodPatch, path, map[string]any{"monthly_budget": 29})
+	if status != http.StatusOK {
+		t.Fatalf("Expected the category to be updated, got %d %+v", status, response.Error)
+	}
+	var updated apiCategory
+	decodeData(t, response, &updated)
+	if updated.MonthlyBudget != 29 || updated.Name != "Entertainment" || updated.Pattern != "cinema" {
+		t.Errorf("Expected only the budget to change, got %+v", updated)
+	}
+
+	status, response = callAPI(t, handler, token, http.MethodGet, "/api/v1/categories", nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected categories, got %d %+v", status, response.Error)
+	}
+	var categories []apiCategory
+	decodeData(t, response, &categories)
+	found := false
+	for _, c := range categories {
+		found = found || c.ID == created.ID
+	}
+	if !found {
+		t.Errorf("Expected the created category, got %+v", categories)
+	}
+
+	status, _ = callAPI(t, handler, token, http.MethodDelete, path, nil)
+	if status != http.StatusNoContent {
+		t.Errorf("Expected the category to be deleted, got %d", status)
+	}
+	status, _ = callAPI(t, handler, token, http.MethodGet, path, nil)
+	if status != http.StatusNotFound {
+		t.Errorf("Expected a deleted category to be not found, got %d", status)
+	}
+}
+
+func TestAPIReport(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	handler := New(s, logger)
+	token := createAPIToken(t, s, logger, user)
+
+	now := time.Now()
+	_, err := s.InsertExpenses(t.Context(), user.ID(), []domain.Expense{
+		domain.NewExpense(0, "Bank", "salary", "EUR", 300000, now, domain.IncomeType, nil, nil),
+		domain.NewExpense(0, "Bank", "rent", "EUR", -100000, now, domain.ChargeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	status, response := callAPI(t, handler, token, http.MethodGet,
+		fmt.Sprintf("/api/v1/reports/%d/%d", now.Year(), now.Month()), nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected the report, got %d %+v", status, response.Error)
+	}
+	var report struct {
+		Income   int64 `json:"income"`
+		Spending int64 `json:"spending"`
+	}
+	decodeData(t, response, &report)
+	if report.Income != 300000 || report.Spending != -100000 {
+		t.Errorf("Expected the month's income and spending, got %d and %d", report.Income, report.Spending)
+	}
+
+	status, _ = callAPI(t, handler, token, http.MethodGet, "/api/v1/reports/2025/13", nil)
+	if status != http.StatusNotFound {
+		t.Errorf("Expected an invalid month to be not found, got %d", status)
+	}
+}
+
+func TestAPIImport(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	handler := New(s, logger)
+	token := createAPIToken(t, s, logger, user)
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "statement.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write([]byte("Day,Concept,Value\n2025-01-02,Coffee,-3.50\n2025-01-03,Bakery,-2.10\n"))
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/imports", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	status, response := serveAPI(t, handler, req)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected the file to be uploaded, got %d %+v", status, response.Error)
+	}
+	var upload apiImport
+	decodeData(t, response, &upload)
+	if upload.Status != importStatusMappingRequired || upload.Session == nil || upload.Session.TotalRows != 2 {
+		t.Fatalf("Expected an import session for the unknown file, got %+v", upload)
+	}
+
+	sessionPath := "/api/v1/imports/" + upload.Session.ID
+	status, response = callAPI(t, handler, token, http.MethodPost, sessionPath+"/execute", nil)
+	if status != http.StatusUnprocessableEntity || response.Error.Code != errCodeImport {
+		t.Errorf("Expected executing an unmapped import to fail, got %d %+v", status, response.Error)
+	}
+
+	status, response = callAPI(t, handler, token, http.MethodPost, sessionPath+"/mapping", map[string]any{
+		"mapping": map[string]any{
+			"source":             "Bank",
+			"date_column":        0,
+			"description_column": 1,
+			"amount_column":      2,
+			"default_currency":   "EUR",
+		},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Expected the mapping to be applied, got %d %+v", status, response.Error)
+	}
+	var preview struct {
+		NewRows int      `json:"new_rows"`
+		Errors  []string `json:"errors"`
+	}
+	decodeData(t, response, &preview)
+	if preview.NewRows != 2 || len(preview.Errors) != 0 {
+		t.Errorf("Expected two new rows, got %+v", preview)
+	}
+
+	status, response = callAPI(t, handler, token, http.MethodPost, sessionPath+"/execute", map[string]any{})
+	if status != http.StatusCreated {
+		t.Fatalf("Expected the import to be executed, got %d %+v", status, response.Error)
+	}
+	var executed apiImport
+	decodeData(t, response, &executed)
+	if executed.Result == nil || executed.Result.Imported != 2 || executed.Result.ImportBatchID == 0 {
+		t.Errorf("Expected two expenses imported in a batch, got %+v", executed.Result)
+	}
+
+	expenses, err := s.GetExpenses(t.Context(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	if len(expenses) != 2 {
+		t.Errorf("Expected 2 expenses stored, got %d", len(expenses))
+	}
+}
@@ -20,7 +20,7 @@ type authHandler struct {
 	router *router
 }
 
-func (a *authHandler) RegisterRoutes(mux *http.ServeMux) {
+func (a *authHandler) RegisterRoutes(mux *routeMux) {
 	mux.HandleFunc("GET /signup", a.signupPage)
 	mux.HandleFunc("POST /signup", a.signup)
 	mux.HandleFunc("GET /signin", a.signinPage)
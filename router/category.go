@@ -18,7 +18,7 @@ type categoryHandler struct {
 	*router
 }
 
-func (c *categoryHandler) RegisterRoutes(mux *http.ServeMux) {
+func (c *categoryHandler) RegisterRoutes(mux *routeMux) {
 	mux.HandleFunc("GET /categories", func(w http.ResponseWriter, r *http.Request) {
 		c.categoriesHandler(r.Context(), w, nil, nil)
 	})
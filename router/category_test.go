@@ -138,6 +138,7 @@ func TestUncategorizedHandler(t *testing.T) {
 			time.Now(),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -176,6 +177,7 @@ func TestUncategorizedSearchHandler(t *testing.T) {
 			time.Now(),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -186,6 +188,7 @@ func TestUncategorizedSearchHandler(t *testing.T) {
 			time.Now(),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -251,7 +254,7 @@ func TestCreateCategoryHandler(t *testing.T) {
 	s, user := testutil.SetupTestStorage(t, logger)
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "cinema", "USD", -123456, time.Now(), domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "cinema", "USD", -123456, time.Now(), domain.ChargeType, nil, nil),
 	}
 
 	_, expenseError := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -431,8 +434,9 @@ func TestUpdateHandler(t *testing.T) {
 					time.Now(),
 					domain.ChargeType,
 					&categoryID,
+					nil,
 				),
-				domain.NewExpense(0, "Test Source", "gym", "USD", -123, time.Now(), domain.ChargeType, nil),
+				domain.NewExpense(0, "Test Source", "gym", "USD", -123, time.Now(), domain.ChargeType, nil, nil),
 			}
 
 			_, expenseError := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -514,6 +518,7 @@ func TestUpdateCategoryPatternDoesNotAffectExcludeCategory(t *testing.T) {
 			time.Now(),
 			domain.ChargeType,
 			&entertainmentCategoryID,
+			nil,
 		),
 		// Expense that matches the new pattern, but is not updated as it already has a category (exclude)
 		domain.NewExpense(
@@ -525,6 +530,7 @@ func TestUpdateCategoryPatternDoesNotAffectExcludeCategory(t *testing.T) {
 			time.Now(),
 			domain.ChargeType,
 			&excludeCategoryID,
+			nil,
 		),
 		// Internal transfer excluded
 		domain.NewExpense(
@@ -536,15 +542,26 @@ func TestUpdateCategoryPatternDoesNotAffectExcludeCategory(t *testing.T) {
 			time.Now(),
 			domain.ChargeType,
 			&excludeCategoryID,
+			nil,
 		),
 		// Income in exclude category
-		domain.NewExpense(0, "bank", "salary refund", "USD", 3000, time.Now(), domain.IncomeType, &excludeCategoryID),
+		domain.NewExpense(
+			0,
+			"bank",
+			"salary refund",
+			"USD",
+			3000,
+			time.Now(),
+			domain.IncomeType,
+			&excludeCategoryID,
+			nil,
+		),
 		// Income with no category that should match the pattern, but won't get updated
-		domain.NewExpense(0, "bank", "cinema refund", "USD", 3000, time.Now(), domain.IncomeType, nil),
+		domain.NewExpense(0, "bank", "cinema refund", "USD", 3000, time.Now(), domain.IncomeType, nil, nil),
 		// Uncategorized expense that should match new pattern
-		domain.NewExpense(0, "bank", "theater show", "USD", -2000, time.Now(), domain.ChargeType, nil),
+		domain.NewExpense(0, "bank", "theater show", "USD", -2000, time.Now(), domain.ChargeType, nil, nil),
 		// Uncategorized expense that should not match
-		domain.NewExpense(0, "bank", "grocery shopping", "USD", -4000, time.Now(), domain.ChargeType, nil),
+		domain.NewExpense(0, "bank", "grocery shopping", "USD", -4000, time.Now(), domain.ChargeType, nil, nil),
 	}
 
 	_, err = s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -674,6 +691,7 @@ func TestUpdateUncategorizedHandler(t *testing.T) {
 			time.Now(),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -745,8 +763,8 @@ func TestResetCategoryHandler(t *testing.T) {
 	}
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "bank", "Restaurant dinner", "EUR", -2500, time.Now(), domain.ChargeType, &cat1ID),
-		domain.NewExpense(0, "bank", "Uber ride", "EUR", -1500, time.Now(), domain.ChargeType, &cat2ID),
+		domain.NewExpense(0, "bank", "Restaurant dinner", "EUR", -2500, time.Now(), domain.ChargeType, &cat1ID, nil),
+		domain.NewExpense(0, "bank", "Uber ride", "EUR", -1500, time.Now(), domain.ChargeType, &cat2ID, nil),
 	}
 
 	_, err = s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -11,4 +11,7 @@ const (
 	typeIsRequired        = "Type is required"
 	typeInvalid           = "Invalid type"
 	categoryInvalid       = "Invalid category"
+	accountInvalid        = "Invalid account"
+	nameIsRequired        = "Name is required"
+	currencyInvalid       = "Currency must be a three letter code"
 )
@@ -0,0 +1,150 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+const rateDateLayout = "2006-01-02"
+
+type exchangeRateHandler struct {
+	*router
+}
+
+func (e *exchangeRateHandler) RegisterRoutes(mux *routeMux) {
+	mux.HandleFunc("GET /profile/rates", func(w http.ResponseWriter, r *http.Request) {
+		e.ratesHandler(r.Context(), w, domain.ViewBase{})
+	})
+
+	mux.HandleFunc("POST /profile/rates", func(w http.ResponseWriter, r *http.Request) {
+		e.addRateHandler(r.Context(), w, r)
+	})
+
+	mux.HandleFunc("POST /profile/rates/import", func(w http.ResponseWriter, r *http.Request) {
+		e.importRatesHandler(r.Context(), w, r)
+	})
+
+	mux.HandleFunc("POST /profile/rates/{id}/delete", func(w http.ResponseWriter, r *http.Request) {
+		e.deleteRateHandler(r.Context(), w, r)
+	})
+}
+
+// ratesHandler renders the user's most recent exchange rates. base carries
+// the banner or error of the action that led to the page.
+func (e *exchangeRateHandler) ratesHandler(ctx context.Context, w http.ResponseWriter, base domain.ViewBase) {
+	userID := userIDFromContext(ctx)
+	data := domain.ExchangeRatesViewData{
+		ViewBase: viewBaseFromContext(ctx),
+	}
+	data.Banner = base.Banner
+	data.Error = base.Error
+
+	defer func() {
+		e.renderHTML(w, http.StatusOK, data, "base", "pages/profile/rates.html")
+	}()
+
+	rates, err := e.exchangeService.Rates(ctx, userID)
+	if err != nil {
+		e.logger.Error("Failed to get exchange rates", "error", err, "user_id", userID)
+		data.Error = err.Error()
+		return
+	}
+
+	data.Rates = rates
+}
+
+func (e *exchangeRateHandler) addRateHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		e.ratesHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Invalid form data: %s", err.Error())})
+		return
+	}
+
+	date, err := time.Parse(rateDateLayout, r.FormValue("date"))
+	if err != nil {
+		e.ratesHandler(ctx, w, domain.ViewBase{Error: "Invalid date, use the format YYYY-MM-DD"})
+		return
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("rate")), 64)
+	if err != nil {
+		e.ratesHandler(ctx, w, domain.ViewBase{Error: "Invalid rate"})
+		return
+	}
+
+	rate := domain.NewExchangeRate(0, date, r.FormValue("base"), r.FormValue("quote"), value)
+	if _, err = e.exchangeService.Add(ctx, userID, rate); err != nil {
+		e.ratesHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Error saving the exchange rate: %s", err.Error())})
+		return
+	}
+
+	e.ratesHandler(ctx, w, domain.ViewBase{Banner: domain.Banner{
+		Icon:    "✅",
+		Message: "Exchange rate saved",
+	}})
+}
+
+func (e *exchangeRateHandler) importRatesHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxMemory); err != nil { //nolint:gosec // MaxBytesReader applied above
+		e.ratesHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Error parsing form: %s", err.Error())})
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		errorMessage := "Error retrieving the file"
+		if errors.Is(err, http.ErrMissingFile) {
+			errorMessage = "No file submitted"
+		}
+		e.ratesHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Error parsing form: %s", errorMessage)})
+		return
+	}
+	defer file.Close()
+
+	imported, err := e.exchangeService.ImportCSV(ctx, userID, r.FormValue("base"), file)
+	if err != nil {
+		e.ratesHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Error importing exchange rates: %s", err.Error())})
+		return
+	}
+
+	e.ratesHandler(ctx, w, domain.ViewBase{Banner: domain.Banner{
+		Icon:    "✅",
+		Message: fmt.Sprintf("%d exchange rates imported", imported),
+	}})
+}
+
+func (e *exchangeRateHandler) deleteRateHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		e.ratesHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Invalid exchange rate ID: %s", err.Error())})
+		return
+	}
+
+	if err = e.exchangeService.Delete(ctx, userID, id); err != nil {
+		var notFound *domain.NotFoundError
+		if errors.As(err, &notFound) {
+			err = errors.New("exchange rate not found")
+		}
+		e.ratesHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Error deleting the exchange rate: %s", err.Error())})
+		return
+	}
+
+	e.ratesHandler(ctx, w, domain.ViewBase{Banner: domain.Banner{
+		Icon:    "✅",
+		Message: "Exchange rate deleted",
+	}})
+}
@@ -0,0 +1,118 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func TestExchangeRateHandlers(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	handler := New(s, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/profile/rates", nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status OK; got %v", w.Code)
+	}
+	ensureNoErrorInTemplateResponse(t, "exchange rates", w.Result().Body)
+
+	formData := url.Values{}
+	formData.Set("date", "2024-06-14")
+	formData.Set("base", "eur")
+	formData.Set("quote", "usd")
+	formData.Set("rate", "1.0713")
+
+	req = httptest.NewRequest(http.MethodPost, "/profile/rates", strings.NewReader(formData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Exchange rate saved") || !strings.Contains(body, "EUR/USD") {
+		t.Fatalf("Expected the rate to be saved and listed, got %s", body)
+	}
+
+	upload := new(bytes.Buffer)
+	writer := multipart.NewWriter(upload)
+	part, err := writer.CreateFormFile("file", "eurofxref-hist.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = part.Write([]byte("Date,USD,GBP,\n2024-06-13,1.0793,0.84443,\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/profile/rates/import", upload)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "2 exchange rates imported") {
+		t.Fatalf("Expected the rates to be imported, got %s", w.Body.String())
+	}
+
+	rates, err := s.GetExchangeRates(ctx, user.ID(), 10)
+	if err != nil {
+		t.Fatalf("Failed to get exchange rates: %v", err)
+	}
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 rates, got %d", len(rates))
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/profile/rates/"+strconv.FormatInt(rates[0].ID(), 10)+"/delete", nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "Exchange rate deleted") {
+		t.Errorf("Expected the rate to be deleted, got %s", w.Body.String())
+	}
+}
+
+func TestExchangeRateHandlers_InvalidRate(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	handler := New(s, logger)
+
+	formData := url.Values{}
+	formData.Set("date", "14/06/2024")
+	formData.Set("base", "EUR")
+	formData.Set("quote", "USD")
+	formData.Set("rate", "1.07")
+
+	req := httptest.NewRequest(http.MethodPost, "/profile/rates", strings.NewReader(formData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "Invalid date") {
+		t.Errorf("Expected an invalid date error, got %s", w.Body.String())
+	}
+}
@@ -35,7 +35,7 @@ type expenseHandler struct {
 	*router
 }
 
-func (c *expenseHandler) RegisterRoutes(mux *http.ServeMux) {
+func (c *expenseHandler) RegisterRoutes(mux *routeMux) {
 	mux.HandleFunc("GET /expense/{id}", func(w http.ResponseWriter, r *http.Request) {
 		c.expenseHandler(r.Context(), w, r)
 	})
@@ -114,6 +114,13 @@ func (c *expenseHandler) expensesHandler(
 	data.Filter = expenseFilter
 	data.Sort = sortOptions
 
+	accounts, err := c.accountService.List(ctx, userID)
+	if err != nil {
+		data.Error = err.Error()
+		return
+	}
+	data.Accounts = accounts
+
 	if banner != nil {
 		data.Banner = *banner
 	}
@@ -139,8 +146,9 @@ func (c *expenseHandler) newExpenseHandler(ctx context.Context, w http.ResponseW
 	}
 
 	data.Categories = categories
+	data.Accounts = c.formAccounts(ctx, userID)
 	data.Expense = &domain.ExpenseView{
-		Expense: domain.NewExpense(0, "", "", "", 0, time.Now(), domain.ChargeType, nil),
+		Expense: domain.NewExpense(0, "", "", "", 0, time.Now(), domain.ChargeType, nil, nil),
 		Cat:     domain.NewCategory(0, "", "", 0),
 	}
 }
@@ -155,7 +163,7 @@ func (c *expenseHandler) createExpenseHandler(ctx context.Context, w http.Respon
 	data.CurrentPage = pageExpenses
 	data.FormErrors = make(map[string]string)
 	data.Expense = &domain.ExpenseView{
-		Expense: domain.NewExpense(0, "", "", "", 0, time.Now(), domain.ChargeType, nil),
+		Expense: domain.NewExpense(0, "", "", "", 0, time.Now(), domain.ChargeType, nil, nil),
 		Cat:     domain.NewCategory(0, "", "", 0),
 	}
 
@@ -171,6 +179,7 @@ func (c *expenseHandler) createExpenseHandler(ctx context.Context, w http.Respon
 	}
 
 	data.Categories = categories
+	data.Accounts = c.formAccounts(ctx, userID)
 	newExpense, err := parseExpenseForm(r, w, 0, data.FormErrors)
 	r.Body = http.MaxBytesReader(w, r.Body, maxMemory)
 
@@ -180,6 +189,8 @@ func (c *expenseHandler) createExpenseHandler(ctx context.Context, w http.Respon
 		return
 	}
 
+	c.validateAccount(ctx, userID, newExpense, data.FormErrors)
+
 	if len(data.FormErrors) > 0 {
 		return
 	}
@@ -207,7 +218,7 @@ func (c *expenseHandler) expenseHandler(ctx context.Context, w http.ResponseWrit
 	}
 	data.Action = editAction
 	data.Expense = &domain.ExpenseView{
-		Expense: domain.NewExpense(0, "", "", "", 0, time.Now(), domain.ChargeType, nil),
+		Expense: domain.NewExpense(0, "", "", "", 0, time.Now(), domain.ChargeType, nil, nil),
 		Cat:     domain.NewCategory(0, "", "", 0),
 	}
 
@@ -236,6 +247,7 @@ func (c *expenseHandler) expenseHandler(ctx context.Context, w http.ResponseWrit
 
 	data.Expense = expenseView
 	data.Categories = categories
+	data.Accounts = c.formAccounts(ctx, userID)
 	data.RedirectTo = r.URL.Query().Get("redirect_to")
 }
 
@@ -248,7 +260,7 @@ func (c *expenseHandler) updateExpenseHandler(ctx context.Context, w http.Respon
 	data.FormErrors = make(map[string]string)
 	data.Action = "edit"
 	data.Expense = &domain.ExpenseView{
-		Expense: domain.NewExpense(0, "", "", "", 0, time.Now(), domain.ChargeType, nil),
+		Expense: domain.NewExpense(0, "", "", "", 0, time.Now(), domain.ChargeType, nil, nil),
 		Cat:     domain.NewCategory(0, "", "", 0),
 	}
 
@@ -273,6 +285,7 @@ func (c *expenseHandler) updateExpenseHandler(ctx context.Context, w http.Respon
 		return
 	}
 	data.Categories = categories
+	data.Accounts = c.formAccounts(ctx, userID)
 
 	expenseView, expenseErr := c.expenseService.Get(ctx, userID, id)
 	if expenseErr != nil {
@@ -291,6 +304,8 @@ func (c *expenseHandler) updateExpenseHandler(ctx context.Context, w http.Respon
 		return
 	}
 
+	c.validateAccount(ctx, userID, updatedExpense, data.FormErrors)
+
 	redirectTo := r.FormValue("redirect_to")
 
 	if len(data.FormErrors) > 0 {
@@ -384,6 +399,33 @@ func isValidRedirectTarget(target string) bool {
 	return len(unescaped) > 0 && unescaped[0] == '/' && (len(unescaped) < 2 || unescaped[1] != '/')
 }
 
+// formAccounts returns the accounts offered in the expense form. The form
+// still works without them, failures are only logged.
+func (c *expenseHandler) formAccounts(ctx context.Context, userID int64) []domain.Account {
+	accounts, err := c.accountService.List(ctx, userID)
+	if err != nil {
+		c.logger.Error("Failed to get accounts", "error", err)
+		return []domain.Account{}
+	}
+	return accounts
+}
+
+// validateAccount records an error when the expense is set to an account
+// that is not one of the user's.
+func (c *expenseHandler) validateAccount(
+	ctx context.Context,
+	userID int64,
+	expense domain.Expense,
+	formErrors map[string]string,
+) {
+	if expense.AccountID() == nil {
+		return
+	}
+	if _, err := c.accountService.Get(ctx, userID, *expense.AccountID()); err != nil {
+		formErrors["account_id"] = accountInvalid
+	}
+}
+
 func parseExpenseForm(
 	r *http.Request,
 	w http.ResponseWriter,
@@ -403,6 +445,7 @@ func parseExpenseForm(
 	dateStr := r.FormValue("date")
 	typeStr := r.FormValue("type")
 	categoryIDStr := r.FormValue("category_id")
+	accountIDStr := r.FormValue("account_id")
 
 	if source == "" {
 		formErrors["source"] = sourceIsRequired
@@ -470,7 +513,17 @@ func parseExpenseForm(
 		categoryID = nil
 	}
 
-	return domain.NewExpense(id, source, description, currency, amount, date, expenseType, categoryID), nil
+	var accountID *int64
+	if accountIDStr != "" {
+		acctID, parseErr := strconv.ParseInt(accountIDStr, 10, 64)
+		if parseErr != nil {
+			formErrors["account_id"] = accountInvalid
+		} else {
+			accountID = &acctID
+		}
+	}
+
+	return domain.NewExpense(id, source, description, currency, amount, date, expenseType, categoryID, accountID), nil
 }
 
 func (c *expenseHandler) deleteExpenseHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
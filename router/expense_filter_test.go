@@ -30,6 +30,7 @@ func TestFilteringEndToEnd(t *testing.T) {
 			time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -40,6 +41,7 @@ func TestFilteringEndToEnd(t *testing.T) {
 			time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		// Lunch on mastercard
 		domain.NewExpense(
@@ -51,6 +53,7 @@ func TestFilteringEndToEnd(t *testing.T) {
 			time.Date(2024, 1, 18, 12, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		// Grocery on visa (different month)
 		domain.NewExpense(
@@ -62,6 +65,7 @@ func TestFilteringEndToEnd(t *testing.T) {
 			time.Date(2024, 2, 5, 14, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		// Income
 		domain.NewExpense(
@@ -73,6 +77,7 @@ func TestFilteringEndToEnd(t *testing.T) {
 			time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 			domain.IncomeType,
 			nil,
+			nil,
 		),
 	}
 
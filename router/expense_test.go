@@ -45,8 +45,9 @@ func TestExpensesHandler(t *testing.T) {
 			now,
 			domain.ChargeType,
 			&categoryIDs[0],
+			nil,
 		),
-		domain.NewExpense(0, "Test Source", "Uber ride", "USD", -50000, now, domain.ChargeType, &categoryIDs[1]),
+		domain.NewExpense(0, "Test Source", "Uber ride", "USD", -50000, now, domain.ChargeType, &categoryIDs[1], nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -79,8 +80,8 @@ func TestExpensesGroupByYearAndMonth(t *testing.T) {
 	now := time.Now()
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, now, domain.ChargeType, &cat1),
-		domain.NewExpense(0, "Test Source", "Uber ride", "USD", -50000, now, domain.ChargeType, &cat2),
+		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, now, domain.ChargeType, &cat1, nil),
+		domain.NewExpense(0, "Test Source", "Uber ride", "USD", -50000, now, domain.ChargeType, &cat2, nil),
 	}
 
 	svc := expense.New(s, logger)
@@ -150,6 +151,7 @@ func TestExpenseHandler(t *testing.T) {
 			now,
 			domain.ChargeType,
 			&categoryID,
+			nil,
 		),
 	}
 
@@ -233,7 +235,17 @@ func TestUpdateExpenseHandler(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Original Source", "Original description", "EUR", -100000, now, domain.ChargeType, nil),
+		domain.NewExpense(
+			0,
+			"Original Source",
+			"Original description",
+			"EUR",
+			-100000,
+			now,
+			domain.ChargeType,
+			nil,
+			nil,
+		),
 	}
 
 	_, err = s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -303,7 +315,17 @@ func TestUpdateExpenseHandlerUpdatesCategoryPattern(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Original Source", "Trader Joes", "USD", -100000, now, domain.ChargeType, &categoryID),
+		domain.NewExpense(
+			0,
+			"Original Source",
+			"Trader Joes",
+			"USD",
+			-100000,
+			now,
+			domain.ChargeType,
+			&categoryID,
+			nil,
+		),
 	}
 
 	_, err = s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -359,7 +381,17 @@ func TestUpdateExpenseHandlerDoesNotUpdateCategoryPatternWhenUnchecked(t *testin
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Original Source", "Trader Joes", "USD", -100000, now, domain.ChargeType, &categoryID),
+		domain.NewExpense(
+			0,
+			"Original Source",
+			"Trader Joes",
+			"USD",
+			-100000,
+			now,
+			domain.ChargeType,
+			&categoryID,
+			nil,
+		),
 	}
 
 	_, err = s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -409,7 +441,7 @@ func TestUpdateExpenseHandlerValidationErrors(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Test expense", "USD", -100000, now, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "Test expense", "USD", -100000, now, domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -512,8 +544,8 @@ func TestDeleteExpenseHandler(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source 1", "Test expense 1", "USD", -100000, now, domain.ChargeType, nil),
-		domain.NewExpense(0, "Test Source 2", "Test expense 2", "USD", -200000, now, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source 1", "Test expense 1", "USD", -100000, now, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Test Source 2", "Test expense 2", "USD", -200000, now, domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -600,6 +632,7 @@ func TestExpenseHandlersIntegration(t *testing.T) {
 			now,
 			domain.ChargeType,
 			&categoryID,
+			nil,
 		),
 	}
 
@@ -1052,7 +1085,7 @@ func TestExpenseHandlerPassesRedirectTo(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Test expense", "USD", -123456, now, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "Test expense", "USD", -123456, now, domain.ChargeType, nil, nil),
 	}
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
 	if err != nil {
@@ -1086,7 +1119,17 @@ func TestUpdateExpenseHandlerRedirectsWithHXRedirect(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Original Source", "Original description", "EUR", -100000, now, domain.ChargeType, nil),
+		domain.NewExpense(
+			0,
+			"Original Source",
+			"Original description",
+			"EUR",
+			-100000,
+			now,
+			domain.ChargeType,
+			nil,
+			nil,
+		),
 	}
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
 	if err != nil {
@@ -1129,7 +1172,17 @@ func TestUpdateExpenseHandlerIgnoresInvalidRedirect(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Original Source", "Original description", "EUR", -100000, now, domain.ChargeType, nil),
+		domain.NewExpense(
+			0,
+			"Original Source",
+			"Original description",
+			"EUR",
+			-100000,
+			now,
+			domain.ChargeType,
+			nil,
+			nil,
+		),
 	}
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
 	if err != nil {
@@ -1201,6 +1254,7 @@ func TestExpensesHandlerWithFilters(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -1211,6 +1265,7 @@ func TestExpensesHandlerWithFilters(t *testing.T) {
 			time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -1221,6 +1276,7 @@ func TestExpensesHandlerWithFilters(t *testing.T) {
 			time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -1231,6 +1287,7 @@ func TestExpensesHandlerWithFilters(t *testing.T) {
 			time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -1241,6 +1298,7 @@ func TestExpensesHandlerWithFilters(t *testing.T) {
 			time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 			domain.IncomeType,
 			nil,
+			nil,
 		),
 	}
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -1428,6 +1486,7 @@ func TestExpensesHandlerSortOrder(t *testing.T) {
 			time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -1438,6 +1497,7 @@ func TestExpensesHandlerSortOrder(t *testing.T) {
 			time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -1448,6 +1508,7 @@ func TestExpensesHandlerSortOrder(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
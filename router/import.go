@@ -11,26 +11,34 @@ import (
 
 	"github.com/GustavoCaso/expensetrace/domain"
 	importUtil "github.com/GustavoCaso/expensetrace/import"
+	"github.com/GustavoCaso/expensetrace/service/importsvc"
+	"github.com/GustavoCaso/expensetrace/util"
 )
 
 const (
 	maxMemory = 5 << 20 // 5MB
+	// maxUploadSize bounds uploaded files, the part over maxMemory is kept in
+	// temporary files while the request is handled.
+	maxUploadSize = 50 << 20 // 50MB
 )
 
 type importHandler struct {
 	*router
 }
 
-func (i *importHandler) RegisterRoutes(mux *http.ServeMux) {
+func (i *importHandler) RegisterRoutes(mux *routeMux) {
 	mux.HandleFunc("GET /import", func(w http.ResponseWriter, r *http.Request) {
-		base := viewBaseFromContext(r.Context())
-		i.renderHTML(w, http.StatusOK, base, "base", "pages/import/index.html")
+		i.importPageHandler(r.Context(), w)
 	})
 
 	mux.HandleFunc("POST /import", func(w http.ResponseWriter, r *http.Request) {
 		i.importHandler(r.Context(), w, r)
 	})
 
+	mux.HandleFunc("POST /import/reparse", func(w http.ResponseWriter, r *http.Request) {
+		i.reparseHandler(r.Context(), w, r)
+	})
+
 	mux.HandleFunc("POST /import/map", func(w http.ResponseWriter, r *http.Request) {
 		i.mappingHandler(r.Context(), w, r)
 	})
@@ -38,11 +46,127 @@ func (i *importHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /import/execute", func(w http.ResponseWriter, r *http.Request) {
 		i.executeImportHandler(r.Context(), w, r)
 	})
+
+	mux.HandleFunc("DELETE /import/mappings/{id}", func(w http.ResponseWriter, r *http.Request) {
+		i.deleteMappingProfileHandler(r.Context(), w, r)
+	})
+
+	mux.HandleFunc("GET /import/history", func(w http.ResponseWriter, r *http.Request) {
+		i.importHistoryHandler(r.Context(), w)
+	})
+
+	mux.HandleFunc("POST /import/history/{id}/rollback", func(w http.ResponseWriter, r *http.Request) {
+		i.rollbackImportHandler(r.Context(), w, r)
+	})
+
+	mux.HandleFunc("POST /import/inbox/dismiss", func(w http.ResponseWriter, r *http.Request) {
+		i.dismissInboxFailuresHandler(r.Context(), w)
+	})
+}
+
+// dismissInboxFailuresHandler clears the inbox failures banner. The banner
+// is replaced with the empty response.
+func (i *importHandler) dismissInboxFailuresHandler(ctx context.Context, w http.ResponseWriter) {
+	if err := i.importService.DismissInboxFailures(ctx, userIDFromContext(ctx)); err != nil {
+		i.logger.Error("Failed to dismiss inbox failures", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (i *importHandler) importPageHandler(ctx context.Context, w http.ResponseWriter) {
+	data := domain.ImportViewData{ViewBase: viewBaseFromContext(ctx)}
+	data.Accounts = i.importAccounts(ctx, userIDFromContext(ctx))
+
+	profiles, err := i.importService.MappingProfiles(ctx, userIDFromContext(ctx))
+	if err != nil {
+		data.Error = fmt.Sprintf("Error loading saved mappings: %s", err.Error())
+	}
+	data.MappingProfiles = profiles
+
+	i.renderHTML(w, http.StatusOK, data, "base", "pages/import/index.html")
+}
+
+// deleteMappingProfileHandler deletes a saved mapping and renders the
+// remaining ones.
+func (i *importHandler) deleteMappingProfileHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
+	data := domain.ImportViewData{ViewBase: viewBaseFromContext(ctx)}
+
+	defer func() {
+		i.renderHTML(w, http.StatusOK, data, "import/mapping-profiles")
+	}()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		data.Error = fmt.Sprintf("Invalid mapping ID. %s", err.Error())
+		return
+	}
+
+	if err = i.importService.DeleteMappingProfile(ctx, userID, id); err != nil {
+		i.logger.Error("Failed to delete mapping profile", "error", err, "id", id)
+		data.Error = fmt.Sprintf("Error deleting the saved mapping. %s", err.Error())
+	}
+
+	profiles, err := i.importService.MappingProfiles(ctx, userID)
+	if err != nil {
+		data.Error = fmt.Sprintf("Error loading saved mappings: %s", err.Error())
+	}
+	data.MappingProfiles = profiles
+}
+
+func (i *importHandler) importHistoryHandler(ctx context.Context, w http.ResponseWriter) {
+	data := domain.ImportHistoryViewData{ViewBase: viewBaseFromContext(ctx)}
+
+	batches, err := i.importService.ImportBatches(ctx, userIDFromContext(ctx))
+	if err != nil {
+		data.Error = fmt.Sprintf("Error loading import history: %s", err.Error())
+	}
+	data.Batches = batches
+
+	i.renderHTML(w, http.StatusOK, data, "base", "pages/import/history.html")
+}
+
+// rollbackImportHandler deletes the expenses of an import and renders the
+// updated history.
+func (i *importHandler) rollbackImportHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
+	data := domain.ImportHistoryViewData{ViewBase: viewBaseFromContext(ctx)}
+
+	defer func() {
+		i.renderHTML(w, http.StatusOK, data, "import/history")
+	}()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		data.Error = fmt.Sprintf("Invalid import ID. %s", err.Error())
+		return
+	}
+
+	deleted, err := i.importService.RollbackImportBatch(ctx, userID, id)
+	if err != nil {
+		i.logger.Error("Failed to roll back import", "error", err, "id", id)
+		data.Error = fmt.Sprintf("Error rolling back the import. %s", err.Error())
+	} else {
+		data.Banner = domain.Banner{
+			Icon:    "✅",
+			Message: fmt.Sprintf("Import rolled back. %d expenses deleted", deleted),
+		}
+	}
+
+	batches, err := i.importService.ImportBatches(ctx, userID)
+	if err != nil {
+		data.Error = fmt.Sprintf("Error loading import history: %s", err.Error())
+	}
+	data.Batches = batches
 }
 
 func (i *importHandler) importHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	userID := userIDFromContext(ctx)
-	data := viewBaseFromContext(ctx)
+	data := domain.ImportViewData{ViewBase: viewBaseFromContext(ctx)}
+	data.Accounts = i.importAccounts(ctx, userID)
 	previewFlow := false
 
 	defer func() {
@@ -51,7 +175,7 @@ func (i *importHandler) importHandler(ctx context.Context, w http.ResponseWriter
 		}
 	}()
 
-	r.Body = http.MaxBytesReader(w, r.Body, maxMemory)
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 	err := r.ParseMultipartForm(maxMemory) //nolint:gosec // MaxBytesReader applied above
 	if err != nil {
 		data.Error = fmt.Sprintf("Error parsing form: %s", err.Error())
@@ -71,6 +195,16 @@ func (i *importHandler) importHandler(ctx context.Context, w http.ResponseWriter
 	}
 	defer file.Close()
 
+	var accountID *int64
+	if value := r.FormValue("account_id"); value != "" {
+		id, parseErr := strconv.ParseInt(value, 10, 64)
+		if parseErr != nil {
+			data.Error = accountInvalid
+			return
+		}
+		accountID = &id
+	}
+
 	categoryMatcher, err := i.categoryMatcher(ctx, userID)
 	if err != nil {
 		data.Error = err.Error()
@@ -80,6 +214,7 @@ func (i *importHandler) importHandler(ctx context.Context, w http.ResponseWriter
 	info, needsPreview, previewReader, err := i.importService.ImportFile(
 		ctx,
 		userID,
+		accountID,
 		header.Filename,
 		file,
 		categoryMatcher,
@@ -91,7 +226,7 @@ func (i *importHandler) importHandler(ctx context.Context, w http.ResponseWriter
 
 	if needsPreview {
 		previewFlow = true
-		i.previewHandler(header.Filename, previewReader, w)
+		i.previewHandler(ctx, userID, accountID, header.Filename, previewReader, w)
 		return
 	}
 
@@ -107,6 +242,23 @@ func (i *importHandler) importHandler(ctx context.Context, w http.ResponseWriter
 	if info.TotalImports > 0 {
 		fmt.Fprintf(&b, "%d expenses without category", info.ImportWithoutCategory)
 	}
+	if info.ExactDuplicates > 0 {
+		fmt.Fprintf(&b, ". %d exact duplicates skipped", info.ExactDuplicates)
+	}
+	if info.LikelyDuplicates > 0 {
+		fmt.Fprintf(&b, ". %d likely duplicates imported, review them", info.LikelyDuplicates)
+	}
+	if info.ClosingBalance != nil {
+		fmt.Fprintf(
+			&b,
+			". Closing balance %s %s",
+			util.FormatMoney(info.ClosingBalance.Amount, ".", ","),
+			info.ClosingBalance.Currency,
+		)
+	}
+	if info.RejectedStatements > 0 {
+		fmt.Fprintf(&b, ". %d statements left out: %s", info.RejectedStatements, info.RejectedReason)
+	}
 
 	banner := domain.Banner{
 		Icon:    "✅",
@@ -117,31 +269,107 @@ func (i *importHandler) importHandler(ctx context.Context, w http.ResponseWriter
 
 // previewHandler handles file upload and shows preview with column detection.
 func (i *importHandler) previewHandler(
+	ctx context.Context,
+	userID int64,
+	accountID *int64,
 	filename string,
 	reader io.Reader,
 	w http.ResponseWriter,
 ) {
-	data := domain.PreviewData{ViewBase: domain.ViewBase{CurrentPage: pageImport, LoggedIn: true}}
+	preview, err := i.importService.Preview(ctx, userID, filename, reader)
+	i.renderPreview(ctx, userID, accountID, preview, err, w)
+}
+
+// reparseHandler reads the uploaded file again with another sheet, header
+// row, CSV dialect or JSON records path and shows the new preview.
+func (i *importHandler) reparseHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxMemory)
+	if err := r.ParseForm(); err != nil {
+		i.renderPreview(ctx, userID, nil, importsvc.FilePreview{}, fmt.Errorf("error parsing form: %w", err), w)
+		return
+	}
+
+	options := importUtil.ParseOptions{
+		Sheet:       r.FormValue("sheet"),
+		HeaderRow:   importUtil.AutoHeaderRow,
+		Delimiter:   r.FormValue("delimiter"),
+		Encoding:    r.FormValue("encoding"),
+		RecordsPath: r.FormValue("records_path"),
+	}
+	if value := r.FormValue("header_row"); value != "" {
+		headerRow, err := strconv.Atoi(value)
+		if err != nil {
+			i.renderPreview(ctx, userID, nil, importsvc.FilePreview{}, errors.New("invalid header row"), w)
+			return
+		}
+		options.HeaderRow = headerRow
+	}
+
+	preview, err := i.importService.Reparse(ctx, userID, r.FormValue("import_session_id"), options)
+	i.renderPreview(ctx, userID, nil, preview, err, w)
+}
+
+// renderPreview renders the preview of an uploaded file, preselecting the
+// account picked when uploading it, if any.
+func (i *importHandler) renderPreview(
+	ctx context.Context,
+	userID int64,
+	accountID *int64,
+	preview importsvc.FilePreview,
+	err error,
+	w http.ResponseWriter,
+) {
+	data := domain.PreviewData{ViewBase: domain.ViewBase{
+		CurrentPage:     pageImport,
+		LoggedIn:        true,
+		DefaultCurrency: viewBaseFromContext(ctx).DefaultCurrency,
+	}}
 
 	defer func() {
 		i.renderHTML(w, http.StatusOK, data, "import/preview")
 	}()
 
-	headers, previewRows, totalRows, sessionID, err := i.importService.Preview(filename, reader)
 	if err != nil {
 		data.Error = fmt.Sprintf("Error parsing file: %s", err.Error())
 		return
 	}
 
-	data.ImportSessionID = sessionID
-	data.Filename = filename
-	data.Headers = headers
-	data.PreviewRows = previewRows
-	data.TotalRows = totalRows
+	data.ImportSessionID = preview.SessionID
+	data.Filename = preview.Filename
+	data.Headers = preview.Headers
+	data.PreviewRows = preview.PreviewRows
+	data.TotalRows = preview.TotalRows
+	data.Sheets = preview.Sheets
+	data.Sheet = preview.Sheet
+	data.HeaderRow = preview.HeaderRow
+	data.Delimiter = preview.Delimiter
+	data.Encoding = preview.Encoding
+	data.Delimiters = importUtil.Delimiters
+	data.Encodings = importUtil.Encodings
+	data.RecordsPath = preview.RecordsPath
+	data.DateLayouts = importUtil.DateLayouts
+	data.Accounts = i.importAccounts(ctx, userID)
+	if accountID != nil {
+		data.AccountID = *accountID
+	}
+
+	// Offer the saved mapping for files with the same layout. A lookup
+	// failure only means the user maps the file by hand.
+	profile, found, err := i.importService.MatchMappingProfile(ctx, userID, preview.Headers)
+	if err != nil {
+		i.logger.Error("Failed to look up saved mapping", "error", err)
+		return
+	}
+	if found {
+		data.MatchedProfile = profile
+	}
 }
 
 // mappingHandler handles field mapping and shows confirmation preview.
 func (i *importHandler) mappingHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
 	data := domain.MappingData{ViewBase: domain.ViewBase{CurrentPage: pageImport}}
 
 	defer func() {
@@ -160,54 +388,42 @@ func (i *importHandler) mappingHandler(ctx context.Context, w http.ResponseWrite
 		return
 	}
 
-	source := r.FormValue("source")
-	if source == "" {
-		data.Error = sourceIsRequired
-		return
-	}
-
-	dateCol, err := strconv.Atoi(r.FormValue("date_column"))
-	if err != nil {
-		data.Error = "Invalid date column"
-		return
-	}
-
-	descCol, err := strconv.Atoi(r.FormValue("description_column"))
+	categoryMatcher, err := i.categoryMatcher(ctx, userID)
 	if err != nil {
-		data.Error = "Invalid description column"
+		data.Error = err.Error()
 		return
 	}
 
-	amountCol, err := strconv.Atoi(r.FormValue("amount_column"))
-	if err != nil {
-		data.Error = "Invalid amount column"
-		return
-	}
+	var result importsvc.MappingApplication
+	if profileID := r.FormValue("mapping_profile_id"); profileID != "" {
+		id, parseErr := strconv.ParseInt(profileID, 10, 64)
+		if parseErr != nil {
+			data.Error = "Invalid saved mapping"
+			return
+		}
 
-	currencyCol, err := strconv.Atoi(r.FormValue("currency_column"))
-	if err != nil {
-		data.Error = "Invalid currency column"
-		return
-	}
+		result, err = i.importService.ApplyMappingProfile(ctx, userID, sessionID, id, categoryMatcher)
+	} else {
+		mapping, message := fieldMappingFromForm(r)
+		if message != "" {
+			data.Error = message
+			return
+		}
 
-	mapping := &importUtil.FieldMapping{
-		Source:            source,
-		DateColumn:        dateCol,
-		DescriptionColumn: descCol,
-		AmountColumn:      amountCol,
-		CurrencyColumn:    currencyCol,
+		result, err = i.importService.ApplyMapping(ctx, userID, sessionID, mapping, categoryMatcher)
 	}
-
-	categoryMatcher, err := i.categoryMatcher(ctx, userIDFromContext(ctx))
 	if err != nil {
 		data.Error = err.Error()
 		return
 	}
 
-	result, err := i.importService.ApplyMapping(sessionID, mapping, categoryMatcher)
-	if err != nil {
-		data.Error = err.Error()
-		return
+	if name := r.FormValue("save_as"); name != "" {
+		profile, saveErr := i.importService.SaveMappingProfile(ctx, userID, sessionID, name)
+		if saveErr != nil {
+			data.Error = fmt.Sprintf("Error saving mapping: %s", saveErr.Error())
+			return
+		}
+		data.SavedProfileName = profile.Name()
 	}
 
 	data.ImportSessionID = sessionID
@@ -215,14 +431,20 @@ func (i *importHandler) mappingHandler(ctx context.Context, w http.ResponseWrite
 	data.PreviewExpenses = result.PreviewExpenses
 	data.TotalRows = result.TotalRows
 	data.Errors = result.Errors
+	data.DateLayout = result.DateLayout
+	data.Warnings = result.Warnings
+	data.Ambiguities = result.Ambiguities
+	data.NewRows = result.NewRows
+	data.Duplicates = result.Duplicates
 }
 
 // executeImportHandler executes the final import with stored mapping.
 func (i *importHandler) executeImportHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	userID := userIDFromContext(ctx)
-	data := domain.ViewBase{}
+	data := domain.ImportViewData{}
 	data.CurrentPage = pageImport
 	data.LoggedIn = true
+	data.Accounts = i.importAccounts(ctx, userID)
 
 	defer func() {
 		i.renderHTML(w, http.StatusOK, data, "import/form")
@@ -246,16 +468,44 @@ func (i *importHandler) executeImportHandler(ctx context.Context, w http.Respons
 		return
 	}
 
-	inserted, withoutCategory, _, err := i.importService.Execute(ctx, userID, sessionID, categoryMatcher)
+	// Importing with a saved mapping skips the mapping preview step
+	if profileID := r.FormValue("mapping_profile_id"); profileID != "" {
+		id, parseErr := strconv.ParseInt(profileID, 10, 64)
+		if parseErr != nil {
+			data.Error = "Invalid saved mapping"
+			return
+		}
+
+		if _, err = i.importService.ApplyMappingProfile(ctx, userID, sessionID, id, categoryMatcher); err != nil {
+			data.Error = err.Error()
+			return
+		}
+	}
+
+	includeRows := make([]int, 0, len(r.Form["include_row"]))
+	for _, value := range r.Form["include_row"] {
+		row, parseErr := strconv.Atoi(value)
+		if parseErr != nil {
+			data.Error = "Invalid row to include"
+			return
+		}
+		includeRows = append(includeRows, row)
+	}
+
+	result, err := i.importService.Execute(ctx, userID, sessionID, includeRows, categoryMatcher)
 	if err != nil {
 		data.Error = err.Error()
 		return
 	}
 
 	var b strings.Builder
-	fmt.Fprintf(&b, "%d expenses imported.", int(inserted))
-	if int(inserted) > 0 {
-		fmt.Fprintf(&b, "%d expenses without category", withoutCategory)
+	fmt.Fprintf(&b, "%d expenses imported.", int(result.Imported))
+	if int(result.Imported) > 0 {
+		fmt.Fprintf(&b, "%d expenses without category", result.WithoutCategory)
+	}
+	if duplicates := result.ExactDuplicates + result.LikelyDuplicates; duplicates > 0 {
+		fmt.Fprintf(&b, ". %d duplicates found (%d exact, %d likely), %d skipped",
+			duplicates, result.ExactDuplicates, result.LikelyDuplicates, result.SkippedDuplicates)
 	}
 
 	banner := domain.Banner{
@@ -264,3 +514,90 @@ func (i *importHandler) executeImportHandler(ctx context.Context, w http.Respons
 	}
 	data.Banner = banner
 }
+
+// fieldMappingFromForm reads the column selection of the mapping form. It
+// returns the message to show when the form is not valid.
+func fieldMappingFromForm(r *http.Request) (*importUtil.FieldMapping, string) {
+	source := r.FormValue("source")
+	if source == "" {
+		return nil, sourceIsRequired
+	}
+
+	dateCol, err := strconv.Atoi(r.FormValue("date_column"))
+	if err != nil {
+		return nil, "Invalid date column"
+	}
+
+	descCol, err := strconv.Atoi(r.FormValue("description_column"))
+	if err != nil {
+		return nil, "Invalid description column"
+	}
+
+	currencyCol := importUtil.NoColumn
+	if value := r.FormValue("currency_column"); value != "" {
+		if currencyCol, err = strconv.Atoi(value); err != nil {
+			return nil, "Invalid currency column"
+		}
+	}
+
+	var accountID *int64
+	if value := r.FormValue("account_id"); value != "" {
+		id, parseErr := strconv.ParseInt(value, 10, 64)
+		if parseErr != nil {
+			return nil, accountInvalid
+		}
+		accountID = &id
+	}
+
+	mapping := &importUtil.FieldMapping{
+		Source:             source,
+		AccountID:          accountID,
+		DateColumn:         dateCol,
+		DescriptionColumn:  descCol,
+		CurrencyColumn:     currencyCol,
+		DefaultCurrency:    strings.ToUpper(strings.TrimSpace(r.FormValue("default_currency"))),
+		DateLayout:         r.FormValue("date_layout"),
+		DecimalSeparator:   r.FormValue("decimal_separator"),
+		ThousandsSeparator: r.FormValue("thousands_separator"),
+		InvertSign:         r.FormValue("invert_sign") == "on",
+		AmountMode:         r.FormValue("amount_mode"),
+	}
+
+	if mapping.AmountMode == importUtil.AmountDebitCredit {
+		if mapping.DebitColumn, err = strconv.Atoi(r.FormValue("debit_column")); err != nil {
+			return nil, "Invalid debit column"
+		}
+		if mapping.CreditColumn, err = strconv.Atoi(r.FormValue("credit_column")); err != nil {
+			return nil, "Invalid credit column"
+		}
+		return mapping, ""
+	}
+
+	if mapping.AmountColumn, err = strconv.Atoi(r.FormValue("amount_column")); err != nil {
+		return nil, "Invalid amount column"
+	}
+
+	if mapping.AmountMode == importUtil.AmountIndicator {
+		if mapping.IndicatorColumn, err = strconv.Atoi(r.FormValue("indicator_column")); err != nil {
+			return nil, "Invalid indicator column"
+		}
+		for _, value := range strings.Split(r.FormValue("charge_values"), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				mapping.ChargeValues = append(mapping.ChargeValues, value)
+			}
+		}
+	}
+
+	return mapping, ""
+}
+
+// importAccounts returns the accounts offered to import into. Files can
+// still be imported without them, failures are only logged.
+func (i *importHandler) importAccounts(ctx context.Context, userID int64) []domain.Account {
+	accounts, err := i.accountService.List(ctx, userID)
+	if err != nil {
+		i.logger.Error("Failed to get accounts", "error", err)
+		return []domain.Account{}
+	}
+	return accounts
+}
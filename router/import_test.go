@@ -1,18 +1,24 @@
 package router
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/GustavoCaso/expensetrace/domain"
+	importUtil "github.com/GustavoCaso/expensetrace/import"
 	"github.com/GustavoCaso/expensetrace/testutil"
 )
 
@@ -34,8 +40,8 @@ func TestImport(t *testing.T) {
 	// Create test expenses
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, now, domain.ChargeType, nil),
-		domain.NewExpense(0, "Test Source", "Uber ride", "USD", -50000, now, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, now, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Test Source", "Uber ride", "USD", -50000, now, domain.ChargeType, nil, nil),
 	}
 
 	_, expenseError := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -122,6 +128,10 @@ func TestInteractiveImportPreview(t *testing.T) {
 
 	handler := New(s, logger)
 
+	if err := s.UpdateDefaultCurrency(context.Background(), user.ID(), "EUR"); err != nil {
+		t.Fatalf("Failed to set default currency: %v", err)
+	}
+
 	// Create CSV data for upload
 	csvData := `source,date,description,amount,currency
 Bank A,01/01/2024,Coffee,-5.00,USD
@@ -174,6 +184,61 @@ Bank B,02/01/2024,Lunch,-12.00,USD`
 	if !strings.Contains(responseBody, "import_session_id") {
 		t.Error("Response should contain import_session_id hidden field")
 	}
+	if !strings.Contains(responseBody, `name="default_currency" value="EUR"`) {
+		t.Error("Response should pre-fill the user's default currency")
+	}
+	if !strings.Contains(responseBody, "Comma (,) separated, utf-8") {
+		t.Error("Response should show the detected delimiter and encoding")
+	}
+}
+
+// TestInteractiveImportLargeFile tests files over maxMemory are accepted.
+func TestInteractiveImportLargeFile(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	handler := New(s, logger)
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	dataPart, err := writer.CreateFormFile("file", "test.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csvData := &bytes.Buffer{}
+	csvData.WriteString("source,date,description,amount,currency\n")
+	for i := 0; csvData.Len() <= maxMemory; i++ {
+		fmt.Fprintf(csvData, "Bank A,01/01/2024,Coffee %d,-5.00,USD\n", i)
+	}
+	if _, err = dataPart.Write(csvData.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	bodyBytes, err := io.ReadAll(w.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseBody := string(bodyBytes)
+	if strings.Contains(responseBody, "Error parsing form") {
+		t.Fatal("Expected a file over maxMemory to be accepted")
+	}
+	if !strings.Contains(responseBody, "import_session_id") {
+		t.Error("Response should contain import_session_id hidden field")
+	}
 }
 
 // TestInteractiveImportInvalidFile tests error handling for invalid files.
@@ -222,3 +287,471 @@ func TestInteractiveImportInvalidFile(t *testing.T) {
 		t.Error("Response should contain error message for invalid file format")
 	}
 }
+
+// TestInteractiveImportWithSavedMapping tests that a saved mapping is offered
+// for files with the same header and can import them directly.
+func TestInteractiveImportWithSavedMapping(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	handler := New(s, logger)
+
+	profile, err := s.SaveMappingProfile(
+		context.Background(),
+		user.ID(),
+		"Monthly export",
+		importUtil.HeaderFingerprint([]string{"source", "date", "description", "amount", "currency"}),
+		[]byte(`{"source":"Bank A","date_column":1,"description_column":2,"amount_column":3,"currency_column":4}`),
+	)
+	if err != nil {
+		t.Fatalf("Failed to save mapping profile: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/import", nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "Monthly export") {
+		t.Error("Import page should list the saved mapping")
+	}
+
+	csvData := `source,date,description,amount,currency
+Bank A,01/01/2024,Coffee,-5.00,USD
+Bank A,02/01/2024,Lunch,-12.00,USD`
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	dataPart, err := writer.CreateFormFile("file", "test.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = dataPart.Write([]byte(csvData)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	responseBody := w.Body.String()
+	if !strings.Contains(responseBody, "Import with saved mapping") {
+		t.Fatal("Preview should offer importing with the saved mapping")
+	}
+
+	match := regexp.MustCompile(`name="import_session_id" value="([a-f0-9]+)"`).FindStringSubmatch(responseBody)
+	if match == nil {
+		t.Fatal("Preview should contain the import session ID")
+	}
+
+	form := url.Values{}
+	form.Set("import_session_id", match[1])
+	form.Set("mapping_profile_id", strconv.FormatInt(profile.ID(), 10))
+
+	req = httptest.NewRequest(http.MethodPost, "/import/execute", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "2 expenses imported") {
+		t.Fatalf("Expected 2 expenses imported, got %s", w.Body.String())
+	}
+
+	expenses, err := s.GetAllExpenseTypes(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	if len(expenses) != 2 || expenses[0].Source() != "Bank A" {
+		t.Fatalf("Expected 2 expenses from Bank A, got %d", len(expenses))
+	}
+}
+
+func TestImportHistoryRollback(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	manual := domain.NewExpense(0, "Cash", "market", "EUR", -1500, time.Now(), domain.ChargeType, nil, nil)
+	if _, err := s.InsertExpenses(context.Background(), user.ID(), []domain.Expense{manual}); err != nil {
+		t.Fatalf("Failed to insert expense: %v", err)
+	}
+
+	handler := New(s, logger)
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	dataPart, err := writer.CreateFormFile("file", "expenses.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open("test_data/import.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err = io.Copy(dataPart, f); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %v; got %v", http.StatusOK, w.Code)
+	}
+
+	batches, err := s.GetImportBatches(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get import batches: %v", err)
+	}
+
+	if len(batches) != 1 {
+		t.Fatalf("Expected 1 import batch, got %d", len(batches))
+	}
+
+	batch := batches[0]
+	if batch.Filename() != "expenses.json" || batch.Format() != importUtil.FormatJSON || batch.Imported() == 0 {
+		t.Fatalf("Unexpected import batch %q %q %d", batch.Filename(), batch.Format(), batch.Imported())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/import/history", nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	history := w.Body.String()
+	if !strings.Contains(history, "expenses.json") {
+		t.Error("Import history should list the imported file")
+	}
+	if !strings.Contains(history, "/import/history/"+strconv.FormatInt(batch.ID(), 10)+"/rollback") {
+		t.Error("Import history should offer rolling back the import")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/import/history/"+strconv.FormatInt(batch.ID(), 10)+"/rollback", nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	response := w.Body.String()
+	if !strings.Contains(response, "Import rolled back") {
+		t.Errorf("Expected rollback confirmation, got %s", response)
+	}
+
+	remaining, err := s.GetAllExpenseTypes(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	if len(remaining) != 1 || remaining[0].Description() != "market" {
+		t.Fatalf("Expected only the manual expense to remain, got %d expenses", len(remaining))
+	}
+}
+
+func TestInteractiveImportDuplicates(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	existing := domain.NewExpense(
+		0, "Bank A", "coffee", "USD", -500,
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), domain.ChargeType, nil,
+		nil,
+	)
+	if _, err := s.InsertExpenses(context.Background(), user.ID(), []domain.Expense{existing}); err != nil {
+		t.Fatalf("Failed to insert expense: %v", err)
+	}
+
+	handler := New(s, logger)
+
+	csvData := `date,description,amount,currency
+01/01/2024,Coffee,-5.00,USD
+02/01/2024,Lunch,-12.00,USD`
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	dataPart, err := writer.CreateFormFile("file", "test.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = dataPart.Write([]byte(csvData)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	match := regexp.MustCompile(`name="import_session_id" value="([a-f0-9]+)"`).FindStringSubmatch(w.Body.String())
+	if match == nil {
+		t.Fatal("Preview should contain the import session ID")
+	}
+
+	form := url.Values{}
+	form.Set("import_session_id", match[1])
+	form.Set("source", "Bank A")
+	form.Set("date_column", "0")
+	form.Set("description_column", "1")
+	form.Set("amount_column", "2")
+	form.Set("currency_column", "3")
+	form.Set("date_layout", "02/01/2006")
+
+	req = httptest.NewRequest(http.MethodPost, "/import/map", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	mappingPreview := w.Body.String()
+	if !strings.Contains(mappingPreview, `name="include_row" value="1"`) {
+		t.Fatalf("Mapping preview should offer including the duplicate row, got %s", mappingPreview)
+	}
+	if strings.Contains(mappingPreview, `name="include_row" value="2"`) {
+		t.Error("Mapping preview should not flag new rows")
+	}
+
+	// Force the exact duplicate in
+	form = url.Values{}
+	form.Set("import_session_id", match[1])
+	form.Add("include_row", "1")
+
+	req = httptest.NewRequest(http.MethodPost, "/import/execute", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	response := w.Body.String()
+	if !strings.Contains(response, "2 expenses imported") ||
+		!strings.Contains(response, "1 duplicates found (1 exact, 0 likely), 0 skipped") {
+		t.Fatalf("Expected the duplicate to be imported, got %s", response)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	if len(expenses) != 3 {
+		t.Fatalf("Expected 3 expenses, got %d", len(expenses))
+	}
+}
+
+func TestInteractiveImportSpreadsheetSheets(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	handler := New(s, logger)
+
+	var ods bytes.Buffer
+	archive := zip.NewWriter(&ods)
+	content, err := archive.Create("content.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = content.Write([]byte(`<office:document-content
+  xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+  xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+  xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+  <office:body><office:spreadsheet>
+    <table:table table:name="Cover">
+      <table:table-row><table:table-cell><text:p>Statement</text:p></table:table-cell></table:table-row>
+      <table:table-row><table:table-cell><text:p>January</text:p></table:table-cell></table:table-row>
+    </table:table>
+    <table:table table:name="Movements">
+      <table:table-row>
+        <table:table-cell><text:p>Booked on</text:p></table:table-cell>
+        <table:table-cell><text:p>Amount</text:p></table:table-cell>
+      </table:table-row>
+      <table:table-row>
+        <table:table-cell office:value-type="date" office:date-value="2024-01-05"/>
+        <table:table-cell office:value-type="float" office:value="-12"/>
+      </table:table-row>
+    </table:table>
+  </office:spreadsheet></office:body>
+</office:document-content>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	dataPart, err := writer.CreateFormFile("file", "statement.ods")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = dataPart.Write(ods.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	responseBody := w.Body.String()
+	if !strings.Contains(responseBody, `<option value="Movements" >Movements</option>`) ||
+		!strings.Contains(responseBody, "Statement") {
+		t.Fatalf("Preview should show the first sheet and offer the others, got %s", responseBody)
+	}
+
+	match := regexp.MustCompile(`name="import_session_id" value="([a-f0-9]+)"`).FindStringSubmatch(responseBody)
+	if match == nil {
+		t.Fatal("Preview should contain the import session ID")
+	}
+
+	form := url.Values{}
+	form.Set("import_session_id", match[1])
+	form.Set("sheet", "Movements")
+	form.Set("header_row", "0")
+
+	req = httptest.NewRequest(http.MethodPost, "/import/reparse", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	responseBody = w.Body.String()
+	if !strings.Contains(responseBody, "Booked on") || !strings.Contains(responseBody, "2024-01-05") {
+		t.Fatalf("Preview should show the chosen sheet, got %s", responseBody)
+	}
+}
+
+func TestInteractiveImportNestedJSON(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	handler := New(s, logger)
+
+	jsonData := `{"data": {"transactions": [
+		{
+			"bookedAt": "2024-01-05",
+			"amount": {"value": "-12.50", "currency": "EUR"},
+			"merchant": {"name": "Coffee shop"}
+		}
+	]}}`
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	dataPart, err := writer.CreateFormFile("file", "export.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = dataPart.Write([]byte(jsonData)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	responseBody := w.Body.String()
+	if !strings.Contains(responseBody, "amount.value") || !strings.Contains(responseBody, "merchant.name") {
+		t.Errorf("Preview should show nested fields as columns, got %s", responseBody)
+	}
+	if !strings.Contains(responseBody, `name="records_path" value="data.transactions[*]"`) {
+		t.Errorf("Preview should show where the records were found, got %s", responseBody)
+	}
+}
+
+func TestInboxFailuresBanner(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	ctx := context.Background()
+
+	_, err := s.CreateInboxFailure(ctx, user.ID(), "statement.csv", "no saved mapping matches")
+	if err != nil {
+		t.Fatalf("Failed to create inbox failure: %v", err)
+	}
+
+	handler := New(s, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/expenses", nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %v; got %v", http.StatusOK, w.Code)
+	}
+
+	page := w.Body.String()
+	if !strings.Contains(page, `id="inbox-failures"`) || !strings.Contains(page, "statement.csv") {
+		t.Fatal("Expected the inbox failures banner on the page")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/import/inbox/dismiss", nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %v; got %v", http.StatusOK, w.Code)
+	}
+
+	failures, err := s.GetInboxFailures(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get inbox failures: %v", err)
+	}
+	if len(failures) != 0 {
+		t.Errorf("Expected the failures to be dismissed, got %d", len(failures))
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -23,6 +22,7 @@ const (
 	pageReports    = "reports"
 	pageExpenses   = "expenses"
 	pageCategories = "categories"
+	pageAccounts   = "accounts"
 	pageImport     = "import"
 	pageProfile    = "profile"
 )
@@ -51,6 +51,8 @@ func currentPageFromPath(path string) string {
 		return pageExpenses
 	case "category", "categories":
 		return pageCategories
+	case "accounts":
+		return pageAccounts
 	case "import":
 		return pageImport
 	case "profile":
@@ -117,11 +119,13 @@ func xFrameDenyHeaderMiddleware(next http.Handler) http.Handler {
 
 func authMiddleware(router *router, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip authentication for auth endpoints and static files
+		// Skip authentication for auth endpoints and static files. The API
+		// is authenticated by tokenAuthMiddleware
 		path := r.URL.Path
 		if strings.HasPrefix(path, "/signin") ||
 			strings.HasPrefix(path, "/signup") ||
-			strings.HasPrefix(path, "/static/") {
+			strings.HasPrefix(path, "/static/") ||
+			strings.HasPrefix(path, "/api/") {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -148,33 +152,40 @@ func authMiddleware(router *router, next http.Handler) http.Handler {
 			return
 		}
 
+		inboxFailures, err := router.importService.InboxFailures(r.Context(), user.ID())
+		if err != nil {
+			// The page is still usable without the inbox banner
+			router.logger.Error("Failed to load inbox failures", "error", err)
+		}
+
 		// Add user ID and view base data to context
 		ctx := context.WithValue(r.Context(), userIDKey, user.ID())
 		ctx = context.WithValue(ctx, viewBaseKey, domain.ViewBase{
 			LoggedIn:         true,
 			Username:         user.Username(),
 			UsernameInitials: getInitials(user.Username()),
+			DefaultCurrency:  user.DefaultCurrency(),
+			BaseCurrency:     user.BaseCurrency(),
 			CurrentPage:      currentPageFromPath(path),
+			InboxFailures:    inboxFailures,
 		})
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func csrfProtectionMiddleware(logger *logger.Logger, next http.Handler) http.Handler {
+func csrfProtectionMiddleware(logger *logger.Logger, trustedOrigins []string, next http.Handler) http.Handler {
 	csrf := &http.CrossOriginProtection{}
 
-	if trustedOrigins := os.Getenv("EXPENSETRACE_TRUSTED_ORIGINS"); trustedOrigins != "" {
-		origins := strings.Split(trustedOrigins, ",")
-		for _, origin := range origins {
-			origin = strings.TrimSpace(origin)
-			if origin != "" {
-				err := csrf.AddTrustedOrigin(origin)
-				if err != nil {
-					logger.Error("error adding trusted origin", "err", err.Error())
-					continue
-				}
-			}
+	// The API only accepts bearer tokens, which browsers never send on
+	// their own, so cross-origin requests cannot forge them
+	csrf.AddInsecureBypassPattern("/api/")
+
+	for _, origin := range trustedOrigins {
+		err := csrf.AddTrustedOrigin(origin)
+		if err != nil {
+			logger.Error("error adding trusted origin", "err", err.Error())
+			continue
 		}
 	}
 
@@ -92,6 +92,8 @@ func TestAuthMiddlewareSetsUserAndViewBase(t *testing.T) {
 		{"/expenses", pageExpenses},
 		{"/expense/1", pageExpenses},
 		{"/categories", pageCategories},
+		{"/accounts", pageAccounts},
+		{"/accounts/3", pageAccounts},
 		{"/category/new", pageCategories},
 		{"/import", pageImport},
 		{"/profile", pageProfile},
@@ -145,6 +147,9 @@ func TestCurrentPageFromPath(t *testing.T) {
 		{"/expenses/export", pageExpenses},
 		{"/expense/new", pageExpenses},
 		{"/categories", pageCategories},
+		{"/accounts", pageAccounts},
+		{"/accounts/3", pageAccounts},
+		{"/accounts/transfers", pageAccounts},
 		{"/category/uncategorized", pageCategories},
 		{"/import", pageImport},
 		{"/import/execute", pageImport},
@@ -168,7 +173,7 @@ func TestCSRFProtectionMiddleware(t *testing.T) {
 		w.Write([]byte("OK"))
 	})
 
-	protected := csrfProtectionMiddleware(logger, handler)
+	protected := csrfProtectionMiddleware(logger, nil, handler)
 
 	tests := []struct {
 		name           string
@@ -281,7 +286,7 @@ func TestCSRFProtectionMiddleware_WithOriginHeader(t *testing.T) {
 		w.Write([]byte("OK"))
 	})
 
-	protected := csrfProtectionMiddleware(logger, handler)
+	protected := csrfProtectionMiddleware(logger, nil, handler)
 
 	tests := []struct {
 		name           string
@@ -345,8 +350,7 @@ func TestCSRFProtectionMiddleware_TrustedOrigins(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
-	t.Setenv("EXPENSETRACE_TRUSTED_ORIGINS", "https://another.com")
-	protected := csrfProtectionMiddleware(logger, handler)
+	protected := csrfProtectionMiddleware(logger, []string{"https://another.com"}, handler)
 
 	tests := []struct {
 		name           string
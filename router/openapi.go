@@ -0,0 +1,688 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+// openAPIPath is where the OpenAPI document describing every route of the
+// router is served. It is public, so clients can be generated without a
+// token.
+const openAPIPath = "/api/openapi.json"
+
+const openAPIVersion = "3.0.3"
+
+type openAPIHandler struct {
+	router *router
+
+	once     sync.Once
+	document []byte
+	err      error
+}
+
+func (o *openAPIHandler) RegisterRoutes(mux *routeMux) {
+	mux.HandleFunc("GET "+openAPIPath, o.documentHandler)
+}
+
+func (o *openAPIHandler) documentHandler(w http.ResponseWriter, _ *http.Request) {
+	// The routes are known once New returns, so the document is built on the
+	// first request
+	o.once.Do(func() {
+		o.document, o.err = json.Marshal(newOpenAPIDocument(o.router.routes))
+	})
+	if o.err != nil {
+		o.router.logger.Error("Failed to build the OpenAPI document", "error", o.err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(o.document)
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*jsonSchema            `json:"schemas"`
+	SecuritySchemes map[string]*openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary"`
+	Tags        []string                    `json:"tags"`
+	Security    []map[string][]string       `json:"security"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required"`
+	Schema      *jsonSchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *jsonSchema `json:"schema"`
+}
+
+// jsonSchema is the subset of the OpenAPI schema object the document uses.
+type jsonSchema struct {
+	Ref         string                 `json:"$ref,omitempty"`
+	Type        string                 `json:"type,omitempty"`
+	Format      string                 `json:"format,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Nullable    bool                   `json:"nullable,omitempty"`
+	Enum        []any                  `json:"enum,omitempty"`
+	Properties  map[string]*jsonSchema `json:"properties,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	Items       *jsonSchema            `json:"items,omitempty"`
+	AllOf       []*jsonSchema          `json:"allOf,omitempty"`
+	// AdditionalProperties is false for objects with a fixed set of fields,
+	// or the schema of the values of a map.
+	AdditionalProperties any `json:"additionalProperties,omitempty"`
+}
+
+// apiOperation documents a route of the JSON API. Bodies are described by
+// values of the types the handler decodes and encodes.
+type apiOperation struct {
+	id      string
+	summary string
+	query   []openAPIParameter
+	// request is the JSON body of the request, upload bodies are a
+	// multipart form with a file field instead.
+	request     any
+	upload      bool
+	status      int
+	description string
+	// response is the data of the response envelope, nil when the response
+	// has no content.
+	response any
+	// paginated responses hold a page of the response slice.
+	paginated bool
+}
+
+// apiOperations documents every route registered by apiHandler. The test
+// fails when a route is missing.
+var apiOperations = map[string]apiOperation{
+	"GET " + apiPrefix + "/expenses": {
+		id:          "listExpenses",
+		summary:     "List expenses",
+		query:       append(expenseFilterParameters(), paginationParameters()...),
+		status:      http.StatusOK,
+		description: "A page of expenses",
+		response:    []domain.Expense{},
+		paginated:   true,
+	},
+	"POST " + apiPrefix + "/expenses": {
+		id:          "createExpense",
+		summary:     "Create an expense",
+		request:     apiExpenseRequest{},
+		status:      http.StatusCreated,
+		description: "The created expense",
+		response:    (*domain.Expense)(nil),
+	},
+	"GET " + apiPrefix + "/expenses/{id}": {
+		id:          "getExpense",
+		summary:     "Get an expense",
+		status:      http.StatusOK,
+		description: "The expense",
+		response:    (*domain.Expense)(nil),
+	},
+	"PUT " + apiPrefix + "/expenses/{id}": {
+		id:          "updateExpense",
+		summary:     "Replace an expense",
+		request:     apiExpenseRequest{},
+		status:      http.StatusOK,
+		description: "The updated expense",
+		response:    (*domain.Expense)(nil),
+	},
+	"DELETE " + apiPrefix + "/expenses/{id}": {
+		id:          "deleteExpense",
+		summary:     "Delete an expense",
+		status:      http.StatusNoContent,
+		description: "The expense was deleted",
+	},
+	"GET " + apiPrefix + "/categories": {
+		id:          "listCategories",
+		summary:     "List categories",
+		query:       paginationParameters(),
+		status:      http.StatusOK,
+		description: "A page of categories",
+		response:    []apiCategory{},
+		paginated:   true,
+	},
+	"POST " + apiPrefix + "/categories": {
+		id:          "createCategory",
+		summary:     "Create a category, categorizing the uncategorized expenses it matches",
+		request:     apiCategoryRequest{},
+		status:      http.StatusCreated,
+		description: "The created category",
+		response:    apiCreatedCategory{},
+	},
+	"GET " + apiPrefix + "/categories/uncategorized": {
+		id:      "listUncategorizedExpenses",
+		summary: "List uncategorized expenses grouped by description",
+		query: append([]openAPIParameter{
+			queryParameter("q", "Groups whose description contains the value", &jsonSchema{Type: "string"}),
+		}, paginationParameters()...),
+		status:      http.StatusOK,
+		description: "A page of groups of uncategorized expenses",
+		response:    []apiUncategorizedGroup{},
+		paginated:   true,
+	},
+	"GET " + apiPrefix + "/categories/{id}": {
+		id:          "getCategory",
+		summary:     "Get a category",
+		status:      http.StatusOK,
+		description: "The category",
+		response:    apiCategory{},
+	},
+	"PATCH " + apiPrefix + "/categories/{id}": {
+		id:          "updateCategory",
+		summary:     "Update a category, omitted fields keep their value",
+		request:     apiCategoryRequest{},
+		status:      http.StatusOK,
+		description: "The updated category",
+		response:    apiCategory{},
+	},
+	"DELETE " + apiPrefix + "/categories/{id}": {
+		id:          "deleteCategory",
+		summary:     "Delete a category, leaving its expenses uncategorized",
+		status:      http.StatusNoContent,
+		description: "The category was deleted",
+	},
+	"GET " + apiPrefix + "/reports/{year}/{month}": {
+		id:          "getMonthlyReport",
+		summary:     "Get the report of a month",
+		status:      http.StatusOK,
+		description: "The report of the month",
+		response:    domain.Report{},
+	},
+	"POST " + apiPrefix + "/imports": {
+		id:          "uploadImport",
+		summary:     "Import a file, or start an import session when its fields must be mapped",
+		upload:      true,
+		status:      http.StatusCreated,
+		description: "The imported expenses, or the import session to map",
+		response:    apiImport{},
+	},
+	"POST " + apiPrefix + "/imports/{session}/mapping": {
+		id:          "mapImport",
+		summary:     "Apply a field mapping or a saved mapping to an import session",
+		request:     apiMappingRequest{},
+		status:      http.StatusOK,
+		description: "The preview of the mapped rows",
+		response:    apiMappingPreview{},
+	},
+	"POST " + apiPrefix + "/imports/{session}/execute": {
+		id:          "executeImport",
+		summary:     "Import the rows of a mapped import session",
+		request:     apiExecuteRequest{},
+		status:      http.StatusCreated,
+		description: "The imported expenses",
+		response:    apiImport{},
+	},
+}
+
+func expenseFilterParameters() []openAPIParameter {
+	return []openAPIParameter{
+		queryParameter("description", "Expenses whose description contains the value", &jsonSchema{Type: "string"}),
+		queryParameter("source", "Expenses of the source", &jsonSchema{Type: "string"}),
+		queryParameter("amount_min", "Minimum amount, in units of the currency", &jsonSchema{Type: "number"}),
+		queryParameter("amount_max", "Maximum amount, in units of the currency", &jsonSchema{Type: "number"}),
+		queryParameter("date_from", "First date, inclusive", &jsonSchema{Type: "string", Format: "date"}),
+		queryParameter("date_to", "Last date, inclusive", &jsonSchema{Type: "string", Format: "date"}),
+		queryParameter("account", "Expenses of the account", &jsonSchema{Type: "integer", Format: "int64"}),
+		queryParameter("sort", "Sort order", &jsonSchema{
+			Type: "string",
+			Enum: []any{"date:asc", "date:desc", "amount:asc", "amount:desc"},
+		}),
+	}
+}
+
+func paginationParameters() []openAPIParameter {
+	return []openAPIParameter{
+		queryParameter("page", "Page number, starting at 1", &jsonSchema{Type: "integer"}),
+		queryParameter(
+			"per_page",
+			fmt.Sprintf("Page size, between 1 and %d", maxPageSize),
+			&jsonSchema{Type: "integer"},
+		),
+	}
+}
+
+func queryParameter(name, description string, schema *jsonSchema) openAPIParameter {
+	return openAPIParameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// newOpenAPIDocument describes the routes registered with the patterns. API
+// routes are described by apiOperations, the routes of the web interface
+// answer with HTML.
+func newOpenAPIDocument(patterns []string) *openAPIDocument {
+	generator := &schemaGenerator{
+		schemas: map[string]*jsonSchema{},
+		types:   map[string]reflect.Type{},
+	}
+
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:       "expensetrace",
+			Description: "The JSON API and the web interface of expensetrace. Amounts are in cents.",
+			Version:     strings.TrimPrefix(apiPrefix, "/api/"),
+		},
+		Paths: map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{
+			Schemas: generator.schemas,
+			SecuritySchemes: map[string]*openAPISecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer"},
+				"cookieAuth": {Type: "apiKey", In: "cookie", Name: sessionCookieName},
+			},
+		},
+	}
+
+	for _, pattern := range patterns {
+		method, path, found := strings.Cut(pattern, " ")
+		// Patterns without a method are fallbacks, not routes
+		if !found {
+			continue
+		}
+
+		path, parameters := openAPIPathParameters(path)
+
+		var operation *openAPIOperation
+		if documented, ok := apiOperations[pattern]; ok {
+			operation = generator.apiOperation(documented)
+		} else {
+			operation = htmlOperation(method, path)
+		}
+		operation.Parameters = append(parameters, operation.Parameters...)
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[path][strings.ToLower(method)] = operation
+	}
+
+	return doc
+}
+
+// openAPIPathParameters converts a mux path to an OpenAPI path. Paths
+// matching a subtree get a trailing path parameter.
+func openAPIPathParameters(path string) (string, []openAPIParameter) {
+	path = strings.TrimSuffix(path, "{$}")
+	if path != "/" && strings.HasSuffix(path, "/") {
+		path += "{path}"
+	}
+
+	parameters := []openAPIParameter{}
+	for _, segment := range strings.Split(path, "/") {
+		name, ok := strings.CutPrefix(segment, "{")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSuffix(strings.TrimSuffix(name, "}"), "...")
+
+		schema := &jsonSchema{Type: "string"}
+		switch name {
+		case "id", "year", "month":
+			schema = &jsonSchema{Type: "integer", Format: "int64"}
+		}
+		parameters = append(parameters, openAPIParameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+
+	return path, parameters
+}
+
+func (g *schemaGenerator) apiOperation(documented apiOperation) *openAPIOperation {
+	operation := &openAPIOperation{
+		OperationID: documented.id,
+		Summary:     documented.summary,
+		Tags:        []string{"api"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+		Parameters:  documented.query,
+		Responses: map[string]*openAPIResponse{
+			"default": {
+				Description: "The error of the request",
+				Content:     jsonContent(g.schema(reflect.TypeOf(apiErrorResponse{}), false)),
+			},
+		},
+	}
+
+	switch {
+	case documented.upload:
+		operation.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content: map[string]*openAPIMediaType{
+				"multipart/form-data": {Schema: &jsonSchema{
+					Type:                 "object",
+					Properties:           map[string]*jsonSchema{"file": {Type: "string", Format: "binary"}},
+					Required:             []string{"file"},
+					AdditionalProperties: false,
+				}},
+			},
+		}
+	case documented.request != nil:
+		operation.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  jsonContent(g.schema(reflect.TypeOf(documented.request), true)),
+		}
+	}
+
+	response := &openAPIResponse{Description: documented.description}
+	if documented.response != nil {
+		response.Content = jsonContent(g.envelope(documented))
+	}
+	operation.Responses[fmt.Sprint(documented.status)] = response
+
+	return operation
+}
+
+// envelope is the schema of apiResponse holding the data of the operation.
+func (g *schemaGenerator) envelope(documented apiOperation) *jsonSchema {
+	t := reflect.TypeOf(documented.response)
+	// Interfaces are documented with a nil pointer to them
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	data := g.schema(t, false)
+
+	envelope := &jsonSchema{
+		Type:                 "object",
+		Properties:           map[string]*jsonSchema{"data": data},
+		Required:             []string{"data"},
+		AdditionalProperties: false,
+	}
+
+	if documented.paginated {
+		// Pages are never null
+		data.Nullable = false
+		envelope.Properties["pagination"] = g.schema(reflect.TypeOf(apiPagination{}), false)
+		envelope.Required = append(envelope.Required, "pagination")
+	}
+
+	return envelope
+}
+
+// htmlOperation describes a route of the web interface. They are
+// authenticated with the session cookie, except the pages to sign in.
+func htmlOperation(method, path string) *openAPIOperation {
+	tag := "web"
+	security := []map[string][]string{{"cookieAuth": {}}}
+	if strings.HasPrefix(path, "/signin") || strings.HasPrefix(path, "/signup") || strings.HasPrefix(path, "/static/") {
+		security = []map[string][]string{}
+	}
+	if path == openAPIPath {
+		tag = "api"
+		security = []map[string][]string{}
+	}
+
+	operation := &openAPIOperation{
+		OperationID: operationID(method, path),
+		Summary:     method + " " + path,
+		Tags:        []string{tag},
+		Security:    security,
+		Responses: map[string]*openAPIResponse{
+			"default": {
+				Description: "An HTML page, an HTMX fragment or a redirect",
+				Content:     map[string]*openAPIMediaType{"text/html": {Schema: &jsonSchema{Type: "string"}}},
+			},
+		},
+	}
+
+	if path == openAPIPath {
+		operation.OperationID = "getOpenAPIDocument"
+		operation.Summary = "Get this OpenAPI document"
+		operation.Responses = map[string]*openAPIResponse{
+			"200": {
+				Description: "The OpenAPI document",
+				Content:     jsonContent(&jsonSchema{Type: "object"}),
+			},
+		}
+	}
+
+	return operation
+}
+
+// operationID names an operation after its method and path, e.g.
+// getExpensesById for GET /expenses/{id}.
+func operationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+
+	segments := 0
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+		segments++
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			id.WriteString("By")
+			segment = strings.TrimSuffix(name, "}")
+		}
+		upper := true
+		for _, r := range segment {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				upper = true
+				continue
+			}
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			id.WriteRune(r)
+		}
+	}
+	if segments == 0 {
+		id.WriteString("Index")
+	}
+
+	return id.String()
+}
+
+func jsonContent(schema *jsonSchema) map[string]*openAPIMediaType {
+	return map[string]*openAPIMediaType{"application/json": {Schema: schema}}
+}
+
+// schemaGenerator builds schemas from Go types, adding named structs to the
+// components of the document.
+type schemaGenerator struct {
+	schemas map[string]*jsonSchema
+	types   map[string]reflect.Type
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	expenseType = reflect.TypeOf((*domain.Expense)(nil)).Elem()
+)
+
+// schema returns the schema of values of t. Fields of request bodies are
+// optional, fields of responses are required unless they are omitempty.
+func (g *schemaGenerator) schema(t reflect.Type, request bool) *jsonSchema {
+	switch {
+	case t == expenseType:
+		return g.component(t, func() *jsonSchema { return expenseSchema() })
+	case t == timeType:
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem(), request))
+	case reflect.Interface:
+		// domain.Expense is the only interface encoded, any other value is
+		// left undescribed
+		return &jsonSchema{}
+	case reflect.Struct:
+		return g.component(t, func() *jsonSchema { return g.structSchema(t, request) })
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: "array", Items: g.schema(t.Elem(), request), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: g.schema(t.Elem(), request), Nullable: true}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &jsonSchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &jsonSchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &jsonSchema{Type: "number", Format: "double"}
+	default:
+		panic(fmt.Sprintf("openapi: cannot describe values of type %s", t))
+	}
+}
+
+// component adds the schema of a named type to the components, returning a
+// reference to it.
+func (g *schemaGenerator) component(t reflect.Type, build func() *jsonSchema) *jsonSchema {
+	name := componentName(t)
+	if existing, ok := g.types[name]; ok && existing != t {
+		panic(fmt.Sprintf("openapi: types %s and %s share the schema name %s", existing, t, name))
+	}
+
+	if _, ok := g.types[name]; !ok {
+		g.types[name] = t
+		// Reserve the name before building, so recursive types end
+		g.schemas[name] = &jsonSchema{}
+		*g.schemas[name] = *build()
+	}
+
+	return &jsonSchema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName names the schema of t after the type, without the api
+// prefix of the types of this package.
+func componentName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "api")
+	if name == "" {
+		return t.Name()
+	}
+
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type, request bool) *jsonSchema {
+	schema := &jsonSchema{
+		Type:                 "object",
+		Properties:           map[string]*jsonSchema{},
+		AdditionalProperties: false,
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Untagged embedded structs have their fields promoted
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := g.structSchema(field.Type, request)
+			for property, fieldSchema := range embedded.Properties {
+				schema.Properties[property] = fieldSchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = g.schema(field.Type, request)
+		if !request && !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	sort.Strings(schema.Required)
+	return schema
+}
+
+// nullable allows null besides the values of schema. References cannot
+// have siblings, so they are wrapped.
+func nullable(schema *jsonSchema) *jsonSchema {
+	if schema.Ref != "" {
+		return &jsonSchema{AllOf: []*jsonSchema{schema}, Nullable: true}
+	}
+
+	schema.Nullable = true
+	return schema
+}
+
+// expenseSchema describes domain.Expense as encoded by its MarshalJSON.
+func expenseSchema() *jsonSchema {
+	return &jsonSchema{
+		Type: "object",
+		Properties: map[string]*jsonSchema{
+			"id":          {Type: "integer", Format: "int64"},
+			"source":      {Type: "string"},
+			"date":        {Type: "string", Format: "date-time"},
+			"description": {Type: "string"},
+			"amount":      {Type: "integer", Format: "int64", Description: "Negative for charges"},
+			"expense_type": {
+				Type:        "integer",
+				Description: "0 for charges, 1 for income",
+				Enum:        []any{domain.ChargeType, domain.IncomeType},
+			},
+			"currency":    {Type: "string"},
+			"category_id": {Type: "integer", Format: "int64", Nullable: true},
+			"account_id":  {Type: "integer", Format: "int64", Nullable: true},
+		},
+		Required: []string{
+			"account_id", "amount", "category_id", "currency", "date", "description", "expense_type", "id", "source",
+		},
+		AdditionalProperties: false,
+	}
+}
@@ -0,0 +1,527 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func fetchOpenAPIDocument(t *testing.T, handler http.Handler) *openAPIDocument {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, openAPIPath, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the OpenAPI document without authentication, got %d: %s", w.Code, w.Body)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("Expected a JSON document, got %q", contentType)
+	}
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to decode the OpenAPI document: %v", err)
+	}
+
+	return &doc
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, _ := testutil.SetupTestStorage(t, logger)
+	doc := fetchOpenAPIDocument(t, New(s, logger))
+
+	if doc.OpenAPI != openAPIVersion {
+		t.Errorf("Expected OpenAPI %s, got %q", openAPIVersion, doc.OpenAPI)
+	}
+
+	routes := newRouter(s, logger).registerRoutes().patterns
+
+	documented := 0
+	for _, route := range routes {
+		method, path, found := strings.Cut(route, " ")
+		if !found {
+			continue
+		}
+
+		if strings.HasPrefix(path, apiPrefix+"/") {
+			if _, ok := apiOperations[route]; !ok {
+				t.Errorf("API route %q has no entry in apiOperations", route)
+			}
+		}
+
+		path, _ = openAPIPathParameters(path)
+		operation, ok := doc.Paths[path][strings.ToLower(method)]
+		if !ok {
+			t.Errorf("Route %q is not in the OpenAPI document", route)
+			continue
+		}
+		documented++
+
+		for _, segment := range strings.Split(path, "/") {
+			name, isParameter := strings.CutPrefix(segment, "{")
+			if !isParameter {
+				continue
+			}
+			name = strings.TrimSuffix(name, "}")
+			if !slices.ContainsFunc(operation.Parameters, func(p openAPIParameter) bool {
+				return p.In == "path" && p.Name == name && p.Required
+			}) {
+				t.Errorf("Route %q does not document the path parameter %s", route, name)
+			}
+		}
+	}
+
+	operations := 0
+	ids := map[string]string{}
+	for path, methods := range doc.Paths {
+		for method, operation := range methods {
+			operations++
+			if other, ok := ids[operation.OperationID]; ok {
+				t.Errorf("Operation ID %s is shared by %s and %s %s", operation.OperationID, other, method, path)
+			}
+			ids[operation.OperationID] = method + " " + path
+		}
+	}
+	if operations != documented {
+		t.Errorf("Expected the document to only hold the %d routes, got %d operations", documented, operations)
+	}
+
+	for pattern := range apiOperations {
+		if !slices.Contains(routes, pattern) {
+			t.Errorf("apiOperations documents %q, which is not a route", pattern)
+		}
+	}
+}
+
+// openAPIClient calls the API validating every request and response against
+// the OpenAPI document, recording the operations called.
+type openAPIClient struct {
+	t       *testing.T
+	handler http.Handler
+	doc     *openAPIDocument
+	token   string
+	called  map[string]bool
+}
+
+func (c *openAPIClient) call(method, target string, body any) (int, apiTestResponse) {
+	c.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatalf("Failed to encode body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.serve(req, body)
+}
+
+func (c *openAPIClient) upload(target, filename, content string) (int, apiTestResponse) {
+	c.t.Helper()
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	_, _ = part.Write([]byte(content))
+	if err = writer.Close(); err != nil {
+		c.t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, target, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return c.serve(req, nil)
+}
+
+func (c *openAPIClient) serve(req *http.Request, body any) (int, apiTestResponse) {
+	c.t.Helper()
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	name := req.Method + " " + req.URL.Path
+
+	operation := c.operation(req.Method, req.URL.Path)
+	if operation == nil {
+		c.t.Fatalf("%s is not in the OpenAPI document", name)
+	}
+	c.called[operation.OperationID] = true
+
+	for key := range req.URL.Query() {
+		if !slices.ContainsFunc(operation.Parameters, func(p openAPIParameter) bool {
+			return p.In == "query" && p.Name == key
+		}) {
+			c.t.Errorf("%s: query parameter %s is not documented", name, key)
+		}
+	}
+
+	if body != nil {
+		if operation.RequestBody == nil || operation.RequestBody.Content["application/json"] == nil {
+			c.t.Fatalf("%s: the document has no JSON request body", name)
+		}
+		if err := c.validate(operation.RequestBody.Content["application/json"].Schema, body); err != nil {
+			c.t.Errorf("%s: request body does not match the document: %v", name, err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	c.handler.ServeHTTP(w, req)
+
+	response, ok := operation.Responses[strconv.Itoa(w.Code)]
+	if !ok {
+		response, ok = operation.Responses["default"]
+	}
+	if !ok {
+		c.t.Fatalf("%s: status %d is not documented", name, w.Code)
+	}
+
+	if response.Content == nil {
+		if w.Body.Len() != 0 {
+			c.t.Errorf("%s: expected no content, got %s", name, w.Body)
+		}
+		return w.Code, apiTestResponse{}
+	}
+
+	media := response.Content[w.Header().Get("Content-Type")]
+	if media == nil {
+		c.t.Fatalf("%s: content type %q is not documented", name, w.Header().Get("Content-Type"))
+	}
+	if err := c.validate(media.Schema, json.RawMessage(w.Body.Bytes())); err != nil {
+		c.t.Errorf("%s: %d response does not match the document: %v\n%s", name, w.Code, err, w.Body)
+	}
+
+	var decoded apiTestResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &decoded); err != nil {
+		c.t.Fatalf("%s: failed to decode response: %v", name, err)
+	}
+
+	return w.Code, decoded
+}
+
+// operation finds the operation of the request, preferring paths with a
+// literal segment over a parameter, like the mux does.
+func (c *openAPIClient) operation(method, path string) *openAPIOperation {
+	segments := strings.Split(path, "/")
+
+	var best *openAPIOperation
+	bestLiterals := -1
+	for template, methods := range c.doc.Paths {
+		operation, ok := methods[strings.ToLower(method)]
+		if !ok {
+			continue
+		}
+
+		templateSegments := strings.Split(template, "/")
+		if len(templateSegments) != len(segments) {
+			continue
+		}
+
+		literals := 0
+		for i, segment := range templateSegments {
+			if strings.HasPrefix(segment, "{") {
+				continue
+			}
+			if segment != segments[i] {
+				literals = -1
+				break
+			}
+			literals++
+		}
+
+		if literals > bestLiterals {
+			best, bestLiterals = operation, literals
+		}
+	}
+
+	return best
+}
+
+func (c *openAPIClient) validate(schema *jsonSchema, value any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	var decoded any
+	if err = json.Unmarshal(encoded, &decoded); err != nil {
+		return err
+	}
+
+	return validateSchema(c.doc, schema, decoded, "$")
+}
+
+// validateSchema checks a decoded JSON value against the subset of the
+// schema object the document uses.
+func validateSchema(doc *openAPIDocument, schema *jsonSchema, value any, at string) error {
+	if schema.Ref != "" {
+		component, ok := doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if !ok {
+			return fmt.Errorf("%s: unknown reference %s", at, schema.Ref)
+		}
+		return validateSchema(doc, component, value, at)
+	}
+
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: unexpected null", at)
+	}
+
+	for _, sub := range schema.AllOf {
+		if err := validateSchema(doc, sub, value, at); err != nil {
+			return err
+		}
+	}
+
+	inEnum := func(v any) bool { return reflect.DeepEqual(v, value) }
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, inEnum) {
+		return fmt.Errorf("%s: %v is not one of %v", at, value, schema.Enum)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %T", at, value)
+		}
+		for _, name := range schema.Required {
+			if _, ok = object[name]; !ok {
+				return fmt.Errorf("%s: missing required property %s", at, name)
+			}
+		}
+		for name, property := range object {
+			propertySchema, known := schema.Properties[name]
+			if !known {
+				switch additional := schema.AdditionalProperties.(type) {
+				case bool:
+					if !additional {
+						return fmt.Errorf("%s: unexpected property %s", at, name)
+					}
+					continue
+				case map[string]any:
+					encoded, _ := json.Marshal(additional)
+					propertySchema = &jsonSchema{}
+					_ = json.Unmarshal(encoded, propertySchema)
+				default:
+					continue
+				}
+			}
+			if err := validateSchema(doc, propertySchema, property, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %T", at, value)
+		}
+		for i, item := range items {
+			if err := validateSchema(doc, schema.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected a string, got %T", at, value)
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return fmt.Errorf("%s: expected a date-time: %w", at, err)
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: expected an integer, got %v", at, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected a number, got %T", at, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean, got %T", at, value)
+		}
+	case "":
+	default:
+		return errors.New(at + ": unknown type " + schema.Type)
+	}
+
+	return nil
+}
+
+func TestValidateSchema(t *testing.T) {
+	doc := newOpenAPIDocument(nil)
+	generator := &schemaGenerator{schemas: doc.Components.Schemas, types: map[string]reflect.Type{}}
+	schema := generator.schema(reflect.TypeOf(apiCategory{}), false)
+
+	tests := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"valid", `{"id":1,"name":"Food","pattern":"food","monthly_budget":0}`, true},
+		{"missing field", `{"id":1,"name":"Food","pattern":"food"}`, false},
+		{"unknown field", `{"id":1,"name":"Food","pattern":"food","monthly_budget":0,"color":"red"}`, false},
+		{"wrong type", `{"id":"1","name":"Food","pattern":"food","monthly_budget":0}`, false},
+		{"fractional integer", `{"id":1.5,"name":"Food","pattern":"food","monthly_budget":0}`, false},
+		{"null", `null`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value any
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+
+			err := validateSchema(doc, schema, value, "$")
+			if tt.valid && err != nil {
+				t.Errorf("Expected a valid value, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected an invalid value")
+			}
+		})
+	}
+}
+
+// TestOpenAPIMatchesAPI calls every operation of the API, failing when a
+// request or a response drifts from the document.
+func TestOpenAPIMatchesAPI(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	handler := New(s, logger)
+
+	client := &openAPIClient{
+		t:       t,
+		handler: handler,
+		doc:     fetchOpenAPIDocument(t, handler),
+		token:   createAPIToken(t, s, logger, user),
+		called:  map[string]bool{},
+	}
+
+	status, response := client.call(http.MethodPost, "/api/v1/categories", map[string]any{
+		"name":           "Coffee",
+		"pattern":        "coffee",
+		"monthly_budget": 5000,
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("Expected the category to be created, got %d %+v", status, response.Error)
+	}
+	var category apiCategory
+	decodeData(t, response, &category)
+	categoryPath := fmt.Sprintf("/api/v1/categories/%d", category.ID)
+
+	client.call(http.MethodGet, "/api/v1/categories?page=1&per_page=10", nil)
+	client.call(http.MethodGet, categoryPath, nil)
+	client.call(http.MethodPatch, categoryPath, map[string]any{"name": "Coffee shops"})
+
+	var expenseID int64
+	for _, description := range []string{"Coffee", "Bakery"} {
+		status, response = client.call(http.MethodPost, "/api/v1/expenses", map[string]any{
+			"source":       "Bank",
+			"description":  description,
+			"amount":       350,
+			"currency":     "EUR",
+			"date":         "2025-01-02",
+			"expense_type": domain.ChargeType,
+		})
+		if status != http.StatusCreated {
+			t.Fatalf("Expected the expense to be created, got %d %+v", status, response.Error)
+		}
+		var created apiTestExpense
+		decodeData(t, response, &created)
+		expenseID = created.ID
+	}
+	expensePath := fmt.Sprintf("/api/v1/expenses/%d", expenseID)
+
+	client.call(http.MethodGet, "/api/v1/expenses?description=a&amount_min=1&date_from=2025-01-01&sort=date:desc", nil)
+	client.call(http.MethodGet, expensePath, nil)
+	client.call(http.MethodPut, expensePath, map[string]any{
+		"source":       "Bank",
+		"description":  "Bakery",
+		"amount":       420,
+		"currency":     "EUR",
+		"date":         "2025-01-03T10:00:00Z",
+		"expense_type": domain.ChargeType,
+		"category_id":  nil,
+	})
+	client.call(http.MethodGet, "/api/v1/categories/uncategorized?q=bak", nil)
+	client.call(http.MethodGet, "/api/v1/reports/2025/1", nil)
+
+	// Errors are documented too
+	status, _ = client.call(http.MethodPost, "/api/v1/expenses", map[string]any{"source": "Bank"})
+	if status != http.StatusUnprocessableEntity {
+		t.Errorf("Expected a validation error, got %d", status)
+	}
+	status, _ = client.call(http.MethodGet, "/api/v1/expenses/999999", nil)
+	if status != http.StatusNotFound {
+		t.Errorf("Expected a missing expense to be not found, got %d", status)
+	}
+
+	status, response = client.upload("/api/v1/imports", "statement.csv", "Day,Concept,Value\n2025-01-02,Coffee,-3.50\n")
+	if status != http.StatusCreated {
+		t.Fatalf("Expected the file to be uploaded, got %d %+v", status, response.Error)
+	}
+	var upload apiImport
+	decodeData(t, response, &upload)
+	if upload.Session == nil {
+		t.Fatalf("Expected an import session, got %+v", upload)
+	}
+
+	sessionPath := "/api/v1/imports/" + upload.Session.ID
+	client.call(http.MethodPost, sessionPath+"/mapping", map[string]any{
+		"mapping": map[string]any{
+			"source":             "Bank",
+			"date_column":        0,
+			"description_column": 1,
+			"amount_column":      2,
+			"default_currency":   "EUR",
+		},
+	})
+	status, response = client.call(http.MethodPost, sessionPath+"/execute", map[string]any{"include_rows": []int{}})
+	if status != http.StatusCreated {
+		t.Errorf("Expected the import to be executed, got %d %+v", status, response.Error)
+	}
+
+	client.call(http.MethodDelete, expensePath, nil)
+	client.call(http.MethodDelete, categoryPath, nil)
+
+	for path, methods := range client.doc.Paths {
+		if !strings.HasPrefix(path, apiPrefix+"/") {
+			continue
+		}
+		for method, operation := range methods {
+			if !client.called[operation.OperationID] {
+				t.Errorf("%s %s is not covered by the test", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
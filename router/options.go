@@ -0,0 +1,41 @@
+package router
+
+import "time"
+
+// options are the web settings of the router, set from the configuration.
+type options struct {
+	secureCookies    bool
+	trustedOrigins   []string
+	allowEmbedding   bool
+	importSessionTTL time.Duration
+}
+
+type Option func(*options)
+
+// WithSecureCookies marks session cookies as HTTPS only.
+func WithSecureCookies(secure bool) Option {
+	return func(o *options) {
+		o.secureCookies = secure
+	}
+}
+
+// WithTrustedOrigins allows cross-origin requests from the given origins.
+func WithTrustedOrigins(origins []string) Option {
+	return func(o *options) {
+		o.trustedOrigins = origins
+	}
+}
+
+// WithEmbedding allows the app to be embedded in an iframe.
+func WithEmbedding(allow bool) Option {
+	return func(o *options) {
+		o.allowEmbedding = allow
+	}
+}
+
+// WithImportSessionTTL sets how long an in-progress import is kept.
+func WithImportSessionTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.importSessionTTL = ttl
+	}
+}
@@ -1,36 +1,81 @@
 package router
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/util"
 )
 
 type profileHandler struct {
 	router *router
 }
 
-func (p *profileHandler) RegisterRoutes(mux *http.ServeMux) {
+func (p *profileHandler) RegisterRoutes(mux *routeMux) {
 	mux.HandleFunc("GET /profile", func(w http.ResponseWriter, r *http.Request) {
 		p.profilePage(w, r, nil, nil)
 	})
 	mux.HandleFunc("POST /profile/username", p.updateUsername)
 	mux.HandleFunc("POST /profile/password", p.updatePassword)
+	mux.HandleFunc("POST /profile/currency", p.updateDefaultCurrency)
+	mux.HandleFunc("POST /profile/base-currency", p.updateBaseCurrency)
+	mux.HandleFunc("POST /profile/tokens", p.createAPIToken)
+	mux.HandleFunc("POST /profile/tokens/{id}/revoke", p.revokeAPIToken)
+	mux.HandleFunc("POST /profile/webhooks", p.createWebhook)
+	mux.HandleFunc("POST /profile/webhooks/{id}/delete", p.deleteWebhook)
 }
 
 func (p *profileHandler) profilePage(w http.ResponseWriter, r *http.Request, banner *domain.Banner, err error) {
-	ctx := r.Context()
-	base := viewBaseFromContext(ctx)
+	data := domain.ProfileViewData{
+		ViewBase: viewBaseFromContext(r.Context()),
+	}
 	if banner != nil {
-		base.Banner = *banner
+		data.Banner = *banner
+	}
+
+	if err != nil {
+		data.Error = err.Error()
+	}
+
+	p.renderProfile(w, r, data)
+}
+
+func (p *profileHandler) renderProfile(w http.ResponseWriter, r *http.Request, data domain.ProfileViewData) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	tokens, err := p.router.authService.APITokens(ctx, userID)
+	if err != nil {
+		p.router.logger.Error("Failed to get API tokens", "error", err, "user_id", userID)
+		if data.Error == "" {
+			data.Error = err.Error()
+		}
+	}
+	data.APITokens = tokens
+
+	webhooks, err := p.router.webhookService.List(ctx, userID)
+	if err != nil {
+		p.router.logger.Error("Failed to get webhooks", "error", err, "user_id", userID)
+		if data.Error == "" {
+			data.Error = err.Error()
+		}
 	}
+	data.Webhooks = webhooks
 
+	deliveries, err := p.router.webhookService.Deliveries(ctx, userID)
 	if err != nil {
-		base.Error = err.Error()
+		p.router.logger.Error("Failed to get webhook deliveries", "error", err, "user_id", userID)
+		if data.Error == "" {
+			data.Error = err.Error()
+		}
 	}
+	data.WebhookDeliveries = deliveries
+	data.WebhookEvents = domain.WebhookEvents
 
-	p.router.renderHTML(w, http.StatusOK, base, "base", "pages/profile/index.html")
+	p.router.renderHTML(w, http.StatusOK, data, "base", "pages/profile/index.html")
 }
 
 func (p *profileHandler) updateUsername(w http.ResponseWriter, r *http.Request) {
@@ -95,6 +140,166 @@ func (p *profileHandler) updatePassword(w http.ResponseWriter, r *http.Request)
 	p.renderSuccess(w, r, "Password changed successfully")
 }
 
+func (p *profileHandler) updateDefaultCurrency(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		p.renderError(w, r, errors.New("invalid form data"))
+		return
+	}
+
+	currency := r.FormValue("currency")
+	validationErr, err := p.router.profileService.UpdateDefaultCurrency(ctx, userID, currency)
+	if err != nil {
+		p.router.logger.Error("Failed to update default currency", "error", err, "user_id", userID)
+		p.renderError(w, r, err)
+		return
+	}
+
+	if validationErr != nil {
+		p.renderError(w, r, validationErr)
+		return
+	}
+
+	// The page shows the currency loaded with the session, refresh it.
+	base := viewBaseFromContext(ctx)
+	base.DefaultCurrency, _ = util.NormalizeCurrency(currency)
+	r = r.WithContext(context.WithValue(ctx, viewBaseKey, base))
+
+	p.renderSuccess(w, r, "Default currency updated successfully")
+}
+
+func (p *profileHandler) updateBaseCurrency(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		p.renderError(w, r, errors.New("invalid form data"))
+		return
+	}
+
+	currency := r.FormValue("currency")
+	validationErr, err := p.router.profileService.UpdateBaseCurrency(ctx, userID, currency)
+	if err != nil {
+		p.router.logger.Error("Failed to update base currency", "error", err, "user_id", userID)
+		p.renderError(w, r, err)
+		return
+	}
+
+	if validationErr != nil {
+		p.renderError(w, r, validationErr)
+		return
+	}
+
+	// The page shows the currency loaded with the session, refresh it.
+	base := viewBaseFromContext(ctx)
+	base.BaseCurrency, _ = util.NormalizeCurrency(currency)
+	r = r.WithContext(context.WithValue(ctx, viewBaseKey, base))
+
+	p.renderSuccess(w, r, "Base currency updated successfully")
+}
+
+func (p *profileHandler) createAPIToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		p.renderError(w, r, errors.New("invalid form data"))
+		return
+	}
+
+	token, _, err := p.router.authService.CreateAPIToken(ctx, userID, r.FormValue("name"))
+	if err != nil {
+		p.renderError(w, r, err)
+		return
+	}
+
+	data := domain.ProfileViewData{
+		ViewBase:    viewBaseFromContext(ctx),
+		NewAPIToken: token,
+	}
+	data.Banner = domain.Banner{
+		Icon:    "✓",
+		Message: "API token created. Copy it now, it will not be shown again",
+	}
+	p.renderProfile(w, r, data)
+}
+
+func (p *profileHandler) revokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		p.renderError(w, r, errors.New("invalid token ID"))
+		return
+	}
+
+	if err = p.router.authService.RevokeAPIToken(ctx, userID, id); err != nil {
+		var notFound *domain.NotFoundError
+		if errors.As(err, &notFound) {
+			err = errors.New("API token not found")
+		}
+		p.renderError(w, r, err)
+		return
+	}
+
+	p.renderSuccess(w, r, "API token revoked")
+}
+
+func (p *profileHandler) createWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		p.renderError(w, r, errors.New("invalid form data"))
+		return
+	}
+
+	webhook, err := p.router.webhookService.Create(ctx, userID, r.FormValue("url"), r.Form["events"])
+	if err != nil {
+		p.renderError(w, r, err)
+		return
+	}
+
+	data := domain.ProfileViewData{
+		ViewBase:         viewBaseFromContext(ctx),
+		NewWebhookSecret: webhook.Secret(),
+	}
+	data.Banner = domain.Banner{
+		Icon:    "✓",
+		Message: "Webhook added. Copy its signing secret now, it will not be shown again",
+	}
+	p.renderProfile(w, r, data)
+}
+
+func (p *profileHandler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := userIDFromContext(ctx)
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		p.renderError(w, r, errors.New("invalid webhook ID"))
+		return
+	}
+
+	if err = p.router.webhookService.Delete(ctx, userID, id); err != nil {
+		var notFound *domain.NotFoundError
+		if errors.As(err, &notFound) {
+			err = errors.New("webhook not found")
+		}
+		p.renderError(w, r, err)
+		return
+	}
+
+	p.renderSuccess(w, r, "Webhook deleted")
+}
+
 func (p *profileHandler) renderError(w http.ResponseWriter, r *http.Request, err error) {
 	p.profilePage(w, r, nil, err)
 }
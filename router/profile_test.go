@@ -2,6 +2,7 @@ package router
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -10,6 +11,7 @@ import (
 
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/GustavoCaso/expensetrace/domain"
 	"github.com/GustavoCaso/expensetrace/testutil"
 )
 
@@ -312,3 +314,204 @@ func TestUpdatePasswordHandlerFormParseError(t *testing.T) {
 		t.Error("Response should contain error message for form parse error")
 	}
 }
+
+func TestUpdateDefaultCurrencyHandler(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	handler := New(s, logger)
+
+	formData := url.Values{}
+	formData.Set("currency", "gbp")
+
+	req := httptest.NewRequest(http.MethodPost, "/profile/currency", strings.NewReader(formData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status OK; got %v", resp.Status)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Default currency updated successfully") {
+		t.Error("Response should contain success message")
+	}
+	if !strings.Contains(body, `value="GBP"`) {
+		t.Error("Response should show the updated currency")
+	}
+
+	updatedUser, err := s.GetUserByID(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to retrieve updated user: %v", err)
+	}
+
+	if updatedUser.DefaultCurrency() != "GBP" {
+		t.Errorf("Expected default currency 'GBP', got '%s'", updatedUser.DefaultCurrency())
+	}
+}
+
+func TestAPITokenHandlers(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	handler := New(s, logger)
+
+	formData := url.Values{}
+	formData.Set("name", "Budget script")
+
+	req := httptest.NewRequest(http.MethodPost, "/profile/tokens", strings.NewReader(formData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "API token created") {
+		t.Error("Response should contain success message")
+	}
+	if !strings.Contains(body, "Budget script") {
+		t.Error("Response should list the new token")
+	}
+
+	tokens, err := s.GetAPITokens(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get API tokens: %v", err)
+	}
+	if len(tokens) != 1 {
+		t.Fatalf("Expected 1 API token, got %d", len(tokens))
+	}
+	if !strings.Contains(body, `value="`+tokens[0].Prefix()) {
+		t.Error("Response should show the new token once")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/profile/tokens/%d/revoke", tokens[0].ID()), nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "API token revoked") {
+		t.Error("Response should contain revoke message")
+	}
+
+	tokens, err = s.GetAPITokens(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get API tokens: %v", err)
+	}
+	if len(tokens) != 0 {
+		t.Errorf("Expected the token to be revoked, got %d tokens", len(tokens))
+	}
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	handler := New(s, logger)
+
+	formData := url.Values{}
+	formData.Set("url", "https://example.com/hooks")
+	formData.Add("events", domain.EventExpenseCreated)
+	formData.Add("events", domain.EventBudgetExceeded)
+
+	req := httptest.NewRequest(http.MethodPost, "/profile/webhooks", strings.NewReader(formData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Webhook added") {
+		t.Error("Response should contain success message")
+	}
+	if !strings.Contains(body, "https://example.com/hooks") {
+		t.Error("Response should list the new webhook")
+	}
+
+	webhooks, err := s.GetWebhooks(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get webhooks: %v", err)
+	}
+	if len(webhooks) != 1 {
+		t.Fatalf("Expected 1 webhook, got %d", len(webhooks))
+	}
+	if !webhooks[0].Subscribed(domain.EventBudgetExceeded) || webhooks[0].Subscribed(domain.EventExpenseDeleted) {
+		t.Errorf("Expected the selected events to be subscribed, got %v", webhooks[0].Events())
+	}
+	if !strings.Contains(body, `value="`+webhooks[0].Secret()+`"`) {
+		t.Error("Response should show the signing secret once")
+	}
+
+	formData = url.Values{}
+	formData.Set("url", "ftp://example.com/hooks")
+	formData.Add("events", domain.EventExpenseCreated)
+
+	req = httptest.NewRequest(http.MethodPost, "/profile/webhooks", strings.NewReader(formData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "webhook URL must be an http or https URL") {
+		t.Error("Response should reject URLs that are not http or https")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/profile/webhooks/%d/delete", webhooks[0].ID()), nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "Webhook deleted") {
+		t.Error("Response should contain delete message")
+	}
+
+	webhooks, err = s.GetWebhooks(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get webhooks: %v", err)
+	}
+	if len(webhooks) != 0 {
+		t.Errorf("Expected the webhook to be deleted, got %d webhooks", len(webhooks))
+	}
+}
+
+func TestUpdateBaseCurrencyHandler(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	handler := New(s, logger)
+
+	formData := url.Values{}
+	formData.Set("currency", "usd")
+
+	req := httptest.NewRequest(http.MethodPost, "/profile/base-currency", strings.NewReader(formData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Base currency updated successfully") {
+		t.Error("Response should contain success message")
+	}
+	if !strings.Contains(body, `id="base-currency" name="currency" value="USD"`) {
+		t.Error("Response should show the updated base currency")
+	}
+
+	updatedUser, err := s.GetUserByID(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to retrieve updated user: %v", err)
+	}
+
+	if updatedUser.BaseCurrency() != "USD" {
+		t.Errorf("Expected base currency 'USD', got '%s'", updatedUser.BaseCurrency())
+	}
+}
@@ -62,6 +62,12 @@ func newHTMLRenderer(templateFS fs.FS, sharedTemplateFiles ...string) (*htmlRend
 			}
 			return *s
 		},
+		"derefID": func(id *int64) int64 {
+			if id == nil {
+				return 0
+			}
+			return *id
+		},
 	}
 
 	sharedTemplates, err := template.New("").Funcs(funcs).ParseFS(templateFS, sharedTemplateFiles...)
@@ -18,9 +18,9 @@ func newReportsHandlder(router *router) *reportHandler {
 	}
 }
 
-func (rh *reportHandler) RegisterRoutes(mux *http.ServeMux) {
+func (rh *reportHandler) RegisterRoutes(mux *routeMux) {
 	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
-		rh.reportService.Generate(r.Context(), userIDFromContext(r.Context()))
+		rh.reportService.Generate(r.Context(), userIDFromContext(r.Context()), reportAccount(r))
 		rh.reportsHandler(w, r)
 	})
 }
@@ -37,6 +37,11 @@ func (rh *reportHandler) reportsHandler(w http.ResponseWriter, r *http.Request)
 	selectedYear := now.Year()
 	selectedMonth := int(now.Month())
 	query := r.URL.Query()
+	accountID := reportAccount(r)
+	var selectedAccount int64
+	if accountID != nil {
+		selectedAccount = *accountID
+	}
 
 	// ?month=X&year=Y — HTMX partial swap only
 	if monthQuery := query.Get("month"); monthQuery != "" {
@@ -49,20 +54,28 @@ func (rh *reportHandler) reportsHandler(w http.ResponseWriter, r *http.Request)
 				selectedYear = y
 			}
 		}
-		rep := rh.reportService.ForMonth(userID, selectedMonth, selectedYear)
+		rep := rh.reportService.ForMonth(userID, accountID, selectedMonth, selectedYear)
 		openCategory := query.Get("open_category")
 		rh.renderHTML(w, http.StatusOK, domain.ReportCardData{
 			Report:       rep,
 			OpenCategory: openCategory,
 			OpenMonth:    selectedMonth,
 			OpenYear:     selectedYear,
+			AccountID:    selectedAccount,
 		}, "reports/card")
 		return
 	}
 
 	// Full page — chart + optional pre-rendered card via open_month/open_year/open_category
-	chartData := rh.reportService.ChartData(userID)
+	chartData := rh.reportService.ChartData(userID, accountID)
 	data.ChartData = chartData
+	data.AccountID = selectedAccount
+
+	accounts, err := rh.accountService.List(ctx, userID)
+	if err != nil {
+		rh.logger.Error("Failed to get accounts", "error", err)
+	}
+	data.Accounts = accounts
 
 	openCategory := query.Get("open_category")
 
@@ -78,11 +91,22 @@ func (rh *reportHandler) reportsHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	data.ReportCard = domain.ReportCardData{
-		Report:       rh.reportService.ForMonth(userID, selectedMonth, selectedYear),
+		Report:       rh.reportService.ForMonth(userID, accountID, selectedMonth, selectedYear),
 		OpenCategory: openCategory,
 		OpenMonth:    selectedMonth,
 		OpenYear:     selectedYear,
+		AccountID:    selectedAccount,
 	}
 
 	rh.renderHTML(w, http.StatusOK, data, "base", "pages/reports/index.html")
 }
+
+// reportAccount returns the account the reports are limited to, from the
+// account query parameter. Invalid values show the reports of every account.
+func reportAccount(r *http.Request) *int64 {
+	id, err := strconv.ParseInt(r.URL.Query().Get("account"), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
@@ -19,7 +19,7 @@ func TestHomeHandlerWithOpenParams(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, now, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, now, domain.ChargeType, nil, nil),
 	}
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
 	if err != nil {
@@ -73,7 +73,7 @@ func TestHomeHandlerHTMXPartialSwap(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, now, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, now, domain.ChargeType, nil, nil),
 	}
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
 	if err != nil {
@@ -109,8 +109,8 @@ func TestHomeHandler(t *testing.T) {
 	// Create test expenses
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, now, domain.ChargeType, nil),
-		domain.NewExpense(0, "Test Source", "Uber ride", "USD", -50000, now, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, now, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Test Source", "Uber ride", "USD", -50000, now, domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
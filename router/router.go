@@ -9,18 +9,22 @@ import (
 	"github.com/GustavoCaso/expensetrace/assets"
 	"github.com/GustavoCaso/expensetrace/logger"
 	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/service/account"
 	"github.com/GustavoCaso/expensetrace/service/auth"
 	"github.com/GustavoCaso/expensetrace/service/category"
+	"github.com/GustavoCaso/expensetrace/service/exchange"
 	"github.com/GustavoCaso/expensetrace/service/expense"
 	"github.com/GustavoCaso/expensetrace/service/importsvc"
 	"github.com/GustavoCaso/expensetrace/service/profile"
 	"github.com/GustavoCaso/expensetrace/service/report"
+	"github.com/GustavoCaso/expensetrace/service/transfer"
+	"github.com/GustavoCaso/expensetrace/service/webhook"
 	"github.com/GustavoCaso/expensetrace/storage"
 )
 
-// importSessionTTL is how long an in-progress import session is kept alive
-// before it expires.
-const importSessionTTL = 30 * time.Minute
+// defaultImportSessionTTL is how long an in-progress import session is kept
+// alive before it expires, unless set with WithImportSessionTTL.
+const defaultImportSessionTTL = 30 * time.Minute
 
 type router struct {
 	logger          *logger.Logger
@@ -30,12 +34,38 @@ type router struct {
 	importService   *importsvc.Service
 	authService     *auth.Service
 	profileService  *profile.Service
+	webhookService  *webhook.Service
+	accountService  *account.Service
+	transferService *transfer.Service
+	exchangeService *exchange.Service
 	secureCookie    bool
+	trustedOrigins  []string
+	allowEmbedding  bool
 	html            *htmlRenderer
+	// routes are the patterns registered in New, described by the OpenAPI
+	// document.
+	routes []string
 }
 
-func New(storage storage.Storage, logger *logger.Logger) http.Handler {
-	router := newRouter(storage, logger)
+// routeMux records the patterns registered on the mux, so every route can
+// be described without keeping a separate list.
+type routeMux struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func (m *routeMux) Handle(pattern string, handler http.Handler) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.Handle(pattern, handler)
+}
+
+func (m *routeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.HandleFunc(pattern, handler)
+}
+
+func New(storage storage.Storage, logger *logger.Logger, opts ...Option) http.Handler {
+	router := newRouter(storage, logger, opts...)
 
 	htmlRenderer, err := newHTMLRenderer(assets.HTMLFiles, "base.html", "partials/*.html", "partials/*/*.html")
 	if err != nil {
@@ -45,29 +75,96 @@ func New(storage storage.Storage, logger *logger.Logger) http.Handler {
 
 	router.html = htmlRenderer
 
-	reports := newReportsHandlder(router)
+	mux := router.registerRoutes()
+
+	// wrap entire mux with middlewares
+	wrappedMux := authMiddleware(router, mux)
+	wrappedMux = tokenAuthMiddleware(router, wrappedMux)
+	wrappedMux = loggingMiddleware(logger, wrappedMux)
+
+	if !router.allowEmbedding {
+		wrappedMux = xFrameDenyHeaderMiddleware(wrappedMux)
+	}
+
+	wrappedMux = csrfProtectionMiddleware(logger, router.trustedOrigins, wrappedMux)
+
+	return wrappedMux
+}
+
+// newRouter builds the router with its services. Split from New so tests can
+// exercise internal functions directly.
+func newRouter(storage storage.Storage, logger *logger.Logger, opts ...Option) *router {
+	o := &options{importSessionTTL: defaultImportSessionTTL}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	router := &router{
+		secureCookie:    o.secureCookies,
+		trustedOrigins:  o.trustedOrigins,
+		allowEmbedding:  o.allowEmbedding,
+		logger:          logger,
+		categoryService: category.New(storage, logger),
+		expenseService:  expense.New(storage, logger),
+		reportService:   report.New(storage, logger),
+		importService:   importsvc.New(storage, logger, o.importSessionTTL),
+		authService:     auth.New(storage, logger),
+		profileService:  profile.New(storage, logger),
+		webhookService:  webhook.New(storage, logger),
+		accountService:  account.New(storage, logger),
+		transferService: transfer.New(storage, logger),
+		exchangeService: exchange.New(storage, logger),
+	}
+
+	return router
+}
+
+// registerRoutes registers the routes of every handler on a new mux,
+// recording them for the OpenAPI document.
+func (r *router) registerRoutes() *routeMux {
+	reports := newReportsHandlder(r)
 
 	categories := &categoryHandler{
-		router,
+		r,
 	}
 
 	expenses := &expenseHandler{
-		router,
+		r,
 	}
 
 	importHanlder := &importHandler{
-		router,
+		r,
 	}
 
 	auth := &authHandler{
-		router,
+		r,
 	}
 
 	profile := &profileHandler{
-		router,
+		r,
 	}
 
-	mux := &http.ServeMux{}
+	accounts := &accountHandler{
+		r,
+	}
+
+	transfers := &transferHandler{
+		r,
+	}
+
+	exchangeRates := &exchangeRateHandler{
+		r,
+	}
+
+	api := &apiHandler{
+		r,
+	}
+
+	openAPI := &openAPIHandler{
+		router: r,
+	}
+
+	mux := &routeMux{ServeMux: &http.ServeMux{}}
 
 	// Register auth routes first (these will be excluded from auth middleware)
 	auth.RegisterRoutes(mux)
@@ -76,7 +173,12 @@ func New(storage storage.Storage, logger *logger.Logger) http.Handler {
 	importHanlder.RegisterRoutes(mux)
 	expenses.RegisterRoutes(mux)
 	categories.RegisterRoutes(mux)
+	accounts.RegisterRoutes(mux)
+	transfers.RegisterRoutes(mux)
 	profile.RegisterRoutes(mux)
+	exchangeRates.RegisterRoutes(mux)
+	api.RegisterRoutes(mux)
+	openAPI.RegisterRoutes(mux)
 
 	// Create a file server that serves the files from assets/static.
 
@@ -84,36 +186,9 @@ func New(storage storage.Storage, logger *logger.Logger) http.Handler {
 
 	mux.Handle("GET /static/", http.StripPrefix("/static/", fileserver))
 
-	allowEmbedding := os.Getenv("EXPENSETRACE_ALLOW_EMBEDDING") == "true"
-
-	// wrap entire mux with middlewares
-	wrappedMux := authMiddleware(router, mux)
-	wrappedMux = loggingMiddleware(logger, wrappedMux)
-
-	if !allowEmbedding {
-		wrappedMux = xFrameDenyHeaderMiddleware(wrappedMux)
-	}
+	r.routes = mux.patterns
 
-	wrappedMux = csrfProtectionMiddleware(logger, wrappedMux)
-
-	return wrappedMux
-}
-
-// newRouter builds the router with its services. Split from New so tests can
-// exercise internal functions directly.
-func newRouter(storage storage.Storage, logger *logger.Logger) *router {
-	router := &router{
-		secureCookie:    os.Getenv("EXPENSETRACE_SECURE_COOKIES") == "true",
-		logger:          logger,
-		categoryService: category.New(storage, logger),
-		expenseService:  expense.New(storage, logger),
-		reportService:   report.New(storage, logger),
-		importService:   importsvc.New(storage, logger, importSessionTTL),
-		authService:     auth.New(storage, logger),
-		profileService:  profile.New(storage, logger),
-	}
-
-	return router
+	return mux
 }
 
 // categoryMatcher builds a matcher from the user's current categories. It is
@@ -1,6 +1,10 @@
 package router
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/GustavoCaso/expensetrace/domain"
@@ -18,6 +22,46 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewWithOptions(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, _ := testutil.SetupTestStorage(t, logger)
+
+	signin := func(handler http.Handler) *http.Response {
+		formData := url.Values{}
+		formData.Set("username", "test")
+		formData.Set("password", "test")
+
+		req := httptest.NewRequest(http.MethodPost, "/signin", strings.NewReader(formData.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	resp := signin(New(s, logger))
+	if resp.Header.Get("X-Frame-Options") != "DENY" {
+		t.Errorf("Expected embedding to be denied by default, got %q", resp.Header.Get("X-Frame-Options"))
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Secure {
+			t.Errorf("Expected cookie %s not to be secure by default", cookie.Name)
+		}
+	}
+
+	resp = signin(New(s, logger, WithEmbedding(true), WithSecureCookies(true)))
+	if resp.Header.Get("X-Frame-Options") != "" {
+		t.Errorf("Expected embedding to be allowed, got %q", resp.Header.Get("X-Frame-Options"))
+	}
+	if len(resp.Cookies()) == 0 {
+		t.Fatal("Expected a session cookie")
+	}
+	for _, cookie := range resp.Cookies() {
+		if !cookie.Secure {
+			t.Errorf("Expected cookie %s to be secure", cookie.Name)
+		}
+	}
+}
+
 func TestCategoryMatcherIncludesExcludeCategory(t *testing.T) {
 	logger := testutil.TestLogger(t)
 	s, user := testutil.SetupTestStorage(t, logger)
@@ -0,0 +1,121 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/service/transfer"
+)
+
+type transferHandler struct {
+	*router
+}
+
+func (t *transferHandler) RegisterRoutes(mux *routeMux) {
+	mux.HandleFunc("GET /accounts/transfers", func(w http.ResponseWriter, r *http.Request) {
+		t.transfersHandler(r.Context(), w, domain.ViewBase{})
+	})
+
+	mux.HandleFunc("POST /accounts/transfers", func(w http.ResponseWriter, r *http.Request) {
+		t.linkTransferHandler(r.Context(), w, r)
+	})
+
+	mux.HandleFunc("POST /accounts/transfers/detected", func(w http.ResponseWriter, r *http.Request) {
+		t.linkDetectedTransfersHandler(r.Context(), w)
+	})
+
+	mux.HandleFunc("POST /accounts/transfers/{id}/delete", func(w http.ResponseWriter, r *http.Request) {
+		t.unlinkTransferHandler(r.Context(), w, r)
+	})
+}
+
+// transfersHandler renders the linked transfers and the detected ones.
+// base carries the banner or error of the action that led to the page.
+func (t *transferHandler) transfersHandler(ctx context.Context, w http.ResponseWriter, base domain.ViewBase) {
+	userID := userIDFromContext(ctx)
+	data := domain.TransfersViewData{
+		ViewBase:   viewBaseFromContext(ctx),
+		WindowDays: transfer.WindowDays,
+	}
+	data.Banner = base.Banner
+	data.Error = base.Error
+
+	defer func() {
+		t.renderHTML(w, http.StatusOK, data, "base", "pages/accounts/transfers.html")
+	}()
+
+	transfers, candidates, err := t.transferService.List(ctx, userID)
+	if err != nil {
+		t.logger.Error("Failed to get transfers", "error", err, "user_id", userID)
+		data.Error = err.Error()
+		return
+	}
+
+	data.Transfers = transfers
+	data.Candidates = candidates
+}
+
+func (t *transferHandler) linkTransferHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		t.transfersHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Invalid form data: %s", err.Error())})
+		return
+	}
+
+	chargeID, chargeErr := strconv.ParseInt(r.FormValue("charge_id"), 10, 64)
+	incomeID, incomeErr := strconv.ParseInt(r.FormValue("income_id"), 10, 64)
+	if chargeErr != nil || incomeErr != nil {
+		t.transfersHandler(ctx, w, domain.ViewBase{Error: "Invalid transfer expenses"})
+		return
+	}
+
+	if _, err := t.transferService.Link(ctx, userID, chargeID, incomeID); err != nil {
+		t.transfersHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Error linking the transfer: %s", err.Error())})
+		return
+	}
+
+	t.transfersHandler(ctx, w, domain.ViewBase{Banner: domain.Banner{
+		Icon:    "✅",
+		Message: "Transfer linked",
+	}})
+}
+
+func (t *transferHandler) linkDetectedTransfersHandler(ctx context.Context, w http.ResponseWriter) {
+	userID := userIDFromContext(ctx)
+
+	linked, err := t.transferService.LinkDetected(ctx, userID)
+	if err != nil {
+		t.transfersHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Error linking the transfers: %s", err.Error())})
+		return
+	}
+
+	t.transfersHandler(ctx, w, domain.ViewBase{Banner: domain.Banner{
+		Icon:    "✅",
+		Message: fmt.Sprintf("%d transfers linked", linked),
+	}})
+}
+
+func (t *transferHandler) unlinkTransferHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(ctx)
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		t.transfersHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Invalid transfer ID: %s", err.Error())})
+		return
+	}
+
+	if err = t.transferService.Unlink(ctx, userID, id); err != nil {
+		t.transfersHandler(ctx, w, domain.ViewBase{Error: fmt.Sprintf("Error unlinking the transfer: %s", err.Error())})
+		return
+	}
+
+	t.transfersHandler(ctx, w, domain.ViewBase{Banner: domain.Banner{
+		Icon:    "✅",
+		Message: "Transfer unlinked",
+	}})
+}
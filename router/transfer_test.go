@@ -0,0 +1,60 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func TestTransfersHandler(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	date := time.Now()
+	_, err := s.InsertExpenses(context.Background(), user.ID(), []domain.Expense{
+		domain.NewExpense(0, "checking", "to savings", "EUR", -50000, date, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "savings", "from checking", "EUR", 50000, date, domain.IncomeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	handler := New(s, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts/transfers", nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status OK; got %v", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Link all 1") {
+		t.Fatalf("Expected the detected transfer to be offered, got %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/accounts/transfers/detected", nil)
+	testutil.SetupAuthCookie(t, s, req, user, sessionCookieName, sessionDuration)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "1 transfers linked") {
+		t.Fatalf("Expected the detected transfer to be linked, got %s", w.Body.String())
+	}
+
+	transfers, err := s.GetTransfers(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get transfers: %v", err)
+	}
+	if len(transfers) != 1 {
+		t.Errorf("Expected 1 transfer, got %d", len(transfers))
+	}
+}
@@ -0,0 +1,303 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/logger"
+	"github.com/GustavoCaso/expensetrace/service/exchange"
+	"github.com/GustavoCaso/expensetrace/storage"
+	"github.com/GustavoCaso/expensetrace/util"
+)
+
+type Service struct {
+	storage storage.Storage
+	logger  *logger.Logger
+}
+
+func New(storage storage.Storage, logger *logger.Logger) *Service {
+	return &Service{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// List returns the user's accounts sorted by name.
+func (s *Service) List(ctx context.Context, userID int64) ([]domain.Account, error) {
+	return s.storage.GetAccounts(ctx, userID)
+}
+
+// Get returns one of the user's accounts.
+func (s *Service) Get(ctx context.Context, userID, id int64) (domain.Account, error) {
+	return s.storage.GetAccount(ctx, userID, id)
+}
+
+// Summaries returns the user's accounts with their current balance: the
+// opening balance plus every expense of the account. Expenses in another
+// currency are converted to the account's with the user's exchange rates,
+// the ones without a rate are left out and counted.
+func (s *Service) Summaries(ctx context.Context, userID int64) ([]domain.AccountSummary, error) {
+	accounts, err := s.storage.GetAccounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := s.storage.GetAccountTotals(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]domain.AccountSummary, len(accounts))
+	for i, account := range accounts {
+		total := totals[account.ID()]
+		summaries[i] = domain.AccountSummary{
+			Account:      account,
+			Balance:      account.OpeningBalance() + total.Amount,
+			ExpenseCount: total.Count,
+		}
+		if total.Foreign == 0 {
+			continue
+		}
+
+		// Only accounts with expenses in other currencies need them read
+		// one by one
+		converted, unconverted, foreignErr := s.foreignTotal(ctx, userID, account)
+		if foreignErr != nil {
+			return nil, foreignErr
+		}
+		summaries[i].Balance += converted
+		summaries[i].Unconverted = unconverted
+	}
+
+	return summaries, nil
+}
+
+// foreignTotal adds up the expenses of the account in another currency,
+// converted to the account's. It also returns how many have no rate to be
+// converted with.
+func (s *Service) foreignTotal(ctx context.Context, userID int64, account domain.Account) (int64, int, error) {
+	converter, err := exchange.NewConverterTo(ctx, s.storage, userID, account.Currency())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	accountID := account.ID()
+	expenses, err := s.storage.GetExpensesFiltered(
+		ctx,
+		userID,
+		&domain.ExpenseFilter{AccountID: &accountID},
+		domain.DefaultSortOptions(),
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var total int64
+	var unconverted int
+	for _, expense := range expenses {
+		if sameCurrency(expense, account) {
+			continue
+		}
+
+		amount, converted, convertErr := converter.Convert(ctx, expense)
+		if convertErr != nil {
+			return 0, 0, convertErr
+		}
+		if !converted {
+			unconverted++
+			continue
+		}
+		total += amount
+	}
+
+	return total, unconverted, nil
+}
+
+// sameCurrency reports whether the expense is in the account's currency.
+// Expenses without a currency are taken to be.
+func sameCurrency(expense domain.Expense, account domain.Account) bool {
+	return expense.Currency() == "" || strings.EqualFold(expense.Currency(), account.Currency())
+}
+
+// History returns the account with its expenses, newest first, each with
+// the balance of the account right after it. Expenses in another currency
+// are converted like in Summaries.
+func (s *Service) History(
+	ctx context.Context,
+	userID, id int64,
+) (domain.AccountSummary, []domain.AccountEntry, error) {
+	account, err := s.storage.GetAccount(ctx, userID, id)
+	if err != nil {
+		return domain.AccountSummary{}, nil, err
+	}
+
+	accountID := account.ID()
+	expenses, err := s.storage.GetExpensesFiltered(
+		ctx,
+		userID,
+		&domain.ExpenseFilter{AccountID: &accountID},
+		&domain.SortOptions{Field: domain.SortByDate, Direction: domain.SortAsc},
+	)
+	if err != nil {
+		return domain.AccountSummary{}, nil, err
+	}
+
+	transfers, err := s.storage.GetTransfers(ctx, userID)
+	if err != nil {
+		return domain.AccountSummary{}, nil, err
+	}
+	transferred := make(map[int64]bool, len(transfers)*2)
+	for _, t := range transfers {
+		transferred[t.ChargeID()] = true
+		transferred[t.IncomeID()] = true
+	}
+
+	converter, err := exchange.NewConverterTo(ctx, s.storage, userID, account.Currency())
+	if err != nil {
+		return domain.AccountSummary{}, nil, err
+	}
+
+	// Transfers move the balance like any other expense
+	balance := account.OpeningBalance()
+	unconverted := 0
+	history := make([]domain.AccountEntry, len(expenses))
+	for i, expense := range expenses {
+		amount, converted, convertErr := converter.Convert(ctx, expense)
+		if convertErr != nil {
+			return domain.AccountSummary{}, nil, convertErr
+		}
+		if converted {
+			balance += amount
+		} else {
+			unconverted++
+		}
+
+		// Filled from the end, the newest expense goes first
+		history[len(expenses)-1-i] = domain.AccountEntry{
+			Expense:     expense,
+			Balance:     balance,
+			Transfer:    transferred[expense.ID()],
+			Unconverted: !converted,
+		}
+	}
+
+	return domain.AccountSummary{
+		Account:      account,
+		Balance:      balance,
+		ExpenseCount: len(expenses),
+		Unconverted:  unconverted,
+	}, history, nil
+}
+
+// Create adds an account. Names are unique per user, ignoring case.
+func (s *Service) Create(ctx context.Context, userID int64, form domain.AccountFormData) (domain.Account, error) {
+	form, err := s.validate(ctx, userID, 0, form)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.storage.CreateAccount(ctx, userID, domain.NewAccount(
+		0,
+		form.Name,
+		form.Institution,
+		form.Currency,
+		form.Type,
+		form.OpeningBalance,
+		time.Time{},
+	))
+	if err != nil {
+		s.logger.Error("Failed to create account", "error", err, "user_id", userID)
+		return nil, err
+	}
+
+	s.logger.Info("Account created", "user_id", userID, "account_id", account.ID())
+	return account, nil
+}
+
+// Update replaces the details of one of the user's accounts.
+func (s *Service) Update(
+	ctx context.Context,
+	userID, id int64,
+	form domain.AccountFormData,
+) (domain.Account, error) {
+	existing, err := s.storage.GetAccount(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	form, err = s.validate(ctx, userID, id, form)
+	if err != nil {
+		return nil, err
+	}
+
+	account := domain.NewAccount(
+		id,
+		form.Name,
+		form.Institution,
+		form.Currency,
+		form.Type,
+		form.OpeningBalance,
+		existing.CreatedAt(),
+	)
+	if _, err = s.storage.UpdateAccount(ctx, userID, account); err != nil {
+		s.logger.Error("Failed to update account", "error", err, "user_id", userID)
+		return nil, err
+	}
+
+	s.logger.Info("Account updated", "user_id", userID, "account_id", id)
+	return account, nil
+}
+
+// Delete deletes one of the user's accounts. Its expenses are kept, without
+// an account.
+func (s *Service) Delete(ctx context.Context, userID, id int64) error {
+	deleted, err := s.storage.DeleteAccount(ctx, userID, id)
+	if err != nil {
+		s.logger.Error("Failed to delete account", "error", err, "user_id", userID)
+		return err
+	}
+	if deleted == 0 {
+		return &domain.NotFoundError{}
+	}
+
+	s.logger.Info("Account deleted", "user_id", userID, "account_id", id)
+	return nil
+}
+
+// validate normalizes the currency of the form and checks the form of the
+// account with the given ID, 0 for a new account.
+func (s *Service) validate(
+	ctx context.Context,
+	userID, id int64,
+	form domain.AccountFormData,
+) (domain.AccountFormData, error) {
+	if strings.TrimSpace(form.Name) == "" {
+		return form, errors.New("account name is required")
+	}
+	if !slices.Contains(domain.AccountTypes, form.Type) {
+		return form, fmt.Errorf("unknown account type %q", form.Type)
+	}
+
+	currency, ok := util.NormalizeCurrency(form.Currency)
+	if !ok {
+		return form, errors.New("currency must be a three letter code like EUR")
+	}
+	form.Currency = currency
+
+	accounts, err := s.storage.GetAccounts(ctx, userID)
+	if err != nil {
+		return form, err
+	}
+	for _, account := range accounts {
+		if account.ID() != id && strings.EqualFold(account.Name(), form.Name) {
+			return form, fmt.Errorf("an account named %q already exists", account.Name())
+		}
+	}
+
+	return form, nil
+}
@@ -0,0 +1,192 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func TestServiceCreate_RejectsDuplicateNames(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	svc := New(s, logger)
+
+	form := domain.AccountFormData{Name: "Revolut", Currency: "EUR", Type: domain.CheckingAccount}
+	if _, err := svc.Create(context.Background(), user.ID(), form); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	form.Name = "REVOLUT"
+	if _, err := svc.Create(context.Background(), user.ID(), form); err == nil {
+		t.Fatal("Expected an error creating an account with a duplicate name")
+	}
+
+	form.Name = "Cash"
+	form.Type = "piggy bank"
+	if _, err := svc.Create(context.Background(), user.ID(), form); err == nil {
+		t.Fatal("Expected an error creating an account with an unknown type")
+	}
+
+	form.Type = domain.CashAccount
+	form.Currency = "EURO"
+	if _, err := svc.Create(context.Background(), user.ID(), form); err == nil {
+		t.Fatal("Expected an error creating an account with a currency that is not a three letter code")
+	}
+
+	form.Currency = "usd"
+	account, err := svc.Create(context.Background(), user.ID(), form)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if account.Currency() != "USD" {
+		t.Errorf("Expected the currency to be upper cased, got %q", account.Currency())
+	}
+}
+
+func TestServiceSummariesAndHistory(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	svc := New(s, logger)
+	ctx := context.Background()
+
+	account, err := svc.Create(ctx, user.ID(), domain.AccountFormData{
+		Name:           "Checking",
+		Currency:       "EUR",
+		Type:           domain.CheckingAccount,
+		OpeningBalance: 10000,
+	})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	accountID := account.ID()
+
+	_, err = s.InsertExpenses(ctx, user.ID(), []domain.Expense{
+		domain.NewExpense(0, "bank", "salary", "EUR", 250000,
+			time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), domain.IncomeType, nil, &accountID),
+		domain.NewExpense(0, "bank", "rent", "EUR", -90000,
+			time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC), domain.ChargeType, nil, &accountID),
+		domain.NewExpense(0, "bank", "groceries", "EUR", -5000,
+			time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC), domain.ChargeType, nil, &accountID),
+		domain.NewExpense(0, "card", "coffee", "EUR", -300,
+			time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC), domain.ChargeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	summaries, err := svc.Summaries(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Summaries returned error: %v", err)
+	}
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 account, got %d", len(summaries))
+	}
+	if summaries[0].Balance != 165000 || summaries[0].ExpenseCount != 3 {
+		t.Errorf("Expected a balance of 165000 over 3 expenses, got %d over %d",
+			summaries[0].Balance, summaries[0].ExpenseCount)
+	}
+
+	summary, history, err := svc.History(ctx, user.ID(), accountID)
+	if err != nil {
+		t.Fatalf("History returned error: %v", err)
+	}
+	if summary.Balance != 165000 {
+		t.Errorf("Expected a balance of 165000, got %d", summary.Balance)
+	}
+
+	// Newest first, each with the balance right after it
+	expected := []struct {
+		description string
+		balance     int64
+	}{
+		{"rent", 165000},
+		{"groceries", 255000},
+		{"salary", 260000},
+	}
+	if len(history) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(history))
+	}
+	for i, want := range expected {
+		if history[i].Expense.Description() != want.description || history[i].Balance != want.balance {
+			t.Errorf("Entry %d: expected %s with balance %d, got %s with balance %d",
+				i, want.description, want.balance, history[i].Expense.Description(), history[i].Balance)
+		}
+	}
+}
+
+func TestServiceSummariesAndHistory_ConvertsOtherCurrencies(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	svc := New(s, logger)
+	ctx := context.Background()
+
+	account, err := svc.Create(ctx, user.ID(), domain.AccountFormData{
+		Name:     "Travel card",
+		Currency: "EUR",
+		Type:     domain.CreditAccount,
+	})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	accountID := account.ID()
+
+	_, err = s.SaveExchangeRates(ctx, user.ID(), []domain.ExchangeRate{
+		domain.NewExchangeRate(0, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), "EUR", "USD", 1.25),
+	})
+	if err != nil {
+		t.Fatalf("Failed to save exchange rates: %v", err)
+	}
+
+	_, err = s.InsertExpenses(ctx, user.ID(), []domain.Expense{
+		domain.NewExpense(0, "bank", "lunch", "EUR", -2000,
+			time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC), domain.ChargeType, nil, &accountID),
+		domain.NewExpense(0, "bank", "hotel", "usd", -10000,
+			time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC), domain.ChargeType, nil, &accountID),
+		domain.NewExpense(0, "bank", "taxi", "JPY", -300000,
+			time.Date(2024, time.January, 4, 0, 0, 0, 0, time.UTC), domain.ChargeType, nil, &accountID),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	// The hotel is converted, the taxi has no rate and is left out
+	summaries, err := svc.Summaries(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Summaries returned error: %v", err)
+	}
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 account, got %d", len(summaries))
+	}
+	if summaries[0].Balance != -10000 || summaries[0].Unconverted != 1 || summaries[0].ExpenseCount != 3 {
+		t.Errorf("Expected a balance of -10000 with 1 of 3 expenses unconverted, got %d with %d of %d",
+			summaries[0].Balance, summaries[0].Unconverted, summaries[0].ExpenseCount)
+	}
+
+	summary, history, err := svc.History(ctx, user.ID(), accountID)
+	if err != nil {
+		t.Fatalf("History returned error: %v", err)
+	}
+	if summary.Balance != -10000 || summary.Unconverted != 1 {
+		t.Errorf("Expected a balance of -10000 with 1 expense unconverted, got %d with %d",
+			summary.Balance, summary.Unconverted)
+	}
+	if len(history) != 3 || !history[0].Unconverted || history[0].Balance != -10000 || history[1].Unconverted {
+		t.Errorf("Expected the taxi to be left out of the balance, got %+v", history)
+	}
+}
+
+func TestServiceDelete_UnknownAccount(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	svc := New(s, logger)
+
+	err := svc.Delete(context.Background(), user.ID(), 42)
+	var notFound *domain.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Expected a NotFoundError, got %v", err)
+	}
+}
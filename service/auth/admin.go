@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+// CreateUser creates a user without signing them in, for administration
+// outside the web app.
+func (s *Service) CreateUser(ctx context.Context, username, password string) (domain.User, error) {
+	if username == "" {
+		return nil, errors.New("username is required")
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.storage.CreateUser(ctx, username, hashedPassword)
+	if err != nil {
+		s.logger.Error("Failed to create user", "error", err, "username", username)
+		return nil, err
+	}
+
+	s.logger.Info("User created", "user_id", user.ID(), "username", username)
+	return user, nil
+}
+
+// ResetPassword sets a new password for the user without asking for the
+// current one, and signs them out everywhere.
+func (s *Service) ResetPassword(ctx context.Context, username, password string) error {
+	user, err := s.storage.GetUserByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if err = s.storage.UpdatePassword(ctx, user.ID(), hashedPassword); err != nil {
+		s.logger.Error("Failed to update password", "error", err, "user_id", user.ID())
+		return err
+	}
+
+	if err = s.storage.DeleteUserSessions(ctx, user.ID()); err != nil {
+		s.logger.Error("Failed to delete sessions", "error", err, "user_id", user.ID())
+		return err
+	}
+
+	s.logger.Info("Password reset", "user_id", user.ID())
+	return nil
+}
+
+// DeleteUser removes the user and all of their data.
+func (s *Service) DeleteUser(ctx context.Context, username string) error {
+	user, err := s.storage.GetUserByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	if err = s.storage.DeleteUser(ctx, user.ID()); err != nil {
+		s.logger.Error("Failed to delete user", "error", err, "user_id", user.ID())
+		return err
+	}
+
+	s.logger.Info("User deleted", "user_id", user.ID(), "username", username)
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", errors.New("password must be at least 8 characters long")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hashedPassword), nil
+}
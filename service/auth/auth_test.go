@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/GustavoCaso/expensetrace/domain"
@@ -202,3 +203,93 @@ func TestSignout_DeletesSession(t *testing.T) {
 		t.Fatal("Expected session to be deleted")
 	}
 }
+
+func TestResetPassword_SignsTheUserOut(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, _ := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger)
+
+	if _, err := svc.CreateUser(ctx, "operator", "short"); err == nil {
+		t.Error("Expected a short password to be rejected")
+	}
+
+	if _, err := svc.CreateUser(ctx, "operator", "password123"); err != nil {
+		t.Fatalf("CreateUser returned error: %v", err)
+	}
+
+	sessionID, _, validationErr, err := svc.Signin(ctx, "operator", "password123")
+	if err != nil || validationErr != nil {
+		t.Fatalf("Signin failed: %v %v", validationErr, err)
+	}
+
+	if err = svc.ResetPassword(ctx, "operator", "new-password"); err != nil {
+		t.Fatalf("ResetPassword returned error: %v", err)
+	}
+
+	if _, err = svc.AuthenticatedUser(ctx, sessionID); err == nil {
+		t.Error("Expected the sessions to be removed after a password reset")
+	}
+
+	_, _, validationErr, err = svc.Signin(ctx, "operator", "new-password")
+	if err != nil || validationErr != nil {
+		t.Errorf("Expected signin with the new password to work: %v %v", validationErr, err)
+	}
+
+	var notFound *domain.NotFoundError
+	if err = svc.ResetPassword(ctx, "nobody", "new-password"); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError for an unknown user, got %v", err)
+	}
+}
+
+func TestAPITokens_AuthenticateTheirOwner(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger)
+
+	if _, _, err := svc.CreateAPIToken(ctx, user.ID(), "  "); err == nil {
+		t.Error("Expected a token without a name to be rejected")
+	}
+
+	token, apiToken, err := svc.CreateAPIToken(ctx, user.ID(), "scripts")
+	if err != nil {
+		t.Fatalf("CreateAPIToken returned error: %v", err)
+	}
+	if !strings.HasPrefix(token, TokenPrefix) || !strings.HasPrefix(token, apiToken.Prefix()) {
+		t.Errorf("Expected token %q to start with %q", token, apiToken.Prefix())
+	}
+
+	tokenUser, err := svc.AuthenticatedTokenUser(ctx, token)
+	if err != nil {
+		t.Fatalf("AuthenticatedTokenUser returned error: %v", err)
+	}
+	if tokenUser.ID() != user.ID() {
+		t.Errorf("Expected user %d, got %d", user.ID(), tokenUser.ID())
+	}
+
+	tokens, err := svc.APITokens(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("APITokens returned error: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].LastUsedAt().IsZero() {
+		t.Errorf("Expected the token use to be recorded, got %v", tokens)
+	}
+
+	var notFound *domain.NotFoundError
+	if _, err = svc.AuthenticatedTokenUser(ctx, token+"0"); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError for an unknown token, got %v", err)
+	}
+
+	if err = svc.RevokeAPIToken(ctx, user.ID(), apiToken.ID()); err != nil {
+		t.Fatalf("RevokeAPIToken returned error: %v", err)
+	}
+	if _, err = svc.AuthenticatedTokenUser(ctx, token); !errors.As(err, &notFound) {
+		t.Errorf("Expected a revoked token to be rejected, got %v", err)
+	}
+	if err = svc.RevokeAPIToken(ctx, user.ID(), apiToken.ID()); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError revoking twice, got %v", err)
+	}
+}
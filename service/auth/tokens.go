@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/util"
+)
+
+const (
+	// TokenPrefix starts every API token, so leaked tokens are easy to spot.
+	TokenPrefix = "et_"
+	// tokenBytes is the randomness of an API token, resulting in twice as
+	// many hex characters.
+	tokenBytes = 32
+	// tokenDisplayLength is how many characters of the token are kept in
+	// clear to tell the tokens apart.
+	tokenDisplayLength = len(TokenPrefix) + 8
+	// maxTokenNameLength bounds the name given to a token.
+	maxTokenNameLength = 100
+)
+
+// CreateAPIToken creates a token for the user. The token itself is only
+// returned here, only its hash is stored.
+func (s *Service) CreateAPIToken(ctx context.Context, userID int64, name string) (string, domain.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.New("token name is required")
+	}
+	if utf8.RuneCountInString(name) > maxTokenNameLength {
+		return "", nil, errors.New("token name must be at most 100 characters long")
+	}
+
+	token := TokenPrefix + util.GenerateRandomID(tokenBytes)
+	apiToken, err := s.storage.CreateAPIToken(ctx, userID, name, token[:tokenDisplayLength], hashToken(token))
+	if err != nil {
+		s.logger.Error("Failed to create API token", "error", err, "user_id", userID)
+		return "", nil, err
+	}
+
+	s.logger.Info("API token created", "user_id", userID, "token_id", apiToken.ID())
+	return token, apiToken, nil
+}
+
+// APITokens lists the user's tokens, newest first.
+func (s *Service) APITokens(ctx context.Context, userID int64) ([]domain.APIToken, error) {
+	return s.storage.GetAPITokens(ctx, userID)
+}
+
+// RevokeAPIToken deletes one of the user's tokens.
+func (s *Service) RevokeAPIToken(ctx context.Context, userID, id int64) error {
+	deleted, err := s.storage.DeleteAPIToken(ctx, userID, id)
+	if err != nil {
+		s.logger.Error("Failed to revoke API token", "error", err, "user_id", userID)
+		return err
+	}
+	if deleted == 0 {
+		return &domain.NotFoundError{}
+	}
+
+	s.logger.Info("API token revoked", "user_id", userID, "token_id", id)
+	return nil
+}
+
+// AuthenticatedTokenUser returns the user that owns the given API token,
+// recording that the token was used.
+func (s *Service) AuthenticatedTokenUser(ctx context.Context, token string) (domain.User, error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return nil, &domain.NotFoundError{}
+	}
+
+	apiToken, err := s.storage.GetAPITokenByHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.storage.UpdateAPITokenLastUsed(ctx, apiToken.ID(), time.Now()); err != nil {
+		// The request can go on, only the last use is out of date
+		s.logger.Error("Failed to record API token use", "error", err, "token_id", apiToken.ID())
+	}
+
+	return s.storage.GetUserByID(ctx, apiToken.UserID())
+}
+
+// hashToken hashes API tokens for storage. Tokens are random, so unlike
+// passwords a fast hash is enough and lets them be looked up directly.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
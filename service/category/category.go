@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"math"
 	"regexp"
 	"slices"
 	"sort"
@@ -15,18 +16,22 @@ import (
 	"github.com/GustavoCaso/expensetrace/domain"
 	"github.com/GustavoCaso/expensetrace/logger"
 	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/service/exchange"
+	"github.com/GustavoCaso/expensetrace/service/webhook"
 	"github.com/GustavoCaso/expensetrace/storage"
 )
 
 type Service struct {
-	storage storage.Storage
-	logger  *logger.Logger
+	storage  storage.Storage
+	logger   *logger.Logger
+	webhooks *webhook.Service
 }
 
 func New(storage storage.Storage, logger *logger.Logger) *Service {
 	return &Service{
-		storage: storage,
-		logger:  logger,
+		storage:  storage,
+		logger:   logger,
+		webhooks: webhook.New(storage, logger),
 	}
 }
 
@@ -59,6 +64,11 @@ func (c *Service) EnhancedList(
 	}
 	uncategorizedCount := len(uncategorizedInfos)
 
+	converter, err := exchange.NewConverter(ctx, c.storage, userID)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
 	enhancedCategories := make([]domain.EnhancedCategory, len(cats))
 
 	categorizedCount := 0
@@ -69,7 +79,11 @@ func (c *Service) EnhancedList(
 		}
 
 		categorizedCount += len(expenses)
-		enhancedCategories[i] = createEnhancedCategory(cat, expenses)
+		enhanced, enhancedErr := createEnhancedCategory(ctx, converter, cat, expenses)
+		if enhancedErr != nil {
+			return nil, 0, 0, enhancedErr
+		}
+		enhancedCategories[i] = enhanced
 	}
 
 	return enhancedCategories, categorizedCount, uncategorizedCount, nil
@@ -103,18 +117,37 @@ func ValidateBudget(budgetStr string) (int64, error) {
 		return 0, errors.New("budget cannot be negative")
 	}
 
-	budgetCents := int64(budgetFloat * 100) //nolint:mnd // the value is obvious
+	// Rounded, as amounts like 0.29 are not exact in binary
+	budgetCents := int64(math.Round(budgetFloat * 100)) //nolint:mnd // the value is obvious
 	return budgetCents, nil
 }
 
-func createEnhancedCategory(category domain.Category, expenses []domain.Expense) domain.EnhancedCategory {
+// createEnhancedCategory adds up the expenses of the category in the base
+// currency. Expenses without an exchange rate are counted apart.
+func createEnhancedCategory(
+	ctx context.Context,
+	converter *exchange.Converter,
+	category domain.Category,
+	expenses []domain.Expense,
+) (domain.EnhancedCategory, error) {
 	var totalAmount int64
 	var lastTransaction time.Time
 	spendingCount := 0
 	incomeCount := 0
+	converted := 0
+	unconverted := 0
 
 	for _, exp := range expenses {
-		totalAmount += exp.Amount()
+		amount, ok, err := converter.Convert(ctx, exp)
+		if err != nil {
+			return domain.EnhancedCategory{}, err
+		}
+		if ok {
+			totalAmount += amount
+			converted++
+		} else {
+			unconverted++
+		}
 
 		if exp.Amount() < 0 {
 			spendingCount++
@@ -128,8 +161,8 @@ func createEnhancedCategory(category domain.Category, expenses []domain.Expense)
 	}
 
 	avgAmount := int64(0)
-	if len(expenses) > 0 {
-		avgAmount = totalAmount / int64(len(expenses))
+	if converted > 0 {
+		avgAmount = totalAmount / int64(converted)
 	}
 
 	lastTransactionStr := ""
@@ -145,7 +178,9 @@ func createEnhancedCategory(category domain.Category, expenses []domain.Expense)
 		TotalAmount:     totalAmount,
 		SpendingCount:   spendingCount,
 		IncomeCount:     incomeCount,
-	}
+		Currency:        converter.Base(),
+		Unconverted:     unconverted,
+	}, nil
 }
 
 func (c *Service) UpdateCategoryPattern(
@@ -197,6 +232,7 @@ func (c *Service) UpdateCategoryPattern(
 				ex.Date(),
 				ex.Type(),
 				&categoryID,
+				ex.AccountID(),
 			)
 			updatedExpenses[i] = expense
 		}
@@ -212,6 +248,10 @@ func (c *Service) UpdateCategoryPattern(
 		}
 	}
 
+	c.webhooks.Publish(ctx, userID, domain.EventCategoryUpdated, webhook.NewCategory(
+		domain.NewCategory(cat.ID(), cat.Name(), extendedRegex, cat.MonthlyBudget()),
+	))
+
 	return nil
 }
 
@@ -262,6 +302,7 @@ func (c *Service) Create(
 				ex.Date(),
 				ex.Type(),
 				&categoryID,
+				ex.AccountID(),
 			)
 			updatedExpenses[i] = expense
 		}
@@ -348,6 +389,7 @@ func (c *Service) Update(
 	}
 
 	if !patternChanged {
+		c.webhooks.Publish(ctx, userID, domain.EventCategoryUpdated, webhook.NewCategory(updatedCategory))
 		return updatedCategory, true, false, nil
 	}
 
@@ -393,6 +435,7 @@ func (c *Service) Update(
 				ex.Date(),
 				ex.Type(),
 				id,
+				ex.AccountID(),
 			)
 			toUpdated = append(toUpdated, expense)
 		}
@@ -412,6 +455,8 @@ func (c *Service) Update(
 		}
 	}
 
+	c.webhooks.Publish(ctx, userID, domain.EventCategoryUpdated, webhook.NewCategory(updatedCategory))
+
 	return updatedCategory, true, patternChanged, nil
 }
 
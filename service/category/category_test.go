@@ -49,12 +49,62 @@ func TestServiceList_IncludesExcludeCategory(t *testing.T) {
 	}
 }
 
+func TestServiceEnhancedList_LeavesUnconvertedAmountsOut(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	categoryID, err := s.CreateCategory(ctx, user.ID(), "Travel", "hotel|taxi", 0)
+	if err != nil {
+		t.Fatalf("Failed to create Category: %v", err)
+	}
+
+	now := time.Now()
+	_, err = s.InsertExpenses(ctx, user.ID(), []domain.Expense{
+		domain.NewExpense(0, "card", "hotel", "EUR", -2000, now, domain.ChargeType, &categoryID, nil),
+		domain.NewExpense(0, "card", "hotel", "USD", -5000, now, domain.ChargeType, &categoryID, nil),
+		domain.NewExpense(0, "card", "taxi", "JPY", -300, now, domain.ChargeType, &categoryID, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert test expenses: %v", err)
+	}
+
+	_, err = s.SaveExchangeRates(ctx, user.ID(), []domain.ExchangeRate{
+		domain.NewExchangeRate(0, now.AddDate(0, -1, 0), "EUR", "USD", 1.25),
+	})
+	if err != nil {
+		t.Fatalf("Failed to save exchange rates: %v", err)
+	}
+	if err = s.UpdateBaseCurrency(ctx, user.ID(), "EUR"); err != nil {
+		t.Fatalf("Failed to update base currency: %v", err)
+	}
+
+	svc := New(s, logger)
+	categories, _, _, err := svc.EnhancedList(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("EnhancedList returned error: %v", err)
+	}
+
+	for _, c := range categories {
+		if c.ID() != categoryID {
+			continue
+		}
+		// 20 EUR and 50 USD as 40 EUR, 300 JPY without a rate are left out
+		if c.TotalAmount != -6000 || c.AvgAmount != -3000 || c.Unconverted != 1 || c.Total != 3 {
+			t.Errorf("Expected a total of -6000, average of -3000 and 1 unconverted of 3, got %d, %d, %d of %d",
+				c.TotalAmount, c.AvgAmount, c.Unconverted, c.Total)
+		}
+		return
+	}
+	t.Fatal("Expected the Travel category")
+}
+
 func TestServiceCreate_MatchesExistingUncategorizedExpenses(t *testing.T) {
 	logger := testutil.TestLogger(t)
 	s, user := testutil.SetupTestStorage(t, logger)
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "cinema", "USD", -123456, time.Now(), domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "cinema", "USD", -123456, time.Now(), domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -105,8 +155,8 @@ func TestServiceUpdate_PatternChangeRecategorizesExpenses(t *testing.T) {
 	}
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "cinema", "USD", -123456, time.Now(), domain.ChargeType, &categoryID),
-		domain.NewExpense(0, "Test Source", "gym", "USD", -123, time.Now(), domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "cinema", "USD", -123456, time.Now(), domain.ChargeType, &categoryID, nil),
+		domain.NewExpense(0, "Test Source", "gym", "USD", -123, time.Now(), domain.ChargeType, nil, nil),
 	}
 
 	_, err = s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -204,8 +254,8 @@ func TestServiceUpdateCategoryPattern_ExtendsPatternAndRecategorizes(t *testing.
 	}
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "netflix", "USD", -1500, time.Now(), domain.ChargeType, nil),
-		domain.NewExpense(0, "Test Source", "cinema", "USD", -1000, time.Now(), domain.ChargeType, &categoryID),
+		domain.NewExpense(0, "Test Source", "netflix", "USD", -1500, time.Now(), domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Test Source", "cinema", "USD", -1000, time.Now(), domain.ChargeType, &categoryID, nil),
 	}
 
 	_, err = s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -260,9 +310,9 @@ func TestServiceGetUncategorized_GroupsByDescription(t *testing.T) {
 	s, user := testutil.SetupTestStorage(t, logger)
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "coffee shop", "USD", -500, time.Now(), domain.ChargeType, nil),
-		domain.NewExpense(0, "Test Source", "coffee shop", "USD", -600, time.Now(), domain.ChargeType, nil),
-		domain.NewExpense(0, "Test Source", "hardware store", "USD", -1500, time.Now(), domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "coffee shop", "USD", -500, time.Now(), domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Test Source", "coffee shop", "USD", -600, time.Now(), domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Test Source", "hardware store", "USD", -1500, time.Now(), domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -317,8 +367,8 @@ func TestServiceTest_ReturnsMatchesWithoutWriting(t *testing.T) {
 	s, user := testutil.SetupTestStorage(t, logger)
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "cinema", "USD", -123456, time.Now(), domain.ChargeType, nil),
-		domain.NewExpense(0, "Test Source", "grocery", "USD", -2000, time.Now(), domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "cinema", "USD", -123456, time.Now(), domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Test Source", "grocery", "USD", -2000, time.Now(), domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -0,0 +1,152 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/storage"
+)
+
+// Converter converts expense amounts to the user's base currency with the
+// rate of the expense's date, or the latest one before it.
+type Converter struct {
+	storage storage.Storage
+	userID  int64
+	base    string
+	// pivots are the bases of the user's rates, used for cross rates when
+	// there is no rate between a currency and the base currency.
+	pivots  []string
+	factors map[factorKey]factor
+}
+
+type factorKey struct {
+	currency string
+	date     string
+}
+
+type factor struct {
+	value float64
+	found bool
+}
+
+// NewConverter returns a converter to the user's base currency. Without a
+// base currency amounts are kept as they are.
+func NewConverter(ctx context.Context, storage storage.Storage, userID int64) (*Converter, error) {
+	user, err := storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConverterTo(ctx, storage, userID, user.BaseCurrency())
+}
+
+// NewConverterTo returns a converter to the given currency, like the one of
+// an account, with the user's rates. Without a currency amounts are kept as
+// they are.
+func NewConverterTo(ctx context.Context, storage storage.Storage, userID int64, currency string) (*Converter, error) {
+	c := &Converter{
+		storage: storage,
+		userID:  userID,
+		base:    strings.ToUpper(strings.TrimSpace(currency)),
+		factors: map[factorKey]factor{},
+	}
+	if c.base == "" {
+		return c, nil
+	}
+
+	var err error
+	c.pivots, err = storage.GetExchangeRateBases(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Base is the currency amounts are converted to, empty when there is none.
+func (c *Converter) Base() string {
+	return c.base
+}
+
+// Convert returns the amount of the expense in the base currency. It reports
+// false, returning 0, when there is no rate to convert it: amounts in other
+// currencies are never added to base currency totals. Currencies are
+// compared upper cased, like rates are stored.
+func (c *Converter) Convert(ctx context.Context, ex domain.Expense) (int64, bool, error) {
+	currency := strings.ToUpper(strings.TrimSpace(ex.Currency()))
+	if c.base == "" || currency == "" || currency == c.base {
+		return ex.Amount(), true, nil
+	}
+
+	key := factorKey{currency: currency, date: ex.Date().Format(dateLayout)}
+	f, ok := c.factors[key]
+	if !ok {
+		value, found, err := c.factor(ctx, currency, ex.Date())
+		if err != nil {
+			return 0, false, err
+		}
+		f = factor{value: value, found: found}
+		c.factors[key] = f
+	}
+
+	if !f.found {
+		return 0, false, nil
+	}
+
+	return int64(math.Round(float64(ex.Amount()) * f.value)), true, nil
+}
+
+// factor returns what an amount in currency is multiplied by to get it in
+// the base currency, from a rate between both currencies in either
+// direction or, failing that, a cross rate through another base.
+func (c *Converter) factor(ctx context.Context, currency string, date time.Time) (float64, bool, error) {
+	rate, found, err := c.rate(ctx, c.base, currency, date)
+	if err != nil {
+		return 0, false, err
+	}
+	if found {
+		return 1 / rate, true, nil
+	}
+
+	rate, found, err = c.rate(ctx, currency, c.base, date)
+	if err != nil || found {
+		return rate, found, err
+	}
+
+	for _, pivot := range c.pivots {
+		if pivot == c.base || pivot == currency {
+			continue
+		}
+
+		toCurrency, currencyFound, currencyErr := c.rate(ctx, pivot, currency, date)
+		if currencyErr != nil {
+			return 0, false, currencyErr
+		}
+		toBase, baseFound, baseErr := c.rate(ctx, pivot, c.base, date)
+		if baseErr != nil {
+			return 0, false, baseErr
+		}
+		if currencyFound && baseFound {
+			return toBase / toCurrency, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+func (c *Converter) rate(ctx context.Context, base, quote string, date time.Time) (float64, bool, error) {
+	rate, err := c.storage.GetExchangeRate(ctx, c.userID, base, quote, date)
+	if err != nil {
+		var notFound *domain.NotFoundError
+		if errors.As(err, &notFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	return rate.Rate(), true, nil
+}
@@ -0,0 +1,124 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func TestConverter(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	june := func(d int) time.Time {
+		return time.Date(2024, time.June, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	_, err := s.SaveExchangeRates(ctx, user.ID(), []domain.ExchangeRate{
+		domain.NewExchangeRate(0, june(1), "EUR", "USD", 1.25),
+		domain.NewExchangeRate(0, june(10), "EUR", "USD", 1.6),
+		domain.NewExchangeRate(0, june(1), "EUR", "GBP", 0.8),
+		domain.NewExchangeRate(0, june(1), "CHF", "EUR", 1.05),
+	})
+	if err != nil {
+		t.Fatalf("Failed to save exchange rates: %v", err)
+	}
+
+	if err = s.UpdateBaseCurrency(ctx, user.ID(), "EUR"); err != nil {
+		t.Fatalf("Failed to update base currency: %v", err)
+	}
+
+	converter, err := NewConverter(ctx, s, user.ID())
+	if err != nil {
+		t.Fatalf("NewConverter returned error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		currency  string
+		amount    int64
+		date      time.Time
+		want      int64
+		converted bool
+	}{
+		{name: "base currency", currency: "EUR", amount: -1000, date: june(5), want: -1000, converted: true},
+		{name: "lower case base", currency: "eur", amount: -1000, date: june(5), want: -1000, converted: true},
+		{name: "direct rate", currency: "USD", amount: -1000, date: june(5), want: -800, converted: true},
+		{name: "lower case currency", currency: "usd", amount: -1000, date: june(5), want: -800, converted: true},
+		{name: "rate of the day", currency: "USD", amount: -1000, date: june(10), want: -625, converted: true},
+		{name: "inverse rate", currency: "CHF", amount: 1000, date: june(5), want: 1050, converted: true},
+		{name: "no rate yet", currency: "USD", amount: -1000, date: june(0), want: 0},
+		{name: "unknown currency", currency: "JPY", amount: -1000, date: june(5), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ex := domain.NewExpense(0, "bank", "coffee", tt.currency, tt.amount, tt.date, domain.ChargeType, nil, nil)
+			got, converted, convertErr := converter.Convert(ctx, ex)
+			if convertErr != nil {
+				t.Fatalf("Convert returned error: %v", convertErr)
+			}
+			if got != tt.want || converted != tt.converted {
+				t.Errorf("Convert = %d, %v; want %d, %v", got, converted, tt.want, tt.converted)
+			}
+		})
+	}
+}
+
+func TestConverter_CrossRate(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	date := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+	_, err := s.SaveExchangeRates(ctx, user.ID(), []domain.ExchangeRate{
+		domain.NewExchangeRate(0, date, "EUR", "USD", 1.25),
+		domain.NewExchangeRate(0, date, "EUR", "GBP", 0.8),
+	})
+	if err != nil {
+		t.Fatalf("Failed to save exchange rates: %v", err)
+	}
+
+	if err = s.UpdateBaseCurrency(ctx, user.ID(), "USD"); err != nil {
+		t.Fatalf("Failed to update base currency: %v", err)
+	}
+
+	converter, err := NewConverter(ctx, s, user.ID())
+	if err != nil {
+		t.Fatalf("NewConverter returned error: %v", err)
+	}
+
+	// 1 GBP = 1.25 / 0.8 USD, through EUR
+	ex := domain.NewExpense(0, "bank", "tea", "GBP", -800, date, domain.ChargeType, nil, nil)
+	got, converted, err := converter.Convert(ctx, ex)
+	if err != nil {
+		t.Fatalf("Convert returned error: %v", err)
+	}
+	if !converted || got != -1250 {
+		t.Errorf("Convert = %d, %v; want -1250, true", got, converted)
+	}
+}
+
+func TestConverter_WithoutBaseCurrency(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	converter, err := NewConverter(ctx, s, user.ID())
+	if err != nil {
+		t.Fatalf("NewConverter returned error: %v", err)
+	}
+
+	ex := domain.NewExpense(0, "bank", "coffee", "USD", -1000, time.Now(), domain.ChargeType, nil, nil)
+	got, converted, err := converter.Convert(ctx, ex)
+	if err != nil {
+		t.Fatalf("Convert returned error: %v", err)
+	}
+	if !converted || got != -1000 {
+		t.Errorf("Convert = %d, %v; want the amount as it is", got, converted)
+	}
+}
@@ -0,0 +1,192 @@
+package exchange
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/logger"
+	"github.com/GustavoCaso/expensetrace/storage"
+	"github.com/GustavoCaso/expensetrace/util"
+)
+
+// RatesShown is how many of the most recent rates are listed.
+const RatesShown = 100
+
+// DefaultCSVBase is the base currency of rate files that don't name one,
+// the one of the European Central Bank's reference rates.
+const DefaultCSVBase = "EUR"
+
+const dateLayout = "2006-01-02"
+
+type Service struct {
+	storage storage.Storage
+	logger  *logger.Logger
+}
+
+func New(storage storage.Storage, logger *logger.Logger) *Service {
+	return &Service{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// Rates returns the user's most recent exchange rates.
+func (s *Service) Rates(ctx context.Context, userID int64) ([]domain.ExchangeRate, error) {
+	return s.storage.GetExchangeRates(ctx, userID, RatesShown)
+}
+
+// Add stores a rate entered by hand, replacing the rate of the same pair on
+// the same day.
+func (s *Service) Add(ctx context.Context, userID int64, rate domain.ExchangeRate) (domain.ExchangeRate, error) {
+	rate, err := validate(rate)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.storage.SaveExchangeRates(ctx, userID, []domain.ExchangeRate{rate}); err != nil {
+		s.logger.Error("Failed to save exchange rate", "error", err, "user_id", userID)
+		return nil, err
+	}
+
+	s.logger.Info("Exchange rate saved", "user_id", userID, "base", rate.Base(), "quote", rate.Quote())
+	return rate, nil
+}
+
+// ImportCSV stores the rates of a CSV file with a Date column, formatted as
+// 2006-01-02, and one column per quote currency, like the historical
+// reference rates of the European Central Bank. Each cell is the price of
+// one unit of base in that currency. Empty and N/A cells are skipped.
+func (s *Service) ImportCSV(ctx context.Context, userID int64, base string, file io.Reader) (int64, error) {
+	if strings.TrimSpace(base) == "" {
+		base = DefaultCSVBase
+	}
+	base, ok := util.NormalizeCurrency(base)
+	if !ok {
+		return 0, errors.New("base currency must be a three letter code like EUR")
+	}
+
+	rates, err := parseCSV(base, file)
+	if err != nil {
+		return 0, err
+	}
+	if len(rates) == 0 {
+		return 0, errors.New("the file has no exchange rates")
+	}
+
+	saved, err := s.storage.SaveExchangeRates(ctx, userID, rates)
+	if err != nil {
+		s.logger.Error("Failed to import exchange rates", "error", err, "user_id", userID)
+		return 0, err
+	}
+
+	s.logger.Info("Exchange rates imported", "user_id", userID, "base", base, "count", saved)
+	return saved, nil
+}
+
+// Delete deletes one of the user's exchange rates.
+func (s *Service) Delete(ctx context.Context, userID, id int64) error {
+	deleted, err := s.storage.DeleteExchangeRate(ctx, userID, id)
+	if err != nil {
+		s.logger.Error("Failed to delete exchange rate", "error", err, "user_id", userID)
+		return err
+	}
+	if deleted == 0 {
+		return &domain.NotFoundError{}
+	}
+
+	s.logger.Info("Exchange rate deleted", "user_id", userID, "rate_id", id)
+	return nil
+}
+
+func parseCSV(base string, file io.Reader) ([]domain.ExchangeRate, error) {
+	reader := csv.NewReader(file)
+	// The ECB files end each line with a comma
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header: %w", err)
+	}
+
+	dateColumn := -1
+	quotes := make([]string, len(header))
+	for i, column := range header {
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		if strings.EqualFold(column, "date") {
+			dateColumn = i
+			continue
+		}
+		if code, ok := util.NormalizeCurrency(column); ok && code != base {
+			quotes[i] = code
+		}
+	}
+	if dateColumn == -1 {
+		return nil, errors.New("the file has no Date column")
+	}
+
+	rates := []domain.ExchangeRate{}
+	for line := 2; ; line++ {
+		record, readErr := reader.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("line %d: %w", line, readErr)
+		}
+		if dateColumn >= len(record) {
+			continue
+		}
+
+		date, dateErr := time.Parse(dateLayout, strings.TrimSpace(record[dateColumn]))
+		if dateErr != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", line, record[dateColumn])
+		}
+
+		for i, cell := range record {
+			cell = strings.TrimSpace(cell)
+			if i >= len(quotes) || quotes[i] == "" || cell == "" || strings.EqualFold(cell, "N/A") {
+				continue
+			}
+
+			value, parseErr := strconv.ParseFloat(cell, 64)
+			if parseErr != nil || value <= 0 {
+				return nil, fmt.Errorf("line %d: invalid %s rate %q", line, quotes[i], cell)
+			}
+			rates = append(rates, domain.NewExchangeRate(0, date, base, quotes[i], value))
+		}
+	}
+
+	return rates, nil
+}
+
+// validate normalizes the currencies of the rate and checks it can be used
+// to convert amounts.
+func validate(rate domain.ExchangeRate) (domain.ExchangeRate, error) {
+	base, ok := util.NormalizeCurrency(rate.Base())
+	if !ok {
+		return nil, errors.New("base currency must be a three letter code like EUR")
+	}
+	quote, ok := util.NormalizeCurrency(rate.Quote())
+	if !ok {
+		return nil, errors.New("quote currency must be a three letter code like USD")
+	}
+	if base == quote {
+		return nil, errors.New("base and quote currencies must be different")
+	}
+	if rate.Date().IsZero() {
+		return nil, errors.New("date is required")
+	}
+	if rate.Rate() <= 0 {
+		return nil, errors.New("rate must be greater than zero")
+	}
+
+	return domain.NewExchangeRate(rate.ID(), rate.Date(), base, quote, rate.Rate()), nil
+}
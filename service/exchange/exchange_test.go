@@ -0,0 +1,109 @@
+package exchange
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+// ecbRates is shaped like the historical reference rates of the European
+// Central Bank, newest first and with a trailing comma.
+const ecbRates = `Date,USD,JPY,GBP,CYP,
+2024-06-14,1.0713,168.43,0.84325,N/A,
+2024-06-13,1.0793,169.62,0.84443,N/A,
+`
+
+func TestImportCSV(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger)
+
+	imported, err := svc.ImportCSV(ctx, user.ID(), "", strings.NewReader(ecbRates))
+	if err != nil {
+		t.Fatalf("ImportCSV returned error: %v", err)
+	}
+	if imported != 6 {
+		t.Errorf("Expected 6 imported rates, got %d", imported)
+	}
+
+	rate, err := s.GetExchangeRate(ctx, user.ID(), "EUR", "GBP", time.Date(2024, time.June, 13, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to get exchange rate: %v", err)
+	}
+	if rate.Rate() != 0.84443 {
+		t.Errorf("Expected the GBP rate of June 13th, got %v", rate.Rate())
+	}
+}
+
+func TestImportCSV_Invalid(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	svc := New(s, logger)
+
+	tests := []struct {
+		name string
+		base string
+		file string
+	}{
+		{name: "invalid base", base: "euro", file: ecbRates},
+		{name: "no date column", file: "Day,USD\n2024-06-14,1.07\n"},
+		{name: "invalid date", file: "Date,USD\n14/06/2024,1.07\n"},
+		{name: "invalid rate", file: "Date,USD\n2024-06-14,abc\n"},
+		{name: "no rates", file: "Date,USD\n2024-06-14,N/A\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.ImportCSV(context.Background(), user.ID(), tt.base, strings.NewReader(tt.file))
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger)
+	date := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+
+	if _, err := svc.Add(ctx, user.ID(), domain.NewExchangeRate(0, date, "EUR", "eur", 1)); err == nil {
+		t.Error("Expected an error for a rate between the same currency")
+	}
+	if _, err := svc.Add(ctx, user.ID(), domain.NewExchangeRate(0, date, "EUR", "USD", 0)); err == nil {
+		t.Error("Expected an error for a zero rate")
+	}
+
+	rate, err := svc.Add(ctx, user.ID(), domain.NewExchangeRate(0, date, " eur", "usd", 1.07))
+	if err != nil {
+		t.Fatalf("Add returned error: %v", err)
+	}
+	if rate.Base() != "EUR" || rate.Quote() != "USD" {
+		t.Errorf("Expected normalized currencies, got %s/%s", rate.Base(), rate.Quote())
+	}
+
+	rates, err := svc.Rates(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Rates returned error: %v", err)
+	}
+	if len(rates) != 1 {
+		t.Fatalf("Expected 1 rate, got %d", len(rates))
+	}
+
+	if err = svc.Delete(ctx, user.ID(), rates[0].ID()); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if err = svc.Delete(ctx, user.ID(), rates[0].ID()); err == nil {
+		t.Error("Expected an error deleting a missing rate")
+	}
+}
@@ -9,18 +9,21 @@ import (
 
 	"github.com/GustavoCaso/expensetrace/domain"
 	"github.com/GustavoCaso/expensetrace/logger"
+	"github.com/GustavoCaso/expensetrace/service/webhook"
 	"github.com/GustavoCaso/expensetrace/storage"
 )
 
 type Service struct {
-	storage storage.Storage
-	logger  *logger.Logger
+	storage  storage.Storage
+	logger   *logger.Logger
+	webhooks *webhook.Service
 }
 
 func New(storage storage.Storage, logger *logger.Logger) *Service {
 	return &Service{
-		storage: storage,
-		logger:  logger,
+		storage:  storage,
+		logger:   logger,
+		webhooks: webhook.New(storage, logger),
 	}
 }
 
@@ -49,6 +52,16 @@ func (s *Service) GroupByYearAndMonth(
 	groupedExpenses := domain.ExpensesByYear{}
 	years := []int{}
 
+	accounts, err := s.storage.GetAccounts(ctx, userID)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("error GetAccounts %s", err.Error()))
+		return groupedExpenses, years, err
+	}
+	accountsByID := make(map[int64]domain.Account, len(accounts))
+	for _, account := range accounts {
+		accountsByID[account.ID()] = account
+	}
+
 	for _, exp := range expenses {
 		var category domain.Category
 
@@ -63,6 +76,11 @@ func (s *Service) GroupByYearAndMonth(
 			category = c
 		}
 
+		var account domain.Account
+		if exp.AccountID() != nil {
+			account = accountsByID[*exp.AccountID()]
+		}
+
 		expenseYear := exp.Date().Year()
 		expenseMonth := exp.Date().Month().String()
 
@@ -74,12 +92,14 @@ func (s *Service) GroupByYearAndMonth(
 				expenseview := &domain.ExpenseView{
 					Expense: exp,
 					Cat:     category,
+					Acct:    account,
 				}
 				month = append(month, expenseview)
 			} else {
 				expenseview := &domain.ExpenseView{
 					Expense: exp,
 					Cat:     category,
+					Acct:    account,
 				}
 				month = []*domain.ExpenseView{expenseview}
 			}
@@ -91,6 +111,7 @@ func (s *Service) GroupByYearAndMonth(
 			expenseview := &domain.ExpenseView{
 				Expense: exp,
 				Cat:     category,
+				Acct:    account,
 			}
 			newYear[expenseMonth] = []*domain.ExpenseView{expenseview}
 			groupedExpenses[expenseYear] = newYear
@@ -124,26 +145,34 @@ func (s *Service) Get(ctx context.Context, userID, id int64) (*domain.ExpenseVie
 		category = cat
 	}
 
+	var account domain.Account
+	if exp.AccountID() != nil {
+		acct, accountErr := s.storage.GetAccount(ctx, userID, *exp.AccountID())
+		if accountErr != nil {
+			s.logger.Error(fmt.Sprintf("error GetAccount %s", accountErr.Error()))
+			return nil, accountErr
+		}
+		account = acct
+	}
+
 	return &domain.ExpenseView{
 		Expense: exp,
 		Cat:     category,
+		Acct:    account,
 	}, nil
 }
 
-// Create inserts a new expense.
+// Create inserts a new expense, returning it with its ID.
 func (s *Service) Create(ctx context.Context, userID int64, e domain.Expense) (domain.Expense, error) {
-	created, err := s.storage.InsertExpenses(ctx, userID, []domain.Expense{e})
+	created, err := s.storage.CreateExpense(ctx, userID, e)
 	if err != nil {
-		s.logger.Error(fmt.Sprintf("error InsertExpenses %s", err.Error()))
+		s.logger.Error(fmt.Sprintf("error CreateExpense %s", err.Error()))
 		return nil, err
 	}
 
-	if created != 1 {
-		s.logger.Error("error InsertExpenses expense not created")
-		return nil, errors.New("expense not created")
-	}
+	s.webhooks.Publish(ctx, userID, domain.EventExpenseCreated, created)
 
-	return e, nil
+	return created, nil
 }
 
 // Update updates an expense's fields.
@@ -153,16 +182,24 @@ func (s *Service) Update(ctx context.Context, userID int64, e domain.Expense) (i
 		s.logger.Error(fmt.Sprintf("error UpdateExpense %s", err.Error()))
 		return 0, err
 	}
+
+	if updated > 0 {
+		s.webhooks.Publish(ctx, userID, domain.EventExpenseUpdated, e)
+	}
 	return updated, nil
 }
 
 // Delete deletes an expense.
 func (s *Service) Delete(ctx context.Context, userID, id int64) error {
-	_, err := s.storage.DeleteExpense(ctx, userID, id)
+	deleted, err := s.storage.DeleteExpense(ctx, userID, id)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("error DeleteExpense %s", err.Error()))
 		return err
 	}
+
+	if deleted > 0 {
+		s.webhooks.Publish(ctx, userID, domain.EventExpenseDeleted, webhook.ExpenseDeleted{ID: id})
+	}
 	return nil
 }
 
@@ -181,3 +218,19 @@ func (s *Service) Export(ctx context.Context, userID int64, w io.Writer) error {
 
 	return nil
 }
+
+// ExportJSON writes all of the user's expenses as a JSON array to w.
+func (s *Service) ExportJSON(ctx context.Context, userID int64, w io.Writer) error {
+	expenses, err := s.storage.GetAllExpenseTypes(ctx, userID)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("error GetAllExpenseTypes %s", err.Error()))
+		return err
+	}
+
+	if exportErr := jsonExport(ctx, userID, w, expenses, s.storage); exportErr != nil {
+		s.logger.Error(fmt.Sprintf("error export.JSON %s", exportErr.Error()))
+		return exportErr
+	}
+
+	return nil
+}
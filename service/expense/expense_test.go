@@ -17,8 +17,8 @@ func TestList_FiltersByUser(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "coffee", "USD", -500, now, domain.ChargeType, nil),
-		domain.NewExpense(0, "Test Source", "lunch", "USD", -1200, now, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "coffee", "USD", -500, now, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Test Source", "lunch", "USD", -1200, now, domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -49,8 +49,18 @@ func TestGroupByYearAndMonth_GroupsCorrectly(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, now, domain.ChargeType, &categoryID),
-		domain.NewExpense(0, "Test Source", "Uber ride", "USD", -50000, now, domain.ChargeType, nil),
+		domain.NewExpense(
+			0,
+			"Test Source",
+			"Restaurant bill",
+			"USD",
+			-123456,
+			now,
+			domain.ChargeType,
+			&categoryID,
+			nil,
+		),
+		domain.NewExpense(0, "Test Source", "Uber ride", "USD", -50000, now, domain.ChargeType, nil, nil),
 	}
 
 	svc := New(s, logger)
@@ -101,7 +111,17 @@ func TestCreate_InsertsExpense(t *testing.T) {
 
 	svc := New(s, logger)
 
-	newExpense := domain.NewExpense(0, "Test Source", "New expense", "USD", -1000, time.Now(), domain.ChargeType, nil)
+	newExpense := domain.NewExpense(
+		0,
+		"Test Source",
+		"New expense",
+		"USD",
+		-1000,
+		time.Now(),
+		domain.ChargeType,
+		nil,
+		nil,
+	)
 
 	created, err := svc.Create(context.Background(), user.ID(), newExpense)
 	if err != nil {
@@ -128,7 +148,17 @@ func TestUpdate_UpdatesFields(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Original Source", "Original description", "EUR", -100000, now, domain.ChargeType, nil),
+		domain.NewExpense(
+			0,
+			"Original Source",
+			"Original description",
+			"EUR",
+			-100000,
+			now,
+			domain.ChargeType,
+			nil,
+			nil,
+		),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -147,6 +177,7 @@ func TestUpdate_UpdatesFields(t *testing.T) {
 		now,
 		domain.IncomeType,
 		nil,
+		nil,
 	)
 
 	updated, err := svc.Update(context.Background(), user.ID(), updatedExpense)
@@ -180,7 +211,7 @@ func TestDelete_RemovesExpense(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Test expense", "USD", -1000, now, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "Test expense", "USD", -1000, now, domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -207,7 +238,7 @@ func TestExport_WritesCSV(t *testing.T) {
 
 	now := time.Now()
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Test expense for export", "USD", -1000, now, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "Test expense for export", "USD", -1000, now, domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -227,3 +258,44 @@ func TestExport_WritesCSV(t *testing.T) {
 		t.Fatalf("Expected CSV output to contain expense description, got: %s", buf.String())
 	}
 }
+
+func TestCreate_PublishesWebhookEvent(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	_, err := s.CreateWebhook(
+		ctx, user.ID(), "https://example.com/hooks", "secret", []string{domain.EventExpenseCreated},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	svc := New(s, logger)
+
+	newExpense := domain.NewExpense(
+		0,
+		"Test Source",
+		"New expense",
+		"USD",
+		-1000,
+		time.Now(),
+		domain.ChargeType,
+		nil,
+		nil,
+	)
+	if _, err = svc.Create(ctx, user.ID(), newExpense); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	deliveries, err := s.GetWebhookDeliveries(ctx, user.ID(), 10)
+	if err != nil {
+		t.Fatalf("Failed to get webhook deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Event() != domain.EventExpenseCreated {
+		t.Fatalf("Expected an expense.created delivery, got %v", deliveries)
+	}
+	if !strings.Contains(string(deliveries[0].Payload()), `"description":"New expense"`) {
+		t.Errorf("Expected the expense in the payload, got %s", deliveries[0].Payload())
+	}
+}
@@ -3,6 +3,7 @@ package expense
 import (
 	"context"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
@@ -49,6 +50,51 @@ func csvExport(
 	return nil
 }
 
+// exportedExpense is an expense as written by jsonExport, with the same
+// fields as the CSV export.
+type exportedExpense struct {
+	ID          int64   `json:"id"`
+	Source      string  `json:"source"`
+	Date        string  `json:"date"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+	Type        string  `json:"type"`
+	Currency    string  `json:"currency"`
+	Category    string  `json:"category"`
+}
+
+// jsonExport exports expenses as a JSON array.
+func jsonExport(
+	ctx context.Context,
+	userID int64,
+	writer io.Writer,
+	expenses []domain.Expense,
+	storage storageType.Storage,
+) error {
+	exported := make([]exportedExpense, 0, len(expenses))
+	for _, expense := range expenses {
+		record := expenseToCSVRecord(ctx, userID, expense, storage)
+		exported = append(exported, exportedExpense{
+			ID:          expense.ID(),
+			Source:      record[1],
+			Date:        record[2],
+			Description: record[3],
+			Amount:      float64(expense.Amount()) / centsToDecimal,
+			Type:        record[5],
+			Currency:    record[6],
+			Category:    record[7],
+		})
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(exported); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+
+	return nil
+}
+
 func expenseToCSVRecord(
 	ctx context.Context,
 	userID int64,
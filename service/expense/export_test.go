@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
+	"encoding/json"
 	"strings"
 	"testing"
 	"time"
@@ -40,9 +41,10 @@ func TestCSV(t *testing.T) {
 			testDate,
 			domain.ChargeType,
 			&categoryID1,
+			nil,
 		),
-		domain.NewExpense(0, "TestSource", "uber ride", "EUR", -3000, testDate, domain.ChargeType, &categoryID2),
-		domain.NewExpense(0, "TestSource", "salary", "USD", 500000, testDate, domain.IncomeType, nil),
+		domain.NewExpense(0, "TestSource", "uber ride", "EUR", -3000, testDate, domain.ChargeType, &categoryID2, nil),
+		domain.NewExpense(0, "TestSource", "salary", "USD", 500000, testDate, domain.IncomeType, nil, nil),
 	}
 
 	_, err = s.InsertExpenses(ctx, user.ID(), expenses)
@@ -214,6 +216,7 @@ func TestExpenseToCSVRecord(t *testing.T) {
 				time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
 				domain.ChargeType,
 				&categoryID,
+				nil,
 			),
 			validateFunc: func(t *testing.T, row []string) {
 				if row[0] != "123" {
@@ -253,6 +256,7 @@ func TestExpenseToCSVRecord(t *testing.T) {
 				time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
 				domain.IncomeType,
 				nil,
+				nil,
 			),
 			validateFunc: func(t *testing.T, row []string) {
 				if row[0] != "456" {
@@ -311,6 +315,7 @@ func TestCSVRoundTrip(t *testing.T) {
 			testDate,
 			domain.ChargeType,
 			&categoryID,
+			nil,
 		),
 	}
 
@@ -368,3 +373,62 @@ func TestCSVRoundTrip(t *testing.T) {
 		t.Errorf("Category mismatch: got %v, want Groceries", dataRow[7])
 	}
 }
+
+func TestJSON(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	categoryID, err := s.CreateCategory(ctx, user.ID(), "Food", "restaurant|food", 0)
+	if err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+
+	testDate := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	expenses := []domain.Expense{
+		domain.NewExpense(
+			0,
+			"TestSource",
+			"restaurant bill",
+			"USD",
+			-5025,
+			testDate,
+			domain.ChargeType,
+			&categoryID,
+			nil,
+		),
+		domain.NewExpense(0, "TestSource", "salary", "USD", 500000, testDate, domain.IncomeType, nil, nil),
+	}
+	if _, err = s.InsertExpenses(ctx, user.ID(), expenses); err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err = New(s, logger).ExportJSON(ctx, user.ID(), &buf); err != nil {
+		t.Fatalf("ExportJSON returned error: %v", err)
+	}
+
+	var exported []exportedExpense
+	if err = json.Unmarshal(buf.Bytes(), &exported); err != nil {
+		t.Fatalf("Failed to decode JSON export: %v", err)
+	}
+
+	if len(exported) != 2 {
+		t.Fatalf("Expected 2 exported expenses, got %d", len(exported))
+	}
+
+	for _, e := range exported {
+		switch e.Description {
+		case "restaurant bill":
+			if e.Amount != -50.25 || e.Type != "charge" || e.Category != "Food" || e.Date != "2024-01-15" {
+				t.Errorf("Unexpected exported expense %+v", e)
+			}
+		case "salary":
+			if e.Amount != 5000 || e.Type != "income" || e.Category != "" {
+				t.Errorf("Unexpected exported expense %+v", e)
+			}
+		default:
+			t.Errorf("Unexpected exported expense %+v", e)
+		}
+	}
+}
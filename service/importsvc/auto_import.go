@@ -0,0 +1,165 @@
+package importsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/matcher"
+)
+
+// errNoMatchingMapping is returned when a file needing a field mapping is
+// imported unattended and none of the user's saved mappings fits it.
+var errNoMatchingMapping = errors.New(
+	"no saved mapping matches the columns of this file, import it once from the web page and save its mapping",
+)
+
+// AutoImport imports a file without any user interaction. Files from
+// recognized providers and statement formats are imported as ImportFile
+// does; any other file is read with the saved mapping made for its header
+// row, skipping the rows that exactly duplicate stored expenses. Files no
+// saved mapping fits are not imported.
+func (s *Service) AutoImport(
+	ctx context.Context,
+	userID int64,
+	filename string,
+	r io.Reader,
+	m *matcher.Matcher,
+) (ExecuteResult, error) {
+	info, needsPreview, previewReader, err := s.ImportFile(ctx, userID, nil, filename, r, m)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+
+	if !needsPreview {
+		if info.Error != nil {
+			return ExecuteResult{}, info.Error
+		}
+		return ExecuteResult{
+			Imported:           int64(info.TotalImports),
+			WithoutCategory:    info.ImportWithoutCategory,
+			ErrorRows:          info.InvalidRows,
+			ExactDuplicates:    info.ExactDuplicates,
+			LikelyDuplicates:   info.LikelyDuplicates,
+			SkippedDuplicates:  info.SkippedDuplicates,
+			ImportBatchID:      info.ImportBatchID,
+			RejectedStatements: info.RejectedStatements,
+			RejectedReason:     info.RejectedReason,
+		}, nil
+	}
+
+	preview, err := s.Preview(ctx, userID, filename, previewReader)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+
+	profile, found, err := s.MatchMappingProfile(ctx, userID, preview.Headers)
+	if err != nil || !found {
+		s.discardSession(ctx, userID, preview.SessionID)
+		if err == nil {
+			err = errNoMatchingMapping
+		}
+		return ExecuteResult{}, err
+	}
+
+	return s.importWithProfile(ctx, userID, preview.SessionID, profile, m)
+}
+
+// ImportWithMappingProfile imports a file without any user interaction,
+// reading it with the user's saved mapping of the given name whatever its
+// format. Rows exactly duplicating stored expenses are skipped.
+func (s *Service) ImportWithMappingProfile(
+	ctx context.Context,
+	userID int64,
+	filename string,
+	r io.Reader,
+	profileName string,
+	m *matcher.Matcher,
+) (ExecuteResult, error) {
+	profiles, err := s.MappingProfiles(ctx, userID)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+
+	idx := slices.IndexFunc(profiles, func(p domain.MappingProfile) bool {
+		return p.Name() == profileName
+	})
+	if idx < 0 {
+		return ExecuteResult{}, fmt.Errorf("no saved mapping named %q", profileName)
+	}
+
+	preview, err := s.Preview(ctx, userID, filename, r)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+
+	return s.importWithProfile(ctx, userID, preview.SessionID, profiles[idx], m)
+}
+
+func (s *Service) importWithProfile(
+	ctx context.Context,
+	userID int64,
+	sessionID string,
+	profile domain.MappingProfile,
+	m *matcher.Matcher,
+) (ExecuteResult, error) {
+	s.logger.Info(
+		"Importing file with saved mapping",
+		"import_session_id", sessionID,
+		"mapping_profile_id", profile.ID(),
+	)
+
+	application, err := s.ApplyMappingProfile(ctx, userID, sessionID, profile.ID(), m)
+	if err != nil {
+		s.discardSession(ctx, userID, sessionID)
+		return ExecuteResult{}, err
+	}
+
+	// Like files imported directly, exact duplicates are skipped and likely
+	// ones imported
+	likely := []int{}
+	for _, duplicate := range application.Duplicates {
+		if duplicate.Status == domain.DuplicateLikely {
+			likely = append(likely, duplicate.Row)
+		}
+	}
+
+	result, err := s.Execute(ctx, userID, sessionID, likely, m)
+	if err != nil {
+		s.discardSession(ctx, userID, sessionID)
+		return ExecuteResult{}, err
+	}
+
+	return result, nil
+}
+
+// discardSession removes the session of a failed unattended import. Execute
+// removes the session once imported, failed imports leave nothing behind
+// either.
+func (s *Service) discardSession(ctx context.Context, userID int64, sessionID string) {
+	if err := s.sessionStore.Delete(ctx, userID, sessionID); err != nil {
+		s.logger.Warn("Failed to delete import session", "import_session_id", sessionID, "error", err)
+	}
+}
+
+// InboxFailures lists the files from the user's import inbox that could not
+// be imported and have not been dismissed yet.
+func (s *Service) InboxFailures(ctx context.Context, userID int64) ([]domain.InboxFailure, error) {
+	return s.storage.GetInboxFailures(ctx, userID)
+}
+
+// DismissInboxFailures clears the user's inbox failures once reviewed.
+func (s *Service) DismissInboxFailures(ctx context.Context, userID int64) error {
+	_, err := s.storage.DeleteInboxFailures(ctx, userID)
+	return err
+}
+
+// RecordInboxFailure keeps the reason a file from the user's import inbox
+// was not imported, to be shown until dismissed.
+func (s *Service) RecordInboxFailure(ctx context.Context, userID int64, filename string, reason error) error {
+	_, err := s.storage.CreateInboxFailure(ctx, userID, filename, reason.Error())
+	return err
+}
@@ -3,8 +3,9 @@
 package importsvc
 
 import (
-	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -15,6 +16,7 @@ import (
 	importUtil "github.com/GustavoCaso/expensetrace/import"
 	"github.com/GustavoCaso/expensetrace/logger"
 	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/service/webhook"
 	"github.com/GustavoCaso/expensetrace/storage"
 )
 
@@ -32,27 +34,57 @@ type Service struct {
 	storage      storage.Storage
 	logger       *logger.Logger
 	sessionStore *importUtil.SessionStore
+	webhooks     *webhook.Service
 }
 
 func New(storage storage.Storage, logger *logger.Logger, sessionTTL time.Duration) *Service {
 	return &Service{
 		storage:      storage,
 		logger:       logger,
-		sessionStore: importUtil.NewSessionStore(sessionTTL),
+		sessionStore: importUtil.NewSessionStore(storage, sessionTTL),
+		webhooks:     webhook.New(storage, logger),
 	}
 }
 
 // ImportFile dispatches an uploaded file for import based on its extension.
 //
-// For CSV files from a recognized provider, and for JSON files matching the
-// supported schema, the import is performed immediately and info is
-// returned with needsPreview=false.
+// For CSV files from a recognized provider, for JSON files matching the
+// supported schema, and for OFX/QFX, camt.053/camt.052 and MT940 statements,
+// the import is performed immediately and info is returned with needsPreview=false.
+// XLSX and ODS spreadsheets always need a preview.
 //
 // Otherwise, needsPreview is true and previewReader holds the (possibly
 // rewound) file contents ready to be passed to Preview.
+//
+// Files imported immediately are stored in the given account, or in none
+// when accountID is nil. Files needing a preview take the account of their
+// mapping instead.
 func (s *Service) ImportFile(
 	ctx context.Context,
 	userID int64,
+	accountID *int64,
+	filename string,
+	r io.Reader,
+	m *matcher.Matcher,
+) (importUtil.ImportInfo, bool, io.Reader, error) {
+	if accountID != nil {
+		if _, err := s.storage.GetAccount(ctx, userID, *accountID); err != nil {
+			return importUtil.ImportInfo{}, false, nil, fmt.Errorf("unknown account %d: %w", *accountID, err)
+		}
+	}
+
+	info, needsPreview, previewReader, err := s.importFile(ctx, userID, accountID, filename, r, m)
+	if err == nil && !needsPreview {
+		s.importCompleted(ctx, userID, filename, info)
+	}
+
+	return info, needsPreview, previewReader, err
+}
+
+func (s *Service) importFile(
+	ctx context.Context,
+	userID int64,
+	accountID *int64,
 	filename string,
 	r io.Reader,
 	m *matcher.Matcher,
@@ -62,72 +94,204 @@ func (s *Service) ImportFile(
 	switch fileExtension {
 	case ".csv":
 	case ".json":
+	case ".ofx", ".qfx":
+	case ".xml":
+	case ".sta", ".mt940", ".940":
+	case ".xlsx", ".ods":
+		// Spreadsheets are always mapped interactively
+		return importUtil.ImportInfo{}, true, r, nil
 	default:
 		//nolint:staticcheck // preserves original user-facing message text
 		return importUtil.ImportInfo{}, false, nil, fmt.Errorf("Error: unsupported file extesion: %s", fileExtension)
 	}
 
-	// Copy the file data to my buffer
-	var buf bytes.Buffer
-	_, err := io.Copy(&buf, r)
+	// Keep the file on disk, large exports are read from there
+	file, err := spoolUpload(r)
 	if err != nil {
 		//nolint:staticcheck // preserves original user-facing message text
 		return importUtil.ImportInfo{}, false, nil, fmt.Errorf("Error copying bytes: %w", err)
 	}
 
-	sizeKB := fmt.Sprintf("%dKB", buf.Len()/bytesPerKB)
+	sizeKB := fmt.Sprintf("%dKB", file.size()/bytesPerKB)
 	s.logger.Info("File uploaded for import", "filename", filename, "size", sizeKB)
 
-	if fileExtension == ".csv" {
-		if !importUtil.SupportedProvider(filename) {
-			// Start interactive flow
-			return importUtil.ImportInfo{}, true, &buf, nil
+	needsPreview := false
+	defer func() {
+		// Files needing a preview are handed over to Preview
+		if !needsPreview {
+			file.Close()
 		}
+	}()
 
-		info := importUtil.ImportCSV(ctx, userID, filename, &buf, s.storage, m)
+	if fileExtension == ".ofx" || fileExtension == ".qfx" {
+		// OFX statements are self-describing, no mapping step is needed
+		info := importUtil.ImportOFX(ctx, userID, accountID, filename, file, s.storage, m)
 		return info, false, nil, nil
 	}
 
-	// .json
-	reader := bytes.NewReader(buf.Bytes())
-	valid, jsonExpenses := importUtil.SupportedJSONSchema(reader)
+	if fileExtension == ".xml" {
+		// camt statements are self-describing, no mapping step is needed
+		info := importUtil.ImportCamt(ctx, userID, accountID, filename, file, s.storage, m)
+		return info, false, nil, nil
+	}
 
-	if !valid {
-		// Rewind reader
-		_, seekErr := reader.Seek(0, io.SeekStart)
-		if seekErr != nil {
-			//nolint:staticcheck // preserves original user-facing message text
-			return importUtil.ImportInfo{}, false, nil, fmt.Errorf("Error occurred when reading the file: %w", seekErr)
-		}
+	if fileExtension == ".sta" || fileExtension == ".mt940" || fileExtension == ".940" {
+		// MT940 statements are self-describing, no mapping step is needed
+		info := importUtil.ImportMT940(ctx, userID, accountID, filename, file, s.storage, m)
+		return info, false, nil, nil
+	}
+
+	var supported bool
+	if fileExtension == ".csv" {
+		// Providers can be recognized by their header row, a malformed file
+		// simply falls through to the interactive flow
+		header, _ := csv.NewReader(file).Read()
+		supported = importUtil.SupportedProvider(filename, header)
+	} else {
+		// .json
+		supported = importUtil.SupportedJSONSchema(file)
+	}
+
+	// Rewind reader
+	if err = file.rewind(); err != nil {
+		//nolint:staticcheck // preserves original user-facing message text
+		return importUtil.ImportInfo{}, false, nil, fmt.Errorf("Error occurred when reading the file: %w", err)
+	}
+
+	if !supported {
 		// Start interactive flow
-		return importUtil.ImportInfo{}, true, reader, nil
+		needsPreview = true
+		return importUtil.ImportInfo{}, true, file, nil
 	}
 
-	info := importUtil.ImportJSON(ctx, userID, jsonExpenses, s.storage, m)
+	if fileExtension == ".csv" {
+		info := importUtil.ImportCSV(ctx, userID, accountID, filename, file, s.storage, m)
+		return info, false, nil, nil
+	}
+
+	info := importUtil.ImportJSON(ctx, userID, accountID, filename, file, s.storage, m)
 	return info, false, nil, nil
 }
 
-// Preview parses a file and creates an import session, returning enough
-// information to render a preview of its contents.
-func (s *Service) Preview(filename string, r io.Reader) (
-	[]string,
-	[][]string,
-	int,
-	string,
-	error,
-) {
-	parsedData, err := importUtil.ParseFile(filename, r)
+// FilePreview contains everything a caller needs to render the preview of
+// an uploaded file.
+type FilePreview struct {
+	SessionID   string
+	Filename    string
+	Headers     []string
+	PreviewRows [][]string
+	TotalRows   int
+	// Sheets lists the sheets of a spreadsheet, Sheet being the one shown.
+	Sheets []string
+	Sheet  string
+	// HeaderRow is the number of rows skipped before the header row.
+	HeaderRow int
+	// Delimiter and Encoding are the detected or chosen CSV dialect.
+	Delimiter string
+	Encoding  string
+	// RecordsPath selects the records of a JSON file.
+	RecordsPath string
+}
+
+// Preview parses a file and creates an import session for the user,
+// returning enough information to render a preview of its contents. The
+// session stores a copy of the file and a sample of its rows.
+func (s *Service) Preview(ctx context.Context, userID int64, filename string, r io.Reader) (FilePreview, error) {
+	file, err := spoolUpload(r)
+	if err != nil {
+		//nolint:staticcheck // preserves original user-facing message text
+		return FilePreview{}, fmt.Errorf("Error reading the file: %w", err)
+	}
+	defer file.Close()
+
+	parsedData, err := importUtil.ParseFile(filename, file)
+	if err != nil {
+		return FilePreview{}, err
+	}
+
+	if err = file.rewind(); err != nil {
+		//nolint:staticcheck // preserves original user-facing message text
+		return FilePreview{}, fmt.Errorf("Error reading the file: %w", err)
+	}
+
+	sessionID, err := s.sessionStore.Create(ctx, userID, filename, file, parsedData)
+	if err != nil {
+		return FilePreview{}, fmt.Errorf("error creating import session: %w", err)
+	}
+
+	return filePreview(sessionID, filename, parsedData), nil
+}
+
+// Reparse reads the file of an import session again, choosing another sheet,
+// header row, CSV dialect or JSON records path, and returns the new preview.
+// Any mapping applied is discarded.
+func (s *Service) Reparse(
+	ctx context.Context,
+	userID int64,
+	sessionID string,
+	options importUtil.ParseOptions,
+) (FilePreview, error) {
+	session, err := s.session(ctx, userID, sessionID)
 	if err != nil {
-		return nil, nil, 0, "", err
+		return FilePreview{}, err
 	}
 
-	sessionID := s.sessionStore.Create(filename, parsedData)
+	if err = s.reparse(ctx, session, options); err != nil {
+		return FilePreview{}, err
+	}
 
-	headers := parsedData.Headers
-	previewRows := parsedData.GetPreviewRows(previewExpenseCount)
-	totalRows := parsedData.GetTotalRows()
+	return filePreview(sessionID, session.Filename, session.Data), nil
+}
 
-	return headers, previewRows, totalRows, sessionID, nil
+// reparse reads the file of the session again with options, replacing its
+// parsed data.
+func (s *Service) reparse(
+	ctx context.Context,
+	session *importUtil.ImportSession,
+	options importUtil.ParseOptions,
+) error {
+	file, err := s.sessionStore.Open(ctx, session.UserID, session.ID)
+	if err != nil {
+		return s.sessionError(err)
+	}
+	defer file.Close()
+
+	parsedData, err := importUtil.ParseFileWithOptions(session.Filename, file, options)
+	if err != nil {
+		return err
+	}
+
+	if err = s.sessionStore.Replace(ctx, session, parsedData); err != nil {
+		return s.sessionError(err)
+	}
+
+	s.logger.Info(
+		"Import file read again",
+		"import_session_id", session.ID,
+		"sheet", parsedData.Sheet,
+		"header_row", parsedData.HeaderRow,
+		"delimiter", parsedData.Delimiter,
+		"encoding", parsedData.Encoding,
+		"records_path", parsedData.RecordsPath,
+	)
+
+	return nil
+}
+
+func filePreview(sessionID, filename string, data *importUtil.ParsedData) FilePreview {
+	return FilePreview{
+		SessionID:   sessionID,
+		Filename:    filename,
+		Headers:     data.Headers,
+		PreviewRows: data.GetPreviewRows(previewExpenseCount),
+		TotalRows:   data.GetTotalRows(),
+		Sheets:      data.Sheets,
+		Sheet:       data.Sheet,
+		HeaderRow:   data.HeaderRow,
+		Delimiter:   data.Delimiter,
+		Encoding:    data.Encoding,
+		RecordsPath: data.RecordsPath,
+	}
 }
 
 // MappingApplication contains everything a caller needs to render the
@@ -137,28 +301,58 @@ type MappingApplication struct {
 	PreviewExpenses []domain.Expense
 	TotalRows       int
 	Errors          []string
+	// DateLayout is the label of the date format applied.
+	DateLayout  string
+	Warnings    []string
+	Ambiguities []domain.FormatAmbiguity
+	// NewRows counts the rows not matching any stored expense.
+	NewRows int
+	// Duplicates lists the rows matching a stored expense, numbered like
+	// mapping errors.
+	Duplicates []domain.DuplicateRow
 }
 
 // ApplyMapping validates that the given import session exists, applies the
 // field mapping to its parsed data, stores the mapping on the session, and
-// returns the data needed to render a mapping preview.
+// returns the data needed to render a mapping preview, including the rows
+// that duplicate the user's stored expenses.
 func (s *Service) ApplyMapping(
+	ctx context.Context,
+	userID int64,
 	sessionID string,
 	mapping *importUtil.FieldMapping,
 	m *matcher.Matcher,
 ) (MappingApplication, error) {
-	session, exists := s.sessionStore.Get(sessionID)
-	if !exists {
-		return MappingApplication{}, errSessionNotFound
+	session, err := s.session(ctx, userID, sessionID)
+	if err != nil {
+		return MappingApplication{}, err
 	}
 
+	// Saved mappings read the file the way the file they were made for was
+	if options, found := mapping.ParseOptions(); found && options != session.Data.Options() {
+		if err = s.reparse(ctx, session, options); err != nil {
+			return MappingApplication{}, err
+		}
+	}
+	mapping.SetParseOptions(session.Data.Options())
+
 	result, err := importUtil.ApplyMapping(session.Data, mapping, m)
 	if err != nil {
 		//nolint:staticcheck // preserves original user-facing message text
 		return MappingApplication{}, fmt.Errorf("Error applying mapping: %w", err)
 	}
 
-	s.sessionStore.Update(sessionID, mapping)
+	// The sample settles the date format, every row of the file is then
+	// checked so all the rows Execute would leave out are reported
+	classification, err := s.classifyRows(ctx, session, mapping, result.DateLayout, m)
+	if err != nil {
+		return MappingApplication{}, err
+	}
+	counts := classification.Counts
+
+	if err = s.sessionStore.Update(ctx, session, mapping); err != nil {
+		return MappingApplication{}, s.sessionError(err)
+	}
 
 	previewCount := min(previewExpenseCount, len(result.Expenses))
 
@@ -167,18 +361,36 @@ func (s *Service) ApplyMapping(
 		previewExpenses[i] = result.Expenses[i]
 	}
 
-	errorMessages := make([]string, 0, len(result.Errors))
-	for _, mappingErr := range result.Errors {
+	errorMessages := make([]string, 0, len(classification.Errors))
+	for _, mappingErr := range classification.Errors {
 		rowNum := mappingErr.RowIndex + headerRowOffset
 		errorMsg := fmt.Sprintf("Row %d: %s", rowNum, mappingErr.Error.Error())
 		errorMessages = append(errorMessages, errorMsg)
 	}
 
+	// Report rows the same way as mapping errors
+	ambiguities := make([]domain.FormatAmbiguity, 0, len(result.Ambiguities))
+	for _, ambiguity := range result.Ambiguities {
+		rows := make([]domain.AmbiguousRow, 0, len(ambiguity.Rows))
+		for _, row := range ambiguity.Rows {
+			row.Row += headerRowOffset
+			rows = append(rows, row)
+		}
+		ambiguity.Rows = rows
+		ambiguities = append(ambiguities, ambiguity)
+	}
+
+	var dateLayout string
+	if result.DateLayout != "" {
+		dateLayout = importUtil.DateLayoutLabel(result.DateLayout)
+	}
+
 	s.logger.Info(
 		"Field mapping applied",
 		"import_session_id", sessionID,
-		"valid_rows", len(result.Expenses),
-		"error_rows", len(result.Errors),
+		"valid_rows", counts.New+counts.Exact+counts.Likely,
+		"error_rows", len(classification.Errors),
+		"duplicate_rows", counts.Exact+counts.Likely,
 	)
 
 	return MappingApplication{
@@ -186,67 +398,250 @@ func (s *Service) ApplyMapping(
 		PreviewExpenses: previewExpenses,
 		TotalRows:       session.Data.GetTotalRows(),
 		Errors:          errorMessages,
+		DateLayout:      dateLayout,
+		Warnings:        result.Warnings,
+		Ambiguities:     ambiguities,
+		NewRows:         counts.New,
+		Duplicates:      duplicateRows(classification.Duplicates),
 	}, nil
 }
 
+// classifyRows reads every row of the session's file with the mapping and
+// the date layout settled on the sample.
+func (s *Service) classifyRows(
+	ctx context.Context,
+	session *importUtil.ImportSession,
+	mapping *importUtil.FieldMapping,
+	dateLayout string,
+	m *matcher.Matcher,
+) (*importUtil.RowClassification, error) {
+	file, err := s.sessionStore.Open(ctx, session.UserID, session.ID)
+	if err != nil {
+		return nil, s.sessionError(err)
+	}
+	defer file.Close()
+
+	_, rows, err := importUtil.OpenFile(session.Filename, file, session.Data.Options())
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := *mapping
+	resolved.DateLayout = dateLayout
+	return importUtil.ClassifyRows(ctx, session.UserID, s.storage, rows, &resolved, m)
+}
+
+// duplicateRows lists the rows flagged as duplicates, numbered like mapping
+// errors.
+func duplicateRows(flagged []importUtil.FlaggedRow) []domain.DuplicateRow {
+	rows := make([]domain.DuplicateRow, 0, len(flagged))
+	for _, f := range flagged {
+		rows = append(rows, domain.DuplicateRow{
+			Row:      f.Row + headerRowOffset,
+			Status:   f.Duplicate.Status,
+			Expense:  f.Expense,
+			Existing: f.Duplicate.Existing,
+		})
+	}
+	return rows
+}
+
 // errSessionNotFound is returned when an import session is missing or has
 // expired.
 //
 //nolint:staticcheck,revive // preserves original user-facing message text
 var errSessionNotFound = errors.New("Session expired or not found. Please upload the file again.")
 
+// session returns an import session of the user. Sessions of other users
+// are reported as not found.
+func (s *Service) session(ctx context.Context, userID int64, sessionID string) (*importUtil.ImportSession, error) {
+	session, err := s.sessionStore.Get(ctx, userID, sessionID)
+	if err != nil {
+		return nil, s.sessionError(err)
+	}
+	return session, nil
+}
+
+// sessionError reports sessions missing from storage with
+// errSessionNotFound.
+func (s *Service) sessionError(err error) error {
+	var notFound *domain.NotFoundError
+	if errors.As(err, &notFound) {
+		return errSessionNotFound
+	}
+	return fmt.Errorf("error loading import session: %w", err)
+}
+
 // errNoMapping is returned when Execute is called on a session that has not
 // yet had a field mapping applied.
 //
 //nolint:staticcheck,revive // preserves original user-facing message text
 var errNoMapping = errors.New("No field mapping found. Please complete the mapping step first.")
 
+// ExecuteResult summarizes an executed import.
+type ExecuteResult struct {
+	Imported        int64
+	WithoutCategory int
+	// ErrorRows counts the rows the mapping could not read.
+	ErrorRows int
+	// ExactDuplicates and LikelyDuplicates count the rows matching a stored
+	// expense, whether they were imported or not.
+	ExactDuplicates  int
+	LikelyDuplicates int
+	// SkippedDuplicates counts the duplicates left out of the import.
+	SkippedDuplicates int
+	ImportBatchID     int64
+	// RejectedStatements counts the MT940 statements left out because they
+	// do not match their closing balance, RejectedReason explaining which.
+	RejectedStatements int
+	RejectedReason     string
+}
+
 // Execute applies the stored field mapping for the given import session and
-// inserts the resulting expenses, then deletes the session.
+// inserts the resulting expenses as an import batch, then deletes the session.
+// Rows flagged as duplicates are skipped unless their row number, as reported
+// by ApplyMapping, is in includeRows.
 func (s *Service) Execute(
 	ctx context.Context,
 	userID int64,
 	sessionID string,
+	includeRows []int,
 	m *matcher.Matcher,
-) (int64, int, int, error) {
-	session, exists := s.sessionStore.Get(sessionID)
-	if !exists {
-		return 0, 0, 0, errSessionNotFound
+) (ExecuteResult, error) {
+	session, err := s.session(ctx, userID, sessionID)
+	if err != nil {
+		return ExecuteResult{}, err
 	}
 
 	if session.Mapping == nil {
-		return 0, 0, 0, errNoMapping
+		return ExecuteResult{}, errNoMapping
 	}
 
 	s.logger.Info("Executing import", "import_session_id", sessionID, "filename", session.Filename)
 
+	// The sample validates the mapping and settles the date format, the
+	// whole file is then read from disk a row at a time
 	result, err := importUtil.ApplyMapping(session.Data, session.Mapping, m)
 	if err != nil {
 		//nolint:staticcheck // preserves original user-facing message text
-		return 0, 0, 0, fmt.Errorf("Error applying mapping: %w", err)
+		return ExecuteResult{}, fmt.Errorf("Error applying mapping: %w", err)
 	}
 
-	withoutCategory := 0
-	for _, mappedExp := range result.Expenses {
-		if mappedExp.CategoryID() == nil {
-			withoutCategory++
-		}
+	mapping, err := json.Marshal(session.Mapping)
+	if err != nil {
+		return ExecuteResult{}, fmt.Errorf("error encoding mapping: %w", err)
 	}
 
-	inserted, err := s.storage.InsertExpenses(ctx, userID, result.Expenses)
+	resolved := *session.Mapping
+	resolved.DateLayout = result.DateLayout
+	resolved.AccountID = s.mappingAccount(ctx, userID, resolved.AccountID)
+
+	file, err := s.sessionStore.Open(ctx, userID, sessionID)
 	if err != nil {
+		return ExecuteResult{}, s.sessionError(err)
+	}
+	defer file.Close()
+
+	_, rows, err := importUtil.OpenFile(session.Filename, file, session.Data.Options())
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+
+	included := make(map[int]bool, len(includeRows))
+	for _, row := range includeRows {
+		included[row] = true
+	}
+
+	info := importUtil.ImportRows(
+		ctx,
+		userID,
+		s.storage,
+		domain.NewImportBatch(0, session.Filename, importUtil.FormatMapping, mapping, 0, 0, 0, time.Time{}, nil),
+		rows,
+		&resolved,
+		m,
+		func(row int, _ importUtil.Duplicate) bool {
+			return included[row+headerRowOffset]
+		},
+	)
+	if info.Error != nil {
 		//nolint:staticcheck // preserves original user-facing message text
-		return 0, 0, 0, fmt.Errorf("Error inserting expenses: %w", err)
+		return ExecuteResult{}, fmt.Errorf("Error inserting expenses: %w", info.Error)
 	}
 
 	s.logger.Info(
 		"Import completed successfully",
 		"import_session_id", sessionID,
-		"imported", inserted,
-		"errors", len(result.Errors),
+		"import_batch_id", info.ImportBatchID,
+		"imported", info.TotalImports,
+		"skipped_duplicates", info.SkippedDuplicates,
+		"errors", info.InvalidRows,
 	)
 
-	s.sessionStore.Delete(sessionID)
+	if err = s.sessionStore.Delete(ctx, userID, sessionID); err != nil {
+		s.logger.Warn("Failed to delete import session", "import_session_id", sessionID, "error", err)
+	}
+
+	s.importCompleted(ctx, userID, session.Filename, info)
+
+	return ExecuteResult{
+		Imported:          int64(info.TotalImports),
+		WithoutCategory:   info.ImportWithoutCategory,
+		ErrorRows:         info.InvalidRows,
+		ExactDuplicates:   info.ExactDuplicates,
+		LikelyDuplicates:  info.LikelyDuplicates,
+		SkippedDuplicates: info.SkippedDuplicates,
+		ImportBatchID:     info.ImportBatchID,
+	}, nil
+}
+
+// importCompleted publishes import.completed for imports that recorded a
+// batch. Failed imports and files without expenses are not published.
+func (s *Service) importCompleted(ctx context.Context, userID int64, filename string, info importUtil.ImportInfo) {
+	if info.Error != nil || info.ImportBatchID == 0 {
+		return
+	}
+
+	s.webhooks.Publish(ctx, userID, domain.EventImportCompleted, webhook.ImportCompleted{
+		ImportBatchID:     info.ImportBatchID,
+		Filename:          filename,
+		Imported:          info.TotalImports,
+		WithoutCategory:   info.ImportWithoutCategory,
+		ErrorRows:         info.InvalidRows,
+		SkippedDuplicates: info.SkippedDuplicates,
+	})
+}
+
+// ImportBatches lists the user's imports, newest first.
+func (s *Service) ImportBatches(ctx context.Context, userID int64) ([]domain.ImportBatch, error) {
+	return s.storage.GetImportBatches(ctx, userID)
+}
+
+// RollbackImportBatch deletes the expenses created by an import, leaving
+// the rest untouched. It returns the number of expenses deleted.
+func (s *Service) RollbackImportBatch(ctx context.Context, userID, batchID int64) (int64, error) {
+	deleted, err := s.storage.RollbackImportBatch(ctx, userID, batchID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Info("Import rolled back", "import_batch_id", batchID, "deleted", deleted)
+
+	return deleted, nil
+}
+
+// mappingAccount returns the account of a mapping when it is still one of
+// the user's accounts. Saved mappings can name an account deleted since,
+// their files are then imported without an account.
+func (s *Service) mappingAccount(ctx context.Context, userID int64, accountID *int64) *int64 {
+	if accountID == nil {
+		return nil
+	}
+
+	if _, err := s.storage.GetAccount(ctx, userID, *accountID); err != nil {
+		s.logger.Warn("Importing without the account of the mapping", "account_id", *accountID, "error", err)
+		return nil
+	}
 
-	return inserted, withoutCategory, len(result.Errors), nil
+	return accountID
 }
@@ -2,10 +2,13 @@ package importsvc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/GustavoCaso/expensetrace/domain"
 	importUtil "github.com/GustavoCaso/expensetrace/import"
 	"github.com/GustavoCaso/expensetrace/matcher"
 	"github.com/GustavoCaso/expensetrace/testutil"
@@ -40,6 +43,7 @@ func TestImportFile_CSVKnownProvider(t *testing.T) {
 	info, needsPreview, previewReader, err := svc.ImportFile(
 		context.Background(),
 		user.ID(),
+		nil,
 		"evo_test.csv",
 		strings.NewReader(evoCSV),
 		m,
@@ -80,6 +84,7 @@ func TestImportFile_UnsupportedCSVNeedsPreview(t *testing.T) {
 	info, needsPreview, previewReader, err := svc.ImportFile(
 		context.Background(),
 		user.ID(),
+		nil,
 		"unknown_format.csv",
 		strings.NewReader(genericCSV),
 		m,
@@ -120,6 +125,7 @@ func TestImportFile_InvalidJSONSchemaNeedsPreview(t *testing.T) {
 	info, needsPreview, previewReader, err := svc.ImportFile(
 		context.Background(),
 		user.ID(),
+		nil,
 		"invalid_schema.json",
 		strings.NewReader(invalidSchemaJSON),
 		m,
@@ -145,10 +151,14 @@ func TestImportFile_InvalidJSONSchemaNeedsPreview(t *testing.T) {
 	// io.SeekStart) call were removed, this Preview call would read from
 	// wherever SupportedJSONSchema's decoder left off (typically EOF),
 	// and would either fail to parse or return no rows.
-	headers, previewRows, totalRows, sessionID, err := svc.Preview("invalid_schema.json", previewReader)
+	preview, err := svc.Preview(context.Background(), user.ID(), "invalid_schema.json", previewReader)
 	if err != nil {
 		t.Fatalf("Preview returned error: %v", err)
 	}
+	sessionID := preview.SessionID
+	headers := preview.Headers
+	previewRows := preview.PreviewRows
+	totalRows := preview.TotalRows
 
 	if sessionID == "" {
 		t.Fatal("Expected non-empty sessionID from Preview")
@@ -182,17 +192,18 @@ func TestImportFile_InvalidJSONSchemaNeedsPreview(t *testing.T) {
 
 func TestExecute_FailsWithoutMapping(t *testing.T) {
 	logger := testutil.TestLogger(t)
-	s, _ := testutil.SetupTestStorage(t, logger)
+	s, user := testutil.SetupTestStorage(t, logger)
 
 	svc := New(s, logger, testSessionTTL)
 	m := matcher.New(nil)
 
-	_, _, _, sessionID, err := svc.Preview("unknown_format.csv", strings.NewReader(genericCSV))
+	preview, err := svc.Preview(context.Background(), user.ID(), "unknown_format.csv", strings.NewReader(genericCSV))
 	if err != nil {
 		t.Fatalf("Preview returned error: %v", err)
 	}
+	sessionID := preview.SessionID
 
-	_, _, _, err = svc.Execute(context.Background(), 0, sessionID, m)
+	_, err = svc.Execute(context.Background(), user.ID(), sessionID, nil, m)
 	if err == nil {
 		t.Fatal("Expected error when calling Execute without a mapping applied")
 	}
@@ -205,15 +216,16 @@ func TestExecute_FailsWithoutMapping(t *testing.T) {
 
 func TestApplyMapping_ReturnsPreviewRows(t *testing.T) {
 	logger := testutil.TestLogger(t)
-	s, _ := testutil.SetupTestStorage(t, logger)
+	s, user := testutil.SetupTestStorage(t, logger)
 
 	svc := New(s, logger, testSessionTTL)
 	m := matcher.New(nil)
 
-	_, _, _, sessionID, err := svc.Preview("unknown_format.csv", strings.NewReader(genericCSV))
+	preview, err := svc.Preview(context.Background(), user.ID(), "unknown_format.csv", strings.NewReader(genericCSV))
 	if err != nil {
 		t.Fatalf("Preview returned error: %v", err)
 	}
+	sessionID := preview.SessionID
 
 	mapping := &importUtil.FieldMapping{
 		Source:            "MyBank",
@@ -223,7 +235,7 @@ func TestApplyMapping_ReturnsPreviewRows(t *testing.T) {
 		CurrencyColumn:    3,
 	}
 
-	result, err := svc.ApplyMapping(sessionID, mapping, m)
+	result, err := svc.ApplyMapping(context.Background(), user.ID(), sessionID, mapping, m)
 	if err != nil {
 		t.Fatalf("ApplyMapping returned error: %v", err)
 	}
@@ -253,10 +265,11 @@ func TestExecute_InsertsMappedExpenses(t *testing.T) {
 	svc := New(s, logger, testSessionTTL)
 	m := matcher.New(nil)
 
-	_, _, _, sessionID, err := svc.Preview("unknown_format.csv", strings.NewReader(genericCSV))
+	preview, err := svc.Preview(context.Background(), user.ID(), "unknown_format.csv", strings.NewReader(genericCSV))
 	if err != nil {
 		t.Fatalf("Preview returned error: %v", err)
 	}
+	sessionID := preview.SessionID
 
 	mapping := &importUtil.FieldMapping{
 		Source:            "MyBank",
@@ -266,26 +279,44 @@ func TestExecute_InsertsMappedExpenses(t *testing.T) {
 		CurrencyColumn:    3,
 	}
 
-	_, err = svc.ApplyMapping(sessionID, mapping, m)
+	_, err = svc.ApplyMapping(context.Background(), user.ID(), sessionID, mapping, m)
 	if err != nil {
 		t.Fatalf("ApplyMapping returned error: %v", err)
 	}
 
-	inserted, withoutCategory, resultErrorsCount, err := svc.Execute(context.Background(), user.ID(), sessionID, m)
+	result, err := svc.Execute(context.Background(), user.ID(), sessionID, nil, m)
 	if err != nil {
 		t.Fatalf("Execute returned error: %v", err)
 	}
 
-	if inserted != 2 {
-		t.Fatalf("Expected 2 inserted, got %d", inserted)
+	if result.Imported != 2 {
+		t.Fatalf("Expected 2 inserted, got %d", result.Imported)
 	}
 
-	if withoutCategory != 2 {
-		t.Fatalf("Expected 2 without category, got %d", withoutCategory)
+	if result.WithoutCategory != 2 {
+		t.Fatalf("Expected 2 without category, got %d", result.WithoutCategory)
 	}
 
-	if resultErrorsCount != 0 {
-		t.Fatalf("Expected 0 result errors, got %d", resultErrorsCount)
+	if result.ErrorRows != 0 {
+		t.Fatalf("Expected 0 result errors, got %d", result.ErrorRows)
+	}
+
+	batches, err := s.GetImportBatches(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get import batches: %v", err)
+	}
+	if len(batches) != 1 || batches[0].ID() != result.ImportBatchID {
+		t.Fatalf("Expected the import batch %d, got %d batches", result.ImportBatchID, len(batches))
+	}
+
+	batch := batches[0]
+
+	if batch.Filename() != "unknown_format.csv" || batch.Format() != importUtil.FormatMapping {
+		t.Errorf("Unexpected import batch %q %q", batch.Filename(), batch.Format())
+	}
+
+	if !strings.Contains(string(batch.Mapping()), `"source":"MyBank"`) {
+		t.Errorf("Expected the import batch to record the mapping, got %s", batch.Mapping())
 	}
 
 	allExpenses, err := s.GetAllExpenseTypes(context.Background(), user.ID())
@@ -298,8 +329,630 @@ func TestExecute_InsertsMappedExpenses(t *testing.T) {
 	}
 
 	// A second Execute should fail since the session was deleted.
-	_, _, _, err = svc.Execute(context.Background(), user.ID(), sessionID, m)
+	_, err = svc.Execute(context.Background(), user.ID(), sessionID, nil, m)
 	if err == nil {
 		t.Fatal("Expected error on second Execute call after session deletion")
 	}
 }
+
+func TestImportFile_OFXImportsDirectly(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	svc := New(s, logger, testSessionTTL)
+	m := matcher.New(nil)
+
+	const ofx = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>EUR</CURDEF><BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240101</DTPOSTED><TRNAMT>-12.34</TRNAMT>` +
+		`<FITID>1</FITID><NAME>Restaurant bill</NAME></STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+	info, needsPreview, previewReader, err := svc.ImportFile(
+		context.Background(),
+		user.ID(),
+		nil,
+		"statement.qfx",
+		strings.NewReader(ofx),
+		m,
+	)
+	if err != nil {
+		t.Fatalf("ImportFile returned error: %v", err)
+	}
+
+	if needsPreview {
+		t.Fatal("Expected needsPreview=false for OFX statements")
+	}
+
+	if previewReader != nil {
+		t.Fatal("Expected nil previewReader for OFX statements")
+	}
+
+	if info.TotalImports != 1 {
+		t.Fatalf("Expected 1 import, got %d", info.TotalImports)
+	}
+}
+
+func TestImportFile_CamtImportsDirectly(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	svc := New(s, logger, testSessionTTL)
+	m := matcher.New(nil)
+
+	const camt = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"><BkToCstmrStmt><Stmt>
+<Ntry><Amt Ccy="EUR">12.34</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+<BookgDt><Dt>2024-01-01</Dt></BookgDt><AddtlNtryInf>Restaurant bill</AddtlNtryInf></Ntry>
+</Stmt></BkToCstmrStmt></Document>`
+
+	info, needsPreview, previewReader, err := svc.ImportFile(
+		context.Background(),
+		user.ID(),
+		nil,
+		"statement.xml",
+		strings.NewReader(camt),
+		m,
+	)
+	if err != nil {
+		t.Fatalf("ImportFile returned error: %v", err)
+	}
+
+	if needsPreview {
+		t.Fatal("Expected needsPreview=false for camt statements")
+	}
+
+	if previewReader != nil {
+		t.Fatal("Expected nil previewReader for camt statements")
+	}
+
+	if info.TotalImports != 1 {
+		t.Fatalf("Expected 1 import, got %d", info.TotalImports)
+	}
+}
+
+func TestImportFile_MT940ReportsClosingBalance(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	svc := New(s, logger, testSessionTTL)
+	m := matcher.New(nil)
+
+	const mt940 = `:20:REF
+:25:123456
+:60F:C240101EUR100,00
+:61:240102D12,34NMSCREF
+:86:Restaurant bill
+:62F:C240102EUR87,66
+-`
+
+	info, needsPreview, _, err := svc.ImportFile(
+		context.Background(),
+		user.ID(),
+		nil,
+		"statement.sta",
+		strings.NewReader(mt940),
+		m,
+	)
+	if err != nil {
+		t.Fatalf("ImportFile returned error: %v", err)
+	}
+
+	if needsPreview {
+		t.Fatal("Expected needsPreview=false for MT940 statements")
+	}
+
+	if info.TotalImports != 1 {
+		t.Fatalf("Expected 1 import, got %d", info.TotalImports)
+	}
+
+	if info.ClosingBalance == nil || info.ClosingBalance.Amount != 8766 {
+		t.Fatalf("Expected closing balance 8766, got %+v", info.ClosingBalance)
+	}
+}
+
+func TestApplyMapping_ReportsAmbiguousDates(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	svc := New(s, logger, testSessionTTL)
+
+	csvData := `date,description,amount,currency
+01/02/2024,Coffee,-5.00,USD
+05/05/2024,Lunch,-12.00,USD`
+
+	preview, err := svc.Preview(context.Background(), user.ID(), "unknown_format.csv", strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+	sessionID := preview.SessionID
+
+	mapping := &importUtil.FieldMapping{
+		Source:            "MyBank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+	}
+
+	result, err := svc.ApplyMapping(context.Background(), user.ID(), sessionID, mapping, matcher.New(nil))
+	if err != nil {
+		t.Fatalf("ApplyMapping returned error: %v", err)
+	}
+
+	if result.DateLayout != "DD/MM/YYYY" {
+		t.Errorf("DateLayout = %q, want DD/MM/YYYY", result.DateLayout)
+	}
+
+	if len(result.Warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %v", result.Warnings)
+	}
+
+	if len(result.Ambiguities) != 1 || len(result.Ambiguities[0].Rows) != 1 {
+		t.Fatalf("Expected one ambiguous row, got %+v", result.Ambiguities)
+	}
+
+	// Row numbers match the ones used for mapping errors
+	if result.Ambiguities[0].Rows[0].Row != 1 {
+		t.Errorf("Ambiguous row = %d, want 1", result.Ambiguities[0].Rows[0].Row)
+	}
+}
+
+func TestExecute_SkipsDuplicatesUnlessIncluded(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	existing := []domain.Expense{
+		domain.NewExpense(
+			0, "MyBank", "restaurant bill", "USD", -123456,
+			time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), domain.ChargeType, nil,
+			nil,
+		),
+		domain.NewExpense(
+			0, "Card", "uber ride madrid", "USD", -500000,
+			time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), domain.ChargeType, nil,
+			nil,
+		),
+	}
+	if _, err := s.InsertExpenses(ctx, user.ID(), existing); err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	svc := New(s, logger, testSessionTTL)
+	m := matcher.New(nil)
+
+	preview, err := svc.Preview(ctx, user.ID(), "unknown_format.csv", strings.NewReader(genericCSV))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+	sessionID := preview.SessionID
+
+	mapping := &importUtil.FieldMapping{
+		Source:            "MyBank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+	}
+
+	applied, err := svc.ApplyMapping(ctx, user.ID(), sessionID, mapping, m)
+	if err != nil {
+		t.Fatalf("ApplyMapping returned error: %v", err)
+	}
+
+	if applied.NewRows != 0 || len(applied.Duplicates) != 2 {
+		t.Fatalf("Expected 0 new rows and 2 duplicates, got %d and %d", applied.NewRows, len(applied.Duplicates))
+	}
+
+	if applied.Duplicates[0].Status != domain.DuplicateExact || applied.Duplicates[0].Row != 1 {
+		t.Errorf("Expected row 1 to be an exact duplicate, got %+v", applied.Duplicates[0])
+	}
+
+	if applied.Duplicates[1].Status != domain.DuplicateLikely || applied.Duplicates[1].Row != 2 {
+		t.Errorf("Expected row 2 to be a likely duplicate, got %+v", applied.Duplicates[1])
+	}
+
+	// Include the likely duplicate, the exact one stays out
+	result, err := svc.Execute(ctx, user.ID(), sessionID, []int{applied.Duplicates[1].Row}, m)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+
+	if result.Imported != 1 || result.SkippedDuplicates != 1 {
+		t.Errorf("Expected 1 imported and 1 skipped, got %d and %d", result.Imported, result.SkippedDuplicates)
+	}
+
+	if result.ExactDuplicates != 1 || result.LikelyDuplicates != 1 {
+		t.Errorf("Expected 1 exact and 1 likely duplicate, got %d and %d",
+			result.ExactDuplicates, result.LikelyDuplicates)
+	}
+
+	allExpenses, err := s.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	if len(allExpenses) != 3 {
+		t.Fatalf("Expected 3 expenses in storage, got %d", len(allExpenses))
+	}
+}
+
+func TestImportWithMappingProfile_ImportsLikelyDuplicates(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger, testSessionTTL)
+	m := matcher.New(nil)
+
+	preview, err := svc.Preview(ctx, user.ID(), "december.csv", strings.NewReader(genericCSV))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+
+	mapping := &importUtil.FieldMapping{
+		Source:            "MyBank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+	}
+	if _, err = svc.ApplyMapping(ctx, user.ID(), preview.SessionID, mapping, m); err != nil {
+		t.Fatalf("ApplyMapping returned error: %v", err)
+	}
+	if _, err = svc.SaveMappingProfile(ctx, user.ID(), preview.SessionID, "MyBank"); err != nil {
+		t.Fatalf("SaveMappingProfile returned error: %v", err)
+	}
+
+	existing := []domain.Expense{
+		domain.NewExpense(
+			0, "MyBank", "restaurant bill", "USD", -123456,
+			time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), domain.ChargeType, nil,
+			nil,
+		),
+		domain.NewExpense(
+			0, "Card", "uber ride madrid", "USD", -500000,
+			time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), domain.ChargeType, nil,
+			nil,
+		),
+	}
+	if _, err = s.InsertExpenses(ctx, user.ID(), existing); err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	reader := strings.NewReader(genericCSV)
+	result, err := svc.ImportWithMappingProfile(ctx, user.ID(), "january.csv", reader, "MyBank", m)
+	if err != nil {
+		t.Fatalf("ImportWithMappingProfile returned error: %v", err)
+	}
+
+	if result.Imported != 1 || result.SkippedDuplicates != 1 || result.LikelyDuplicates != 1 {
+		t.Errorf("Expected the likely duplicate imported and the exact one skipped, got %d imported, "+
+			"%d skipped and %d likely", result.Imported, result.SkippedDuplicates, result.LikelyDuplicates)
+	}
+}
+
+func TestReparse_ChangesHowTheFileIsRead(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger, testSessionTTL)
+
+	preview, err := svc.Preview(ctx, user.ID(), "statement.csv", strings.NewReader("Account statement\n"+genericCSV))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+
+	if preview.HeaderRow != 1 || preview.Headers[0] != "date" || preview.Delimiter != "," {
+		t.Fatalf("Expected the title row to be skipped, got %+v", preview)
+	}
+
+	reparsed, err := svc.Reparse(ctx, user.ID(), preview.SessionID, importUtil.ParseOptions{HeaderRow: 0})
+	if err != nil {
+		t.Fatalf("Reparse returned error: %v", err)
+	}
+
+	if reparsed.HeaderRow != 0 || reparsed.TotalRows != 3 || reparsed.Headers[0] != "Account statement" {
+		t.Errorf("Unexpected preview %+v", reparsed)
+	}
+
+	reparsed, err = svc.Reparse(ctx, user.ID(), preview.SessionID, importUtil.ParseOptions{
+		HeaderRow: importUtil.AutoHeaderRow,
+		Delimiter: ";",
+	})
+	if err != nil {
+		t.Fatalf("Reparse returned error: %v", err)
+	}
+
+	if reparsed.Delimiter != ";" || len(reparsed.Headers) != 1 {
+		t.Errorf("Expected a single column reading commas as part of the values, got %v", reparsed.Headers)
+	}
+
+	_, err = svc.Reparse(ctx, user.ID(), "missing-session", importUtil.ParseOptions{HeaderRow: 1})
+	if err == nil {
+		t.Fatal("Expected error for a missing session")
+	}
+}
+
+func TestApplyMappingProfile_ReadsFileLikeTheSavedMapping(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger, testSessionTTL)
+	m := matcher.New(nil)
+	content := "Account 1\nExported today\n" + genericCSV
+
+	preview, err := svc.Preview(ctx, user.ID(), "january.csv", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+
+	mapping := &importUtil.FieldMapping{
+		Source:            "MyBank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+	}
+	if _, err = svc.ApplyMapping(ctx, user.ID(), preview.SessionID, mapping, m); err != nil {
+		t.Fatalf("ApplyMapping returned error: %v", err)
+	}
+
+	profile, err := svc.SaveMappingProfile(ctx, user.ID(), preview.SessionID, "MyBank")
+	if err != nil {
+		t.Fatalf("SaveMappingProfile returned error: %v", err)
+	}
+
+	if !strings.Contains(string(profile.Mapping()), `"header_row":2`) {
+		t.Fatalf("Expected the saved mapping to record the header row, got %s", profile.Mapping())
+	}
+
+	// The user reads the next file from its first row, the saved mapping
+	// reads it as the first one was
+	preview, err = svc.Preview(ctx, user.ID(), "february.csv", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+	if _, err = svc.Reparse(ctx, user.ID(), preview.SessionID, importUtil.ParseOptions{HeaderRow: 0}); err != nil {
+		t.Fatalf("Reparse returned error: %v", err)
+	}
+
+	result, err := svc.ApplyMappingProfile(ctx, user.ID(), preview.SessionID, profile.ID(), m)
+	if err != nil {
+		t.Fatalf("ApplyMappingProfile returned error: %v", err)
+	}
+
+	if len(result.Errors) != 0 || result.TotalRows != 2 || result.Headers[0] != "date" {
+		t.Errorf("Expected the file to be read from its header, got %v rows and errors %v",
+			result.TotalRows, result.Errors)
+	}
+}
+
+func TestExecute_StreamsFilesLargerThanTheSample(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger, testSessionTTL)
+	m := matcher.New(nil)
+
+	const rows = importUtil.SampleRows + 500
+
+	var b strings.Builder
+	b.WriteString("date,description,amount,currency\n")
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range rows {
+		fmt.Fprintf(&b, "%s,Coffee %d,-3.50,EUR\n", start.AddDate(0, 0, i).Format("2006-01-02"), i)
+	}
+
+	preview, err := svc.Preview(ctx, user.ID(), "history.csv", strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+	if preview.TotalRows != rows {
+		t.Errorf("TotalRows = %d, want %d", preview.TotalRows, rows)
+	}
+
+	session, err := svc.sessionStore.Get(ctx, user.ID(), preview.SessionID)
+	if err != nil {
+		t.Fatalf("Failed to get import session: %v", err)
+	}
+	if len(session.Data.Rows) != importUtil.SampleRows {
+		t.Errorf("Session kept %d rows, want %d", len(session.Data.Rows), importUtil.SampleRows)
+	}
+
+	mapping := &importUtil.FieldMapping{
+		Source:            "MyBank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+	}
+	if _, err = svc.ApplyMapping(ctx, user.ID(), preview.SessionID, mapping, m); err != nil {
+		t.Fatalf("ApplyMapping returned error: %v", err)
+	}
+
+	result, err := svc.Execute(ctx, user.ID(), preview.SessionID, nil, m)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+
+	if result.Imported != rows {
+		t.Errorf("Imported = %d, want %d", result.Imported, rows)
+	}
+
+	if _, err = s.OpenImportSessionFile(ctx, user.ID(), preview.SessionID); err == nil {
+		t.Error("Expected the uploaded file to be removed after the import")
+	}
+}
+
+func TestApplyMapping_ChecksRowsPastTheSample(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger, testSessionTTL)
+	m := matcher.New(nil)
+
+	const rows = importUtil.SampleRows + 500
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	duplicateRow := importUtil.SampleRows + 100
+	invalidRow := importUtil.SampleRows + 200
+
+	existing := domain.NewExpense(
+		0, "MyBank", fmt.Sprintf("coffee %d", duplicateRow), "EUR", -350,
+		start.AddDate(0, 0, duplicateRow), domain.ChargeType, nil, nil,
+	)
+	if _, err := s.InsertExpenses(ctx, user.ID(), []domain.Expense{existing}); err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("date,description,amount,currency\n")
+	for i := range rows {
+		amount := "-3.50"
+		if i == invalidRow {
+			amount = "not an amount"
+		}
+		fmt.Fprintf(&b, "%s,Coffee %d,%s,EUR\n", start.AddDate(0, 0, i).Format("2006-01-02"), i, amount)
+	}
+
+	preview, err := svc.Preview(ctx, user.ID(), "history.csv", strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+
+	mapping := &importUtil.FieldMapping{
+		Source:            "MyBank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+	}
+	applied, err := svc.ApplyMapping(ctx, user.ID(), preview.SessionID, mapping, m)
+	if err != nil {
+		t.Fatalf("ApplyMapping returned error: %v", err)
+	}
+
+	if len(applied.Duplicates) != 1 || applied.Duplicates[0].Row != duplicateRow+1 ||
+		applied.Duplicates[0].Status != domain.DuplicateExact {
+		t.Fatalf("Expected row %d to be an exact duplicate, got %+v", duplicateRow+1, applied.Duplicates)
+	}
+	if applied.NewRows != rows-2 {
+		t.Errorf("NewRows = %d, want %d", applied.NewRows, rows-2)
+	}
+	if len(applied.Errors) != 1 || !strings.HasPrefix(applied.Errors[0], fmt.Sprintf("Row %d:", invalidRow+1)) {
+		t.Errorf("Expected an error for row %d, got %v", invalidRow+1, applied.Errors)
+	}
+
+	result, err := svc.Execute(ctx, user.ID(), preview.SessionID, []int{applied.Duplicates[0].Row}, m)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if result.Imported != rows-1 || result.SkippedDuplicates != 0 || result.ErrorRows != 1 {
+		t.Errorf("Expected %d imported, none skipped and 1 error row, got %d, %d and %d",
+			rows-1, result.Imported, result.SkippedDuplicates, result.ErrorRows)
+	}
+}
+
+func TestImportSession_BelongsToTheUserWhoUploaded(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger, testSessionTTL)
+	m := matcher.New(nil)
+
+	other, err := s.CreateUser(ctx, "other", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	preview, err := svc.Preview(ctx, user.ID(), "unknown_format.csv", strings.NewReader(genericCSV))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+
+	mapping := &importUtil.FieldMapping{
+		Source:            "MyBank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+	}
+
+	_, err = svc.Reparse(ctx, other.ID(), preview.SessionID, importUtil.ParseOptions{})
+	if !errors.Is(err, errSessionNotFound) {
+		t.Errorf("Expected Reparse by another user to fail with errSessionNotFound, got %v", err)
+	}
+	_, err = svc.ApplyMapping(ctx, other.ID(), preview.SessionID, mapping, m)
+	if !errors.Is(err, errSessionNotFound) {
+		t.Errorf("Expected ApplyMapping by another user to fail with errSessionNotFound, got %v", err)
+	}
+
+	if _, err = svc.ApplyMapping(ctx, user.ID(), preview.SessionID, mapping, m); err != nil {
+		t.Fatalf("ApplyMapping returned error: %v", err)
+	}
+
+	_, err = svc.Execute(ctx, other.ID(), preview.SessionID, nil, m)
+	if !errors.Is(err, errSessionNotFound) {
+		t.Errorf("Expected Execute by another user to fail with errSessionNotFound, got %v", err)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(ctx, other.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	if len(expenses) != 0 {
+		t.Errorf("Expected no expenses imported for the other user, got %d", len(expenses))
+	}
+
+	// The session is still there for its owner
+	result, err := svc.Execute(ctx, user.ID(), preview.SessionID, nil, m)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if result.Imported != 2 {
+		t.Errorf("Imported = %d, want 2", result.Imported)
+	}
+}
+
+func TestImportSession_SurvivesARestart(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+	m := matcher.New(nil)
+
+	preview, err := New(s, logger, testSessionTTL).Preview(
+		ctx, user.ID(), "unknown_format.csv", strings.NewReader(genericCSV),
+	)
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+
+	mapping := &importUtil.FieldMapping{
+		Source:            "MyBank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+	}
+	_, err = New(s, logger, testSessionTTL).ApplyMapping(ctx, user.ID(), preview.SessionID, mapping, m)
+	if err != nil {
+		t.Fatalf("ApplyMapping returned error: %v", err)
+	}
+
+	// A new service, as after a restart or on another server, finishes
+	// the import
+	result, err := New(s, logger, testSessionTTL).Execute(ctx, user.ID(), preview.SessionID, nil, m)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if result.Imported != 2 {
+		t.Errorf("Imported = %d, want 2", result.Imported)
+	}
+}
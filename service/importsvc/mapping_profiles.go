@@ -0,0 +1,127 @@
+package importsvc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	importUtil "github.com/GustavoCaso/expensetrace/import"
+	"github.com/GustavoCaso/expensetrace/matcher"
+)
+
+// MappingProfiles lists the user's saved field mappings.
+func (s *Service) MappingProfiles(ctx context.Context, userID int64) ([]domain.MappingProfile, error) {
+	return s.storage.GetMappingProfiles(ctx, userID)
+}
+
+// MatchMappingProfile returns the saved mapping created for files with the
+// same header row. The boolean reports whether one was found.
+func (s *Service) MatchMappingProfile(
+	ctx context.Context,
+	userID int64,
+	headers []string,
+) (domain.MappingProfile, bool, error) {
+	profile, err := s.storage.GetMappingProfileByFingerprint(ctx, userID, importUtil.HeaderFingerprint(headers))
+	if err != nil {
+		var notFound *domain.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return profile, true, nil
+}
+
+// SaveMappingProfile stores the mapping applied to an import session under
+// the given name. An existing profile with the same name is replaced.
+func (s *Service) SaveMappingProfile(
+	ctx context.Context,
+	userID int64,
+	sessionID, name string,
+) (domain.MappingProfile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("mapping name is required")
+	}
+
+	session, err := s.session(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Mapping == nil {
+		return nil, errNoMapping
+	}
+
+	encoded, err := json.Marshal(session.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding mapping: %w", err)
+	}
+
+	profile, err := s.storage.SaveMappingProfile(
+		ctx,
+		userID,
+		name,
+		importUtil.HeaderFingerprint(session.Data.Headers),
+		encoded,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Mapping profile saved", "import_session_id", sessionID, "mapping_profile_id", profile.ID())
+
+	return profile, nil
+}
+
+// ApplyMappingProfile applies a saved mapping to an import session, exactly
+// as ApplyMapping does for a mapping chosen by hand.
+func (s *Service) ApplyMappingProfile(
+	ctx context.Context,
+	userID int64,
+	sessionID string,
+	profileID int64,
+	m *matcher.Matcher,
+) (MappingApplication, error) {
+	mapping, err := s.mappingFromProfile(ctx, userID, profileID)
+	if err != nil {
+		return MappingApplication{}, err
+	}
+
+	return s.ApplyMapping(ctx, userID, sessionID, mapping, m)
+}
+
+// DeleteMappingProfile removes a saved mapping.
+func (s *Service) DeleteMappingProfile(ctx context.Context, userID, profileID int64) error {
+	deleted, err := s.storage.DeleteMappingProfile(ctx, userID, profileID)
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return &domain.NotFoundError{}
+	}
+
+	return nil
+}
+
+func (s *Service) mappingFromProfile(
+	ctx context.Context,
+	userID, profileID int64,
+) (*importUtil.FieldMapping, error) {
+	profile, err := s.storage.GetMappingProfile(ctx, userID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading saved mapping: %w", err)
+	}
+
+	mapping := &importUtil.FieldMapping{}
+	if err = json.Unmarshal(profile.Mapping(), mapping); err != nil {
+		return nil, fmt.Errorf("error decoding saved mapping %q: %w", profile.Name(), err)
+	}
+
+	return mapping, nil
+}
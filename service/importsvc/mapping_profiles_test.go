@@ -0,0 +1,132 @@
+package importsvc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	importUtil "github.com/GustavoCaso/expensetrace/import"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func TestSaveMappingProfile_MatchesFilesWithSameHeader(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	svc := New(s, logger, testSessionTTL)
+	m := matcher.New(nil)
+	ctx := context.Background()
+
+	preview, err := svc.Preview(ctx, user.ID(), "january.csv", strings.NewReader(genericCSV))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+	sessionID := preview.SessionID
+	headers := preview.Headers
+
+	_, found, err := svc.MatchMappingProfile(ctx, user.ID(), headers)
+	if err != nil {
+		t.Fatalf("MatchMappingProfile returned error: %v", err)
+	}
+	if found {
+		t.Fatal("Expected no saved mapping before saving one")
+	}
+
+	_, err = svc.SaveMappingProfile(ctx, user.ID(), sessionID, "MyBank")
+	if !errors.Is(err, errNoMapping) {
+		t.Fatalf("Expected errNoMapping before a mapping is applied, got %v", err)
+	}
+
+	mapping := &importUtil.FieldMapping{
+		Source:            "MyBank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+	}
+
+	if _, err = svc.ApplyMapping(ctx, user.ID(), sessionID, mapping, m); err != nil {
+		t.Fatalf("ApplyMapping returned error: %v", err)
+	}
+
+	saved, err := svc.SaveMappingProfile(ctx, user.ID(), sessionID, "  MyBank  ")
+	if err != nil {
+		t.Fatalf("SaveMappingProfile returned error: %v", err)
+	}
+
+	if saved.Name() != "MyBank" {
+		t.Errorf("Name = %q, want MyBank", saved.Name())
+	}
+
+	// Next month's export has the same columns, differently cased
+	februaryCSV := `Date,Description,Amount,Currency
+2024-02-01,Coffee,-3.00,USD`
+
+	preview, err = svc.Preview(ctx, user.ID(), "february.csv", strings.NewReader(februaryCSV))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+	sessionID = preview.SessionID
+	headers = preview.Headers
+
+	profile, found, err := svc.MatchMappingProfile(ctx, user.ID(), headers)
+	if err != nil {
+		t.Fatalf("MatchMappingProfile returned error: %v", err)
+	}
+	if !found || profile.ID() != saved.ID() {
+		t.Fatalf("Expected saved mapping %d to match, got found=%v", saved.ID(), found)
+	}
+
+	result, err := svc.ApplyMappingProfile(ctx, user.ID(), sessionID, profile.ID(), m)
+	if err != nil {
+		t.Fatalf("ApplyMappingProfile returned error: %v", err)
+	}
+
+	if len(result.PreviewExpenses) != 1 || result.PreviewExpenses[0].Source() != "MyBank" {
+		t.Fatalf("Expected one expense mapped with the saved source, got %v", result.PreviewExpenses)
+	}
+
+	executed, err := svc.Execute(ctx, user.ID(), sessionID, nil, m)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+
+	if executed.Imported != 1 {
+		t.Fatalf("Expected 1 inserted, got %d", executed.Imported)
+	}
+}
+
+func TestApplyMappingProfile_OtherUsersProfile(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	svc := New(s, logger, testSessionTTL)
+	ctx := context.Background()
+
+	profile, err := s.SaveMappingProfile(ctx, user.ID(), "MyBank", "fingerprint", []byte(`{"source":"MyBank"}`))
+	if err != nil {
+		t.Fatalf("Failed to save mapping profile: %v", err)
+	}
+
+	other, err := s.CreateUser(ctx, "other", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	preview, err := svc.Preview(ctx, other.ID(), "january.csv", strings.NewReader(genericCSV))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+	sessionID := preview.SessionID
+
+	_, err = svc.ApplyMappingProfile(ctx, other.ID(), sessionID, profile.ID(), matcher.New(nil))
+	if err == nil {
+		t.Fatal("Expected error applying another user's saved mapping")
+	}
+
+	if err = svc.DeleteMappingProfile(ctx, other.ID(), profile.ID()); err == nil {
+		t.Fatal("Expected error deleting another user's saved mapping")
+	}
+}
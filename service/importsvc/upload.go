@@ -0,0 +1,64 @@
+package importsvc
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// upload is a temporary copy of an uploaded file, so files of any size are
+// read from disk instead of memory.
+type upload struct {
+	file *os.File
+}
+
+// spoolUpload copies r to a temporary file, ready to be read from the
+// start. Uploads already spooled by ImportFile are rewound and reused.
+func spoolUpload(r io.Reader) (*upload, error) {
+	if u, ok := r.(*upload); ok {
+		return u, u.rewind()
+	}
+
+	file, err := os.CreateTemp("", "expensetrace-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary file: %w", err)
+	}
+
+	u := &upload{file: file}
+	if _, err = io.Copy(file, r); err != nil {
+		u.Close()
+		return nil, err
+	}
+
+	if err = u.rewind(); err != nil {
+		u.Close()
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (u *upload) Read(p []byte) (int, error) {
+	return u.file.Read(p)
+}
+
+// rewind moves back to the start of the file.
+func (u *upload) rewind() error {
+	_, err := u.file.Seek(0, io.SeekStart)
+	return err
+}
+
+// size returns the size of the file in bytes.
+func (u *upload) size() int64 {
+	info, err := u.file.Stat()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// Close closes and removes the file.
+func (u *upload) Close() {
+	_ = u.file.Close()
+	_ = os.Remove(u.file.Name())
+}
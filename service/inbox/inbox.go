@@ -0,0 +1,251 @@
+// Package inbox imports the statements users drop in a watched directory,
+// without going through the web import flow.
+//
+// Each user has an inbox named after their username inside the watched
+// directory. Imported files are moved to its processed subdirectory and
+// files that could not be imported to its failed one, and every outcome is
+// appended to its result log.
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/logger"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/service/importsvc"
+	"github.com/GustavoCaso/expensetrace/storage"
+)
+
+const (
+	// ProcessedDir and FailedDir are the subdirectories of a user's inbox
+	// files are moved to once imported or rejected.
+	ProcessedDir = "processed"
+	FailedDir    = "failed"
+	// LogFile is the result log kept in a user's inbox.
+	LogFile = "inbox.log"
+)
+
+// settleTime is how long a file must go unchanged before it is imported, so
+// files still being written are picked up on a later check.
+const settleTime = 5 * time.Second
+
+type Watcher struct {
+	storage       storage.Storage
+	importService *importsvc.Service
+	logger        *logger.Logger
+	dir           string
+	interval      time.Duration
+	settle        time.Duration
+}
+
+func New(
+	storage storage.Storage,
+	importService *importsvc.Service,
+	logger *logger.Logger,
+	dir string,
+	interval time.Duration,
+) *Watcher {
+	return &Watcher{
+		storage:       storage,
+		importService: importService,
+		logger:        logger,
+		dir:           dir,
+		interval:      interval,
+		settle:        settleTime,
+	}
+}
+
+// Run checks the inbox every interval until ctx is done.
+func (w *Watcher) Run(ctx context.Context) {
+	w.logger.Info("Watching import inbox", "path", w.dir, "interval", w.interval.String())
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Check(ctx); err != nil {
+			w.logger.Error("Failed to check import inbox", "path", w.dir, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check imports the files waiting in every user's inbox.
+func (w *Watcher) Check(ctx context.Context) error {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		user, userErr := w.storage.GetUserByUsername(ctx, entry.Name())
+		if userErr != nil {
+			var notFound *domain.NotFoundError
+			if errors.As(userErr, &notFound) {
+				w.logger.Warn("Ignoring inbox of unknown user", "username", entry.Name())
+				continue
+			}
+			return userErr
+		}
+
+		if err = w.checkUser(ctx, user, filepath.Join(w.dir, entry.Name())); err != nil {
+			w.logger.Error("Failed to check user inbox", "username", user.Username(), "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (w *Watcher) checkUser(ctx context.Context, user domain.User, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var m *matcher.Matcher
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") || entry.Name() == LogFile {
+			continue
+		}
+
+		info, infoErr := entry.Info()
+		if infoErr != nil || time.Since(info.ModTime()) < w.settle {
+			continue
+		}
+
+		// Categories are loaded once a file is waiting
+		if m == nil {
+			categories, categoriesErr := w.storage.GetCategories(ctx, user.ID())
+			if categoriesErr != nil {
+				return categoriesErr
+			}
+			m = matcher.New(categories)
+		}
+
+		w.importFile(ctx, user, dir, entry.Name(), m)
+	}
+
+	return nil
+}
+
+func (w *Watcher) importFile(ctx context.Context, user domain.User, dir, name string, m *matcher.Matcher) {
+	path := filepath.Join(dir, name)
+
+	result, err := w.autoImport(ctx, user.ID(), path, name, m)
+	if err != nil {
+		w.logger.Warn("Inbox file not imported", "username", user.Username(), "filename", name, "error", err)
+
+		if recordErr := w.importService.RecordInboxFailure(ctx, user.ID(), name, err); recordErr != nil {
+			w.logger.Error("Failed to record inbox failure", "filename", name, "error", recordErr)
+		}
+		w.finish(dir, name, FailedDir, fmt.Sprintf("failed: %s", err))
+		return
+	}
+
+	w.logger.Info(
+		"Inbox file imported",
+		"username", user.Username(),
+		"filename", name,
+		"import_batch_id", result.ImportBatchID,
+		"imported", result.Imported,
+	)
+	w.finish(dir, name, ProcessedDir, summary(result))
+}
+
+func (w *Watcher) autoImport(
+	ctx context.Context,
+	userID int64,
+	path, name string,
+	m *matcher.Matcher,
+) (importsvc.ExecuteResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return importsvc.ExecuteResult{}, err
+	}
+	defer file.Close()
+
+	return w.importService.AutoImport(ctx, userID, name, file, m)
+}
+
+// finish moves a file out of the inbox into the given subdirectory and logs
+// the outcome.
+func (w *Watcher) finish(dir, name, subdir, outcome string) {
+	moved, err := moveFile(dir, name, subdir)
+	if err != nil {
+		// Left in place the file would be imported again on every check
+		w.logger.Error("Failed to move inbox file", "filename", name, "error", err)
+		outcome += fmt.Sprintf(" (could not be moved to %s: %s)", subdir, err)
+	} else if moved != name {
+		outcome += fmt.Sprintf(" (moved to %s as %s)", subdir, moved)
+	}
+
+	line := fmt.Sprintf("%s %s: %s\n", time.Now().UTC().Format(time.RFC3339), name, outcome)
+	if err = appendLog(filepath.Join(dir, LogFile), line); err != nil {
+		w.logger.Error("Failed to write inbox log", "filename", name, "error", err)
+	}
+}
+
+func summary(result importsvc.ExecuteResult) string {
+	outcome := fmt.Sprintf("imported %d expenses", result.Imported)
+	if result.SkippedDuplicates > 0 {
+		outcome += fmt.Sprintf(", skipped %d duplicates", result.SkippedDuplicates)
+	}
+	if result.LikelyDuplicates > 0 {
+		outcome += fmt.Sprintf(", %d likely duplicates imported, review them", result.LikelyDuplicates)
+	}
+	if result.ErrorRows > 0 {
+		outcome += fmt.Sprintf(", %d rows could not be read", result.ErrorRows)
+	}
+	if result.RejectedStatements > 0 {
+		outcome += fmt.Sprintf(", %d statements left out: %s", result.RejectedStatements, result.RejectedReason)
+	}
+	return outcome
+}
+
+// moveFile moves dir/name into dir/subdir, adding a timestamp to the name
+// when a file with the same name was moved there before. It returns the
+// name the file was moved as.
+func moveFile(dir, name, subdir string) (string, error) {
+	target := filepath.Join(dir, subdir)
+	if err := os.MkdirAll(target, 0o750); err != nil {
+		return "", err
+	}
+
+	moved := name
+	if _, err := os.Stat(filepath.Join(target, moved)); !errors.Is(err, fs.ErrNotExist) {
+		ext := filepath.Ext(name)
+		moved = fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), time.Now().Format("20060102-150405"), ext)
+	}
+
+	return moved, os.Rename(filepath.Join(dir, name), filepath.Join(target, moved))
+}
+
+func appendLog(path, line string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.WriteString(line); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
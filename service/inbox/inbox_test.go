@@ -0,0 +1,165 @@
+package inbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	importUtil "github.com/GustavoCaso/expensetrace/import"
+	"github.com/GustavoCaso/expensetrace/matcher"
+	"github.com/GustavoCaso/expensetrace/service/importsvc"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+const evoCSV = `Fecha de la operación,Fecha Valor,Concepto,Importe,Divisa,Tipo de movimiento,Saldo disponible
+01/01/2024,,Restaurant bill,-1234.56,USD,,5000.00
+02/01/2024,,Uber ride,-5000.00,USD,,0.00`
+
+const bankCSV = `date,description,amount,currency
+2024-02-01,Groceries,-45.10,EUR
+2024-02-02,Cinema,-12.00,EUR`
+
+const unknownCSV = `when,what,how much
+2024-02-01,Groceries,-45.10`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestCheckImportsInboxFiles(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	importService := importsvc.New(s, logger, time.Minute)
+
+	// Save a mapping for the bank files, as the user would from the web page
+	preview, err := importService.Preview(ctx, user.ID(), "january.csv", strings.NewReader(bankCSV))
+	if err != nil {
+		t.Fatalf("Preview returned error: %v", err)
+	}
+	mapping := &importUtil.FieldMapping{
+		Source:            "MyBank",
+		DateColumn:        0,
+		DescriptionColumn: 1,
+		AmountColumn:      2,
+		CurrencyColumn:    3,
+	}
+	if _, err = importService.ApplyMapping(ctx, user.ID(), preview.SessionID, mapping, matcher.New(nil)); err != nil {
+		t.Fatalf("ApplyMapping returned error: %v", err)
+	}
+	if _, err = importService.SaveMappingProfile(ctx, user.ID(), preview.SessionID, "MyBank"); err != nil {
+		t.Fatalf("SaveMappingProfile returned error: %v", err)
+	}
+
+	dir := t.TempDir()
+	userDir := filepath.Join(dir, user.Username())
+	if err = os.Mkdir(userDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(userDir, "evo_transactions.csv"), evoCSV)
+	writeFile(t, filepath.Join(userDir, "february.csv"), bankCSV)
+	writeFile(t, filepath.Join(userDir, "unknown.csv"), unknownCSV)
+
+	watcher := New(s, importService, logger, dir, time.Minute)
+	watcher.settle = 0
+
+	if err = watcher.Check(ctx); err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(userDir, ProcessedDir, "evo_transactions.csv"),
+		filepath.Join(userDir, ProcessedDir, "february.csv"),
+		filepath.Join(userDir, FailedDir, "unknown.csv"),
+	} {
+		if _, err = os.Stat(path); err != nil {
+			t.Errorf("Expected %s to exist: %v", path, err)
+		}
+	}
+
+	expenses, err := s.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	if len(expenses) != 4 {
+		t.Errorf("Expected 4 expenses imported, got %d", len(expenses))
+	}
+
+	log, err := os.ReadFile(filepath.Join(userDir, LogFile))
+	if err != nil {
+		t.Fatalf("Failed to read the result log: %v", err)
+	}
+	for _, expected := range []string{
+		"evo_transactions.csv: imported 2 expenses",
+		"february.csv: imported 2 expenses",
+		"unknown.csv: failed: no saved mapping matches",
+	} {
+		if !strings.Contains(string(log), expected) {
+			t.Errorf("Expected the result log to contain %q, got:\n%s", expected, log)
+		}
+	}
+
+	failures, err := importService.InboxFailures(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("InboxFailures returned error: %v", err)
+	}
+	if len(failures) != 1 || failures[0].Filename() != "unknown.csv" {
+		t.Fatalf("Expected unknown.csv to be recorded as failed, got %v", failures)
+	}
+
+	// The same file dropped again is kept next to the first one
+	writeFile(t, filepath.Join(userDir, "unknown.csv"), unknownCSV)
+	if err = watcher.Check(ctx); err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+
+	failed, err := os.ReadDir(filepath.Join(userDir, FailedDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 2 {
+		t.Errorf("Expected 2 files in the failed folder, got %d", len(failed))
+	}
+}
+
+func TestCheckSkipsFilesStillBeingWritten(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	userDir := filepath.Join(dir, user.Username())
+	if err := os.Mkdir(userDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(userDir, "evo_transactions.csv"), evoCSV)
+
+	// Inboxes of unknown users are left alone
+	unknownDir := filepath.Join(dir, "nobody")
+	if err := os.Mkdir(unknownDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(unknownDir, "evo_transactions.csv"), evoCSV)
+
+	watcher := New(s, importsvc.New(s, logger, time.Minute), logger, dir, time.Minute)
+
+	if err := watcher.Check(ctx); err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(userDir, "evo_transactions.csv"),
+		filepath.Join(unknownDir, "evo_transactions.csv"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be left in place: %v", path, err)
+		}
+	}
+}
@@ -1,16 +1,18 @@
 // Package profile contains the business logic for updating a user's
-// username and password, independent of the HTTP layer.
+// username, password and preferences, independent of the HTTP layer.
 package profile
 
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/GustavoCaso/expensetrace/domain"
 	"github.com/GustavoCaso/expensetrace/logger"
 	"github.com/GustavoCaso/expensetrace/storage"
+	"github.com/GustavoCaso/expensetrace/util"
 )
 
 // minPasswordLength is the minimum accepted password length.
@@ -112,3 +114,58 @@ func (s *Service) UpdatePassword(
 	s.logger.Info("Password updated", "user_id", userID)
 	return nil, nil //nolint:nilnil // both return values are error; nil,nil means success
 }
+
+// UpdateDefaultCurrency validates and stores the currency pre-filled when
+// importing files without a currency column. An empty currency clears it.
+// Failures are reported as validationErr, like the other profile updates.
+func (s *Service) UpdateDefaultCurrency(
+	ctx context.Context,
+	userID int64,
+	currency string,
+) (error, error) {
+	if strings.TrimSpace(currency) != "" {
+		normalized, ok := util.NormalizeCurrency(currency)
+		if !ok {
+			return errors.New("currency must be a three letter code like EUR"), nil
+		}
+		currency = normalized
+	} else {
+		currency = ""
+	}
+
+	if updateErr := s.storage.UpdateDefaultCurrency(ctx, userID, currency); updateErr != nil {
+		s.logger.Error("Failed to update default currency", "error", updateErr, "user_id", userID)
+		return errors.New("failed to update default currency"), nil
+	}
+
+	s.logger.Info("Default currency updated", "user_id", userID, "currency", currency)
+	return nil, nil //nolint:nilnil // both return values are error; nil,nil means success
+}
+
+// UpdateBaseCurrency validates and stores the currency reports are
+// converted to. An empty currency clears it, amounts are then added as they
+// are. Failures are reported as validationErr, like the other profile
+// updates.
+func (s *Service) UpdateBaseCurrency(
+	ctx context.Context,
+	userID int64,
+	currency string,
+) (error, error) {
+	if strings.TrimSpace(currency) != "" {
+		normalized, ok := util.NormalizeCurrency(currency)
+		if !ok {
+			return errors.New("currency must be a three letter code like EUR"), nil
+		}
+		currency = normalized
+	} else {
+		currency = ""
+	}
+
+	if updateErr := s.storage.UpdateBaseCurrency(ctx, userID, currency); updateErr != nil {
+		s.logger.Error("Failed to update base currency", "error", updateErr, "user_id", userID)
+		return errors.New("failed to update base currency"), nil
+	}
+
+	s.logger.Info("Base currency updated", "user_id", userID, "currency", currency)
+	return nil, nil //nolint:nilnil // both return values are error; nil,nil means success
+}
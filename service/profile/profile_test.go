@@ -90,3 +90,72 @@ func TestUpdatePassword_RejectsShortPassword(t *testing.T) {
 		t.Fatalf("Expected validationErr message %q, got %q", expectedMsg, validationErr.Error())
 	}
 }
+
+func TestUpdateDefaultCurrency(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	svc := New(s, logger)
+
+	validationErr, err := svc.UpdateDefaultCurrency(context.Background(), user.ID(), "euro")
+	if err != nil {
+		t.Fatalf("UpdateDefaultCurrency returned unexpected internal error: %v", err)
+	}
+	if validationErr == nil {
+		t.Fatal("Expected validationErr for invalid currency")
+	}
+
+	validationErr, err = svc.UpdateDefaultCurrency(context.Background(), user.ID(), " eur ")
+	if err != nil || validationErr != nil {
+		t.Fatalf("UpdateDefaultCurrency failed: %v %v", validationErr, err)
+	}
+
+	updated, getErr := s.GetUserByID(context.Background(), user.ID())
+	if getErr != nil {
+		t.Fatalf("Failed to get user: %v", getErr)
+	}
+	if updated.DefaultCurrency() != "EUR" {
+		t.Fatalf("Expected default currency EUR, got %q", updated.DefaultCurrency())
+	}
+
+	validationErr, err = svc.UpdateDefaultCurrency(context.Background(), user.ID(), "")
+	if err != nil || validationErr != nil {
+		t.Fatalf("UpdateDefaultCurrency failed to clear currency: %v %v", validationErr, err)
+	}
+
+	updated, getErr = s.GetUserByID(context.Background(), user.ID())
+	if getErr != nil {
+		t.Fatalf("Failed to get user: %v", getErr)
+	}
+	if updated.DefaultCurrency() != "" {
+		t.Fatalf("Expected default currency to be cleared, got %q", updated.DefaultCurrency())
+	}
+}
+
+func TestUpdateBaseCurrency(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	svc := New(s, logger)
+
+	validationErr, err := svc.UpdateBaseCurrency(context.Background(), user.ID(), "dollars")
+	if err != nil {
+		t.Fatalf("UpdateBaseCurrency returned unexpected internal error: %v", err)
+	}
+	if validationErr == nil {
+		t.Fatal("Expected validationErr for invalid currency")
+	}
+
+	validationErr, err = svc.UpdateBaseCurrency(context.Background(), user.ID(), "usd")
+	if err != nil || validationErr != nil {
+		t.Fatalf("UpdateBaseCurrency failed: %v %v", validationErr, err)
+	}
+
+	updated, getErr := s.GetUserByID(context.Background(), user.ID())
+	if getErr != nil {
+		t.Fatalf("Failed to get user: %v", getErr)
+	}
+	if updated.BaseCurrency() != "USD" {
+		t.Fatalf("Expected base currency USD, got %q", updated.BaseCurrency())
+	}
+}
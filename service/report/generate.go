@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/service/exchange"
 	"github.com/GustavoCaso/expensetrace/storage"
 )
 
@@ -25,10 +26,16 @@ func generate(
 ) (domain.Report, error) {
 	var report domain.Report
 
-	expenseCategories, incomeCategories, totalIncome, totalSpending, err := splitByExpenseType(
+	converter, err := exchange.NewConverter(ctx, storage, userID)
+	if err != nil {
+		return report, err
+	}
+
+	expenseCategories, incomeCategories, totalIncome, totalSpending, unconverted, err := splitByExpenseType(
 		ctx,
 		userID,
 		storage,
+		converter,
 		expenses,
 	)
 
@@ -40,6 +47,8 @@ func generate(
 	report.Spending = totalSpending
 	report.StartDate = startDate
 	report.EndDate = endDate
+	report.Currency = converter.Base()
+	report.Unconverted = unconverted
 	savings := totalIncome - (totalSpending)*-1
 	report.Savings = savings
 	savingsPercentage := (float32(savings) / float32(totalIncome)) * percentageOfTotal
@@ -64,14 +73,19 @@ func generate(
 	return report, nil
 }
 
+// splitByExpenseType groups the expenses by category, adding up their
+// amounts in the base currency. Expenses without an exchange rate are left
+// out, it returns how many.
 func splitByExpenseType(
 	ctx context.Context,
 	userID int64,
 	storage storage.Storage,
+	converter *exchange.Converter,
 	expenses []domain.Expense,
-) ([]domain.CategoryReport, []domain.CategoryReport, int64, int64, error) {
+) ([]domain.CategoryReport, []domain.CategoryReport, int64, int64, int, error) {
 	var incomeTotal int64
 	var spendingTotal int64
+	unconverted := 0
 	// Track category budgets by category name
 	categoryBudgets := make(map[string]int64)
 	expenseCategories := []domain.CategoryReport{}
@@ -79,13 +93,28 @@ func splitByExpenseType(
 	// internal map to keep track of expense categories
 	expenseCategoryMap := map[string]domain.CategoryReport{}
 
+	// Money moved between the user's own accounts is neither spent nor earned
+	transfers, err := storage.GetTransfers(ctx, userID)
+	if err != nil {
+		return expenseCategories, incomeCategories, incomeTotal, spendingTotal, unconverted, err
+	}
+	transferred := make(map[int64]bool, len(transfers)*2)
+	for _, t := range transfers {
+		transferred[t.ChargeID()] = true
+		transferred[t.IncomeID()] = true
+	}
+
 	for _, ex := range expenses {
+		if transferred[ex.ID()] {
+			continue
+		}
+
 		categoryName := ""
 		if ex.CategoryID() != nil {
 			category, categoryError := storage.GetCategory(ctx, userID, *ex.CategoryID())
 
 			if categoryError != nil {
-				return expenseCategories, incomeCategories, incomeTotal, spendingTotal, categoryError
+				return expenseCategories, incomeCategories, incomeTotal, spendingTotal, unconverted, categoryError
 			}
 
 			if category.Name() == domain.ExcludeCategory {
@@ -97,13 +126,22 @@ func splitByExpenseType(
 			categoryBudgets[categoryName] = category.MonthlyBudget()
 		}
 
+		amount, converted, convertErr := converter.Convert(ctx, ex)
+		if convertErr != nil {
+			return expenseCategories, incomeCategories, incomeTotal, spendingTotal, unconverted, convertErr
+		}
+		if !converted {
+			unconverted++
+			continue
+		}
+
 		switch ex.Type() {
 		case domain.ChargeType:
-			spendingTotal += ex.Amount()
+			spendingTotal += amount
 		case domain.IncomeType:
-			incomeTotal += ex.Amount()
+			incomeTotal += amount
 		}
-		addExpenseToCategory(expenseCategoryMap, ex, categoryName)
+		addExpenseToCategory(expenseCategoryMap, ex, amount, categoryName)
 	}
 
 	for key, category := range expenseCategoryMap {
@@ -119,16 +157,13 @@ func splitByExpenseType(
 		// Find most recent transaction
 		if len(category.Expenses) > 0 {
 			category.LastTransaction = category.Expenses[0].Date()
-			// Calculate average amount
-			total := int64(0)
 			for _, exp := range category.Expenses {
-				total += exp.Amount()
-
 				if exp.Date().After(category.LastTransaction) {
 					category.LastTransaction = exp.Date()
 				}
 			}
-			category.AvgAmount = total / int64(len(category.Expenses))
+			// The amount is the total of the expenses, in the base currency
+			category.AvgAmount = category.Amount / int64(len(category.Expenses))
 		}
 
 		// Calculate budget information for expense categories only
@@ -157,20 +192,26 @@ func splitByExpenseType(
 		}
 	}
 
-	return expenseCategories, incomeCategories, incomeTotal, spendingTotal, nil
+	return expenseCategories, incomeCategories, incomeTotal, spendingTotal, unconverted, nil
 }
 
-func addExpenseToCategory(categories map[string]domain.CategoryReport, ex domain.Expense, categoryString string) {
+// addExpenseToCategory adds the expense to its category, amount being the
+// expense's amount in the base currency.
+func addExpenseToCategory(
+	categories map[string]domain.CategoryReport,
+	ex domain.Expense,
+	amount int64,
+	categoryString string,
+) {
 	categoryName := expeseCategoryName(ex, categoryString)
 
 	c, ok := categories[categoryName]
 	if ok {
-		c.Amount += ex.Amount()
+		c.Amount += amount
 		c.Expenses = append(c.Expenses, ex)
 
 		categories[categoryName] = c
 	} else {
-		amount := ex.Amount()
 		cat := domain.CategoryReport{
 			Amount: amount,
 			Name:   categoryName,
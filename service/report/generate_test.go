@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/service/exchange"
 	"github.com/GustavoCaso/expensetrace/testutil"
 )
 
@@ -18,7 +19,7 @@ func TestGenerate(t *testing.T) {
 	endDate := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, startDate, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, startDate, domain.ChargeType, nil, nil),
 		domain.NewExpense(
 			0,
 			"Test Source",
@@ -28,6 +29,7 @@ func TestGenerate(t *testing.T) {
 			startDate.Add(24*time.Hour),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -38,6 +40,7 @@ func TestGenerate(t *testing.T) {
 			startDate.Add(48*time.Hour),
 			domain.IncomeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -105,7 +108,7 @@ func TestCategories(t *testing.T) {
 
 	// Test with duplicate expenses
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, startDate, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "Restaurant bill", "USD", -123456, startDate, domain.ChargeType, nil, nil),
 		domain.NewExpense(
 			0,
 			"Test Source",
@@ -115,6 +118,7 @@ func TestCategories(t *testing.T) {
 			startDate.Add(24*time.Hour),
 			domain.ChargeType,
 			nil,
+			nil,
 		), // Duplicate description
 		domain.NewExpense(
 			0,
@@ -125,6 +129,7 @@ func TestCategories(t *testing.T) {
 			startDate.Add(48*time.Hour),
 			domain.IncomeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -135,6 +140,7 @@ func TestCategories(t *testing.T) {
 			startDate.Add(48*time.Hour),
 			domain.ChargeType,
 			&catID,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -145,13 +151,20 @@ func TestCategories(t *testing.T) {
 			startDate.Add(48*time.Hour),
 			domain.IncomeType,
 			&catID,
+			nil,
 		),
 	}
 
-	expenseCategories, incomeCategories, totalIncome, totalSpending, err := splitByExpenseType(
+	converter, err := exchange.NewConverter(context.Background(), s, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to create converter: %v", err)
+	}
+
+	expenseCategories, incomeCategories, totalIncome, totalSpending, _, err := splitByExpenseType(
 		context.Background(),
 		user.ID(),
 		s,
+		converter,
 		expenses,
 	)
 
@@ -19,20 +19,36 @@ import (
 type Service struct {
 	storage        storage.Storage
 	logger         *logger.Logger
-	reportsPerUser map[int64]map[string]domain.Report
+	reportsPerUser map[reportsKey]map[string]domain.Report
+}
+
+// reportsKey identifies the cached reports of a user, either of all their
+// expenses (account 0) or of the expenses of one account.
+type reportsKey struct {
+	userID    int64
+	accountID int64
+}
+
+func newReportsKey(userID int64, accountID *int64) reportsKey {
+	key := reportsKey{userID: userID}
+	if accountID != nil {
+		key.accountID = *accountID
+	}
+	return key
 }
 
 func New(storage storage.Storage, logger *logger.Logger) *Service {
 	return &Service{
 		storage:        storage,
 		logger:         logger,
-		reportsPerUser: map[int64]map[string]domain.Report{},
+		reportsPerUser: map[reportsKey]map[string]domain.Report{},
 	}
 }
 
 // Generate builds monthly reports for the user, walking backwards from the
 // current month to the month of the user's first expense, and caches them.
-func (s *Service) Generate(ctx context.Context, userID int64) {
+// A non-nil accountID limits the reports to the expenses of that account.
+func (s *Service) Generate(ctx context.Context, userID int64, accountID *int64) {
 	now := time.Now()
 	month := now.Month()
 	year := now.Year()
@@ -53,16 +69,7 @@ func (s *Service) Generate(ctx context.Context, userID int64) {
 			skipYear = true
 		}
 
-		firstDay, lastDay := util.GetMonthDates(int(month), year)
-
-		expenses, expenseErr := s.storage.GetExpensesFromDateRange(ctx, userID, firstDay, lastDay)
-
-		if expenseErr != nil {
-			s.logger.Warn("Failed to generate reports", "error", expenseErr, "userID", userID)
-			return
-		}
-
-		result, reportErr := generate(ctx, userID, firstDay, lastDay, s.storage, expenses, "monthly")
+		result, reportErr := Month(ctx, s.storage, userID, accountID, month, year)
 
 		if reportErr != nil {
 			s.logger.Warn("Failed to generate reports", "error", reportErr, "userID", userID)
@@ -85,13 +92,45 @@ func (s *Service) Generate(ctx context.Context, userID int64) {
 		month--
 	}
 
-	s.reportsPerUser[userID] = reports
+	s.reportsPerUser[newReportsKey(userID, accountID)] = reports
+}
+
+// Month builds the report of a month from the stored expenses, without the
+// reports cached by Generate. A non-nil accountID limits the report to the
+// expenses of that account.
+func Month(
+	ctx context.Context,
+	storage storage.Storage,
+	userID int64,
+	accountID *int64,
+	month time.Month,
+	year int,
+) (domain.Report, error) {
+	firstDay, lastDay := util.GetMonthDates(int(month), year)
+
+	var expenses []domain.Expense
+	var err error
+	if accountID != nil {
+		expenses, err = storage.GetExpensesFiltered(ctx, userID, &domain.ExpenseFilter{
+			DateFrom:  &firstDay,
+			DateTo:    &lastDay,
+			AccountID: accountID,
+		}, &domain.SortOptions{Field: domain.SortByDate, Direction: domain.SortAsc})
+	} else {
+		expenses, err = storage.GetExpensesFromDateRange(ctx, userID, firstDay, lastDay)
+	}
+	if err != nil {
+		return domain.Report{}, err
+	}
+
+	return generate(ctx, userID, firstDay, lastDay, storage, expenses, "monthly")
 }
 
 // ChartData returns the user's cached reports as chart data points, ordered
-// oldest-to-newest.
-func (s *Service) ChartData(userID int64) []domain.ChartDataPoint {
-	reports := s.reportsPerUser[userID]
+// oldest-to-newest. The URLs of the points keep the account the reports are
+// limited to.
+func (s *Service) ChartData(userID int64, accountID *int64) []domain.ChartDataPoint {
+	reports := s.reportsPerUser[newReportsKey(userID, accountID)]
 	reportKeys := slices.Collect(maps.Keys(reports))
 
 	sort.SliceStable(reportKeys, func(i, j int) bool {
@@ -115,11 +154,15 @@ func (s *Service) ChartData(userID int64) []domain.ChartDataPoint {
 	for _, key := range reportKeys {
 		parts := strings.Split(key, "-")
 		rep := reports[key]
+		url := fmt.Sprintf("/?month=%s&year=%s", parts[1], parts[0])
+		if accountID != nil {
+			url += fmt.Sprintf("&account=%d", *accountID)
+		}
 
 		chartData = append(chartData, domain.ChartDataPoint{
 			Month:             rep.Title,
 			Income:            rep.Income,
-			URL:               fmt.Sprintf("/?month=%s&year=%s", parts[1], parts[0]),
+			URL:               url,
 			Spending:          rep.Spending,
 			Savings:           rep.Savings,
 			SavingsPercentage: rep.SavingsPercentage,
@@ -136,6 +179,6 @@ func (s *Service) ChartData(userID int64) []domain.ChartDataPoint {
 
 // ForMonth returns the cached report for the given month/year, or a
 // zero-value report if none has been generated.
-func (s *Service) ForMonth(userID int64, month, year int) domain.Report {
-	return s.reportsPerUser[userID][fmt.Sprintf("%d-%d", year, month)]
+func (s *Service) ForMonth(userID int64, accountID *int64, month, year int) domain.Report {
+	return s.reportsPerUser[newReportsKey(userID, accountID)][fmt.Sprintf("%d-%d", year, month)]
 }
@@ -21,8 +21,8 @@ func TestGenerate_BuildsMonthlyReportsBackToFirstExpense(t *testing.T) {
 	twoMonthsAgo := now.AddDate(0, -2, 0)
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "current month expense", "USD", -1000, now, domain.ChargeType, nil),
-		domain.NewExpense(0, "Test Source", "old expense", "USD", -2000, twoMonthsAgo, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "current month expense", "USD", -1000, now, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Test Source", "old expense", "USD", -2000, twoMonthsAgo, domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -32,9 +32,9 @@ func TestGenerate_BuildsMonthlyReportsBackToFirstExpense(t *testing.T) {
 
 	svc := New(s, logger)
 
-	svc.Generate(context.Background(), user.ID())
+	svc.Generate(context.Background(), user.ID(), nil)
 
-	reports, found := svc.reportsPerUser[user.ID()]
+	reports, found := svc.reportsPerUser[newReportsKey(user.ID(), nil)]
 	if !found {
 		t.Fatal("Expected reports to be generated for user")
 	}
@@ -70,8 +70,8 @@ func TestChartData_OrdersOldestFirst(t *testing.T) {
 	twoMonthsAgo := now.AddDate(0, -2, 0)
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "current month expense", "USD", -1000, now, domain.ChargeType, nil),
-		domain.NewExpense(0, "Test Source", "old expense", "USD", -2000, twoMonthsAgo, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "current month expense", "USD", -1000, now, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Test Source", "old expense", "USD", -2000, twoMonthsAgo, domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -80,9 +80,9 @@ func TestChartData_OrdersOldestFirst(t *testing.T) {
 	}
 
 	svc := New(s, logger)
-	svc.Generate(context.Background(), user.ID())
+	svc.Generate(context.Background(), user.ID(), nil)
 
-	chartData := svc.ChartData(user.ID())
+	chartData := svc.ChartData(user.ID(), nil)
 
 	if len(chartData) != 3 {
 		t.Fatalf("Expected 3 chart points, got %d", len(chartData))
@@ -111,7 +111,7 @@ func TestForMonth_ReturnsCachedReport(t *testing.T) {
 	now := time.Now()
 
 	expenses := []domain.Expense{
-		domain.NewExpense(0, "Test Source", "current month expense", "USD", -1000, now, domain.ChargeType, nil),
+		domain.NewExpense(0, "Test Source", "current month expense", "USD", -1000, now, domain.ChargeType, nil, nil),
 	}
 
 	_, err := s.InsertExpenses(context.Background(), user.ID(), expenses)
@@ -120,11 +120,145 @@ func TestForMonth_ReturnsCachedReport(t *testing.T) {
 	}
 
 	svc := New(s, logger)
-	svc.Generate(context.Background(), user.ID())
+	svc.Generate(context.Background(), user.ID(), nil)
 
-	rep := svc.ForMonth(user.ID(), int(now.Month()), now.Year())
+	rep := svc.ForMonth(user.ID(), nil, int(now.Month()), now.Year())
 
 	if rep.Spending != -1000 {
 		t.Errorf("Expected spending -1000, got %d", rep.Spending)
 	}
 }
+
+func TestForMonth_LimitedToAccount(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+
+	account, err := s.CreateAccount(context.Background(), user.ID(), domain.NewAccount(
+		0, "Credit card", "", "USD", domain.CreditAccount, 0, time.Time{},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	accountID := account.ID()
+
+	now := time.Now()
+
+	expenses := []domain.Expense{
+		domain.NewExpense(0, "Test Source", "card expense", "USD", -1000, now, domain.ChargeType, nil, &accountID),
+		domain.NewExpense(0, "Test Source", "cash expense", "USD", -500, now, domain.ChargeType, nil, nil),
+	}
+
+	_, err = s.InsertExpenses(context.Background(), user.ID(), expenses)
+	if err != nil {
+		t.Fatalf("Failed to insert test expenses: %v", err)
+	}
+
+	svc := New(s, logger)
+	svc.Generate(context.Background(), user.ID(), nil)
+	svc.Generate(context.Background(), user.ID(), &accountID)
+
+	if rep := svc.ForMonth(user.ID(), nil, int(now.Month()), now.Year()); rep.Spending != -1500 {
+		t.Errorf("Expected spending -1500 for every account, got %d", rep.Spending)
+	}
+
+	if rep := svc.ForMonth(user.ID(), &accountID, int(now.Month()), now.Year()); rep.Spending != -1000 {
+		t.Errorf("Expected spending -1000 for the account, got %d", rep.Spending)
+	}
+
+	chartData := svc.ChartData(user.ID(), &accountID)
+	if len(chartData) == 0 || !strings.HasSuffix(chartData[0].URL, fmt.Sprintf("&account=%d", accountID)) {
+		t.Errorf("Expected the chart URLs to keep the account, got %+v", chartData)
+	}
+}
+
+func TestMonth_ExcludesTransfers(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	now := time.Now()
+
+	_, err := s.InsertExpenses(ctx, user.ID(), []domain.Expense{
+		domain.NewExpense(0, "checking", "to savings", "USD", -50000, now, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "savings", "from checking", "USD", 50000, now, domain.IncomeType, nil, nil),
+		domain.NewExpense(0, "checking", "groceries", "USD", -2000, now, domain.ChargeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert test expenses: %v", err)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	_, err = s.CreateTransfer(ctx, user.ID(), domain.NewTransfer(0, expenses[0].ID(), expenses[1].ID(), time.Time{}))
+	if err != nil {
+		t.Fatalf("Failed to create transfer: %v", err)
+	}
+
+	rep, err := Month(ctx, s, user.ID(), nil, now.Month(), now.Year())
+	if err != nil {
+		t.Fatalf("Month returned error: %v", err)
+	}
+
+	if rep.Spending != -2000 {
+		t.Errorf("Expected spending -2000 without the transfer, got %d", rep.Spending)
+	}
+	if rep.Income != 0 {
+		t.Errorf("Expected no income without the transfer, got %d", rep.Income)
+	}
+}
+
+func TestMonth_ConvertsToBaseCurrency(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	now := time.Now()
+
+	_, err := s.InsertExpenses(ctx, user.ID(), []domain.Expense{
+		domain.NewExpense(0, "bank", "groceries", "EUR", -2000, now, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "card", "hotel", "USD", -5000, now, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "card", "taxi", "JPY", -300, now, domain.ChargeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert test expenses: %v", err)
+	}
+
+	_, err = s.SaveExchangeRates(ctx, user.ID(), []domain.ExchangeRate{
+		domain.NewExchangeRate(0, now.AddDate(0, -1, 0), "EUR", "USD", 1.25),
+	})
+	if err != nil {
+		t.Fatalf("Failed to save exchange rates: %v", err)
+	}
+
+	if err = s.UpdateBaseCurrency(ctx, user.ID(), "EUR"); err != nil {
+		t.Fatalf("Failed to update base currency: %v", err)
+	}
+
+	rep, err := Month(ctx, s, user.ID(), nil, now.Month(), now.Year())
+	if err != nil {
+		t.Fatalf("Month returned error: %v", err)
+	}
+
+	// 20 EUR and 50 USD as 40 EUR, 300 JPY without a rate are left out
+	if rep.Spending != -6000 {
+		t.Errorf("Expected spending -6000, got %d", rep.Spending)
+	}
+	if rep.Currency != "EUR" {
+		t.Errorf("Expected the report in EUR, got %q", rep.Currency)
+	}
+	if rep.Unconverted != 1 {
+		t.Errorf("Expected 1 unconverted expense, got %d", rep.Unconverted)
+	}
+
+	// The expenses keep their original amounts
+	for _, category := range rep.ExpenseCategories {
+		for _, ex := range category.Expenses {
+			if ex.Currency() == "USD" && ex.Amount() != -5000 {
+				t.Errorf("Expected the original USD amount, got %d", ex.Amount())
+			}
+		}
+	}
+}
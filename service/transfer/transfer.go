@@ -0,0 +1,217 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/logger"
+	"github.com/GustavoCaso/expensetrace/storage"
+)
+
+// WindowDays is how many days apart the two sides of a transfer can be
+// booked. Banks don't always book both on the same day.
+const WindowDays = 3
+
+const hoursInDay = 24
+
+type Service struct {
+	storage storage.Storage
+	logger  *logger.Logger
+}
+
+func New(storage storage.Storage, logger *logger.Logger) *Service {
+	return &Service{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// List returns the user's linked transfers, most recently linked first, and
+// the detected pairs of expenses that look like transfers but are not
+// linked yet.
+func (s *Service) List(ctx context.Context, userID int64) ([]domain.TransferPair, []domain.TransferPair, error) {
+	expenses, err := s.storage.GetAllExpenseTypes(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transfers, err := s.storage.GetTransfers(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[int64]domain.Expense, len(expenses))
+	for _, ex := range expenses {
+		byID[ex.ID()] = ex
+	}
+
+	linked := make(map[int64]bool, len(transfers)*2)
+	pairs := make([]domain.TransferPair, 0, len(transfers))
+	for _, t := range transfers {
+		linked[t.ChargeID()] = true
+		linked[t.IncomeID()] = true
+		pairs = append(pairs, domain.TransferPair{
+			ID:     t.ID(),
+			Charge: byID[t.ChargeID()],
+			Income: byID[t.IncomeID()],
+		})
+	}
+
+	return pairs, detect(expenses, linked), nil
+}
+
+// Link links a charge and an income as the two sides of a transfer.
+func (s *Service) Link(ctx context.Context, userID, chargeID, incomeID int64) (domain.Transfer, error) {
+	charge, err := s.storage.GetExpenseByID(ctx, userID, chargeID)
+	if err != nil {
+		return nil, err
+	}
+
+	income, err := s.storage.GetExpenseByID(ctx, userID, incomeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if charge.Type() != domain.ChargeType || income.Type() != domain.IncomeType {
+		return nil, errors.New("a transfer links a charge and an income")
+	}
+	if charge.Amount() != -income.Amount() || charge.Currency() != income.Currency() {
+		return nil, errors.New("the charge and the income of a transfer must have the same amount and currency")
+	}
+
+	transfers, err := s.storage.GetTransfers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transfers {
+		if t.ChargeID() == chargeID || t.IncomeID() == incomeID {
+			return nil, errors.New("the expense is already part of a transfer")
+		}
+	}
+
+	transfer, err := s.storage.CreateTransfer(ctx, userID, domain.NewTransfer(0, chargeID, incomeID, time.Time{}))
+	if err != nil {
+		s.logger.Error("Failed to link transfer", "error", err, "user_id", userID)
+		return nil, err
+	}
+
+	s.logger.Info("Transfer linked", "user_id", userID, "transfer_id", transfer.ID())
+	return transfer, nil
+}
+
+// LinkDetected links every detected pair, returning how many were linked.
+func (s *Service) LinkDetected(ctx context.Context, userID int64) (int, error) {
+	_, candidates, err := s.List(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, candidate := range candidates {
+		_, err = s.storage.CreateTransfer(ctx, userID, domain.NewTransfer(
+			0,
+			candidate.Charge.ID(),
+			candidate.Income.ID(),
+			time.Time{},
+		))
+		if err != nil {
+			s.logger.Error("Failed to link transfer", "error", err, "user_id", userID)
+			return i, fmt.Errorf("failed to link transfer: %w", err)
+		}
+	}
+
+	s.logger.Info("Detected transfers linked", "user_id", userID, "count", len(candidates))
+	return len(candidates), nil
+}
+
+// Unlink removes a transfer. Its expenses are kept and count again as
+// spending and income.
+func (s *Service) Unlink(ctx context.Context, userID, id int64) error {
+	deleted, err := s.storage.DeleteTransfer(ctx, userID, id)
+	if err != nil {
+		s.logger.Error("Failed to unlink transfer", "error", err, "user_id", userID)
+		return err
+	}
+	if deleted == 0 {
+		return &domain.NotFoundError{}
+	}
+
+	s.logger.Info("Transfer unlinked", "user_id", userID, "transfer_id", id)
+	return nil
+}
+
+// detect pairs each charge with an income of the same amount and currency
+// booked in another account, or from another source for expenses without an
+// account, at most WindowDays apart. Each expense is used once, charges
+// picking the closest income by date.
+func detect(expenses []domain.Expense, linked map[int64]bool) []domain.TransferPair {
+	incomes := map[matchKey][]domain.Expense{}
+	charges := []domain.Expense{}
+	for _, ex := range expenses {
+		if linked[ex.ID()] || ex.Amount() == 0 {
+			continue
+		}
+		switch ex.Type() {
+		case domain.ChargeType:
+			charges = append(charges, ex)
+		case domain.IncomeType:
+			key := matchKey{amount: ex.Amount(), currency: ex.Currency()}
+			incomes[key] = append(incomes[key], ex)
+		}
+	}
+
+	sort.SliceStable(charges, func(i, j int) bool {
+		if charges[i].Date().Equal(charges[j].Date()) {
+			return charges[i].ID() < charges[j].ID()
+		}
+		return charges[i].Date().Before(charges[j].Date())
+	})
+
+	window := WindowDays * hoursInDay * time.Hour
+	used := map[int64]bool{}
+	pairs := []domain.TransferPair{}
+	for _, charge := range charges {
+		var match domain.Expense
+		var matchGap time.Duration
+		for _, income := range incomes[matchKey{amount: -charge.Amount(), currency: charge.Currency()}] {
+			if used[income.ID()] || sameOrigin(charge, income) {
+				continue
+			}
+
+			gap := income.Date().Sub(charge.Date()).Abs()
+			if gap > window {
+				continue
+			}
+			if match == nil || gap < matchGap {
+				match = income
+				matchGap = gap
+			}
+		}
+
+		if match != nil {
+			used[match.ID()] = true
+			pairs = append(pairs, domain.TransferPair{Charge: charge, Income: match})
+		}
+	}
+
+	return pairs
+}
+
+// matchKey groups the incomes a charge can be paired with.
+type matchKey struct {
+	amount   int64
+	currency string
+}
+
+// sameOrigin reports whether both expenses were booked in the same account,
+// or by the same source when either of them has no account.
+func sameOrigin(a, b domain.Expense) bool {
+	if a.AccountID() != nil && b.AccountID() != nil {
+		return *a.AccountID() == *b.AccountID()
+	}
+	return strings.EqualFold(strings.TrimSpace(a.Source()), strings.TrimSpace(b.Source()))
+}
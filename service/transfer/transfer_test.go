@@ -0,0 +1,134 @@
+package transfer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, time.June, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDetect(t *testing.T) {
+	expenses := []domain.Expense{
+		domain.NewExpense(1, "checking", "to savings", "EUR", -50000, day(1), domain.ChargeType, nil, nil),
+		// Same source, not a transfer
+		domain.NewExpense(2, "checking", "refund", "EUR", 50000, day(1), domain.IncomeType, nil, nil),
+		// Too far apart
+		domain.NewExpense(3, "savings", "from checking", "EUR", 50000, day(10), domain.IncomeType, nil, nil),
+		// The closest match wins
+		domain.NewExpense(4, "Savings", "from checking", "EUR", 50000, day(3), domain.IncomeType, nil, nil),
+		domain.NewExpense(5, "savings", "from checking", "EUR", 50000, day(2), domain.IncomeType, nil, nil),
+		// Different amount
+		domain.NewExpense(6, "card", "card payment", "EUR", -1999, day(5), domain.ChargeType, nil, nil),
+		domain.NewExpense(7, "checking", "card bill", "EUR", 2000, day(5), domain.IncomeType, nil, nil),
+		// Different currency
+		domain.NewExpense(8, "checking", "to brokerage", "EUR", -30000, day(6), domain.ChargeType, nil, nil),
+		domain.NewExpense(9, "brokerage", "deposit", "USD", 30000, day(6), domain.IncomeType, nil, nil),
+	}
+
+	pairs := detect(expenses, map[int64]bool{})
+	if len(pairs) != 1 {
+		t.Fatalf("Expected 1 detected transfer, got %d", len(pairs))
+	}
+	if pairs[0].Charge.ID() != 1 || pairs[0].Income.ID() != 5 {
+		t.Errorf("Expected expenses 1 and 5 to be paired, got %d and %d", pairs[0].Charge.ID(), pairs[0].Income.ID())
+	}
+
+	// Linked expenses are not detected again
+	pairs = detect(expenses, map[int64]bool{1: true, 5: true})
+	if len(pairs) != 0 {
+		t.Errorf("Expected no detected transfers, got %d", len(pairs))
+	}
+}
+
+func TestDetect_Accounts(t *testing.T) {
+	checking := int64(1)
+	savings := int64(2)
+
+	expenses := []domain.Expense{
+		// Same account, even from different sources
+		domain.NewExpense(1, "bank", "to savings", "EUR", -50000, day(1), domain.ChargeType, nil, &checking),
+		domain.NewExpense(2, "bank app", "refund", "EUR", 50000, day(1), domain.IncomeType, nil, &checking),
+		// Another account, even from the same source
+		domain.NewExpense(3, "bank", "from checking", "EUR", 50000, day(2), domain.IncomeType, nil, &savings),
+	}
+
+	pairs := detect(expenses, map[int64]bool{})
+	if len(pairs) != 1 {
+		t.Fatalf("Expected 1 detected transfer, got %d", len(pairs))
+	}
+	if pairs[0].Charge.ID() != 1 || pairs[0].Income.ID() != 3 {
+		t.Errorf("Expected expenses 1 and 3 to be paired, got %d and %d", pairs[0].Charge.ID(), pairs[0].Income.ID())
+	}
+}
+
+func TestServiceLink(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	_, err := s.InsertExpenses(ctx, user.ID(), []domain.Expense{
+		domain.NewExpense(0, "checking", "to savings", "EUR", -50000, day(1), domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "savings", "from checking", "EUR", 50000, day(2), domain.IncomeType, nil, nil),
+		domain.NewExpense(0, "savings", "interest", "EUR", 120, day(2), domain.IncomeType, nil, nil),
+		domain.NewExpense(0, "brokerage", "deposit", "USD", 50000, day(2), domain.IncomeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	charge, income, interest, deposit := expenses[0], expenses[1], expenses[2], expenses[3]
+
+	svc := New(s, logger)
+
+	if _, err = svc.Link(ctx, user.ID(), charge.ID(), interest.ID()); err == nil {
+		t.Error("Expected an error linking expenses with different amounts")
+	}
+	if _, err = svc.Link(ctx, user.ID(), charge.ID(), deposit.ID()); err == nil {
+		t.Error("Expected an error linking expenses in different currencies")
+	}
+	if _, err = svc.Link(ctx, user.ID(), income.ID(), charge.ID()); err == nil {
+		t.Error("Expected an error linking an income as the charge")
+	}
+
+	transfer, err := svc.Link(ctx, user.ID(), charge.ID(), income.ID())
+	if err != nil {
+		t.Fatalf("Link returned error: %v", err)
+	}
+
+	transfers, candidates, err := svc.List(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(transfers) != 1 || transfers[0].ID != transfer.ID() {
+		t.Fatalf("Expected the linked transfer, got %+v", transfers)
+	}
+	if transfers[0].Charge.ID() != charge.ID() || transfers[0].Income.ID() != income.ID() {
+		t.Errorf("Expected the transfer expenses, got %d and %d",
+			transfers[0].Charge.ID(), transfers[0].Income.ID())
+	}
+	if len(candidates) != 0 {
+		t.Errorf("Expected no detected transfers, got %d", len(candidates))
+	}
+
+	if err = svc.Unlink(ctx, user.ID(), transfer.ID()); err != nil {
+		t.Fatalf("Unlink returned error: %v", err)
+	}
+
+	linked, err := svc.LinkDetected(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("LinkDetected returned error: %v", err)
+	}
+	if linked != 1 {
+		t.Errorf("Expected 1 detected transfer to be linked, got %d", linked)
+	}
+}
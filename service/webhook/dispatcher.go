@@ -0,0 +1,261 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/logger"
+	"github.com/GustavoCaso/expensetrace/storage"
+)
+
+// Headers of every delivery.
+const (
+	// SignatureHeader holds Sign of the delivery body.
+	SignatureHeader = "X-Expensetrace-Signature"
+	// TimestampHeader holds the Unix time the delivery was signed at.
+	TimestampHeader = "X-Expensetrace-Timestamp"
+	EventHeader     = "X-Expensetrace-Event"
+	// DeliveryHeader identifies the delivery, the same on every attempt.
+	DeliveryHeader = "X-Expensetrace-Delivery"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is posted before it is
+	// given up.
+	MaxAttempts = 8
+	// retryBackoff is the wait before the first retry, doubled on every
+	// retry after it. With MaxAttempts, deliveries are retried for about an
+	// hour.
+	retryBackoff = 30 * time.Second
+	// deliveryTimeout bounds how long a receiver takes to answer.
+	deliveryTimeout = 10 * time.Second
+	// dispatchBatchSize is how many deliveries are posted per check.
+	dispatchBatchSize = 50
+	// deliveryRetention is how long finished deliveries are kept in the log.
+	deliveryRetention = 30 * 24 * time.Hour
+	// pruneInterval is how often deliveries past their retention are deleted.
+	pruneInterval = 24 * time.Hour
+)
+
+// errNonPublicAddress is returned when a webhook URL resolves to a loopback,
+// private or link-local address, which would let users reach services only
+// the server can reach.
+var errNonPublicAddress = errors.New("webhook URL must resolve to a public address")
+
+// Dispatcher posts the queued deliveries to their webhooks.
+type Dispatcher struct {
+	storage  storage.Storage
+	logger   *logger.Logger
+	client   *http.Client
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewDispatcher(storage storage.Storage, logger *logger.Logger, interval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		storage:  storage,
+		logger:   logger,
+		client:   newDeliveryClient(),
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Run posts the due deliveries every interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.logger.Info("Dispatching webhook deliveries", "interval", d.interval.String())
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		if err := d.Dispatch(ctx); err != nil {
+			d.logger.Error("Failed to dispatch webhook deliveries", "error", err)
+		}
+
+		if d.now().Sub(pruned) > pruneInterval {
+			d.prune(ctx)
+			pruned = d.now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch posts the deliveries whose next attempt is due, a batch at a
+// time. Deliveries left over are posted on the next check.
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	deliveries, err := d.storage.GetDueWebhookDeliveries(ctx, d.now(), dispatchBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return nil
+		}
+		d.deliver(ctx, delivery)
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery domain.WebhookDelivery) {
+	attempt := d.post(ctx, delivery)
+
+	status := domain.WebhookDeliveryDelivered
+	nextAttemptAt := attempt.At
+	attempts := delivery.Attempts() + 1
+	if attempt.Error != "" {
+		status = domain.WebhookDeliveryPending
+		nextAttemptAt = attempt.At.Add(backoff(attempts))
+		if attempts >= MaxAttempts {
+			status = domain.WebhookDeliveryFailed
+		}
+
+		d.logger.Warn(
+			"Webhook delivery failed",
+			"delivery_id", delivery.ID(),
+			"webhook_id", delivery.Webhook().ID(),
+			"attempts", attempts,
+			"error", attempt.Error,
+		)
+	}
+
+	err := d.storage.RecordWebhookAttempt(ctx, delivery.ID(), status, attempt, nextAttemptAt)
+	if err != nil {
+		d.logger.Error("Failed to record webhook attempt", "delivery_id", delivery.ID(), "error", err)
+	}
+}
+
+// post posts the delivery to its webhook. Receivers accept deliveries by
+// answering with a 2xx status.
+func (d *Dispatcher) post(ctx context.Context, delivery domain.WebhookDelivery) domain.WebhookAttempt {
+	attempt := domain.WebhookAttempt{At: d.now()}
+	webhook := delivery.Webhook()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL(), bytes.NewReader(delivery.Payload()))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	timestamp := attempt.At.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "expensetrace-webhooks")
+	req.Header.Set(EventHeader, delivery.Event())
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID(), 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret(), timestamp, delivery.Payload()))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	// Only the status is recorded, the body of the response is never shown
+	// to the user
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		attempt.Error = fmt.Sprintf("unexpected status %s", resp.Status)
+	}
+	// Drain the body so the connection is reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return attempt
+}
+
+// newDeliveryClient returns a client that only connects to public
+// addresses. The address is checked when dialing, after DNS resolution, so
+// host names resolving to internal addresses are rejected too. Redirects are
+// not followed, they count as a failed delivery.
+func newDeliveryClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: deliveryTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !isPublicAddress(ip) {
+				return errNonPublicAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:errcheck // always an *http.Transport
+	// A proxy would connect to the webhook on our behalf, skipping the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   deliveryTimeout,
+		Transport: transport,
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, not routable on the
+// internet either.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublicAddress reports whether ip can be reached on the internet: not
+// loopback, private, link-local (like cloud metadata services), multicast
+// or unspecified.
+func isPublicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+func (d *Dispatcher) prune(ctx context.Context) {
+	deleted, err := d.storage.DeleteWebhookDeliveriesBefore(ctx, d.now().Add(-deliveryRetention))
+	if err != nil {
+		d.logger.Error("Failed to prune webhook deliveries", "error", err)
+		return
+	}
+
+	if deleted > 0 {
+		d.logger.Info("Pruned webhook deliveries", "deleted", deleted)
+	}
+}
+
+// backoff is the wait after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	return retryBackoff << (attempts - 1)
+}
+
+// Sign returns the signature of a delivery body: the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook secret, prefixed by
+// "sha256=". Receivers recompute it to check the delivery comes from the
+// app, and reject old timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
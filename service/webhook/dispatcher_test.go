@@ -0,0 +1,256 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func TestDispatch(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	status := http.StatusInternalServerError
+	var received *http.Request
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("receiver down"))
+	}))
+	defer server.Close()
+
+	// The test server listens on loopback, which Create refuses
+	svc := New(s, logger)
+	webhook, err := s.CreateWebhook(ctx, user.ID(), server.URL, "secret", []string{domain.EventExpenseDeleted})
+	if err != nil {
+		t.Fatalf("CreateWebhook returned error: %v", err)
+	}
+	svc.Publish(ctx, user.ID(), domain.EventExpenseDeleted, ExpenseDeleted{ID: 3})
+
+	now := time.Now()
+	d := NewDispatcher(s, logger, time.Second)
+	d.client = server.Client()
+	d.now = func() time.Time { return now }
+
+	if err = d.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch returned error: %v", err)
+	}
+
+	deliveries, err := svc.Deliveries(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Deliveries returned error: %v", err)
+	}
+	delivery := deliveries[0]
+	if delivery.Status() != domain.WebhookDeliveryPending || delivery.Attempts() != 1 ||
+		delivery.LastAttempt().StatusCode != http.StatusInternalServerError ||
+		delivery.LastAttempt().Error != "unexpected status 500 Internal Server Error" {
+		t.Errorf("Expected the failed attempt to be recorded, got %s %d %+v",
+			delivery.Status(), delivery.Attempts(), delivery.LastAttempt())
+	}
+	if delivery.NextAttemptAt().Unix() != now.Add(retryBackoff).Unix() {
+		t.Errorf("Expected a retry after %s, got %s", retryBackoff, delivery.NextAttemptAt())
+	}
+
+	// Not due yet
+	received = nil
+	if err = d.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch returned error: %v", err)
+	}
+	if received != nil {
+		t.Fatal("Expected the delivery to wait for its retry")
+	}
+
+	status = http.StatusNoContent
+	now = now.Add(retryBackoff)
+	if err = d.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch returned error: %v", err)
+	}
+	if received == nil {
+		t.Fatal("Expected the delivery to be retried")
+	}
+
+	timestamp := received.Header.Get(TimestampHeader)
+	if timestamp != strconv.FormatInt(now.Unix(), 10) {
+		t.Errorf("Expected the timestamp of the attempt, got %q", timestamp)
+	}
+	if received.Header.Get(SignatureHeader) != Sign(webhook.Secret(), now.Unix(), receivedBody) {
+		t.Errorf("Expected the body to be signed, got %q", received.Header.Get(SignatureHeader))
+	}
+	if received.Header.Get(EventHeader) != domain.EventExpenseDeleted ||
+		received.Header.Get(DeliveryHeader) != strconv.FormatInt(delivery.ID(), 10) ||
+		received.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Got the wrong headers %v", received.Header)
+	}
+	if string(receivedBody) != string(delivery.Payload()) {
+		t.Errorf("Expected the queued payload, got %s", receivedBody)
+	}
+
+	deliveries, err = svc.Deliveries(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Deliveries returned error: %v", err)
+	}
+	if deliveries[0].Status() != domain.WebhookDeliveryDelivered || deliveries[0].Attempts() != 2 ||
+		deliveries[0].LastAttempt().Error != "" {
+		t.Errorf("Expected the delivery to be delivered, got %s %d %+v",
+			deliveries[0].Status(), deliveries[0].Attempts(), deliveries[0].LastAttempt())
+	}
+
+	// Delivered deliveries are pruned once past their retention
+	now = now.Add(deliveryRetention + time.Hour)
+	d.prune(ctx)
+	deliveries, err = svc.Deliveries(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Deliveries returned error: %v", err)
+	}
+	if len(deliveries) != 0 {
+		t.Errorf("Expected the delivery to be pruned, got %d", len(deliveries))
+	}
+}
+
+func TestDispatchGivesUp(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	svc := New(s, logger)
+	_, err := s.CreateWebhook(ctx, user.ID(), server.URL, "secret", []string{domain.EventExpenseDeleted})
+	if err != nil {
+		t.Fatalf("CreateWebhook returned error: %v", err)
+	}
+	svc.Publish(ctx, user.ID(), domain.EventExpenseDeleted, ExpenseDeleted{ID: 3})
+
+	now := time.Now()
+	d := NewDispatcher(s, logger, time.Second)
+	d.client = server.Client()
+	d.now = func() time.Time { return now }
+
+	for range MaxAttempts + 2 {
+		if err := d.Dispatch(ctx); err != nil {
+			t.Fatalf("Dispatch returned error: %v", err)
+		}
+		now = now.Add(24 * time.Hour)
+	}
+
+	if attempts != MaxAttempts {
+		t.Errorf("Expected %d attempts, got %d", MaxAttempts, attempts)
+	}
+
+	deliveries, err := svc.Deliveries(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Deliveries returned error: %v", err)
+	}
+	if deliveries[0].Status() != domain.WebhookDeliveryFailed || deliveries[0].Attempts() != MaxAttempts {
+		t.Errorf("Expected the delivery to fail after %d attempts, got %s after %d",
+			MaxAttempts, deliveries[0].Status(), deliveries[0].Attempts())
+	}
+}
+
+func TestDispatchRefusesNonPublicAddresses(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	received := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		received = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// The address is checked once the host name is resolved
+	hostURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	if _, err := s.CreateWebhook(ctx, user.ID(), hostURL, "secret", []string{domain.EventExpenseDeleted}); err != nil {
+		t.Fatalf("CreateWebhook returned error: %v", err)
+	}
+	svc := New(s, logger)
+	svc.Publish(ctx, user.ID(), domain.EventExpenseDeleted, ExpenseDeleted{ID: 3})
+
+	d := NewDispatcher(s, logger, time.Second)
+	if err := d.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch returned error: %v", err)
+	}
+	if received {
+		t.Fatal("Expected the delivery to a loopback address to be refused")
+	}
+
+	deliveries, err := svc.Deliveries(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Deliveries returned error: %v", err)
+	}
+	if !strings.Contains(deliveries[0].LastAttempt().Error, errNonPublicAddress.Error()) {
+		t.Errorf("Expected the attempt to fail on the address, got %+v", deliveries[0].LastAttempt())
+	}
+}
+
+func TestDispatchDoesNotFollowRedirects(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	redirected := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			redirected = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	}))
+	defer server.Close()
+
+	_, err := s.CreateWebhook(ctx, user.ID(), server.URL, "secret", []string{domain.EventExpenseDeleted})
+	if err != nil {
+		t.Fatalf("CreateWebhook returned error: %v", err)
+	}
+	svc := New(s, logger)
+	svc.Publish(ctx, user.ID(), domain.EventExpenseDeleted, ExpenseDeleted{ID: 3})
+
+	// Loopback is allowed here to reach the test server, redirects are not
+	d := NewDispatcher(s, logger, time.Second)
+	client := server.Client()
+	client.CheckRedirect = d.client.CheckRedirect
+	d.client = client
+	if err := d.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch returned error: %v", err)
+	}
+	if redirected {
+		t.Fatal("Expected the redirect not to be followed")
+	}
+
+	deliveries, err := svc.Deliveries(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Deliveries returned error: %v", err)
+	}
+	if deliveries[0].LastAttempt().StatusCode != http.StatusFound ||
+		deliveries[0].Status() != domain.WebhookDeliveryPending {
+		t.Errorf("Expected the redirect to fail the attempt, got %s %+v",
+			deliveries[0].Status(), deliveries[0].LastAttempt())
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Computed with: printf '1700000000.{"event":"expense.created"}' | openssl dgst -sha256 -hmac whsec_test
+	want := "sha256=6d0b7363b062cb4a33f5ea3b2fac0679b1d965b20eecc781380b406d26035eef"
+
+	got := Sign("whsec_test", 1700000000, []byte(`{"event":"expense.created"}`))
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
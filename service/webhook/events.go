@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+// The data of the events. expense.created and expense.updated events hold
+// the expense, encoded as the API does.
+
+// ExpenseDeleted is the data of expense.deleted events.
+type ExpenseDeleted struct {
+	ID int64 `json:"id"`
+}
+
+// Category is the data of category.updated events.
+type Category struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Pattern       string `json:"pattern"`
+	MonthlyBudget int64  `json:"monthly_budget"`
+}
+
+func NewCategory(c domain.Category) Category {
+	return Category{
+		ID:            c.ID(),
+		Name:          c.Name(),
+		Pattern:       c.Pattern(),
+		MonthlyBudget: c.MonthlyBudget(),
+	}
+}
+
+// ImportCompleted is the data of import.completed events.
+type ImportCompleted struct {
+	ImportBatchID     int64  `json:"import_batch_id"`
+	Filename          string `json:"filename"`
+	Imported          int    `json:"imported"`
+	WithoutCategory   int    `json:"without_category"`
+	ErrorRows         int    `json:"error_rows"`
+	SkippedDuplicates int    `json:"skipped_duplicates"`
+}
+
+// BudgetExceeded is the data of budget.exceeded events. Month is formatted
+// as YYYY-MM.
+type BudgetExceeded struct {
+	Category string            `json:"category"`
+	Month    string            `json:"month"`
+	Budget   domain.BudgetInfo `json:"budget"`
+}
@@ -0,0 +1,217 @@
+// Package webhook posts the events of a user's data to the webhooks they
+// subscribe.
+//
+// Events are not posted right away: Publish queues a delivery for every
+// subscribed webhook in storage, and the Dispatcher posts the queued
+// deliveries, retrying the failed ones. Deliveries survive restarts, and
+// are kept as the delivery log shown to the user.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/logger"
+	"github.com/GustavoCaso/expensetrace/service/report"
+	"github.com/GustavoCaso/expensetrace/storage"
+	"github.com/GustavoCaso/expensetrace/util"
+)
+
+const (
+	// SecretPrefix starts every signing secret.
+	SecretPrefix = "whsec_"
+	// secretBytes is the randomness of a signing secret.
+	secretBytes = 32
+	// eventIDBytes is the randomness of the ID of an event.
+	eventIDBytes = 16
+	maxURLLength = 2048
+	// deliveryLogSize is how many deliveries the delivery log lists.
+	deliveryLogSize = 50
+)
+
+// Event is the body posted to webhooks. Every webhook subscribed to an event
+// gets the same ID, Data depends on the event.
+type Event struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type Service struct {
+	storage storage.Storage
+	logger  *logger.Logger
+}
+
+func New(storage storage.Storage, logger *logger.Logger) *Service {
+	return &Service{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// Create adds a webhook posting the given events to rawURL, signed with a
+// new secret.
+func (s *Service) Create(ctx context.Context, userID int64, rawURL string, events []string) (domain.Webhook, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if err := validateURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return nil, errors.New("select at least one event")
+	}
+	for _, event := range events {
+		if !slices.Contains(domain.WebhookEvents, event) {
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+	}
+
+	// Stored in the order of domain.WebhookEvents, without repeats
+	subscribed := []string{}
+	for _, event := range domain.WebhookEvents {
+		if slices.Contains(events, event) {
+			subscribed = append(subscribed, event)
+		}
+	}
+
+	secret := SecretPrefix + util.GenerateRandomID(secretBytes)
+	webhook, err := s.storage.CreateWebhook(ctx, userID, rawURL, secret, subscribed)
+	if err != nil {
+		s.logger.Error("Failed to create webhook", "error", err, "user_id", userID)
+		return nil, err
+	}
+
+	s.logger.Info("Webhook created", "user_id", userID, "webhook_id", webhook.ID())
+	return webhook, nil
+}
+
+func validateURL(rawURL string) error {
+	if rawURL == "" {
+		return errors.New("webhook URL is required")
+	}
+	if len(rawURL) > maxURLLength {
+		return errors.New("webhook URL must be at most 2048 characters long")
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return errors.New("webhook URL must be an http or https URL")
+	}
+
+	// Host names are checked again when delivering, once resolved
+	host := parsed.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return errNonPublicAddress
+	}
+	if ip, parseErr := netip.ParseAddr(host); parseErr == nil && !isPublicAddress(ip) {
+		return errNonPublicAddress
+	}
+
+	return nil
+}
+
+// List returns the user's webhooks, newest first.
+func (s *Service) List(ctx context.Context, userID int64) ([]domain.Webhook, error) {
+	return s.storage.GetWebhooks(ctx, userID)
+}
+
+// Delete deletes one of the user's webhooks along with its deliveries.
+func (s *Service) Delete(ctx context.Context, userID, id int64) error {
+	deleted, err := s.storage.DeleteWebhook(ctx, userID, id)
+	if err != nil {
+		s.logger.Error("Failed to delete webhook", "error", err, "user_id", userID)
+		return err
+	}
+	if deleted == 0 {
+		return &domain.NotFoundError{}
+	}
+
+	s.logger.Info("Webhook deleted", "user_id", userID, "webhook_id", id)
+	return nil
+}
+
+// Deliveries returns the user's latest deliveries, newest first.
+func (s *Service) Deliveries(ctx context.Context, userID int64) ([]domain.WebhookDelivery, error) {
+	return s.storage.GetWebhookDeliveries(ctx, userID, deliveryLogSize)
+}
+
+// Publish queues the event for the user's webhooks subscribed to it. The
+// change behind the event is already made, so failures are only logged.
+//
+// As any change can take the spending of a category over its budget, the
+// budgets of the current month are checked after every event.
+func (s *Service) Publish(ctx context.Context, userID int64, event string, data any) {
+	s.enqueue(ctx, userID, event, "", data)
+	s.checkBudgets(ctx, userID)
+}
+
+// enqueue queues the event, at most once per webhook when key is set.
+func (s *Service) enqueue(ctx context.Context, userID int64, event, key string, data any) {
+	payload, err := json.Marshal(Event{
+		ID:        util.GenerateRandomID(eventIDBytes),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		s.logger.Error("Failed to encode webhook event", "event", event, "error", err)
+		return
+	}
+
+	queued, err := s.storage.EnqueueWebhookEvent(ctx, userID, event, key, payload)
+	if err != nil {
+		s.logger.Error("Failed to queue webhook event", "event", event, "user_id", userID, "error", err)
+		return
+	}
+
+	if queued > 0 {
+		s.logger.Debug("Webhook event queued", "event", event, "user_id", userID, "deliveries", queued)
+	}
+}
+
+// checkBudgets publishes budget.exceeded for the categories of the current
+// month over their budget, once per category and month. Past months are
+// not checked, importing old statements does not flood the webhooks.
+func (s *Service) checkBudgets(ctx context.Context, userID int64) {
+	webhooks, err := s.storage.GetWebhooks(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get webhooks", "user_id", userID, "error", err)
+		return
+	}
+
+	// Building the report is only worth it when someone listens
+	if !slices.ContainsFunc(webhooks, func(w domain.Webhook) bool {
+		return w.Subscribed(domain.EventBudgetExceeded)
+	}) {
+		return
+	}
+
+	now := time.Now()
+	monthReport, err := report.Month(ctx, s.storage, userID, nil, now.Month(), now.Year())
+	if err != nil {
+		s.logger.Error("Failed to check budgets", "user_id", userID, "error", err)
+		return
+	}
+
+	month := now.Format("2006-01")
+	for _, category := range monthReport.ExpenseCategories {
+		if category.Budget.Status != domain.BudgetStatusOver {
+			continue
+		}
+
+		s.enqueue(ctx, userID, domain.EventBudgetExceeded, month+":"+category.Name, BudgetExceeded{
+			Category: category.Name,
+			Month:    month,
+			Budget:   category.Budget,
+		})
+	}
+}
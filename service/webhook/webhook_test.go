@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/testutil"
+)
+
+func TestCreate(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger)
+
+	tests := []struct {
+		name   string
+		url    string
+		events []string
+		err    string
+	}{
+		{"missing URL", " ", []string{domain.EventExpenseCreated}, "webhook URL is required"},
+		{"not http", "ftp://example.com", []string{domain.EventExpenseCreated}, "must be an http or https URL"},
+		{"no host", "https://", []string{domain.EventExpenseCreated}, "must be an http or https URL"},
+		{"localhost", "http://localhost:8080/hooks", []string{domain.EventExpenseCreated}, "public address"},
+		{"loopback", "http://127.0.0.1/hooks", []string{domain.EventExpenseCreated}, "public address"},
+		{"private", "http://192.168.1.10/hooks", []string{domain.EventExpenseCreated}, "public address"},
+		{"metadata", "http://169.254.169.254/latest", []string{domain.EventExpenseCreated}, "public address"},
+		{"mapped loopback", "http://[::ffff:127.0.0.1]/hooks", []string{domain.EventExpenseCreated}, "public address"},
+		{"no events", "https://example.com", nil, "select at least one event"},
+		{"unknown event", "https://example.com", []string{"expense.exploded"}, `unknown event "expense.exploded"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Create(ctx, user.ID(), tt.url, tt.events)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+
+	webhook, err := svc.Create(ctx, user.ID(), " https://example.com/hooks ",
+		[]string{domain.EventBudgetExceeded, domain.EventExpenseCreated, domain.EventExpenseCreated})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	if webhook.URL() != "https://example.com/hooks" {
+		t.Errorf("Expected the URL to be trimmed, got %q", webhook.URL())
+	}
+	if !strings.HasPrefix(webhook.Secret(), SecretPrefix) {
+		t.Errorf("Expected a signing secret, got %q", webhook.Secret())
+	}
+	events := webhook.Events()
+	if len(events) != 2 || events[0] != domain.EventExpenseCreated || events[1] != domain.EventBudgetExceeded {
+		t.Errorf("Expected the events without repeats in a fixed order, got %v", events)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger)
+
+	_, err := svc.Create(ctx, user.ID(), "https://example.com/deleted", []string{domain.EventExpenseDeleted})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	svc.Publish(ctx, user.ID(), domain.EventExpenseCreated, ExpenseDeleted{ID: 1})
+	svc.Publish(ctx, user.ID(), domain.EventExpenseDeleted, ExpenseDeleted{ID: 7})
+
+	deliveries, err := svc.Deliveries(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Deliveries returned error: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected a delivery for the subscribed event only, got %d", len(deliveries))
+	}
+
+	var event struct {
+		ID        string         `json:"id"`
+		Event     string         `json:"event"`
+		CreatedAt time.Time      `json:"created_at"`
+		Data      ExpenseDeleted `json:"data"`
+	}
+	if err = json.Unmarshal(deliveries[0].Payload(), &event); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if event.ID == "" || event.Event != domain.EventExpenseDeleted || event.CreatedAt.IsZero() || event.Data.ID != 7 {
+		t.Errorf("Got the wrong payload %s", deliveries[0].Payload())
+	}
+}
+
+func TestPublishBudgetExceeded(t *testing.T) {
+	logger := testutil.TestLogger(t)
+	s, user := testutil.SetupTestStorage(t, logger)
+	ctx := context.Background()
+
+	svc := New(s, logger)
+
+	_, err := svc.Create(ctx, user.ID(), "https://example.com/budgets", []string{domain.EventBudgetExceeded})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	categoryID, err := s.CreateCategory(ctx, user.ID(), "Food", "restaurant", 10000)
+	if err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+
+	spend := func(amount int64) {
+		_, err = s.InsertExpenses(ctx, user.ID(), []domain.Expense{
+			domain.NewExpense(0, "bank", "restaurant", "EUR", amount, time.Now(), domain.ChargeType, &categoryID, nil),
+		})
+		if err != nil {
+			t.Fatalf("Failed to insert expense: %v", err)
+		}
+		svc.Publish(ctx, user.ID(), domain.EventExpenseCreated, nil)
+	}
+
+	spend(-5000)
+	deliveries, err := svc.Deliveries(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Deliveries returned error: %v", err)
+	}
+	if len(deliveries) != 0 {
+		t.Fatalf("Expected no delivery within budget, got %d", len(deliveries))
+	}
+
+	spend(-6000)
+	spend(-1000)
+	deliveries, err = svc.Deliveries(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Deliveries returned error: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected one delivery per category and month, got %d", len(deliveries))
+	}
+
+	var event struct {
+		Event string         `json:"event"`
+		Data  BudgetExceeded `json:"data"`
+	}
+	if err = json.Unmarshal(deliveries[0].Payload(), &event); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if event.Event != domain.EventBudgetExceeded || event.Data.Category != "Food" ||
+		event.Data.Month != time.Now().Format("2006-01") || event.Data.Budget.Amount != 10000 ||
+		event.Data.Budget.Spent != 11000 {
+		t.Errorf("Got the wrong payload %s", deliveries[0].Payload())
+	}
+}
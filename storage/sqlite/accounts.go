@@ -0,0 +1,189 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+const accountColumns = "id, name, institution, currency, type, opening_balance, created_at"
+
+func (s *sqliteStorage) CreateAccount(
+	ctx context.Context,
+	userID int64,
+	account domain.Account,
+) (domain.Account, error) {
+	createdAt := time.Now()
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO accounts (user_id, name, institution, currency, type, opening_balance, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		userID,
+		account.Name(),
+		account.Institution(),
+		account.Currency(),
+		string(account.Type()),
+		account.OpeningBalance(),
+		createdAt.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create account: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewAccount(
+		id,
+		account.Name(),
+		account.Institution(),
+		account.Currency(),
+		account.Type(),
+		account.OpeningBalance(),
+		time.Unix(createdAt.Unix(), 0),
+	), nil
+}
+
+// GetAccounts returns the user's accounts sorted by name.
+func (s *sqliteStorage) GetAccounts(ctx context.Context, userID int64) ([]domain.Account, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+accountColumns+" FROM accounts WHERE user_id = ? ORDER BY name",
+		userID,
+	)
+	if err != nil {
+		return []domain.Account{}, err
+	}
+	defer rows.Close()
+
+	accounts := []domain.Account{}
+	for rows.Next() {
+		account, scanErr := scanAccount(rows)
+		if scanErr != nil {
+			return accounts, scanErr
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+func (s *sqliteStorage) GetAccount(ctx context.Context, userID, id int64) (domain.Account, error) {
+	row := s.db.QueryRowContext(ctx,
+		"SELECT "+accountColumns+" FROM accounts WHERE id = ? AND user_id = ?",
+		id,
+		userID,
+	)
+
+	account, err := scanAccount(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &domain.NotFoundError{}
+	}
+	return account, err
+}
+
+func (s *sqliteStorage) UpdateAccount(ctx context.Context, userID int64, account domain.Account) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE accounts SET name = ?, institution = ?, currency = ?, type = ?, opening_balance = ?
+		WHERE id = ? AND user_id = ?`,
+		account.Name(),
+		account.Institution(),
+		account.Currency(),
+		string(account.Type()),
+		account.OpeningBalance(),
+		account.ID(),
+		userID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update account: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteAccount deletes the account, its expenses are kept without an
+// account.
+func (s *sqliteStorage) DeleteAccount(ctx context.Context, userID, id int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() // Will be no-op if committed
+	}()
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE expenses SET account_id = NULL WHERE account_id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unlink account expenses: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM accounts WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete account: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit()
+}
+
+// GetAccountTotals returns the totals of the expenses of each of the user's
+// accounts, keyed by account ID. Accounts without expenses are left out.
+// Expenses without a currency are taken to be in the account's currency.
+func (s *sqliteStorage) GetAccountTotals(ctx context.Context, userID int64) (map[int64]domain.AccountTotals, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.account_id,
+			SUM(CASE WHEN e.currency = '' OR UPPER(e.currency) = UPPER(a.currency) THEN e.amount ELSE 0 END),
+			COUNT(*),
+			SUM(CASE WHEN e.currency = '' OR UPPER(e.currency) = UPPER(a.currency) THEN 0 ELSE 1 END)
+		FROM expenses e
+		JOIN accounts a ON a.id = e.account_id
+		WHERE e.user_id = ?
+		GROUP BY e.account_id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := map[int64]domain.AccountTotals{}
+	for rows.Next() {
+		var accountID int64
+		var total domain.AccountTotals
+		if err = rows.Scan(&accountID, &total.Amount, &total.Count, &total.Foreign); err != nil {
+			return nil, err
+		}
+		totals[accountID] = total
+	}
+
+	return totals, rows.Err()
+}
+
+func scanAccount(row scanner) (domain.Account, error) {
+	var id, openingBalance, createdAt int64
+	var name, institution, currency, accountType string
+	err := row.Scan(&id, &name, &institution, &currency, &accountType, &openingBalance, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewAccount(
+		id,
+		name,
+		institution,
+		currency,
+		domain.AccountType(accountType),
+		openingBalance,
+		time.Unix(createdAt, 0),
+	), nil
+}
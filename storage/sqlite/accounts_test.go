@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func TestAccountsCRUD(t *testing.T) {
+	stor, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	created, err := stor.CreateAccount(ctx, user.ID(), domain.NewAccount(
+		0, "Revolut", "Revolut Ltd", "EUR", domain.CheckingAccount, 15000, time.Time{},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	if created.ID() == 0 {
+		t.Fatal("Expected the created account to have an ID")
+	}
+
+	if _, err = stor.CreateAccount(ctx, user.ID(), domain.NewAccount(
+		0, "Cash", "", "EUR", domain.CashAccount, 0, time.Time{},
+	)); err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	// Names are unique per user, ignoring case
+	if _, err = stor.CreateAccount(ctx, user.ID(), domain.NewAccount(
+		0, "revolut", "", "EUR", domain.CheckingAccount, 0, time.Time{},
+	)); err == nil {
+		t.Fatal("Expected an error creating an account with a duplicate name")
+	}
+
+	accounts, err := stor.GetAccounts(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get accounts: %v", err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("Expected 2 accounts, got %d", len(accounts))
+	}
+	if accounts[0].Name() != "Cash" || accounts[1].Name() != "Revolut" {
+		t.Errorf("Expected accounts sorted by name, got %s and %s", accounts[0].Name(), accounts[1].Name())
+	}
+
+	account, err := stor.GetAccount(ctx, user.ID(), created.ID())
+	if err != nil {
+		t.Fatalf("Failed to get account: %v", err)
+	}
+	if account.Institution() != "Revolut Ltd" || account.Currency() != "EUR" ||
+		account.Type() != domain.CheckingAccount || account.OpeningBalance() != 15000 {
+		t.Errorf("Unexpected account details: %+v", account)
+	}
+
+	updated, err := stor.UpdateAccount(ctx, user.ID(), domain.NewAccount(
+		created.ID(), "Revolut Card", "Revolut Ltd", "EUR", domain.CreditAccount, 0, created.CreatedAt(),
+	))
+	if err != nil {
+		t.Fatalf("Failed to update account: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("Expected 1 updated account, got %d", updated)
+	}
+
+	account, err = stor.GetAccount(ctx, user.ID(), created.ID())
+	if err != nil {
+		t.Fatalf("Failed to get account: %v", err)
+	}
+	if account.Name() != "Revolut Card" || account.Type() != domain.CreditAccount {
+		t.Errorf("Expected the account to be updated, got %s (%s)", account.Name(), account.Type())
+	}
+
+	deleted, err := stor.DeleteAccount(ctx, user.ID(), created.ID())
+	if err != nil {
+		t.Fatalf("Failed to delete account: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 deleted account, got %d", deleted)
+	}
+
+	_, err = stor.GetAccount(ctx, user.ID(), created.ID())
+	var notFound *domain.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("Expected a NotFoundError after deleting the account, got %v", err)
+	}
+}
+
+func TestAccountExpenses(t *testing.T) {
+	stor, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	account, err := stor.CreateAccount(ctx, user.ID(), domain.NewAccount(
+		0, "Checking", "", "EUR", domain.CheckingAccount, 0, time.Time{},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	accountID := account.ID()
+
+	date := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+	_, err = stor.InsertExpenses(ctx, user.ID(), []domain.Expense{
+		domain.NewExpense(0, "bank", "salary", "EUR", 200000, date, domain.IncomeType, nil, &accountID),
+		domain.NewExpense(0, "bank", "rent", "EUR", -80000, date, domain.ChargeType, nil, &accountID),
+		domain.NewExpense(0, "bank", "hotel", "USD", -15000, date, domain.ChargeType, nil, &accountID),
+		domain.NewExpense(0, "bank", "coffee", "EUR", -300, date, domain.ChargeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	totals, err := stor.GetAccountTotals(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get account totals: %v", err)
+	}
+	if len(totals) != 1 {
+		t.Fatalf("Expected totals for 1 account, got %d", len(totals))
+	}
+	// The expense in dollars is counted but left out of the amount
+	if totals[accountID].Amount != 120000 || totals[accountID].Count != 3 || totals[accountID].Foreign != 1 {
+		t.Errorf("Expected 3 expenses, 1 in another currency, adding up to 120000, got %+v", totals[accountID])
+	}
+
+	expenses, err := stor.GetExpensesFiltered(
+		ctx,
+		user.ID(),
+		&domain.ExpenseFilter{AccountID: &accountID},
+		domain.DefaultSortOptions(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to filter expenses: %v", err)
+	}
+	if len(expenses) != 3 {
+		t.Fatalf("Expected 3 expenses of the account, got %d", len(expenses))
+	}
+	for _, ex := range expenses {
+		if ex.AccountID() == nil || *ex.AccountID() != accountID {
+			t.Errorf("Expected expense %s to belong to the account", ex.Description())
+		}
+	}
+
+	// Deleting the account keeps its expenses
+	if _, err = stor.DeleteAccount(ctx, user.ID(), accountID); err != nil {
+		t.Fatalf("Failed to delete account: %v", err)
+	}
+
+	all, err := stor.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("Expected the 4 expenses to be kept, got %d", len(all))
+	}
+	for _, ex := range all {
+		if ex.AccountID() != nil {
+			t.Errorf("Expected expense %s to have no account", ex.Description())
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func (s *sqliteStorage) CreateAPIToken(
+	ctx context.Context,
+	userID int64,
+	name, prefix, tokenHash string,
+) (domain.APIToken, error) {
+	createdAt := time.Now()
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO api_tokens (user_id, name, prefix, token_hash, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		userID,
+		name,
+		prefix,
+		tokenHash,
+		createdAt.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api token: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewAPIToken(id, userID, name, prefix, time.Unix(createdAt.Unix(), 0), time.Time{}), nil
+}
+
+// GetAPITokens returns the user's tokens, newest first.
+func (s *sqliteStorage) GetAPITokens(ctx context.Context, userID int64) ([]domain.APIToken, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, name, prefix, created_at, last_used_at FROM api_tokens
+		WHERE user_id = ? ORDER BY created_at DESC, id DESC`,
+		userID,
+	)
+	if err != nil {
+		return []domain.APIToken{}, err
+	}
+	defer rows.Close()
+
+	tokens := []domain.APIToken{}
+	for rows.Next() {
+		token, scanErr := scanAPIToken(rows)
+		if scanErr != nil {
+			return tokens, scanErr
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// GetAPITokenByHash finds the token whose hash matches, whoever owns it.
+func (s *sqliteStorage) GetAPITokenByHash(ctx context.Context, tokenHash string) (domain.APIToken, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, user_id, name, prefix, created_at, last_used_at FROM api_tokens
+		WHERE token_hash = ?`,
+		tokenHash,
+	)
+
+	token, err := scanAPIToken(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &domain.NotFoundError{}
+		}
+		return nil, fmt.Errorf("failed to scan api token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (s *sqliteStorage) UpdateAPITokenLastUsed(ctx context.Context, id int64, usedAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE api_tokens SET last_used_at = ? WHERE id = ?", usedAt.Unix(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update api token: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteStorage) DeleteAPIToken(ctx context.Context, userID, id int64) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIToken(row scanner) (domain.APIToken, error) {
+	var id, userID, createdAt int64
+	var name, prefix string
+	var lastUsedAt sql.NullInt64
+	if err := row.Scan(&id, &userID, &name, &prefix, &createdAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+
+	lastUsed := time.Time{}
+	if lastUsedAt.Valid {
+		lastUsed = time.Unix(lastUsedAt.Int64, 0)
+	}
+
+	return domain.NewAPIToken(id, userID, name, prefix, time.Unix(createdAt, 0), lastUsed), nil
+}
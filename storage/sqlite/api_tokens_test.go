@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func TestAPITokens(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	other, err := s.CreateUser(ctx, "other", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	first, err := s.CreateAPIToken(ctx, user.ID(), "scripts", "et_1111", "hash-1")
+	if err != nil {
+		t.Fatalf("Failed to create api token: %v", err)
+	}
+	second, err := s.CreateAPIToken(ctx, user.ID(), "backups", "et_2222", "hash-2")
+	if err != nil {
+		t.Fatalf("Failed to create api token: %v", err)
+	}
+	if _, err = s.CreateAPIToken(ctx, other.ID(), "other", "et_3333", "hash-3"); err != nil {
+		t.Fatalf("Failed to create api token: %v", err)
+	}
+
+	if _, err = s.CreateAPIToken(ctx, user.ID(), "duplicate", "et_1111", "hash-1"); err == nil {
+		t.Error("Expected token hashes to be unique")
+	}
+
+	tokens, err := s.GetAPITokens(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get api tokens: %v", err)
+	}
+	if len(tokens) != 2 || tokens[0].ID() != second.ID() || tokens[1].Name() != "scripts" {
+		t.Fatalf("Expected the user's tokens newest first, got %v", tokens)
+	}
+
+	token, err := s.GetAPITokenByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("Failed to get api token: %v", err)
+	}
+	if token.ID() != first.ID() || token.UserID() != user.ID() || token.Prefix() != "et_1111" {
+		t.Errorf("Got the wrong token %v", token)
+	}
+	if !token.LastUsedAt().IsZero() {
+		t.Errorf("Expected a token never used, got %v", token.LastUsedAt())
+	}
+
+	usedAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	if err = s.UpdateAPITokenLastUsed(ctx, first.ID(), usedAt); err != nil {
+		t.Fatalf("Failed to update api token: %v", err)
+	}
+	token, err = s.GetAPITokenByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("Failed to get api token: %v", err)
+	}
+	if !token.LastUsedAt().Equal(usedAt) {
+		t.Errorf("LastUsedAt = %v, want %v", token.LastUsedAt(), usedAt)
+	}
+
+	deleted, err := s.DeleteAPIToken(ctx, other.ID(), first.ID())
+	if err != nil || deleted != 0 {
+		t.Errorf("Expected another user's token to be kept, deleted %d: %v", deleted, err)
+	}
+
+	deleted, err = s.DeleteAPIToken(ctx, user.ID(), first.ID())
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected the token to be deleted, deleted %d: %v", deleted, err)
+	}
+
+	var notFound *domain.NotFoundError
+	if _, err = s.GetAPITokenByHash(ctx, "hash-1"); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError for a revoked token, got %v", err)
+	}
+}
@@ -166,7 +166,7 @@ func TestDeleteCategoriesWithExpenses(t *testing.T) {
 	}
 	excludeID := excludeCategory.ID()
 	testTime := time.Now()
-	expense := domain.NewExpense(0, "bank", "Restaurant dinner", "EUR", -2500, testTime, domain.ChargeType, &catID)
+	expense := domain.NewExpense(0, "bank", "Restaurant dinner", "EUR", -2500, testTime, domain.ChargeType, &catID, nil)
 	excludeExpense := domain.NewExpense(
 		0,
 		"bank",
@@ -176,6 +176,7 @@ func TestDeleteCategoriesWithExpenses(t *testing.T) {
 		testTime,
 		domain.IncomeType,
 		&excludeID,
+		nil,
 	)
 
 	expenses := []domain.Expense{expense, excludeExpense}
@@ -250,7 +251,7 @@ func TestDeleteCategory(t *testing.T) {
 		t.Fatalf("Failed to create test category: %v", createCategoryErr)
 	}
 	testTime := time.Now()
-	expense := domain.NewExpense(0, "bank", "Restaurant dinner", "EUR", -2500, testTime, domain.ChargeType, &catID)
+	expense := domain.NewExpense(0, "bank", "Restaurant dinner", "EUR", -2500, testTime, domain.ChargeType, &catID, nil)
 
 	expenses := []domain.Expense{expense}
 	_, insertErr := stor.InsertExpenses(context.Background(), user.ID(), expenses)
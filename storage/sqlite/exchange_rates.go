@@ -0,0 +1,143 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+// SaveExchangeRates stores the rates, replacing the rate of the same pair on
+// the same day. Rates are kept by day, in UTC.
+func (s *sqliteStorage) SaveExchangeRates(
+	ctx context.Context,
+	userID int64,
+	rates []domain.ExchangeRate,
+) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() // Will be no-op if committed
+	}()
+
+	statement, err := tx.PrepareContext(ctx, `
+		INSERT INTO exchange_rates (user_id, date, base, quote, rate) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, base, quote, date) DO UPDATE SET rate = excluded.rate
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare exchange rate statement: %w", err)
+	}
+	defer statement.Close()
+
+	var saved int64
+	for _, rate := range rates {
+		_, err = statement.ExecContext(ctx, userID, utcDay(rate.Date()).Unix(), rate.Base(), rate.Quote(), rate.Rate())
+		if err != nil {
+			return 0, fmt.Errorf("failed to save exchange rate: %w", err)
+		}
+		saved++
+	}
+
+	return saved, tx.Commit()
+}
+
+// GetExchangeRates returns the user's most recent rates, at most limit.
+func (s *sqliteStorage) GetExchangeRates(ctx context.Context, userID int64, limit int) ([]domain.ExchangeRate, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, date, base, quote, rate FROM exchange_rates
+		WHERE user_id = ?
+		ORDER BY date DESC, base, quote LIMIT ?`,
+		userID, limit,
+	)
+	if err != nil {
+		return []domain.ExchangeRate{}, err
+	}
+	defer rows.Close()
+
+	rates := []domain.ExchangeRate{}
+	for rows.Next() {
+		rate, scanErr := scanExchangeRate(rows)
+		if scanErr != nil {
+			return rates, scanErr
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// GetExchangeRate returns the rate of the pair on the given date or, when
+// there is none that day, the latest one before it.
+func (s *sqliteStorage) GetExchangeRate(
+	ctx context.Context,
+	userID int64,
+	base, quote string,
+	date time.Time,
+) (domain.ExchangeRate, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, date, base, quote, rate FROM exchange_rates
+		WHERE user_id = ? AND base = ? AND quote = ? AND date <= ?
+		ORDER BY date DESC LIMIT 1`,
+		userID, base, quote, utcDay(date).Unix(),
+	)
+
+	rate, err := scanExchangeRate(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &domain.NotFoundError{}
+		}
+		return nil, err
+	}
+
+	return rate, nil
+}
+
+// GetExchangeRateBases returns the base currencies the user has rates for.
+func (s *sqliteStorage) GetExchangeRateBases(ctx context.Context, userID int64) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT DISTINCT base FROM exchange_rates WHERE user_id = ? ORDER BY base", userID)
+	if err != nil {
+		return []string{}, err
+	}
+	defer rows.Close()
+
+	bases := []string{}
+	for rows.Next() {
+		var base string
+		if err = rows.Scan(&base); err != nil {
+			return bases, err
+		}
+		bases = append(bases, base)
+	}
+
+	return bases, rows.Err()
+}
+
+func (s *sqliteStorage) DeleteExchangeRate(ctx context.Context, userID, id int64) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM exchange_rates WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete exchange rate: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func scanExchangeRate(row scanner) (domain.ExchangeRate, error) {
+	var id, date int64
+	var base, quote string
+	var rate float64
+	if err := row.Scan(&id, &date, &base, &quote, &rate); err != nil {
+		return nil, err
+	}
+
+	return domain.NewExchangeRate(id, time.Unix(date, 0).UTC(), base, quote, rate), nil
+}
+
+func utcDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func TestExchangeRates(t *testing.T) {
+	stor, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	first := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+	third := time.Date(2024, time.May, 3, 0, 0, 0, 0, time.UTC)
+
+	saved, err := stor.SaveExchangeRates(ctx, user.ID(), []domain.ExchangeRate{
+		domain.NewExchangeRate(0, first, "EUR", "USD", 1.07),
+		domain.NewExchangeRate(0, third, "EUR", "USD", 1.08),
+		domain.NewExchangeRate(0, third, "EUR", "GBP", 0.85),
+		// The same pair on the same day replaces the rate
+		domain.NewExchangeRate(0, third.Add(15*time.Hour), "EUR", "USD", 1.09),
+	})
+	if err != nil {
+		t.Fatalf("Failed to save exchange rates: %v", err)
+	}
+	if saved != 4 {
+		t.Errorf("Expected 4 saved rates, got %d", saved)
+	}
+
+	rates, err := stor.GetExchangeRates(ctx, user.ID(), 10)
+	if err != nil {
+		t.Fatalf("Failed to get exchange rates: %v", err)
+	}
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 rates, got %d", len(rates))
+	}
+	if rates[0].Quote() != "GBP" || rates[1].Rate() != 1.09 || !rates[2].Date().Equal(first) {
+		t.Errorf("Unexpected rates order %v %v %v", rates[0].Quote(), rates[1].Rate(), rates[2].Date())
+	}
+
+	// Days without a rate use the latest one before them
+	rate, err := stor.GetExchangeRate(ctx, user.ID(), "EUR", "USD", time.Date(2024, time.May, 2, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to get exchange rate: %v", err)
+	}
+	if rate.Rate() != 1.07 {
+		t.Errorf("Expected the rate of May 1st, got %v", rate.Rate())
+	}
+
+	_, err = stor.GetExchangeRate(ctx, user.ID(), "EUR", "USD", first.AddDate(0, 0, -1))
+	var notFound *domain.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError before the first rate, got %v", err)
+	}
+
+	_, err = stor.GetExchangeRate(ctx, user.ID()+1, "EUR", "USD", third)
+	if !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError for another user, got %v", err)
+	}
+
+	bases, err := stor.GetExchangeRateBases(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get exchange rate bases: %v", err)
+	}
+	if len(bases) != 1 || bases[0] != "EUR" {
+		t.Errorf("Expected EUR as the only base, got %v", bases)
+	}
+
+	deleted, err := stor.DeleteExchangeRate(ctx, user.ID(), rates[0].ID())
+	if err != nil {
+		t.Fatalf("Failed to delete exchange rate: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 deleted rate, got %d", deleted)
+	}
+}
+
+func TestUpdateBaseCurrency(t *testing.T) {
+	stor, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	if err := stor.UpdateBaseCurrency(ctx, user.ID(), "USD"); err != nil {
+		t.Fatalf("Failed to update base currency: %v", err)
+	}
+
+	updated, err := stor.GetUserByID(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if updated.BaseCurrency() != "USD" {
+		t.Errorf("Expected base currency USD, got %q", updated.BaseCurrency())
+	}
+
+	err = stor.UpdateBaseCurrency(ctx, user.ID()+1, "USD")
+	var notFound *domain.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError for a missing user, got %v", err)
+	}
+}
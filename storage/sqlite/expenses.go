@@ -15,7 +15,17 @@ import (
 	"github.com/GustavoCaso/expensetrace/domain"
 )
 
-func convertToTemplateExpenses(userID int64, expenses []domain.Expense) []*templateExpense {
+// execer runs statements either directly on the database or inside a
+// transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func convertToTemplateExpenses(
+	userID int64,
+	importBatchID sql.NullInt64,
+	expenses []domain.Expense,
+) []*templateExpense {
 	templateExpenses := make([]*templateExpense, len(expenses))
 	for i, exp := range expenses {
 		categoryID := sql.NullInt64{}
@@ -23,31 +33,42 @@ func convertToTemplateExpenses(userID int64, expenses []domain.Expense) []*templ
 			categoryID = sql.NullInt64{Int64: *exp.CategoryID(), Valid: true}
 		}
 
+		accountID := sql.NullInt64{}
+		if exp.AccountID() != nil {
+			accountID = sql.NullInt64{Int64: *exp.AccountID(), Valid: true}
+		}
+
 		templateExpenses[i] = &templateExpense{
-			ID:          int(exp.ID()),
-			Source:      exp.Source(),
-			Date:        exp.Date(),
-			Description: exp.Description(),
-			Amount:      exp.Amount(),
-			Type:        exp.Type(),
-			Currency:    exp.Currency(),
-			CategoryID:  categoryID,
-			UserID:      userID,
+			ID:            int(exp.ID()),
+			Source:        exp.Source(),
+			Date:          exp.Date(),
+			Description:   exp.Description(),
+			Amount:        exp.Amount(),
+			Type:          exp.Type(),
+			Currency:      exp.Currency(),
+			CategoryID:    categoryID,
+			AccountID:     accountID,
+			UserID:        userID,
+			ImportBatchID: importBatchID,
+			ExternalID:    exp.ExternalID(),
 		}
 	}
 	return templateExpenses
 }
 
 type templateExpense struct {
-	ID          int
-	Source      string
-	Date        time.Time
-	Description string
-	Amount      int64
-	Type        domain.ExpenseType
-	Currency    string
-	CategoryID  sql.NullInt64
-	UserID      int64
+	ID            int
+	Source        string
+	Date          time.Time
+	Description   string
+	Amount        int64
+	Type          domain.ExpenseType
+	Currency      string
+	CategoryID    sql.NullInt64
+	AccountID     sql.NullInt64
+	UserID        int64
+	ImportBatchID sql.NullInt64
+	ExternalID    string
 }
 
 // content holds our static content.
@@ -58,8 +79,8 @@ var content embed.FS
 // Add sorting — use hardcoded strings to avoid SQL injection (gosec G202).
 // sort.Field and sort.Direction are validated by filter.parseSort before reaching here.
 var orderClauses = map[string]string{
-	"date:asc":    " ORDER BY date ASC",
-	"date:desc":   " ORDER BY date DESC",
+	"date:asc":    " ORDER BY date ASC, id ASC",
+	"date:desc":   " ORDER BY date DESC, id DESC",
 	"amount:asc":  " ORDER BY amount ASC",
 	"amount:desc": " ORDER BY amount DESC",
 }
@@ -74,39 +95,144 @@ func (s *sqliteStorage) UpdateExpense(ctx context.Context, userID int64, expense
 	if expense.CategoryID() != nil {
 		categoryID = sql.NullInt64{Int64: *expense.CategoryID(), Valid: true}
 	}
+	accountID := sql.NullInt64{}
+	if expense.AccountID() != nil {
+		accountID = sql.NullInt64{Int64: *expense.AccountID(), Valid: true}
+	}
 
 	r, err := s.db.ExecContext(ctx,
 		`UPDATE expenses SET source = ?, amount = ?, description = ?,
-		 expense_type = ?, date = ?, currency = ?, category_id = ?
+		 expense_type = ?, date = ?, currency = ?, category_id = ?, account_id = ?
 		 WHERE id = ? AND user_id = ?`,
 		expense.Source(), expense.Amount(), expense.Description(),
 		expense.Type(), expense.Date().Unix(), expense.Currency(),
-		categoryID, expense.ID(), userID)
+		categoryID, accountID, expense.ID(), userID)
 	if err != nil {
 		return 0, err
 	}
 	return r.RowsAffected()
 }
 
+// DeleteExpense deletes the expense along with the transfer it is part of.
+// Transfers are deleted explicitly as foreign keys are only enforced on some
+// pooled connections.
 func (s *sqliteStorage) DeleteExpense(ctx context.Context, userID, id int64) (int64, error) {
-	r, err := s.db.ExecContext(ctx,
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() // Will be no-op if committed
+	}()
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM transfers WHERE (charge_id = ? OR income_id = ?) AND user_id = ?", id, id, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expense transfer: %w", err)
+	}
+
+	r, err := tx.ExecContext(ctx,
 		"DELETE FROM expenses WHERE id = ? AND user_id = ?", id, userID)
 	if err != nil {
 		return 0, err
 	}
-	return r.RowsAffected()
+
+	deleted, err := r.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit()
+}
+
+// CreateExpense inserts a single expense, returning it with its new ID.
+func (s *sqliteStorage) CreateExpense(
+	ctx context.Context,
+	userID int64,
+	expense domain.Expense,
+) (domain.Expense, error) {
+	categoryID := sql.NullInt64{}
+	if expense.CategoryID() != nil {
+		categoryID = sql.NullInt64{Int64: *expense.CategoryID(), Valid: true}
+	}
+	accountID := sql.NullInt64{}
+	if expense.AccountID() != nil {
+		accountID = sql.NullInt64{Int64: *expense.AccountID(), Valid: true}
+	}
+
+	r, err := s.db.ExecContext(ctx,
+		`INSERT INTO expenses(source, amount, description, expense_type, date, currency,
+		 category_id, account_id, user_id)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		expense.Source(), expense.Amount(), expense.Description(),
+		expense.Type(), expense.Date().Unix(), expense.Currency(),
+		categoryID, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := r.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewExpense(
+		id,
+		expense.Source(),
+		expense.Description(),
+		expense.Currency(),
+		expense.Amount(),
+		expense.Date(),
+		expense.Type(),
+		expense.CategoryID(),
+		expense.AccountID(),
+	), nil
 }
 
 func (s *sqliteStorage) InsertExpenses(ctx context.Context, userID int64, expenses []domain.Expense) (int64, error) {
-	if len(expenses) == 0 {
-		return 0, nil
+	return s.insertExpenses(ctx, s.db, userID, sql.NullInt64{}, expenses)
+}
+
+// insertChunkSize is the number of expenses inserted by a single statement,
+// keeping the statements built for large imports small.
+const insertChunkSize = 500
+
+// insertExpenses inserts the expenses, optionally linking them to an import
+// batch. Large lists are inserted a chunk at a time.
+func (s *sqliteStorage) insertExpenses(
+	ctx context.Context,
+	db execer,
+	userID int64,
+	importBatchID sql.NullInt64,
+	expenses []domain.Expense,
+) (int64, error) {
+	var inserted int64
+	for start := 0; start < len(expenses); start += insertChunkSize {
+		end := min(start+insertChunkSize, len(expenses))
+
+		affected, err := s.insertExpensesChunk(ctx, db, userID, importBatchID, expenses[start:end])
+		if err != nil {
+			return inserted, err
+		}
+		inserted += affected
 	}
 
+	return inserted, nil
+}
+
+func (s *sqliteStorage) insertExpensesChunk(
+	ctx context.Context,
+	db execer,
+	userID int64,
+	importBatchID sql.NullInt64,
+	expenses []domain.Expense,
+) (int64, error) {
 	// Convert to internal template-compatible type
-	templateExpenses := convertToTemplateExpenses(userID, expenses)
+	templateExpenses := convertToTemplateExpenses(userID, importBatchID, expenses)
 
 	// Insert records
-	query := "INSERT OR IGNORE INTO expenses(source, amount, description, expense_type, date, currency, category_id, user_id) VALUES %s;"
+	query := "INSERT INTO expenses(source, amount, description, expense_type, date, currency, " +
+		"category_id, user_id, import_batch_id, account_id, external_id) VALUES %s;"
 	var buffer = bytes.Buffer{}
 
 	err := s.renderTemplate(&buffer, "expenses/insert.tmpl", struct {
@@ -128,7 +254,7 @@ func (s *sqliteStorage) InsertExpenses(ctx context.Context, userID int64, expens
 		buffer.String(),
 	)
 
-	result, err := s.db.ExecContext(ctx, formattedQuery)
+	result, err := db.ExecContext(ctx, formattedQuery)
 	if err != nil {
 		return 0, err
 	}
@@ -160,10 +286,17 @@ func (s *sqliteStorage) UpdateExpenses(ctx context.Context, userID int64, expens
 	}
 
 	// Convert to internal template-compatible type
-	templateExpenses := convertToTemplateExpenses(userID, expenses)
-
-	// Update records
-	query := "INSERT OR REPLACE INTO expenses(id, source, amount, description, expense_type, date, currency, category_id, user_id) VALUES %s;"
+	templateExpenses := convertToTemplateExpenses(userID, sql.NullInt64{}, expenses)
+
+	// Update records in place, an upsert rather than INSERT OR REPLACE so the
+	// transfers linking the expenses are not deleted along with the old rows.
+	// The import batch of each expense is kept.
+	query := "INSERT INTO expenses(id, source, amount, description, expense_type, date, currency, " +
+		"category_id, user_id, import_batch_id, account_id) VALUES %s " +
+		"ON CONFLICT(id) DO UPDATE SET source = excluded.source, amount = excluded.amount, " +
+		"description = excluded.description, expense_type = excluded.expense_type, date = excluded.date, " +
+		"currency = excluded.currency, category_id = excluded.category_id, account_id = excluded.account_id " +
+		"WHERE expenses.user_id = excluded.user_id;"
 	var buffer = bytes.Buffer{}
 
 	err := s.renderTemplate(&buffer, "expenses/updates.tmpl", struct {
@@ -324,6 +457,11 @@ func (s *sqliteStorage) GetExpensesFiltered(
 		args = append(args, expFilter.DateTo.Unix())
 	}
 
+	if expFilter.AccountID != nil {
+		query += " AND account_id = ?"
+		args = append(args, *expFilter.AccountID)
+	}
+
 	key := string(sort.Field) + ":" + string(sort.Direction)
 	if clause, ok := orderClauses[key]; ok {
 		query += clause
@@ -385,6 +523,9 @@ func expenseFromRow(scan func(dest ...any) error) (domain.Expense, error) {
 	var currency string
 	var categoryID sql.NullInt64
 	var userID int64
+	var importBatchID sql.NullInt64
+	var accountID sql.NullInt64
+	var externalID sql.NullString
 
 	if err := scan(
 		&id,
@@ -396,6 +537,9 @@ func expenseFromRow(scan func(dest ...any) error) (domain.Expense, error) {
 		&currency,
 		&categoryID,
 		&userID,
+		&importBatchID,
+		&accountID,
+		&externalID,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, &domain.NotFoundError{}
@@ -410,7 +554,12 @@ func expenseFromRow(scan func(dest ...any) error) (domain.Expense, error) {
 		catID = nil
 	}
 
-	return domain.NewExpense(
+	var acctID *int64
+	if accountID.Valid {
+		acctID = &accountID.Int64
+	}
+
+	return domain.WithExternalID(domain.NewExpense(
 		id,
 		source,
 		description,
@@ -419,5 +568,6 @@ func expenseFromRow(scan func(dest ...any) error) (domain.Expense, error) {
 		time.Unix(date, 0).UTC(),
 		domain.ExpenseType(expenseType),
 		catID,
-	), nil
+		acctID,
+	), externalID.String), nil
 }
@@ -24,6 +24,7 @@ func TestGetExpensesFiltered_NoFilters(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -34,6 +35,7 @@ func TestGetExpensesFiltered_NoFilters(t *testing.T) {
 			time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -44,6 +46,7 @@ func TestGetExpensesFiltered_NoFilters(t *testing.T) {
 			time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 			domain.IncomeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -86,6 +89,7 @@ func TestGetExpensesFiltered_DescriptionFilter(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -96,6 +100,7 @@ func TestGetExpensesFiltered_DescriptionFilter(t *testing.T) {
 			time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -106,6 +111,7 @@ func TestGetExpensesFiltered_DescriptionFilter(t *testing.T) {
 			time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -152,6 +158,7 @@ func TestGetExpensesFiltered_SourceFilter(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -162,6 +169,7 @@ func TestGetExpensesFiltered_SourceFilter(t *testing.T) {
 			time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -172,6 +180,7 @@ func TestGetExpensesFiltered_SourceFilter(t *testing.T) {
 			time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -217,6 +226,7 @@ func TestGetExpensesFiltered_AmountRangeFilter(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -227,6 +237,7 @@ func TestGetExpensesFiltered_AmountRangeFilter(t *testing.T) {
 			time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -237,6 +248,7 @@ func TestGetExpensesFiltered_AmountRangeFilter(t *testing.T) {
 			time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -281,6 +293,7 @@ func TestGetExpensesFiltered_DateRangeFilter(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -291,6 +304,7 @@ func TestGetExpensesFiltered_DateRangeFilter(t *testing.T) {
 			time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -301,6 +315,7 @@ func TestGetExpensesFiltered_DateRangeFilter(t *testing.T) {
 			time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -345,6 +360,7 @@ func TestGetExpensesFiltered_CombinedFilters(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -355,6 +371,7 @@ func TestGetExpensesFiltered_CombinedFilters(t *testing.T) {
 			time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -365,6 +382,7 @@ func TestGetExpensesFiltered_CombinedFilters(t *testing.T) {
 			time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -375,6 +393,7 @@ func TestGetExpensesFiltered_CombinedFilters(t *testing.T) {
 			time.Date(2024, 1, 18, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -424,6 +443,7 @@ func TestGetExpensesFiltered_SortByDateAsc(t *testing.T) {
 			time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -434,6 +454,7 @@ func TestGetExpensesFiltered_SortByDateAsc(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -444,6 +465,7 @@ func TestGetExpensesFiltered_SortByDateAsc(t *testing.T) {
 			time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -492,6 +514,7 @@ func TestGetExpensesFiltered_SortByAmountDesc(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -502,6 +525,7 @@ func TestGetExpensesFiltered_SortByAmountDesc(t *testing.T) {
 			time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -512,6 +536,7 @@ func TestGetExpensesFiltered_SortByAmountDesc(t *testing.T) {
 			time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 
@@ -568,6 +593,7 @@ func TestGetExpensesFiltered_UserIsolation(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 		domain.NewExpense(
 			0,
@@ -578,6 +604,7 @@ func TestGetExpensesFiltered_UserIsolation(t *testing.T) {
 			time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 	_, err = stor.InsertExpenses(ctx, user1.ID(), user1Expenses)
@@ -596,6 +623,7 @@ func TestGetExpensesFiltered_UserIsolation(t *testing.T) {
 			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
 			domain.ChargeType,
 			nil,
+			nil,
 		),
 	}
 	_, err = stor.InsertExpenses(ctx, user2.ID(), user2Expenses)
@@ -15,8 +15,8 @@ func TestExpenseStorage(t *testing.T) {
 	// Test creating expenses
 	now := time.Now()
 	testExpenses := []domain.Expense{
-		domain.NewExpense(0, "Test Bank", "Coffee shop", "USD", -500, now, domain.ChargeType, nil),
-		domain.NewExpense(0, "Test Bank", "Salary deposit", "USD", 500000, now, domain.IncomeType, nil),
+		domain.NewExpense(0, "Test Bank", "Coffee shop", "USD", -500, now, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "Test Bank", "Salary deposit", "USD", 500000, now, domain.IncomeType, nil, nil),
 	}
 
 	insertedCount, err := stor.InsertExpenses(context.Background(), user.ID(), testExpenses)
@@ -83,7 +83,17 @@ func TestExpenseStorage(t *testing.T) {
 	}
 
 	// Test update expense
-	updatedExpense := domain.NewExpense(1, "Updated Bank", "Updated coffee", "EUR", -600, now, domain.ChargeType, nil)
+	updatedExpense := domain.NewExpense(
+		1,
+		"Updated Bank",
+		"Updated coffee",
+		"EUR",
+		-600,
+		now,
+		domain.ChargeType,
+		nil,
+		nil,
+	)
 	updateCount, err := stor.UpdateExpense(context.Background(), user.ID(), updatedExpense)
 	if err != nil {
 		t.Fatalf("Failed to update expense: %v", err)
@@ -153,6 +163,7 @@ func TestExpenseWithCategories(t *testing.T) {
 		now,
 		domain.ChargeType,
 		&categoryID,
+		nil,
 	)
 	expenseWithoutCategory := domain.NewExpense(
 		0,
@@ -163,6 +174,7 @@ func TestExpenseWithCategories(t *testing.T) {
 		now,
 		domain.ChargeType,
 		nil,
+		nil,
 	)
 
 	expenses := []domain.Expense{expenseWithCategory, expenseWithoutCategory}
@@ -0,0 +1,292 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/storage"
+)
+
+const importBatchColumns = `id, filename, format, mapping, row_count, imported, skipped_duplicates,
+	imported_at, rolled_back_at`
+
+// importBatchWriter inserts the expenses of an import batch inside a single
+// transaction, so a failed import leaves nothing behind.
+type importBatchWriter struct {
+	storage    *sqliteStorage
+	tx         *sql.Tx
+	userID     int64
+	batch      domain.ImportBatch
+	batchID    int64
+	importedAt time.Time
+	inserted   int64
+}
+
+// BeginImportBatch records an import and returns a writer inserting its
+// expenses a chunk at a time. Nothing is visible until the writer commits.
+func (s *sqliteStorage) BeginImportBatch(
+	ctx context.Context,
+	userID int64,
+	batch domain.ImportBatch,
+) (storage.ImportBatchWriter, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	importedAt := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO import_batches (user_id, filename, format, mapping, row_count, skipped_duplicates, imported_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		userID,
+		batch.Filename(),
+		batch.Format(),
+		string(batch.Mapping()),
+		batch.RowCount(),
+		batch.SkippedDuplicates(),
+		importedAt.Unix(),
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("failed to create import batch: %w", err)
+	}
+
+	batchID, err := result.LastInsertId()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("failed to get import batch id: %w", err)
+	}
+
+	return &importBatchWriter{
+		storage:    s,
+		tx:         tx,
+		userID:     userID,
+		batch:      batch,
+		batchID:    batchID,
+		importedAt: importedAt,
+	}, nil
+}
+
+// GetExpensesFromDateRange returns the expenses stored before the import
+// began, leaving out the ones it inserted.
+func (w *importBatchWriter) GetExpensesFromDateRange(
+	ctx context.Context,
+	start time.Time,
+	end time.Time,
+) ([]domain.Expense, error) {
+	rows, err := w.tx.QueryContext(ctx, `
+		SELECT * FROM expenses
+		WHERE date BETWEEN ? and ? AND user_id = ? AND import_batch_id IS NOT ?`,
+		start.Unix(), end.Unix(), w.userID, w.batchID)
+	if err != nil {
+		return []domain.Expense{}, err
+	}
+
+	return extractExpensesFromRows(rows)
+}
+
+// GetExpensesByExternalIDs returns the expenses stored before the import
+// began carrying any of the external IDs.
+func (w *importBatchWriter) GetExpensesByExternalIDs(
+	ctx context.Context,
+	externalIDs []string,
+) ([]domain.Expense, error) {
+	if len(externalIDs) == 0 {
+		return []domain.Expense{}, nil
+	}
+
+	args := make([]any, 0, len(externalIDs)+2)
+	args = append(args, w.userID, w.batchID)
+	for _, id := range externalIDs {
+		args = append(args, id)
+	}
+
+	//nolint:gosec // only placeholders are added to the query
+	rows, err := w.tx.QueryContext(ctx, `
+		SELECT * FROM expenses
+		WHERE user_id = ? AND import_batch_id IS NOT ? AND external_id IN (?`+
+		strings.Repeat(", ?", len(externalIDs)-1)+`)`,
+		args...)
+	if err != nil {
+		return []domain.Expense{}, err
+	}
+
+	return extractExpensesFromRows(rows)
+}
+
+func (w *importBatchWriter) InsertExpenses(ctx context.Context, expenses []domain.Expense) (int64, error) {
+	inserted, err := w.storage.insertExpenses(
+		ctx,
+		w.tx,
+		w.userID,
+		sql.NullInt64{Int64: w.batchID, Valid: true},
+		expenses,
+	)
+	w.inserted += inserted
+	return inserted, err
+}
+
+func (w *importBatchWriter) Commit(ctx context.Context, rowCount, skippedDuplicates int) (domain.ImportBatch, error) {
+	_, err := w.tx.ExecContext(ctx,
+		"UPDATE import_batches SET imported = ?, row_count = ?, skipped_duplicates = ? WHERE id = ?",
+		w.inserted, rowCount, skippedDuplicates, w.batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update import batch: %w", err)
+	}
+
+	if err = w.tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return domain.NewImportBatch(
+		w.batchID,
+		w.batch.Filename(),
+		w.batch.Format(),
+		w.batch.Mapping(),
+		rowCount,
+		int(w.inserted),
+		skippedDuplicates,
+		time.Unix(w.importedAt.Unix(), 0),
+		nil,
+	), nil
+}
+
+func (w *importBatchWriter) Rollback() error {
+	err := w.tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
+	}
+	return err
+}
+
+func (s *sqliteStorage) GetImportBatches(ctx context.Context, userID int64) ([]domain.ImportBatch, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+importBatchColumns+" FROM import_batches WHERE user_id = ? ORDER BY imported_at DESC, id DESC",
+		userID,
+	)
+	if err != nil {
+		return []domain.ImportBatch{}, err
+	}
+	defer rows.Close()
+
+	batches := []domain.ImportBatch{}
+	for rows.Next() {
+		batch, batchErr := importBatchFromRow(rows.Scan)
+		if batchErr != nil {
+			return batches, batchErr
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches, rows.Err()
+}
+
+// RollbackImportBatch deletes the expenses of an import batch and marks it as
+// rolled back. It returns the number of expenses deleted.
+func (s *sqliteStorage) RollbackImportBatch(ctx context.Context, userID, id int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() // Will be no-op if committed
+	}()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE import_batches SET rolled_back_at = ? WHERE id = ? AND user_id = ? AND rolled_back_at IS NULL",
+		time.Now().Unix(), id, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update import batch: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if updated == 0 {
+		return 0, &domain.NotFoundError{}
+	}
+
+	// Transfers are deleted explicitly, foreign keys are only enforced on
+	// some pooled connections
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM transfers WHERE user_id = ? AND (
+			charge_id IN (SELECT id FROM expenses WHERE import_batch_id = ? AND user_id = ?) OR
+			income_id IN (SELECT id FROM expenses WHERE import_batch_id = ? AND user_id = ?)
+		)`, userID, id, userID, id, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete import batch transfers: %w", err)
+	}
+
+	result, err = tx.ExecContext(ctx,
+		"DELETE FROM expenses WHERE import_batch_id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete import batch expenses: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return deleted, nil
+}
+
+func importBatchFromRow(scan func(dest ...any) error) (domain.ImportBatch, error) {
+	var id int64
+	var filename, format, mapping string
+	var rowCount, imported, skippedDuplicates int
+	var importedAt int64
+	var rolledBackAt sql.NullInt64
+
+	if err := scan(
+		&id,
+		&filename,
+		&format,
+		&mapping,
+		&rowCount,
+		&imported,
+		&skippedDuplicates,
+		&importedAt,
+		&rolledBackAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &domain.NotFoundError{}
+		}
+		return nil, err
+	}
+
+	var rolledBack *time.Time
+	if rolledBackAt.Valid {
+		t := time.Unix(rolledBackAt.Int64, 0)
+		rolledBack = &t
+	}
+
+	var mappingBytes []byte
+	if mapping != "" {
+		mappingBytes = []byte(mapping)
+	}
+
+	return domain.NewImportBatch(
+		id,
+		filename,
+		format,
+		mappingBytes,
+		rowCount,
+		imported,
+		skippedDuplicates,
+		time.Unix(importedAt, 0),
+		rolledBack,
+	), nil
+}
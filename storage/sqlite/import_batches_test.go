@@ -0,0 +1,303 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+	"github.com/GustavoCaso/expensetrace/storage"
+)
+
+// createImportBatch stores an import batch with the given expenses, the way
+// imports do.
+func createImportBatch(
+	t *testing.T,
+	s storage.Storage,
+	userID int64,
+	batch domain.ImportBatch,
+	expenses []domain.Expense,
+) domain.ImportBatch {
+	t.Helper()
+
+	writer, err := s.BeginImportBatch(context.Background(), userID, batch)
+	if err != nil {
+		t.Fatalf("Failed to begin import batch: %v", err)
+	}
+
+	if _, err = writer.InsertExpenses(context.Background(), expenses); err != nil {
+		_ = writer.Rollback()
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	stored, err := writer.Commit(context.Background(), batch.RowCount(), batch.SkippedDuplicates())
+	if err != nil {
+		t.Fatalf("Failed to commit import batch: %v", err)
+	}
+
+	return stored
+}
+
+func TestGetImportBatches(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	expenses := []domain.Expense{
+		domain.NewExpense(0, "bank", "coffee", "EUR", -300, date, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "bank", "coffee", "EUR", -300, date, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "bank", "salary", "EUR", 250000, date, domain.IncomeType, nil, nil),
+	}
+
+	batch := createImportBatch(
+		t,
+		s,
+		user.ID(),
+		domain.NewImportBatch(0, "bank.csv", "Bank", []byte(`{"source":"bank"}`), 4, 0, 1, time.Time{}, nil),
+		expenses,
+	)
+
+	if batch.ID() == 0 {
+		t.Error("Expected batch ID to be set")
+	}
+	// Repeated charges on the same day are kept
+	if batch.Imported() != 3 || batch.SkippedDuplicates() != 1 {
+		t.Errorf("Imported = %d, skipped = %d, want 3 and 1", batch.Imported(), batch.SkippedDuplicates())
+	}
+
+	batches, err := s.GetImportBatches(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get import batches: %v", err)
+	}
+	if len(batches) != 1 || batches[0].ID() != batch.ID() {
+		t.Fatalf("Expected the batch %d, got %d batches", batch.ID(), len(batches))
+	}
+
+	stored := batches[0]
+
+	if stored.Filename() != "bank.csv" || stored.Format() != "Bank" || stored.RowCount() != 4 {
+		t.Errorf("Unexpected batch %q %q %d", stored.Filename(), stored.Format(), stored.RowCount())
+	}
+	if string(stored.Mapping()) != `{"source":"bank"}` {
+		t.Errorf("Mapping = %s, want the stored mapping", stored.Mapping())
+	}
+	if stored.Imported() != 3 || stored.SkippedDuplicates() != 1 {
+		t.Errorf("Stored imported = %d, skipped = %d, want 3 and 1", stored.Imported(), stored.SkippedDuplicates())
+	}
+	if stored.RolledBackAt() != nil {
+		t.Error("New batches should not be rolled back")
+	}
+
+	batches, err = s.GetImportBatches(ctx, user.ID()+1)
+	if err != nil {
+		t.Fatalf("Failed to get import batches: %v", err)
+	}
+	if len(batches) != 0 {
+		t.Errorf("Expected no batches for another user, got %d", len(batches))
+	}
+}
+
+func TestRollbackImportBatch(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	manual := domain.NewExpense(0, "cash", "market", "EUR", -1500, date, domain.ChargeType, nil, nil)
+	if _, err := s.InsertExpenses(ctx, user.ID(), []domain.Expense{manual}); err != nil {
+		t.Fatalf("Failed to insert expense: %v", err)
+	}
+
+	first := createImportBatch(
+		t,
+		s,
+		user.ID(),
+		domain.NewImportBatch(0, "first.csv", "Bank", nil, 2, 0, 0, time.Time{}, nil),
+		[]domain.Expense{
+			domain.NewExpense(0, "bank", "coffee", "EUR", -300, date, domain.ChargeType, nil, nil),
+			domain.NewExpense(0, "bank", "lunch", "EUR", -1200, date, domain.ChargeType, nil, nil),
+		},
+	)
+
+	createImportBatch(
+		t,
+		s,
+		user.ID(),
+		domain.NewImportBatch(0, "second.csv", "Bank", nil, 1, 0, 0, time.Time{}, nil),
+		[]domain.Expense{
+			domain.NewExpense(0, "bank", "dinner", "EUR", -2500, date, domain.ChargeType, nil, nil),
+		},
+	)
+
+	// Recategorizing replaces the rows, they must stay in their batch
+	all, err := s.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	if _, err = s.UpdateExpenses(ctx, user.ID(), all); err != nil {
+		t.Fatalf("Failed to update expenses: %v", err)
+	}
+
+	deleted, err := s.RollbackImportBatch(ctx, user.ID(), first.ID())
+	if err != nil {
+		t.Fatalf("Failed to roll back import batch: %v", err)
+	}
+
+	if deleted != 2 {
+		t.Errorf("Deleted = %d, want 2", deleted)
+	}
+
+	remaining, err := s.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	descriptions := map[string]bool{}
+	for _, e := range remaining {
+		descriptions[e.Description()] = true
+	}
+	if len(remaining) != 2 || !descriptions["market"] || !descriptions["dinner"] {
+		t.Errorf("Expected market and dinner to remain, got %v", descriptions)
+	}
+
+	batches, err := s.GetImportBatches(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get import batches: %v", err)
+	}
+	if len(batches) != 2 || batches[0].Filename() != "second.csv" {
+		t.Fatalf("Expected the newest batch first, got %d batches", len(batches))
+	}
+	if batches[1].RolledBackAt() == nil {
+		t.Error("Expected batch to be marked as rolled back")
+	}
+
+	// A batch can only be rolled back once
+	_, err = s.RollbackImportBatch(ctx, user.ID(), first.ID())
+	var notFound *domain.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError rolling back twice, got %v", err)
+	}
+}
+
+func TestBeginImportBatch(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	stored := domain.WithExternalID(
+		domain.NewExpense(0, "cash", "market", "EUR", -1500, date, domain.ChargeType, nil, nil),
+		"M1",
+	)
+	if _, err := s.InsertExpenses(ctx, user.ID(), []domain.Expense{stored}); err != nil {
+		t.Fatalf("Failed to insert expense: %v", err)
+	}
+
+	writer, err := s.BeginImportBatch(
+		ctx,
+		user.ID(),
+		domain.NewImportBatch(0, "big.csv", "Bank", nil, 0, 0, 0, time.Time{}, nil),
+	)
+	if err != nil {
+		t.Fatalf("Failed to begin import batch: %v", err)
+	}
+
+	// More expenses than a single insert statement holds
+	expenses := make([]domain.Expense, 0, insertChunkSize*2+1)
+	for i := range cap(expenses) {
+		expenses = append(expenses, domain.NewExpense(
+			0, "bank", "coffee", "EUR", int64(-100-i), date, domain.ChargeType, nil,
+			nil,
+		))
+	}
+
+	inserted, err := writer.InsertExpenses(ctx, expenses)
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+	if inserted != int64(len(expenses)) {
+		t.Errorf("Inserted %d expenses, want %d", inserted, len(expenses))
+	}
+
+	// Duplicates are looked for among the expenses stored before the import
+	existing, err := writer.GetExpensesFromDateRange(ctx, date, date)
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	if len(existing) != 1 || existing[0].Description() != "market" {
+		t.Errorf("Expected only the stored expense, got %d expenses", len(existing))
+	}
+
+	expenses[0] = domain.WithExternalID(expenses[0], "M1")
+	if _, err = writer.InsertExpenses(ctx, expenses[:1]); err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	sameID, err := writer.GetExpensesByExternalIDs(ctx, []string{"M1", "M2"})
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	if len(sameID) != 1 || sameID[0].Description() != "market" || sameID[0].ExternalID() != "M1" {
+		t.Errorf("Expected only the stored expense with external ID M1, got %d expenses", len(sameID))
+	}
+
+	batch, err := writer.Commit(ctx, len(expenses)+4, 3)
+	if err != nil {
+		t.Fatalf("Failed to commit import batch: %v", err)
+	}
+
+	if batch.Imported() != len(expenses)+1 || batch.RowCount() != len(expenses)+4 || batch.SkippedDuplicates() != 3 {
+		t.Errorf("Unexpected batch: imported %d, rows %d, skipped %d",
+			batch.Imported(), batch.RowCount(), batch.SkippedDuplicates())
+	}
+
+	if err = writer.Rollback(); err != nil {
+		t.Errorf("Rollback after commit should be a no-op, got %v", err)
+	}
+
+	all, err := s.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	if len(all) != len(expenses)+2 {
+		t.Errorf("Expected %d expenses, got %d", len(expenses)+2, len(all))
+	}
+}
+
+func TestBeginImportBatchRollback(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	writer, err := s.BeginImportBatch(
+		ctx,
+		user.ID(),
+		domain.NewImportBatch(0, "bad.csv", "Bank", nil, 0, 0, 0, time.Time{}, nil),
+	)
+	if err != nil {
+		t.Fatalf("Failed to begin import batch: %v", err)
+	}
+
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	_, err = writer.InsertExpenses(ctx, []domain.Expense{
+		domain.NewExpense(0, "bank", "coffee", "EUR", -300, date, domain.ChargeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	if err = writer.Rollback(); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+
+	expenses, err := s.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	batches, err := s.GetImportBatches(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get import batches: %v", err)
+	}
+	if len(expenses) != 0 || len(batches) != 0 {
+		t.Errorf("A rolled back import should store nothing, got %d expenses and %d batches",
+			len(expenses), len(batches))
+	}
+}
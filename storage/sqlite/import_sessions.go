@@ -0,0 +1,257 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+// importSessionChunkSize is the size of the pieces the uploaded file of an
+// import session is stored in, so it is never held in memory whole.
+const importSessionChunkSize = 1 << 20
+
+const importSessionColumns = "id, user_id, filename, data, mapping, created_at, expires_at"
+
+// CreateImportSession stores an import session along with its uploaded file.
+func (s *sqliteStorage) CreateImportSession(
+	ctx context.Context,
+	session domain.ImportSession,
+	file io.Reader,
+) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO import_sessions (`+importSessionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		session.ID(),
+		session.UserID(),
+		session.Filename(),
+		string(session.Data()),
+		string(session.Mapping()),
+		session.CreatedAt().Unix(),
+		session.ExpiresAt().Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create import session: %w", err)
+	}
+
+	statement, err := tx.PrepareContext(ctx, `
+		INSERT INTO import_session_chunks (session_id, seq, data) VALUES (?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare import session chunk statement: %w", err)
+	}
+	defer statement.Close()
+
+	chunk := make([]byte, importSessionChunkSize)
+	for seq := 0; ; seq++ {
+		n, readErr := io.ReadFull(file, chunk)
+		if n > 0 {
+			if _, err = statement.ExecContext(ctx, session.ID(), seq, chunk[:n]); err != nil {
+				return fmt.Errorf("failed to store import session file: %w", err)
+			}
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read import session file: %w", readErr)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetImportSession returns an import session of the user, unless it has
+// expired.
+func (s *sqliteStorage) GetImportSession(
+	ctx context.Context,
+	userID int64,
+	id string,
+) (domain.ImportSession, error) {
+	row := s.db.QueryRowContext(
+		ctx,
+		"SELECT "+importSessionColumns+" FROM import_sessions WHERE id = ? AND user_id = ? AND expires_at > ?",
+		id,
+		userID,
+		time.Now().Unix(),
+	)
+
+	var sessionID, filename, data, mapping string
+	var ownerID, createdAt, expiresAt int64
+
+	err := row.Scan(&sessionID, &ownerID, &filename, &data, &mapping, &createdAt, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &domain.NotFoundError{}
+		}
+		return nil, fmt.Errorf("failed to scan import session: %w", err)
+	}
+
+	var mappingBytes []byte
+	if mapping != "" {
+		mappingBytes = []byte(mapping)
+	}
+
+	return domain.NewImportSession(
+		sessionID,
+		ownerID,
+		filename,
+		[]byte(data),
+		mappingBytes,
+		time.Unix(createdAt, 0),
+		time.Unix(expiresAt, 0),
+	), nil
+}
+
+// UpdateImportSession replaces the data and mapping of an import session of
+// the user.
+func (s *sqliteStorage) UpdateImportSession(
+	ctx context.Context,
+	userID int64,
+	id string,
+	data, mapping []byte,
+) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE import_sessions SET data = ?, mapping = ?
+		WHERE id = ? AND user_id = ? AND expires_at > ?`,
+		string(data),
+		string(mapping),
+		id,
+		userID,
+		time.Now().Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update import session: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return &domain.NotFoundError{}
+	}
+
+	return nil
+}
+
+// OpenImportSessionFile returns a reader over the uploaded file of an import
+// session of the user, loading one chunk at a time.
+func (s *sqliteStorage) OpenImportSessionFile(
+	ctx context.Context,
+	userID int64,
+	id string,
+) (io.ReadCloser, error) {
+	if _, err := s.GetImportSession(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	var chunks int
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM import_session_chunks WHERE session_id = ?",
+		id,
+	).Scan(&chunks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count import session chunks: %w", err)
+	}
+
+	return &importSessionFile{ctx: ctx, db: s.db, sessionID: id, chunks: chunks}, nil
+}
+
+// DeleteImportSession removes an import session of the user and its file.
+func (s *sqliteStorage) DeleteImportSession(ctx context.Context, userID int64, id string) error {
+	_, err := s.deleteImportSessions(ctx, "id = ? AND user_id = ?", id, userID)
+	return err
+}
+
+// DeleteExpiredImportSessions removes the expired import sessions of every
+// user and their files, returning how many were removed.
+func (s *sqliteStorage) DeleteExpiredImportSessions(ctx context.Context) (int64, error) {
+	return s.deleteImportSessions(ctx, "expires_at <= ?", time.Now().Unix())
+}
+
+// deleteImportSessions removes the import sessions matching where. Chunks
+// are removed explicitly, as foreign keys are only enforced on the
+// connection that enabled them.
+func (s *sqliteStorage) deleteImportSessions(ctx context.Context, where string, args ...any) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM import_session_chunks
+		WHERE session_id IN (SELECT id FROM import_sessions WHERE `+where+`)`,
+		args...,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete import session files: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM import_sessions WHERE "+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete import sessions: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit()
+}
+
+// importSessionFile reads the chunks of an import session file in order.
+type importSessionFile struct {
+	ctx       context.Context
+	db        *sql.DB
+	sessionID string
+	chunks    int
+
+	seq     int
+	pending []byte
+}
+
+func (f *importSessionFile) Read(p []byte) (int, error) {
+	if len(f.pending) == 0 {
+		if f.seq >= f.chunks {
+			return 0, io.EOF
+		}
+
+		err := f.db.QueryRowContext(
+			f.ctx,
+			"SELECT data FROM import_session_chunks WHERE session_id = ? AND seq = ?",
+			f.sessionID,
+			f.seq,
+		).Scan(&f.pending)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.New("import session file is no longer available")
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read import session file: %w", err)
+		}
+		f.seq++
+	}
+
+	n := copy(p, f.pending)
+	f.pending = f.pending[n:]
+	return n, nil
+}
+
+func (f *importSessionFile) Close() error {
+	f.pending = nil
+	f.seq = f.chunks
+	return nil
+}
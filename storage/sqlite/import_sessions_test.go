@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func TestImportSessionStoresLargeFilesInChunks(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("2024-01-01,Coffee,-3.50,EUR\n"), importSessionChunkSize/10)
+	now := time.Now()
+
+	session := domain.NewImportSession("session-1", user.ID(), "large.csv", []byte(`{}`), nil, now, now.Add(time.Hour))
+	if err := s.CreateImportSession(ctx, session, bytes.NewReader(content)); err != nil {
+		t.Fatalf("Failed to create import session: %v", err)
+	}
+
+	stored, err := s.GetImportSession(ctx, user.ID(), "session-1")
+	if err != nil {
+		t.Fatalf("Failed to get import session: %v", err)
+	}
+	if stored.Filename() != "large.csv" || string(stored.Data()) != `{}` || stored.Mapping() != nil {
+		t.Errorf("Unexpected import session %q %q %q", stored.Filename(), stored.Data(), stored.Mapping())
+	}
+
+	file, err := s.OpenImportSessionFile(ctx, user.ID(), "session-1")
+	if err != nil {
+		t.Fatalf("Failed to open import session file: %v", err)
+	}
+	defer file.Close()
+
+	read, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("Failed to read import session file: %v", err)
+	}
+	if !bytes.Equal(read, content) {
+		t.Errorf("Read %d bytes, want the %d bytes stored", len(read), len(content))
+	}
+}
+
+func TestUpdateImportSession(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	session := domain.NewImportSession("session-1", user.ID(), "test.csv", []byte(`{}`), nil, now, now.Add(time.Hour))
+	if err := s.CreateImportSession(ctx, session, bytes.NewReader(nil)); err != nil {
+		t.Fatalf("Failed to create import session: %v", err)
+	}
+
+	err := s.UpdateImportSession(ctx, user.ID(), "session-1", []byte(`{"a":1}`), []byte(`{"source":"Bank"}`))
+	if err != nil {
+		t.Fatalf("Failed to update import session: %v", err)
+	}
+
+	stored, err := s.GetImportSession(ctx, user.ID(), "session-1")
+	if err != nil {
+		t.Fatalf("Failed to get import session: %v", err)
+	}
+	if string(stored.Data()) != `{"a":1}` || string(stored.Mapping()) != `{"source":"Bank"}` {
+		t.Errorf("Unexpected import session %q %q", stored.Data(), stored.Mapping())
+	}
+
+	other, err := s.CreateUser(ctx, "other", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	var notFound *domain.NotFoundError
+	err = s.UpdateImportSession(ctx, other.ID(), "session-1", []byte(`{}`), nil)
+	if !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError updating another user's session, got %v", err)
+	}
+}
+
+func TestDeleteImportSessions(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	sessions := []domain.ImportSession{
+		domain.NewImportSession("active", user.ID(), "a.csv", []byte(`{}`), nil, now, now.Add(time.Hour)),
+		domain.NewImportSession("expired", user.ID(), "b.csv", []byte(`{}`), nil, now, now.Add(-time.Hour)),
+		domain.NewImportSession("deleted", user.ID(), "c.csv", []byte(`{}`), nil, now, now.Add(time.Hour)),
+	}
+	for _, session := range sessions {
+		if err := s.CreateImportSession(ctx, session, bytes.NewReader([]byte("date\n"))); err != nil {
+			t.Fatalf("Failed to create import session: %v", err)
+		}
+	}
+
+	var notFound *domain.NotFoundError
+	if _, err := s.GetImportSession(ctx, user.ID(), "expired"); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError for an expired session, got %v", err)
+	}
+
+	deleted, err := s.DeleteExpiredImportSessions(ctx)
+	if err != nil {
+		t.Fatalf("Failed to delete expired import sessions: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Deleted %d expired sessions, want 1", deleted)
+	}
+
+	if err = s.DeleteImportSession(ctx, user.ID(), "deleted"); err != nil {
+		t.Fatalf("Failed to delete import session: %v", err)
+	}
+	if _, err = s.OpenImportSessionFile(ctx, user.ID(), "deleted"); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError for a deleted session, got %v", err)
+	}
+
+	if _, err = s.GetImportSession(ctx, user.ID(), "active"); err != nil {
+		t.Errorf("Expected the active session to be kept, got %v", err)
+	}
+
+	// Files of removed sessions are removed too
+	var chunks int
+	row := s.(*sqliteStorage).db.QueryRowContext(ctx, "SELECT COUNT(*) FROM import_session_chunks")
+	if err = row.Scan(&chunks); err != nil {
+		t.Fatalf("Failed to count chunks: %v", err)
+	}
+	if chunks != 1 {
+		t.Errorf("Expected only the active session file to be kept, got %d chunks", chunks)
+	}
+}
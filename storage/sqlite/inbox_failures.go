@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func (s *sqliteStorage) CreateInboxFailure(
+	ctx context.Context,
+	userID int64,
+	filename, message string,
+) (domain.InboxFailure, error) {
+	failedAt := time.Now()
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO inbox_failures (user_id, filename, message, failed_at)
+		VALUES (?, ?, ?, ?)`,
+		userID,
+		filename,
+		message,
+		failedAt.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create inbox failure: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewInboxFailure(id, filename, message, failedAt), nil
+}
+
+// GetInboxFailures returns the user's inbox failures, newest first.
+func (s *sqliteStorage) GetInboxFailures(ctx context.Context, userID int64) ([]domain.InboxFailure, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, filename, message, failed_at FROM inbox_failures
+		WHERE user_id = ? ORDER BY failed_at DESC, id DESC`,
+		userID,
+	)
+	if err != nil {
+		return []domain.InboxFailure{}, err
+	}
+	defer rows.Close()
+
+	failures := []domain.InboxFailure{}
+	for rows.Next() {
+		var id, failedAt int64
+		var filename, message string
+		if err = rows.Scan(&id, &filename, &message, &failedAt); err != nil {
+			return failures, err
+		}
+		failures = append(failures, domain.NewInboxFailure(id, filename, message, time.Unix(failedAt, 0)))
+	}
+
+	return failures, rows.Err()
+}
+
+func (s *sqliteStorage) DeleteInboxFailures(ctx context.Context, userID int64) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM inbox_failures WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
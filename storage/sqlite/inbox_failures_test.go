@@ -0,0 +1,49 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+)
+
+func TestInboxFailures(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	other, err := s.CreateUser(ctx, "other", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	for _, filename := range []string{"first.csv", "second.csv"} {
+		if _, err = s.CreateInboxFailure(ctx, user.ID(), filename, "unsupported file"); err != nil {
+			t.Fatalf("Failed to create inbox failure: %v", err)
+		}
+	}
+	if _, err = s.CreateInboxFailure(ctx, other.ID(), "other.csv", "unsupported file"); err != nil {
+		t.Fatalf("Failed to create inbox failure: %v", err)
+	}
+
+	failures, err := s.GetInboxFailures(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get inbox failures: %v", err)
+	}
+	if len(failures) != 2 || failures[0].Filename() != "second.csv" || failures[0].Message() != "unsupported file" {
+		t.Fatalf("Expected the user's failures newest first, got %v", failures)
+	}
+
+	deleted, err := s.DeleteInboxFailures(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to delete inbox failures: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Deleted %d failures, want 2", deleted)
+	}
+
+	failures, err = s.GetInboxFailures(ctx, other.ID())
+	if err != nil {
+		t.Fatalf("Failed to get inbox failures: %v", err)
+	}
+	if len(failures) != 1 {
+		t.Errorf("Expected the other user's failure to be kept, got %d", len(failures))
+	}
+}
@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+const mappingProfileColumns = "id, name, header_fingerprint, mapping, updated_at"
+
+// SaveMappingProfile creates a mapping profile or, when the user already has
+// one with the same name, replaces it.
+func (s *sqliteStorage) SaveMappingProfile(
+	ctx context.Context,
+	userID int64,
+	name, headerFingerprint string,
+	mapping []byte,
+) (domain.MappingProfile, error) {
+	updatedAt := time.Now()
+
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO mapping_profiles (user_id, name, header_fingerprint, mapping, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(name, user_id) DO UPDATE SET
+			header_fingerprint = excluded.header_fingerprint,
+			mapping = excluded.mapping,
+			updated_at = excluded.updated_at
+		RETURNING `+mappingProfileColumns,
+		userID,
+		name,
+		headerFingerprint,
+		string(mapping),
+		updatedAt.Unix(),
+	)
+
+	profile, err := mappingProfileFromRow(row.Scan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save mapping profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+func (s *sqliteStorage) GetMappingProfiles(ctx context.Context, userID int64) ([]domain.MappingProfile, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+mappingProfileColumns+" FROM mapping_profiles WHERE user_id = ? ORDER BY name",
+		userID,
+	)
+	if err != nil {
+		return []domain.MappingProfile{}, err
+	}
+	defer rows.Close()
+
+	profiles := []domain.MappingProfile{}
+	for rows.Next() {
+		profile, profileErr := mappingProfileFromRow(rows.Scan)
+		if profileErr != nil {
+			return profiles, profileErr
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, rows.Err()
+}
+
+func (s *sqliteStorage) GetMappingProfile(ctx context.Context, userID, id int64) (domain.MappingProfile, error) {
+	row := s.db.QueryRowContext(
+		ctx,
+		"SELECT "+mappingProfileColumns+" FROM mapping_profiles WHERE id = ? AND user_id = ?",
+		id,
+		userID,
+	)
+	return mappingProfileFromRow(row.Scan)
+}
+
+// GetMappingProfileByFingerprint returns the most recently saved profile for
+// the given header fingerprint.
+func (s *sqliteStorage) GetMappingProfileByFingerprint(
+	ctx context.Context,
+	userID int64,
+	headerFingerprint string,
+) (domain.MappingProfile, error) {
+	row := s.db.QueryRowContext(
+		ctx,
+		"SELECT "+mappingProfileColumns+` FROM mapping_profiles
+		WHERE header_fingerprint = ? AND user_id = ?
+		ORDER BY updated_at DESC, id DESC LIMIT 1`,
+		headerFingerprint,
+		userID,
+	)
+	return mappingProfileFromRow(row.Scan)
+}
+
+func (s *sqliteStorage) DeleteMappingProfile(ctx context.Context, userID, id int64) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM mapping_profiles WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func mappingProfileFromRow(scan func(dest ...any) error) (domain.MappingProfile, error) {
+	var id int64
+	var name, headerFingerprint, mapping string
+	var updatedAt int64
+
+	if err := scan(&id, &name, &headerFingerprint, &mapping, &updatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &domain.NotFoundError{}
+		}
+		return nil, err
+	}
+
+	return domain.NewMappingProfile(id, name, headerFingerprint, []byte(mapping), time.Unix(updatedAt, 0)), nil
+}
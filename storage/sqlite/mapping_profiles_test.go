@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func TestSaveMappingProfile(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	profile, err := s.SaveMappingProfile(ctx, user.ID(), "Chase", "abc", []byte(`{"source":"Chase"}`))
+	if err != nil {
+		t.Fatalf("Failed to save mapping profile: %v", err)
+	}
+
+	if profile.ID() == 0 {
+		t.Error("Expected profile ID to be set")
+	}
+
+	if profile.Name() != "Chase" || profile.HeaderFingerprint() != "abc" {
+		t.Errorf("Unexpected profile %q %q", profile.Name(), profile.HeaderFingerprint())
+	}
+
+	// Saving with the same name replaces the mapping
+	updated, err := s.SaveMappingProfile(ctx, user.ID(), "Chase", "def", []byte(`{"source":"Chase Bank"}`))
+	if err != nil {
+		t.Fatalf("Failed to update mapping profile: %v", err)
+	}
+
+	if updated.ID() != profile.ID() {
+		t.Errorf("Expected profile %d to be updated, got new profile %d", profile.ID(), updated.ID())
+	}
+
+	if string(updated.Mapping()) != `{"source":"Chase Bank"}` {
+		t.Errorf("Mapping = %s, want updated mapping", updated.Mapping())
+	}
+
+	profiles, err := s.GetMappingProfiles(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get mapping profiles: %v", err)
+	}
+
+	if len(profiles) != 1 {
+		t.Fatalf("Expected 1 profile, got %d", len(profiles))
+	}
+}
+
+func TestGetMappingProfileByFingerprint(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	_, err := s.SaveMappingProfile(ctx, user.ID(), "Chase", "abc", []byte(`{}`))
+	if err != nil {
+		t.Fatalf("Failed to save mapping profile: %v", err)
+	}
+
+	profile, err := s.GetMappingProfileByFingerprint(ctx, user.ID(), "abc")
+	if err != nil {
+		t.Fatalf("Failed to get mapping profile: %v", err)
+	}
+
+	if profile.Name() != "Chase" {
+		t.Errorf("Name = %q, want Chase", profile.Name())
+	}
+
+	_, err = s.GetMappingProfileByFingerprint(ctx, user.ID(), "unknown")
+	var notFound *domain.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError, got %v", err)
+	}
+
+	// Profiles belong to a single user
+	other, err := s.CreateUser(ctx, "other", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	_, err = s.GetMappingProfileByFingerprint(ctx, other.ID(), "abc")
+	if !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError for another user, got %v", err)
+	}
+}
+
+func TestDeleteMappingProfile(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	profile, err := s.SaveMappingProfile(ctx, user.ID(), "Chase", "abc", []byte(`{}`))
+	if err != nil {
+		t.Fatalf("Failed to save mapping profile: %v", err)
+	}
+
+	deleted, err := s.DeleteMappingProfile(ctx, user.ID(), profile.ID())
+	if err != nil {
+		t.Fatalf("Failed to delete mapping profile: %v", err)
+	}
+
+	if deleted != 1 {
+		t.Errorf("Expected 1 profile deleted, got %d", deleted)
+	}
+
+	_, err = s.GetMappingProfile(ctx, user.ID(), profile.ID())
+	var notFound *domain.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError, got %v", err)
+	}
+}
@@ -37,6 +37,24 @@ func DropTables(db *sql.DB) error {
 	}
 
 	// drop tables (in order to respect foreign keys)
+	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS exchange_rates;")
+	if err != nil {
+		rErr := tx.Rollback()
+		if rErr != nil {
+			return rErr
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS transfers;")
+	if err != nil {
+		rErr := tx.Rollback()
+		if rErr != nil {
+			return rErr
+		}
+		return err
+	}
+
 	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS expenses;")
 	if err != nil {
 		rErr := tx.Rollback()
@@ -46,6 +64,15 @@ func DropTables(db *sql.DB) error {
 		return err
 	}
 
+	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS accounts;")
+	if err != nil {
+		rErr := tx.Rollback()
+		if rErr != nil {
+			return rErr
+		}
+		return err
+	}
+
 	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS categories;")
 	if err != nil {
 		rErr := tx.Rollback()
@@ -55,6 +82,78 @@ func DropTables(db *sql.DB) error {
 		return err
 	}
 
+	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS mapping_profiles;")
+	if err != nil {
+		rErr := tx.Rollback()
+		if rErr != nil {
+			return rErr
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS import_batches;")
+	if err != nil {
+		rErr := tx.Rollback()
+		if rErr != nil {
+			return rErr
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS inbox_failures;")
+	if err != nil {
+		rErr := tx.Rollback()
+		if rErr != nil {
+			return rErr
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS import_session_chunks;")
+	if err != nil {
+		rErr := tx.Rollback()
+		if rErr != nil {
+			return rErr
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS import_sessions;")
+	if err != nil {
+		rErr := tx.Rollback()
+		if rErr != nil {
+			return rErr
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS webhook_deliveries;")
+	if err != nil {
+		rErr := tx.Rollback()
+		if rErr != nil {
+			return rErr
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS webhooks;")
+	if err != nil {
+		rErr := tx.Rollback()
+		if rErr != nil {
+			return rErr
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS api_tokens;")
+	if err != nil {
+		rErr := tx.Rollback()
+		if rErr != nil {
+			return rErr
+		}
+		return err
+	}
+
 	_, err = tx.ExecContext(ctx, "DROP TABLE IF EXISTS sessions;")
 	if err != nil {
 		rErr := tx.Rollback()
@@ -89,24 +188,15 @@ func DropTables(db *sql.DB) error {
 	return nil
 }
 
-func (s *sqliteStorage) ApplyMigrations(ctx context.Context, logger *logger.Logger) error {
-	// Create migrations table if it doesn't exist
-	if err := createMigrationsTable(s.db); err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
-	}
-
-	// Get current schema version
-	currentVersion := 0
-	row := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
-	if err := row.Scan(&currentVersion); err != nil {
-		return fmt.Errorf("failed to get current schema version: %w", err)
-	}
+type migration struct {
+	name string
+	up   func(*sql.Tx) error
+}
 
-	// Define migrations
-	migrations := []struct {
-		name string
-		up   func(*sql.Tx) error
-	}{
+// schemaMigrations lists the migrations in the order they are applied, a
+// migration's version is its position in the list.
+func schemaMigrations(ctx context.Context) []migration {
+	return []migration{
 		{
 			name: "Create expenses table",
 			up: func(tx *sql.Tx) error {
@@ -564,10 +654,344 @@ func (s *sqliteStorage) ApplyMigrations(ctx context.Context, logger *logger.Logg
 				return nil
 			},
 		},
+		{
+			name: "Create mapping_profiles table",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					CREATE TABLE IF NOT EXISTS mapping_profiles (
+						id INTEGER PRIMARY KEY,
+						user_id INTEGER NOT NULL,
+						name TEXT NOT NULL,
+						header_fingerprint TEXT NOT NULL,
+						mapping TEXT NOT NULL,
+						updated_at INTEGER NOT NULL,
+						UNIQUE(name, user_id) ON CONFLICT FAIL,
+						FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+					) STRICT;`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `
+					CREATE INDEX IF NOT EXISTS mapping_profiles_fingerprint
+					ON mapping_profiles(user_id, header_fingerprint);`)
+				return err
+			},
+		},
+		{
+			name: "Add default_currency column to users",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					ALTER TABLE users ADD COLUMN default_currency TEXT NOT NULL DEFAULT '';
+				`)
+				return err
+			},
+		},
+		{
+			name: "Create import_batches table",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					CREATE TABLE IF NOT EXISTS import_batches (
+						id INTEGER PRIMARY KEY,
+						user_id INTEGER NOT NULL,
+						filename TEXT NOT NULL,
+						format TEXT NOT NULL,
+						mapping TEXT NOT NULL DEFAULT '',
+						row_count INTEGER NOT NULL,
+						imported INTEGER NOT NULL DEFAULT 0,
+						skipped_duplicates INTEGER NOT NULL DEFAULT 0,
+						imported_at INTEGER NOT NULL,
+						rolled_back_at INTEGER,
+						FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+					) STRICT;`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `
+					ALTER TABLE expenses ADD COLUMN import_batch_id INTEGER
+					REFERENCES import_batches(id) ON DELETE SET NULL;`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `
+					CREATE INDEX IF NOT EXISTS expenses_import_batch
+					ON expenses(import_batch_id);`)
+				return err
+			},
+		},
+		{
+			// Repeated charges, like two coffees on the same day, are valid
+			// expenses. Duplicates are detected when importing instead.
+			name: "Drop unique constraint from expenses",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					CREATE TABLE expenses_new (
+						id INTEGER PRIMARY KEY,
+						source TEXT,
+						amount INTEGER NOT NULL,
+						description TEXT NOT NULL,
+						expense_type INTEGER NOT NULL,
+						date INTEGER NOT NULL,
+						currency TEXT NOT NULL,
+						category_id INTEGER,
+						user_id INTEGER NOT NULL DEFAULT 1,
+						import_batch_id INTEGER,
+						FOREIGN KEY(category_id) REFERENCES categories(id),
+						FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+						FOREIGN KEY(import_batch_id) REFERENCES import_batches(id) ON DELETE SET NULL
+					) STRICT;
+				`)
+				if err != nil {
+					return fmt.Errorf("failed to create expenses_new table: %w", err)
+				}
+
+				_, err = tx.ExecContext(ctx, `INSERT INTO expenses_new SELECT * FROM expenses`)
+				if err != nil {
+					return fmt.Errorf("failed to copy data to expenses_new: %w", err)
+				}
+
+				if _, err = tx.ExecContext(ctx, "DROP TABLE expenses"); err != nil {
+					return fmt.Errorf("failed to drop old expenses table: %w", err)
+				}
+
+				if _, err = tx.ExecContext(ctx, "ALTER TABLE expenses_new RENAME TO expenses"); err != nil {
+					return fmt.Errorf("failed to rename expenses_new table: %w", err)
+				}
+
+				_, err = tx.ExecContext(ctx, `
+					CREATE INDEX IF NOT EXISTS expenses_user_date ON expenses(user_id, date);
+					CREATE INDEX IF NOT EXISTS expenses_import_batch ON expenses(import_batch_id);`)
+				return err
+			},
+		},
+		{
+			name: "Create import_sessions tables",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					CREATE TABLE IF NOT EXISTS import_sessions (
+						id TEXT PRIMARY KEY,
+						user_id INTEGER NOT NULL,
+						filename TEXT NOT NULL,
+						data TEXT NOT NULL,
+						mapping TEXT NOT NULL DEFAULT '',
+						created_at INTEGER NOT NULL,
+						expires_at INTEGER NOT NULL,
+						FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+					) STRICT;`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `
+					CREATE TABLE IF NOT EXISTS import_session_chunks (
+						session_id TEXT NOT NULL,
+						seq INTEGER NOT NULL,
+						data BLOB NOT NULL,
+						PRIMARY KEY(session_id, seq),
+						FOREIGN KEY(session_id) REFERENCES import_sessions(id) ON DELETE CASCADE
+					) STRICT;`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `
+					CREATE INDEX IF NOT EXISTS import_sessions_expires_at ON import_sessions(expires_at);`)
+				return err
+			},
+		},
+		{
+			name: "Create inbox_failures table",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					CREATE TABLE IF NOT EXISTS inbox_failures (
+						id INTEGER PRIMARY KEY,
+						user_id INTEGER NOT NULL,
+						filename TEXT NOT NULL,
+						message TEXT NOT NULL,
+						failed_at INTEGER NOT NULL,
+						FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+					) STRICT;`)
+				return err
+			},
+		},
+		{
+			name: "Create api_tokens table",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					CREATE TABLE IF NOT EXISTS api_tokens (
+						id INTEGER PRIMARY KEY,
+						user_id INTEGER NOT NULL,
+						name TEXT NOT NULL,
+						prefix TEXT NOT NULL,
+						token_hash TEXT NOT NULL UNIQUE,
+						created_at INTEGER NOT NULL,
+						last_used_at INTEGER,
+						FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+					) STRICT;`)
+				return err
+			},
+		},
+		{
+			name: "Create webhooks and webhook_deliveries tables",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					CREATE TABLE IF NOT EXISTS webhooks (
+						id INTEGER PRIMARY KEY,
+						user_id INTEGER NOT NULL,
+						url TEXT NOT NULL,
+						secret TEXT NOT NULL,
+						events TEXT NOT NULL,
+						created_at INTEGER NOT NULL,
+						FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+					) STRICT;`)
+				if err != nil {
+					return err
+				}
+
+				// event_key is set for events sent at most once per webhook
+				_, err = tx.ExecContext(ctx, `
+					CREATE TABLE IF NOT EXISTS webhook_deliveries (
+						id INTEGER PRIMARY KEY,
+						webhook_id INTEGER NOT NULL,
+						user_id INTEGER NOT NULL,
+						event TEXT NOT NULL,
+						event_key TEXT,
+						payload BLOB NOT NULL,
+						status TEXT NOT NULL,
+						attempts INTEGER NOT NULL DEFAULT 0,
+						next_attempt_at INTEGER NOT NULL,
+						last_attempt_at INTEGER,
+						last_status_code INTEGER,
+						last_error TEXT,
+						created_at INTEGER NOT NULL,
+						UNIQUE(webhook_id, event_key),
+						FOREIGN KEY(webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+					) STRICT;`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `
+					CREATE INDEX IF NOT EXISTS webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `
+					CREATE INDEX IF NOT EXISTS webhook_deliveries_user ON webhook_deliveries(user_id, created_at);`)
+				return err
+			},
+		},
+		{
+			name: "Create accounts table",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					CREATE TABLE IF NOT EXISTS accounts (
+						id INTEGER PRIMARY KEY,
+						user_id INTEGER NOT NULL,
+						name TEXT NOT NULL COLLATE NOCASE,
+						institution TEXT NOT NULL DEFAULT '',
+						currency TEXT NOT NULL,
+						type TEXT NOT NULL,
+						opening_balance INTEGER NOT NULL DEFAULT 0,
+						created_at INTEGER NOT NULL,
+						UNIQUE(user_id, name),
+						FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+					) STRICT;`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `
+					ALTER TABLE expenses ADD COLUMN account_id INTEGER
+					REFERENCES accounts(id) ON DELETE SET NULL;`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `
+					CREATE INDEX IF NOT EXISTS expenses_account ON expenses(account_id, date);`)
+				return err
+			},
+		},
+		{
+			name: "Create transfers table",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					CREATE TABLE IF NOT EXISTS transfers (
+						id INTEGER PRIMARY KEY,
+						user_id INTEGER NOT NULL,
+						charge_id INTEGER NOT NULL UNIQUE,
+						income_id INTEGER NOT NULL UNIQUE,
+						created_at INTEGER NOT NULL,
+						FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+						FOREIGN KEY(charge_id) REFERENCES expenses(id) ON DELETE CASCADE,
+						FOREIGN KEY(income_id) REFERENCES expenses(id) ON DELETE CASCADE
+					) STRICT;`)
+				return err
+			},
+		},
+		{
+			name: "Add base_currency column to users",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					ALTER TABLE users ADD COLUMN base_currency TEXT NOT NULL DEFAULT '';
+				`)
+				return err
+			},
+		},
+		{
+			name: "Create exchange_rates table",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					CREATE TABLE IF NOT EXISTS exchange_rates (
+						id INTEGER PRIMARY KEY,
+						user_id INTEGER NOT NULL,
+						date INTEGER NOT NULL,
+						base TEXT NOT NULL,
+						quote TEXT NOT NULL,
+						rate REAL NOT NULL,
+						UNIQUE(user_id, base, quote, date),
+						FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+					) STRICT;`)
+				return err
+			},
+		},
+		{
+			name: "Add external_id column to expenses",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					ALTER TABLE expenses ADD COLUMN external_id TEXT;`)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecContext(ctx, `
+					CREATE INDEX IF NOT EXISTS expenses_external_id
+					ON expenses(user_id, account_id, external_id);`)
+				return err
+			},
+		},
+	}
+}
+
+func (s *sqliteStorage) ApplyMigrations(ctx context.Context, logger *logger.Logger) error {
+	// Create migrations table if it doesn't exist
+	if err := createMigrationsTable(s.db); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	// Get current schema version
+	currentVersion := 0
+	row := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
+	if err := row.Scan(&currentVersion); err != nil {
+		return fmt.Errorf("failed to get current schema version: %w", err)
 	}
 
 	// Apply pending migrations
-	for i, migration := range migrations {
+	for i, migration := range schemaMigrations(ctx) {
 		// Check if migration is already applied
 		migrationVersion := i + 1
 		//nolint:nestif // No need to extract this code to a function as is clear
@@ -618,3 +1042,42 @@ func (s *sqliteStorage) ApplyMigrations(ctx context.Context, logger *logger.Logg
 
 	return nil
 }
+
+// GetMigrations lists every schema migration known to this build, with the
+// time it was applied or a zero time when it is still pending.
+func (s *sqliteStorage) GetMigrations(ctx context.Context) ([]domain.Migration, error) {
+	if err := createMigrationsTable(s.db); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = time.Unix(appliedAt, 0)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	known := schemaMigrations(ctx)
+	migrations := make([]domain.Migration, 0, len(known))
+	for i, m := range known {
+		migrations = append(migrations, domain.Migration{
+			Version:   i + 1,
+			Name:      m.name,
+			AppliedAt: applied[i+1],
+		})
+	}
+
+	return migrations, nil
+}
@@ -26,3 +26,22 @@ func TestMigrations(t *testing.T) {
 		t.Fatalf("Failed to create category after migrations: %v", err)
 	}
 }
+
+func TestGetMigrations(t *testing.T) {
+	stor, _ := setupTestStorage(t)
+
+	migrations, err := stor.GetMigrations(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get migrations: %v", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("Expected migrations to be listed")
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+1 || m.Name == "" || !m.Applied() {
+			t.Errorf("Expected migration %d to be applied, got %+v", i+1, m)
+		}
+	}
+}
@@ -89,3 +89,13 @@ func (s *sqliteStorage) DeleteExpiredSessions(ctx context.Context) error {
 
 	return nil
 }
+
+// DeleteUserSessions signs the user out of every session.
+func (s *sqliteStorage) DeleteUserSessions(ctx context.Context, userID int64) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+
+	return nil
+}
@@ -32,3 +32,10 @@ func New(dbsource string) (storage.Storage, error) {
 func (s *sqliteStorage) Close() error {
 	return s.db.Close()
 }
+
+// Backup writes a consistent copy of the database to path, which must not
+// exist yet. It is safe to run while the database is in use.
+func (s *sqliteStorage) Backup(ctx context.Context, path string) error {
+	_, err := s.db.ExecContext(ctx, "VACUUM INTO ?", path)
+	return err
+}
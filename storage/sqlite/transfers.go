@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func (s *sqliteStorage) CreateTransfer(
+	ctx context.Context,
+	userID int64,
+	transfer domain.Transfer,
+) (domain.Transfer, error) {
+	createdAt := time.Now()
+
+	result, err := s.db.ExecContext(ctx,
+		"INSERT INTO transfers (user_id, charge_id, income_id, created_at) VALUES (?, ?, ?, ?)",
+		userID,
+		transfer.ChargeID(),
+		transfer.IncomeID(),
+		createdAt.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transfer: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewTransfer(id, transfer.ChargeID(), transfer.IncomeID(), time.Unix(createdAt.Unix(), 0)), nil
+}
+
+// GetTransfers returns the user's transfers, most recently linked first.
+func (s *sqliteStorage) GetTransfers(ctx context.Context, userID int64) ([]domain.Transfer, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, charge_id, income_id, created_at FROM transfers WHERE user_id = ? ORDER BY id DESC",
+		userID,
+	)
+	if err != nil {
+		return []domain.Transfer{}, err
+	}
+	defer rows.Close()
+
+	transfers := []domain.Transfer{}
+	for rows.Next() {
+		var id, chargeID, incomeID, createdAt int64
+		if err = rows.Scan(&id, &chargeID, &incomeID, &createdAt); err != nil {
+			return transfers, err
+		}
+		transfers = append(transfers, domain.NewTransfer(id, chargeID, incomeID, time.Unix(createdAt, 0)))
+	}
+
+	return transfers, rows.Err()
+}
+
+// DeleteTransfer unlinks the expenses of the transfer, the expenses are kept.
+func (s *sqliteStorage) DeleteTransfer(ctx context.Context, userID, id int64) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM transfers WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete transfer: %w", err)
+	}
+
+	return result.RowsAffected()
+}
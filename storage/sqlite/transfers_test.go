@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func TestTransfers(t *testing.T) {
+	stor, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	date := time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)
+	_, err := stor.InsertExpenses(ctx, user.ID(), []domain.Expense{
+		domain.NewExpense(0, "checking", "to savings", "EUR", -50000, date, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "savings", "from checking", "EUR", 50000, date, domain.IncomeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	expenses, err := stor.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	charge, income := expenses[0], expenses[1]
+
+	created, err := stor.CreateTransfer(ctx, user.ID(), domain.NewTransfer(0, charge.ID(), income.ID(), time.Time{}))
+	if err != nil {
+		t.Fatalf("Failed to create transfer: %v", err)
+	}
+
+	// An expense is part of one transfer at most
+	_, err = stor.CreateTransfer(ctx, user.ID(), domain.NewTransfer(0, charge.ID(), income.ID(), time.Time{}))
+	if err == nil {
+		t.Fatal("Expected an error linking the same expenses twice")
+	}
+
+	// Updating the expenses in bulk keeps the transfer
+	categoryID, err := stor.CreateCategory(ctx, user.ID(), "Savings", "savings", 0)
+	if err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+	recategorized := domain.NewExpense(
+		charge.ID(),
+		charge.Source(),
+		charge.Description(),
+		charge.Currency(),
+		charge.Amount(),
+		charge.Date(),
+		charge.Type(),
+		&categoryID,
+		nil,
+	)
+	if _, err = stor.UpdateExpenses(ctx, user.ID(), []domain.Expense{recategorized}); err != nil {
+		t.Fatalf("Failed to update expenses: %v", err)
+	}
+
+	transfers, err := stor.GetTransfers(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get transfers: %v", err)
+	}
+	if len(transfers) != 1 {
+		t.Fatalf("Expected 1 transfer, got %d", len(transfers))
+	}
+	if transfers[0].ID() != created.ID() || transfers[0].ChargeID() != charge.ID() ||
+		transfers[0].IncomeID() != income.ID() {
+		t.Errorf("Unexpected transfer %+v", transfers[0])
+	}
+
+	// Deleting one of the expenses deletes the transfer
+	if _, err = stor.DeleteExpense(ctx, user.ID(), income.ID()); err != nil {
+		t.Fatalf("Failed to delete expense: %v", err)
+	}
+
+	transfers, err = stor.GetTransfers(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get transfers: %v", err)
+	}
+	if len(transfers) != 0 {
+		t.Errorf("Expected the transfer to be deleted with its expense, got %d transfers", len(transfers))
+	}
+}
+
+func TestDeleteTransfer(t *testing.T) {
+	stor, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	date := time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)
+	_, err := stor.InsertExpenses(ctx, user.ID(), []domain.Expense{
+		domain.NewExpense(0, "checking", "to savings", "EUR", -50000, date, domain.ChargeType, nil, nil),
+		domain.NewExpense(0, "savings", "from checking", "EUR", 50000, date, domain.IncomeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	expenses, err := stor.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	created, err := stor.CreateTransfer(ctx, user.ID(), domain.NewTransfer(
+		0, expenses[0].ID(), expenses[1].ID(), time.Time{},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create transfer: %v", err)
+	}
+
+	deleted, err := stor.DeleteTransfer(ctx, user.ID(), created.ID())
+	if err != nil {
+		t.Fatalf("Failed to delete transfer: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 deleted transfer, got %d", deleted)
+	}
+
+	// The expenses are kept
+	expenses, err = stor.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+	if len(expenses) != 2 {
+		t.Errorf("Expected the 2 expenses to be kept, got %d", len(expenses))
+	}
+}
+
+func TestTransfers_RollbackImportBatch(t *testing.T) {
+	stor, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	date := time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)
+	_, err := stor.InsertExpenses(ctx, user.ID(), []domain.Expense{
+		domain.NewExpense(0, "checking", "to savings", "EUR", -50000, date, domain.ChargeType, nil, nil),
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert expenses: %v", err)
+	}
+
+	batch := createImportBatch(
+		t,
+		stor,
+		user.ID(),
+		domain.NewImportBatch(0, "savings.csv", "Bank", nil, 1, 0, 0, time.Time{}, nil),
+		[]domain.Expense{
+			domain.NewExpense(0, "savings", "from checking", "EUR", 50000, date, domain.IncomeType, nil, nil),
+		},
+	)
+
+	expenses, err := stor.GetAllExpenseTypes(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get expenses: %v", err)
+	}
+
+	_, err = stor.CreateTransfer(ctx, user.ID(), domain.NewTransfer(
+		0, expenses[0].ID(), expenses[1].ID(), time.Time{},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create transfer: %v", err)
+	}
+
+	// Rolling back the batch deletes the transfers of its expenses
+	if _, err = stor.RollbackImportBatch(ctx, user.ID(), batch.ID()); err != nil {
+		t.Fatalf("Failed to roll back import batch: %v", err)
+	}
+
+	transfers, err := stor.GetTransfers(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get transfers: %v", err)
+	}
+	if len(transfers) != 0 {
+		t.Errorf("Expected the transfer to be deleted with the batch, got %d transfers", len(transfers))
+	}
+}
@@ -46,12 +46,12 @@ func (s *sqliteStorage) CreateUser(ctx context.Context, username, passwordHash s
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return domain.NewUser(userID, username, passwordHash, createdAt), nil
+	return domain.NewUser(userID, username, passwordHash, "", "", createdAt), nil
 }
 
 func (s *sqliteStorage) GetUserByUsername(ctx context.Context, username string) (domain.User, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, username, password_hash, created_at
+		SELECT id, username, password_hash, default_currency, base_currency, created_at
 		FROM users
 		WHERE username = ?
 	`, username)
@@ -59,9 +59,11 @@ func (s *sqliteStorage) GetUserByUsername(ctx context.Context, username string)
 	var id int64
 	var uname string
 	var passwordHash string
+	var defaultCurrency string
+	var baseCurrency string
 	var createdAt int64
 
-	err := row.Scan(&id, &uname, &passwordHash, &createdAt)
+	err := row.Scan(&id, &uname, &passwordHash, &defaultCurrency, &baseCurrency, &createdAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, &domain.NotFoundError{}
@@ -69,12 +71,12 @@ func (s *sqliteStorage) GetUserByUsername(ctx context.Context, username string)
 		return nil, fmt.Errorf("failed to scan user: %w", err)
 	}
 
-	return domain.NewUser(id, uname, passwordHash, time.Unix(createdAt, 0)), nil
+	return domain.NewUser(id, uname, passwordHash, defaultCurrency, baseCurrency, time.Unix(createdAt, 0)), nil
 }
 
 func (s *sqliteStorage) GetUserByID(ctx context.Context, id int64) (domain.User, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, username, password_hash, created_at
+		SELECT id, username, password_hash, default_currency, base_currency, created_at
 		FROM users
 		WHERE id = ?
 	`, id)
@@ -82,9 +84,11 @@ func (s *sqliteStorage) GetUserByID(ctx context.Context, id int64) (domain.User,
 	var userID int64
 	var username string
 	var passwordHash string
+	var defaultCurrency string
+	var baseCurrency string
 	var createdAt int64
 
-	err := row.Scan(&userID, &username, &passwordHash, &createdAt)
+	err := row.Scan(&userID, &username, &passwordHash, &defaultCurrency, &baseCurrency, &createdAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, &domain.NotFoundError{}
@@ -92,7 +96,7 @@ func (s *sqliteStorage) GetUserByID(ctx context.Context, id int64) (domain.User,
 		return nil, fmt.Errorf("failed to scan user: %w", err)
 	}
 
-	return domain.NewUser(userID, username, passwordHash, time.Unix(createdAt, 0)), nil
+	return domain.NewUser(userID, username, passwordHash, defaultCurrency, baseCurrency, time.Unix(createdAt, 0)), nil
 }
 
 func (s *sqliteStorage) UpdateUsername(ctx context.Context, userID int64, newUsername string) error {
@@ -138,3 +142,99 @@ func (s *sqliteStorage) UpdatePassword(ctx context.Context, userID int64, newPas
 
 	return nil
 }
+
+func (s *sqliteStorage) UpdateDefaultCurrency(ctx context.Context, userID int64, currency string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE users
+		SET default_currency = ?
+		WHERE id = ?
+	`, currency, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update default currency: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &domain.NotFoundError{}
+	}
+
+	return nil
+}
+
+func (s *sqliteStorage) UpdateBaseCurrency(ctx context.Context, userID int64, currency string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE users
+		SET base_currency = ?
+		WHERE id = ?
+	`, currency, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update base currency: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &domain.NotFoundError{}
+	}
+
+	return nil
+}
+
+// DeleteUser removes the user and everything they own. Rows are deleted
+// explicitly as foreign keys are only enforced on some pooled connections.
+func (s *sqliteStorage) DeleteUser(ctx context.Context, userID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() // Will be no-op if committed
+	}()
+
+	// Children first, to respect foreign keys
+	statements := []string{
+		`DELETE FROM import_session_chunks
+		WHERE session_id IN (SELECT id FROM import_sessions WHERE user_id = ?)`,
+		"DELETE FROM import_sessions WHERE user_id = ?",
+		"DELETE FROM inbox_failures WHERE user_id = ?",
+		"DELETE FROM sessions WHERE user_id = ?",
+		"DELETE FROM api_tokens WHERE user_id = ?",
+		"DELETE FROM webhook_deliveries WHERE user_id = ?",
+		"DELETE FROM webhooks WHERE user_id = ?",
+		"DELETE FROM exchange_rates WHERE user_id = ?",
+		"DELETE FROM transfers WHERE user_id = ?",
+		"DELETE FROM expenses WHERE user_id = ?",
+		"DELETE FROM accounts WHERE user_id = ?",
+		"DELETE FROM import_batches WHERE user_id = ?",
+		"DELETE FROM mapping_profiles WHERE user_id = ?",
+		"DELETE FROM categories WHERE user_id = ?",
+	}
+	for _, statement := range statements {
+		if _, err = tx.ExecContext(ctx, statement, userID); err != nil {
+			return fmt.Errorf("failed to delete user data: %w", err)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &domain.NotFoundError{}
+	}
+
+	return tx.Commit()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
@@ -264,3 +265,74 @@ func TestUpdatePasswordNotFound(t *testing.T) {
 		t.Errorf("Expected NotFoundError, got %v", err)
 	}
 }
+
+func TestUpdateDefaultCurrency(t *testing.T) {
+	s, user := setupTestStorage(t)
+
+	if user.DefaultCurrency() != "" {
+		t.Fatalf("New users should not have a default currency, got %q", user.DefaultCurrency())
+	}
+
+	err := s.UpdateDefaultCurrency(context.Background(), user.ID(), "EUR")
+	if err != nil {
+		t.Fatalf("Failed to update default currency: %v", err)
+	}
+
+	updated, err := s.GetUserByID(context.Background(), user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get updated user: %v", err)
+	}
+
+	if updated.DefaultCurrency() != "EUR" {
+		t.Errorf("Expected default currency EUR, got %q", updated.DefaultCurrency())
+	}
+
+	err = s.UpdateDefaultCurrency(context.Background(), 99999, "EUR")
+	var notFoundErr *domain.NotFoundError
+	if !errors.As(err, &notFoundErr) {
+		t.Errorf("Expected NotFoundError, got %v", err)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	other, err := s.CreateUser(ctx, "other", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	for _, u := range []domain.User{user, other} {
+		if _, err = s.CreateCategory(ctx, u.ID(), "Food", "food", 0); err != nil {
+			t.Fatalf("Failed to create category: %v", err)
+		}
+		if _, err = s.CreateSession(ctx, u.ID(), "session-"+u.Username(), time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+
+	if err = s.DeleteUser(ctx, user.ID()); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+
+	var notFound *domain.NotFoundError
+	if _, err = s.GetUserByID(ctx, user.ID()); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError for the deleted user, got %v", err)
+	}
+	if _, err = s.GetSession(ctx, "session-"+user.Username()); !errors.As(err, &notFound) {
+		t.Errorf("Expected the sessions of the deleted user to be removed, got %v", err)
+	}
+
+	categories, err := s.GetCategories(ctx, other.ID())
+	if err != nil {
+		t.Fatalf("Failed to get categories: %v", err)
+	}
+	if len(categories) != 2 {
+		t.Errorf("Expected the other user's categories to be kept, got %d", len(categories))
+	}
+
+	if err = s.DeleteUser(ctx, user.ID()); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError deleting a missing user, got %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func (s *sqliteStorage) CreateWebhook(
+	ctx context.Context,
+	userID int64,
+	url, secret string,
+	events []string,
+) (domain.Webhook, error) {
+	createdAt := time.Now()
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhooks (user_id, url, secret, events, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		userID,
+		url,
+		secret,
+		strings.Join(events, ","),
+		createdAt.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewWebhook(id, userID, url, secret, events, time.Unix(createdAt.Unix(), 0)), nil
+}
+
+// GetWebhooks returns the user's webhooks, newest first.
+func (s *sqliteStorage) GetWebhooks(ctx context.Context, userID int64) ([]domain.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, url, secret, events, created_at FROM webhooks
+		WHERE user_id = ? ORDER BY created_at DESC, id DESC`,
+		userID,
+	)
+	if err != nil {
+		return []domain.Webhook{}, err
+	}
+	defer rows.Close()
+
+	webhooks := []domain.Webhook{}
+	for rows.Next() {
+		var id, webhookUserID, createdAt int64
+		var url, secret, events string
+		if err = rows.Scan(&id, &webhookUserID, &url, &secret, &events, &createdAt); err != nil {
+			return webhooks, err
+		}
+		webhooks = append(webhooks, domain.NewWebhook(
+			id, webhookUserID, url, secret, splitEvents(events), time.Unix(createdAt, 0),
+		))
+	}
+
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook deletes the webhook along with its deliveries.
+func (s *sqliteStorage) DeleteWebhook(ctx context.Context, userID, id int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() // Will be no-op if committed
+	}()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit()
+}
+
+// EnqueueWebhookEvent queues a delivery of the payload to every webhook of
+// the user subscribed to the event. Events with a key are queued at most
+// once per webhook, later events with the same key are ignored. It returns
+// the number of deliveries queued.
+func (s *sqliteStorage) EnqueueWebhookEvent(
+	ctx context.Context,
+	userID int64,
+	event, key string,
+	payload []byte,
+) (int64, error) {
+	now := time.Now().Unix()
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO webhook_deliveries
+			(webhook_id, user_id, event, event_key, payload, status, next_attempt_at, created_at)
+		SELECT id, user_id, ?, NULLIF(?, ''), ?, ?, ?, ? FROM webhooks
+		WHERE user_id = ? AND instr(',' || events || ',', ',' || ? || ',') > 0`,
+		event,
+		key,
+		payload,
+		string(domain.WebhookDeliveryPending),
+		now,
+		now,
+		userID,
+		event,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook event: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+const webhookDeliveryColumns = `
+	d.id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_attempt_at,
+	d.last_status_code, d.last_error, d.created_at,
+	w.id, w.user_id, w.url, w.secret, w.events, w.created_at`
+
+// GetDueWebhookDeliveries returns the pending deliveries of every user whose
+// next attempt is due, oldest first.
+func (s *sqliteStorage) GetDueWebhookDeliveries(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]domain.WebhookDelivery, error) {
+	return s.queryWebhookDeliveries(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = ? AND d.next_attempt_at <= ?
+		ORDER BY d.next_attempt_at, d.id LIMIT ?`,
+		string(domain.WebhookDeliveryPending),
+		now.Unix(),
+		limit,
+	)
+}
+
+// GetWebhookDeliveries returns the user's latest deliveries, newest first.
+func (s *sqliteStorage) GetWebhookDeliveries(
+	ctx context.Context,
+	userID int64,
+	limit int,
+) ([]domain.WebhookDelivery, error) {
+	return s.queryWebhookDeliveries(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.user_id = ?
+		ORDER BY d.created_at DESC, d.id DESC LIMIT ?`,
+		userID,
+		limit,
+	)
+}
+
+// RecordWebhookAttempt stores the outcome of an attempt to post the
+// delivery, with its new status and when it is attempted next.
+func (s *sqliteStorage) RecordWebhookAttempt(
+	ctx context.Context,
+	id int64,
+	status domain.WebhookDeliveryStatus,
+	attempt domain.WebhookAttempt,
+	nextAttemptAt time.Time,
+) error {
+	var lastError sql.NullString
+	if attempt.Error != "" {
+		lastError = sql.NullString{String: attempt.Error, Valid: true}
+	}
+	var statusCode sql.NullInt64
+	if attempt.StatusCode != 0 {
+		statusCode = sql.NullInt64{Int64: int64(attempt.StatusCode), Valid: true}
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, last_attempt_at = ?, last_status_code = ?,
+			last_error = ?, next_attempt_at = ?
+		WHERE id = ?`,
+		string(status),
+		attempt.At.Unix(),
+		statusCode,
+		lastError,
+		nextAttemptAt.Unix(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWebhookDeliveriesBefore deletes the delivered and failed deliveries
+// created before the given time, pending deliveries are kept.
+func (s *sqliteStorage) DeleteWebhookDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE status != ? AND created_at < ?",
+		string(domain.WebhookDeliveryPending),
+		before.Unix(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (s *sqliteStorage) queryWebhookDeliveries(
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]domain.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return []domain.WebhookDelivery{}, err
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		delivery, scanErr := scanWebhookDelivery(rows)
+		if scanErr != nil {
+			return deliveries, scanErr
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func scanWebhookDelivery(row scanner) (domain.WebhookDelivery, error) {
+	var id, nextAttemptAt, createdAt int64
+	var attempts int
+	var event, status string
+	var payload []byte
+	var lastAttemptAt, lastStatusCode sql.NullInt64
+	var lastError sql.NullString
+	var webhookID, userID, webhookCreatedAt int64
+	var url, secret, events string
+	err := row.Scan(
+		&id, &event, &payload, &status, &attempts, &nextAttemptAt, &lastAttemptAt,
+		&lastStatusCode, &lastError, &createdAt,
+		&webhookID, &userID, &url, &secret, &events, &webhookCreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lastAttempt := domain.WebhookAttempt{
+		StatusCode: int(lastStatusCode.Int64),
+		Error:      lastError.String,
+	}
+	if lastAttemptAt.Valid {
+		lastAttempt.At = time.Unix(lastAttemptAt.Int64, 0)
+	}
+
+	return domain.NewWebhookDelivery(
+		id,
+		domain.NewWebhook(webhookID, userID, url, secret, splitEvents(events), time.Unix(webhookCreatedAt, 0)),
+		event,
+		payload,
+		domain.WebhookDeliveryStatus(status),
+		attempts,
+		lastAttempt,
+		time.Unix(nextAttemptAt, 0),
+		time.Unix(createdAt, 0),
+	), nil
+}
+
+func splitEvents(events string) []string {
+	if events == "" {
+		return []string{}
+	}
+	return strings.Split(events, ",")
+}
@@ -0,0 +1,145 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/expensetrace/domain"
+)
+
+func TestWebhooks(t *testing.T) {
+	s, user := setupTestStorage(t)
+	ctx := context.Background()
+
+	other, err := s.CreateUser(ctx, "other", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	first, err := s.CreateWebhook(ctx, user.ID(), "https://example.com/first", "secret-1",
+		[]string{domain.EventExpenseCreated, domain.EventBudgetExceeded})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	second, err := s.CreateWebhook(ctx, user.ID(), "https://example.com/second", "secret-2",
+		[]string{domain.EventExpenseDeleted})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	if _, err = s.CreateWebhook(ctx, other.ID(), "https://example.com/other", "secret-3",
+		[]string{domain.EventExpenseCreated}); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	webhooks, err := s.GetWebhooks(ctx, user.ID())
+	if err != nil {
+		t.Fatalf("Failed to get webhooks: %v", err)
+	}
+	if len(webhooks) != 2 || webhooks[0].ID() != second.ID() || webhooks[1].URL() != "https://example.com/first" {
+		t.Fatalf("Expected the user's webhooks newest first, got %v", webhooks)
+	}
+	if !webhooks[1].Subscribed(domain.EventBudgetExceeded) || webhooks[1].Subscribed(domain.EventExpenseDeleted) {
+		t.Errorf("Expected the events to be stored, got %v", webhooks[1].Events())
+	}
+
+	queued, err := s.EnqueueWebhookEvent(ctx, user.ID(), domain.EventExpenseCreated, "", []byte(`{"id":"1"}`))
+	if err != nil {
+		t.Fatalf("Failed to enqueue event: %v", err)
+	}
+	if queued != 1 {
+		t.Errorf("Expected a delivery to the one subscribed webhook of the user, got %d", queued)
+	}
+
+	for range 2 {
+		queued, err = s.EnqueueWebhookEvent(ctx, user.ID(), domain.EventBudgetExceeded, "food:2025-01", []byte(`{}`))
+		if err != nil {
+			t.Fatalf("Failed to enqueue event: %v", err)
+		}
+	}
+	if queued != 0 {
+		t.Errorf("Expected an event with the same key to be ignored, got %d deliveries", queued)
+	}
+
+	deliveries, err := s.GetWebhookDeliveries(ctx, user.ID(), 10)
+	if err != nil {
+		t.Fatalf("Failed to get deliveries: %v", err)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d", len(deliveries))
+	}
+	delivery := deliveries[1]
+	if delivery.Event() != domain.EventExpenseCreated || string(delivery.Payload()) != `{"id":"1"}` ||
+		delivery.Status() != domain.WebhookDeliveryPending || delivery.Webhook().Secret() != "secret-1" {
+		t.Errorf("Got the wrong delivery %v", delivery)
+	}
+
+	now := time.Now()
+	due, err := s.GetDueWebhookDeliveries(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("Failed to get due deliveries: %v", err)
+	}
+	if len(due) != 2 {
+		t.Fatalf("Expected 2 due deliveries, got %d", len(due))
+	}
+
+	err = s.RecordWebhookAttempt(ctx, delivery.ID(), domain.WebhookDeliveryPending, domain.WebhookAttempt{
+		At:         now,
+		StatusCode: 500,
+		Error:      "unexpected status 500",
+	}, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to record attempt: %v", err)
+	}
+
+	due, err = s.GetDueWebhookDeliveries(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("Failed to get due deliveries: %v", err)
+	}
+	if len(due) != 1 || due[0].ID() == delivery.ID() {
+		t.Errorf("Expected the retried delivery to wait for its next attempt, got %v", due)
+	}
+
+	deliveries, err = s.GetWebhookDeliveries(ctx, user.ID(), 10)
+	if err != nil {
+		t.Fatalf("Failed to get deliveries: %v", err)
+	}
+	attempted := deliveries[1]
+	if attempted.Attempts() != 1 || attempted.LastAttempt().StatusCode != 500 ||
+		attempted.LastAttempt().Error != "unexpected status 500" || attempted.LastAttempt().At.Unix() != now.Unix() {
+		t.Errorf("Expected the attempt to be recorded, got %d attempts and %+v",
+			attempted.Attempts(), attempted.LastAttempt())
+	}
+
+	err = s.RecordWebhookAttempt(ctx, delivery.ID(), domain.WebhookDeliveryDelivered,
+		domain.WebhookAttempt{At: now, StatusCode: 200}, now)
+	if err != nil {
+		t.Fatalf("Failed to record attempt: %v", err)
+	}
+
+	pruned, err := s.DeleteWebhookDeliveriesBefore(ctx, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to prune deliveries: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("Expected only the finished delivery to be pruned, got %d", pruned)
+	}
+
+	deleted, err := s.DeleteWebhook(ctx, other.ID(), first.ID())
+	if err != nil || deleted != 0 {
+		t.Errorf("Expected webhooks of other users to be left alone, got %d %v", deleted, err)
+	}
+
+	deleted, err = s.DeleteWebhook(ctx, user.ID(), first.ID())
+	if err != nil || deleted != 1 {
+		t.Fatalf("Expected the webhook to be deleted, got %d %v", deleted, err)
+	}
+
+	deliveries, err = s.GetWebhookDeliveries(ctx, user.ID(), 10)
+	if err != nil {
+		t.Fatalf("Failed to get deliveries: %v", err)
+	}
+	if len(deliveries) != 0 {
+		t.Errorf("Expected the deliveries of the webhook to be deleted, got %d", len(deliveries))
+	}
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/GustavoCaso/expensetrace/domain"
@@ -11,6 +12,7 @@ import (
 type Storage interface {
 	// Migrations
 	ApplyMigrations(ctx context.Context, logger *logger.Logger) error
+	GetMigrations(ctx context.Context) ([]domain.Migration, error)
 
 	// Users
 	CreateUser(ctx context.Context, username, passwordHash string) (domain.User, error)
@@ -18,17 +20,45 @@ type Storage interface {
 	GetUserByID(ctx context.Context, id int64) (domain.User, error)
 	UpdateUsername(ctx context.Context, userID int64, newUsername string) error
 	UpdatePassword(ctx context.Context, userID int64, newPasswordHash string) error
+	UpdateDefaultCurrency(ctx context.Context, userID int64, currency string) error
+	UpdateBaseCurrency(ctx context.Context, userID int64, currency string) error
+	DeleteUser(ctx context.Context, userID int64) error
 
 	// Sessions
 	CreateSession(ctx context.Context, userID int64, sessionID string, expiresAt time.Time) (domain.Session, error)
 	GetSession(ctx context.Context, sessionID string) (domain.Session, error)
 	DeleteSession(ctx context.Context, sessionID string) error
 	DeleteExpiredSessions(ctx context.Context) error
+	DeleteUserSessions(ctx context.Context, userID int64) error
+
+	// API tokens
+	CreateAPIToken(ctx context.Context, userID int64, name, prefix, tokenHash string) (domain.APIToken, error)
+	GetAPITokens(ctx context.Context, userID int64) ([]domain.APIToken, error)
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (domain.APIToken, error)
+	UpdateAPITokenLastUsed(ctx context.Context, id int64, usedAt time.Time) error
+	DeleteAPIToken(ctx context.Context, userID, id int64) (int64, error)
+
+	// Webhooks
+	CreateWebhook(ctx context.Context, userID int64, url, secret string, events []string) (domain.Webhook, error)
+	GetWebhooks(ctx context.Context, userID int64) ([]domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID, id int64) (int64, error)
+	EnqueueWebhookEvent(ctx context.Context, userID int64, event, key string, payload []byte) (int64, error)
+	GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, userID int64, limit int) ([]domain.WebhookDelivery, error)
+	RecordWebhookAttempt(
+		ctx context.Context,
+		id int64,
+		status domain.WebhookDeliveryStatus,
+		attempt domain.WebhookAttempt,
+		nextAttemptAt time.Time,
+	) error
+	DeleteWebhookDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
 
 	// Expenses
 	GetExpenseByID(ctx context.Context, userID, id int64) (domain.Expense, error)
 	UpdateExpense(ctx context.Context, userID int64, expense domain.Expense) (int64, error)
 	DeleteExpense(ctx context.Context, userID, id int64) (int64, error)
+	CreateExpense(ctx context.Context, userID int64, expense domain.Expense) (domain.Expense, error)
 	InsertExpenses(ctx context.Context, userID int64, expenses []domain.Expense) (int64, error)
 	GetExpenses(ctx context.Context, userID int64) ([]domain.Expense, error)
 	GetAllExpenseTypes(ctx context.Context, userID int64) ([]domain.Expense, error)
@@ -61,6 +91,79 @@ type Storage interface {
 	DeleteCategories(ctx context.Context, userID int64) (int64, error)
 	GetExcludeCategory(ctx context.Context, userID int64) (domain.Category, error)
 
+	// Accounts
+	CreateAccount(ctx context.Context, userID int64, account domain.Account) (domain.Account, error)
+	GetAccounts(ctx context.Context, userID int64) ([]domain.Account, error)
+	GetAccount(ctx context.Context, userID, id int64) (domain.Account, error)
+	UpdateAccount(ctx context.Context, userID int64, account domain.Account) (int64, error)
+	DeleteAccount(ctx context.Context, userID, id int64) (int64, error)
+	GetAccountTotals(ctx context.Context, userID int64) (map[int64]domain.AccountTotals, error)
+
+	// Transfers
+	CreateTransfer(ctx context.Context, userID int64, transfer domain.Transfer) (domain.Transfer, error)
+	GetTransfers(ctx context.Context, userID int64) ([]domain.Transfer, error)
+	DeleteTransfer(ctx context.Context, userID, id int64) (int64, error)
+
+	// Exchange rates
+	SaveExchangeRates(ctx context.Context, userID int64, rates []domain.ExchangeRate) (int64, error)
+	GetExchangeRates(ctx context.Context, userID int64, limit int) ([]domain.ExchangeRate, error)
+	GetExchangeRate(ctx context.Context, userID int64, base, quote string, date time.Time) (domain.ExchangeRate, error)
+	GetExchangeRateBases(ctx context.Context, userID int64) ([]string, error)
+	DeleteExchangeRate(ctx context.Context, userID, id int64) (int64, error)
+
+	// Mapping profiles
+	SaveMappingProfile(
+		ctx context.Context,
+		userID int64,
+		name, headerFingerprint string,
+		mapping []byte,
+	) (domain.MappingProfile, error)
+	GetMappingProfiles(ctx context.Context, userID int64) ([]domain.MappingProfile, error)
+	GetMappingProfile(ctx context.Context, userID, id int64) (domain.MappingProfile, error)
+	GetMappingProfileByFingerprint(
+		ctx context.Context,
+		userID int64,
+		headerFingerprint string,
+	) (domain.MappingProfile, error)
+	DeleteMappingProfile(ctx context.Context, userID, id int64) (int64, error)
+
+	// Import batches
+	BeginImportBatch(ctx context.Context, userID int64, batch domain.ImportBatch) (ImportBatchWriter, error)
+	GetImportBatches(ctx context.Context, userID int64) ([]domain.ImportBatch, error)
+	RollbackImportBatch(ctx context.Context, userID, id int64) (int64, error)
+
+	// Import sessions
+	CreateImportSession(ctx context.Context, session domain.ImportSession, file io.Reader) error
+	GetImportSession(ctx context.Context, userID int64, id string) (domain.ImportSession, error)
+	UpdateImportSession(ctx context.Context, userID int64, id string, data, mapping []byte) error
+	OpenImportSessionFile(ctx context.Context, userID int64, id string) (io.ReadCloser, error)
+	DeleteImportSession(ctx context.Context, userID int64, id string) error
+	DeleteExpiredImportSessions(ctx context.Context) (int64, error)
+
+	// Inbox failures
+	CreateInboxFailure(ctx context.Context, userID int64, filename, message string) (domain.InboxFailure, error)
+	GetInboxFailures(ctx context.Context, userID int64) ([]domain.InboxFailure, error)
+	DeleteInboxFailures(ctx context.Context, userID int64) (int64, error)
+
 	// Resource managment
+	Backup(ctx context.Context, path string) error
 	Close() error
 }
+
+// ImportBatchWriter inserts the expenses of an import batch a chunk at a
+// time inside a single transaction, so large files are stored without
+// holding all their expenses in memory. Nothing is visible until Commit.
+type ImportBatchWriter interface {
+	// GetExpensesFromDateRange returns the user's expenses stored before the
+	// import began, to detect duplicates.
+	GetExpensesFromDateRange(ctx context.Context, start time.Time, end time.Time) ([]domain.Expense, error)
+	// GetExpensesByExternalIDs returns the user's expenses stored before the
+	// import began that carry any of the external IDs, whatever their date.
+	GetExpensesByExternalIDs(ctx context.Context, externalIDs []string) ([]domain.Expense, error)
+	InsertExpenses(ctx context.Context, expenses []domain.Expense) (int64, error)
+	// Commit records the rows read and the duplicates skipped, and stores
+	// the import.
+	Commit(ctx context.Context, rowCount, skippedDuplicates int) (domain.ImportBatch, error)
+	// Rollback discards the import, a no-op once committed.
+	Rollback() error
+}
@@ -5,6 +5,7 @@ import "github.com/fatih/color"
 var colorsOptions = map[string]color.Attribute{
 	"red":       color.FgHiRed,
 	"green":     color.FgGreen,
+	"yellow":    color.FgYellow,
 	"underline": color.Underline,
 	"bold":      color.Bold,
 	"bgRed":     color.BgRed,
@@ -0,0 +1,22 @@
+package util
+
+import "strings"
+
+const currencyCodeLength = 3
+
+// NormalizeCurrency upper cases and trims an ISO 4217 currency code like
+// "eur". It reports false when code is not three letters.
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != currencyCodeLength {
+		return "", false
+	}
+
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", false
+		}
+	}
+
+	return code, true
+}
@@ -0,0 +1,30 @@
+package util
+
+import "testing"
+
+func TestNormalizeCurrency(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+		valid    bool
+	}{
+		{code: "EUR", expected: "EUR", valid: true},
+		{code: " usd ", expected: "USD", valid: true},
+		{code: "", valid: false},
+		{code: "EURO", valid: false},
+		{code: "E1R", valid: false},
+		{code: "€", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			code, valid := NormalizeCurrency(tt.code)
+			if valid != tt.valid {
+				t.Fatalf("NormalizeCurrency(%q) valid = %v, want %v", tt.code, valid, tt.valid)
+			}
+			if code != tt.expected {
+				t.Errorf("NormalizeCurrency(%q) = %q, want %q", tt.code, code, tt.expected)
+			}
+		})
+	}
+}
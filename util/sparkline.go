@@ -0,0 +1,31 @@
+package util
+
+import "strings"
+
+// sparkBlocks are the bars of a sparkline, from lowest to highest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as a row of bars, one per value, scaled between
+// zero (or the smallest value, when negative) and the largest value.
+func Sparkline(values []int64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	lowest, highest := int64(0), int64(0)
+	for _, v := range values {
+		lowest = min(lowest, v)
+		highest = max(highest, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if highest > lowest {
+			level = int((v - lowest) * int64(len(sparkBlocks)-1) / (highest - lowest))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package util
+
+import "testing"
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []int64
+		expected string
+	}{
+		{
+			name:     "no values",
+			values:   nil,
+			expected: "",
+		},
+		{
+			name:     "scaled from zero",
+			values:   []int64{0, 100, 350, 700},
+			expected: "▁▂▄█",
+		},
+		{
+			name:     "all zero",
+			values:   []int64{0, 0, 0},
+			expected: "▁▁▁",
+		},
+		{
+			name:     "negative values",
+			values:   []int64{-700, 0, 700},
+			expected: "▁▄█",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := Sparkline(tt.values); result != tt.expected {
+				t.Errorf("Sparkline(%v) = %q, want %q", tt.values, result, tt.expected)
+			}
+		})
+	}
+}